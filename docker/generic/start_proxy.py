@@ -77,6 +77,23 @@ def gen_bootstrap_conf(args):
     if args.ads_named_pipe:
         cmd.extend(["--ads_named_pipe", args.ads_named_pipe])
 
+    if args.admin_loopback_only:
+        cmd.append("--admin_loopback_only")
+    if args.admin_socket_path:
+        cmd.extend(["--admin_socket_path", args.admin_socket_path])
+
+    if args.enable_operation_stats:
+        cmd.append("--enable_operation_stats")
+
+    if args.max_downstream_connections:
+        cmd.extend(["--max_downstream_connections", args.max_downstream_connections])
+    if args.overload_max_heap_size_bytes:
+        cmd.extend(["--overload_max_heap_size_bytes", args.overload_max_heap_size_bytes])
+    if args.overload_shrink_heap_threshold:
+        cmd.extend(["--overload_shrink_heap_threshold", args.overload_shrink_heap_threshold])
+    if args.overload_stop_accepting_requests_threshold:
+        cmd.extend(["--overload_stop_accepting_requests_threshold", args.overload_stop_accepting_requests_threshold])
+
     bootstrap_file = DEFAULT_CONFIG_DIR + BOOTSTRAP_CONFIG
     cmd.append(bootstrap_file)
     print(cmd)
@@ -991,215 +1008,1515 @@ environment variable or by passing "-k" flag to this script.
         default envoy compression settings. Please see envoy document for detail.
         https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/compressor_filter.''')
 
-    # Start Deprecated Flags Section
+    parser.add_argument(
+        '--readiness_port',
+        default=None,
+        help='''
+        Port for Config Manager to serve the readiness endpoint "/ready" on,
+        reporting whether the proxy has a config to serve. Not served if 0.''')
 
     parser.add_argument(
-        '--enable_backend_routing',
+        '--readiness_check_backend',
         action='store_true',
-        default=False,
         help='''
-        ===
-        DEPRECATED: This flag will automatically be enabled if needed, so it
-        does NOT need to be set manually.
-        ===
-        Enable ESPv2 to route requests according to the
-        "x-google-backend" or "backend" configuration
-        ''')
+        Also fail the readiness endpoint when no TCP connection can be
+        opened to the "--backend_address" backend.''')
+
     parser.add_argument(
-        '--backend_protocol',
+        '--readiness_check_timeout',
         default=None,
         help='''
-        ===
-        DEPRECATED: This flag will automatically be set based on the scheme
-        specified in the --backend flag. Overrides are no longer needed.
-        ===
-        Backend Protocol. Overrides the protocol in --backend.
-        Choices: [http1|http2|grpc].
-        Default value: http1.''',
-        choices=['http1', 'http2', 'grpc'])
-
-    parser.add_argument('--http_port', default=None, type=int, help='''
-        This flag is exactly same as --listener_port. It is added for
-        backward compatible for ESPv1 and will be deprecated.
-        Please use the flag --listener_port.''')
-
-    parser.add_argument('--http2_port', default=None, type=int, help='''
-        This flag is exactly same as --listener_port. It is added for
-        backward compatible for ESPv1 and will be deprecated.
-        Please use the flag --listener_port.''')
+        How long each dependency check behind the readiness endpoint may
+        take, such as "2s".''')
 
-    parser.add_argument('--ssl_port', default=None, type=int, help='''
-        This flag added for backward compatible for ESPv1 and will be deprecated.
-        Please use the flags --listener_port and --ssl_server_cert_path instead. 
-        When configured, ESPv2 accepts HTTP/1.x and HTTP/2 secure connections on this port,
-        Requires the certificate and key files /etc/nginx/ssl/nginx.crt and
-        /etc/nginx/ssl/nginx.key''')
+    parser.add_argument(
+        '--k8s_config_map',
+        default=None,
+        help='''
+        Read the service config JSON from a Kubernetes ConfigMap, given as
+        "namespace/name". The ConfigMap is polled for changes instead of
+        using the Service Management API.''')
 
-    parser.add_argument('--dns',  help='''
-        This flag is exactly same as --dns_resolver_addresses. This flag is added
-        for backward compatible for ESPv1 and will be deprecated.
-        Please use the flag --dns_resolver_addresses instead.''')
+    parser.add_argument(
+        '--k8s_config_map_key',
+        default=None,
+        help='''
+        Key of the data entry holding the service config JSON in the
+        ConfigMap of "--k8s_config_map".''')
 
-    parser.add_argument('-t', '--tls_mutual_auth', action='store_true', help='''
-        This flag added for backward compatible for ESPv1 and will be deprecated.
-        Please use the flag --ssl_backend_client_cert_path instead.
-        Enable TLS mutual authentication for HTTPS backends.
-        Default value: Not enabled. Please provide the certificate and key files
-        /etc/nginx/ssl/backend.crt and /etc/nginx/ssl/backend.key.''')
+    parser.add_argument(
+        '--k8s_api_server_url',
+        default=None,
+        help='''
+        Address of the Kubernetes API server used to read the ConfigMap
+        of "--k8s_config_map". Defaults to the in-cluster API server.''')
 
-    parser.add_argument('--ssl_protocols',
-        default=None, action='append', help='''
-        This flag added for backward compatible for ESPv1 and will be deprecated.
-        Please use the flag --ssl_minimum_protocol and  --ssl_maximum_protocol
-        instead.
-        Enable the specified SSL protocols. Please refer to
-        https://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_protocols.
-        The "ssl_protocols" argument can be repeated multiple times to specify multiple
-        SSL protocols (e.g., --ssl_protocols=TLSv1.1 --ssl_protocols=TLSv1.2).
-        ''')
+    parser.add_argument(
+        '--http_redirect_port',
+        default=None,
+        help='''
+        Also listen for plain HTTP on this port and answer every request
+        with a redirect to HTTPS. Not used if 0.''')
 
-    parser.add_argument('--enable_grpc_backend_ssl',
-        action='store_true', help='''
-        This flag added for backward compatible for ESPv1 and will be deprecated.
-        Enable SSL for gRPC backend. ESPv2 auto enables SSL if schema `grpcs` is
-        detected.''')
+    parser.add_argument(
+        '--https_redirect_port',
+        default=None,
+        help='''
+        Port the HTTPS redirects of "--http_redirect_port" point to. If not
+        set, they point to the port of "--listener_port".''')
 
-    parser.add_argument('--grpc_backend_ssl_root_certs_file',
-        default='/etc/nginx/trusted-ca-certificates.crt',
-        help='''This flag added for backward compatible for ESPv1 and will be deprecated.
-        ESPv2 uses `/etc/ssl/certs/ca-certificates.crt` by default.
-        The file path for gRPC backend SSL root certificates.''')
+    parser.add_argument(
+        '--http_redirect_response_code',
+        default=None,
+        help='''
+        Status code of the HTTPS redirects of "--http_redirect_port":
+        301, 302, 303, 307 or 308.''')
 
-    parser.add_argument('--ssl_client_cert_path', default=None, help='''
-        This flag is renamed and deprecated for clarity. 
-        Use `--ssl_backend_client_cert_path` instead.''')
+    parser.add_argument(
+        '--healthz_check_backend',
+        action='store_true',
+        help='''
+        Make the "--healthz" endpoint report unhealthy while the
+        "--backend_address" backend is failing.''')
 
-    parser.add_argument('--ssl_client_root_certs_file', default=None, help='''
-        This flag is renamed and deprecated for clarity. 
-        Use `--ssl_backend_client_root_certs_file` instead.''')
+    parser.add_argument(
+        '--healthz_backend_consecutive_failures',
+        default=None,
+        help='''
+        Number of back-to-back failed backend requests, 5xx or
+        connection errors, before "--healthz_check_backend" reports the
+        backend unhealthy.''')
 
-    # End Deprecated Flags Section
+    parser.add_argument(
+        '--healthz_backend_evaluation_window',
+        default=None,
+        help='''
+        Time window over which "--healthz_check_backend" judges
+        the backend health, such as "30s".''')
 
-    # Start internal flags section
+    parser.add_argument(
+        '--backend_health_check_path',
+        default=None,
+        help='''
+        Actively health check the backends by sending periodic HTTP GET
+        requests to this path, and stop routing to the backends that fail.''')
 
     parser.add_argument(
-        '--on_serverless',
+        '--backend_health_check_grpc',
         action='store_true',
-        default=False,
         help='''
-        When ESPv2 is started via the serverless image, this is true.
-        ''')
+        Actively health check the gRPC backends with the
+        grpc.health.v1 Health service instead of HTTP GET.''')
 
-    # End internal flags section
+    parser.add_argument(
+        '--backend_health_check_interval',
+        default=None,
+        help='''
+        Time between two active health checks of a backend, such
+        as "10s".''')
 
-    return parser
+    parser.add_argument(
+        '--backend_health_check_timeout',
+        default=None,
+        help='''
+        How long to wait for each active health check response of a
+        backend, such as "2s".''')
 
-# Check whether there are conflict flags. If so, return the error string.
-# Otherwise returns None. This function also changes some default flag value.
-def enforce_conflict_args(args):
-    if args.rollout_strategy == "managed":
-        if args.version:
-            return "Flag --version cannot be used if --rollout_strategy=managed."
-        if args.service_json_path:
-            return "Flag -R or --rollout_strategy must be fixed with --service_json_path."
-    else:
-        if not args.version and not args.service_json_path:
-            return "Flag --version is required if --rollout_strategy=fixed."
+    parser.add_argument(
+        '--backend_health_check_healthy_threshold',
+        default=None,
+        help='''
+        Number of passing active health checks before an
+        unhealthy backend receives traffic again.''')
 
-    if args.service_json_path:
-        if args.service:
-            return "Flag --service cannot be used together with --service_json_path."
-        if args.version:
-            return "Flag --version cannot be used together with --service_json_path."
+    parser.add_argument(
+        '--backend_health_check_unhealthy_threshold',
+        default=None,
+        help='''
+        Number of failing active health checks before a
+        backend stops receiving traffic.''')
 
-    if args.non_gcp:
-        if args.service_account_key is None and not args.enable_application_default_credentials:
-            return "If --non_gcp is specified, --service_account_key or --enable_application_default_credentials has to be specified, or GOOGLE_APPLICATION_CREDENTIALS has to set in os.environ."
-        if args.service_account_key and args.enable_application_default_credentials:
-            return "Only one of --service_account_key or --enable_application_default_credentials can be supplied for credentials at once."
-        if not args.tracing_project_id:
-            # for non gcp case, disable tracing if tracing project id is not provided.
-            args.disable_tracing = True
+    parser.add_argument(
+        '--backend_health_check_expected_statuses',
+        default=None,
+        help='''
+        HTTP status codes that make an active health check
+        pass, as codes or inclusive ranges separated by commas, such as
+        "200,300-399".''')
 
-    if not args.access_log and args.access_log_format:
-        return "Flag --access_log_format has to be used together with --access_log."
+    parser.add_argument(
+        '--enable_grpc_web',
+        action='store_true',
+        help='''
+        Let browser clients call the gRPC backends with the gRPC-Web
+        protocol.''')
 
-    if args.ssl_port and args.ssl_server_cert_path:
-        return "Flag --ssl_port is going to be deprecated, please use --ssl_server_cert_path only."
-    if args.tls_mutual_auth and (args.ssl_backend_client_cert_path or args.ssl_client_cert_path):
-        return "Flag --tls_mutual_auth is going to be deprecated, please use --ssl_backend_client_cert_path only."
-    if (args.ssl_backend_client_root_certs_file or args.ssl_client_root_certs_file) and args.enable_grpc_backend_ssl:
-        return "Flag --enable_grpc_backend_ssl are going to be deprecated, please use --ssl_backend_client_root_certs_file only."
-    if args.generate_self_signed_cert and args.ssl_server_cert_path:
-         return "Flag --generate_self_signed_cert and --ssl_server_cert_path cannot be used simutaneously."
+    parser.add_argument(
+        '--upgrade_types',
+        default=None,
+        help='''
+        Connection upgrades allowed on every operation, separated by commas,
+        such as "websocket".''')
 
-    port_flags = []
-    port_num = DEFAULT_LISTENER_PORT
-    if args.http_port:
-        port_flags.append("--http_port")
-        port_num = args.http_port
-    if args.http2_port:
-        port_flags.append("--http2_port")
-        port_num = args.http2_port
-    if args.listener_port:
-        port_flags.append("--listener_port")
-        port_num = args.listener_port
-    if args.ssl_port:
-        port_flags.append("--ssl_port")
-        port_num = args.ssl_port
+    parser.add_argument(
+        '--operation_upgrade_types',
+        default=None,
+        help='''
+        Connection upgrades allowed on single operations, in the format
+        of "SELECTOR=TYPE1,TYPE2;SELECTOR=TYPE3".''')
 
-    if len(port_flags) > 1:
-        return "Multiple port flags {} are not allowed, use only the --listener_port flag".format(",".join(port_flags))
-    elif port_num < 1024:
-        return "Port {} is a privileged port. " \
-               "For security purposes, the ESPv2 container cannot bind to it. " \
-               "Use any port above 1024 instead.".format(port_num)
+    parser.add_argument(
+        '--operation_upgrade_idle_timeouts',
+        default=None,
+        help='''
+        Idle timeouts of the upgraded connections of single
+        operations, such as websockets, in the format of
+        "SELECTOR=1h;SELECTOR=30m".''')
 
-    if args.ssl_protocols and (args.ssl_minimum_protocol or args.ssl_maximum_protocol):
-        return "Flag --ssl_protocols is going to be deprecated, please use --ssl_minimum_protocol and --ssl_maximum_protocol."
+    parser.add_argument(
+        '--grpc_error_status_codes',
+        default=None,
+        help='''
+        Map gRPC status codes of the transcoded errors to other HTTP
+        status codes, in the format of "NOT_FOUND=404;14=503".''')
 
-    if args.transcoding_ignore_query_parameters \
-        and args.transcoding_ignore_unknown_query_parameters:
-        return "Flag --transcoding_ignore_query_parameters cannot be used" \
-               " together with --transcoding_ignore_unknown_query_parameters."
+    parser.add_argument(
+        '--error_response_template',
+        default=None,
+        help='''
+        JSON body template of the error responses sent by ESPv2,
+        where %%RESPONSE_CODE%% and %%LOCAL_REPLY_BODY%% are replaced by the
+        status code and the error message.''')
 
-    if args.dns_resolver_addresses and args.dns:
-        return "Flag --dns_resolver_addresses cannot be used together with" \
-               " together with --dns."
+    parser.add_argument(
+        '--operation_streaming_downloads',
+        default=None,
+        help='''
+        Choose per operation whether a server streaming method
+        answering google.api.HttpBody is streamed to the client, in the
+        format of "SELECTOR=true;SELECTOR=false".''')
 
-    if args.ssl_backend_client_cert_path and args.ssl_client_cert_path:
-        return "Flag --ssl_client_cert_path is renamed to " \
-               "--ssl_backend_client_cert_path, only use the latter flag."
+    parser.add_argument(
+        '--streaming_download_buffer_limit_bytes',
+        default=None,
+        help='''
+        Bytes buffered per request by the streaming
+        downloads before flow control pauses the backend.''')
 
-    if args.ssl_backend_client_root_certs_file and args.ssl_client_root_certs_file:
-        return "Flag --ssl_client_root_certs_file is renamed to " \
-               "--ssl_backend_client_root_certs_file, only use the latter flag."
+    parser.add_argument(
+        '--transcoding_proto_descriptor_url',
+        default=None,
+        help='''
+        Load the proto descriptor of the gRPC-JSON transcoder
+        from this HTTPS or "gs://" URL instead of the service config.''')
 
-    # health_check_grpc_backend flags
-    if args.health_check_grpc_backend and not args.backend.startswith("grpc"):
-        return "Flag --health_check_grpc_backend requires the flag --backend to use grpc scheme."
-    if not args.health_check_grpc_backend and args.health_check_grpc_backend_interval:
-        return "Flag --health_check_grpc_backend_interval requires the flag --health_check_grpc_backend to be used."
-    if not args.health_check_grpc_backend and args.health_check_grpc_backend_service:
-        return "Flag --health_check_grpc_backend_service requires the flag --health_check_grpc_backend to be used."
-    if not args.health_check_grpc_backend and args.health_check_grpc_backend_no_traffic_interval:
-        return "Flag --health_check_grpc_backend_no_traffic_interval requires the flag --health_check_grpc_backend to be used."
+    parser.add_argument(
+        '--transcoding_proto_descriptor_refresh_interval',
+        default=None,
+        help='''
+        How often the descriptor of
+        "--transcoding_proto_descriptor_url" is checked for a new version,
+        such as "5m".''')
 
-    return None
+    parser.add_argument(
+        '--response_compression_types',
+        default=None,
+        help='''
+        Compression algorithms offered for responses when
+        "--enable_response_compression" is set, "gzip" and/or "br",
+        separated by commas and most preferred first.''')
 
-def gen_proxy_config(args):
-    # Copy Google default application credential file between the flag and the environment variable.
-    # We need to set both, the flag is used for calling service_control and service management,
-    # the environment variable is used for calling StackDriver for tracing.
-    if args.service_account_key is None:
-        if GOOGLE_CREDS_KEY in os.environ:
-            args.service_account_key = os.environ[GOOGLE_CREDS_KEY]
-    else:
-        if GOOGLE_CREDS_KEY not in os.environ:
-            os.environ[GOOGLE_CREDS_KEY] = args.service_account_key
+    parser.add_argument(
+        '--response_compression_content_types',
+        default=None,
+        help='''
+        Only compress responses with these content types,
+        separated by commas, such as "application/json,text/html".''')
 
-    check_conflict_result = enforce_conflict_args(args)
-    if check_conflict_result:
+    parser.add_argument(
+        '--response_compression_min_length',
+        default=None,
+        help='''
+        Responses shorter than this many bytes are sent
+        uncompressed.''')
+
+    parser.add_argument(
+        '--response_compression_gzip_level',
+        default=None,
+        help='''
+        The gzip level of the response compression, 1 for
+        fastest to 9 for smallest.''')
+
+    parser.add_argument(
+        '--response_compression_brotli_quality',
+        default=None,
+        help='''
+        The brotli quality of the response compression, 0
+        for fastest to 11 for smallest.''')
+
+    parser.add_argument(
+        '--max_request_bytes',
+        default=None,
+        help='''
+        Reject request bodies larger than this many bytes with 413
+        before they reach the backend. Unlimited if 0.''')
+
+    parser.add_argument(
+        '--operation_max_request_bytes',
+        default=None,
+        help='''
+        Request body limits of single operations, in the format
+        of "SELECTOR=1048576;SELECTOR=0" where 0 lifts the limit.''')
+
+    parser.add_argument(
+        '--allowed_client_ips',
+        default=None,
+        help='''
+        Only accept requests from these client CIDRs or IP addresses,
+        separated by commas, such as "10.0.0.0/8,192.168.1.1".''')
+
+    parser.add_argument(
+        '--denied_client_ips',
+        default=None,
+        help='''
+        Reject requests from these client CIDRs or IP addresses with
+        403, separated by commas.''')
+
+    parser.add_argument(
+        '--operation_allowed_client_ips',
+        default=None,
+        help='''
+        Client allowlists of single operations, in the format of
+        "SELECTOR=10.0.0.0/8,::1;SELECTOR=".''')
+
+    parser.add_argument(
+        '--operation_denied_client_ips',
+        default=None,
+        help='''
+        Client denylists of single operations, in the format of
+        "SELECTOR=10.0.0.0/8,::1;SELECTOR=".''')
+
+    parser.add_argument(
+        '--ext_proc_address',
+        default=None,
+        help='''
+        Send requests and responses to this external processing gRPC
+        service, such as "grpc://127.0.0.1:9000".''')
+
+    parser.add_argument(
+        '--ext_proc_processing_mode',
+        default=None,
+        help='''
+        What the external processing service receives:
+        "headers_only" or "buffered" for headers and bodies.''')
+
+    parser.add_argument(
+        '--ext_proc_timeout',
+        default=None,
+        help='''
+        How long to wait for each answer of the external processing
+        service, such as "200ms".''')
+
+    parser.add_argument(
+        '--ext_proc_failure_mode_allow',
+        action='store_true',
+        help='''
+        Keep serving requests when the external processing service
+        fails or times out, instead of rejecting them.''')
+
+    parser.add_argument(
+        '--operation_ext_proc_processing_modes',
+        default=None,
+        help='''
+        External processing modes of single operations, in
+        the format of "SELECTOR=buffered;SELECTOR=disabled".''')
+
+    parser.add_argument(
+        '--wasm_filter_uri',
+        default=None,
+        help='''
+        Load a WASM HTTP filter module from this local path or HTTPS
+        URL, such as "/etc/espv2/filter.wasm".''')
+
+    parser.add_argument(
+        '--wasm_filter_sha256',
+        default=None,
+        help='''
+        Expected SHA-256 of the module of "--wasm_filter_uri".
+        Required for HTTPS URLs.''')
+
+    parser.add_argument(
+        '--wasm_filter_config',
+        default=None,
+        help='''
+        Configuration string handed to the WASM filter module.''')
+
+    parser.add_argument(
+        '--wasm_filter_before',
+        default=None,
+        help='''
+        Name of the filter the WASM filter is placed in front of, such
+        as "envoy.filters.http.jwt_authn".''')
+
+    parser.add_argument(
+        '--operation_wasm_filter_enabled',
+        default=None,
+        help='''
+        Turn the WASM filter on or off for single operations, in the
+        format of "SELECTOR=false;SELECTOR=true".''')
+
+    parser.add_argument(
+        '--lua_filter_script',
+        default=None,
+        help='''
+        Run the Lua script at this path on every request, such as
+        "/etc/espv2/remap_headers.lua".''')
+
+    parser.add_argument(
+        '--lua_scripts',
+        default=None,
+        help='''
+        Named Lua scripts that single operations can opt into, in the
+        format of "NAME=/etc/espv2/a.lua;NAME=/etc/espv2/b.lua".''')
+
+    parser.add_argument(
+        '--operation_lua_scripts',
+        default=None,
+        help='''
+        Lua script of single operations by its name in "--lua_scripts",
+        in the format of "SELECTOR=NAME;SELECTOR=disabled".''')
+
+    parser.add_argument(
+        '--admin_read_only_paths',
+        default=None,
+        help='''
+        Expose these read only Envoy admin paths on the main listener,
+        separated by commas, such as "/stats,/ready". Requests must carry
+        the token in the file of "--admin_token_path".''')
+
+    parser.add_argument(
+        '--admin_path_prefix',
+        default=None,
+        help='''
+        Prefix the admin paths of "--admin_read_only_paths" are served
+        under on the main listener, such as "/espv2_admin".''')
+
+    parser.add_argument(
+        '--admin_token_path',
+        default=None,
+        help='''
+        File holding the token required by the admin paths of
+        "--admin_read_only_paths", such as a mounted secret.''')
+
+    parser.add_argument(
+        '--admin_token_header',
+        default=None,
+        help='''
+        Request header carrying the token of the admin paths of
+        "--admin_read_only_paths".''')
+
+    parser.add_argument(
+        '--admin_loopback_only',
+        action='store_true',
+        help='''
+        Bind the Envoy admin interface to the loopback address so it
+        cannot be reached from outside the host.''')
+
+    parser.add_argument(
+        '--admin_socket_path',
+        default=None,
+        help='''
+        Serve the Envoy admin interface on this unix domain socket
+        instead of a TCP port.''')
+
+    parser.add_argument(
+        '--operation_cors_allow_origin',
+        default=None,
+        help='''
+        CORS origins allowed by single operations, in the format of
+        "SELECTOR=ORIGIN1,ORIGIN2;SELECTOR=*".''')
+
+    parser.add_argument(
+        '--operation_cors_allow_credentials',
+        default=None,
+        help='''
+        Override "--cors_allow_credentials" for single
+        operations, in the format of "SELECTOR=true;SELECTOR=false".''')
+
+    parser.add_argument(
+        '--operation_cors_expose_headers',
+        default=None,
+        help='''
+        Override "--cors_expose_headers" for single operations, in
+        the format of "SELECTOR=Header-A,Header-B".''')
+
+    parser.add_argument(
+        '--cors_allow_unmatched_preflight',
+        action='store_true',
+        help='''
+        With "allow_cors" in the service config endpoints, also pass
+        the preflight requests of paths missing from the service config to
+        the backend instead of answering 404.''')
+
+    parser.add_argument(
+        '--oidc_discovery_timeout',
+        default=None,
+        help='''
+        Timeout of each attempt to fetch an OpenID Connect discovery
+        document, such as "5s".''')
+
+    parser.add_argument(
+        '--oidc_discovery_num_retries',
+        default=None,
+        help='''
+        How many times a failed OpenID Connect discovery fetch is
+        retried.''')
+
+    parser.add_argument(
+        '--oidc_discovery_retry_backoff_base_interval',
+        default=None,
+        help='''
+        First backoff between the retries of an
+        OpenID Connect discovery fetch, doubled on each retry.''')
+
+    parser.add_argument(
+        '--oidc_discovery_retry_backoff_max_interval',
+        default=None,
+        help='''
+        Longest backoff between the retries of an
+        OpenID Connect discovery fetch.''')
+
+    parser.add_argument(
+        '--oidc_discovery_max_redirects',
+        default=None,
+        help='''
+        How many redirects are followed when fetching an OpenID
+        Connect discovery document or a JWKS.''')
+
+    parser.add_argument(
+        '--disable_http_fetch_cache',
+        action='store_true',
+        help='''
+        Always refetch remote documents, such as the service configs
+        and the OpenID Connect discovery documents, without caching them.''')
+
+    parser.add_argument(
+        '--backend_cluster_connect_timeout',
+        default=None,
+        help='''
+        Timeout to open a connection to a backend, such as
+        "5s".''')
+
+    parser.add_argument(
+        '--backend_tcp_keepalive_time',
+        default=None,
+        help='''
+        Idle time before TCP keepalive probes are sent on the backend
+        connections, in whole seconds such as "60s".''')
+
+    parser.add_argument(
+        '--backend_tcp_keepalive_interval',
+        default=None,
+        help='''
+        Time between two TCP keepalive probes on the backend
+        connections, in whole seconds such as "10s".''')
+
+    parser.add_argument(
+        '--backend_tcp_keepalive_probes',
+        default=None,
+        help='''
+        Unanswered TCP keepalive probes before a backend connection
+        is dropped.''')
+
+    parser.add_argument(
+        '--backend_happy_eyeballs',
+        action='store_true',
+        help='''
+        Resolve the backends to both IPv4 and IPv6 and race the
+        connections to them.''')
+
+    parser.add_argument(
+        '--max_request_headers_kb',
+        default=None,
+        help='''
+        Largest total size of the request headers in KiB, at most
+        8192.''')
+
+    parser.add_argument(
+        '--max_request_headers_count',
+        default=None,
+        help='''
+        Most request headers accepted on a single request.''')
+
+    parser.add_argument(
+        '--headers_with_underscores_action',
+        default=None,
+        help='''
+        What to do with request headers that have underscores in
+        their names: "allow", "reject_request" or "drop_header".''')
+
+    parser.add_argument(
+        '--header_key_format',
+        default=None,
+        help='''
+        Casing of the HTTP/1 header names written to clients and
+        backends: "proper_case" or "preserve_case".''')
+
+    parser.add_argument(
+        '--strict_route_allowlist',
+        action='store_true',
+        help='''
+        Only route requests whose paths belong to an operation of the
+        service config.''')
+
+    parser.add_argument(
+        '--passthrough_path_prefixes',
+        default=None,
+        help='''
+        Path prefixes, separated by commas, proxied to the backend
+        without ESPv2 checks even though no operation matches them, such as
+        "/static/,/assets/".''')
+
+    parser.add_argument(
+        '--default_backend_address',
+        default=None,
+        help='''
+        Send requests that match no operation to this backend, such as
+        "https://legacy.example.com", instead of answering 404.''')
+
+    parser.add_argument(
+        '--tenant_mapping_file',
+        default=None,
+        help='''
+        JSON file mapping API consumers to tenants that have their own
+        backends.''')
+
+    parser.add_argument(
+        '--tenant_mapping_metadata_attribute',
+        default=None,
+        help='''
+        Read the tenant mapping JSON of
+        "--tenant_mapping_file" from this instance metadata attribute
+        instead.''')
+
+    parser.add_argument(
+        '--enable_operation_stats',
+        action='store_true',
+        help='''
+        Emit Envoy statistics for every operation, named after the
+        operation selector.''')
+
+    parser.add_argument(
+        '--enable_grpc_reflection',
+        action='store_true',
+        help='''
+        Serve gRPC server reflection on "--ads_named_pipe", so tools
+        such as grpcurl can explore the Config Manager services.''')
+
+    parser.add_argument(
+        '--grpc_health_port',
+        default=None,
+        help='''
+        Port for Config Manager to serve the grpc.health.v1 service on,
+        such as for Kubernetes gRPC probes. Not served if 0.''')
+
+    parser.add_argument(
+        '--startup_timeout',
+        default=None,
+        help='''
+        Exit Config Manager with an error when no config is generated
+        within this time, such as "2m".''')
+
+    parser.add_argument(
+        '--require_backend_dns_resolution',
+        action='store_true',
+        help='''
+        Exit Config Manager at startup when a backend hostname does
+        not resolve.''')
+
+    parser.add_argument(
+        '--snapshot_admin_port',
+        default=None,
+        help='''
+        Loopback port for Config Manager to export, import and list the
+        served xDS snapshot on. Not served if 0.''')
+
+    parser.add_argument(
+        '--snapshot_replay_file',
+        default=None,
+        help='''
+        Serve the xDS snapshot from a tarball exported by
+        "/snapshot/export" instead of generating one.''')
+
+    parser.add_argument(
+        '--backend_hedge_get_requests',
+        action='store_true',
+        help='''
+        Send a second attempt of GET requests still unanswered after
+        "--backend_per_try_timeout", and use whichever answers first.''')
+
+    parser.add_argument(
+        '--backend_retry_budget_percent',
+        default=None,
+        help='''
+        Share of the active requests to a backend that may be
+        retries, above 0 and up to 100.''')
+
+    parser.add_argument(
+        '--backend_retry_budget_min_concurrency',
+        default=None,
+        help='''
+        Concurrent retries to a backend always allowed,
+        whatever "--backend_retry_budget_percent" says.''')
+
+    parser.add_argument(
+        '--operation_hedge_delays',
+        default=None,
+        help='''
+        Hedge the requests of single operations after the given delay,
+        in the format of "SELECTOR=50ms;SELECTOR=200ms".''')
+
+    parser.add_argument(
+        '--maintenance_mode',
+        action='store_true',
+        help='''
+        Answer every request with "--maintenance_response" without
+        calling the backends.''')
+
+    parser.add_argument(
+        '--maintenance_operations',
+        default=None,
+        help='''
+        Selectors of the operations answered with
+        "--maintenance_response", separated by commas.''')
+
+    parser.add_argument(
+        '--maintenance_response',
+        default=None,
+        help='''
+        JSON response served in maintenance, with "status", "body" and
+        "headers", such as '{"status": 503, "body": "Down for maintenance"}'.''')
+
+    parser.add_argument(
+        '--static_paths',
+        default=None,
+        help='''
+        JSON object of paths whose GET requests get a fixed inline
+        response, without calling the backend, such as
+        '{"/favicon.ico": {"status": 204}}'.''')
+
+    parser.add_argument(
+        '--redirect_rules',
+        default=None,
+        help='''
+        JSON array of redirect rules matching an exact "path" or a
+        "pathPrefix", answered without calling the backend.''')
+
+    parser.add_argument(
+        '--consumer_backends',
+        default=None,
+        help='''
+        JSON array of dedicated backends for named API consumers, such as
+        '[{"name": "bigcorp", "backendAddress": "https://bigcorp.example.com",
+        "consumerNumbers": ["123456"]}]'.''')
+
+    parser.add_argument(
+        '--version_backends',
+        default=None,
+        help='''
+        JSON object routing requests to backends by the value of an API
+        version header, such as '{"header": "Accept-Version", "versions":
+        [{"version": "v2", "backendAddress": "https://v2.example.com"}]}'.''')
+
+    parser.add_argument(
+        '--grpc_metadata',
+        default=None,
+        help='''
+        Extra gRPC metadata added to the requests of the gRPC methods,
+        in the format of "SELECTOR=KEY:VALUE;SELECTOR=KEY:VALUE".''')
+
+    parser.add_argument(
+        '--listener_connection_rate_limit',
+        default=None,
+        help='''
+        Most new downstream connections accepted per second by the
+        ingress listener. Unlimited if 0.''')
+
+    parser.add_argument(
+        '--max_downstream_connections',
+        default=None,
+        help='''
+        Most open downstream connections across all the listeners.''')
+
+    parser.add_argument(
+        '--overload_max_heap_size_bytes',
+        default=None,
+        help='''
+        Heap size the Envoy overload manager measures the memory
+        usage against.''')
+
+    parser.add_argument(
+        '--overload_shrink_heap_threshold',
+        default=None,
+        help='''
+        Share of "--overload_max_heap_size_bytes" in use at which
+        Envoy gives free memory back to the system, such as 0.9.''')
+
+    parser.add_argument(
+        '--overload_stop_accepting_requests_threshold',
+        default=None,
+        help='''
+        Share of "--overload_max_heap_size_bytes" in
+        use at which Envoy answers new requests with 503, such as 0.95.''')
+
+    parser.add_argument(
+        '--enable_adaptive_concurrency',
+        action='store_true',
+        help='''
+        Limit the concurrent requests by the observed backend latency
+        and answer the requests over the limit with 503.''')
+
+    parser.add_argument(
+        '--adaptive_concurrency_sample_percentile',
+        default=None,
+        help='''
+        Latency percentile of the samples compared to the
+        minimum latency, such as 50.''')
+
+    parser.add_argument(
+        '--adaptive_concurrency_update_interval',
+        default=None,
+        help='''
+        How often the concurrency limit is recomputed, such
+        as "100ms".''')
+
+    parser.add_argument(
+        '--adaptive_concurrency_min_rtt_interval',
+        default=None,
+        help='''
+        How often the minimum backend latency is measured
+        again, such as "60s".''')
+
+    parser.add_argument(
+        '--adaptive_concurrency_min_rtt_request_count',
+        default=None,
+        help='''
+        Requests sampled to measure the minimum
+        backend latency.''')
+
+    parser.add_argument(
+        '--adaptive_concurrency_buffer_percent',
+        default=None,
+        help='''
+        Percent added to the minimum latency to absorb normal
+        latency noise.''')
+
+    parser.add_argument(
+        '--adaptive_concurrency_max_limit',
+        default=None,
+        help='''
+        Highest concurrency limit allowed.''')
+
+    parser.add_argument(
+        '--backend_slow_start_window',
+        default=None,
+        help='''
+        Ramp the traffic to newly added endpoints of the remote
+        backends up over this window, such as "30s".''')
+
+    parser.add_argument(
+        '--backend_slow_start_aggression',
+        default=None,
+        help='''
+        Shape of the "--backend_slow_start_window" ramp, where
+        larger values send more traffic early.''')
+
+    parser.add_argument(
+        '--backend_address_overrides',
+        default=None,
+        help='''
+        Replace x-google-backend addresses of the service config, in
+        the format of "SELECTOR=ADDRESS;SELECTOR=ADDRESS", to reuse one
+        service config across environments.''')
+
+    parser.add_argument(
+        '--service_management_service_account_key',
+        default=None,
+        help='''
+        Service account key JSON file used only to fetch
+        the service config from Service Management, overriding
+        "--service_account_key".''')
+
+    parser.add_argument(
+        '--service_control_service_account_key',
+        default=None,
+        help='''
+        Service account key JSON file used only to call
+        Service Control, overriding "--service_account_key".''')
+
+    parser.add_argument(
+        '--backend_auth_service_account_key',
+        default=None,
+        help='''
+        Service account key JSON file used only to mint the
+        backend auth tokens, overriding "--service_account_key".''')
+
+    parser.add_argument(
+        '--metadata_stub_file',
+        default=None,
+        help='''
+        Serve fake metadata server values from this JSON file, keyed by
+        the path under "/computeMetadata/v1/", for running outside Google
+        Cloud.''')
+
+    parser.add_argument(
+        '--metadata_stub_port',
+        default=None,
+        help='''
+        Loopback port for Config Manager to serve the fake metadata
+        server of "--metadata_stub_file" on.''')
+
+    parser.add_argument(
+        '--enable_token_service',
+        action='store_true',
+        help='''
+        Share the access and identity tokens of ESPv2 with the other
+        containers of the pod on 127.0.0.1 at "--token_agent_port".''')
+
+    parser.add_argument(
+        '--deployment_labels',
+        default=None,
+        help='''
+        Labels of this deployment attached to logs and traces, in the
+        format of "KEY=VALUE;KEY=VALUE".''')
+
+    parser.add_argument(
+        '--operation_quota_metric_costs',
+        default=None,
+        help='''
+        Override the quota metric costs of single operations without
+        republishing the service config, in the format of
+        "SELECTOR=METRIC:COST,METRIC:COST;SELECTOR=METRIC:COST".''')
+
+    parser.add_argument(
+        '--backend_http2_keepalive_interval',
+        default=None,
+        help='''
+        Send HTTP/2 PINGs on idle gRPC and HTTP/2 backend
+        connections this often, such as "30s".''')
+
+    parser.add_argument(
+        '--backend_http2_keepalive_timeout',
+        default=None,
+        help='''
+        Close a gRPC or HTTP/2 backend connection when its PING is
+        not answered within this time.''')
+
+    parser.add_argument(
+        '--backend_http2_keepalive_interval_jitter',
+        default=None,
+        help='''
+        Random jitter added to
+        "--backend_http2_keepalive_interval", as a percent from 0 to 100.''')
+
+    parser.add_argument(
+        '--backend_protocol_selection',
+        default=None,
+        help='''
+        How to pick the protocol for backends speaking both HTTP/1.1
+        and h2c on one port, in the format of "HOST:PORT=auto;HOST:PORT=downstream".''')
+
+    parser.add_argument(
+        '--health_port',
+        default=None,
+        help='''
+        Also serve the "--healthz" endpoint on this plain HTTP port, with
+        no API key, JWT or Service Control checks. Not used if 0.''')
+
+    parser.add_argument(
+        '--tcp_proxy_routes',
+        default=None,
+        help='''
+        Proxy non-HTTP traffic with a TCP listener per port, in the
+        format of "PORT[/SERVER_NAME]=HOST:PORT;...".''')
+
+    parser.add_argument(
+        '--enable_request_validation',
+        action='store_true',
+        help='''
+        Reject requests that do not match the request message of their
+        operation in the service config.''')
+
+    parser.add_argument(
+        '--enable_security_headers',
+        action='store_true',
+        help='''
+        Add Strict-Transport-Security, X-Content-Type-Options,
+        X-Frame-Options, Referrer-Policy and Content-Security-Policy to
+        responses that lack them.''')
+
+    parser.add_argument(
+        '--security_headers',
+        default=None,
+        help='''
+        JSON object overriding the values added by
+        "--enable_security_headers", where an empty value drops the header.''')
+
+    parser.add_argument(
+        '--operation_security_headers_enabled',
+        default=None,
+        help='''
+        Turn the security headers on or off for single
+        operations, in the format of "SELECTOR=false;SELECTOR=true".''')
+
+    parser.add_argument(
+        '--api_key_bearer_token_prefix',
+        default=None,
+        help='''
+        Also accept API keys sent as bearer tokens that start with this
+        prefix, such as "AIza". Not allowed with JWT providers reading the
+        Authorization header.''')
+
+    parser.add_argument(
+        '--api_key_from_basic_auth_username',
+        action='store_true',
+        help='''
+        Also accept API keys sent as the username of Basic
+        authentication.''')
+
+    parser.add_argument(
+        '--enable_deprecation_headers',
+        action='store_true',
+        help='''
+        Add "Deprecation: true" to the responses of operations marked
+        deprecated in the service config documentation.''')
+
+    parser.add_argument(
+        '--operation_deprecations',
+        default=None,
+        help='''
+        Mark single operations deprecated, in the format of
+        "SELECTOR=2025-06-30;SELECTOR=true;SELECTOR=false".''')
+
+    parser.add_argument(
+        '--access_log_consumer_sample_rates',
+        default=None,
+        help='''
+        Access log sampling percent of single API consumers, in the
+        format of "CONSUMER_NUMBER=PERCENT;CONSUMER_NUMBER=PERCENT".''')
+
+    parser.add_argument(
+        '--propagate_request_timeout',
+        action='store_true',
+        help='''
+        Tell the backends the remaining request deadline in
+        x-envoy-expected-rq-timeout-ms, and grpc-timeout for gRPC.''')
+
+    parser.add_argument(
+        '--quota_override_consumers',
+        default=None,
+        help='''
+        Project numbers of API consumers, separated by commas, whose
+        quota overrides are enforced locally.''')
+
+    parser.add_argument(
+        '--service_consumer_management_url',
+        default=None,
+        help='''
+        Address of the Service Consumer Management API used by
+        "--quota_override_consumers".''')
+
+    parser.add_argument(
+        '--backend_failover_addresses',
+        default=None,
+        help='''
+        Failover backends of x-google-backend addresses, in the format
+        of "PRIMARY=FAILOVER;PRIMARY=FAILOVER".''')
+
+    parser.add_argument(
+        '--backend_regional_addresses',
+        default=None,
+        help='''
+        Nearest and farther regional backends of x-google-backend
+        addresses, in the format of "ADDRESS=NEAR,FAR;ADDRESS=NEAR,FAR".''')
+
+    parser.add_argument(
+        '--downstream_idle_timeout',
+        default=None,
+        help='''
+        Close downstream connections without active requests after
+        this time, such as "5m".''')
+
+    parser.add_argument(
+        '--downstream_max_connection_duration',
+        default=None,
+        help='''
+        Close downstream connections after they have been open
+        this long, such as "1h".''')
+
+    parser.add_argument(
+        '--downstream_drain_timeout',
+        default=None,
+        help='''
+        Time between the GOAWAY of a draining HTTP/2 connection and its
+        close, letting in-flight requests finish.''')
+
+    parser.add_argument(
+        '--disable_transcoding',
+        action='store_true',
+        help='''
+        Turn off the gRPC-JSON transcoder.''')
+
+    parser.add_argument(
+        '--grpc_reflection',
+        default=None,
+        help='''
+        Handle gRPC server reflection calls: "allow" passes them to the
+        backend without Service Control reporting, "deny" rejects them.''')
+
+    parser.add_argument(
+        '--grpc_reflection_jwt_providers',
+        default=None,
+        help='''
+        Authentication providers, separated by commas, one of whose
+        JWTs the reflection calls allowed by "--grpc_reflection" need.''')
+
+    parser.add_argument(
+        '--jwks_resolve_at_startup',
+        action='store_true',
+        help='''
+        Resolve the JWKS and issuer hostnames when the config is
+        generated, so Envoy fetches JWKS without DNS.''')
+
+    parser.add_argument(
+        '--jwks_resolve_ttl',
+        default=None,
+        help='''
+        How often the addresses of "--jwks_resolve_at_startup" are
+        resolved again, such as "1h".''')
+
+    parser.add_argument(
+        '--readiness_max_config_age',
+        default=None,
+        help='''
+        Fail the readiness endpoint when the served service config was
+        not confirmed as the latest rollout for this long.''')
+
+    parser.add_argument(
+        '--readiness_max_rollout_fetch_failures',
+        default=None,
+        help='''
+        Fail the readiness endpoint after this many failed
+        rollout fetches in a row.''')
+
+    parser.add_argument(
+        '--http_filter_order',
+        default=None,
+        help='''
+        Move or disable generated HTTP filters, in the format of
+        "FILTER=before:FILTER;FILTER=disabled".''')
+
+    parser.add_argument(
+        '--backend_rule_inheritance',
+        action='store_true',
+        help='''
+        Let operations inherit the x-google-backend settings of backend
+        rules with wildcard selectors.''')
+
+    parser.add_argument(
+        '--operation_request_timeouts',
+        default=None,
+        help='''
+        Response timeouts of single operations, in the format of
+        "SELECTOR=60s;SELECTOR=10s".''')
+
+    parser.add_argument(
+        '--operation_per_try_timeouts',
+        default=None,
+        help='''
+        Override "--backend_per_try_timeout" for single operations,
+        in the format of "SELECTOR=25s;SELECTOR=3s".''')
+
+    parser.add_argument(
+        '--service_control_compress_reports',
+        action='store_true',
+        help='''
+        Gzip the bodies of the Service Control Report requests.''')
+
+    parser.add_argument(
+        '--allowed_source_ranges',
+        default=None,
+        help='''
+        Only accept connections from these peer CIDRs or IP addresses
+        on the ingress listener, separated by commas.''')
+
+    parser.add_argument(
+        '--backend_jwt_audience_template',
+        default=None,
+        help='''
+        Template of the backend auth audience for backend rules without
+        "jwt_audience", such as "{scheme}://{host}{path}".''')
+
+    parser.add_argument(
+        '--operation_jwt_audience_templates',
+        default=None,
+        help='''
+        Override "--backend_jwt_audience_template" for single
+        operations, in the format of "SELECTOR=TEMPLATE;SELECTOR=TEMPLATE".''')
+
+    parser.add_argument(
+        '--suppress_upstream_service_time',
+        action='store_true',
+        help='''
+        Drop the x-envoy-upstream-service-time response header.''')
+
+    parser.add_argument(
+        '--operation_response_headers_to_remove',
+        default=None,
+        help='''
+        Response headers dropped for single operations, in the
+        format of "SELECTOR=HEADER1,HEADER2;SELECTOR=".''')
+
+    parser.add_argument(
+        '--server_header',
+        default=None,
+        help='''
+        Value of the Server response header.''')
+
+    parser.add_argument(
+        '--server_header_transformation',
+        default=None,
+        help='''
+        How the Server response header is handled: "overwrite",
+        "append_if_absent", "pass_through" or "suppress".''')
+
+    parser.add_argument(
+        '--via_header',
+        default=None,
+        help='''
+        Add a Via header with this value to requests and responses.''')
+
+    parser.add_argument(
+        '--merge_duplicate_routes',
+        action='store_true',
+        help='''
+        Merge neighbouring routes that only differ by operation and path,
+        shrinking the route table of large service configs.''')
+
+    parser.add_argument(
+        '--tap_output_path_prefix',
+        default=None,
+        help='''
+        Write the requests and responses captured by
+        "--operation_tap_enabled" to files starting with this path.''')
+
+    parser.add_argument(
+        '--operation_tap_enabled',
+        default=None,
+        help='''
+        Capture the traffic of single operations, in the format of
+        "SELECTOR=true;SELECTOR=false".''')
+
+    parser.add_argument(
+        '--tap_max_buffered_bytes',
+        default=None,
+        help='''
+        Most bytes captured of each request and response body.''')
+
+    parser.add_argument(
+        '--tap_admin_config_id',
+        default=None,
+        help='''
+        Let the Envoy admin "/tap" endpoint stream captures with this
+        config id.''')
+
+    parser.add_argument(
+        '--operation_fault_abort',
+        default=None,
+        help='''
+        Fail a share of the requests of single operations for chaos
+        testing, in the format of "SELECTOR=503@10;SELECTOR=429".''')
+
+    parser.add_argument(
+        '--operation_fault_delay',
+        default=None,
+        help='''
+        Delay a share of the requests of single operations for chaos
+        testing, in the format of "SELECTOR=2s@50;SELECTOR=500ms".''')
+
+    parser.add_argument(
+        '--fault_trigger_header',
+        default=None,
+        help='''
+        Only inject the faults into requests with this header, "NAME"
+        for any value or "NAME=VALUE".''')
+
+    parser.add_argument(
+        '--enable_response_cache',
+        action='store_true',
+        help='''
+        Cache GET responses in Envoy memory as their Cache-Control
+        headers allow.''')
+
+    parser.add_argument(
+        '--operation_response_cache_ttl',
+        default=None,
+        help='''
+        Cache lifetimes of the responses of single GET operations, in
+        the format of "SELECTOR=60s;SELECTOR=0s". Backend "private" and
+        "no-store" responses are still not cached, and operations needing a
+        JWT or an API key cannot have a lifetime.''')
+
+    parser.add_argument(
+        '--response_cache_max_body_bytes',
+        default=None,
+        help='''
+        Largest response body kept by "--enable_response_cache".
+        Unlimited if 0.''')
+
+    parser.add_argument(
+        '--consumer_max_concurrent_requests',
+        default=None,
+        help='''
+        Most in-flight requests of a single API key. Requests over it
+        get 429.''')
+
+    parser.add_argument(
+        '--operation_consumer_max_concurrent_requests',
+        default=None,
+        help='''
+        Override "--consumer_max_concurrent_requests" for
+        single operations, in the format of "SELECTOR=2;SELECTOR=0".''')
+
+    parser.add_argument(
+        '--client_ip_rate_limit_per_second',
+        default=None,
+        help='''
+        Requests per second allowed from each client IP.''')
+
+    parser.add_argument(
+        '--client_ip_rate_limit_burst',
+        default=None,
+        help='''
+        Requests above "--client_ip_rate_limit_per_second" a client IP
+        may burst.''')
+
+    parser.add_argument(
+        '--deployment_mode',
+        default=None,
+        help='''
+        Pick the flag defaults for the deployment: "edge", "sidecar" or
+        "gateway".''')
+
+    # Start Deprecated Flags Section
+
+    parser.add_argument(
+        '--enable_backend_routing',
+        action='store_true',
+        default=False,
+        help='''
+        ===
+        DEPRECATED: This flag will automatically be enabled if needed, so it
+        does NOT need to be set manually.
+        ===
+        Enable ESPv2 to route requests according to the
+        "x-google-backend" or "backend" configuration
+        ''')
+    parser.add_argument(
+        '--backend_protocol',
+        default=None,
+        help='''
+        ===
+        DEPRECATED: This flag will automatically be set based on the scheme
+        specified in the --backend flag. Overrides are no longer needed.
+        ===
+        Backend Protocol. Overrides the protocol in --backend.
+        Choices: [http1|http2|grpc].
+        Default value: http1.''',
+        choices=['http1', 'http2', 'grpc'])
+
+    parser.add_argument('--http_port', default=None, type=int, help='''
+        This flag is exactly same as --listener_port. It is added for
+        backward compatible for ESPv1 and will be deprecated.
+        Please use the flag --listener_port.''')
+
+    parser.add_argument('--http2_port', default=None, type=int, help='''
+        This flag is exactly same as --listener_port. It is added for
+        backward compatible for ESPv1 and will be deprecated.
+        Please use the flag --listener_port.''')
+
+    parser.add_argument('--ssl_port', default=None, type=int, help='''
+        This flag added for backward compatible for ESPv1 and will be deprecated.
+        Please use the flags --listener_port and --ssl_server_cert_path instead. 
+        When configured, ESPv2 accepts HTTP/1.x and HTTP/2 secure connections on this port,
+        Requires the certificate and key files /etc/nginx/ssl/nginx.crt and
+        /etc/nginx/ssl/nginx.key''')
+
+    parser.add_argument('--dns',  help='''
+        This flag is exactly same as --dns_resolver_addresses. This flag is added
+        for backward compatible for ESPv1 and will be deprecated.
+        Please use the flag --dns_resolver_addresses instead.''')
+
+    parser.add_argument('-t', '--tls_mutual_auth', action='store_true', help='''
+        This flag added for backward compatible for ESPv1 and will be deprecated.
+        Please use the flag --ssl_backend_client_cert_path instead.
+        Enable TLS mutual authentication for HTTPS backends.
+        Default value: Not enabled. Please provide the certificate and key files
+        /etc/nginx/ssl/backend.crt and /etc/nginx/ssl/backend.key.''')
+
+    parser.add_argument('--ssl_protocols',
+        default=None, action='append', help='''
+        This flag added for backward compatible for ESPv1 and will be deprecated.
+        Please use the flag --ssl_minimum_protocol and  --ssl_maximum_protocol
+        instead.
+        Enable the specified SSL protocols. Please refer to
+        https://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_protocols.
+        The "ssl_protocols" argument can be repeated multiple times to specify multiple
+        SSL protocols (e.g., --ssl_protocols=TLSv1.1 --ssl_protocols=TLSv1.2).
+        ''')
+
+    parser.add_argument('--enable_grpc_backend_ssl',
+        action='store_true', help='''
+        This flag added for backward compatible for ESPv1 and will be deprecated.
+        Enable SSL for gRPC backend. ESPv2 auto enables SSL if schema `grpcs` is
+        detected.''')
+
+    parser.add_argument('--grpc_backend_ssl_root_certs_file',
+        default='/etc/nginx/trusted-ca-certificates.crt',
+        help='''This flag added for backward compatible for ESPv1 and will be deprecated.
+        ESPv2 uses `/etc/ssl/certs/ca-certificates.crt` by default.
+        The file path for gRPC backend SSL root certificates.''')
+
+    parser.add_argument('--ssl_client_cert_path', default=None, help='''
+        This flag is renamed and deprecated for clarity. 
+        Use `--ssl_backend_client_cert_path` instead.''')
+
+    parser.add_argument('--ssl_client_root_certs_file', default=None, help='''
+        This flag is renamed and deprecated for clarity. 
+        Use `--ssl_backend_client_root_certs_file` instead.''')
+
+    # End Deprecated Flags Section
+
+    # Start internal flags section
+
+    parser.add_argument(
+        '--on_serverless',
+        action='store_true',
+        default=False,
+        help='''
+        When ESPv2 is started via the serverless image, this is true.
+        ''')
+
+    # End internal flags section
+
+    return parser
+
+# Check whether there are conflict flags. If so, return the error string.
+# Otherwise returns None. This function also changes some default flag value.
+def enforce_conflict_args(args):
+    if args.rollout_strategy == "managed":
+        if args.version:
+            return "Flag --version cannot be used if --rollout_strategy=managed."
+        if args.service_json_path:
+            return "Flag -R or --rollout_strategy must be fixed with --service_json_path."
+    else:
+        if not args.version and not args.service_json_path:
+            return "Flag --version is required if --rollout_strategy=fixed."
+
+    if args.service_json_path:
+        if args.service:
+            return "Flag --service cannot be used together with --service_json_path."
+        if args.version:
+            return "Flag --version cannot be used together with --service_json_path."
+
+    if args.non_gcp:
+        if args.service_account_key is None and not args.enable_application_default_credentials:
+            return "If --non_gcp is specified, --service_account_key or --enable_application_default_credentials has to be specified, or GOOGLE_APPLICATION_CREDENTIALS has to set in os.environ."
+        if args.service_account_key and args.enable_application_default_credentials:
+            return "Only one of --service_account_key or --enable_application_default_credentials can be supplied for credentials at once."
+        if not args.tracing_project_id:
+            # for non gcp case, disable tracing if tracing project id is not provided.
+            args.disable_tracing = True
+
+    if not args.access_log and args.access_log_format:
+        return "Flag --access_log_format has to be used together with --access_log."
+
+    if args.ssl_port and args.ssl_server_cert_path:
+        return "Flag --ssl_port is going to be deprecated, please use --ssl_server_cert_path only."
+    if args.tls_mutual_auth and (args.ssl_backend_client_cert_path or args.ssl_client_cert_path):
+        return "Flag --tls_mutual_auth is going to be deprecated, please use --ssl_backend_client_cert_path only."
+    if (args.ssl_backend_client_root_certs_file or args.ssl_client_root_certs_file) and args.enable_grpc_backend_ssl:
+        return "Flag --enable_grpc_backend_ssl are going to be deprecated, please use --ssl_backend_client_root_certs_file only."
+    if args.generate_self_signed_cert and args.ssl_server_cert_path:
+         return "Flag --generate_self_signed_cert and --ssl_server_cert_path cannot be used simutaneously."
+
+    port_flags = []
+    port_num = DEFAULT_LISTENER_PORT
+    if args.http_port:
+        port_flags.append("--http_port")
+        port_num = args.http_port
+    if args.http2_port:
+        port_flags.append("--http2_port")
+        port_num = args.http2_port
+    if args.listener_port:
+        port_flags.append("--listener_port")
+        port_num = args.listener_port
+    if args.ssl_port:
+        port_flags.append("--ssl_port")
+        port_num = args.ssl_port
+
+    if len(port_flags) > 1:
+        return "Multiple port flags {} are not allowed, use only the --listener_port flag".format(",".join(port_flags))
+    elif port_num < 1024:
+        return "Port {} is a privileged port. " \
+               "For security purposes, the ESPv2 container cannot bind to it. " \
+               "Use any port above 1024 instead.".format(port_num)
+
+    if args.ssl_protocols and (args.ssl_minimum_protocol or args.ssl_maximum_protocol):
+        return "Flag --ssl_protocols is going to be deprecated, please use --ssl_minimum_protocol and --ssl_maximum_protocol."
+
+    if args.transcoding_ignore_query_parameters \
+        and args.transcoding_ignore_unknown_query_parameters:
+        return "Flag --transcoding_ignore_query_parameters cannot be used" \
+               " together with --transcoding_ignore_unknown_query_parameters."
+
+    if args.dns_resolver_addresses and args.dns:
+        return "Flag --dns_resolver_addresses cannot be used together with" \
+               " together with --dns."
+
+    if args.ssl_backend_client_cert_path and args.ssl_client_cert_path:
+        return "Flag --ssl_client_cert_path is renamed to " \
+               "--ssl_backend_client_cert_path, only use the latter flag."
+
+    if args.ssl_backend_client_root_certs_file and args.ssl_client_root_certs_file:
+        return "Flag --ssl_client_root_certs_file is renamed to " \
+               "--ssl_backend_client_root_certs_file, only use the latter flag."
+
+    # health_check_grpc_backend flags
+    if args.health_check_grpc_backend and not args.backend.startswith("grpc"):
+        return "Flag --health_check_grpc_backend requires the flag --backend to use grpc scheme."
+    if not args.health_check_grpc_backend and args.health_check_grpc_backend_interval:
+        return "Flag --health_check_grpc_backend_interval requires the flag --health_check_grpc_backend to be used."
+    if not args.health_check_grpc_backend and args.health_check_grpc_backend_service:
+        return "Flag --health_check_grpc_backend_service requires the flag --health_check_grpc_backend to be used."
+    if not args.health_check_grpc_backend and args.health_check_grpc_backend_no_traffic_interval:
+        return "Flag --health_check_grpc_backend_no_traffic_interval requires the flag --health_check_grpc_backend to be used."
+
+    return None
+
+def gen_proxy_config(args):
+    # Copy Google default application credential file between the flag and the environment variable.
+    # We need to set both, the flag is used for calling service_control and service management,
+    # the environment variable is used for calling StackDriver for tracing.
+    if args.service_account_key is None:
+        if GOOGLE_CREDS_KEY in os.environ:
+            args.service_account_key = os.environ[GOOGLE_CREDS_KEY]
+    else:
+        if GOOGLE_CREDS_KEY not in os.environ:
+            os.environ[GOOGLE_CREDS_KEY] = args.service_account_key
+
+    check_conflict_result = enforce_conflict_args(args)
+    if check_conflict_result:
         logging.error(check_conflict_result)
         sys.exit(1)
 
@@ -1549,6 +2866,454 @@ def gen_proxy_config(args):
     if args.ads_named_pipe:
         proxy_conf.extend(["--ads_named_pipe", args.ads_named_pipe])
 
+    if args.readiness_port:
+        proxy_conf.extend(["--readiness_port", args.readiness_port])
+    if args.readiness_check_backend:
+        proxy_conf.append("--readiness_check_backend")
+    if args.readiness_check_timeout:
+        proxy_conf.extend(["--readiness_check_timeout", args.readiness_check_timeout])
+
+    if args.k8s_config_map:
+        proxy_conf.extend(["--k8s_config_map", args.k8s_config_map])
+    if args.k8s_config_map_key:
+        proxy_conf.extend(["--k8s_config_map_key", args.k8s_config_map_key])
+    if args.k8s_api_server_url:
+        proxy_conf.extend(["--k8s_api_server_url", args.k8s_api_server_url])
+
+    if args.http_redirect_port:
+        proxy_conf.extend(["--http_redirect_port", args.http_redirect_port])
+    if args.https_redirect_port:
+        proxy_conf.extend(["--https_redirect_port", args.https_redirect_port])
+    if args.http_redirect_response_code:
+        proxy_conf.extend(["--http_redirect_response_code", args.http_redirect_response_code])
+
+    if args.healthz_check_backend:
+        proxy_conf.append("--healthz_check_backend")
+    if args.healthz_backend_consecutive_failures:
+        proxy_conf.extend(["--healthz_backend_consecutive_failures", args.healthz_backend_consecutive_failures])
+    if args.healthz_backend_evaluation_window:
+        proxy_conf.extend(["--healthz_backend_evaluation_window", args.healthz_backend_evaluation_window])
+
+    if args.backend_health_check_path:
+        proxy_conf.extend(["--backend_health_check_path", args.backend_health_check_path])
+    if args.backend_health_check_grpc:
+        proxy_conf.append("--backend_health_check_grpc")
+    if args.backend_health_check_interval:
+        proxy_conf.extend(["--backend_health_check_interval", args.backend_health_check_interval])
+    if args.backend_health_check_timeout:
+        proxy_conf.extend(["--backend_health_check_timeout", args.backend_health_check_timeout])
+    if args.backend_health_check_healthy_threshold:
+        proxy_conf.extend(["--backend_health_check_healthy_threshold", args.backend_health_check_healthy_threshold])
+    if args.backend_health_check_unhealthy_threshold:
+        proxy_conf.extend(["--backend_health_check_unhealthy_threshold", args.backend_health_check_unhealthy_threshold])
+    if args.backend_health_check_expected_statuses:
+        proxy_conf.extend(["--backend_health_check_expected_statuses", args.backend_health_check_expected_statuses])
+
+    if args.enable_grpc_web:
+        proxy_conf.append("--enable_grpc_web")
+
+    if args.upgrade_types:
+        proxy_conf.extend(["--upgrade_types", args.upgrade_types])
+    if args.operation_upgrade_types:
+        proxy_conf.extend(["--operation_upgrade_types", args.operation_upgrade_types])
+    if args.operation_upgrade_idle_timeouts:
+        proxy_conf.extend(["--operation_upgrade_idle_timeouts", args.operation_upgrade_idle_timeouts])
+
+    if args.grpc_error_status_codes:
+        proxy_conf.extend(["--grpc_error_status_codes", args.grpc_error_status_codes])
+    if args.error_response_template:
+        proxy_conf.extend(["--error_response_template", args.error_response_template])
+
+    if args.operation_streaming_downloads:
+        proxy_conf.extend(["--operation_streaming_downloads", args.operation_streaming_downloads])
+    if args.streaming_download_buffer_limit_bytes:
+        proxy_conf.extend(["--streaming_download_buffer_limit_bytes", args.streaming_download_buffer_limit_bytes])
+
+    if args.transcoding_proto_descriptor_url:
+        proxy_conf.extend(["--transcoding_proto_descriptor_url", args.transcoding_proto_descriptor_url])
+    if args.transcoding_proto_descriptor_refresh_interval:
+        proxy_conf.extend(["--transcoding_proto_descriptor_refresh_interval", args.transcoding_proto_descriptor_refresh_interval])
+
+    if args.response_compression_types:
+        proxy_conf.extend(["--response_compression_types", args.response_compression_types])
+    if args.response_compression_content_types:
+        proxy_conf.extend(["--response_compression_content_types", args.response_compression_content_types])
+    if args.response_compression_min_length:
+        proxy_conf.extend(["--response_compression_min_length", args.response_compression_min_length])
+    if args.response_compression_gzip_level:
+        proxy_conf.extend(["--response_compression_gzip_level", args.response_compression_gzip_level])
+    if args.response_compression_brotli_quality:
+        proxy_conf.extend(["--response_compression_brotli_quality", args.response_compression_brotli_quality])
+
+    if args.max_request_bytes:
+        proxy_conf.extend(["--max_request_bytes", args.max_request_bytes])
+    if args.operation_max_request_bytes:
+        proxy_conf.extend(["--operation_max_request_bytes", args.operation_max_request_bytes])
+
+    if args.allowed_client_ips:
+        proxy_conf.extend(["--allowed_client_ips", args.allowed_client_ips])
+    if args.denied_client_ips:
+        proxy_conf.extend(["--denied_client_ips", args.denied_client_ips])
+    if args.operation_allowed_client_ips:
+        proxy_conf.extend(["--operation_allowed_client_ips", args.operation_allowed_client_ips])
+    if args.operation_denied_client_ips:
+        proxy_conf.extend(["--operation_denied_client_ips", args.operation_denied_client_ips])
+
+    if args.ext_proc_address:
+        proxy_conf.extend(["--ext_proc_address", args.ext_proc_address])
+    if args.ext_proc_processing_mode:
+        proxy_conf.extend(["--ext_proc_processing_mode", args.ext_proc_processing_mode])
+    if args.ext_proc_timeout:
+        proxy_conf.extend(["--ext_proc_timeout", args.ext_proc_timeout])
+    if args.ext_proc_failure_mode_allow:
+        proxy_conf.append("--ext_proc_failure_mode_allow")
+    if args.operation_ext_proc_processing_modes:
+        proxy_conf.extend(["--operation_ext_proc_processing_modes", args.operation_ext_proc_processing_modes])
+
+    if args.wasm_filter_uri:
+        proxy_conf.extend(["--wasm_filter_uri", args.wasm_filter_uri])
+    if args.wasm_filter_sha256:
+        proxy_conf.extend(["--wasm_filter_sha256", args.wasm_filter_sha256])
+    if args.wasm_filter_config:
+        proxy_conf.extend(["--wasm_filter_config", args.wasm_filter_config])
+    if args.wasm_filter_before:
+        proxy_conf.extend(["--wasm_filter_before", args.wasm_filter_before])
+    if args.operation_wasm_filter_enabled:
+        proxy_conf.extend(["--operation_wasm_filter_enabled", args.operation_wasm_filter_enabled])
+
+    if args.lua_filter_script:
+        proxy_conf.extend(["--lua_filter_script", args.lua_filter_script])
+    if args.lua_scripts:
+        proxy_conf.extend(["--lua_scripts", args.lua_scripts])
+    if args.operation_lua_scripts:
+        proxy_conf.extend(["--operation_lua_scripts", args.operation_lua_scripts])
+
+    if args.admin_read_only_paths:
+        proxy_conf.extend(["--admin_read_only_paths", args.admin_read_only_paths])
+    if args.admin_path_prefix:
+        proxy_conf.extend(["--admin_path_prefix", args.admin_path_prefix])
+    if args.admin_token_path:
+        proxy_conf.extend(["--admin_token_path", args.admin_token_path])
+    if args.admin_token_header:
+        proxy_conf.extend(["--admin_token_header", args.admin_token_header])
+    if args.admin_loopback_only:
+        proxy_conf.append("--admin_loopback_only")
+    if args.admin_socket_path:
+        proxy_conf.extend(["--admin_socket_path", args.admin_socket_path])
+
+    if args.operation_cors_allow_origin:
+        proxy_conf.extend(["--operation_cors_allow_origin", args.operation_cors_allow_origin])
+    if args.operation_cors_allow_credentials:
+        proxy_conf.extend(["--operation_cors_allow_credentials", args.operation_cors_allow_credentials])
+    if args.operation_cors_expose_headers:
+        proxy_conf.extend(["--operation_cors_expose_headers", args.operation_cors_expose_headers])
+
+    if args.cors_allow_unmatched_preflight:
+        proxy_conf.append("--cors_allow_unmatched_preflight")
+
+    if args.oidc_discovery_timeout:
+        proxy_conf.extend(["--oidc_discovery_timeout", args.oidc_discovery_timeout])
+    if args.oidc_discovery_num_retries:
+        proxy_conf.extend(["--oidc_discovery_num_retries", args.oidc_discovery_num_retries])
+    if args.oidc_discovery_retry_backoff_base_interval:
+        proxy_conf.extend(["--oidc_discovery_retry_backoff_base_interval", args.oidc_discovery_retry_backoff_base_interval])
+    if args.oidc_discovery_retry_backoff_max_interval:
+        proxy_conf.extend(["--oidc_discovery_retry_backoff_max_interval", args.oidc_discovery_retry_backoff_max_interval])
+    if args.oidc_discovery_max_redirects:
+        proxy_conf.extend(["--oidc_discovery_max_redirects", args.oidc_discovery_max_redirects])
+
+    if args.disable_http_fetch_cache:
+        proxy_conf.append("--disable_http_fetch_cache")
+
+    if args.backend_cluster_connect_timeout:
+        proxy_conf.extend(["--backend_cluster_connect_timeout", args.backend_cluster_connect_timeout])
+    if args.backend_tcp_keepalive_time:
+        proxy_conf.extend(["--backend_tcp_keepalive_time", args.backend_tcp_keepalive_time])
+    if args.backend_tcp_keepalive_interval:
+        proxy_conf.extend(["--backend_tcp_keepalive_interval", args.backend_tcp_keepalive_interval])
+    if args.backend_tcp_keepalive_probes:
+        proxy_conf.extend(["--backend_tcp_keepalive_probes", args.backend_tcp_keepalive_probes])
+    if args.backend_happy_eyeballs:
+        proxy_conf.append("--backend_happy_eyeballs")
+
+    if args.max_request_headers_kb:
+        proxy_conf.extend(["--max_request_headers_kb", args.max_request_headers_kb])
+    if args.max_request_headers_count:
+        proxy_conf.extend(["--max_request_headers_count", args.max_request_headers_count])
+    if args.headers_with_underscores_action:
+        proxy_conf.extend(["--headers_with_underscores_action", args.headers_with_underscores_action])
+    if args.header_key_format:
+        proxy_conf.extend(["--header_key_format", args.header_key_format])
+
+    if args.strict_route_allowlist:
+        proxy_conf.append("--strict_route_allowlist")
+    if args.passthrough_path_prefixes:
+        proxy_conf.extend(["--passthrough_path_prefixes", args.passthrough_path_prefixes])
+
+    if args.default_backend_address:
+        proxy_conf.extend(["--default_backend_address", args.default_backend_address])
+
+    if args.tenant_mapping_file:
+        proxy_conf.extend(["--tenant_mapping_file", args.tenant_mapping_file])
+    if args.tenant_mapping_metadata_attribute:
+        proxy_conf.extend(["--tenant_mapping_metadata_attribute", args.tenant_mapping_metadata_attribute])
+
+    if args.enable_operation_stats:
+        proxy_conf.append("--enable_operation_stats")
+
+    if args.enable_grpc_reflection:
+        proxy_conf.append("--enable_grpc_reflection")
+    if args.grpc_health_port:
+        proxy_conf.extend(["--grpc_health_port", args.grpc_health_port])
+
+    if args.startup_timeout:
+        proxy_conf.extend(["--startup_timeout", args.startup_timeout])
+    if args.require_backend_dns_resolution:
+        proxy_conf.append("--require_backend_dns_resolution")
+
+    if args.snapshot_admin_port:
+        proxy_conf.extend(["--snapshot_admin_port", args.snapshot_admin_port])
+    if args.snapshot_replay_file:
+        proxy_conf.extend(["--snapshot_replay_file", args.snapshot_replay_file])
+
+    if args.backend_hedge_get_requests:
+        proxy_conf.append("--backend_hedge_get_requests")
+    if args.backend_retry_budget_percent:
+        proxy_conf.extend(["--backend_retry_budget_percent", args.backend_retry_budget_percent])
+    if args.backend_retry_budget_min_concurrency:
+        proxy_conf.extend(["--backend_retry_budget_min_concurrency", args.backend_retry_budget_min_concurrency])
+
+    if args.operation_hedge_delays:
+        proxy_conf.extend(["--operation_hedge_delays", args.operation_hedge_delays])
+
+    if args.maintenance_mode:
+        proxy_conf.append("--maintenance_mode")
+    if args.maintenance_operations:
+        proxy_conf.extend(["--maintenance_operations", args.maintenance_operations])
+    if args.maintenance_response:
+        proxy_conf.extend(["--maintenance_response", args.maintenance_response])
+
+    if args.static_paths:
+        proxy_conf.extend(["--static_paths", args.static_paths])
+
+    if args.redirect_rules:
+        proxy_conf.extend(["--redirect_rules", args.redirect_rules])
+
+    if args.consumer_backends:
+        proxy_conf.extend(["--consumer_backends", args.consumer_backends])
+
+    if args.version_backends:
+        proxy_conf.extend(["--version_backends", args.version_backends])
+
+    if args.grpc_metadata:
+        proxy_conf.extend(["--grpc_metadata", args.grpc_metadata])
+
+    if args.listener_connection_rate_limit:
+        proxy_conf.extend(["--listener_connection_rate_limit", args.listener_connection_rate_limit])
+    if args.max_downstream_connections:
+        proxy_conf.extend(["--max_downstream_connections", args.max_downstream_connections])
+    if args.overload_max_heap_size_bytes:
+        proxy_conf.extend(["--overload_max_heap_size_bytes", args.overload_max_heap_size_bytes])
+    if args.overload_shrink_heap_threshold:
+        proxy_conf.extend(["--overload_shrink_heap_threshold", args.overload_shrink_heap_threshold])
+    if args.overload_stop_accepting_requests_threshold:
+        proxy_conf.extend(["--overload_stop_accepting_requests_threshold", args.overload_stop_accepting_requests_threshold])
+
+    if args.enable_adaptive_concurrency:
+        proxy_conf.append("--enable_adaptive_concurrency")
+    if args.adaptive_concurrency_sample_percentile:
+        proxy_conf.extend(["--adaptive_concurrency_sample_percentile", args.adaptive_concurrency_sample_percentile])
+    if args.adaptive_concurrency_update_interval:
+        proxy_conf.extend(["--adaptive_concurrency_update_interval", args.adaptive_concurrency_update_interval])
+    if args.adaptive_concurrency_min_rtt_interval:
+        proxy_conf.extend(["--adaptive_concurrency_min_rtt_interval", args.adaptive_concurrency_min_rtt_interval])
+    if args.adaptive_concurrency_min_rtt_request_count:
+        proxy_conf.extend(["--adaptive_concurrency_min_rtt_request_count", args.adaptive_concurrency_min_rtt_request_count])
+    if args.adaptive_concurrency_buffer_percent:
+        proxy_conf.extend(["--adaptive_concurrency_buffer_percent", args.adaptive_concurrency_buffer_percent])
+    if args.adaptive_concurrency_max_limit:
+        proxy_conf.extend(["--adaptive_concurrency_max_limit", args.adaptive_concurrency_max_limit])
+
+    if args.backend_slow_start_window:
+        proxy_conf.extend(["--backend_slow_start_window", args.backend_slow_start_window])
+    if args.backend_slow_start_aggression:
+        proxy_conf.extend(["--backend_slow_start_aggression", args.backend_slow_start_aggression])
+
+    if args.backend_address_overrides:
+        proxy_conf.extend(["--backend_address_overrides", args.backend_address_overrides])
+
+    if args.service_management_service_account_key:
+        proxy_conf.extend(["--service_management_service_account_key", args.service_management_service_account_key])
+    if args.service_control_service_account_key:
+        proxy_conf.extend(["--service_control_service_account_key", args.service_control_service_account_key])
+    if args.backend_auth_service_account_key:
+        proxy_conf.extend(["--backend_auth_service_account_key", args.backend_auth_service_account_key])
+
+    if args.metadata_stub_file:
+        proxy_conf.extend(["--metadata_stub_file", args.metadata_stub_file])
+    if args.metadata_stub_port:
+        proxy_conf.extend(["--metadata_stub_port", args.metadata_stub_port])
+
+    if args.enable_token_service:
+        proxy_conf.append("--enable_token_service")
+
+    if args.deployment_labels:
+        proxy_conf.extend(["--deployment_labels", args.deployment_labels])
+
+    if args.operation_quota_metric_costs:
+        proxy_conf.extend(["--operation_quota_metric_costs", args.operation_quota_metric_costs])
+
+    if args.backend_http2_keepalive_interval:
+        proxy_conf.extend(["--backend_http2_keepalive_interval", args.backend_http2_keepalive_interval])
+    if args.backend_http2_keepalive_timeout:
+        proxy_conf.extend(["--backend_http2_keepalive_timeout", args.backend_http2_keepalive_timeout])
+    if args.backend_http2_keepalive_interval_jitter:
+        proxy_conf.extend(["--backend_http2_keepalive_interval_jitter", args.backend_http2_keepalive_interval_jitter])
+
+    if args.backend_protocol_selection:
+        proxy_conf.extend(["--backend_protocol_selection", args.backend_protocol_selection])
+
+    if args.health_port:
+        proxy_conf.extend(["--health_port", args.health_port])
+
+    if args.tcp_proxy_routes:
+        proxy_conf.extend(["--tcp_proxy_routes", args.tcp_proxy_routes])
+
+    if args.enable_request_validation:
+        proxy_conf.append("--enable_request_validation")
+
+    if args.enable_security_headers:
+        proxy_conf.append("--enable_security_headers")
+    if args.security_headers:
+        proxy_conf.extend(["--security_headers", args.security_headers])
+    if args.operation_security_headers_enabled:
+        proxy_conf.extend(["--operation_security_headers_enabled", args.operation_security_headers_enabled])
+
+    if args.api_key_bearer_token_prefix:
+        proxy_conf.extend(["--api_key_bearer_token_prefix", args.api_key_bearer_token_prefix])
+    if args.api_key_from_basic_auth_username:
+        proxy_conf.append("--api_key_from_basic_auth_username")
+
+    if args.enable_deprecation_headers:
+        proxy_conf.append("--enable_deprecation_headers")
+    if args.operation_deprecations:
+        proxy_conf.extend(["--operation_deprecations", args.operation_deprecations])
+
+    if args.access_log_consumer_sample_rates:
+        proxy_conf.extend(["--access_log_consumer_sample_rates", args.access_log_consumer_sample_rates])
+
+    if args.propagate_request_timeout:
+        proxy_conf.append("--propagate_request_timeout")
+
+    if args.quota_override_consumers:
+        proxy_conf.extend(["--quota_override_consumers", args.quota_override_consumers])
+    if args.service_consumer_management_url:
+        proxy_conf.extend(["--service_consumer_management_url", args.service_consumer_management_url])
+
+    if args.backend_failover_addresses:
+        proxy_conf.extend(["--backend_failover_addresses", args.backend_failover_addresses])
+
+    if args.backend_regional_addresses:
+        proxy_conf.extend(["--backend_regional_addresses", args.backend_regional_addresses])
+
+    if args.downstream_idle_timeout:
+        proxy_conf.extend(["--downstream_idle_timeout", args.downstream_idle_timeout])
+    if args.downstream_max_connection_duration:
+        proxy_conf.extend(["--downstream_max_connection_duration", args.downstream_max_connection_duration])
+    if args.downstream_drain_timeout:
+        proxy_conf.extend(["--downstream_drain_timeout", args.downstream_drain_timeout])
+
+    if args.disable_transcoding:
+        proxy_conf.append("--disable_transcoding")
+
+    if args.grpc_reflection:
+        proxy_conf.extend(["--grpc_reflection", args.grpc_reflection])
+    if args.grpc_reflection_jwt_providers:
+        proxy_conf.extend(["--grpc_reflection_jwt_providers", args.grpc_reflection_jwt_providers])
+
+    if args.jwks_resolve_at_startup:
+        proxy_conf.append("--jwks_resolve_at_startup")
+    if args.jwks_resolve_ttl:
+        proxy_conf.extend(["--jwks_resolve_ttl", args.jwks_resolve_ttl])
+
+    if args.readiness_max_config_age:
+        proxy_conf.extend(["--readiness_max_config_age", args.readiness_max_config_age])
+    if args.readiness_max_rollout_fetch_failures:
+        proxy_conf.extend(["--readiness_max_rollout_fetch_failures", args.readiness_max_rollout_fetch_failures])
+
+    if args.http_filter_order:
+        proxy_conf.extend(["--http_filter_order", args.http_filter_order])
+
+    if args.backend_rule_inheritance:
+        proxy_conf.append("--backend_rule_inheritance")
+
+    if args.operation_request_timeouts:
+        proxy_conf.extend(["--operation_request_timeouts", args.operation_request_timeouts])
+    if args.operation_per_try_timeouts:
+        proxy_conf.extend(["--operation_per_try_timeouts", args.operation_per_try_timeouts])
+
+    if args.service_control_compress_reports:
+        proxy_conf.append("--service_control_compress_reports")
+
+    if args.allowed_source_ranges:
+        proxy_conf.extend(["--allowed_source_ranges", args.allowed_source_ranges])
+
+    if args.backend_jwt_audience_template:
+        proxy_conf.extend(["--backend_jwt_audience_template", args.backend_jwt_audience_template])
+    if args.operation_jwt_audience_templates:
+        proxy_conf.extend(["--operation_jwt_audience_templates", args.operation_jwt_audience_templates])
+
+    if args.suppress_upstream_service_time:
+        proxy_conf.append("--suppress_upstream_service_time")
+    if args.operation_response_headers_to_remove:
+        proxy_conf.extend(["--operation_response_headers_to_remove", args.operation_response_headers_to_remove])
+
+    if args.server_header:
+        proxy_conf.extend(["--server_header", args.server_header])
+    if args.server_header_transformation:
+        proxy_conf.extend(["--server_header_transformation", args.server_header_transformation])
+    if args.via_header:
+        proxy_conf.extend(["--via_header", args.via_header])
+
+    if args.merge_duplicate_routes:
+        proxy_conf.append("--merge_duplicate_routes")
+
+    if args.tap_output_path_prefix:
+        proxy_conf.extend(["--tap_output_path_prefix", args.tap_output_path_prefix])
+    if args.operation_tap_enabled:
+        proxy_conf.extend(["--operation_tap_enabled", args.operation_tap_enabled])
+    if args.tap_max_buffered_bytes:
+        proxy_conf.extend(["--tap_max_buffered_bytes", args.tap_max_buffered_bytes])
+    if args.tap_admin_config_id:
+        proxy_conf.extend(["--tap_admin_config_id", args.tap_admin_config_id])
+
+    if args.operation_fault_abort:
+        proxy_conf.extend(["--operation_fault_abort", args.operation_fault_abort])
+    if args.operation_fault_delay:
+        proxy_conf.extend(["--operation_fault_delay", args.operation_fault_delay])
+    if args.fault_trigger_header:
+        proxy_conf.extend(["--fault_trigger_header", args.fault_trigger_header])
+
+    if args.enable_response_cache:
+        proxy_conf.append("--enable_response_cache")
+    if args.operation_response_cache_ttl:
+        proxy_conf.extend(["--operation_response_cache_ttl", args.operation_response_cache_ttl])
+    if args.response_cache_max_body_bytes:
+        proxy_conf.extend(["--response_cache_max_body_bytes", args.response_cache_max_body_bytes])
+
+    if args.consumer_max_concurrent_requests:
+        proxy_conf.extend(["--consumer_max_concurrent_requests", args.consumer_max_concurrent_requests])
+    if args.operation_consumer_max_concurrent_requests:
+        proxy_conf.extend(["--operation_consumer_max_concurrent_requests", args.operation_consumer_max_concurrent_requests])
+
+    if args.client_ip_rate_limit_per_second:
+        proxy_conf.extend(["--client_ip_rate_limit_per_second", args.client_ip_rate_limit_per_second])
+    if args.client_ip_rate_limit_burst:
+        proxy_conf.extend(["--client_ip_rate_limit_burst", args.client_ip_rate_limit_burst])
+
+    if args.deployment_mode:
+        proxy_conf.extend(["--deployment_mode", args.deployment_mode])
+
     return proxy_conf
 
 def gen_envoy_args(args):
@@ -16,15 +16,37 @@ EXTENSIONS = {
     "envoy.access_loggers.file": "//source/extensions/access_loggers/file:config",
     "envoy.compression.gzip.compressor": "//source/extensions/compression/gzip/compressor:config",
     "envoy.compression.brotli.compressor": "//source/extensions/compression/brotli/compressor:config",
+    "envoy.filters.http.buffer": "//source/extensions/filters/http/buffer:config",
+    "envoy.filters.http.cache": "//source/extensions/filters/http/cache:config",
+    "envoy.extensions.http.cache.simple": "//source/extensions/http/cache/simple_http_cache:config",
     "envoy.filters.http.compressor": "//source/extensions/filters/http/compressor:config",
+    "envoy.filters.http.custom_response": "//source/extensions/filters/http/custom_response:factory_config",
+    "envoy.http.custom_response.local_response_policy": "//source/extensions/http/custom_response/local_response_policy:local_response_policy_lib",
     "envoy.filters.http.cors": "//source/extensions/filters/http/cors:config",
+    "envoy.filters.http.ext_proc": "//source/extensions/filters/http/ext_proc:config",
+    "envoy.filters.http.fault": "//source/extensions/filters/http/fault:config",
     "envoy.filters.http.grpc_json_transcoder": "//source/extensions/filters/http/grpc_json_transcoder:config",
     "envoy.filters.http.grpc_web": "//source/extensions/filters/http/grpc_web:config",
     "envoy.filters.http.health_check": "//source/extensions/filters/http/health_check:config",
     "envoy.filters.http.jwt_authn": "//source/extensions/filters/http/jwt_authn:config",
+    "envoy.filters.http.lua": "//source/extensions/filters/http/lua:config",
+    "envoy.filters.http.rbac": "//source/extensions/filters/http/rbac:config",
     "envoy.filters.http.router": "//source/extensions/filters/http/router:config",
+    "envoy.filters.http.tap": "//source/extensions/filters/http/tap:config",
+    "envoy.filters.http.wasm": "//source/extensions/filters/http/wasm:config",
+    "envoy.wasm.runtime.v8": "//source/extensions/wasm_runtime/v8:config",
     "envoy.filters.network.http_connection_manager": "//source/extensions/filters/network/http_connection_manager:config",
+    "envoy.filters.network.rbac": "//source/extensions/filters/network/rbac:config",
+    "envoy.http.stateful_header_formatters.preserve_case": "//source/extensions/http/header_formatters/preserve_case:config",
     "envoy.tracers.opencensus": "//source/extensions/tracers/opencensus:config",
+    "envoy.filters.network.local_ratelimit": "//source/extensions/filters/network/local_ratelimit:config",
+    "envoy.resource_monitors.fixed_heap": "//source/extensions/resource_monitors/fixed_heap:config",
+    "envoy.resource_monitors.global_downstream_max_connections": "//source/extensions/resource_monitors/downstream_connections:config",
+    "envoy.filters.http.adaptive_concurrency": "//source/extensions/filters/http/adaptive_concurrency:config",
+    "envoy.filters.network.tcp_proxy": "//source/extensions/filters/network/tcp_proxy:config",
+    "envoy.filters.listener.tls_inspector": "//source/extensions/filters/listener/tls_inspector:config",
+    "envoy.filters.http.local_ratelimit": "//source/extensions/filters/http/local_ratelimit:config",
+    "envoy.clusters.aggregate": "//source/extensions/clusters/aggregate:cluster",
 
     # Implicitly needed for TLS config.
     "envoy.transport_sockets.raw_buffer": "//source/extensions/transport_sockets/raw_buffer:config",
@@ -37,6 +59,7 @@ EXTENSIONS = {
     "envoy.stat_sinks.statsd": "//source/extensions/stat_sinks/statsd:config",
     "envoy.config_mux.grpc_mux_factory": "//source/extensions/config_subscription/grpc:grpc_subscription_lib",
     "envoy.health_checkers.grpc": "//source/extensions/health_checkers/grpc:health_checker_lib",
+    "envoy.health_checkers.http": "//source/extensions/health_checkers/http:health_checker_lib",
 }
 
 EXTENSION_CONFIG_VISIBILITY = ["//visibility:public"]
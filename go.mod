@@ -6,6 +6,7 @@ require (
 	cloud.google.com/go/storage v1.28.1
 	github.com/cenkalti/backoff v2.2.1+incompatible
 	github.com/census-instrumentation/opencensus-proto v0.4.1
+	github.com/cncf/xds/go v0.0.0-20230105202645-06c439db220b
 	github.com/envoyproxy/go-control-plane v0.11.0
 	github.com/envoyproxy/protoc-gen-validate v0.10.1
 	github.com/golang/glog v1.0.0
@@ -33,7 +34,6 @@ require (
 	cloud.google.com/go/servicecontrol v1.11.0 // indirect
 	cloud.google.com/go/servicemanagement v1.6.0 // indirect
 	cloud.google.com/go/trace v1.8.0 // indirect
-	github.com/cncf/xds/go v0.0.0-20230105202645-06c439db220b // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.2.3 // indirect
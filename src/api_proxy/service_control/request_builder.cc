@@ -848,6 +848,11 @@ void FillLogEntry(const ReportRequestInfo& info, const std::string& name,
     }
   }
 
+  // Add the labels of the deployment.
+  for (const auto& label : info.deployment_labels) {
+    (*log_entry->mutable_labels())[label.first] = label.second;
+  }
+
   // Fill in http request.
   auto* http_request = log_entry->mutable_http_request();
   http_request->set_protocol(protocol::ToString(info.frontend_protocol));
@@ -15,6 +15,7 @@
 #pragma once
 
 #include <chrono>
+#include <map>
 #include <memory>
 #include <string>
 
@@ -249,6 +250,9 @@ struct ReportRequestInfo : public OperationInfo {
   // A recognized compute platform (GAE, GCE, GKE).
   std::string compute_platform;
 
+  // The labels of the deployment, attached to the log entry labels.
+  std::map<std::string, std::string> deployment_labels;
+
   // If consumer data should be sent.
   CheckResponseInfo check_response_info;
 
@@ -0,0 +1,61 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/client_ip_rate_limit/filter.h"
+
+#include <string>
+
+#include "envoy/network/address.h"
+#include "src/envoy/utils/rc_detail_utils.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace client_ip_rate_limit {
+
+using Envoy::Http::FilterHeadersStatus;
+using Envoy::Http::RequestHeaderMap;
+
+FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap&, bool) {
+  const Envoy::Network::Address::InstanceConstSharedPtr& remote_address =
+      decoder_callbacks_->streamInfo()
+          .downstreamAddressProvider()
+          .remoteAddress();
+  if (remote_address == nullptr || remote_address->ip() == nullptr) {
+    // Such as the requests from the unix domain sockets.
+    ENVOY_LOG(debug, "No client IP, request is not limited");
+    config_->stats().no_client_ip_.inc();
+    return FilterHeadersStatus::Continue;
+  }
+
+  const std::string& client_ip = remote_address->ip()->addressAsString();
+  if (config_->buckets().tryConsume(client_ip)) {
+    config_->stats().allowed_.inc();
+    return FilterHeadersStatus::Continue;
+  }
+
+  ENVOY_LOG(debug, "Too many requests from client IP {}", client_ip);
+  config_->stats().denied_.inc();
+  decoder_callbacks_->sendLocalReply(
+      Envoy::Http::Code::TooManyRequests, "Too many requests from the client.",
+      nullptr, absl::nullopt,
+      utils::generateRcDetails(utils::kRcDetailFilterClientIpRateLimit,
+                               utils::kRcDetailErrorTypeRateLimited));
+  return FilterHeadersStatus::StopIteration;
+}
+
+}  // namespace client_ip_rate_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include "api/envoy/v12/http/client_ip_rate_limit/config.pb.h"
+#include "envoy/common/time.h"
+#include "envoy/stats/scope.h"
+#include "envoy/stats/stats_macros.h"
+#include "src/envoy/http/client_ip_rate_limit/token_buckets.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace client_ip_rate_limit {
+
+// The filter name.
+constexpr const char kFilterName[] =
+    "com.google.espv2.filters.http.client_ip_rate_limit";
+
+// The max clients with a bucket. It bounds the memory of the buckets when the
+// requests come from too many client IPs.
+constexpr size_t kMaxClients = 100000;
+
+/**
+ * All stats for the client IP rate limit filter. @see stats_macros.h
+ */
+#define ALL_CLIENT_IP_RATE_LIMIT_FILTER_STATS(COUNTER) \
+  COUNTER(allowed)                                     \
+  COUNTER(denied)                                      \
+  COUNTER(no_client_ip)
+
+/**
+ * Wrapper struct for client IP rate limit filter stats. @see stats_macros.h
+ */
+struct FilterStats {
+  ALL_CLIENT_IP_RATE_LIMIT_FILTER_STATS(GENERATE_COUNTER_STRUCT)
+};
+
+class FilterConfig {
+ public:
+  FilterConfig(
+      const ::espv2::api::envoy::v12::http::client_ip_rate_limit::FilterConfig&
+          proto_config,
+      const std::string& stats_prefix, Envoy::Stats::Scope& scope,
+      Envoy::TimeSource& time_source)
+      : stats_(generateStats(stats_prefix, scope)),
+        buckets_(proto_config.tokens_per_second(),
+                 proto_config.max_tokens() > 0
+                     ? proto_config.max_tokens()
+                     : proto_config.tokens_per_second(),
+                 kMaxClients, time_source) {}
+
+  FilterStats& stats() { return stats_; }
+
+  ClientTokenBuckets& buckets() { return buckets_; }
+
+ private:
+  FilterStats generateStats(const std::string& prefix,
+                            Envoy::Stats::Scope& scope) {
+    const std::string final_prefix = prefix + "client_ip_rate_limit.";
+    return {ALL_CLIENT_IP_RATE_LIMIT_FILTER_STATS(
+        POOL_COUNTER_PREFIX(scope, final_prefix))};
+  }
+
+  // The stats
+  FilterStats stats_;
+  // The buckets are shared by the filters of all the worker threads.
+  ClientTokenBuckets buckets_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<FilterConfig>;
+
+}  // namespace client_ip_rate_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,121 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/client_ip_rate_limit/filter.h"
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "source/common/network/address_impl.h"
+#include "test/mocks/http/mocks.h"
+#include "test/mocks/stats/mocks.h"
+#include "test/test_common/simulated_time_system.h"
+#include "test/test_common/utility.h"
+
+using ::testing::_;
+using ::testing::NiceMock;
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace client_ip_rate_limit {
+
+class FilterTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    ::espv2::api::envoy::v12::http::client_ip_rate_limit::FilterConfig
+        proto_config;
+    proto_config.set_tokens_per_second(1);
+    filter_config_ = std::make_shared<FilterConfig>(
+        proto_config, "", *scope_.rootScope(), test_time_);
+
+    filter_ = std::make_unique<Filter>(filter_config_);
+    filter_->setDecoderFilterCallbacks(mock_decoder_callbacks_);
+  }
+
+  void setClientIp(const std::string& ip) {
+    mock_decoder_callbacks_.stream_info_.downstream_connection_info_provider_
+        ->setRemoteAddress(
+            std::make_shared<Envoy::Network::Address::Ipv4Instance>(ip));
+  }
+
+  void checkCounter(const std::string& name, uint64_t value) {
+    const Envoy::Stats::CounterSharedPtr counter =
+        Envoy::TestUtility::findCounter(scope_,
+                                        "client_ip_rate_limit." + name);
+    ASSERT_NE(counter, nullptr);
+    EXPECT_EQ(counter->value(), value);
+  }
+
+  NiceMock<Envoy::Stats::MockIsolatedStatsStore> scope_;
+  Envoy::Event::SimulatedTimeSystem test_time_;
+  std::shared_ptr<FilterConfig> filter_config_;
+  NiceMock<Envoy::Http::MockStreamDecoderFilterCallbacks>
+      mock_decoder_callbacks_;
+  std::unique_ptr<Filter> filter_;
+};
+
+TEST_F(FilterTest, RequestsOverLimitRejected) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/login"}};
+  setClientIp("10.0.0.1");
+
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, true));
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::TooManyRequests,
+                             "Too many requests from the client.", _, _,
+                             "client_ip_rate_limit_rate_limited"));
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::StopIteration,
+            filter_->decodeHeaders(headers, true));
+
+  checkCounter("allowed", 1);
+  checkCounter("denied", 1);
+}
+
+TEST_F(FilterTest, ClientsLimitedSeparately) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/login"}};
+
+  EXPECT_CALL(mock_decoder_callbacks_, sendLocalReply(_, _, _, _, _)).Times(0);
+  setClientIp("10.0.0.1");
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, true));
+  setClientIp("10.0.0.2");
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, true));
+
+  checkCounter("allowed", 2);
+  checkCounter("denied", 0);
+}
+
+TEST_F(FilterTest, NoClientIpNotLimited) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/login"}};
+  mock_decoder_callbacks_.stream_info_.downstream_connection_info_provider_
+      ->setRemoteAddress(nullptr);
+
+  EXPECT_CALL(mock_decoder_callbacks_, sendLocalReply(_, _, _, _, _)).Times(0);
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, true));
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, true));
+
+  checkCounter("no_client_ip", 2);
+}
+
+}  // namespace client_ip_rate_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,102 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/client_ip_rate_limit/token_buckets.h"
+
+#include <algorithm>
+#include <chrono>
+
+#include "absl/hash/hash.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace client_ip_rate_limit {
+
+ClientTokenBuckets::ClientTokenBuckets(uint32_t tokens_per_second,
+                                       uint32_t max_tokens, size_t max_clients,
+                                       Envoy::TimeSource& time_source,
+                                       size_t num_shards)
+    : tokens_per_second_(tokens_per_second),
+      max_tokens_(max_tokens),
+      shard_capacity_(
+          std::max<size_t>(1, (max_clients + num_shards - 1) / num_shards)),
+      time_source_(time_source) {
+  for (size_t i = 0; i < num_shards; ++i) {
+    shards_.push_back(std::make_unique<Shard>());
+  }
+}
+
+bool ClientTokenBuckets::tryConsume(const std::string& client) {
+  const Envoy::MonotonicTime now = time_source_.monotonicTime();
+  Shard& shard = *shards_[absl::Hash<std::string>{}(client) % shards_.size()];
+  absl::MutexLock lock(&shard.mutex);
+  sweep(shard, now);
+
+  Bucket* bucket;
+  auto it = shard.index.find(client);
+  if (it == shard.index.end()) {
+    if (shard.lru.size() >= shard_capacity_) {
+      shard.index.erase(shard.lru.back().first);
+      shard.lru.pop_back();
+    }
+    shard.lru.emplace_front(client, Bucket{max_tokens_, now});
+    shard.index.emplace(client, shard.lru.begin());
+    bucket = &shard.lru.front().second;
+  } else {
+    shard.lru.splice(shard.lru.begin(), shard.lru, it->second);
+    bucket = &it->second->second;
+    refill(*bucket, now);
+  }
+
+  if (bucket->tokens < 1) {
+    return false;
+  }
+  bucket->tokens -= 1;
+  return true;
+}
+
+size_t ClientTokenBuckets::size() const {
+  size_t size = 0;
+  for (const auto& shard : shards_) {
+    absl::MutexLock lock(&shard->mutex);
+    size += shard->lru.size();
+  }
+  return size;
+}
+
+void ClientTokenBuckets::refill(Bucket& bucket,
+                                Envoy::MonotonicTime now) const {
+  const std::chrono::duration<double> elapsed = now - bucket.last_refill;
+  bucket.tokens = std::min(
+      max_tokens_, bucket.tokens + elapsed.count() * tokens_per_second_);
+  bucket.last_refill = now;
+}
+
+void ClientTokenBuckets::sweep(Shard& shard, Envoy::MonotonicTime now) const {
+  while (!shard.lru.empty()) {
+    Bucket& bucket = shard.lru.back().second;
+    refill(bucket, now);
+    if (bucket.tokens < max_tokens_) {
+      return;
+    }
+    shard.index.erase(shard.lru.back().first);
+    shard.lru.pop_back();
+  }
+}
+
+}  // namespace client_ip_rate_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
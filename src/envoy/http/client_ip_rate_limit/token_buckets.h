@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <cstdint>
+#include <list>
+#include <memory>
+#include <string>
+#include <vector>
+
+#include "absl/base/thread_annotations.h"
+#include "absl/container/flat_hash_map.h"
+#include "absl/synchronization/mutex.h"
+#include "envoy/common/time.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace client_ip_rate_limit {
+
+// The number of the shards of the buckets, each with its own lock, so the
+// worker threads rarely wait for each other.
+constexpr size_t kDefaultNumShards = 16;
+
+// The token buckets of the clients shared by the worker threads. The buckets
+// are refilled continuously at `tokens_per_second` up to `max_tokens`.
+//
+// At most `max_clients` buckets are kept. Beyond it, the least recently used
+// bucket is evicted, and its client starts over with a full bucket.
+class ClientTokenBuckets {
+ public:
+  ClientTokenBuckets(uint32_t tokens_per_second, uint32_t max_tokens,
+                     size_t max_clients, Envoy::TimeSource& time_source,
+                     size_t num_shards = kDefaultNumShards);
+
+  // Consumes a token of the bucket of the client and returns true, or returns
+  // false if the bucket is empty.
+  bool tryConsume(const std::string& client);
+
+  // The number of the clients with a bucket.
+  size_t size() const;
+
+ private:
+  struct Bucket {
+    double tokens;
+    Envoy::MonotonicTime last_refill;
+  };
+
+  // The buckets of a part of the clients, most recently used first.
+  struct Shard {
+    using LruList = std::list<std::pair<std::string, Bucket>>;
+
+    mutable absl::Mutex mutex;
+    LruList lru ABSL_GUARDED_BY(mutex);
+    absl::flat_hash_map<std::string, LruList::iterator> index
+        ABSL_GUARDED_BY(mutex);
+  };
+
+  void refill(Bucket& bucket, Envoy::MonotonicTime now) const;
+
+  // Removes the least recently used buckets that are full, they are the same
+  // as the missing ones.
+  void sweep(Shard& shard, Envoy::MonotonicTime now) const
+      ABSL_EXCLUSIVE_LOCKS_REQUIRED(shard.mutex);
+
+  const double tokens_per_second_;
+  const double max_tokens_;
+  // The max buckets of each shard.
+  const size_t shard_capacity_;
+  Envoy::TimeSource& time_source_;
+
+  std::vector<std::unique_ptr<Shard>> shards_;
+};
+using ClientTokenBucketsSharedPtr = std::shared_ptr<ClientTokenBuckets>;
+
+}  // namespace client_ip_rate_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
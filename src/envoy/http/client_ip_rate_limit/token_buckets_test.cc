@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/client_ip_rate_limit/token_buckets.h"
+
+#include "absl/strings/str_cat.h"
+#include "gtest/gtest.h"
+#include "test/test_common/simulated_time_system.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace client_ip_rate_limit {
+namespace {
+
+class ClientTokenBucketsTest : public ::testing::Test {
+ protected:
+  Envoy::Event::SimulatedTimeSystem test_time_;
+};
+
+TEST_F(ClientTokenBucketsTest, LimitsEachClient) {
+  ClientTokenBuckets buckets(1, 2, 1024, test_time_);
+
+  // The burst of the max tokens is allowed.
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.1"));
+
+  // The other clients have their own buckets.
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.2"));
+  EXPECT_EQ(buckets.size(), 2);
+}
+
+TEST_F(ClientTokenBucketsTest, RefillsOverTime) {
+  ClientTokenBuckets buckets(2, 2, 1024, test_time_);
+
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.1"));
+
+  // A token is added every 500ms.
+  test_time_.advanceTimeWait(std::chrono::milliseconds(500));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.1"));
+
+  // The bucket is not refilled beyond the max tokens.
+  test_time_.advanceTimeWait(std::chrono::seconds(10));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.1"));
+}
+
+TEST_F(ClientTokenBucketsTest, SweepsTheFullBuckets) {
+  // A single shard, so every request sweeps all the buckets.
+  ClientTokenBuckets buckets(1, 1, 1024, test_time_, 1);
+
+  for (int i = 0; i < 1024; ++i) {
+    EXPECT_TRUE(
+        buckets.tryConsume(absl::StrCat("10.0.", i / 256, ".", i % 256)));
+  }
+  EXPECT_EQ(buckets.size(), 1024);
+
+  // All the buckets are full again, so they are removed by the next request.
+  test_time_.advanceTimeWait(std::chrono::seconds(1));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.0"));
+  EXPECT_EQ(buckets.size(), 1);
+
+  // The removed buckets are recreated full.
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.0"));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+}
+
+TEST_F(ClientTokenBucketsTest, EvictsTheLeastRecentlyUsedBuckets) {
+  ClientTokenBuckets buckets(1, 1, 2, test_time_, 1);
+
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.1"));
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.2"));
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.1"));
+
+  // 10.0.0.2 is the least recently used, it is evicted by the new client.
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.3"));
+  EXPECT_EQ(buckets.size(), 2);
+  EXPECT_FALSE(buckets.tryConsume("10.0.0.1"));
+
+  // The evicted client starts over with a full bucket.
+  EXPECT_TRUE(buckets.tryConsume("10.0.0.2"));
+}
+
+TEST_F(ClientTokenBucketsTest, CapsTheClients) {
+  ClientTokenBuckets buckets(1, 1, 1024, test_time_);
+
+  // The buckets are not full, so they are only removed by the cap.
+  for (int i = 0; i < 16 * 1024; ++i) {
+    EXPECT_TRUE(
+        buckets.tryConsume(absl::StrCat("10.", i / 65536, ".", i / 256 % 256,
+                                        ".", i % 256)));
+  }
+  EXPECT_LE(buckets.size(), 1024);
+
+  // The recently used clients keep their buckets.
+  EXPECT_FALSE(buckets.tryConsume("10.0.63.255"));
+}
+
+}  // namespace
+}  // namespace client_ip_rate_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -237,11 +237,20 @@ ClientCache::ClientCache(
       absl::StrCat("/", config_.service_name(), ":allocateQuota"),
       quota_token_fn, quota_timeout_ms_, quota_retries_, time_source,
       "Service Control remote call: Allocate Quota");
-  report_call_factory_ = std::make_unique<HttpCallFactoryImpl>(
+  auto report_call_factory = std::make_unique<HttpCallFactoryImpl>(
       cm, dispatcher, filter_config.service_control_uri(),
       absl::StrCat("/", config_.service_name(), ":report"), sc_token_fn,
       report_timeout_ms_, report_retries_, time_source,
       "Service Control remote call: Report");
+  if (filter_config.sc_calling_config().compress_reports()) {
+    report_call_factory->enableBodyCompression(
+        [this](uint64_t uncompressed_bytes, uint64_t compressed_bytes) {
+          filter_stats_.filter_.report_uncompressed_bytes_.add(
+              uncompressed_bytes);
+          filter_stats_.filter_.report_compressed_bytes_.add(compressed_bytes);
+        });
+  }
+  report_call_factory_ = std::move(report_call_factory);
 
   // Note: Check transport is also defined per request.
   // But this must be defined, it will be called on each flush of the cache
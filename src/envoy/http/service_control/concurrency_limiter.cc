@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/service_control/concurrency_limiter.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace service_control {
+
+bool ConsumerConcurrencyLimiter::tryAcquire(const std::string& key,
+                                            uint32_t limit) {
+  absl::MutexLock lock(&mutex_);
+  uint32_t& count = in_flight_[key];
+  if (count >= limit) {
+    if (count == 0) {
+      in_flight_.erase(key);
+    }
+    return false;
+  }
+  ++count;
+  return true;
+}
+
+void ConsumerConcurrencyLimiter::release(const std::string& key) {
+  absl::MutexLock lock(&mutex_);
+  auto it = in_flight_.find(key);
+  if (it == in_flight_.end()) {
+    return;
+  }
+  if (--it->second == 0) {
+    in_flight_.erase(it);
+  }
+}
+
+uint32_t ConsumerConcurrencyLimiter::inFlight(const std::string& key) const {
+  absl::MutexLock lock(&mutex_);
+  auto it = in_flight_.find(key);
+  return it == in_flight_.end() ? 0 : it->second;
+}
+
+}  // namespace service_control
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <cstdint>
+#include <memory>
+#include <string>
+
+#include "absl/base/thread_annotations.h"
+#include "absl/container/flat_hash_map.h"
+#include "absl/synchronization/mutex.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace service_control {
+
+// Counts the in-flight requests of each API consumer across the worker
+// threads, to reject the requests of the consumers over their limits.
+class ConsumerConcurrencyLimiter {
+ public:
+  // Counts the request of the key and returns true if the key has fewer than
+  // `limit` in-flight requests. Returns false otherwise, without counting it.
+  bool tryAcquire(const std::string& key, uint32_t limit);
+
+  // Releases a request of the key acquired by tryAcquire().
+  void release(const std::string& key);
+
+  // The number of in-flight requests of the key.
+  uint32_t inFlight(const std::string& key) const;
+
+ private:
+  mutable absl::Mutex mutex_;
+  // The keys are removed once they have no in-flight request.
+  absl::flat_hash_map<std::string, uint32_t> in_flight_
+      ABSL_GUARDED_BY(mutex_);
+};
+using ConsumerConcurrencyLimiterSharedPtr =
+    std::shared_ptr<ConsumerConcurrencyLimiter>;
+
+}  // namespace service_control
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/service_control/concurrency_limiter.h"
+
+#include "gtest/gtest.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace service_control {
+namespace {
+
+TEST(ConsumerConcurrencyLimiterTest, LimitsEachKey) {
+  ConsumerConcurrencyLimiter limiter;
+
+  EXPECT_TRUE(limiter.tryAcquire("key-1", 2));
+  EXPECT_TRUE(limiter.tryAcquire("key-1", 2));
+  EXPECT_FALSE(limiter.tryAcquire("key-1", 2));
+  EXPECT_EQ(limiter.inFlight("key-1"), 2);
+
+  // The other keys are counted separately.
+  EXPECT_TRUE(limiter.tryAcquire("key-2", 2));
+  EXPECT_EQ(limiter.inFlight("key-2"), 1);
+
+  limiter.release("key-1");
+  EXPECT_EQ(limiter.inFlight("key-1"), 1);
+  EXPECT_TRUE(limiter.tryAcquire("key-1", 2));
+}
+
+TEST(ConsumerConcurrencyLimiterTest, ReleasesTheKeys) {
+  ConsumerConcurrencyLimiter limiter;
+
+  EXPECT_TRUE(limiter.tryAcquire("key-1", 1));
+  limiter.release("key-1");
+  EXPECT_EQ(limiter.inFlight("key-1"), 0);
+
+  // Releasing an unknown key is a no-op.
+  limiter.release("key-1");
+  EXPECT_EQ(limiter.inFlight("key-1"), 0);
+  EXPECT_TRUE(limiter.tryAcquire("key-1", 1));
+}
+
+TEST(ConsumerConcurrencyLimiterTest, ZeroLimitRejectsAll) {
+  ConsumerConcurrencyLimiter limiter;
+
+  EXPECT_FALSE(limiter.tryAcquire("key-1", 0));
+  EXPECT_EQ(limiter.inFlight("key-1"), 0);
+}
+
+}  // namespace
+}  // namespace service_control
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
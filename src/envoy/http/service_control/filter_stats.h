@@ -35,7 +35,10 @@ namespace service_control {
   COUNTER(denied_consumer_blocked)       \
   COUNTER(denied_consumer_error)         \
   COUNTER(denied_consumer_quota)         \
+  COUNTER(denied_consumer_concurrency)   \
   COUNTER(denied_producer_error)         \
+  COUNTER(report_uncompressed_bytes)     \
+  COUNTER(report_compressed_bytes)       \
   HISTOGRAM(request_time, Milliseconds)  \
   HISTOGRAM(backend_time, Milliseconds)  \
   HISTOGRAM(overhead_time, Milliseconds)
@@ -17,6 +17,7 @@
 #include <chrono>
 
 #include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
 #include "source/common/common/empty_string.h"
 #include "source/common/http/headers.h"
 #include "source/common/http/utility.h"
@@ -65,7 +66,8 @@ ServiceControlHandlerImpl::ServiceControlHandlerImpl(
     const Envoy::Http::RequestHeaderMap& headers,
     Envoy::Http::StreamDecoderFilterCallbacks* decoder_callbacks,
     const std::string& uuid, const FilterConfigParser& cfg_parser,
-    Envoy::TimeSource& time_source, ServiceControlFilterStats& filter_stats)
+    Envoy::TimeSource& time_source, ServiceControlFilterStats& filter_stats,
+    ConsumerConcurrencyLimiter& concurrency_limiter)
     : cfg_parser_(cfg_parser),
       stream_info_(decoder_callbacks->streamInfo()),
       decoder_callbacks_(decoder_callbacks),
@@ -77,7 +79,8 @@ ServiceControlHandlerImpl::ServiceControlHandlerImpl(
       consumer_number_header_(cfg_parser_.config().generated_header_prefix() +
                               kConsumerNumberHeaderSuffix),
       is_grpc_(false),
-      filter_stats_(filter_stats) {
+      filter_stats_(filter_stats),
+      concurrency_limiter_(concurrency_limiter) {
   is_grpc_ = Envoy::Grpc::Common::hasGrpcContentType(headers);
 
   http_method_ = std::string(utils::readHeaderEntry(headers.Method()));
@@ -118,7 +121,9 @@ ServiceControlHandlerImpl::ServiceControlHandlerImpl(
   }
 }
 
-ServiceControlHandlerImpl::~ServiceControlHandlerImpl() {}
+ServiceControlHandlerImpl::~ServiceControlHandlerImpl() {
+  releaseConcurrency();
+}
 
 absl::string_view ServiceControlHandlerImpl::getOperationFromPerRoute() {
   const auto* per_route =
@@ -145,6 +150,7 @@ void ServiceControlHandlerImpl::onDestroy() {
     cancel_fn_();
     cancel_fn_ = nullptr;
   }
+  releaseConcurrency();
 }
 
 void ServiceControlHandlerImpl::fillOperationInfo(
@@ -196,6 +202,15 @@ void ServiceControlHandlerImpl::prepareReportRequest(
 void ServiceControlHandlerImpl::callCheck(
     Envoy::Http::RequestHeaderMap& headers, Envoy::Tracing::Span& parent_span,
     CheckDoneCallback& callback) {
+  // The API key sent in the Authorization header is not forwarded to the
+  // backend.
+  removeAPIKeyFromAuthorization(
+      headers,
+      require_ctx_->config().api_key().locations_size() > 0
+          ? require_ctx_->config().api_key().locations()
+          : cfg_parser_.default_api_keys().locations(),
+      api_key_);
+
   // Don't have per-route config so pass through the request, regarded as the
   // unknown method.
   if (!isConfigured()) {
@@ -254,7 +269,7 @@ void ServiceControlHandlerImpl::callCheck(
 // TODO(taoxuy): add unit test
 void ServiceControlHandlerImpl::callQuota() {
   if (!isQuotaRequired()) {
-    check_callback_->onCheckDone(check_status_, rc_detail_);
+    onCheckAndQuotaDone();
     return;
   }
 
@@ -279,10 +294,58 @@ void ServiceControlHandlerImpl::callQuota() {
               response_info.error.name);
         }
         check_status_ = status;
-        check_callback_->onCheckDone(status, rc_detail_);
+        onCheckAndQuotaDone();
       });
 }
 
+void ServiceControlHandlerImpl::onCheckAndQuotaDone() {
+  if (check_status_.ok() && !acquireConcurrency()) {
+    filter_stats_.filter_.denied_consumer_concurrency_.inc();
+    check_status_ = Status(StatusCode::kResourceExhausted,
+                           "Too many concurrent requests of the API consumer.");
+    rc_detail_ = utils::generateRcDetails(
+        utils::kRcDetailFilterServiceControl,
+        utils::kRcDetailErrorTypeConsumerConcurrency,
+        utils::kRcDetailErrorTooManyConcurrentRequests);
+  }
+  check_callback_->onCheckDone(check_status_, rc_detail_);
+}
+
+bool ServiceControlHandlerImpl::acquireConcurrency() {
+  if (!hasApiKey()) {
+    return true;
+  }
+
+  // The requests of the operations with their own limits are counted
+  // separately.
+  uint32_t limit = cfg_parser_.config().max_concurrent_requests_per_consumer();
+  std::string key = api_key_;
+  if (require_ctx_->config().has_max_concurrent_requests_per_consumer()) {
+    limit =
+        require_ctx_->config().max_concurrent_requests_per_consumer().value();
+    key = absl::StrCat(require_ctx_->config().operation_name(), "/", api_key_);
+  }
+  if (limit == 0) {
+    return true;
+  }
+
+  if (!concurrency_limiter_.tryAcquire(key, limit)) {
+    ENVOY_LOG(debug, "API consumer has {} concurrent requests, rejected",
+              limit);
+    return false;
+  }
+  concurrency_key_ = std::move(key);
+  return true;
+}
+
+void ServiceControlHandlerImpl::releaseConcurrency() {
+  if (concurrency_key_.empty()) {
+    return;
+  }
+  concurrency_limiter_.release(concurrency_key_);
+  concurrency_key_.clear();
+}
+
 void ServiceControlHandlerImpl::onCheckResponse(
     Envoy::Http::RequestHeaderMap& headers, const Status& status,
     const CheckResponseInfo& response_info) {
@@ -29,6 +29,7 @@
 #include "source/common/grpc/common.h"
 #include "src/api_proxy/service_control/request_builder.h"
 #include "src/api_proxy/service_control/request_info.h"
+#include "src/envoy/http/service_control/concurrency_limiter.h"
 #include "src/envoy/http/service_control/config_parser.h"
 #include "src/envoy/http/service_control/handler.h"
 #include "src/envoy/utils/http_header_utils.h"
@@ -47,7 +48,8 @@ class ServiceControlHandlerImpl
       const Envoy::Http::RequestHeaderMap& headers,
       Envoy::Http::StreamDecoderFilterCallbacks* decoder_callbacks,
       const std::string& uuid, const FilterConfigParser& cfg_parser,
-      Envoy::TimeSource& timeSource, ServiceControlFilterStats& filter_stats);
+      Envoy::TimeSource& timeSource, ServiceControlFilterStats& filter_stats,
+      ConsumerConcurrencyLimiter& concurrency_limiter);
   ~ServiceControlHandlerImpl() override;
 
   void callCheck(Envoy::Http::RequestHeaderMap& headers,
@@ -68,6 +70,15 @@ class ServiceControlHandlerImpl
 
   void callQuota();
 
+  // Called when Check and Quota are done, to limit the in-flight requests of
+  // the API consumer before the request is allowed.
+  void onCheckAndQuotaDone();
+
+  // Returns false if the API consumer has too many in-flight requests,
+  // otherwise counts the request until it is released.
+  bool acquireConcurrency();
+  void releaseConcurrency();
+
   void fillOperationInfo(
       ::espv2::api_proxy::service_control::OperationInfo& info);
   void prepareReportRequest(
@@ -142,6 +153,12 @@ class ServiceControlHandlerImpl
 
   // Filter statistics.
   ServiceControlFilterStats& filter_stats_;
+
+  // The in-flight requests of the API consumers, shared by all the requests.
+  ConsumerConcurrencyLimiter& concurrency_limiter_;
+  // The key of the in-flight request counted by concurrency_limiter_, empty
+  // if it is not counted.
+  std::string concurrency_key_;
 };
 
 class ServiceControlHandlerFactoryImpl : public ServiceControlHandlerFactory {
@@ -149,7 +166,10 @@ class ServiceControlHandlerFactoryImpl : public ServiceControlHandlerFactory {
   ServiceControlHandlerFactoryImpl(Envoy::Random::RandomGenerator& random,
                                    const FilterConfigParser& cfg_parser,
                                    Envoy::TimeSource& time_source)
-      : random_(random), cfg_parser_(cfg_parser), time_source_(time_source) {}
+      : random_(random),
+        cfg_parser_(cfg_parser),
+        time_source_(time_source),
+        concurrency_limiter_(std::make_shared<ConsumerConcurrencyLimiter>()) {}
 
   ServiceControlHandlerPtr createHandler(
       const Envoy::Http::RequestHeaderMap& headers,
@@ -157,7 +177,7 @@ class ServiceControlHandlerFactoryImpl : public ServiceControlHandlerFactory {
       ServiceControlFilterStats& filter_stats) const override {
     return std::make_unique<ServiceControlHandlerImpl>(
         headers, decoder_callbacks, random_.uuid(), cfg_parser_, time_source_,
-        filter_stats);
+        filter_stats, *concurrency_limiter_);
   }
 
  private:
@@ -167,6 +187,8 @@ class ServiceControlHandlerFactoryImpl : public ServiceControlHandlerFactory {
   const FilterConfigParser& cfg_parser_;
   // The timeSource
   Envoy::TimeSource& time_source_;
+  // The in-flight requests of the API consumers of all the handlers.
+  ConsumerConcurrencyLimiterSharedPtr concurrency_limiter_;
 };
 
 }  // namespace service_control
@@ -15,6 +15,7 @@
 
 #include "src/envoy/http/service_control/handler_impl.h"
 
+#include "absl/strings/str_cat.h"
 #include "envoy/http/header_map.h"
 #include "gmock/gmock.h"
 #include "google/protobuf/text_format.h"
@@ -217,6 +218,7 @@ class HandlerTest : public ::testing::Test {
   std::shared_ptr<testing::NiceMock<MockRoute>> mock_route_;
   testing::NiceMock<MockServiceControlCallFactory> mock_call_factory_;
   Envoy::Event::SimulatedTimeSystem test_time_;
+  ConsumerConcurrencyLimiter concurrency_limiter_;
 
   // This pointer is managed by cfg_parser
   testing::NiceMock<MockServiceControlCall>* mock_call_;
@@ -356,7 +358,7 @@ TEST_F(HandlerTest, HandlerNoOperationFound) {
   TestRequestHeaderMapImpl headers{{":method", "GET"}, {":path", "/echo"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
   EXPECT_CALL(*mock_call_, callCheck(_, _, _)).Times(0);
@@ -383,7 +385,7 @@ TEST_F(HandlerTest, HandlerMissingHeaders) {
   // simple
   ServiceControlHandlerImpl handler(req_headers_, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
   EXPECT_CALL(*mock_call_, callCheck(_, _, _)).Times(0);
@@ -410,7 +412,7 @@ TEST_F(HandlerTest, HandlerNoRequirementMatched) {
   TestRequestHeaderMapImpl headers{{":method", "GET"}, {":path", "/echo"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
   EXPECT_CALL(*mock_call_, callCheck(_, _, _)).Times(0);
   handler.callCheck(headers, mock_span_, mock_check_done_callback_);
@@ -434,7 +436,7 @@ TEST_F(HandlerTest, HandlerCheckNotNeeded) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   EXPECT_CALL(*mock_call_, callCheck(_, _, _)).Times(0);
   EXPECT_CALL(*mock_call_, callQuota(_, _)).Times(0);
@@ -463,7 +465,7 @@ TEST_F(HandlerTest, RequestHeaderSizeWithModificationInUpstream) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(request_headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
   handler.callCheck(request_headers, mock_span_, mock_check_done_callback_);
 
@@ -490,7 +492,7 @@ TEST_F(HandlerTest, HandlerCheckMissingApiKey) {
 
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   Status bad_status =
       Status(StatusCode::kUnauthenticated,
              "Method doesn't allow unregistered callers (callers without "
@@ -531,7 +533,7 @@ TEST_F(HandlerTest, HandlerSuccessfulCheckSyncWithApiKeyRestrictionFields) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   CheckResponseInfo response_info;
 
   CheckRequestInfo expected_check_info;
@@ -575,7 +577,7 @@ TEST_F(HandlerTest, HandlerSuccessfulCheckSyncWithoutApiKeyRestrictionFields) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   CheckResponseInfo response_info;
 
   CheckRequestInfo expected_check_info;
@@ -616,7 +618,7 @@ TEST_F(HandlerTest, TestClientIPWithForwardHeaders) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   CheckResponseInfo response_info;
 
   CheckRequestInfo expected_check_info;
@@ -658,7 +660,7 @@ TEST_F(HandlerTest, TestClientIPWithOutForwardHeaders) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   CheckResponseInfo response_info;
 
   // The extracted client_ip is default, from stream_info.
@@ -694,7 +696,7 @@ TEST_F(HandlerTest, HandlerSuccessfulQuotaSync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   CheckResponseInfo response_info;
 
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
@@ -733,7 +735,7 @@ TEST_F(HandlerTest, HandlerCallQuotaWithoutCheck) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   // Check is not called.
   EXPECT_CALL(*mock_call_, callCheck(_, _, _)).Times(0);
 
@@ -758,6 +760,103 @@ TEST_F(HandlerTest, HandlerCallQuotaWithoutCheck) {
   handler.callReport(&headers, &response_headers, &resp_trailer_, mock_span_);
 }
 
+TEST_F(HandlerTest, HandlerConsumerConcurrencyLimited) {
+  // Test: The requests of an API key beyond the limit are rejected until its
+  // in-flight requests are done.
+  const std::string filter_config =
+      absl::StrCat(kFilterConfig, "\nmax_concurrent_requests_per_consumer: 1");
+  setUp(filter_config.c_str());
+  setPerRouteOperation("get_header_key");
+  TestRequestHeaderMapImpl headers{
+      {":method", "GET"}, {":path", "/echo"}, {"x-api-key", "foobar"}};
+  EXPECT_CALL(*mock_call_, callCheck(_, _, _))
+      .WillRepeatedly(Invoke([](const CheckRequestInfo&, Envoy::Tracing::Span&,
+                                CheckDoneFunc on_done) {
+        on_done(OkStatus(), CheckResponseInfo());
+        return nullptr;
+      }));
+
+  ServiceControlHandlerImpl handler1(headers, &mock_decoder_callbacks_,
+                                     "test-uuid", *cfg_parser_, test_time_,
+                                     stats_, concurrency_limiter_);
+  EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
+  handler1.callCheck(headers, mock_span_, mock_check_done_callback_);
+
+  ServiceControlHandlerImpl handler2(headers, &mock_decoder_callbacks_,
+                                     "test-uuid", *cfg_parser_, test_time_,
+                                     stats_, concurrency_limiter_);
+  EXPECT_CALL(
+      mock_check_done_callback_,
+      onCheckDone(Status(StatusCode::kResourceExhausted,
+                         "Too many concurrent requests of the API consumer."),
+                  "service_control_consumer_concurrency{TOO_MANY_CONCURRENT_"
+                  "REQUESTS}"));
+  handler2.callCheck(headers, mock_span_, mock_check_done_callback_);
+  checkAndReset(stats_.filter_.denied_consumer_concurrency_, 1);
+
+  // The in-flight request is released when it is done.
+  handler1.onDestroy();
+  ServiceControlHandlerImpl handler3(headers, &mock_decoder_callbacks_,
+                                     "test-uuid", *cfg_parser_, test_time_,
+                                     stats_, concurrency_limiter_);
+  EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
+  handler3.callCheck(headers, mock_span_, mock_check_done_callback_);
+  checkAndReset(stats_.filter_.denied_consumer_concurrency_, 0);
+}
+
+TEST_F(HandlerTest, HandlerConsumerConcurrencyPerOperation) {
+  // Test: The requests of the operations with their own limits are counted
+  // separately from the other operations.
+  const std::string filter_config = absl::StrCat(kFilterConfig, R"(
+requirements {
+  service_name: "echo"
+  operation_name: "get_header_key_limited"
+  api_key: {
+    locations: {
+      header: "x-api-key"
+    }
+  }
+  max_concurrent_requests_per_consumer: {
+    value: 1
+  }
+})");
+  setUp(filter_config.c_str());
+  TestRequestHeaderMapImpl headers{
+      {":method", "GET"}, {":path", "/echo"}, {"x-api-key", "foobar"}};
+  EXPECT_CALL(*mock_call_, callCheck(_, _, _))
+      .WillRepeatedly(Invoke([](const CheckRequestInfo&, Envoy::Tracing::Span&,
+                                CheckDoneFunc on_done) {
+        on_done(OkStatus(), CheckResponseInfo());
+        return nullptr;
+      }));
+
+  setPerRouteOperation("get_header_key_limited");
+  ServiceControlHandlerImpl handler1(headers, &mock_decoder_callbacks_,
+                                     "test-uuid", *cfg_parser_, test_time_,
+                                     stats_, concurrency_limiter_);
+  EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
+  handler1.callCheck(headers, mock_span_, mock_check_done_callback_);
+
+  ServiceControlHandlerImpl handler2(headers, &mock_decoder_callbacks_,
+                                     "test-uuid", *cfg_parser_, test_time_,
+                                     stats_, concurrency_limiter_);
+  EXPECT_CALL(
+      mock_check_done_callback_,
+      onCheckDone(Status(StatusCode::kResourceExhausted,
+                         "Too many concurrent requests of the API consumer."),
+                  _));
+  handler2.callCheck(headers, mock_span_, mock_check_done_callback_);
+
+  // The other operations are not limited.
+  setPerRouteOperation("get_header_key");
+  ServiceControlHandlerImpl handler3(headers, &mock_decoder_callbacks_,
+                                     "test-uuid", *cfg_parser_, test_time_,
+                                     stats_, concurrency_limiter_);
+  EXPECT_CALL(mock_check_done_callback_, onCheckDone(OkStatus(), ""));
+  handler3.callCheck(headers, mock_span_, mock_check_done_callback_);
+  checkAndReset(stats_.filter_.denied_consumer_concurrency_, 1);
+}
+
 TEST_F(HandlerTest, HandlerFailCheckSync) {
   // Test: Check is required and a request is made, but service control
   // returns a bad status.
@@ -768,7 +867,7 @@ TEST_F(HandlerTest, HandlerFailCheckSync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   Status bad_status = Status(StatusCode::kPermissionDenied,
                              "test bad status returned from service control");
@@ -815,7 +914,7 @@ TEST_F(HandlerTest, FillFilterState) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   handler.fillFilterState(*mock_decoder_callbacks_.stream_info_.filter_state_);
 
@@ -839,7 +938,7 @@ TEST_F(HandlerTest, HandlerFailQuotaSync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   CheckResponseInfo response_info;
 
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
@@ -880,7 +979,7 @@ TEST_F(HandlerTest, HandlerSuccessfulCheckAsync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   CheckResponseInfo response_info;
 
@@ -923,7 +1022,7 @@ TEST_F(HandlerTest, HandlerSuccessfulQuotaAsync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   CheckResponseInfo response_info;
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
@@ -974,7 +1073,7 @@ TEST_F(HandlerTest, HandlerFailCheckAsync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   CheckResponseInfo response_info;
   response_info.error = {"API_KEY_INVALID", false,
@@ -1026,7 +1125,7 @@ TEST_F(HandlerTest, HandlerFailQuotaAsync) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   CheckResponseInfo response_info;
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
@@ -1085,7 +1184,7 @@ TEST_F(HandlerTest, HandlerCancelFuncResetOnDone) {
 
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
       .WillOnce(Invoke([&stored_on_done, cancel_fn](const CheckRequestInfo&,
                                                     Envoy::Tracing::Span&,
@@ -1112,7 +1211,7 @@ TEST_F(HandlerTest, HandlerCancelFuncCalledOnDestroy) {
 
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
       .WillOnce(
           Invoke([cancel_fn](const CheckRequestInfo&, Envoy::Tracing::Span&,
@@ -1134,7 +1233,7 @@ TEST_F(HandlerTest, HandlerCancelFuncNotCalledOnDestroyForSyncOnDone) {
 
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
   EXPECT_CALL(*mock_call_, callCheck(_, _, _))
       .WillOnce(
           Invoke([cancel_fn](const CheckRequestInfo&, Envoy::Tracing::Span&,
@@ -1162,7 +1261,7 @@ TEST_F(HandlerTest, HandlerReportWithoutCheck) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   ReportRequestInfo expected_report_info;
   initExpectedReportInfo(expected_report_info);
@@ -1188,7 +1287,7 @@ TEST_F(HandlerTest, HandlerReportWithoutTraceId) {
       {"content-type", "application/grpc"}};
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   ReportRequestInfo expected_report_info;
   initExpectedReportInfo(expected_report_info);
@@ -1212,7 +1311,7 @@ TEST_F(HandlerTest, HandlerReportWithTrace) {
   CheckResponseInfo response_info;
   ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                     "test-uuid", *cfg_parser_, test_time_,
-                                    stats_);
+                                    stats_, concurrency_limiter_);
 
   ReportRequestInfo expected_report_info;
   initExpectedReportInfo(expected_report_info);
@@ -1241,7 +1340,7 @@ class HandlerReportStatusTest : public HandlerTest {
     CheckResponseInfo response_info;
     ServiceControlHandlerImpl handler(headers, &mock_decoder_callbacks_,
                                       "test-uuid", *cfg_parser_, test_time_,
-                                      stats_);
+                                      stats_, concurrency_limiter_);
 
     ReportRequestInfo expected_report_info;
     initExpectedReportInfo(expected_report_info);
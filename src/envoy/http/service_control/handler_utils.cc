@@ -17,6 +17,7 @@
 #include <sstream>
 #include <vector>
 
+#include "absl/strings/ascii.h"
 #include "absl/strings/match.h"
 #include "absl/strings/str_cat.h"
 #include "absl/strings/str_split.h"
@@ -25,10 +26,12 @@
 #include "envoy/grpc/status.h"
 #include "envoy/http/header_map.h"
 #include "envoy/server/filter_config.h"
+#include "source/common/common/base64.h"
 #include "source/common/common/empty_string.h"
 #include "source/common/common/logger.h"
 #include "source/common/grpc/common.h"
 #include "source/common/http/header_utility.h"
+#include "source/common/http/headers.h"
 #include "source/common/http/utility.h"
 #include "source/common/network/utility.h"
 #include "source/common/stream_info/utility.h"
@@ -58,6 +61,9 @@ const Envoy::Http::LowerCaseString kForwardedHeader{"forwarded"};
 
 constexpr absl::string_view kForwardedIPTokenPrefix{"for="};
 
+constexpr absl::string_view kBasicAuthPrefix{"Basic "};
+constexpr absl::string_view kBearerTokenPrefix{"Bearer "};
+
 inline int64_t convertNsToMs(std::chrono::nanoseconds ns) {
   return std::chrono::duration_cast<std::chrono::milliseconds>(ns).count();
 }
@@ -107,6 +113,52 @@ bool extractAPIKeyFromCookie(const Envoy::Http::RequestHeaderMap& headers,
   return false;
 }
 
+// Returns the credentials of the `Authorization` header with the scheme, such
+// as "Basic ", or an empty string.
+absl::string_view getAuthorizationCredentials(
+    const Envoy::Http::RequestHeaderMap& headers, absl::string_view scheme) {
+  auto entry = headers.get(Envoy::Http::CustomHeaders::get().Authorization);
+  if (entry.empty()) {
+    return "";
+  }
+  absl::string_view value = entry[0]->value().getStringView();
+  if (!absl::StartsWithIgnoreCase(value, scheme)) {
+    return "";
+  }
+  return absl::StripAsciiWhitespace(value.substr(scheme.size()));
+}
+
+bool extractAPIKeyFromBasicAuth(const Envoy::Http::RequestHeaderMap& headers,
+                                std::string& api_key) {
+  absl::string_view credentials =
+      getAuthorizationCredentials(headers, kBasicAuthPrefix);
+  if (credentials.empty()) {
+    return false;
+  }
+
+  // The decoded credentials are "username:password", the password is ignored.
+  const std::string decoded = Envoy::Base64::decode(credentials);
+  absl::string_view username =
+      absl::string_view(decoded).substr(0, decoded.find(':'));
+  if (username.empty()) {
+    return false;
+  }
+  api_key = std::string(username);
+  return true;
+}
+
+bool extractAPIKeyFromBearerToken(const Envoy::Http::RequestHeaderMap& headers,
+                                  const std::string& prefix,
+                                  std::string& api_key) {
+  absl::string_view token =
+      getAuthorizationCredentials(headers, kBearerTokenPrefix);
+  if (token.size() <= prefix.size() || !absl::StartsWith(token, prefix)) {
+    return false;
+  }
+  api_key = std::string(token);
+  return true;
+}
+
 void extractJwtPayload(const Envoy::ProtobufWkt::Value& value,
                        const std::string& jwt_payload_path,
                        std::string& info_jwt_payloads) {
@@ -162,6 +214,10 @@ void fillGCPInfo(
   if (!gcp_attributes.project_id().empty()) {
     info.gcp_project_id = gcp_attributes.project_id();
   }
+
+  for (const auto& label : gcp_attributes.deployment_labels()) {
+    info.deployment_labels[label.first] = label.second;
+  }
 }
 
 void fillLoggedHeader(
@@ -328,6 +384,14 @@ bool extractAPIKey(
         if (extractAPIKeyFromCookie(headers, location.cookie(), api_key))
           return true;
         break;
+      case ApiKeyLocation::kBasicAuthUsername:
+        if (extractAPIKeyFromBasicAuth(headers, api_key)) return true;
+        break;
+      case ApiKeyLocation::kBearerTokenPrefix:
+        if (extractAPIKeyFromBearerToken(
+                headers, location.bearer_token_prefix(), api_key))
+          return true;
+        break;
       case ApiKeyLocation::KEY_NOT_SET:
         break;
     }
@@ -335,6 +399,36 @@ bool extractAPIKey(
   return false;
 }
 
+void removeAPIKeyFromAuthorization(
+    Envoy::Http::RequestHeaderMap& headers,
+    const ::google::protobuf::RepeatedPtrField<
+        ::espv2::api::envoy::v12::http::service_control::ApiKeyLocation>&
+        locations,
+    const std::string& api_key) {
+  if (api_key.empty()) {
+    return;
+  }
+
+  for (const auto& location : locations) {
+    std::string key;
+    switch (location.key_case()) {
+      case ApiKeyLocation::kBasicAuthUsername:
+        extractAPIKeyFromBasicAuth(headers, key);
+        break;
+      case ApiKeyLocation::kBearerTokenPrefix:
+        extractAPIKeyFromBearerToken(headers, location.bearer_token_prefix(),
+                                     key);
+        break;
+      default:
+        break;
+    }
+    if (key == api_key) {
+      headers.remove(Envoy::Http::CustomHeaders::get().Authorization);
+      return;
+    }
+  }
+}
+
 void fillStatus(const Envoy::Http::ResponseHeaderMap* response_headers,
                 const Envoy::Http::ResponseTrailerMap* response_trailers,
                 const Envoy::StreamInfo::StreamInfo& stream_info,
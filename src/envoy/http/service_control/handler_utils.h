@@ -39,6 +39,15 @@ bool extractAPIKey(
         locations,
     std::string& api_key);
 
+// Removes the `Authorization` header if the `api_key` was found in it at the
+// given locations, so the API key is not forwarded to the backend.
+void removeAPIKeyFromAuthorization(
+    Envoy::Http::RequestHeaderMap& headers,
+    const ::google::protobuf::RepeatedPtrField<
+        ::espv2::api::envoy::v12::http::service_control::ApiKeyLocation>&
+        locations,
+    const std::string& api_key);
+
 // Adds information from the `FilterConfig`'s gcp_attributes to the given info.
 void fillGCPInfo(
     const ::espv2::api::envoy::v12::http::service_control::FilterConfig&
@@ -17,6 +17,7 @@
 #include <memory>
 
 #include "envoy/event/deferred_deletable.h"
+#include "source/common/buffer/buffer_impl.h"
 #include "source/common/common/empty_string.h"
 #include "source/common/common/enum_to_int.h"
 #include "source/common/grpc/status.h"
@@ -24,6 +25,7 @@
 #include "source/common/http/message_impl.h"
 #include "source/common/http/utility.h"
 #include "source/common/tracing/http_tracer_impl.h"
+#include "source/extensions/compression/gzip/compressor/zlib_compressor_impl.h"
 
 using ::absl::OkStatus;
 using ::absl::Status;
@@ -32,6 +34,7 @@ using Envoy::Http::CustomHeaders;
 using Envoy::Http::CustomInlineHeaderRegistry;
 using Envoy::Http::RegisterCustomInlineHeader;
 using ::espv2::api::envoy::v12::http::common::HttpUri;
+using Envoy::Extensions::Compression::Gzip::Compressor::ZlibCompressorImpl;
 
 namespace espv2 {
 namespace envoy {
@@ -44,6 +47,20 @@ constexpr absl::string_view KApplicationProto = "application/x-protobuf";
 RegisterCustomInlineHeader<CustomInlineHeaderRegistry::Type::RequestHeaders>
     authorization_handle(CustomHeaders::get().Authorization);
 
+// The zlib window bits of 15 plus 16 to write the gzip header and trailer.
+constexpr int64_t kGzipWindowBits = 31;
+constexpr uint64_t kGzipMemoryLevel = 8;
+
+std::string gzipCompress(const std::string& data) {
+  ZlibCompressorImpl compressor;
+  compressor.init(ZlibCompressorImpl::CompressionLevel::Standard,
+                  ZlibCompressorImpl::CompressionStrategy::Standard,
+                  kGzipWindowBits, kGzipMemoryLevel);
+  Envoy::Buffer::OwnedImpl buffer(data);
+  compressor.compress(buffer, Envoy::Compression::Compressor::State::Finish);
+  return buffer.toString();
+}
+
 class HttpCallImpl : public HttpCall,
                      public Envoy::Event::DeferredDeletable,
                      public Envoy::Logger::Loggable<Envoy::Logger::Id::filter>,
@@ -56,7 +73,8 @@ class HttpCallImpl : public HttpCall,
                const Envoy::Protobuf::Message& body, uint32_t timeout_ms,
                uint32_t retries, Envoy::Tracing::Span& parent_span,
                Envoy::TimeSource& time_source,
-               const std::string& trace_operation_name)
+               const std::string& trace_operation_name, bool compress_body,
+               const HttpCall::BodyCompressedFunc& on_body_compressed)
       : cm_(cm),
         dispatcher_(dispatcher),
         http_uri_(uri),
@@ -67,11 +85,19 @@ class HttpCallImpl : public HttpCall,
         token_fn_(token_fn),
         parent_span_(parent_span),
         time_source_(time_source),
-        trace_operation_name_(trace_operation_name) {
+        trace_operation_name_(trace_operation_name),
+        compress_body_(compress_body) {
     uri_ = http_uri_.uri() + suffix_url;
 
     Envoy::Http::Utility::extractHostPathFromUri(uri_, host_, path_);
     body.SerializeToString(&str_body_);
+    if (compress_body_) {
+      const uint64_t uncompressed_bytes = str_body_.size();
+      str_body_ = gzipCompress(str_body_);
+      if (on_body_compressed) {
+        on_body_compressed(uncompressed_bytes, str_body_.size());
+      }
+    }
 
     ASSERT(!on_done_);
     ENVOY_LOG(trace, "{}", __func__);
@@ -261,6 +287,11 @@ class HttpCallImpl : public HttpCall,
     message->headers().setInline(authorization_handle.handle(),
                                  "Bearer " + token);
     message->headers().setContentType(KApplicationProto);
+    if (compress_body_) {
+      message->headers().addReferenceKey(
+          CustomHeaders::get().ContentEncoding,
+          CustomHeaders::get().ContentEncodingValues.Gzip);
+    }
     return message;
   }
 
@@ -282,6 +313,8 @@ class HttpCallImpl : public HttpCall,
 
   // The serialized request body
   std::string str_body_;
+  // Whether the request body is compressed with gzip
+  const bool compress_body_;
 
   // The request uri
   std::string uri_;
@@ -327,16 +360,24 @@ HttpCallFactoryImpl::HttpCallFactoryImpl(
       timeout_ms_(timeout_ms),
       retries_(retries),
       destruct_mode_(false),
+      compress_body_(false),
       time_source_(time_source),
       trace_operation_name_(trace_operation_name){};
 
+void HttpCallFactoryImpl::enableBodyCompression(
+    HttpCall::BodyCompressedFunc on_body_compressed) {
+  compress_body_ = true;
+  on_body_compressed_ = on_body_compressed;
+}
+
 HttpCall* HttpCallFactoryImpl::createHttpCall(
     const Envoy::Protobuf::Message& body, Envoy::Tracing::Span& parent_span,
     HttpCall::DoneFunc on_done) {
   ENVOY_LOG(debug, "{} is created", trace_operation_name_);
   HttpCallImpl* http_call = new HttpCallImpl(
       cm_, dispatcher_, uri_, suffix_url_, token_fn_, body, timeout_ms_,
-      retries_, parent_span, time_source_, trace_operation_name_);
+      retries_, parent_span, time_source_, trace_operation_name_,
+      compress_body_, on_body_compressed_);
   http_call->setDoneFunc([this, on_done, http_call](const Status& status,
                                                     const std::string& body) {
     // When the call is finished, it should be removed from active_calls_ .
@@ -28,6 +28,10 @@ class HttpCall {
  public:
   using DoneFunc = std::function<void(const absl::Status& status,
                                       const std::string& response_body)>;
+  // Called with the sizes of the request body before and after the
+  // compression.
+  using BodyCompressedFunc = std::function<void(uint64_t uncompressed_bytes,
+                                                uint64_t compressed_bytes)>;
 
   virtual ~HttpCall() {}
   /*
@@ -64,6 +68,9 @@ class HttpCallFactoryImpl : public HttpCallFactory {
 
   ~HttpCallFactoryImpl();
 
+  // Compresses the request bodies of the created calls with gzip.
+  void enableBodyCompression(HttpCall::BodyCompressedFunc on_body_compressed);
+
  private:
   // all active calls generated by this factory
   absl::flat_hash_set<HttpCall*> active_calls_;
@@ -83,6 +90,10 @@ class HttpCallFactoryImpl : public HttpCallFactory {
   uint32_t timeout_ms_;
   uint32_t retries_;
 
+  // whether the request bodies are compressed with gzip
+  bool compress_body_;
+  HttpCall::BodyCompressedFunc on_body_compressed_;
+
   // whether the factory is being destructed
   bool destruct_mode_;
 
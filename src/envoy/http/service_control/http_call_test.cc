@@ -189,6 +189,51 @@ TEST_F(HttpCallTest, TestSingleCallSuccessHttpOk) {
                                  makeResponseWithStatus(200));
 }
 
+TEST_F(HttpCallTest, TestCompressedBody) {
+  fake_request_.set_service_name("echo.endpoints.project.cloud.goog");
+  uint64_t uncompressed_bytes = 0;
+  uint64_t compressed_bytes = 0;
+  http_call_factory_->enableBodyCompression(
+      [&](uint64_t uncompressed, uint64_t compressed) {
+        uncompressed_bytes = uncompressed;
+        compressed_bytes = compressed;
+      });
+
+  std::string sent_body;
+  EXPECT_CALL(http_client_, send_(_, _, _))
+      .WillOnce(Invoke([&](Envoy::Http::RequestMessagePtr& message_ptr,
+                           Envoy::Http::AsyncClient::Callbacks& callbacks,
+                           const Envoy::Http::AsyncClient::RequestOptions)
+                           -> Envoy::Http::AsyncClient::Request* {
+        auto encoding_header = message_ptr->headers().get(
+            Envoy::Http::CustomHeaders::get().ContentEncoding);
+        EXPECT_EQ(encoding_header[0]->value().getStringView(), "gzip");
+        sent_body = message_ptr->bodyAsString();
+
+        async_callbacks_.push_back(&callbacks);
+        auto request =
+            new NiceMock<Envoy::Http::MockAsyncClientRequest>(&http_client_);
+        http_requests_.push_back(request);
+        return request;
+      }));
+
+  auto mock_child_span = makeMockChildSpan();
+  HttpCall* call = http_call_factory_->createHttpCall(
+      fake_request_, mock_parent_span_, mock_done_fn_.AsStdFunction());
+  call->call();
+
+  // The body is sent in the gzip format, starting with the magic bytes.
+  ASSERT_GE(sent_body.size(), 2);
+  EXPECT_EQ(sent_body.substr(0, 2), "\x1f\x8b");
+  EXPECT_EQ(uncompressed_bytes, fake_request_.ByteSizeLong());
+  EXPECT_EQ(compressed_bytes, sent_body.size());
+
+  EXPECT_CALL(*mock_child_span, finishSpan()).Times(1);
+  EXPECT_CALL(mock_done_fn_, Call(OkStatus(), _)).Times(1);
+  async_callbacks_[0]->onSuccess(lastHttpRequest(),
+                                 makeResponseWithStatus(200));
+}
+
 TEST_F(HttpCallTest, TestSingleCallSuccessHttpNotFound) {
   // Phase 1: Create HttpCall and send the request
   auto mock_child_span = makeMockChildSpan();
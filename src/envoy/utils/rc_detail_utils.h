@@ -25,6 +25,7 @@ namespace utils {
 const char kRcDetailFilterServiceControl[] = "service_control";
 const char kRcDetailFilterBackendAuth[] = "backend_auth";
 const char kRcDetailFilterPathRewrite[] = "path_rewrite";
+const char kRcDetailFilterClientIpRateLimit[] = "client_ip_rate_limit";
 
 // The error types
 //
@@ -36,10 +37,13 @@ const char kRcDetailErrorTypeScCheck[] = "check_error";
 const char kRcDetailErrorTypeScQuota[] = "quota_error";
 const char kRcDetailErrorTypeScCheckNetwork[] = "check_network_failure";
 const char kRcDetailErrorTypeScQuotaNetwork[] = "quota_network_failure";
+const char kRcDetailErrorTypeConsumerConcurrency[] = "consumer_concurrency";
 // The ones specific to the backend auth filter
 const char kRcDetailErrorTypeMissingBackendToken[] = "missing_backend_token";
 // The ones specific to the path rewrite filter
 const char kRcDetailErrorTypeWrongRouteConfig[] = "wrong_route_config";
+// The ones specific to the client IP rate limit filter
+const char kRcDetailErrorTypeRateLimited[] = "rate_limited";
 
 // The detailed errors.
 const char kRcDetailErrorMissingApiKey[] = "MISSING_API_KEY";
@@ -47,6 +51,8 @@ const char kRcDetailErrorMissingMethod[] = "MISSING_METHOD";
 const char kRcDetailErrorMissingPath[] = "MISSING_PATH";
 const char kRcDetailErrorOversizePath[] = "OVERSIZE_PATH";
 const char kRcDetailErrorFragmentIdentifier[] = "PATH_WITH_FRAGMENT_IDENTIFIER";
+const char kRcDetailErrorTooManyConcurrentRequests[] =
+    "TOO_MANY_CONCURRENT_REQUESTS";
 
 // Generate a string for response code details in format of
 // `filter_name`_`error_type`_{`error_detail`}.
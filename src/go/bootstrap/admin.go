@@ -15,7 +15,10 @@
 package bootstrap
 
 import (
+	"strings"
+
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 
 	bootstrappb "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -23,19 +26,49 @@ import (
 
 // CreateAdmin outputs Admin struct for bootstrap config
 func CreateAdmin(opts options.CommonOptions) *bootstrappb.Admin {
-
-	if opts.AdminPort == 0 {
+	address := CreateAdminAddress(opts)
+	if address == nil {
 		return &bootstrappb.Admin{}
 	}
 
 	return &bootstrappb.Admin{
-		Address: &corepb.Address{
-			Address: &corepb.Address_SocketAddress{
-				SocketAddress: &corepb.SocketAddress{
-					Address: opts.AdminAddress,
-					PortSpecifier: &corepb.SocketAddress_PortValue{
-						PortValue: uint32(opts.AdminPort),
-					},
+		Address: address,
+	}
+}
+
+// CreateAdminAddress outputs the address the admin interface listens on, nil
+// if the admin interface is disabled.
+func CreateAdminAddress(opts options.CommonOptions) *corepb.Address {
+	if opts.AdminSocketPath != "" {
+		return &corepb.Address{
+			Address: &corepb.Address_Pipe{
+				Pipe: &corepb.Pipe{
+					Path: opts.AdminSocketPath,
+					// Only the owner can access the admin interface.
+					Mode: 0600,
+				},
+			},
+		}
+	}
+
+	if opts.AdminPort == 0 {
+		return nil
+	}
+
+	address := opts.AdminAddress
+	if opts.AdminLoopbackOnly {
+		address = util.LoopbackIPv4Addr
+		if strings.Contains(opts.AdminAddress, ":") {
+			address = util.LoopbackIPv6Addr
+		}
+	}
+
+	return &corepb.Address{
+		Address: &corepb.Address_SocketAddress{
+			SocketAddress: &corepb.SocketAddress{
+				Address: address,
+				PortSpecifier: &corepb.SocketAddress_PortValue{
+					PortValue: uint32(opts.AdminPort),
 				},
 			},
 		},
@@ -26,9 +26,12 @@ import (
 
 func TestCreateAdmin(t *testing.T) {
 	testData := []struct {
-		desc      string
-		adminPort int
-		want      *bootstrappb.Admin
+		desc              string
+		adminAddress      string
+		adminPort         int
+		adminSocketPath   string
+		adminLoopbackOnly bool
+		want              *bootstrappb.Admin
 	}{
 		{
 			desc:      "Admin interface is disabled",
@@ -51,12 +54,67 @@ func TestCreateAdmin(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc:              "Admin interface is bound to the IPv4 loopback address",
+			adminPort:         8081,
+			adminLoopbackOnly: true,
+			want: &bootstrappb.Admin{
+				Address: &corepb.Address{
+					Address: &corepb.Address_SocketAddress{
+						SocketAddress: &corepb.SocketAddress{
+							Address: "127.0.0.1",
+							PortSpecifier: &corepb.SocketAddress_PortValue{
+								PortValue: 8081,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:              "Admin interface is bound to the IPv6 loopback address",
+			adminAddress:      "::",
+			adminPort:         8081,
+			adminLoopbackOnly: true,
+			want: &bootstrappb.Admin{
+				Address: &corepb.Address{
+					Address: &corepb.Address_SocketAddress{
+						SocketAddress: &corepb.SocketAddress{
+							Address: "::1",
+							PortSpecifier: &corepb.SocketAddress_PortValue{
+								PortValue: 8081,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:            "Admin interface is bound to a unix domain socket",
+			adminPort:       8081,
+			adminSocketPath: "/var/run/espv2/admin.sock",
+			want: &bootstrappb.Admin{
+				Address: &corepb.Address{
+					Address: &corepb.Address_Pipe{
+						Pipe: &corepb.Pipe{
+							Path: "/var/run/espv2/admin.sock",
+							Mode: 0600,
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testData {
 
 		opts := options.DefaultCommonOptions()
+		if tc.adminAddress != "" {
+			opts.AdminAddress = tc.adminAddress
+		}
 		opts.AdminPort = tc.adminPort
+		opts.AdminSocketPath = tc.adminSocketPath
+		opts.AdminLoopbackOnly = tc.adminLoopbackOnly
 
 		got := CreateAdmin(opts)
 
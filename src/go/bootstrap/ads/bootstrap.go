@@ -33,6 +33,11 @@ func CreateBootstrapConfig(opts options.AdsBootstrapperOptions) (string, error)
 	// Parse ADS connect timeout
 	connectTimeoutProto := durationpb.New(opts.AdsConnectTimeout)
 
+	overloadManager, err := bt.CreateOverloadManager(opts.CommonOptions)
+	if err != nil {
+		return "", err
+	}
+
 	bt := &bootstrappb.Bootstrap{
 		// Node info
 		Node: bt.CreateNode(opts.CommonOptions),
@@ -43,6 +48,12 @@ func CreateBootstrapConfig(opts options.AdsBootstrapperOptions) (string, error)
 		// layer runtime
 		LayeredRuntime: bt.CreateLayeredRuntime(),
 
+		// stats
+		StatsConfig: bt.CreateStatsConfig(opts.CommonOptions),
+
+		// overload manager
+		OverloadManager: overloadManager,
+
 		// Dynamic resource
 		DynamicResources: &bootstrappb.Bootstrap_DynamicResources{
 			LdsConfig: &corepb.ConfigSource{
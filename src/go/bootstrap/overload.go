@@ -0,0 +1,121 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	overloadpb "github.com/envoyproxy/go-control-plane/envoy/config/overload/v3"
+	connectionspb "github.com/envoyproxy/go-control-plane/envoy/extensions/resource_monitors/downstream_connections/v3"
+	heappb "github.com/envoyproxy/go-control-plane/envoy/extensions/resource_monitors/fixed_heap/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	fixedHeapMonitorName             = "envoy.resource_monitors.fixed_heap"
+	downstreamConnectionsMonitorName = "envoy.resource_monitors.global_downstream_max_connections"
+
+	shrinkHeapActionName            = "envoy.overload_actions.shrink_heap"
+	stopAcceptingRequestsActionName = "envoy.overload_actions.stop_accepting_requests"
+
+	overloadRefreshInterval = 250 * time.Millisecond
+)
+
+// CreateOverloadManager outputs OverloadManager struct for bootstrap config,
+// nil if neither the downstream connections nor the heap size is limited.
+func CreateOverloadManager(opts options.CommonOptions) (*overloadpb.OverloadManager, error) {
+	if opts.MaxDownstreamConnections == 0 && opts.OverloadMaxHeapSizeBytes == 0 {
+		return nil, nil
+	}
+
+	manager := &overloadpb.OverloadManager{
+		RefreshInterval: durationpb.New(overloadRefreshInterval),
+	}
+
+	if opts.MaxDownstreamConnections < 0 {
+		return nil, fmt.Errorf("invalid flag --max_downstream_connections %d, must be >= 0", opts.MaxDownstreamConnections)
+	}
+	if opts.MaxDownstreamConnections > 0 {
+		monitor, err := makeResourceMonitor(downstreamConnectionsMonitorName, &connectionspb.DownstreamConnectionsConfig{
+			MaxActiveDownstreamConnections: opts.MaxDownstreamConnections,
+		})
+		if err != nil {
+			return nil, err
+		}
+		manager.ResourceMonitors = append(manager.ResourceMonitors, monitor)
+	}
+
+	if opts.OverloadMaxHeapSizeBytes > 0 {
+		monitor, err := makeResourceMonitor(fixedHeapMonitorName, &heappb.FixedHeapConfig{
+			MaxHeapSizeBytes: opts.OverloadMaxHeapSizeBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		manager.ResourceMonitors = append(manager.ResourceMonitors, monitor)
+
+		for _, action := range []struct {
+			name      string
+			flagName  string
+			threshold float64
+		}{
+			{
+				name:      shrinkHeapActionName,
+				flagName:  "overload_shrink_heap_threshold",
+				threshold: opts.OverloadShrinkHeapThreshold,
+			},
+			{
+				name:      stopAcceptingRequestsActionName,
+				flagName:  "overload_stop_accepting_requests_threshold",
+				threshold: opts.OverloadStopAcceptingRequestsThreshold,
+			},
+		} {
+			if action.threshold <= 0 || action.threshold > 1 {
+				return nil, fmt.Errorf("invalid flag --%s %v, must be in (0, 1]", action.flagName, action.threshold)
+			}
+			manager.Actions = append(manager.Actions, &overloadpb.OverloadAction{
+				Name: action.name,
+				Triggers: []*overloadpb.Trigger{
+					{
+						Name: fixedHeapMonitorName,
+						TriggerOneof: &overloadpb.Trigger_Threshold{
+							Threshold: &overloadpb.ThresholdTrigger{
+								Value: action.threshold,
+							},
+						},
+					},
+				},
+			})
+		}
+	}
+	return manager, nil
+}
+
+func makeResourceMonitor(name string, config proto.Message) (*overloadpb.ResourceMonitor, error) {
+	typedConfig, err := anypb.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal resource monitor %q: %v", name, err)
+	}
+	return &overloadpb.ResourceMonitor{
+		Name: name,
+		ConfigType: &overloadpb.ResourceMonitor_TypedConfig{
+			TypedConfig: typedConfig,
+		},
+	}, nil
+}
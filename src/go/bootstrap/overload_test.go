@@ -0,0 +1,145 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+func TestCreateOverloadManager(t *testing.T) {
+	testData := []struct {
+		desc      string
+		opts      options.CommonOptions
+		wantJson  string
+		wantError string
+	}{
+		{
+			desc: "Disabled by default",
+			opts: options.DefaultCommonOptions(),
+		},
+		{
+			desc: "Global downstream connections are limited",
+			opts: options.CommonOptions{
+				MaxDownstreamConnections: 1000,
+			},
+			wantJson: `{
+  "refreshInterval": "0.250s",
+  "resourceMonitors": [
+    {
+      "name": "envoy.resource_monitors.global_downstream_max_connections",
+      "typedConfig": {
+        "@type": "type.googleapis.com/envoy.extensions.resource_monitors.downstream_connections.v3.DownstreamConnectionsConfig",
+        "maxActiveDownstreamConnections": "1000"
+      }
+    }
+  ]
+}`,
+		},
+		{
+			desc: "Heap actions are triggered at the thresholds",
+			opts: options.CommonOptions{
+				OverloadMaxHeapSizeBytes:               536870912,
+				OverloadShrinkHeapThreshold:            0.9,
+				OverloadStopAcceptingRequestsThreshold: 0.95,
+			},
+			wantJson: `{
+  "refreshInterval": "0.250s",
+  "resourceMonitors": [
+    {
+      "name": "envoy.resource_monitors.fixed_heap",
+      "typedConfig": {
+        "@type": "type.googleapis.com/envoy.extensions.resource_monitors.fixed_heap.v3.FixedHeapConfig",
+        "maxHeapSizeBytes": "536870912"
+      }
+    }
+  ],
+  "actions": [
+    {
+      "name": "envoy.overload_actions.shrink_heap",
+      "triggers": [
+        {
+          "name": "envoy.resource_monitors.fixed_heap",
+          "threshold": {
+            "value": 0.9
+          }
+        }
+      ]
+    },
+    {
+      "name": "envoy.overload_actions.stop_accepting_requests",
+      "triggers": [
+        {
+          "name": "envoy.resource_monitors.fixed_heap",
+          "threshold": {
+            "value": 0.95
+          }
+        }
+      ]
+    }
+  ]
+}`,
+		},
+		{
+			desc: "Negative downstream connections",
+			opts: options.CommonOptions{
+				MaxDownstreamConnections: -1,
+			},
+			wantError: "invalid flag --max_downstream_connections -1, must be >= 0",
+		},
+		{
+			desc: "Threshold out of range",
+			opts: options.CommonOptions{
+				OverloadMaxHeapSizeBytes:               536870912,
+				OverloadShrinkHeapThreshold:            1.5,
+				OverloadStopAcceptingRequestsThreshold: 0.95,
+			},
+			wantError: "invalid flag --overload_shrink_heap_threshold 1.5, must be in (0, 1]",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := CreateOverloadManager(tc.opts)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("CreateOverloadManager() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOverloadManager() got error: %v", err)
+			}
+
+			if tc.wantJson == "" {
+				if got != nil {
+					t.Errorf("want no overload manager, got %v", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("fail to marshal overload manager: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantJson, gotJson); err != nil {
+				t.Errorf("CreateOverloadManager() got diff: %v", err)
+			}
+		})
+	}
+}
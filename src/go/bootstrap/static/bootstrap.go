@@ -15,14 +15,10 @@
 package static
 
 import (
-	"fmt"
-
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/bootstrap"
-	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 
-	gen "github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator"
-	sc "github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
 	bootstrappb "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
 )
@@ -31,34 +27,27 @@ import (
 // id is the service configuration ID. It is generated when deploying
 // service config to ServiceManagement Server, example: 2017-02-13r0.
 func ServiceToBootstrapConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (*bootstrappb.Bootstrap, error) {
-	bt := &bootstrappb.Bootstrap{
-		Node:           bootstrap.CreateNode(opts.CommonOptions),
-		Admin:          bootstrap.CreateAdmin(opts.CommonOptions),
-		LayeredRuntime: bootstrap.CreateLayeredRuntime(),
-	}
-
-	serviceInfo, err := sc.NewServiceInfoFromServiceConfig(serviceConfig, opts)
-	if err != nil {
-		return nil, fmt.Errorf("fail to initialize ServiceInfo, %s", err)
-	}
-
-	clusterGensFactories := gen.GetESPv2ClusterGenFactories()
-	clusterGens, err := gen.NewClusterGeneratorsFromOPConfig(serviceConfig, opts, clusterGensFactories)
+	overloadManager, err := bootstrap.CreateOverloadManager(opts.CommonOptions)
 	if err != nil {
 		return nil, err
 	}
-	clusters, err := gen.MakeClusters(clusterGens)
-	if err != nil {
-		return nil, err
+
+	bt := &bootstrappb.Bootstrap{
+		Node:            bootstrap.CreateNode(opts.CommonOptions),
+		Admin:           bootstrap.CreateAdmin(opts.CommonOptions),
+		LayeredRuntime:  bootstrap.CreateLayeredRuntime(),
+		StatsConfig:     bootstrap.CreateStatsConfig(opts.CommonOptions),
+		OverloadManager: overloadManager,
 	}
-	listeners, err := gen.MakeListeners(serviceInfo, filtergen.ServiceControlOPFactoryParams{})
+
+	resources, err := configgen.Generate(serviceConfig, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	bt.StaticResources = &bootstrappb.Bootstrap_StaticResources{
-		Listeners: listeners,
-		Clusters:  clusters,
+		Listeners: resources.Listeners,
+		Clusters:  resources.Clusters,
 	}
 	return bt, nil
 }
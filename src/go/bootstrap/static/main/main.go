@@ -0,0 +1,74 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// espv2-config-gen writes a static Envoy bootstrap generated from a service
+// config and the config manager flags, to run Envoy without the config
+// manager:
+//
+//	espv2-config-gen --service_json_path=service.json \
+//	    --backend_address=grpc://127.0.0.1:8081 envoy.json
+//
+// OpenAPI specs must be deployed first, and their service config downloaded
+// with `gcloud endpoints configs describe --format=json`.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/bootstrap/static"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configmanager/flags"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+)
+
+var (
+	servicePath = flag.String("service_json_path", "", "file path to the service config in JSON.")
+)
+
+func main() {
+	flag.Parse()
+	outPath := flag.Arg(0)
+	glog.Infof("Output path: %s", outPath)
+	if outPath == "" {
+		glog.Exitf("Please specify a path to write bootstrap config file")
+	}
+	if *servicePath == "" {
+		glog.Exitf("Please specify the service config with flag --service_json_path")
+	}
+
+	config, err := ioutil.ReadFile(*servicePath)
+	if err != nil {
+		glog.Exitf("failed to read service config file %v, error: %v", *servicePath, err)
+	}
+	serviceConfig, err := util.UnmarshalServiceConfig(config)
+	if err != nil {
+		glog.Exitf("failed to unmarshal service config, error: %v", err)
+	}
+
+	opts := flags.EnvoyConfigOptionsFromFlags()
+	bootstrap, err := static.ServiceToBootstrapConfig(serviceConfig, opts)
+	if err != nil {
+		glog.Exitf("failed to create bootstrap config, error: %v", err)
+	}
+	bootstrapStr, err := util.ProtoToJson(bootstrap)
+	if err != nil {
+		glog.Exitf("failed to marshal bootstrap config, error: %v", err)
+	}
+
+	err = ioutil.WriteFile(outPath, []byte(bootstrapStr), 0644)
+	if err != nil {
+		glog.Exitf("failed to write config to %v, error: %v", outPath, err)
+	}
+}
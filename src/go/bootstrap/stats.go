@@ -0,0 +1,51 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+
+	metricspb "github.com/envoyproxy/go-control-plane/envoy/config/metrics/v3"
+)
+
+const (
+	// OperationStatTagName is the tag of the per-operation route statistics
+	// holding the operation selector.
+	OperationStatTagName = "operation"
+
+	// The per-operation route statistics are named
+	// vhost.<virtual host>.route.<operation selector>.<stat>, the selector
+	// may contain dots.
+	operationStatTagRegex = `^vhost\.[^.]+\.route\.((.+)\.)[^.]+$`
+)
+
+// CreateStatsConfig outputs StatsConfig struct for bootstrap config, nil if
+// the per-operation statistics are disabled.
+func CreateStatsConfig(opts options.CommonOptions) *metricspb.StatsConfig {
+	if !opts.EnableOperationStats {
+		return nil
+	}
+
+	return &metricspb.StatsConfig{
+		StatsTags: []*metricspb.TagSpecifier{
+			{
+				TagName: OperationStatTagName,
+				TagValue: &metricspb.TagSpecifier_Regex{
+					Regex: operationStatTagRegex,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestCreateStatsConfig(t *testing.T) {
+	if got := CreateStatsConfig(options.CommonOptions{}); got != nil {
+		t.Errorf("want no stats config when the operation stats are disabled, got %v", got)
+	}
+
+	got := CreateStatsConfig(options.CommonOptions{
+		EnableOperationStats: true,
+	})
+	if len(got.GetStatsTags()) != 1 || got.GetStatsTags()[0].GetTagName() != OperationStatTagName {
+		t.Fatalf("want the operation stats tag, got %v", got)
+	}
+
+	// Envoy removes the first capture group from the stat name and uses the
+	// second one as the tag value.
+	testData := []struct {
+		stat      string
+		wantValue string
+	}{
+		{
+			stat:      "vhost.backend.route.1.myapi.GetBook.upstream_rq_200",
+			wantValue: "1.myapi.GetBook",
+		},
+		{
+			stat:      "vhost.backend.route.endpoints.examples.bookstore.Bookstore.ListShelves.upstream_rq_time",
+			wantValue: "endpoints.examples.bookstore.Bookstore.ListShelves",
+		},
+		{
+			stat: "vhost.backend.vcluster.other.upstream_rq_total",
+		},
+	}
+
+	re := regexp.MustCompile(got.GetStatsTags()[0].GetRegex())
+	for _, tc := range testData {
+		matches := re.FindStringSubmatch(tc.stat)
+		var gotValue string
+		if len(matches) == 3 {
+			gotValue = matches[2]
+		}
+		if gotValue != tc.wantValue {
+			t.Errorf("stat %q: want operation tag %q, got %q", tc.stat, tc.wantValue, gotValue)
+		}
+	}
+}
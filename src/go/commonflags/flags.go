@@ -31,10 +31,13 @@ var (
 	AdsNamedPipe                    = flag.String("ads_named_pipe", defaults.AdsNamedPipe, "Unix domain socket to use internally for xDs between config manager and envoy.")
 	DisableTracing                  = flag.Bool("disable_tracing", defaults.TracingOptions.DisableTracing, `Disable stackdriver tracing`)
 	AdminPort                       = flag.Int("admin_port", defaults.AdminPort, "Enables envoy's admin interface on this port if it is not 0. Not recommended for production use-cases, as the admin port is unauthenticated.")
+	AdminSocketPath                 = flag.String("admin_socket_path", defaults.AdminSocketPath, "If set, envoy serves the admin page on this unix domain socket instead of --admin_address and --admin_port, so it is only reachable from the same host.")
+	AdminLoopbackOnly               = flag.Bool("admin_loopback_only", defaults.AdminLoopbackOnly, "If true, envoy serves the admin page on the loopback address instead of --admin_address, so it is only reachable from the same host.")
 	HttpRequestTimeoutS             = flag.Int("http_request_timeout_s", int(defaults.HttpRequestTimeout.Seconds()), `Set the timeout in second for all requests. Must be > 0 and the default is 30 seconds if not set.`)
 	Node                            = flag.String("node", defaults.Node, "envoy node id")
 	NonGCP                          = flag.Bool("non_gcp", defaults.NonGCP, `By default, the proxy tries to talk to GCP metadata server to get VM location in the first few requests. Setting this flag to true to skip this step`)
 	GeneratedHeaderPrefix           = flag.String("generated_header_prefix", defaults.GeneratedHeaderPrefix, "Set the header prefix for the generated headers. By default, it is `X-Endpoint-`")
+	EnableOperationStats            = flag.Bool("enable_operation_stats", defaults.EnableOperationStats, "Enable the per-operation statistics of the routes, named vhost.backend.route.<operation selector>.* and tagged with the operation selector, so the stats do not grow with the path parameters.")
 	TracingProjectId                = flag.String("tracing_project_id", defaults.TracingOptions.ProjectId, "The Google project id required for Stack driver tracing. If not set, will automatically use fetch it from GCP Metadata server")
 	TracingStackdriverAddress       = flag.String("tracing_stackdriver_address", defaults.TracingOptions.StackdriverAddress, "By default, the Stackdriver exporter will connect to production Stackdriver. If this is non-empty, it will connect to this address. It must be in the gRPC format and implement the cloud trace v2 RPCs.")
 	TracingSamplingRate             = flag.Float64("tracing_sample_rate", defaults.TracingOptions.SamplingRate, "tracing sampling rate from 0.0 to 1.0")
@@ -63,17 +66,25 @@ var (
 	BackendAuthIamDelegates            = flag.String("backend_auth_iam_delegates", "", "The sequence of service accounts in a delegation chain used to fetch identity token for the Backend Auth from Google Cloud IAM. The multiple delegates should be separated by \",\" and the flag only applies when BackendAuthIamServiceAccount is not empty.")
 	DisallowColonInWildcardPathSegment = flag.Bool("disallow_colon_in_wildcard_path_segment", false, `Whether disallow colon in the url wildcard path segment for route match. According to Google http url template spec[1], the literal colon cannot be used in url wildcard path segment. This flag isn't enabled for backward compatibility. 
 		[1]https://github.com/googleapis/googleapis/blob/165280d3deea4d225a079eb5c34717b214a5b732/google/api/http.proto#L226-L252`)
+
+	MaxDownstreamConnections               = flag.Int64("max_downstream_connections", defaults.MaxDownstreamConnections, "The maximum number of open downstream connections of all the listeners, enforced by the overload manager of envoy. 0 means unlimited.")
+	OverloadMaxHeapSizeBytes               = flag.Uint64("overload_max_heap_size_bytes", defaults.OverloadMaxHeapSizeBytes, "If set, the overload manager of envoy monitors the heap usage against this size and triggers the actions of --overload_shrink_heap_threshold and --overload_stop_accepting_requests_threshold.")
+	OverloadShrinkHeapThreshold            = flag.Float64("overload_shrink_heap_threshold", defaults.OverloadShrinkHeapThreshold, "The fraction of --overload_max_heap_size_bytes in use at which envoy releases the free memory to the system.")
+	OverloadStopAcceptingRequestsThreshold = flag.Float64("overload_stop_accepting_requests_threshold", defaults.OverloadStopAcceptingRequestsThreshold, "The fraction of --overload_max_heap_size_bytes in use at which envoy rejects the new requests with 503.")
 )
 
 func DefaultCommonOptionsFromFlags() options.CommonOptions {
 	opts := options.CommonOptions{
 		AdminAddress:          *AdminAddress,
 		AdminPort:             *AdminPort,
+		AdminSocketPath:       *AdminSocketPath,
+		AdminLoopbackOnly:     *AdminLoopbackOnly,
 		AdsNamedPipe:          *AdsNamedPipe,
 		HttpRequestTimeout:    time.Duration(*HttpRequestTimeoutS) * time.Second,
 		Node:                  *Node,
 		NonGCP:                *NonGCP,
 		GeneratedHeaderPrefix: *GeneratedHeaderPrefix,
+		EnableOperationStats:  *EnableOperationStats,
 		TracingOptions: &options.TracingOptions{
 			DisableTracing:           *DisableTracing,
 			ProjectId:                *TracingProjectId,
@@ -87,9 +98,13 @@ func DefaultCommonOptionsFromFlags() options.CommonOptions {
 			MaxNumLinks:              *TracingMaxNumLinks,
 			EnableVerboseAnnotations: *TracingEnableVerboseAnnotations,
 		},
-		MetadataURL:                        *MetadataURL,
-		IamURL:                             *IamURL,
-		DisallowColonInWildcardPathSegment: *DisallowColonInWildcardPathSegment,
+		MetadataURL:                            *MetadataURL,
+		IamURL:                                 *IamURL,
+		DisallowColonInWildcardPathSegment:     *DisallowColonInWildcardPathSegment,
+		MaxDownstreamConnections:               *MaxDownstreamConnections,
+		OverloadMaxHeapSizeBytes:               *OverloadMaxHeapSizeBytes,
+		OverloadShrinkHeapThreshold:            *OverloadShrinkHeapThreshold,
+		OverloadStopAcceptingRequestsThreshold: *OverloadStopAcceptingRequestsThreshold,
 	}
 	if *BackendAuthIamServiceAccount != "" {
 		opts.BackendAuthCredentials = &options.IAMCredentialsOptions{
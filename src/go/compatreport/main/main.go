@@ -0,0 +1,93 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// espv2-compat-report reports the features of a service config that require
+// the custom filters of the ESPv2 build of Envoy, so they would not be
+// representable with stock Envoy xDS:
+//
+//	espv2-compat-report --service_json_path=service.json \
+//	    --backend_address=grpc://127.0.0.1:8081
+//
+// The config manager flags are accepted, as they select the generated
+// filters.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	gen "github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	sc "github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configmanager/flags"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+)
+
+var (
+	servicePath = flag.String("service_json_path", "", "file path to the service config in JSON.")
+	reportJson  = flag.Bool("report_json", false, "print the report in JSON instead of text.")
+)
+
+func main() {
+	flag.Parse()
+	if *servicePath == "" {
+		glog.Exitf("Please specify the service config with flag --service_json_path")
+	}
+
+	config, err := ioutil.ReadFile(*servicePath)
+	if err != nil {
+		glog.Exitf("failed to read service config file %v, error: %v", *servicePath, err)
+	}
+	serviceConfig, err := util.UnmarshalServiceConfig(config)
+	if err != nil {
+		glog.Exitf("failed to unmarshal service config, error: %v", err)
+	}
+
+	opts := flags.EnvoyConfigOptionsFromFlags()
+	serviceInfo, err := sc.NewServiceInfoFromServiceConfig(serviceConfig, opts)
+	if err != nil {
+		glog.Exitf("failed to initialize ServiceInfo, error: %v", err)
+	}
+	report, err := gen.MakeStockEnvoyCompatibilityReport(serviceInfo, filtergen.ServiceControlOPFactoryParams{})
+	if err != nil {
+		glog.Exitf("failed to create compatibility report, error: %v", err)
+	}
+
+	if *reportJson {
+		if report == nil {
+			report = []gen.CustomFilterUsage{}
+		}
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			glog.Exitf("failed to marshal compatibility report, error: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	if len(report) == 0 {
+		fmt.Printf("Service %q is representable with stock Envoy xDS.\n", serviceConfig.GetName())
+		return
+	}
+	fmt.Printf("Service %q requires %d custom filters of ESPv2:\n", serviceConfig.GetName(), len(report))
+	for _, usage := range report {
+		fmt.Printf("  %s\n", usage.FilterName)
+		for _, feature := range usage.Features {
+			fmt.Printf("    - %s\n", feature)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configgen is the public API of the ESPv2 config generation, which
+// translates a google.api.Service and the ESPv2 options into the Envoy
+// listeners and clusters, so it can be embedded in other control planes:
+//
+//	opts := configgen.DefaultOptions()
+//	opts.BackendAddress = "grpc://127.0.0.1:8081"
+//	resources, err := configgen.Generate(serviceConfig, opts)
+//
+// The routes and the HTTP filters are inlined in the HTTP connection manager
+// of the ingress listener. The packages under configgenerator are the
+// implementation, and may change between releases.
+package configgen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+
+	gen "github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator"
+	sc "github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
+	scpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/service_control"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// Options are the ESPv2 options of the config generation, the same as the
+// flags of the config manager.
+type Options = options.ConfigGeneratorOptions
+
+// DefaultOptions returns the options with the defaults of the config manager
+// flags.
+func DefaultOptions() Options {
+	return options.DefaultConfigGeneratorOptions()
+}
+
+// Resources are the Envoy resources generated for a service config.
+type Resources struct {
+	Listeners []*listenerpb.Listener
+	Clusters  []*clusterpb.Cluster
+}
+
+// Generator generates the Envoy resources for the service configs.
+type Generator struct {
+	// Options are the ESPv2 options.
+	Options Options
+
+	// GCPAttributes are the attributes of the platform reported to Service
+	// Control, such as the zone and the platform name. Optional.
+	GCPAttributes *scpb.GcpAttributes
+}
+
+// Generate generates the Envoy resources for the service config with the
+// options.
+func Generate(serviceConfig *confpb.Service, opts Options) (*Resources, error) {
+	g := &Generator{
+		Options: opts,
+	}
+	return g.Generate(serviceConfig)
+}
+
+// Generate generates the Envoy resources for the service config.
+func (g *Generator) Generate(serviceConfig *confpb.Service) (*Resources, error) {
+	serviceInfo, err := sc.NewServiceInfoFromServiceConfig(serviceConfig, g.Options)
+	if err != nil {
+		return nil, fmt.Errorf("fail to initialize ServiceInfo, %s", err)
+	}
+
+	clusterGens, err := gen.NewClusterGeneratorsFromOPConfig(serviceInfo.ServiceConfig(), serviceInfo.Options, gen.GetESPv2ClusterGenFactories())
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := gen.MakeClusters(clusterGens)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners, err := gen.MakeListeners(serviceInfo, filtergen.ServiceControlOPFactoryParams{
+		GCPAttributes: g.GCPAttributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resources{
+		Listeners: listeners,
+		Clusters:  clusters,
+	}, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/google/go-cmp/cmp"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func fakeServiceConfig() *confpb.Service {
+	return &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Id:   "2023-01-01r0",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListShelves",
+					},
+				},
+			},
+		},
+		Http: &annotationspb.Http{
+			Rules: []*annotationspb.HttpRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+					Pattern: &annotationspb.HttpRule_Get{
+						Get: "/v1/shelves",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testData := []struct {
+		desc             string
+		optsMod          func(opts *Options)
+		serviceConfig    *confpb.Service
+		wantListeners    []string
+		wantClusters     []string
+		wantErrorContain string
+	}{
+		{
+			desc: "Listener and clusters of the sidecar backend",
+			optsMod: func(opts *Options) {
+				opts.BackendAddress = "http://127.0.0.1:8082"
+				opts.SkipServiceControlFilter = true
+				opts.TracingOptions = &options.TracingOptions{
+					DisableTracing: true,
+				}
+			},
+			serviceConfig: fakeServiceConfig(),
+			wantListeners: []string{util.IngressListenerName},
+			wantClusters: []string{
+				"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+				"metadata-cluster",
+				"service-control-cluster",
+			},
+		},
+		{
+			desc: "Invalid service config",
+			optsMod: func(opts *Options) {
+				opts.SkipServiceControlFilter = true
+			},
+			serviceConfig:    &confpb.Service{},
+			wantErrorContain: "fail to initialize ServiceInfo",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := DefaultOptions()
+			tc.optsMod(&opts)
+
+			resources, err := Generate(tc.serviceConfig, opts)
+			if tc.wantErrorContain != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrorContain) {
+					t.Fatalf("Generate() got error %v, want error containing %q", err, tc.wantErrorContain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() got error %v", err)
+			}
+
+			var gotListeners, gotClusters []string
+			for _, listener := range resources.Listeners {
+				gotListeners = append(gotListeners, listener.GetName())
+			}
+			for _, cluster := range resources.Clusters {
+				gotClusters = append(gotClusters, cluster.GetName())
+			}
+			if diff := cmp.Diff(tc.wantListeners, gotListeners); diff != "" {
+				t.Errorf("Generate() listeners diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantClusters, gotClusters); diff != "" {
+				t.Errorf("Generate() clusters diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
@@ -29,10 +30,18 @@ import (
 func GetESPv2ClusterGenFactories() []clustergen.ClusterGeneratorOPFactory {
 	return []clustergen.ClusterGeneratorOPFactory{
 		clustergen.NewLocalBackendClustersFromOPConfig,
+		clustergen.NewDefaultBackendClustersFromOPConfig,
+		clustergen.NewTenantBackendClustersFromOPConfig,
+		clustergen.NewConsumerBackendClustersFromOPConfig,
+		clustergen.NewVersionBackendClustersFromOPConfig,
 		clustergen.NewTokenAgentClustersFromOPConfig,
 		clustergen.NewIMDSClustersFromOPConfig,
 		clustergen.NewIAMClustersFromOPConfig,
 		clustergen.NewServiceControlClustersFromOPConfig,
+		clustergen.NewExtProcClustersFromOPConfig,
+		clustergen.NewWasmClustersFromOPConfig,
+		clustergen.NewTcpProxyClustersFromOPConfig,
+		clustergen.NewAdminClustersFromOPConfig,
 		clustergen.NewRemoteBackendClustersFromOPConfig,
 		clustergen.NewJWTProviderClustersFromOPConfig,
 	}
@@ -57,16 +66,26 @@ func NewClusterGeneratorsFromOPConfig(serviceConfig *servicepb.Service, opts opt
 }
 
 // MakeClusters creates the xDS cluster configs from the ClusterGenerators.
+//
+// There is one ClusterGenerator per remote backend, so they are run in
+// parallel for the service configs with many backend rules. The clusters are
+// kept in the order of the generators.
 func MakeClusters(gens []clustergen.ClusterGenerator) ([]*clusterpb.Cluster, error) {
-	var clusters []*clusterpb.Cluster
-	for _, gen := range gens {
-		cluster, err := gen.GenConfig()
+	clusters := make([]*clusterpb.Cluster, len(gens))
+	if err := util.ParallelFor(len(gens), func(i int) error {
+		cluster, err := gens[i].GenConfig()
 		if err != nil {
-			return nil, fmt.Errorf("cluster generator %q failed to generate xDS cluster config: %v", gen.GetName(), err)
+			return fmt.Errorf("cluster generator %q failed to generate xDS cluster config: %v", gens[i].GetName(), err)
 		}
-		clusters = append(clusters, cluster)
+		clusters[i] = cluster
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	glog.Infof("generate clusters: %v", clusters)
+	glog.Infof("generated %d clusters", len(clusters))
+	if glog.V(1) {
+		glog.Infof("generate clusters: %v", clusters)
+	}
 	return clusters, nil
 }
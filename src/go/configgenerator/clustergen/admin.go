@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/bootstrap"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// AdminClusterName is the name of the xDS cluster to the Envoy admin
+	// interface.
+	AdminClusterName = "admin-cluster"
+)
+
+// AdminCluster is an Envoy cluster to forward the requests of the read-only
+// admin paths exposed on the main listener to the Envoy admin interface.
+type AdminCluster struct {
+	AdminAddress *corepb.Address
+}
+
+// NewAdminClustersFromOPConfig creates a AdminCluster from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewAdminClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	if opts.AdminReadOnlyPaths == "" {
+		return nil, nil
+	}
+
+	adminAddress := bootstrap.CreateAdminAddress(opts.CommonOptions)
+	if adminAddress == nil {
+		return nil, fmt.Errorf("flag --admin_read_only_paths requires the admin interface, set flag --admin_port or --admin_socket_path")
+	}
+
+	return []ClusterGenerator{
+		&AdminCluster{
+			AdminAddress: adminAddress,
+		},
+	}, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *AdminCluster) GetName() string {
+	return AdminClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *AdminCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return &clusterpb.Cluster{
+		Name:                 AdminClusterName,
+		ConnectTimeout:       durationpb.New(5 * time.Second),
+		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STATIC},
+		LoadAssignment: &endpointpb.ClusterLoadAssignment{
+			ClusterName: AdminClusterName,
+			Endpoints: []*endpointpb.LocalityLbEndpoints{
+				{
+					LbEndpoints: []*endpointpb.LbEndpoint{
+						{
+							HostIdentifier: &endpointpb.LbEndpoint_Endpoint{
+								Endpoint: &endpointpb.Endpoint{
+									Address: c.AdminAddress,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewAdminClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Success with admin unix domain socket",
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					AdminSocketPath: "/var/run/espv2/admin.sock",
+				},
+				AdminReadOnlyPaths: "/stats",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "admin-cluster",
+					ConnectTimeout:       durationpb.New(5 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STATIC},
+					LoadAssignment: &endpointpb.ClusterLoadAssignment{
+						ClusterName: "admin-cluster",
+						Endpoints: []*endpointpb.LocalityLbEndpoints{
+							{
+								LbEndpoints: []*endpointpb.LbEndpoint{
+									{
+										HostIdentifier: &endpointpb.LbEndpoint_Endpoint{
+											Endpoint: &endpointpb.Endpoint{
+												Address: &corepb.Address{
+													Address: &corepb.Address_Pipe{
+														Pipe: &corepb.Pipe{
+															Path: "/var/run/espv2/admin.sock",
+															Mode: 0600,
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Desc:   "No cluster without read-only admin paths",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewAdminClustersFromOPConfig)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	consumerClusterNamePrefix = "backend-cluster-consumer-"
+)
+
+// ConsumerBackend is a dedicated backend pool of the named API consumers. It
+// is read from the JSON array of flag --consumer_backends.
+type ConsumerBackend struct {
+	Name           string `json:"name"`
+	BackendAddress string `json:"backendAddress"`
+
+	// ConsumerNumbers are the project numbers of the API consumers,
+	// identified by the API keys validated by Service Control.
+	ConsumerNumbers []string `json:"consumerNumbers"`
+}
+
+// ConsumerClusterName returns the name of the backend cluster of the consumer
+// backend.
+func ConsumerClusterName(name string) string {
+	return consumerClusterNamePrefix + name
+}
+
+// ParseConsumerBackendsFromOPConfig parses the consumer backends from ESPv2
+// options, nil if consumer routing is disabled.
+func ParseConsumerBackendsFromOPConfig(opts options.ConfigGeneratorOptions) ([]*ConsumerBackend, error) {
+	return ParseConsumerBackends(opts.ConsumerBackends)
+}
+
+// ParseConsumerBackends parses the JSON array of flag --consumer_backends, nil
+// if it is empty.
+func ParseConsumerBackends(content string) ([]*ConsumerBackend, error) {
+	if content == "" {
+		return nil, nil
+	}
+
+	var backends []*ConsumerBackend
+	if err := json.Unmarshal([]byte(content), &backends); err != nil {
+		return nil, fmt.Errorf("invalid flag --consumer_backends, it should be a JSON array: %v", err)
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("invalid flag --consumer_backends, no consumer backend is specified")
+	}
+
+	names := make(map[string]bool)
+	consumers := make(map[string]string)
+	for _, backend := range backends {
+		if backend == nil {
+			return nil, fmt.Errorf("invalid flag --consumer_backends, consumer backend cannot be null")
+		}
+		if !tenantNameRegex.MatchString(backend.Name) {
+			return nil, fmt.Errorf("invalid flag --consumer_backends, name %q must only contain lowercase letters, digits, '-' and '_'", backend.Name)
+		}
+		if names[backend.Name] {
+			return nil, fmt.Errorf("invalid flag --consumer_backends, duplicate name %q", backend.Name)
+		}
+		names[backend.Name] = true
+
+		if backend.BackendAddress == "" {
+			return nil, fmt.Errorf("invalid flag --consumer_backends, consumer backend %q has no backend address", backend.Name)
+		}
+		if len(backend.ConsumerNumbers) == 0 {
+			return nil, fmt.Errorf("invalid flag --consumer_backends, consumer backend %q has no consumer numbers", backend.Name)
+		}
+		for _, consumer := range backend.ConsumerNumbers {
+			if other, ok := consumers[consumer]; ok {
+				return nil, fmt.Errorf("invalid flag --consumer_backends, consumer number %q is mapped to both %q and %q", consumer, other, backend.Name)
+			}
+			consumers[consumer] = backend.Name
+		}
+	}
+	return backends, nil
+}
+
+// ConsumerBackendCluster is an Envoy cluster to communicate with the
+// dedicated backend pool of the named API consumers.
+type ConsumerBackendCluster struct {
+	BackendCluster *helpers.BaseBackendCluster
+}
+
+// NewConsumerBackendClustersFromOPConfig creates ConsumerBackendClusters from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewConsumerBackendClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	backends, err := ParseConsumerBackendsFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if backends == nil {
+		glog.Infof("Not adding consumer backend cluster gens because there is no consumer backend.")
+		return nil, nil
+	}
+
+	var gens []ClusterGenerator
+	for _, backend := range backends {
+		scheme, hostname, port, _, err := util.ParseURI(backend.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of consumer backend %q: %v", backend.Name, err)
+		}
+
+		protocol, useTLS, err := util.ParseBackendProtocol(scheme, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of consumer backend %q: %v", backend.Name, err)
+		}
+
+		var tls *helpers.ClusterTLSConfiger
+		if useTLS {
+			tls = helpers.NewClusterTLSConfigerFromOPConfig(opts, true)
+		}
+
+		gens = append(gens, &ConsumerBackendCluster{
+			BackendCluster: &helpers.BaseBackendCluster{
+				ClusterName:            ConsumerClusterName(backend.Name),
+				Hostname:               hostname,
+				Port:                   port,
+				Protocol:               protocol,
+				ClusterConnectTimeout:  helpers.BackendClusterConnectTimeout(opts),
+				MaxRequestsThreshold:   opts.BackendClusterMaxRequests,
+				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				HeaderKeyFormat:        opts.HeaderKeyFormat,
+				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
+				TLS:                    tls,
+				Connection:             helpers.NewClusterConnectionConfigerFromOPConfig(opts),
+				RetryBudget:            helpers.NewClusterRetryBudgetConfigerFromOPConfig(opts),
+				ProtocolSelection:      helpers.NewClusterProtocolSelectionConfigerFromOPConfig(opts),
+			},
+		})
+	}
+	return gens, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *ConsumerBackendCluster) GetName() string {
+	return c.BackendCluster.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *ConsumerBackendCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return c.BackendCluster.GenBaseConfig()
+}
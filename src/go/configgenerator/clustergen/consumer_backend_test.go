@@ -0,0 +1,118 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewConsumerBackendClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Success for HTTPS and HTTP consumer backends",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `
+[
+  {"name": "bigcorp", "backendAddress": "https://bigcorp.example.com", "consumerNumbers": ["123", "456"]},
+  {"name": "megacorp", "backendAddress": "http://megacorp.internal:8080", "consumerNumbers": ["789"]}
+]`,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "backend-cluster-consumer-bigcorp",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("bigcorp.example.com", 443),
+					TransportSocket:      clustergentest.CreateDefaultTLS(t, "bigcorp.example.com", false),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+				{
+					Name:                 "backend-cluster-consumer-megacorp",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("megacorp.internal", 8080),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewConsumerBackendClustersFromOPConfig)
+	}
+}
+
+func TestNewConsumerBackendClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Malformed consumer backends",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `{}`,
+			},
+			WantFactoryError: "invalid flag --consumer_backends, it should be a JSON array",
+		},
+		{
+			Desc: "No consumer backend",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `[]`,
+			},
+			WantFactoryError: "invalid flag --consumer_backends, no consumer backend is specified",
+		},
+		{
+			Desc: "Invalid name",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `[{"name": "BigCorp", "backendAddress": "https://bigcorp.example.com", "consumerNumbers": ["123"]}]`,
+			},
+			WantFactoryError: `name "BigCorp" must only contain`,
+		},
+		{
+			Desc: "No consumer numbers",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `[{"name": "bigcorp", "backendAddress": "https://bigcorp.example.com"}]`,
+			},
+			WantFactoryError: `consumer backend "bigcorp" has no consumer numbers`,
+		},
+		{
+			Desc: "Invalid backend address",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `[{"name": "bigcorp", "backendAddress": "ftp://bigcorp.example.com", "consumerNumbers": ["123"]}]`,
+			},
+			WantFactoryError: `invalid backend address of consumer backend "bigcorp"`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewConsumerBackendClustersFromOPConfig)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	// DefaultBackendClusterName is the name of the cluster receiving the
+	// requests matching no operation.
+	DefaultBackendClusterName = "backend-cluster-default"
+)
+
+// DefaultBackendCluster is an Envoy cluster to communicate with the backend
+// receiving the requests matching no operation.
+type DefaultBackendCluster struct {
+	BackendCluster *helpers.BaseBackendCluster
+}
+
+// NewDefaultBackendClustersFromOPConfig creates a DefaultBackendCluster from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewDefaultBackendClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	if opts.DefaultBackendAddress == "" {
+		glog.Infof("Not adding default backend cluster gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	scheme, hostname, port, _, err := util.ParseURI(opts.DefaultBackendAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --default_backend_address: %v", err)
+	}
+
+	protocol, useTLS, err := util.ParseBackendProtocol(scheme, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --default_backend_address: %v", err)
+	}
+
+	var tls *helpers.ClusterTLSConfiger
+	if useTLS {
+		tls = helpers.NewClusterTLSConfigerFromOPConfig(opts, true)
+	}
+
+	return []ClusterGenerator{
+		&DefaultBackendCluster{
+			BackendCluster: &helpers.BaseBackendCluster{
+				ClusterName:            DefaultBackendClusterName,
+				Hostname:               hostname,
+				Port:                   port,
+				Protocol:               protocol,
+				ClusterConnectTimeout:  helpers.BackendClusterConnectTimeout(opts),
+				MaxRequestsThreshold:   opts.BackendClusterMaxRequests,
+				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				HeaderKeyFormat:        opts.HeaderKeyFormat,
+				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
+				TLS:                    tls,
+				Connection:             helpers.NewClusterConnectionConfigerFromOPConfig(opts),
+				RetryBudget:            helpers.NewClusterRetryBudgetConfigerFromOPConfig(opts),
+				ProtocolSelection:      helpers.NewClusterProtocolSelectionConfigerFromOPConfig(opts),
+			},
+		},
+	}, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *DefaultBackendCluster) GetName() string {
+	return c.BackendCluster.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *DefaultBackendCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return c.BackendCluster.GenBaseConfig()
+}
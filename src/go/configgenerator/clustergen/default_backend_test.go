@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewDefaultBackendClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Success for HTTPS default backend",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				DefaultBackendAddress: "https://legacy.example.com",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "backend-cluster-default",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("legacy.example.com", 443),
+					TransportSocket:      clustergentest.CreateDefaultTLS(t, "legacy.example.com", false),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewDefaultBackendClustersFromOPConfig)
+	}
+}
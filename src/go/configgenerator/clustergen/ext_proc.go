@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	// ExtProcClusterName is the name of the external processing service xDS
+	// cluster.
+	ExtProcClusterName = "ext-proc-cluster"
+)
+
+// ExtProcCluster is an Envoy cluster to communicate with the external
+// processing (ext_proc) gRPC service.
+type ExtProcCluster struct {
+	BackendCluster *helpers.BaseBackendCluster
+}
+
+// NewExtProcClustersFromOPConfig creates a ExtProcCluster from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewExtProcClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	if opts.ExtProcAddress == "" {
+		return nil, nil
+	}
+
+	scheme, hostname, port, path, err := util.ParseURI(opts.ExtProcAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --ext_proc_address: %v", err)
+	}
+	if path != "" {
+		return nil, fmt.Errorf("invalid flag --ext_proc_address, should not have path part: %s", path)
+	}
+
+	protocol, useTLS, err := util.ParseBackendProtocol(scheme, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --ext_proc_address: %v", err)
+	}
+	if protocol != util.GRPC {
+		return nil, fmt.Errorf("invalid flag --ext_proc_address, scheme must be grpc or grpcs")
+	}
+
+	var tls *helpers.ClusterTLSConfiger
+	if useTLS {
+		tls = helpers.NewClusterTLSConfigerFromOPConfig(opts, false)
+	}
+
+	return []ClusterGenerator{
+		&ExtProcCluster{
+			BackendCluster: &helpers.BaseBackendCluster{
+				ClusterName:            ExtProcClusterName,
+				Hostname:               hostname,
+				Port:                   port,
+				Protocol:               protocol,
+				ClusterConnectTimeout:  opts.ClusterConnectTimeout,
+				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
+				TLS:                    tls,
+			},
+		},
+	}, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *ExtProcCluster) GetName() string {
+	return c.BackendCluster.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *ExtProcCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return c.BackendCluster.GenBaseConfig()
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewExtProcClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Success with grpc address",
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress: "grpc://127.0.0.1:9000",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "ext-proc-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:               clusterpb.Cluster_V4_PREFERRED,
+					LoadAssignment:                util.CreateLoadAssignment("127.0.0.1", 9000),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+				},
+			},
+		},
+		{
+			Desc: "Success with grpcs address",
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress: "grpcs://ext-proc.example.com",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "ext-proc-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:               clusterpb.Cluster_V4_PREFERRED,
+					LoadAssignment:                util.CreateLoadAssignment("ext-proc.example.com", 443),
+					TransportSocket:               clustergentest.CreateDefaultTLS(t, "ext-proc.example.com", true),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+				},
+			},
+		},
+		{
+			Desc:   "No cluster without ext_proc address",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewExtProcClustersFromOPConfig)
+	}
+}
+
+func TestNewExtProcClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "HTTP scheme is not supported",
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress: "http://127.0.0.1:9000",
+			},
+			WantFactoryError: "invalid flag --ext_proc_address, scheme must be grpc or grpcs",
+		},
+		{
+			Desc: "Address with path",
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress: "grpc://127.0.0.1:9000/ext_proc",
+			},
+			WantFactoryError: "invalid flag --ext_proc_address, should not have path part",
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewExtProcClustersFromOPConfig)
+	}
+}
@@ -38,6 +38,9 @@ type BaseBackendCluster struct {
 	ClusterConnectTimeout  time.Duration
 	MaxRequestsThreshold   int
 	BackendDnsLookupFamily string
+	// HeaderKeyFormat is the casing of the HTTP/1 request header names sent to
+	// the backend, empty for lower case.
+	HeaderKeyFormat string
 
 	// DNS adds on additional DNS resolver config to the cluster.
 	// Nil if not needed.
@@ -46,6 +49,30 @@ type BaseBackendCluster struct {
 	// TLS adds on additional TLS transport socket config to the cluster.
 	// Nil if not needed.
 	TLS *ClusterTLSConfiger
+
+	// ActiveHealth adds on active health check config to the cluster.
+	// Nil if not needed.
+	ActiveHealth *ClusterActiveHealthCheckConfiger
+
+	// Connection adds on upstream connection options to the cluster.
+	// Nil if not needed.
+	Connection *ClusterConnectionConfiger
+
+	// RetryBudget adds on the retry budget to the circuit breakers of the
+	// cluster. Nil if not needed.
+	RetryBudget *ClusterRetryBudgetConfiger
+
+	// SlowStart adds on the slow start of the newly added endpoints to the
+	// cluster. Nil if not needed.
+	SlowStart *ClusterSlowStartConfiger
+
+	// ProtocolSelection chooses the upstream protocol per request or
+	// connection instead of Protocol. Nil if not needed.
+	ProtocolSelection *ClusterProtocolSelectionConfiger
+
+	// Failover adds on the failover backend to the cluster as a lower
+	// priority level. Nil if not needed.
+	Failover *ClusterFailoverConfiger
 }
 
 // GenBaseConfig generates the base cluster configuration that is common to
@@ -68,11 +95,25 @@ func (c *BaseBackendCluster) GenBaseConfig() (*clusterpb.Cluster, error) {
 		}
 	}
 
+	if err := MaybeAddRetryBudget(c.RetryBudget, config); err != nil {
+		return nil, err
+	}
+
 	isHttp2 := c.Protocol == util.GRPC || c.Protocol == util.HTTP2
+	protocolSelection, err := c.ProtocolSelection.LookupProtocolSelection(c.Hostname, c.Port, c.Protocol)
+	if err != nil {
+		return nil, err
+	}
 
+	if protocolSelection == AutoProtocolSelection && c.TLS == nil {
+		// Envoy requires the ALPN of the TLS transport socket to choose the protocol.
+		return nil, fmt.Errorf("invalid flag --backend_protocol_selection, backend %s:%d must use TLS for protocol selection %q", c.Hostname, c.Port, AutoProtocolSelection)
+	}
 	if c.TLS != nil {
 		var alpnProtocols []string
-		if isHttp2 {
+		if protocolSelection == AutoProtocolSelection {
+			alpnProtocols = []string{"h2", "http/1.1"}
+		} else if isHttp2 {
 			alpnProtocols = []string{"h2"}
 		}
 		transportSocket, err := c.TLS.MakeTLSConfig(c.Hostname, alpnProtocols)
@@ -82,8 +123,26 @@ func (c *BaseBackendCluster) GenBaseConfig() (*clusterpb.Cluster, error) {
 		config.TransportSocket = transportSocket
 	}
 
-	if isHttp2 {
-		config.TypedExtensionProtocolOptions = util.CreateUpstreamProtocolOptions()
+	headerKeyFormat, err := util.CreateHeaderKeyFormat(c.HeaderKeyFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --header_key_format: %v", err)
+	}
+	if protocolSelection != "" || isHttp2 {
+		keepalive, err := c.Connection.MakeHttp2Keepalive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch protocolSelection {
+		case DownstreamProtocolSelection:
+			config.TypedExtensionProtocolOptions = util.CreateUpstreamDownstreamProtocolOptions(headerKeyFormat, keepalive)
+		case AutoProtocolSelection:
+			config.TypedExtensionProtocolOptions = util.CreateUpstreamAutoProtocolOptions(headerKeyFormat, keepalive)
+		default:
+			config.TypedExtensionProtocolOptions = util.CreateUpstreamHttp2ProtocolOptions(keepalive)
+		}
+	} else if headerKeyFormat != nil {
+		config.TypedExtensionProtocolOptions = util.CreateUpstreamHttp1ProtocolOptions(headerKeyFormat)
 	}
 
 	switch c.BackendDnsLookupFamily {
@@ -101,10 +160,26 @@ func (c *BaseBackendCluster) GenBaseConfig() (*clusterpb.Cluster, error) {
 		return nil, fmt.Errorf("invalid DnsLookupFamily: %s; Only auto, v4only, v6only, v4preferred, and all are valid", c.BackendDnsLookupFamily)
 	}
 
+	if err := MaybeAddConnectionOptions(c.Connection, config); err != nil {
+		return nil, err
+	}
+
 	if err := MaybeAddDNSResolver(c.DNS, config); err != nil {
 		return nil, err
 	}
 
+	if err := MaybeAddActiveHealthCheck(c.ActiveHealth, c.Hostname, c.Protocol, config); err != nil {
+		return nil, err
+	}
+
+	if err := MaybeAddSlowStart(c.SlowStart, config); err != nil {
+		return nil, err
+	}
+
+	if err := MaybeAddFailover(c.Failover, c.Hostname, c.Port, config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
@@ -0,0 +1,173 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// BackendClusterConnectTimeout returns the connect timeout of the backend
+// clusters, which defaults to the connect timeout of all clusters.
+func BackendClusterConnectTimeout(opts options.ConfigGeneratorOptions) time.Duration {
+	if opts.BackendClusterConnectTimeout > 0 {
+		return opts.BackendClusterConnectTimeout
+	}
+	return opts.ClusterConnectTimeout
+}
+
+// ClusterConnectionConfiger is a helper to set the upstream connection options
+// on a backend cluster.
+type ClusterConnectionConfiger struct {
+	TcpKeepaliveTime     time.Duration
+	TcpKeepaliveInterval time.Duration
+	TcpKeepaliveProbes   int
+	HappyEyeballs        bool
+
+	// The HTTP/2 keepalive of the gRPC and HTTP/2 backends, the defaults of
+	// util.Http2KeepaliveInterval and util.Http2KeepaliveTimeout are used if
+	// not set.
+	Http2KeepaliveInterval       time.Duration
+	Http2KeepaliveTimeout        time.Duration
+	Http2KeepaliveIntervalJitter float64
+}
+
+// NewClusterConnectionConfigerFromOPConfig creates a ClusterConnectionConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterConnectionConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterConnectionConfiger {
+	if opts.BackendTcpKeepaliveTime == 0 && opts.BackendTcpKeepaliveInterval == 0 && opts.BackendTcpKeepaliveProbes == 0 && !opts.BackendHappyEyeballs &&
+		opts.BackendHttp2KeepaliveInterval == 0 && opts.BackendHttp2KeepaliveTimeout == 0 && opts.BackendHttp2KeepaliveIntervalJitter == 0 {
+		return nil
+	}
+
+	return &ClusterConnectionConfiger{
+		TcpKeepaliveTime:             opts.BackendTcpKeepaliveTime,
+		TcpKeepaliveInterval:         opts.BackendTcpKeepaliveInterval,
+		TcpKeepaliveProbes:           opts.BackendTcpKeepaliveProbes,
+		HappyEyeballs:                opts.BackendHappyEyeballs,
+		Http2KeepaliveInterval:       opts.BackendHttp2KeepaliveInterval,
+		Http2KeepaliveTimeout:        opts.BackendHttp2KeepaliveTimeout,
+		Http2KeepaliveIntervalJitter: opts.BackendHttp2KeepaliveIntervalJitter,
+	}
+}
+
+// MaybeAddConnectionOptions adds the generated connection options to the given
+// cluster. It must be called after the DNS lookup family is set.
+func MaybeAddConnectionOptions(connectionConfiger *ClusterConnectionConfiger, cluster *clusterpb.Cluster) error {
+	if connectionConfiger == nil {
+		return nil
+	}
+
+	keepalive, err := connectionConfiger.MakeTcpKeepalive()
+	if err != nil {
+		return fmt.Errorf("fail to create TCP keepalive for cluster: %v", err)
+	}
+	if keepalive != nil {
+		cluster.UpstreamConnectionOptions = &clusterpb.UpstreamConnectionOptions{
+			TcpKeepalive: keepalive,
+		}
+	}
+
+	if connectionConfiger.HappyEyeballs {
+		// Envoy races the connection attempts to the resolved addresses of both
+		// families when all of them are looked up.
+		switch cluster.DnsLookupFamily {
+		case clusterpb.Cluster_V4_PREFERRED, clusterpb.Cluster_ALL:
+			cluster.DnsLookupFamily = clusterpb.Cluster_ALL
+		default:
+			return fmt.Errorf("flag --backend_happy_eyeballs cannot be used with DNS lookup family %v", cluster.DnsLookupFamily)
+		}
+	}
+	return nil
+}
+
+// MakeTcpKeepalive creates the TCP keepalive config, nil if not configured.
+func (c *ClusterConnectionConfiger) MakeTcpKeepalive() (*corepb.TcpKeepalive, error) {
+	if c.TcpKeepaliveTime == 0 && c.TcpKeepaliveInterval == 0 && c.TcpKeepaliveProbes == 0 {
+		return nil, nil
+	}
+	if c.TcpKeepaliveProbes < 0 {
+		return nil, fmt.Errorf("invalid flag --backend_tcp_keepalive_probes %d, must be >= 0", c.TcpKeepaliveProbes)
+	}
+
+	keepalive := &corepb.TcpKeepalive{}
+	if c.TcpKeepaliveProbes > 0 {
+		keepalive.KeepaliveProbes = &wrappers.UInt32Value{Value: uint32(c.TcpKeepaliveProbes)}
+	}
+
+	var err error
+	if keepalive.KeepaliveTime, err = keepaliveSeconds("backend_tcp_keepalive_time", c.TcpKeepaliveTime); err != nil {
+		return nil, err
+	}
+	if keepalive.KeepaliveInterval, err = keepaliveSeconds("backend_tcp_keepalive_interval", c.TcpKeepaliveInterval); err != nil {
+		return nil, err
+	}
+	return keepalive, nil
+}
+
+// MakeHttp2Keepalive creates the HTTP/2 keepalive config of the gRPC and
+// HTTP/2 backends. The configer may be nil, when the defaults are used.
+func (c *ClusterConnectionConfiger) MakeHttp2Keepalive() (*corepb.KeepaliveSettings, error) {
+	interval, timeout := util.Http2KeepaliveInterval, util.Http2KeepaliveTimeout
+	var jitter float64
+	if c != nil {
+		if c.Http2KeepaliveInterval != 0 && c.Http2KeepaliveInterval < time.Millisecond {
+			return nil, fmt.Errorf("invalid flag --backend_http2_keepalive_interval %v, must be >= 1ms", c.Http2KeepaliveInterval)
+		}
+		if c.Http2KeepaliveTimeout != 0 && c.Http2KeepaliveTimeout < time.Millisecond {
+			return nil, fmt.Errorf("invalid flag --backend_http2_keepalive_timeout %v, must be >= 1ms", c.Http2KeepaliveTimeout)
+		}
+		if c.Http2KeepaliveIntervalJitter < 0 || c.Http2KeepaliveIntervalJitter > 100 {
+			return nil, fmt.Errorf("invalid flag --backend_http2_keepalive_interval_jitter %v, must be in the range of [0, 100]", c.Http2KeepaliveIntervalJitter)
+		}
+
+		if c.Http2KeepaliveInterval > 0 {
+			interval = c.Http2KeepaliveInterval
+		}
+		if c.Http2KeepaliveTimeout > 0 {
+			timeout = c.Http2KeepaliveTimeout
+		}
+		jitter = c.Http2KeepaliveIntervalJitter
+	}
+
+	keepalive := &corepb.KeepaliveSettings{
+		Interval: durationpb.New(interval),
+		Timeout:  durationpb.New(timeout),
+	}
+	if jitter > 0 {
+		keepalive.IntervalJitter = &typepb.Percent{Value: jitter}
+	}
+	return keepalive, nil
+}
+
+// keepaliveSeconds converts the duration into the whole seconds of the TCP
+// keepalive config, nil if not set.
+func keepaliveSeconds(flagName string, d time.Duration) (*wrappers.UInt32Value, error) {
+	if d == 0 {
+		return nil, nil
+	}
+	if d < time.Second || d%time.Second != 0 {
+		return nil, fmt.Errorf("invalid flag --%s %v, must be a positive whole number of seconds", flagName, d)
+	}
+	return &wrappers.UInt32Value{Value: uint32(d / time.Second)}, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+)
+
+func TestMaybeAddConnectionOptions(t *testing.T) {
+	testData := []struct {
+		desc                string
+		configer            *ClusterConnectionConfiger
+		dnsLookupFamily     clusterpb.Cluster_DnsLookupFamily
+		wantDnsLookupFamily clusterpb.Cluster_DnsLookupFamily
+		wantKeepaliveTime   uint32
+		wantError           string
+	}{
+		{
+			desc:                "Nil configer keeps the cluster unchanged",
+			dnsLookupFamily:     clusterpb.Cluster_V4_PREFERRED,
+			wantDnsLookupFamily: clusterpb.Cluster_V4_PREFERRED,
+		},
+		{
+			desc: "TCP keepalive time only",
+			configer: &ClusterConnectionConfiger{
+				TcpKeepaliveTime: time.Minute,
+			},
+			dnsLookupFamily:     clusterpb.Cluster_V4_PREFERRED,
+			wantDnsLookupFamily: clusterpb.Cluster_V4_PREFERRED,
+			wantKeepaliveTime:   60,
+		},
+		{
+			desc: "Happy eyeballs looks up all the addresses",
+			configer: &ClusterConnectionConfiger{
+				HappyEyeballs: true,
+			},
+			dnsLookupFamily:     clusterpb.Cluster_V4_PREFERRED,
+			wantDnsLookupFamily: clusterpb.Cluster_ALL,
+		},
+		{
+			desc: "Happy eyeballs conflicts with v4only",
+			configer: &ClusterConnectionConfiger{
+				HappyEyeballs: true,
+			},
+			dnsLookupFamily: clusterpb.Cluster_V4_ONLY,
+			wantError:       "flag --backend_happy_eyeballs cannot be used with DNS lookup family V4_ONLY",
+		},
+		{
+			desc: "TCP keepalive time is not whole seconds",
+			configer: &ClusterConnectionConfiger{
+				TcpKeepaliveTime: 1500 * time.Millisecond,
+			},
+			wantError: "invalid flag --backend_tcp_keepalive_time 1.5s, must be a positive whole number of seconds",
+		},
+		{
+			desc: "Negative TCP keepalive probes",
+			configer: &ClusterConnectionConfiger{
+				TcpKeepaliveProbes: -1,
+			},
+			wantError: "invalid flag --backend_tcp_keepalive_probes -1, must be >= 0",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			cluster := &clusterpb.Cluster{
+				DnsLookupFamily: tc.dnsLookupFamily,
+			}
+			err := MaybeAddConnectionOptions(tc.configer, cluster)
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddConnectionOptions() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeAddConnectionOptions() got no error, want error %q", tc.wantError)
+			}
+
+			if cluster.DnsLookupFamily != tc.wantDnsLookupFamily {
+				t.Errorf("got DNS lookup family %v, want %v", cluster.DnsLookupFamily, tc.wantDnsLookupFamily)
+			}
+			if got := cluster.GetUpstreamConnectionOptions().GetTcpKeepalive().GetKeepaliveTime().GetValue(); got != tc.wantKeepaliveTime {
+				t.Errorf("got TCP keepalive time %v, want %v", got, tc.wantKeepaliveTime)
+			}
+		})
+	}
+}
+
+func TestMakeHttp2Keepalive(t *testing.T) {
+	testData := []struct {
+		desc         string
+		configer     *ClusterConnectionConfiger
+		wantInterval time.Duration
+		wantTimeout  time.Duration
+		wantJitter   float64
+		wantError    string
+	}{
+		{
+			desc:         "Nil configer uses the defaults",
+			wantInterval: 30 * time.Second,
+			wantTimeout:  10 * time.Second,
+		},
+		{
+			desc: "Unset fields use the defaults",
+			configer: &ClusterConnectionConfiger{
+				TcpKeepaliveTime: time.Minute,
+			},
+			wantInterval: 30 * time.Second,
+			wantTimeout:  10 * time.Second,
+		},
+		{
+			desc: "All fields are set",
+			configer: &ClusterConnectionConfiger{
+				Http2KeepaliveInterval:       2 * time.Minute,
+				Http2KeepaliveTimeout:        5 * time.Second,
+				Http2KeepaliveIntervalJitter: 20,
+			},
+			wantInterval: 2 * time.Minute,
+			wantTimeout:  5 * time.Second,
+			wantJitter:   20,
+		},
+		{
+			desc: "Interval is too small",
+			configer: &ClusterConnectionConfiger{
+				Http2KeepaliveInterval: time.Microsecond,
+			},
+			wantError: "invalid flag --backend_http2_keepalive_interval 1µs, must be >= 1ms",
+		},
+		{
+			desc: "Negative timeout",
+			configer: &ClusterConnectionConfiger{
+				Http2KeepaliveTimeout: -time.Second,
+			},
+			wantError: "invalid flag --backend_http2_keepalive_timeout -1s, must be >= 1ms",
+		},
+		{
+			desc: "Jitter is out of range",
+			configer: &ClusterConnectionConfiger{
+				Http2KeepaliveIntervalJitter: 101,
+			},
+			wantError: "invalid flag --backend_http2_keepalive_interval_jitter 101, must be in the range of [0, 100]",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			keepalive, err := tc.configer.MakeHttp2Keepalive()
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MakeHttp2Keepalive() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MakeHttp2Keepalive() got no error, want error %q", tc.wantError)
+			}
+
+			if got := keepalive.GetInterval().AsDuration(); got != tc.wantInterval {
+				t.Errorf("got interval %v, want %v", got, tc.wantInterval)
+			}
+			if got := keepalive.GetTimeout().AsDuration(); got != tc.wantTimeout {
+				t.Errorf("got timeout %v, want %v", got, tc.wantTimeout)
+			}
+			if got := keepalive.GetIntervalJitter().GetValue(); got != tc.wantJitter {
+				t.Errorf("got interval jitter %v, want %v", got, tc.wantJitter)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// FailoverBackend is the backend receiving the requests once all the hosts of
+// the primary backend are unhealthy.
+type FailoverBackend struct {
+	Hostname string
+	Port     uint32
+}
+
+// ClusterFailoverConfiger is a helper to add the failover backend to a backend
+// cluster as a lower priority level.
+type ClusterFailoverConfiger struct {
+	// FailoverAddresses is flag --backend_failover_addresses.
+	FailoverAddresses string
+}
+
+// NewClusterFailoverConfigerFromOPConfig creates a ClusterFailoverConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterFailoverConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterFailoverConfiger {
+	if opts.BackendFailoverAddresses == "" {
+		return nil
+	}
+
+	return &ClusterFailoverConfiger{
+		FailoverAddresses: opts.BackendFailoverAddresses,
+	}
+}
+
+// ParseBackendFailoverAddresses parses flag --backend_failover_addresses in
+// the format of "primary_address1=failover_address1;primary_address2=failover_address2"
+// into the failover backends keyed by the "hostname:port" of the primary
+// backends. The failover backend must use the same protocol as the primary.
+func ParseBackendFailoverAddresses(s string) (map[string]*FailoverBackend, error) {
+	failovers := make(map[string]*FailoverBackend)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid failover mapping %q, must be in the format of primary_address=failover_address", pair)
+		}
+		primary, failover := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+
+		primaryScheme, primaryHostname, primaryPort, _, err := util.ParseURI(primary)
+		if err != nil {
+			return nil, fmt.Errorf("invalid primary address %q: %v", primary, err)
+		}
+		failoverScheme, failoverHostname, failoverPort, _, err := util.ParseURI(failover)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failover address %q: %v", failover, err)
+		}
+		if primaryScheme != failoverScheme {
+			return nil, fmt.Errorf("failover address %q must use the scheme %q of primary address %q", failover, primaryScheme, primary)
+		}
+
+		address := fmt.Sprintf("%v:%v", primaryHostname, primaryPort)
+		if _, ok := failovers[address]; ok {
+			return nil, fmt.Errorf("primary address %q has more than one failover address", primary)
+		}
+		failovers[address] = &FailoverBackend{
+			Hostname: failoverHostname,
+			Port:     failoverPort,
+		}
+	}
+	return failovers, nil
+}
+
+// MaybeAddFailover adds the failover backend of the primary backend to the
+// cluster as priority level 1, so it only receives requests once the hosts of
+// the primary backend, priority level 0, are ejected as unhealthy by the
+// outlier detection or the active health check.
+//
+// The endpoints carry their hostnames, so the routes rewrite the Host header
+// and the TLS SNI to the hostname of the chosen backend.
+func MaybeAddFailover(c *ClusterFailoverConfiger, hostname string, port uint32, config *clusterpb.Cluster) error {
+	if c == nil {
+		return nil
+	}
+
+	failovers, err := ParseBackendFailoverAddresses(c.FailoverAddresses)
+	if err != nil {
+		return fmt.Errorf("invalid flag --backend_failover_addresses: %v", err)
+	}
+	failover, ok := failovers[fmt.Sprintf("%v:%v", hostname, port)]
+	if !ok {
+		return nil
+	}
+
+	// Logical DNS clusters only support a single endpoint.
+	config.ClusterDiscoveryType = &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STRICT_DNS}
+	config.LoadAssignment = &endpointpb.ClusterLoadAssignment{
+		ClusterName: hostname,
+		Endpoints: []*endpointpb.LocalityLbEndpoints{
+			makePriorityLbEndpoints(hostname, port, 0),
+			makePriorityLbEndpoints(failover.Hostname, failover.Port, 1),
+		},
+	}
+	if config.OutlierDetection == nil {
+		config.OutlierDetection = &clusterpb.OutlierDetection{
+			// All the hosts of the primary backend may be ejected in an outage.
+			MaxEjectionPercent: wrapperspb.UInt32(100),
+		}
+	}
+
+	if config.TransportSocket != nil && failover.Hostname != hostname {
+		return addAutoSni(config)
+	}
+	return nil
+}
+
+func makePriorityLbEndpoints(hostname string, port uint32, priority uint32) *endpointpb.LocalityLbEndpoints {
+	return &endpointpb.LocalityLbEndpoints{
+		Priority: priority,
+		LbEndpoints: []*endpointpb.LbEndpoint{
+			{
+				HostIdentifier: &endpointpb.LbEndpoint_Endpoint{
+					Endpoint: &endpointpb.Endpoint{
+						Address: &corepb.Address{
+							Address: &corepb.Address_SocketAddress{
+								SocketAddress: &corepb.SocketAddress{
+									Address: hostname,
+									PortSpecifier: &corepb.SocketAddress_PortValue{
+										PortValue: port,
+									},
+								},
+							},
+						},
+						Hostname: hostname,
+					},
+				},
+			},
+		},
+	}
+}
+
+// addAutoSni sets the TLS SNI of the upstream connections, and validates the
+// server certificate, by the rewritten Host header of the requests.
+func addAutoSni(config *clusterpb.Cluster) error {
+	protocolOptions := &httppb.HttpProtocolOptions{
+		UpstreamProtocolOptions: &httppb.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig_HttpProtocolOptions{},
+			},
+		},
+	}
+	if existing, ok := config.TypedExtensionProtocolOptions[util.UpstreamProtocolOptions]; ok {
+		if err := existing.UnmarshalTo(protocolOptions); err != nil {
+			return fmt.Errorf("fail to unmarshal upstream protocol options: %v", err)
+		}
+	}
+	protocolOptions.UpstreamHttpProtocolOptions = &corepb.UpstreamHttpProtocolOptions{
+		AutoSni:           true,
+		AutoSanValidation: true,
+	}
+
+	a, err := anypb.New(protocolOptions)
+	if err != nil {
+		return err
+	}
+	if config.TypedExtensionProtocolOptions == nil {
+		config.TypedExtensionProtocolOptions = make(map[string]*anypb.Any)
+	}
+	config.TypedExtensionProtocolOptions[util.UpstreamProtocolOptions] = a
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMaybeAddFailover(t *testing.T) {
+	primaryOnlyLoadAssignment := util.CreateLoadAssignment("primary.run.app", 443)
+	tlsTransportSocket := &corepb.TransportSocket{Name: "envoy.transport_sockets.tls"}
+
+	autoSniProtocolOptions, err := anypb.New(&httppb.HttpProtocolOptions{
+		UpstreamProtocolOptions: &httppb.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig_HttpProtocolOptions{},
+			},
+		},
+		UpstreamHttpProtocolOptions: &corepb.UpstreamHttpProtocolOptions{
+			AutoSni:           true,
+			AutoSanValidation: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New() got error: %v", err)
+	}
+
+	testData := []struct {
+		desc          string
+		configer      *ClusterFailoverConfiger
+		transportSock *corepb.TransportSocket
+		wantCluster   *clusterpb.Cluster
+		wantError     string
+	}{
+		{
+			desc: "Nil configer keeps the cluster unchanged",
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+				LoadAssignment:       primaryOnlyLoadAssignment,
+			},
+		},
+		{
+			desc: "Backend without failover address keeps the cluster unchanged",
+			configer: &ClusterFailoverConfiger{
+				FailoverAddresses: "https://other.run.app=https://other-failover.run.app",
+			},
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+				LoadAssignment:       primaryOnlyLoadAssignment,
+			},
+		},
+		{
+			desc: "Failover backend is added as priority level 1",
+			configer: &ClusterFailoverConfiger{
+				FailoverAddresses: "https://primary.run.app=https://failover.run.app:8443",
+			},
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STRICT_DNS},
+				LoadAssignment: &endpointpb.ClusterLoadAssignment{
+					ClusterName: "primary.run.app",
+					Endpoints: []*endpointpb.LocalityLbEndpoints{
+						makePriorityLbEndpoints("primary.run.app", 443, 0),
+						makePriorityLbEndpoints("failover.run.app", 8443, 1),
+					},
+				},
+				OutlierDetection: &clusterpb.OutlierDetection{
+					MaxEjectionPercent: wrapperspb.UInt32(100),
+				},
+			},
+		},
+		{
+			desc: "TLS failover backend with another hostname sets the SNI per request",
+			configer: &ClusterFailoverConfiger{
+				FailoverAddresses: "https://primary.run.app=https://failover.run.app",
+			},
+			transportSock: tlsTransportSocket,
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STRICT_DNS},
+				LoadAssignment: &endpointpb.ClusterLoadAssignment{
+					ClusterName: "primary.run.app",
+					Endpoints: []*endpointpb.LocalityLbEndpoints{
+						makePriorityLbEndpoints("primary.run.app", 443, 0),
+						makePriorityLbEndpoints("failover.run.app", 443, 1),
+					},
+				},
+				OutlierDetection: &clusterpb.OutlierDetection{
+					MaxEjectionPercent: wrapperspb.UInt32(100),
+				},
+				TransportSocket: tlsTransportSocket,
+				TypedExtensionProtocolOptions: map[string]*anypb.Any{
+					util.UpstreamProtocolOptions: autoSniProtocolOptions,
+				},
+			},
+		},
+		{
+			desc: "Failover backend with another scheme",
+			configer: &ClusterFailoverConfiger{
+				FailoverAddresses: "https://primary.run.app=http://failover.run.app",
+			},
+			wantError: `invalid flag --backend_failover_addresses: failover address "http://failover.run.app" must use the scheme "https" of primary address "https://primary.run.app"`,
+		},
+		{
+			desc: "Malformed failover mapping",
+			configer: &ClusterFailoverConfiger{
+				FailoverAddresses: "https://primary.run.app",
+			},
+			wantError: `invalid failover mapping "https://primary.run.app"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			cluster := &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+				LoadAssignment:       util.CreateLoadAssignment("primary.run.app", 443),
+				TransportSocket:      tc.transportSock,
+			}
+			err := MaybeAddFailover(tc.configer, "primary.run.app", 443, cluster)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddFailover() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MaybeAddFailover() got error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantCluster, cluster, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddFailover() cluster diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
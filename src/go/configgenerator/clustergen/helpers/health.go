@@ -16,11 +16,15 @@ package helpers
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoytypepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"google.golang.org/protobuf/types/known/durationpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -81,3 +85,183 @@ func (c *ClusterGRPCHealthCheckConfiger) MakeHealthConfig() ([]*corepb.HealthChe
 		},
 	}, nil
 }
+
+// ClusterOutlierDetectionConfiger is a helper to set outlier detection config
+// on a cluster, so hosts that keep failing are marked unhealthy.
+type ClusterOutlierDetectionConfiger struct {
+	ConsecutiveFailures uint
+	EvaluationWindow    time.Duration
+}
+
+// NewClusterOutlierDetectionConfigerFromOPConfig creates a ClusterOutlierDetectionConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterOutlierDetectionConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterOutlierDetectionConfiger {
+	if !opts.HealthzCheckBackend {
+		return nil
+	}
+
+	return &ClusterOutlierDetectionConfiger{
+		ConsecutiveFailures: opts.HealthzBackendConsecutiveFailures,
+		EvaluationWindow:    opts.HealthzBackendEvaluationWindow,
+	}
+}
+
+// MaybeAddOutlierDetection adds the generated outlier detection config to the cluster.
+func MaybeAddOutlierDetection(outlierDetector *ClusterOutlierDetectionConfiger, cluster *clusterpb.Cluster) error {
+	if outlierDetector == nil {
+		return nil
+	}
+
+	outlierDetection, err := outlierDetector.MakeOutlierDetectionConfig()
+	if err != nil {
+		return fmt.Errorf("fail to create outlier detection for cluster: %v", err)
+	}
+
+	cluster.OutlierDetection = outlierDetection
+	return nil
+}
+
+// MakeOutlierDetectionConfig creates an OutlierDetection for a cluster.
+// Connection failures are counted as 5xx, and every host may be ejected.
+func (c *ClusterOutlierDetectionConfiger) MakeOutlierDetectionConfig() (*clusterpb.OutlierDetection, error) {
+	if c.ConsecutiveFailures == 0 {
+		return nil, fmt.Errorf("consecutive failures must be greater than 0")
+	}
+	if c.EvaluationWindow <= 0 {
+		return nil, fmt.Errorf("evaluation window must be greater than 0, got %v", c.EvaluationWindow)
+	}
+
+	windowProto := durationpb.New(c.EvaluationWindow)
+	return &clusterpb.OutlierDetection{
+		Consecutive_5Xx:    &wrappers.UInt32Value{Value: uint32(c.ConsecutiveFailures)},
+		Interval:           windowProto,
+		BaseEjectionTime:   windowProto,
+		MaxEjectionPercent: &wrappers.UInt32Value{Value: 100},
+	}, nil
+}
+
+// ClusterActiveHealthCheckConfiger is a helper to set active health check
+// config on a backend cluster.
+//
+// gRPC backends are checked with the gRPC Health service if GRPC is set,
+// other backends are checked with HTTP requests to Path if it is set.
+type ClusterActiveHealthCheckConfiger struct {
+	Path               string
+	GRPC               bool
+	Interval           time.Duration
+	Timeout            time.Duration
+	HealthyThreshold   uint
+	UnhealthyThreshold uint
+	// ExpectedStatuses is a comma separated list of HTTP status codes or
+	// inclusive ranges, such as "200,300-399". Empty means 200 only.
+	ExpectedStatuses string
+}
+
+// NewClusterActiveHealthCheckConfigerFromOPConfig creates a ClusterActiveHealthCheckConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterActiveHealthCheckConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterActiveHealthCheckConfiger {
+	if opts.BackendHealthCheckPath == "" && !opts.BackendHealthCheckGrpc {
+		return nil
+	}
+
+	return &ClusterActiveHealthCheckConfiger{
+		Path:               opts.BackendHealthCheckPath,
+		GRPC:               opts.BackendHealthCheckGrpc,
+		Interval:           opts.BackendHealthCheckInterval,
+		Timeout:            opts.BackendHealthCheckTimeout,
+		HealthyThreshold:   opts.BackendHealthCheckHealthyThreshold,
+		UnhealthyThreshold: opts.BackendHealthCheckUnhealthyThreshold,
+		ExpectedStatuses:   opts.BackendHealthCheckExpectedStatuses,
+	}
+}
+
+// MaybeAddActiveHealthCheck adds the generated active health check config to
+// the backend cluster with the given hostname and protocol.
+func MaybeAddActiveHealthCheck(activeHealthChecker *ClusterActiveHealthCheckConfiger, hostname string, protocol util.BackendProtocol, cluster *clusterpb.Cluster) error {
+	if activeHealthChecker == nil {
+		return nil
+	}
+
+	healthChecks, err := activeHealthChecker.MakeHealthConfig(hostname, protocol)
+	if err != nil {
+		return fmt.Errorf("fail to create active health checks for cluster: %v", err)
+	}
+
+	cluster.HealthChecks = healthChecks
+	return nil
+}
+
+// MakeHealthConfig creates the active HealthCheck for a backend cluster.
+// It returns nil if the backend should not be checked.
+func (c *ClusterActiveHealthCheckConfiger) MakeHealthConfig(hostname string, protocol util.BackendProtocol) ([]*corepb.HealthCheck, error) {
+	healthCheck := &corepb.HealthCheck{
+		Timeout:            durationpb.New(c.Timeout),
+		Interval:           durationpb.New(c.Interval),
+		HealthyThreshold:   &wrappers.UInt32Value{Value: uint32(c.HealthyThreshold)},
+		UnhealthyThreshold: &wrappers.UInt32Value{Value: uint32(c.UnhealthyThreshold)},
+	}
+
+	switch {
+	case c.GRPC && protocol == util.GRPC:
+		healthCheck.HealthChecker = &corepb.HealthCheck_GrpcHealthCheck_{
+			GrpcHealthCheck: &corepb.HealthCheck_GrpcHealthCheck{
+				Authority: hostname,
+			},
+		}
+	case c.Path != "":
+		expectedStatuses, err := parseExpectedStatuses(c.ExpectedStatuses)
+		if err != nil {
+			return nil, err
+		}
+
+		httpHealthCheck := &corepb.HealthCheck_HttpHealthCheck{
+			Host:             hostname,
+			Path:             c.Path,
+			ExpectedStatuses: expectedStatuses,
+		}
+		if protocol == util.GRPC || protocol == util.HTTP2 {
+			httpHealthCheck.CodecClientType = envoytypepb.CodecClientType_HTTP2
+		}
+		healthCheck.HealthChecker = &corepb.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: httpHealthCheck,
+		}
+	default:
+		return nil, nil
+	}
+
+	return []*corepb.HealthCheck{healthCheck}, nil
+}
+
+// parseExpectedStatuses parses status codes like "200,300-399" into Envoy
+// ranges, whose ends are exclusive.
+func parseExpectedStatuses(statuses string) ([]*envoytypepb.Int64Range, error) {
+	if statuses == "" {
+		return nil, nil
+	}
+
+	var ranges []*envoytypepb.Int64Range
+	for _, status := range strings.Split(statuses, ",") {
+		start, end := strings.TrimSpace(status), strings.TrimSpace(status)
+		if i := strings.Index(status, "-"); i != -1 {
+			start, end = strings.TrimSpace(status[:i]), strings.TrimSpace(status[i+1:])
+		}
+
+		startCode, err := strconv.ParseInt(start, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expected status %q", status)
+		}
+		endCode, err := strconv.ParseInt(end, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expected status %q", status)
+		}
+		if startCode < 100 || endCode > 599 || startCode > endCode {
+			return nil, fmt.Errorf("invalid expected status %q, must be in the range of [100, 599]", status)
+		}
+
+		ranges = append(ranges, &envoytypepb.Int64Range{
+			Start: startCode,
+			End:   endCode + 1,
+		})
+	}
+	return ranges, nil
+}
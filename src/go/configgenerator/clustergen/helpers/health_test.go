@@ -0,0 +1,113 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+func TestClusterActiveHealthCheckConfiger_MakeHealthConfig(t *testing.T) {
+	testData := []struct {
+		desc             string
+		configer         ClusterActiveHealthCheckConfiger
+		protocol         util.BackendProtocol
+		wantHealthChecks string
+		wantError        string
+	}{
+		{
+			desc: "HTTP health check over HTTP/2 with expected statuses",
+			configer: ClusterActiveHealthCheckConfiger{
+				Path:             "/healthz",
+				ExpectedStatuses: "200, 204-206",
+			},
+			protocol: util.HTTP2,
+			wantHealthChecks: `{
+  "timeout": "0s",
+  "interval": "0s",
+  "healthyThreshold": 0,
+  "unhealthyThreshold": 0,
+  "httpHealthCheck": {
+    "host": "mybackend.com",
+    "path": "/healthz",
+    "expectedStatuses": [
+      {"start": "200", "end": "201"},
+      {"start": "204", "end": "207"}
+    ],
+    "codecClientType": "HTTP2"
+  }
+}`,
+		},
+		{
+			desc: "No health check for non-gRPC backend without path",
+			configer: ClusterActiveHealthCheckConfiger{
+				GRPC: true,
+			},
+			protocol: util.HTTP1,
+		},
+		{
+			desc: "Invalid expected status",
+			configer: ClusterActiveHealthCheckConfiger{
+				Path:             "/healthz",
+				ExpectedStatuses: "200-abc",
+			},
+			protocol:  util.HTTP1,
+			wantError: `invalid expected status "200-abc"`,
+		},
+		{
+			desc: "Expected status out of range",
+			configer: ClusterActiveHealthCheckConfiger{
+				Path:             "/healthz",
+				ExpectedStatuses: "200-600",
+			},
+			protocol:  util.HTTP1,
+			wantError: "must be in the range of [100, 599]",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			healthChecks, err := tc.configer.MakeHealthConfig("mybackend.com", tc.protocol)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MakeHealthConfig() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MakeHealthConfig() got error %v", err)
+			}
+
+			if tc.wantHealthChecks == "" {
+				if healthChecks != nil {
+					t.Fatalf("MakeHealthConfig() got %v, want no health check", healthChecks)
+				}
+				return
+			}
+			if len(healthChecks) != 1 {
+				t.Fatalf("MakeHealthConfig() got %d health checks, want 1", len(healthChecks))
+			}
+			gotHealthCheck, err := util.ProtoToJson(healthChecks[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := util.JsonEqual(tc.wantHealthChecks, gotHealthCheck); err != nil {
+				t.Errorf("MakeHealthConfig() got unexpected health check: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+const (
+	// DownstreamProtocolSelection uses the protocol of the downstream
+	// connection for the backend requests.
+	DownstreamProtocolSelection = "downstream"
+
+	// AutoProtocolSelection chooses HTTP/2 or HTTP/1.1 for the backend
+	// connections by the TLS ALPN negotiation.
+	AutoProtocolSelection = "auto"
+)
+
+// ClusterProtocolSelectionConfiger is a helper to choose the upstream
+// protocol of a backend cluster serving both HTTP/1.1 and HTTP/2, instead of
+// the single protocol of the backend address.
+type ClusterProtocolSelectionConfiger struct {
+	// ProtocolSelection is the selector map of flag
+	// --backend_protocol_selection, keyed by the "hostname:port" of the
+	// backends.
+	ProtocolSelection string
+}
+
+// NewClusterProtocolSelectionConfigerFromOPConfig creates a ClusterProtocolSelectionConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterProtocolSelectionConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterProtocolSelectionConfiger {
+	if opts.BackendProtocolSelection == "" {
+		return nil
+	}
+
+	return &ClusterProtocolSelectionConfiger{
+		ProtocolSelection: opts.BackendProtocolSelection,
+	}
+}
+
+// LookupProtocolSelection returns the protocol selection of the backend, empty
+// if the protocol of the backend address is used. The configer may be nil.
+func (c *ClusterProtocolSelectionConfiger) LookupProtocolSelection(hostname string, port uint32, protocol util.BackendProtocol) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+
+	selections, err := util.ParseSelectorMap(c.ProtocolSelection)
+	if err != nil {
+		return "", fmt.Errorf("invalid flag --backend_protocol_selection: %v", err)
+	}
+	for _, selection := range selections.Values() {
+		if selection != DownstreamProtocolSelection && selection != AutoProtocolSelection {
+			return "", fmt.Errorf("invalid flag --backend_protocol_selection, protocol selection %q must be %q or %q", selection, DownstreamProtocolSelection, AutoProtocolSelection)
+		}
+	}
+
+	address := fmt.Sprintf("%s:%d", hostname, port)
+	selection, ok := selections.Lookup(address)
+	if !ok {
+		return "", nil
+	}
+	if protocol == util.GRPC {
+		return "", fmt.Errorf("invalid flag --backend_protocol_selection, backend %q is a gRPC backend which always uses HTTP/2", address)
+	}
+	return selection, nil
+}
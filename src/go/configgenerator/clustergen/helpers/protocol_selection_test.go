@@ -0,0 +1,130 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+)
+
+func TestGenBaseConfigWithProtocolSelection(t *testing.T) {
+	testData := []struct {
+		desc              string
+		hostname          string
+		protocol          util.BackendProtocol
+		useTLS            bool
+		protocolSelection string
+		wantDownstream    bool
+		wantAuto          bool
+		wantHttp2         bool
+		wantError         string
+	}{
+		{
+			desc:              "Unmatched backend uses the protocol of the address",
+			hostname:          "other.example.com",
+			protocol:          util.HTTP2,
+			protocolSelection: "backend.example.com:8080=downstream",
+			wantHttp2:         true,
+		},
+		{
+			desc:              "HTTP/1.1 backend uses the downstream protocol",
+			hostname:          "backend.example.com",
+			protocol:          util.HTTP1,
+			protocolSelection: "backend.example.com:8080=downstream",
+			wantDownstream:    true,
+		},
+		{
+			desc:              "HTTPS backend chooses the protocol by ALPN with wildcard",
+			hostname:          "backend.example.com",
+			protocol:          util.HTTP1,
+			useTLS:            true,
+			protocolSelection: "*.example.com:*=auto",
+			wantAuto:          true,
+		},
+		{
+			desc:              "Auto protocol selection requires TLS",
+			hostname:          "backend.example.com",
+			protocol:          util.HTTP1,
+			protocolSelection: "*=auto",
+			wantError:         `backend backend.example.com:8080 must use TLS for protocol selection "auto"`,
+		},
+		{
+			desc:              "gRPC backend cannot choose the protocol",
+			hostname:          "backend.example.com",
+			protocol:          util.GRPC,
+			protocolSelection: "*=downstream",
+			wantError:         `backend "backend.example.com:8080" is a gRPC backend which always uses HTTP/2`,
+		},
+		{
+			desc:              "Unknown protocol selection",
+			hostname:          "backend.example.com",
+			protocol:          util.HTTP1,
+			protocolSelection: "*=h2c",
+			wantError:         `protocol selection "h2c" must be "downstream" or "auto"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			var tls *ClusterTLSConfiger
+			if tc.useTLS {
+				tls = &ClusterTLSConfiger{
+					RootCertsPath: util.DefaultRootCAPaths,
+				}
+			}
+			cluster := &BaseBackendCluster{
+				ClusterName:            "backend-cluster",
+				Hostname:               tc.hostname,
+				Port:                   8080,
+				Protocol:               tc.protocol,
+				ClusterConnectTimeout:  20 * time.Second,
+				BackendDnsLookupFamily: "v4preferred",
+				TLS:                    tls,
+				ProtocolSelection: &ClusterProtocolSelectionConfiger{
+					ProtocolSelection: tc.protocolSelection,
+				},
+			}
+
+			config, err := cluster.GenBaseConfig()
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("GenBaseConfig() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("GenBaseConfig() got no error, want error %q", tc.wantError)
+			}
+
+			protocolOptions := &httppb.HttpProtocolOptions{}
+			if err := config.GetTypedExtensionProtocolOptions()[util.UpstreamProtocolOptions].UnmarshalTo(protocolOptions); err != nil {
+				t.Fatalf("fail to unmarshal upstream protocol options: %v", err)
+			}
+			if got := protocolOptions.GetUseDownstreamProtocolConfig() != nil; got != tc.wantDownstream {
+				t.Errorf("got downstream protocol config %v, want %v", got, tc.wantDownstream)
+			}
+			if got := protocolOptions.GetAutoConfig() != nil; got != tc.wantAuto {
+				t.Errorf("got auto config %v, want %v", got, tc.wantAuto)
+			}
+			if got := protocolOptions.GetExplicitHttpConfig().GetHttp2ProtocolOptions() != nil; got != tc.wantHttp2 {
+				t.Errorf("got explicit HTTP/2 config %v, want %v", got, tc.wantHttp2)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ClusterRetryBudgetConfiger is a helper to limit the concurrent retries to
+// a backend cluster, so the retries cannot overload the backend.
+type ClusterRetryBudgetConfiger struct {
+	// BudgetPercent is the percent of the active requests allowed to be
+	// retries.
+	BudgetPercent float64
+
+	// MinRetryConcurrency is the number of concurrent retries always
+	// allowed, 0 for the Envoy default.
+	MinRetryConcurrency uint
+}
+
+// NewClusterRetryBudgetConfigerFromOPConfig creates a ClusterRetryBudgetConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterRetryBudgetConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterRetryBudgetConfiger {
+	if opts.BackendRetryBudgetPercent == 0 {
+		return nil
+	}
+
+	return &ClusterRetryBudgetConfiger{
+		BudgetPercent:       opts.BackendRetryBudgetPercent,
+		MinRetryConcurrency: opts.BackendRetryBudgetMinConcurrency,
+	}
+}
+
+// MaybeAddRetryBudget adds the generated retry budget to the circuit breakers
+// of all the routing priorities of the given cluster. It must be called after
+// the circuit breakers are set.
+func MaybeAddRetryBudget(retryBudgetConfiger *ClusterRetryBudgetConfiger, cluster *clusterpb.Cluster) error {
+	if retryBudgetConfiger == nil {
+		return nil
+	}
+
+	retryBudget, err := retryBudgetConfiger.MakeRetryBudget()
+	if err != nil {
+		return err
+	}
+
+	if cluster.CircuitBreakers == nil {
+		cluster.CircuitBreakers = &clusterpb.CircuitBreakers{
+			Thresholds: []*clusterpb.CircuitBreakers_Thresholds{
+				{Priority: corepb.RoutingPriority_DEFAULT},
+				{Priority: corepb.RoutingPriority_HIGH},
+			},
+		}
+	}
+	for _, thresholds := range cluster.CircuitBreakers.Thresholds {
+		thresholds.RetryBudget = retryBudget
+	}
+	return nil
+}
+
+// MakeRetryBudget creates the retry budget of the circuit breakers.
+func (c *ClusterRetryBudgetConfiger) MakeRetryBudget() (*clusterpb.CircuitBreakers_Thresholds_RetryBudget, error) {
+	if c.BudgetPercent <= 0 || c.BudgetPercent > 100 {
+		return nil, fmt.Errorf("invalid flag --backend_retry_budget_percent %v, must be in (0, 100]", c.BudgetPercent)
+	}
+
+	retryBudget := &clusterpb.CircuitBreakers_Thresholds_RetryBudget{
+		BudgetPercent: &typepb.Percent{Value: c.BudgetPercent},
+	}
+	if c.MinRetryConcurrency > 0 {
+		retryBudget.MinRetryConcurrency = &wrappers.UInt32Value{Value: uint32(c.MinRetryConcurrency)}
+	}
+	return retryBudget, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMaybeAddRetryBudget(t *testing.T) {
+	testData := []struct {
+		desc                string
+		configer            *ClusterRetryBudgetConfiger
+		circuitBreakers     *clusterpb.CircuitBreakers
+		wantCircuitBreakers *clusterpb.CircuitBreakers
+		wantError           string
+	}{
+		{
+			desc: "Nil configer keeps the cluster unchanged",
+		},
+		{
+			desc: "Retry budget is added to all the priorities",
+			configer: &ClusterRetryBudgetConfiger{
+				BudgetPercent: 20,
+			},
+			wantCircuitBreakers: &clusterpb.CircuitBreakers{
+				Thresholds: []*clusterpb.CircuitBreakers_Thresholds{
+					{
+						Priority: corepb.RoutingPriority_DEFAULT,
+						RetryBudget: &clusterpb.CircuitBreakers_Thresholds_RetryBudget{
+							BudgetPercent: &typepb.Percent{Value: 20},
+						},
+					},
+					{
+						Priority: corepb.RoutingPriority_HIGH,
+						RetryBudget: &clusterpb.CircuitBreakers_Thresholds_RetryBudget{
+							BudgetPercent: &typepb.Percent{Value: 20},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Retry budget is merged with the max requests",
+			configer: &ClusterRetryBudgetConfiger{
+				BudgetPercent:       12.5,
+				MinRetryConcurrency: 5,
+			},
+			circuitBreakers: &clusterpb.CircuitBreakers{
+				Thresholds: []*clusterpb.CircuitBreakers_Thresholds{
+					makeCircuitBreakersThresholds(corepb.RoutingPriority_DEFAULT, 100),
+				},
+			},
+			wantCircuitBreakers: &clusterpb.CircuitBreakers{
+				Thresholds: []*clusterpb.CircuitBreakers_Thresholds{
+					{
+						Priority:    corepb.RoutingPriority_DEFAULT,
+						MaxRequests: &wrappers.UInt32Value{Value: 100},
+						RetryBudget: &clusterpb.CircuitBreakers_Thresholds_RetryBudget{
+							BudgetPercent:       &typepb.Percent{Value: 12.5},
+							MinRetryConcurrency: &wrappers.UInt32Value{Value: 5},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Budget percent is out of range",
+			configer: &ClusterRetryBudgetConfiger{
+				BudgetPercent: 120,
+			},
+			wantError: "invalid flag --backend_retry_budget_percent 120, must be in (0, 100]",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			cluster := &clusterpb.Cluster{
+				CircuitBreakers: tc.circuitBreakers,
+			}
+			err := MaybeAddRetryBudget(tc.configer, cluster)
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddRetryBudget() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeAddRetryBudget() got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantCircuitBreakers, cluster.CircuitBreakers, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddRetryBudget() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
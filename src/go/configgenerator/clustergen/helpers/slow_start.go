@@ -0,0 +1,98 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	slowStartAggressionRuntimeKey = "espv2.backend_slow_start.aggression"
+)
+
+// ClusterSlowStartConfiger is a helper to ramp up the traffic share of the
+// newly added endpoints of a backend cluster over the slow start window.
+type ClusterSlowStartConfiger struct {
+	Window time.Duration
+
+	// Aggression is the non-linearity of the traffic ramp up, 1.0 for a
+	// linear ramp up.
+	Aggression float64
+}
+
+// NewClusterSlowStartConfigerFromOPConfig creates a ClusterSlowStartConfiger from
+// OP service config + descriptor + ESPv2 options.
+func NewClusterSlowStartConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *ClusterSlowStartConfiger {
+	if opts.BackendSlowStartWindow == 0 {
+		return nil
+	}
+
+	return &ClusterSlowStartConfiger{
+		Window:     opts.BackendSlowStartWindow,
+		Aggression: opts.BackendSlowStartAggression,
+	}
+}
+
+// MaybeAddSlowStart adds the generated slow start config to the round robin
+// load balancer of the given cluster.
+//
+// The cluster resolves all the addresses of the backend hostname as separate
+// endpoints, so the newly resolved addresses, such as the new instances of
+// a scaled backend, are ramped up.
+func MaybeAddSlowStart(slowStartConfiger *ClusterSlowStartConfiger, cluster *clusterpb.Cluster) error {
+	if slowStartConfiger == nil {
+		return nil
+	}
+
+	slowStart, err := slowStartConfiger.MakeSlowStartConfig()
+	if err != nil {
+		return err
+	}
+
+	cluster.ClusterDiscoveryType = &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STRICT_DNS}
+	cluster.LbConfig = &clusterpb.Cluster_RoundRobinLbConfig_{
+		RoundRobinLbConfig: &clusterpb.Cluster_RoundRobinLbConfig{
+			SlowStartConfig: slowStart,
+		},
+	}
+	return nil
+}
+
+// MakeSlowStartConfig creates the slow start config of the load balancer.
+func (c *ClusterSlowStartConfiger) MakeSlowStartConfig() (*clusterpb.Cluster_SlowStartConfig, error) {
+	if c.Window < 0 {
+		return nil, fmt.Errorf("invalid flag --backend_slow_start_window %v, must be >= 0", c.Window)
+	}
+	if c.Aggression < 0 {
+		return nil, fmt.Errorf("invalid flag --backend_slow_start_aggression %v, must be >= 0", c.Aggression)
+	}
+
+	slowStart := &clusterpb.Cluster_SlowStartConfig{
+		SlowStartWindow: durationpb.New(c.Window),
+	}
+	if c.Aggression > 0 {
+		slowStart.Aggression = &corepb.RuntimeDouble{
+			DefaultValue: c.Aggression,
+			RuntimeKey:   slowStartAggressionRuntimeKey,
+		}
+	}
+	return slowStart, nil
+}
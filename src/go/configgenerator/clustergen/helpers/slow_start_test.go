@@ -0,0 +1,110 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMaybeAddSlowStart(t *testing.T) {
+	testData := []struct {
+		desc        string
+		configer    *ClusterSlowStartConfiger
+		wantCluster *clusterpb.Cluster
+		wantError   string
+	}{
+		{
+			desc: "Nil configer keeps the cluster unchanged",
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+			},
+		},
+		{
+			desc: "Slow start resolves all the addresses with a linear ramp up by default",
+			configer: &ClusterSlowStartConfiger{
+				Window: 30 * time.Second,
+			},
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STRICT_DNS},
+				LbConfig: &clusterpb.Cluster_RoundRobinLbConfig_{
+					RoundRobinLbConfig: &clusterpb.Cluster_RoundRobinLbConfig{
+						SlowStartConfig: &clusterpb.Cluster_SlowStartConfig{
+							SlowStartWindow: durationpb.New(30 * time.Second),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Slow start with aggression",
+			configer: &ClusterSlowStartConfiger{
+				Window:     time.Minute,
+				Aggression: 2.5,
+			},
+			wantCluster: &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STRICT_DNS},
+				LbConfig: &clusterpb.Cluster_RoundRobinLbConfig_{
+					RoundRobinLbConfig: &clusterpb.Cluster_RoundRobinLbConfig{
+						SlowStartConfig: &clusterpb.Cluster_SlowStartConfig{
+							SlowStartWindow: durationpb.New(time.Minute),
+							Aggression: &corepb.RuntimeDouble{
+								DefaultValue: 2.5,
+								RuntimeKey:   "espv2.backend_slow_start.aggression",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Negative aggression",
+			configer: &ClusterSlowStartConfiger{
+				Window:     time.Minute,
+				Aggression: -1,
+			},
+			wantError: "invalid flag --backend_slow_start_aggression -1, must be >= 0",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			cluster := &clusterpb.Cluster{
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+			}
+			err := MaybeAddSlowStart(tc.configer, cluster)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddSlowStart() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MaybeAddSlowStart() got error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantCluster, cluster, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddSlowStart() cluster diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
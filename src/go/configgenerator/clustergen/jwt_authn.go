@@ -16,12 +16,15 @@ package clustergen
 
 import (
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -34,6 +37,10 @@ type JWTProviderCluster struct {
 	JWKSURI               string
 	ClusterConnectTimeout time.Duration
 
+	// ResolvedAddresses are the IP addresses of the JWKS URI hostname resolved
+	// by flag --jwks_resolve_at_startup. If set, the cluster is static.
+	ResolvedAddresses []string
+
 	DNS *helpers.ClusterDNSConfiger
 	TLS *helpers.ClusterTLSConfiger
 }
@@ -64,10 +71,23 @@ func NewJWTProviderClustersFromOPConfig(serviceConfig *servicepb.Service, opts o
 		}
 		dedupClusterNames[addr] = true
 
+		var resolvedAddresses []string
+		if opts.JwksResolveAtStartup {
+			_, hostname, _, _, err := util.ParseURI(jwksURI)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse JWKS URI: %v", err)
+			}
+			resolvedAddresses = opts.JwksResolvedAddresses[hostname]
+			if len(resolvedAddresses) == 0 && net.ParseIP(hostname) == nil {
+				glog.Warningf("JWKS URI %q of authn provider with ID %q is not resolved at startup, falling back to DNS.", jwksURI, provider.GetId())
+			}
+		}
+
 		gen := &JWTProviderCluster{
 			ID:                    provider.GetId(),
 			JWKSURI:               jwksURI,
 			ClusterConnectTimeout: opts.ClusterConnectTimeout,
+			ResolvedAddresses:     resolvedAddresses,
 			DNS:                   helpers.NewClusterDNSConfigerFromOPConfig(opts),
 			TLS:                   helpers.NewClusterTLSConfigerFromOPConfig(opts, false),
 		}
@@ -91,7 +111,7 @@ func maybeGetJWKSURIByOpenID(provider *servicepb.AuthProvider, opts options.Conf
 	}
 
 	glog.Infof("jwks_uri is empty for provider (%v), using OpenID Connect Discovery protocol (remote RPC during config gen)", provider.GetId())
-	jwksURIByOpenID, err := util.ResolveJwksUriUsingOpenID(provider.GetIssuer())
+	jwksURIByOpenID, err := util.ResolveJwksUriUsingOpenID(provider.GetIssuer(), options.OidcDiscoveryFetchOptions(opts))
 	if err != nil {
 		return "", fmt.Errorf("error processing authentication provider (%v): failed OpenID Connect Discovery protocol: %v", provider.Id, err)
 	}
@@ -124,6 +144,11 @@ func (c *JWTProviderCluster) GenConfig() (*clusterpb.Cluster, error) {
 		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
 		LoadAssignment:       util.CreateLoadAssignment(hostname, port),
 	}
+	if len(c.ResolvedAddresses) > 0 {
+		// The hostname is still used as the TLS SNI below.
+		config.ClusterDiscoveryType = &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STATIC}
+		config.LoadAssignment = makeResolvedLoadAssignment(hostname, port, c.ResolvedAddresses)
+	}
 	if scheme == "https" {
 		transportSocket, err := c.TLS.MakeTLSConfig(hostname, nil)
 		if err != nil {
@@ -132,9 +157,46 @@ func (c *JWTProviderCluster) GenConfig() (*clusterpb.Cluster, error) {
 		config.TransportSocket = transportSocket
 	}
 
+	if len(c.ResolvedAddresses) > 0 {
+		return config, nil
+	}
 	if err := helpers.MaybeAddDNSResolver(c.DNS, config); err != nil {
 		return nil, err
 	}
 
 	return config, nil
 }
+
+// makeResolvedLoadAssignment creates the load assignment with an endpoint for
+// each of the resolved addresses of the hostname.
+func makeResolvedLoadAssignment(hostname string, port uint32, addresses []string) *endpointpb.ClusterLoadAssignment {
+	var lbEndpoints []*endpointpb.LbEndpoint
+	for _, address := range addresses {
+		lbEndpoints = append(lbEndpoints, &endpointpb.LbEndpoint{
+			HostIdentifier: &endpointpb.LbEndpoint_Endpoint{
+				Endpoint: &endpointpb.Endpoint{
+					Address: &corepb.Address{
+						Address: &corepb.Address_SocketAddress{
+							SocketAddress: &corepb.SocketAddress{
+								Address: address,
+								PortSpecifier: &corepb.SocketAddress_PortValue{
+									PortValue: port,
+								},
+							},
+						},
+					},
+					Hostname: hostname,
+				},
+			},
+		})
+	}
+
+	return &endpointpb.ClusterLoadAssignment{
+		ClusterName: hostname,
+		Endpoints: []*endpointpb.LocalityLbEndpoints{
+			{
+				LbEndpoints: lbEndpoints,
+			},
+		},
+	}
+}
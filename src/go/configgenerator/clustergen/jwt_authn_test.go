@@ -24,6 +24,7 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/types/known/durationpb"
 )
@@ -133,6 +134,71 @@ func TestNewJWTProviderClustersFromOPConfig_GenConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "Use the addresses resolved at startup",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider_0",
+							Issuer:  "issuer_0",
+							JwksUri: "https://metadata.com/pkey",
+						},
+						{
+							Id:      "auth_provider_1",
+							Issuer:  "issuer_1",
+							JwksUri: "http://unresolved.com/pkey",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				DnsResolverAddresses: "8.8.8.8",
+				JwksResolveAtStartup: true,
+				JwksResolvedAddresses: map[string][]string{
+					"metadata.com": {"10.0.0.1", "10.0.0.2"},
+				},
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "jwt-provider-cluster-metadata.com:443",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STATIC},
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					LoadAssignment: &endpointpb.ClusterLoadAssignment{
+						ClusterName: "metadata.com",
+						Endpoints: []*endpointpb.LocalityLbEndpoints{
+							{
+								LbEndpoints: []*endpointpb.LbEndpoint{
+									makeResolvedLbEndpoint("metadata.com", "10.0.0.1", 443),
+									makeResolvedLbEndpoint("metadata.com", "10.0.0.2", 443),
+								},
+							},
+						},
+					},
+					TransportSocket: clustergentest.CreateDefaultTLS(t, "metadata.com", false),
+				},
+				{
+					Name:                 "jwt-provider-cluster-unresolved.com:80",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					LoadAssignment:       util.CreateLoadAssignment("unresolved.com", 80),
+					DnsResolvers: []*corepb.Address{
+						{
+							Address: &corepb.Address_SocketAddress{
+								SocketAddress: &corepb.SocketAddress{
+									Address: "8.8.8.8",
+									PortSpecifier: &corepb.SocketAddress_PortValue{
+										PortValue: 53,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testData {
@@ -140,6 +206,26 @@ func TestNewJWTProviderClustersFromOPConfig_GenConfig(t *testing.T) {
 	}
 }
 
+func makeResolvedLbEndpoint(hostname, address string, port uint32) *endpointpb.LbEndpoint {
+	return &endpointpb.LbEndpoint{
+		HostIdentifier: &endpointpb.LbEndpoint_Endpoint{
+			Endpoint: &endpointpb.Endpoint{
+				Address: &corepb.Address{
+					Address: &corepb.Address_SocketAddress{
+						SocketAddress: &corepb.SocketAddress{
+							Address: address,
+							PortSpecifier: &corepb.SocketAddress_PortValue{
+								PortValue: port,
+							},
+						},
+					},
+				},
+				Hostname: hostname,
+			},
+		},
+	}
+}
+
 func TestNewJWTProviderClustersFromOPConfig_BadInputFactory(t *testing.T) {
 	testData := []clustergentest.FactoryErrorOPTestCase{
 		{
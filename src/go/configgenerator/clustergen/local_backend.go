@@ -29,6 +29,7 @@ import (
 type LocalBackendCluster struct {
 	BackendCluster *helpers.BaseBackendCluster
 	GRPCHealth     *helpers.ClusterGRPCHealthCheckConfiger
+	Outlier        *helpers.ClusterOutlierDetectionConfiger
 }
 
 // NewLocalBackendClustersFromOPConfig creates a LocalBackendCluster from
@@ -50,6 +51,9 @@ func NewLocalBackendClustersFromOPConfig(serviceConfig *servicepb.Service, opts
 		if protocol != util.GRPC {
 			return nil, fmt.Errorf("invalid flag --health_check_grpc_backend, backend protocol must be GRPC")
 		}
+		if opts.BackendHealthCheckPath != "" || opts.BackendHealthCheckGrpc {
+			return nil, fmt.Errorf("flag --health_check_grpc_backend cannot be used with flag --backend_health_check_path or --backend_health_check_grpc")
+		}
 	}
 
 	var tls *helpers.ClusterTLSConfiger
@@ -64,13 +68,19 @@ func NewLocalBackendClustersFromOPConfig(serviceConfig *servicepb.Service, opts
 				Hostname:               hostname,
 				Port:                   port,
 				Protocol:               protocol,
-				ClusterConnectTimeout:  opts.ClusterConnectTimeout,
+				ClusterConnectTimeout:  helpers.BackendClusterConnectTimeout(opts),
 				MaxRequestsThreshold:   opts.BackendClusterMaxRequests,
 				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				HeaderKeyFormat:        opts.HeaderKeyFormat,
 				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
 				TLS:                    tls,
+				ActiveHealth:           helpers.NewClusterActiveHealthCheckConfigerFromOPConfig(opts),
+				Connection:             helpers.NewClusterConnectionConfigerFromOPConfig(opts),
+				RetryBudget:            helpers.NewClusterRetryBudgetConfigerFromOPConfig(opts),
+				ProtocolSelection:      helpers.NewClusterProtocolSelectionConfigerFromOPConfig(opts),
 			},
 			GRPCHealth: helpers.NewClusterGRPCHealthCheckConfigerFromOPConfig(opts),
+			Outlier:    helpers.NewClusterOutlierDetectionConfigerFromOPConfig(opts),
 		},
 	}, nil
 }
@@ -91,6 +101,10 @@ func (c *LocalBackendCluster) GenConfig() (*clusterpb.Cluster, error) {
 		return nil, err
 	}
 
+	if err := helpers.MaybeAddOutlierDetection(c.Outlier, config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
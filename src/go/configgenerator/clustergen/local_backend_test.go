@@ -25,6 +25,7 @@ import (
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -80,6 +81,56 @@ func TestNewLocalBackendClusterFromOPConfig_GenConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "Success for OpenAPI HTTP backend with connection options",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:               "http://127.0.0.1:80",
+				BackendClusterConnectTimeout: 5 * time.Second,
+				BackendTcpKeepaliveTime:      300 * time.Second,
+				BackendTcpKeepaliveInterval:  30 * time.Second,
+				BackendTcpKeepaliveProbes:    4,
+				BackendHappyEyeballs:         true,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+					ConnectTimeout:       durationpb.New(5 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("127.0.0.1", 80),
+					DnsLookupFamily:      clusterpb.Cluster_ALL,
+					UpstreamConnectionOptions: &clusterpb.UpstreamConnectionOptions{
+						TcpKeepalive: &corepb.TcpKeepalive{
+							KeepaliveProbes:   &wrappers.UInt32Value{Value: 4},
+							KeepaliveTime:     &wrappers.UInt32Value{Value: 300},
+							KeepaliveInterval: &wrappers.UInt32Value{Value: 30},
+						},
+					},
+				},
+			},
+		},
+		{
+			Desc: "Success for OpenAPI HTTP backend with preserved header casing",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:  "http://127.0.0.1:80",
+				HeaderKeyFormat: "preserve_case",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("127.0.0.1", 80),
+					DnsLookupFamily:               clusterpb.Cluster_V4_PREFERRED,
+					TypedExtensionProtocolOptions: mustCreateUpstreamHttp1ProtocolOptions(t, "preserve_case"),
+				},
+			},
+		},
 		{
 			Desc: "Success for OpenAPI HTTPS backend",
 			ServiceConfigIn: &servicepb.Service{
@@ -208,6 +259,33 @@ func TestNewLocalBackendClusterFromOPConfig_GenConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "Success for http backend with healthz backend check",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:                    "http://127.0.0.1:80",
+				HealthzCheckBackend:               true,
+				HealthzBackendEvaluationWindow:    10 * time.Second,
+				HealthzBackendConsecutiveFailures: 3,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("127.0.0.1", 80),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					OutlierDetection: &clusterpb.OutlierDetection{
+						Consecutive_5Xx:    &wrappers.UInt32Value{Value: 3},
+						Interval:           durationpb.New(10 * time.Second),
+						BaseEjectionTime:   durationpb.New(10 * time.Second),
+						MaxEjectionPercent: &wrappers.UInt32Value{Value: 100},
+					},
+				},
+			},
+		},
 		{
 			Desc: "Success for custom DNS resolver",
 			ServiceConfigIn: &servicepb.Service{
@@ -256,9 +334,27 @@ func TestNewLocalBackendClusterFromOPConfig_BadInputFactory(t *testing.T) {
 			},
 			WantFactoryError: "--health_check_grpc_backend",
 		},
+		{
+			Desc: "HealthCheckGrpcBackend with backend active health check",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:         "grpc://127.0.0.1:80",
+				HealthCheckGrpcBackend: true,
+				BackendHealthCheckGrpc: true,
+			},
+			WantFactoryError: "flag --health_check_grpc_backend cannot be used with flag --backend_health_check_path or --backend_health_check_grpc",
+		},
 	}
 
 	for _, tc := range testData {
 		tc.RunTest(t, clustergen.NewLocalBackendClustersFromOPConfig)
 	}
 }
+
+func mustCreateUpstreamHttp1ProtocolOptions(t *testing.T, format string) map[string]*anypb.Any {
+	t.Helper()
+	headerKeyFormat, err := util.CreateHeaderKeyFormat(format)
+	if err != nil {
+		t.Fatalf("CreateHeaderKeyFormat() got error: %v", err)
+	}
+	return util.CreateUpstreamHttp1ProtocolOptions(headerKeyFormat)
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	aggregatepb "github.com/envoyproxy/go-control-plane/envoy/extensions/clusters/aggregate/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	aggregateClusterTypeName = "envoy.clusters.aggregate"
+
+	// NearRegion and FarRegion are the regions of the regional backends, in
+	// the order of preference.
+	NearRegion = "near"
+	FarRegion  = "far"
+)
+
+// RegionalBackends are the backends serving a remote backend address in two
+// regions. It is read from flag --backend_regional_addresses.
+type RegionalBackends struct {
+	NearAddress string
+	FarAddress  string
+}
+
+// ParseBackendRegionalAddresses parses flag --backend_regional_addresses in
+// the format of "address1=near_address1,far_address1;address2=near_address2,far_address2"
+// into the regional backends keyed by the "hostname:port" of the remote
+// backend addresses. The regional backends must use the same scheme as the
+// remote backend address.
+func ParseBackendRegionalAddresses(s string) (map[string]*RegionalBackends, error) {
+	regionalBackends := make(map[string]*RegionalBackends)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid regional mapping %q, must be in the format of address=near_address,far_address", pair)
+		}
+		address := strings.TrimSpace(pair[:i])
+		regionalAddresses := strings.Split(pair[i+1:], ",")
+		if len(regionalAddresses) != 2 {
+			return nil, fmt.Errorf("invalid regional mapping %q, must have exactly one near address and one far address", pair)
+		}
+		backends := &RegionalBackends{
+			NearAddress: strings.TrimSpace(regionalAddresses[0]),
+			FarAddress:  strings.TrimSpace(regionalAddresses[1]),
+		}
+
+		scheme, hostname, port, _, err := util.ParseURI(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address %q: %v", address, err)
+		}
+		for _, regionalAddress := range []string{backends.NearAddress, backends.FarAddress} {
+			regionalScheme, _, _, _, err := util.ParseURI(regionalAddress)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regional address %q: %v", regionalAddress, err)
+			}
+			if regionalScheme != scheme {
+				return nil, fmt.Errorf("regional address %q must use the scheme %q of backend address %q", regionalAddress, scheme, address)
+			}
+		}
+
+		key := fmt.Sprintf("%v:%v", hostname, port)
+		if _, ok := regionalBackends[key]; ok {
+			return nil, fmt.Errorf("backend address %q has more than one regional mapping", address)
+		}
+		regionalBackends[key] = backends
+	}
+	return regionalBackends, nil
+}
+
+// RegionalClusterName returns the name of the backend cluster of the remote
+// address in the region.
+func RegionalClusterName(address string, region string) string {
+	return fmt.Sprintf("%s-%s", RemoteAddressToClusterName(address), region)
+}
+
+// makeAggregateCluster creates an aggregate cluster over the regional
+// clusters. The clusters are tried in order as priority levels, so the far
+// region only takes the traffic spilled over once the healthy hosts of the
+// near region drop below the overprovisioning factor, e.g. when they are
+// ejected for failing under load.
+func makeAggregateCluster(name string, connectTimeout *durationpb.Duration, regionalClusterNames []string) (*clusterpb.Cluster, error) {
+	clusterConfig, err := anypb.New(&aggregatepb.ClusterConfig{
+		Clusters: regionalClusterNames,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &clusterpb.Cluster{
+		Name:           name,
+		ConnectTimeout: connectTimeout,
+		LbPolicy:       clusterpb.Cluster_CLUSTER_PROVIDED,
+		ClusterDiscoveryType: &clusterpb.Cluster_ClusterType{
+			ClusterType: &clusterpb.Cluster_CustomClusterType{
+				Name:        aggregateClusterTypeName,
+				TypedConfig: clusterConfig,
+			},
+		},
+	}, nil
+}
+
+// addRegionalOutlierDetection ejects the failing hosts of a regional cluster,
+// so the traffic spills over to the other region.
+func addRegionalOutlierDetection(config *clusterpb.Cluster) {
+	if config.OutlierDetection == nil {
+		config.OutlierDetection = &clusterpb.OutlierDetection{
+			MaxEjectionPercent: wrapperspb.UInt32(100),
+		}
+	}
+}
@@ -23,12 +23,21 @@ import (
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // RemoteBackendCluster is an Envoy cluster to communicate with remote backends
 // via dynamic routing. Primarily for API Gateway use case.
 type RemoteBackendCluster struct {
 	BackendCluster *helpers.BaseBackendCluster
+
+	// RegionalClusters are the clusters of the regional backends if the remote
+	// backend is served in two regions, in which case this cluster aggregates
+	// them.
+	RegionalClusters []*RemoteBackendCluster
+
+	// IsRegional is set for the cluster of a regional backend.
+	IsRegional bool
 }
 
 // NewRemoteBackendClustersFromOPConfig creates all RemoteBackendCluster from
@@ -100,26 +109,60 @@ func backendRuleToCluster(rule *servicepb.BackendRule, opts options.ConfigGenera
 		}
 	}
 
+	address := fmt.Sprintf("%v:%v", hostname, port)
+	cluster := makeRemoteBackendCluster(RemoteAddressToClusterName(address), hostname, port, protocol, useTLS, opts)
+
+	regionalBackends, err := ParseBackendRegionalAddresses(opts.BackendRegionalAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --backend_regional_addresses: %v", err)
+	}
+	if backends, ok := regionalBackends[address]; ok {
+		for _, region := range []string{NearRegion, FarRegion} {
+			regionalAddress := backends.NearAddress
+			if region == FarRegion {
+				regionalAddress = backends.FarAddress
+			}
+
+			_, regionalHostname, regionalPort, _, err := util.ParseURI(regionalAddress)
+			if err != nil {
+				return nil, fmt.Errorf("invalid flag --backend_regional_addresses: %v", err)
+			}
+			regionalCluster := makeRemoteBackendCluster(RegionalClusterName(address, region), regionalHostname, regionalPort, protocol, useTLS, opts)
+			regionalCluster.IsRegional = true
+			cluster.RegionalClusters = append(cluster.RegionalClusters, regionalCluster)
+		}
+	}
+	return cluster, nil
+}
+
+// makeRemoteBackendCluster is a shared helper to create a RemoteBackendCluster
+// for a remote backend.
+func makeRemoteBackendCluster(clusterName string, hostname string, port uint32, protocol util.BackendProtocol, useTLS bool, opts options.ConfigGeneratorOptions) *RemoteBackendCluster {
 	var tls *helpers.ClusterTLSConfiger
 	if useTLS {
 		tls = helpers.NewClusterTLSConfigerFromOPConfig(opts, true)
 	}
 
-	address := fmt.Sprintf("%v:%v", hostname, port)
-	cluster := &RemoteBackendCluster{
+	return &RemoteBackendCluster{
 		BackendCluster: &helpers.BaseBackendCluster{
-			ClusterName:            RemoteAddressToClusterName(address),
+			ClusterName:            clusterName,
 			Hostname:               hostname,
 			Port:                   port,
 			Protocol:               protocol,
-			ClusterConnectTimeout:  opts.ClusterConnectTimeout,
+			ClusterConnectTimeout:  helpers.BackendClusterConnectTimeout(opts),
 			MaxRequestsThreshold:   opts.BackendClusterMaxRequests,
 			BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+			HeaderKeyFormat:        opts.HeaderKeyFormat,
 			DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
 			TLS:                    tls,
+			ActiveHealth:           helpers.NewClusterActiveHealthCheckConfigerFromOPConfig(opts),
+			Connection:             helpers.NewClusterConnectionConfigerFromOPConfig(opts),
+			RetryBudget:            helpers.NewClusterRetryBudgetConfigerFromOPConfig(opts),
+			ProtocolSelection:      helpers.NewClusterProtocolSelectionConfigerFromOPConfig(opts),
+			SlowStart:              helpers.NewClusterSlowStartConfigerFromOPConfig(opts),
+			Failover:               helpers.NewClusterFailoverConfigerFromOPConfig(opts),
 		},
 	}
-	return cluster, nil
 }
 
 // dedupAndAddGenerator is a helper to update tracking variables when adding a new ClusterGenerator to the output.
@@ -131,6 +174,10 @@ func dedupAndAddGenerator(gen *RemoteBackendCluster, gens []ClusterGenerator, de
 	if _, exist := dedupClusterNames[gen.GetName()]; !exist {
 		dedupClusterNames[gen.GetName()] = true
 		gens = append(gens, gen)
+
+		for _, regionalGen := range gen.RegionalClusters {
+			gens = dedupAndAddGenerator(regionalGen, gens, dedupClusterNames)
+		}
 	}
 	return gens
 }
@@ -142,7 +189,22 @@ func (c *RemoteBackendCluster) GetName() string {
 
 // GenConfig implements the ClusterGenerator interface.
 func (c *RemoteBackendCluster) GenConfig() (*clusterpb.Cluster, error) {
-	return c.BackendCluster.GenBaseConfig()
+	if len(c.RegionalClusters) > 0 {
+		var regionalClusterNames []string
+		for _, regionalCluster := range c.RegionalClusters {
+			regionalClusterNames = append(regionalClusterNames, regionalCluster.GetName())
+		}
+		return makeAggregateCluster(c.GetName(), durationpb.New(c.BackendCluster.ClusterConnectTimeout), regionalClusterNames)
+	}
+
+	config, err := c.BackendCluster.GenBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	if c.IsRegional {
+		addRegionalOutlierDetection(config)
+	}
+	return config, nil
 }
 
 // RemoteAddressToClusterName returns the corresponding remote backend cluster
@@ -24,8 +24,13 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	aggregatepb "github.com/envoyproxy/go-control-plane/envoy/extensions/clusters/aggregate/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestNewRemoteBackendClustersFromOPConfig_GenConfig(t *testing.T) {
@@ -483,6 +488,130 @@ func TestNewRemoteBackendClustersFromOPConfig_GenConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "Success for gRPC and HTTP backends with active health check",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Address:  "grpc://mybackend-1.com",
+							Selector: "1.cloudesf_testing_cloud_goog.Foo",
+						},
+						{
+							Address:  "http://mybackend-2.com",
+							Selector: "1.cloudesf_testing_cloud_goog.Bar",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendHealthCheckPath:             "/healthz",
+				BackendHealthCheckGrpc:             true,
+				BackendHealthCheckExpectedStatuses: "200,300-399",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "backend-cluster-mybackend-1.com:80",
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("mybackend-1.com", 80),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+					DnsLookupFamily:               clusterpb.Cluster_V4_PREFERRED,
+					HealthChecks: []*corepb.HealthCheck{
+						{
+							Timeout:            durationpb.New(1 * time.Second),
+							Interval:           durationpb.New(5 * time.Second),
+							HealthyThreshold:   &wrapperspb.UInt32Value{Value: 2},
+							UnhealthyThreshold: &wrapperspb.UInt32Value{Value: 3},
+							HealthChecker: &corepb.HealthCheck_GrpcHealthCheck_{
+								GrpcHealthCheck: &corepb.HealthCheck_GrpcHealthCheck{
+									Authority: "mybackend-1.com",
+								},
+							},
+						},
+					},
+				},
+				{
+					Name:                 "backend-cluster-mybackend-2.com:80",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("mybackend-2.com", 80),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					HealthChecks: []*corepb.HealthCheck{
+						{
+							Timeout:            durationpb.New(1 * time.Second),
+							Interval:           durationpb.New(5 * time.Second),
+							HealthyThreshold:   &wrapperspb.UInt32Value{Value: 2},
+							UnhealthyThreshold: &wrapperspb.UInt32Value{Value: 3},
+							HealthChecker: &corepb.HealthCheck_HttpHealthCheck_{
+								HttpHealthCheck: &corepb.HealthCheck_HttpHealthCheck{
+									Host: "mybackend-2.com",
+									Path: "/healthz",
+									ExpectedStatuses: []*typepb.Int64Range{
+										{Start: 200, End: 201},
+										{Start: 300, End: 400},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Desc: "Success for regional backends aggregated by the backend address",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Address:  "http://mybackend.com",
+							Selector: "1.cloudesf_testing_cloud_goog.Foo",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendRegionalAddresses: "http://mybackend.com=http://us-central1.mybackend.com,http://us-east1.mybackend.com:8080",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:           "backend-cluster-mybackend.com:80",
+					ConnectTimeout: durationpb.New(20 * time.Second),
+					LbPolicy:       clusterpb.Cluster_CLUSTER_PROVIDED,
+					ClusterDiscoveryType: &clusterpb.Cluster_ClusterType{
+						ClusterType: &clusterpb.Cluster_CustomClusterType{
+							Name: "envoy.clusters.aggregate",
+							TypedConfig: mustNewAny(t, &aggregatepb.ClusterConfig{
+								Clusters: []string{
+									"backend-cluster-mybackend.com:80-near",
+									"backend-cluster-mybackend.com:80-far",
+								},
+							}),
+						},
+					},
+				},
+				{
+					Name:                 "backend-cluster-mybackend.com:80-near",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("us-central1.mybackend.com", 80),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					OutlierDetection: &clusterpb.OutlierDetection{
+						MaxEjectionPercent: wrapperspb.UInt32(100),
+					},
+				},
+				{
+					Name:                 "backend-cluster-mybackend.com:80-far",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("us-east1.mybackend.com", 8080),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					OutlierDetection: &clusterpb.OutlierDetection{
+						MaxEjectionPercent: wrapperspb.UInt32(100),
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testData {
@@ -525,9 +654,51 @@ func TestNewRemoteBackendClustersFromOPConfig_BadInputFactory(t *testing.T) {
 			},
 			WantFactoryError: "gRPC protocol conflicted with http backend",
 		},
+		{
+			Desc: "Regional backend with another scheme",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Address:  "https://mybackend.com",
+							Selector: "1.cloudesf_testing_cloud_goog.Foo",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendRegionalAddresses: "https://mybackend.com=https://us-central1.mybackend.com,http://us-east1.mybackend.com",
+			},
+			WantFactoryError: `invalid flag --backend_regional_addresses: regional address "http://us-east1.mybackend.com" must use the scheme "https"`,
+		},
+		{
+			Desc: "Regional mapping without far backend",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Address:  "https://mybackend.com",
+							Selector: "1.cloudesf_testing_cloud_goog.Foo",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendRegionalAddresses: "https://mybackend.com=https://us-central1.mybackend.com",
+			},
+			WantFactoryError: "must have exactly one near address and one far address",
+		},
 	}
 
 	for _, tc := range testData {
 		tc.RunTest(t, clustergen.NewRemoteBackendClustersFromOPConfig)
 	}
 }
+
+func mustNewAny(t *testing.T, msg proto.Message) *anypb.Any {
+	a, err := anypb.New(msg)
+	if err != nil {
+		t.Fatalf("anypb.New() got error: %v", err)
+	}
+	return a
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	tcpProxyClusterNamePrefix = "tcp-proxy-cluster-"
+)
+
+// TcpProxyRoute tunnels the TCP connections of a listener port, optionally
+// only the TLS connections of a server name (SNI), to a destination. It is
+// parsed from flag --tcp_proxy_routes.
+type TcpProxyRoute struct {
+	Port uint32
+
+	// ServerName is the SNI of the TLS connections, such as "mqtt.example.com"
+	// or "*.example.com". Empty matches all the connections.
+	ServerName string
+
+	Hostname string
+	DestPort uint32
+}
+
+// ClusterName returns the name of the cluster of the destination.
+func (r *TcpProxyRoute) ClusterName() string {
+	return fmt.Sprintf("%s%s:%d", tcpProxyClusterNamePrefix, r.Hostname, r.DestPort)
+}
+
+// ParseTcpProxyRoutesFromOPConfig parses the TCP proxy routes from ESPv2
+// options in the format of "PORT[/SERVER_NAME]=HOST:PORT;...", nil if the TCP
+// proxy is disabled.
+func ParseTcpProxyRoutesFromOPConfig(opts options.ConfigGeneratorOptions) ([]*TcpProxyRoute, error) {
+	var routes []*TcpProxyRoute
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(opts.TcpProxyRoutes, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, route %q must be in the format of PORT[/SERVER_NAME]=HOST:PORT", pair)
+		}
+		listen, destination := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+
+		route := &TcpProxyRoute{}
+		portStr := listen
+		if j := strings.Index(listen, "/"); j != -1 {
+			portStr, route.ServerName = listen[:j], listen[j+1:]
+			if route.ServerName == "" {
+				return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, route %q has an empty server name", pair)
+			}
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, route %q must listen on a port in the range of [1, 65535]", pair)
+		}
+		route.Port = uint32(port)
+		if int(port) == opts.ListenerPort || int(port) == opts.HttpRedirectPort || int(port) == opts.HealthPort {
+			return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, route %q cannot listen on the port of the HTTP listeners", pair)
+		}
+
+		hostname, destPortStr, err := net.SplitHostPort(destination)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, route %q must have a destination of HOST:PORT: %v", pair, err)
+		}
+		destPort, err := strconv.ParseUint(destPortStr, 10, 16)
+		if err != nil || destPort == 0 || hostname == "" {
+			return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, route %q must have a destination of HOST:PORT", pair)
+		}
+		route.Hostname = hostname
+		route.DestPort = uint32(destPort)
+
+		if seen[listen] {
+			return nil, fmt.Errorf("invalid flag --tcp_proxy_routes, duplicate route of %q", listen)
+		}
+		seen[listen] = true
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// TcpProxyCluster is an Envoy cluster to tunnel the TCP connections to a
+// destination of the TCP proxy routes.
+type TcpProxyCluster struct {
+	ClusterName           string
+	Hostname              string
+	Port                  uint32
+	ClusterConnectTimeout time.Duration
+
+	DNS *helpers.ClusterDNSConfiger
+}
+
+// NewTcpProxyClustersFromOPConfig creates TcpProxyClusters from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+//
+// Generates 1 cluster per destination.
+func NewTcpProxyClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	routes, err := ParseTcpProxyRoutesFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		glog.Infof("Not adding TCP proxy cluster gens because there is no TCP proxy route.")
+		return nil, nil
+	}
+
+	var gens []ClusterGenerator
+	dedupClusterNames := make(map[string]bool)
+	for _, route := range routes {
+		if dedupClusterNames[route.ClusterName()] {
+			continue
+		}
+		dedupClusterNames[route.ClusterName()] = true
+
+		gens = append(gens, &TcpProxyCluster{
+			ClusterName:           route.ClusterName(),
+			Hostname:              route.Hostname,
+			Port:                  route.DestPort,
+			ClusterConnectTimeout: helpers.BackendClusterConnectTimeout(opts),
+			DNS:                   helpers.NewClusterDNSConfigerFromOPConfig(opts),
+		})
+	}
+	return gens, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *TcpProxyCluster) GetName() string {
+	return c.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *TcpProxyCluster) GenConfig() (*clusterpb.Cluster, error) {
+	config := &clusterpb.Cluster{
+		Name:                 c.ClusterName,
+		LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+		ConnectTimeout:       durationpb.New(c.ClusterConnectTimeout),
+		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+		DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+		LoadAssignment:       util.CreateLoadAssignment(c.Hostname, c.Port),
+	}
+
+	if err := helpers.MaybeAddDNSResolver(c.DNS, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewTcpProxyClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "No TCP proxy routes",
+		},
+		{
+			Desc: "Success, de-duplicated destinations",
+			OptsIn: options.ConfigGeneratorOptions{
+				TcpProxyRoutes: "1883=mqtt-broker:1883;8883/a.example.com=mqtt-tls-broker:8883;8883/*.example.com=mqtt-tls-broker:8883",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "tcp-proxy-cluster-mqtt-broker:1883",
+					LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					LoadAssignment:       util.CreateLoadAssignment("mqtt-broker", 1883),
+				},
+				{
+					Name:                 "tcp-proxy-cluster-mqtt-tls-broker:8883",
+					LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					LoadAssignment:       util.CreateLoadAssignment("mqtt-tls-broker", 8883),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTcpProxyClustersFromOPConfig)
+	}
+}
+
+func TestNewTcpProxyClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Missing destination",
+			OptsIn: options.ConfigGeneratorOptions{
+				TcpProxyRoutes: "1883",
+			},
+			WantFactoryError: `route "1883" must be in the format of PORT[/SERVER_NAME]=HOST:PORT`,
+		},
+		{
+			Desc: "Invalid listen port",
+			OptsIn: options.ConfigGeneratorOptions{
+				TcpProxyRoutes: "70000=mqtt-broker:1883",
+			},
+			WantFactoryError: "must listen on a port in the range of [1, 65535]",
+		},
+		{
+			Desc: "Port of the ingress listener",
+			OptsIn: options.ConfigGeneratorOptions{
+				TcpProxyRoutes: "8080=mqtt-broker:1883",
+			},
+			WantFactoryError: "cannot listen on the port of the HTTP listeners",
+		},
+		{
+			Desc: "Destination without port",
+			OptsIn: options.ConfigGeneratorOptions{
+				TcpProxyRoutes: "1883=mqtt-broker",
+			},
+			WantFactoryError: "must have a destination of HOST:PORT",
+		},
+		{
+			Desc: "Duplicate route",
+			OptsIn: options.ConfigGeneratorOptions{
+				TcpProxyRoutes: "8883/a.example.com=broker-a:8883;8883/a.example.com=broker-b:8883",
+			},
+			WantFactoryError: `duplicate route of "8883/a.example.com"`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTcpProxyClustersFromOPConfig)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	tenantClusterNamePrefix = "backend-cluster-tenant-"
+)
+
+var tenantNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`)
+
+// TenantMapping maps the API consumers to the tenants with their own
+// backends. It is read from the JSON file of flag --tenant_mapping_file or the
+// metadata attribute of flag --tenant_mapping_metadata_attribute.
+type TenantMapping struct {
+	// JwtClaim is the claim of the verified JWT payload matched against
+	// the JwtClaimValues of the tenants, empty if the tenants are not
+	// identified by JWT.
+	JwtClaim string `json:"jwtClaim"`
+
+	Tenants []*Tenant `json:"tenants"`
+}
+
+// Tenant is a tenant with its own backend.
+type Tenant struct {
+	Name           string `json:"name"`
+	BackendAddress string `json:"backendAddress"`
+
+	// ConsumerNumbers are the project numbers of the API consumers,
+	// identified by the API keys validated by Service Control.
+	ConsumerNumbers []string `json:"consumerNumbers"`
+
+	// JwtClaimValues are the values of the JWT claim of the tenant mapping.
+	JwtClaimValues []string `json:"jwtClaimValues"`
+}
+
+// TenantClusterName returns the name of the backend cluster of the tenant.
+func TenantClusterName(tenantName string) string {
+	return tenantClusterNamePrefix + tenantName
+}
+
+// ParseTenantMappingFromOPConfig parses the tenant mapping from ESPv2 options,
+// nil if tenant routing is disabled.
+func ParseTenantMappingFromOPConfig(opts options.ConfigGeneratorOptions) (*TenantMapping, error) {
+	content := opts.TenantMapping
+	if opts.TenantMappingFile != "" {
+		if content != "" {
+			return nil, fmt.Errorf("flag --tenant_mapping_file cannot be used with flag --tenant_mapping_metadata_attribute")
+		}
+		b, err := ioutil.ReadFile(opts.TenantMappingFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read tenant mapping file %q: %v", opts.TenantMappingFile, err)
+		}
+		content = string(b)
+	}
+	if content == "" {
+		return nil, nil
+	}
+
+	mapping := &TenantMapping{}
+	if err := json.Unmarshal([]byte(content), mapping); err != nil {
+		return nil, fmt.Errorf("invalid tenant mapping, it should be a JSON object: %v", err)
+	}
+	if len(mapping.Tenants) == 0 {
+		return nil, fmt.Errorf("invalid tenant mapping, no tenant is specified")
+	}
+
+	names := make(map[string]bool)
+	consumers := make(map[string]string)
+	claimValues := make(map[string]string)
+	for _, tenant := range mapping.Tenants {
+		if !tenantNameRegex.MatchString(tenant.Name) {
+			return nil, fmt.Errorf("invalid tenant mapping, tenant name %q must only contain lowercase letters, digits, '-' and '_'", tenant.Name)
+		}
+		if names[tenant.Name] {
+			return nil, fmt.Errorf("invalid tenant mapping, duplicate tenant %q", tenant.Name)
+		}
+		names[tenant.Name] = true
+
+		if tenant.BackendAddress == "" {
+			return nil, fmt.Errorf("invalid tenant mapping, tenant %q has no backend address", tenant.Name)
+		}
+		if len(tenant.ConsumerNumbers) == 0 && len(tenant.JwtClaimValues) == 0 {
+			return nil, fmt.Errorf("invalid tenant mapping, tenant %q has neither consumer numbers nor JWT claim values", tenant.Name)
+		}
+		if len(tenant.JwtClaimValues) > 0 && mapping.JwtClaim == "" {
+			return nil, fmt.Errorf("invalid tenant mapping, tenant %q has JWT claim values but jwtClaim is not specified", tenant.Name)
+		}
+
+		for _, consumer := range tenant.ConsumerNumbers {
+			if other, ok := consumers[consumer]; ok {
+				return nil, fmt.Errorf("invalid tenant mapping, consumer number %q is mapped to both tenant %q and %q", consumer, other, tenant.Name)
+			}
+			consumers[consumer] = tenant.Name
+		}
+		for _, value := range tenant.JwtClaimValues {
+			if other, ok := claimValues[value]; ok {
+				return nil, fmt.Errorf("invalid tenant mapping, JWT claim value %q is mapped to both tenant %q and %q", value, other, tenant.Name)
+			}
+			claimValues[value] = tenant.Name
+		}
+	}
+	return mapping, nil
+}
+
+// TenantBackendCluster is an Envoy cluster to communicate with the backend of
+// a tenant.
+type TenantBackendCluster struct {
+	BackendCluster *helpers.BaseBackendCluster
+}
+
+// NewTenantBackendClustersFromOPConfig creates TenantBackendClusters from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewTenantBackendClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	mapping, err := ParseTenantMappingFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		glog.Infof("Not adding tenant backend cluster gens because there is no tenant mapping.")
+		return nil, nil
+	}
+
+	var gens []ClusterGenerator
+	for _, tenant := range mapping.Tenants {
+		scheme, hostname, port, _, err := util.ParseURI(tenant.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of tenant %q: %v", tenant.Name, err)
+		}
+
+		protocol, useTLS, err := util.ParseBackendProtocol(scheme, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of tenant %q: %v", tenant.Name, err)
+		}
+
+		var tls *helpers.ClusterTLSConfiger
+		if useTLS {
+			tls = helpers.NewClusterTLSConfigerFromOPConfig(opts, true)
+		}
+
+		gens = append(gens, &TenantBackendCluster{
+			BackendCluster: &helpers.BaseBackendCluster{
+				ClusterName:            TenantClusterName(tenant.Name),
+				Hostname:               hostname,
+				Port:                   port,
+				Protocol:               protocol,
+				ClusterConnectTimeout:  helpers.BackendClusterConnectTimeout(opts),
+				MaxRequestsThreshold:   opts.BackendClusterMaxRequests,
+				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				HeaderKeyFormat:        opts.HeaderKeyFormat,
+				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
+				TLS:                    tls,
+				Connection:             helpers.NewClusterConnectionConfigerFromOPConfig(opts),
+				RetryBudget:            helpers.NewClusterRetryBudgetConfigerFromOPConfig(opts),
+				ProtocolSelection:      helpers.NewClusterProtocolSelectionConfigerFromOPConfig(opts),
+			},
+		})
+	}
+	return gens, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *TenantBackendCluster) GetName() string {
+	return c.BackendCluster.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *TenantBackendCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return c.BackendCluster.GenBaseConfig()
+}
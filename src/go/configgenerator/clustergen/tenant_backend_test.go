@@ -0,0 +1,141 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewTenantBackendClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Success for HTTPS and HTTP tenant backends",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `
+{
+  "jwtClaim": "tenant",
+  "tenants": [
+    {"name": "acme", "backendAddress": "https://acme.example.com", "consumerNumbers": ["123"]},
+    {"name": "globex", "backendAddress": "http://globex.internal:8080", "jwtClaimValues": ["globex"]}
+  ]
+}`,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "backend-cluster-tenant-acme",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("acme.example.com", 443),
+					TransportSocket:      clustergentest.CreateDefaultTLS(t, "acme.example.com", false),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+				{
+					Name:                 "backend-cluster-tenant-globex",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("globex.internal", 8080),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTenantBackendClustersFromOPConfig)
+	}
+}
+
+func TestNewTenantBackendClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Malformed mapping",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `[]`,
+			},
+			WantFactoryError: "invalid tenant mapping, it should be a JSON object",
+		},
+		{
+			Desc: "No tenant",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `{"tenants": []}`,
+			},
+			WantFactoryError: "invalid tenant mapping, no tenant is specified",
+		},
+		{
+			Desc: "Invalid tenant name",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `{"tenants": [{"name": "Acme", "backendAddress": "https://acme.example.com", "consumerNumbers": ["123"]}]}`,
+			},
+			WantFactoryError: `tenant name "Acme" must only contain`,
+		},
+		{
+			Desc: "Consumer mapped to two tenants",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `
+{
+  "tenants": [
+    {"name": "acme", "backendAddress": "https://acme.example.com", "consumerNumbers": ["123"]},
+    {"name": "globex", "backendAddress": "https://globex.example.com", "consumerNumbers": ["123"]}
+  ]
+}`,
+			},
+			WantFactoryError: `consumer number "123" is mapped to both tenant "acme" and "globex"`,
+		},
+		{
+			Desc: "JWT claim values without JWT claim",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `{"tenants": [{"name": "acme", "backendAddress": "https://acme.example.com", "jwtClaimValues": ["acme"]}]}`,
+			},
+			WantFactoryError: `tenant "acme" has JWT claim values but jwtClaim is not specified`,
+		},
+		{
+			Desc: "Missing mapping file",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMappingFile: "/does/not/exist.json",
+			},
+			WantFactoryError: `fail to read tenant mapping file "/does/not/exist.json"`,
+		},
+		{
+			Desc: "Invalid backend address",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `{"tenants": [{"name": "acme", "backendAddress": "ftp://acme.example.com", "consumerNumbers": ["123"]}]}`,
+			},
+			WantFactoryError: `invalid backend address of tenant "acme"`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTenantBackendClustersFromOPConfig)
+	}
+}
@@ -42,7 +42,7 @@ type TokenAgentCluster struct {
 // NewTokenAgentClustersFromOPConfig creates a TokenAgentCluster from
 // OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
 func NewTokenAgentClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
-	if !opts.NonGCP && opts.ServiceAccountKey == "" {
+	if !opts.NonGCP && options.ServiceControlServiceAccountKey(opts) == "" && options.BackendAuthServiceAccountKey(opts) == "" {
 		return nil, nil
 	}
 
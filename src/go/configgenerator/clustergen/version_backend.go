@@ -0,0 +1,180 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	versionClusterNamePrefix = "backend-cluster-version-"
+
+	// DefaultVersionHeader is the request header holding the API version if
+	// the header is not set in flag --version_backends.
+	DefaultVersionHeader = "accept-version"
+)
+
+var (
+	versionRegex       = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+	versionHeaderRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// VersionBackends maps the values of the API version request header to the
+// backends serving the version. It is read from the JSON object of flag
+// --version_backends.
+type VersionBackends struct {
+	// Header is the request header holding the API version, which defaults to
+	// DefaultVersionHeader.
+	Header string `json:"header"`
+
+	Versions []*VersionBackend `json:"versions"`
+}
+
+// VersionBackend is the backend serving an API version.
+type VersionBackend struct {
+	Version        string `json:"version"`
+	BackendAddress string `json:"backendAddress"`
+}
+
+// VersionClusterName returns the name of the backend cluster of the API
+// version.
+func VersionClusterName(version string) string {
+	return versionClusterNamePrefix + version
+}
+
+// ParseVersionBackendsFromOPConfig parses the version backends from ESPv2
+// options, nil if version routing is disabled.
+func ParseVersionBackendsFromOPConfig(opts options.ConfigGeneratorOptions) (*VersionBackends, error) {
+	return ParseVersionBackends(opts.VersionBackends)
+}
+
+// ParseVersionBackends parses the JSON object of flag --version_backends, nil
+// if it is empty.
+func ParseVersionBackends(content string) (*VersionBackends, error) {
+	if content == "" {
+		return nil, nil
+	}
+
+	backends := &VersionBackends{}
+	if err := json.Unmarshal([]byte(content), backends); err != nil {
+		return nil, fmt.Errorf("invalid flag --version_backends, it should be a JSON object: %v", err)
+	}
+
+	backends.Header = strings.ToLower(backends.Header)
+	if backends.Header == "" {
+		backends.Header = DefaultVersionHeader
+	}
+	if !versionHeaderRegex.MatchString(backends.Header) {
+		return nil, fmt.Errorf("invalid flag --version_backends, header %q is not a valid request header name", backends.Header)
+	}
+	if len(backends.Versions) == 0 {
+		return nil, fmt.Errorf("invalid flag --version_backends, no version is specified")
+	}
+
+	versions := make(map[string]bool)
+	for _, backend := range backends.Versions {
+		if backend == nil {
+			return nil, fmt.Errorf("invalid flag --version_backends, version cannot be null")
+		}
+		if !versionRegex.MatchString(backend.Version) {
+			return nil, fmt.Errorf("invalid flag --version_backends, version %q must only contain letters, digits, '.', '-' and '_'", backend.Version)
+		}
+		if versions[backend.Version] {
+			return nil, fmt.Errorf("invalid flag --version_backends, duplicate version %q", backend.Version)
+		}
+		versions[backend.Version] = true
+
+		if backend.BackendAddress == "" {
+			return nil, fmt.Errorf("invalid flag --version_backends, version %q has no backend address", backend.Version)
+		}
+	}
+	return backends, nil
+}
+
+// VersionBackendCluster is an Envoy cluster to communicate with the backend
+// serving an API version.
+type VersionBackendCluster struct {
+	BackendCluster *helpers.BaseBackendCluster
+}
+
+// NewVersionBackendClustersFromOPConfig creates VersionBackendClusters from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewVersionBackendClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	backends, err := ParseVersionBackendsFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if backends == nil {
+		glog.Infof("Not adding version backend cluster gens because there is no version backend.")
+		return nil, nil
+	}
+
+	var gens []ClusterGenerator
+	for _, backend := range backends.Versions {
+		scheme, hostname, port, _, err := util.ParseURI(backend.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of version %q: %v", backend.Version, err)
+		}
+
+		protocol, useTLS, err := util.ParseBackendProtocol(scheme, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of version %q: %v", backend.Version, err)
+		}
+
+		var tls *helpers.ClusterTLSConfiger
+		if useTLS {
+			tls = helpers.NewClusterTLSConfigerFromOPConfig(opts, true)
+		}
+
+		gens = append(gens, &VersionBackendCluster{
+			BackendCluster: &helpers.BaseBackendCluster{
+				ClusterName:            VersionClusterName(backend.Version),
+				Hostname:               hostname,
+				Port:                   port,
+				Protocol:               protocol,
+				ClusterConnectTimeout:  helpers.BackendClusterConnectTimeout(opts),
+				MaxRequestsThreshold:   opts.BackendClusterMaxRequests,
+				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				HeaderKeyFormat:        opts.HeaderKeyFormat,
+				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
+				TLS:                    tls,
+				Connection:             helpers.NewClusterConnectionConfigerFromOPConfig(opts),
+				RetryBudget:            helpers.NewClusterRetryBudgetConfigerFromOPConfig(opts),
+				ProtocolSelection:      helpers.NewClusterProtocolSelectionConfigerFromOPConfig(opts),
+			},
+		})
+	}
+	return gens, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *VersionBackendCluster) GetName() string {
+	return c.BackendCluster.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *VersionBackendCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return c.BackendCluster.GenBaseConfig()
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewVersionBackendClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Success for HTTPS and HTTP version backends",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `
+{
+  "versions": [
+    {"version": "v2", "backendAddress": "https://v2.example.com"},
+    {"version": "2023-01.beta", "backendAddress": "http://beta.internal:8080"}
+  ]
+}`,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "backend-cluster-version-v2",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("v2.example.com", 443),
+					TransportSocket:      clustergentest.CreateDefaultTLS(t, "v2.example.com", false),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+				{
+					Name:                 "backend-cluster-version-2023-01.beta",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("beta.internal", 8080),
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewVersionBackendClustersFromOPConfig)
+	}
+}
+
+func TestNewVersionBackendClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Malformed version backends",
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `[]`,
+			},
+			WantFactoryError: "invalid flag --version_backends, it should be a JSON object",
+		},
+		{
+			Desc: "No version",
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `{"header": "X-Api-Version"}`,
+			},
+			WantFactoryError: "invalid flag --version_backends, no version is specified",
+		},
+		{
+			Desc: "Pseudo header",
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `{"header": ":path", "versions": [{"version": "v2", "backendAddress": "https://v2.example.com"}]}`,
+			},
+			WantFactoryError: `header ":path" is not a valid request header name`,
+		},
+		{
+			Desc: "Invalid version",
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `{"versions": [{"version": "v2 beta", "backendAddress": "https://v2.example.com"}]}`,
+			},
+			WantFactoryError: `version "v2 beta" must only contain`,
+		},
+		{
+			Desc: "Duplicate version",
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `
+{
+  "versions": [
+    {"version": "v2", "backendAddress": "https://v2.example.com"},
+    {"version": "v2", "backendAddress": "https://v2-canary.example.com"}
+  ]
+}`,
+			},
+			WantFactoryError: `duplicate version "v2"`,
+		},
+		{
+			Desc: "Invalid backend address",
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `{"versions": [{"version": "v2", "backendAddress": "ftp://v2.example.com"}]}`,
+			},
+			WantFactoryError: `invalid backend address of version "v2"`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewVersionBackendClustersFromOPConfig)
+	}
+}
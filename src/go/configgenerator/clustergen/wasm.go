@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	// WasmClusterName is the name of the xDS cluster to fetch the remote WASM
+	// filter module.
+	WasmClusterName = "wasm-cluster"
+)
+
+// WasmCluster is an Envoy cluster to fetch the WASM filter module from a
+// HTTPS URL.
+type WasmCluster struct {
+	BackendCluster *helpers.BaseBackendCluster
+}
+
+// NewWasmClustersFromOPConfig creates a WasmCluster from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewWasmClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	// Local WASM filter modules do not need a cluster.
+	if !strings.HasPrefix(opts.WasmFilterURI, "https://") {
+		return nil, nil
+	}
+
+	_, hostname, port, _, err := util.ParseURI(opts.WasmFilterURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --wasm_filter_uri: %v", err)
+	}
+
+	return []ClusterGenerator{
+		&WasmCluster{
+			BackendCluster: &helpers.BaseBackendCluster{
+				ClusterName:            WasmClusterName,
+				Hostname:               hostname,
+				Port:                   port,
+				Protocol:               util.HTTP1,
+				ClusterConnectTimeout:  opts.ClusterConnectTimeout,
+				BackendDnsLookupFamily: opts.BackendDnsLookupFamily,
+				DNS:                    helpers.NewClusterDNSConfigerFromOPConfig(opts),
+				TLS:                    helpers.NewClusterTLSConfigerFromOPConfig(opts, false),
+			},
+		},
+	}, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *WasmCluster) GetName() string {
+	return c.BackendCluster.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *WasmCluster) GenConfig() (*clusterpb.Cluster, error) {
+	return c.BackendCluster.GenBaseConfig()
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewWasmClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "Success with remote WASM filter module",
+			OptsIn: options.ConfigGeneratorOptions{
+				WasmFilterURI: "https://example.com:8443/filter.wasm",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "wasm-cluster",
+					LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_PREFERRED,
+					LoadAssignment:       util.CreateLoadAssignment("example.com", 8443),
+					TransportSocket:      clustergentest.CreateDefaultTLS(t, "example.com", false),
+				},
+			},
+		},
+		{
+			Desc: "No cluster for local WASM filter module",
+			OptsIn: options.ConfigGeneratorOptions{
+				WasmFilterURI: "/etc/espv2/filter.wasm",
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewWasmClustersFromOPConfig)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configgenerator
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	sc "github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
+)
+
+// CustomFilterUsage is a filter of the generated config that requires the
+// ESPv2 build of Envoy, with the features of the service config it serves.
+type CustomFilterUsage struct {
+	FilterName string   `json:"filterName"`
+	Features   []string `json:"features"`
+}
+
+// MakeStockEnvoyCompatibilityReport lists the filters generated for the
+// service config that are not representable with stock Envoy xDS, in the
+// order of the filter chain.
+//
+// The report is empty if the generated config runs on stock Envoy.
+func MakeStockEnvoyCompatibilityReport(serviceInfo *sc.ServiceInfo, scParams filtergen.ServiceControlOPFactoryParams) ([]CustomFilterUsage, error) {
+	filterGens, err := NewFilterGeneratorsFromOPConfig(serviceInfo.ServiceConfig(), serviceInfo.Options, MakeHTTPFilterGenFactories(scParams))
+	if err != nil {
+		return nil, err
+	}
+
+	var report []CustomFilterUsage
+	for _, filterGen := range filterGens {
+		features := filterGen.CustomEnvoyFeatures()
+		if len(features) == 0 {
+			continue
+		}
+		report = append(report, CustomFilterUsage{
+			FilterName: filterGen.FilterName(),
+			Features:   features,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configgenerator
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/google/go-cmp/cmp"
+
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func TestMakeStockEnvoyCompatibilityReport(t *testing.T) {
+	testData := []struct {
+		desc              string
+		fakeServiceConfig *confpb.Service
+		optsMergeFunc     func(opts *options.ConfigGeneratorOptions)
+		wantReport        []CustomFilterUsage
+	}{
+		{
+			desc: "Header sanitizer is the only custom filter without the other features",
+			fakeServiceConfig: &confpb.Service{
+				Name: testProjectName,
+				Apis: []*apipb.Api{
+					{
+						Name: testApiName,
+						Methods: []*apipb.Method{
+							{
+								Name: "CreateShelf",
+							},
+						},
+					},
+				},
+			},
+			optsMergeFunc: func(opts *options.ConfigGeneratorOptions) {
+				opts.EnableGrpcForHttp1 = false
+			},
+			wantReport: []CustomFilterUsage{
+				{
+					FilterName: filtergen.HeaderSanitizerFilterName,
+					Features: []string{
+						"HTTP method override by the x-http-method-override header",
+					},
+				},
+			},
+		},
+		{
+			desc: "Service Control, backend auth and path translation require the custom filters",
+			fakeServiceConfig: &confpb.Service{
+				Name: testProjectName,
+				Apis: []*apipb.Api{
+					{
+						Name: testApiName,
+						Methods: []*apipb.Method{
+							{
+								Name: "CreateShelf",
+							},
+							{
+								Name: "GetShelf",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.CreateShelf",
+							Pattern: &annotationspb.HttpRule_Post{
+								Post: "/shelves",
+							},
+						},
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/shelves/{shelf}",
+							},
+						},
+					},
+				},
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Selector:        "endpoints.examples.bookstore.Bookstore.GetShelf",
+							Address:         "https://backend.test/api",
+							PathTranslation: confpb.BackendRule_APPEND_PATH_TO_ADDRESS,
+							Authentication: &confpb.BackendRule_JwtAudience{
+								JwtAudience: "https://backend.test",
+							},
+						},
+					},
+				},
+				Usage: &confpb.Usage{
+					Rules: []*confpb.UsageRule{
+						{
+							Selector:               "endpoints.examples.bookstore.Bookstore.GetShelf",
+							AllowUnregisteredCalls: true,
+						},
+					},
+				},
+				Control: &confpb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+			},
+			optsMergeFunc: func(opts *options.ConfigGeneratorOptions) {
+				opts.ConsumerMaxConcurrentRequests = 8
+			},
+			wantReport: []CustomFilterUsage{
+				{
+					FilterName: filtergen.HeaderSanitizerFilterName,
+					Features: []string{
+						"HTTP method override by the x-http-method-override header",
+					},
+				},
+				{
+					FilterName: filtergen.ServiceControlFilterName,
+					Features: []string{
+						"Service Control check and report for 2 operations",
+						"API key validation for 1 operations",
+						"concurrency limits per API key (--consumer_max_concurrent_requests)",
+					},
+				},
+				{
+					FilterName: filtergen.BackendAuthFilterName,
+					Features: []string{
+						"backend authentication with ID tokens for 1 operations (1 audiences)",
+					},
+				},
+				{
+					FilterName: filtergen.PathRewriteFilterName,
+					Features: []string{
+						"path translation APPEND_PATH_TO_ADDRESS for 1 operations",
+					},
+				},
+				{
+					FilterName: filtergen.GrpcMetadataScrubberFilterName,
+					Features: []string{
+						"gRPC trailers kept for HTTP/1.1 clients (--enable_grpc_for_http1)",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.CommonOptions.TracingOptions.DisableTracing = true
+			if tc.optsMergeFunc != nil {
+				tc.optsMergeFunc(&opts)
+			}
+
+			fakeServiceInfo, err := configinfo.NewServiceInfoFromServiceConfig(tc.fakeServiceConfig, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotReport, err := MakeStockEnvoyCompatibilityReport(fakeServiceInfo, filtergen.ServiceControlOPFactoryParams{})
+			if err != nil {
+				t.Fatalf("MakeStockEnvoyCompatibilityReport() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantReport, gotReport); diff != "" {
+				t.Errorf("MakeStockEnvoyCompatibilityReport() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
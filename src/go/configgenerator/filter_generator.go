@@ -16,9 +16,12 @@ package configgenerator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 )
@@ -27,18 +30,72 @@ import (
 // (in order).
 func MakeHTTPFilterGenFactories(scParams filtergen.ServiceControlOPFactoryParams) []filtergen.FilterGeneratorOPFactory {
 	return []filtergen.FilterGeneratorOPFactory{
+		// Tap filter is the first so it captures the requests as received
+		// and the responses as sent.
+		func(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
+			var httpPatternsBySelector map[string][]*httppattern.Pattern
+			if opts.OperationTapEnabled != "" {
+				var err error
+				if httpPatternsBySelector, err = routegen.ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig, opts); err != nil {
+					return nil, fmt.Errorf("fail to parse http patterns for the tap filter: %v", err)
+				}
+			}
+			return filtergen.NewTapFilterGensFromOPConfig(serviceConfig, opts, httpPatternsBySelector)
+		},
 		filtergen.NewHeaderSanitizerFilterGensFromOPConfig,
+		filtergen.NewTenantSanitizerFilterGensFromOPConfig,
+
+		// Lua filter is before the other filters so the headers remapped by
+		// the Lua scripts are seen by them.
+		filtergen.NewLuaFilterGensFromOPConfig,
 		filtergen.NewCORSFilterGensFromOPConfig,
 
+		// Custom response filter should be before grpc transcoder filter to
+		// override the transcoded gRPC error responses, and before Service
+		// Control filter so the overridden status codes are reported.
+		filtergen.NewCustomResponseFilterGensFromOPConfig,
+
 		// Health check filter is behind Path Matcher filter, since Service Control
 		// filter needs to get the corresponding rule for health check in order to skip Report
 		filtergen.NewHealthCheckFilterGensFromOPConfig,
+
+		// RBAC filter is behind Health Check filter so the health checks from
+		// the load balancers are not restricted by the client IPs.
+		filtergen.NewRBACFilterGensFromOPConfig,
+
+		// Client IP rate limit filter is behind RBAC filter so the denied
+		// clients do not consume the tokens, and before the authentication
+		// filters so the floods are rejected before they are authenticated or
+		// reported.
+		filtergen.NewClientIPRateLimitFilterGensFromOPConfig,
+
+		// Buffer filter rejects the oversized requests before they are
+		// authenticated or reported.
+		filtergen.NewBufferFilterGensFromOPConfig,
 		filtergen.NewCompressorFilterGensFromOPConfig,
 		filtergen.NewJwtAuthnFilterGensFromOPConfig,
 		func(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
 			return filtergen.NewServiceControlFilterGensFromOPConfig(serviceConfig, opts, scParams)
 		},
 
+		// Request validation filter is behind the Service Control filter so the
+		// rejected requests are reported, and before grpc transcoder filter
+		// since it validates the HTTP requests.
+		filtergen.NewRequestValidationFilterGensFromOPConfig,
+
+		// ext_proc filter is behind the authentication filters so only the
+		// authorized requests are sent to the external processing service,
+		// and before grpc transcoder filter so it processes the HTTP requests.
+		filtergen.NewExtProcFilterGensFromOPConfig,
+
+		// Response cache filter is behind the authentication filters so the
+		// cached responses are only served to the authorized requests, and
+		// behind the Service Control filter so they are reported. It is before
+		// grpc transcoder filter so the HTTP responses are cached, and before
+		// backend auth filter since the requests with its Authorization header
+		// are never served from the cache.
+		filtergen.NewResponseCacheFilterGensFromOPConfig,
+
 		// grpc-web filter should be before grpc transcoder filter.
 		// It converts content-type application/grpc-web to application/grpc and
 		// grpc transcoder will bypass requests with application/grpc content type.
@@ -50,6 +107,33 @@ func MakeHTTPFilterGenFactories(scParams filtergen.ServiceControlOPFactoryParams
 		filtergen.NewPathRewriteFilterGensFromOPConfig,
 		filtergen.NewGRPCMetadataScrubberFilterGensFromOPConfig,
 
+		// WASM filter is moved before the filter named by flag
+		// --wasm_filter_before once all the filters are generated.
+		filtergen.NewWasmFilterGensFromOPConfig,
+
+		// Tenant routing filter is behind the authentication filters since it
+		// chooses the tenant by the consumer number from the Service Control
+		// filter or the verified JWT.
+		filtergen.NewTenantRoutingFilterGensFromOPConfig,
+
+		// Consumer routing filter is behind the Service Control filter since
+		// it matches the routes by the consumer number from it.
+		filtergen.NewConsumerRoutingFilterGensFromOPConfig,
+
+		// Consumer rate limit filter is behind the Service Control filter since
+		// the routes limit the requests by the consumer number from it.
+		filtergen.NewConsumerRateLimitFilterGensFromOPConfig,
+
+		// Fault filter is behind the authentication filters so only the
+		// authorized requests are faulted and the faults are reported, and
+		// before the adaptive concurrency filter so the injected delays are
+		// not sampled as the latency of the backends.
+		filtergen.NewFaultFilterGensFromOPConfig,
+
+		// Adaptive concurrency filter is right before the router so the
+		// sampled latency is the latency of the backends.
+		filtergen.NewAdaptiveConcurrencyFilterGensFromOPConfig,
+
 		// Add Envoy Router filter so requests are routed upstream.
 		// Router filter should be the last.
 		filtergen.NewRouterFilterGensFromOPConfig,
@@ -68,8 +152,201 @@ func NewFilterGeneratorsFromOPConfig(serviceConfig *servicepb.Service, opts opti
 		gens = append(gens, generator...)
 	}
 
+	gens, err := moveFilterGenBefore(gens, filtergen.WasmFilterName, opts.WasmFilterBefore)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --wasm_filter_before: %v", err)
+	}
+
+	if opts.HttpFilterOrder != "" {
+		gens, err = applyHttpFilterOrder(gens, opts.HttpFilterOrder)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag --http_filter_order: %v", err)
+		}
+	}
+
 	for i, gen := range gens {
 		glog.Infof("FilterGenerator %d is %q", i, gen.FilterName())
 	}
 	return gens, nil
 }
+
+// moveFilterGenBefore moves the FilterGenerator with the given name right
+// before the one named `before`. It is a no-op if the former is not generated.
+func moveFilterGenBefore(gens []filtergen.FilterGenerator, name string, before string) ([]filtergen.FilterGenerator, error) {
+	var moved filtergen.FilterGenerator
+	var rest []filtergen.FilterGenerator
+	for _, gen := range gens {
+		if gen.FilterName() == name && moved == nil {
+			moved = gen
+			continue
+		}
+		rest = append(rest, gen)
+	}
+	if moved == nil {
+		return gens, nil
+	}
+
+	for i, gen := range rest {
+		if gen.FilterName() == before {
+			result := append([]filtergen.FilterGenerator{}, rest[:i]...)
+			result = append(result, moved)
+			return append(result, rest[i:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("filter %q is not in the filter chain", before)
+}
+
+const (
+	filterOrderBefore   = "before:"
+	filterOrderAfter    = "after:"
+	filterOrderDisabled = "disabled"
+)
+
+// undisableableFilters are the filters that cannot be disabled by flag
+// --http_filter_order, since the APIs would be served without authentication
+// or reporting, the untrusted headers would reach the other filters and the
+// backends, or the requests would be sent to the wrong backend paths.
+var undisableableFilters = map[string]bool{
+	filtergen.HeaderSanitizerFilterName: true,
+	filtergen.TenantSanitizerFilterName: true,
+	filtergen.JWTAuthnFilterName:        true,
+	filtergen.ServiceControlFilterName:  true,
+	filtergen.BackendAuthFilterName:     true,
+	filtergen.PathRewriteFilterName:     true,
+	filtergen.RouterFilterName:          true,
+}
+
+// filterOrderDependencies are the pairs of filters where the first one must be
+// before the second one if both are generated.
+var filterOrderDependencies = [][2]string{
+	// The untrusted headers are removed before the other filters read them.
+	{filtergen.HeaderSanitizerFilterName, filtergen.JWTAuthnFilterName},
+	{filtergen.HeaderSanitizerFilterName, filtergen.ServiceControlFilterName},
+	{filtergen.TenantSanitizerFilterName, filtergen.TenantRoutingFilterName},
+	// Service Control filter reads the verified JWT.
+	{filtergen.JWTAuthnFilterName, filtergen.ServiceControlFilterName},
+	// The routing filters read the consumer number from the Service Control
+	// filter or the verified JWT.
+	{filtergen.JWTAuthnFilterName, filtergen.TenantRoutingFilterName},
+	{filtergen.ServiceControlFilterName, filtergen.TenantRoutingFilterName},
+	{filtergen.ServiceControlFilterName, filtergen.ConsumerRoutingFilterName},
+	{filtergen.ServiceControlFilterName, filtergen.ConsumerRateLimitFilterName},
+	// See the comments in MakeHTTPFilterGenFactories.
+	{filtergen.GRPCWebFilterName, filtergen.GRPCTranscoderFilterName},
+	{filtergen.ServiceControlFilterName, filtergen.ResponseCacheFilterName},
+	{filtergen.ResponseCacheFilterName, filtergen.GRPCTranscoderFilterName},
+	{filtergen.ResponseCacheFilterName, filtergen.BackendAuthFilterName},
+	{filtergen.RequestValidationFilterName, filtergen.GRPCTranscoderFilterName},
+}
+
+// applyHttpFilterOrder moves or disables the FilterGenerators by flag
+// --http_filter_order in the format of
+// "filter1=before:filter2;filter3=after:filter4;filter5=disabled", and
+// validates the resulting order.
+func applyHttpFilterOrder(gens []filtergen.FilterGenerator, order string) ([]filtergen.FilterGenerator, error) {
+	for _, rule := range strings.Split(order, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+
+		i := strings.Index(rule, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid rule %q, must be in the format of filter=before:other_filter, filter=after:other_filter or filter=disabled", rule)
+		}
+		name, action := strings.TrimSpace(rule[:i]), strings.TrimSpace(rule[i+1:])
+		if name == filtergen.RouterFilterName && action != filterOrderDisabled {
+			return nil, fmt.Errorf("filter %q must be the last filter", name)
+		}
+		if !hasFilterGen(gens, name) {
+			glog.Infof("Ignoring --http_filter_order rule %q because filter %q is not generated.", rule, name)
+			continue
+		}
+
+		var err error
+		switch {
+		case action == filterOrderDisabled:
+			if undisableableFilters[name] {
+				return nil, fmt.Errorf("filter %q cannot be disabled", name)
+			}
+			gens = removeFilterGen(gens, name)
+		case strings.HasPrefix(action, filterOrderBefore):
+			gens, err = moveFilterGenBefore(gens, name, strings.TrimPrefix(action, filterOrderBefore))
+		case strings.HasPrefix(action, filterOrderAfter):
+			gens, err = moveFilterGenAfter(gens, name, strings.TrimPrefix(action, filterOrderAfter))
+		default:
+			return nil, fmt.Errorf("invalid action %q of filter %q, must be before:other_filter, after:other_filter or disabled", action, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateFilterOrder(gens); err != nil {
+		return nil, err
+	}
+	return gens, nil
+}
+
+// moveFilterGenAfter moves the FilterGenerator with the given name right after
+// the one named `after`, which must not be the router filter.
+func moveFilterGenAfter(gens []filtergen.FilterGenerator, name string, after string) ([]filtergen.FilterGenerator, error) {
+	if after == filtergen.RouterFilterName {
+		return nil, fmt.Errorf("filter %q cannot be after the router filter", name)
+	}
+
+	for i, gen := range gens {
+		if gen.FilterName() != after {
+			continue
+		}
+		if i+1 == len(gens) {
+			return nil, fmt.Errorf("filter %q cannot be after the last filter %q", name, after)
+		}
+		if gens[i+1].FilterName() == name {
+			return gens, nil
+		}
+		return moveFilterGenBefore(gens, name, gens[i+1].FilterName())
+	}
+	return nil, fmt.Errorf("filter %q is not in the filter chain", after)
+}
+
+func hasFilterGen(gens []filtergen.FilterGenerator, name string) bool {
+	for _, gen := range gens {
+		if gen.FilterName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFilterGen(gens []filtergen.FilterGenerator, name string) []filtergen.FilterGenerator {
+	var result []filtergen.FilterGenerator
+	for _, gen := range gens {
+		if gen.FilterName() != name {
+			result = append(result, gen)
+		}
+	}
+	return result
+}
+
+// validateFilterOrder checks the router filter is the last one, and the
+// dependencies between the generated filters are kept.
+func validateFilterOrder(gens []filtergen.FilterGenerator) error {
+	positions := make(map[string]int)
+	for i, gen := range gens {
+		if _, ok := positions[gen.FilterName()]; !ok {
+			positions[gen.FilterName()] = i
+		}
+	}
+
+	if i, ok := positions[filtergen.RouterFilterName]; ok && i != len(gens)-1 {
+		return fmt.Errorf("filter %q must be the last filter", filtergen.RouterFilterName)
+	}
+	for _, dependency := range filterOrderDependencies {
+		first, ok1 := positions[dependency[0]]
+		second, ok2 := positions[dependency[1]]
+		if ok1 && ok2 && first > second {
+			return fmt.Errorf("filter %q must be before filter %q", dependency[0], dependency[1])
+		}
+	}
+	return nil
+}
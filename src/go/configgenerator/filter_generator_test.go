@@ -0,0 +1,262 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configgenerator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/google/go-cmp/cmp"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
+	apipb "google.golang.org/genproto/protobuf/api"
+	"google.golang.org/protobuf/proto"
+	descpb "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type fakeFilterGenerator struct {
+	name string
+
+	filtergen.NoopFilterGenerator
+}
+
+func (g *fakeFilterGenerator) FilterName() string {
+	return g.name
+}
+
+func (g *fakeFilterGenerator) GenFilterConfig() (proto.Message, error) {
+	return nil, nil
+}
+
+func TestApplyHttpFilterOrder(t *testing.T) {
+	defaultChain := []string{
+		filtergen.HeaderSanitizerFilterName,
+		filtergen.TenantSanitizerFilterName,
+		filtergen.HealthCheckFilterName,
+		filtergen.JWTAuthnFilterName,
+		filtergen.ServiceControlFilterName,
+		filtergen.GRPCWebFilterName,
+		filtergen.GRPCTranscoderFilterName,
+		filtergen.PathRewriteFilterName,
+		filtergen.WasmFilterName,
+		filtergen.RouterFilterName,
+	}
+
+	testData := []struct {
+		desc        string
+		order       string
+		wantFilters []string
+		wantError   string
+	}{
+		{
+			desc:  "Move WASM filter before jwt_authn and disable health check",
+			order: "envoy.filters.http.wasm=before:envoy.filters.http.jwt_authn;envoy.filters.http.health_check=disabled",
+			wantFilters: []string{
+				filtergen.HeaderSanitizerFilterName,
+				filtergen.TenantSanitizerFilterName,
+				filtergen.WasmFilterName,
+				filtergen.JWTAuthnFilterName,
+				filtergen.ServiceControlFilterName,
+				filtergen.GRPCWebFilterName,
+				filtergen.GRPCTranscoderFilterName,
+				filtergen.PathRewriteFilterName,
+				filtergen.RouterFilterName,
+			},
+		},
+		{
+			desc:  "Move health check after Service Control, ignoring the filters not generated",
+			order: " envoy.filters.http.health_check = after:com.google.espv2.filters.http.service_control ; envoy.filters.http.cors=disabled;",
+			wantFilters: []string{
+				filtergen.HeaderSanitizerFilterName,
+				filtergen.TenantSanitizerFilterName,
+				filtergen.JWTAuthnFilterName,
+				filtergen.ServiceControlFilterName,
+				filtergen.HealthCheckFilterName,
+				filtergen.GRPCWebFilterName,
+				filtergen.GRPCTranscoderFilterName,
+				filtergen.PathRewriteFilterName,
+				filtergen.WasmFilterName,
+				filtergen.RouterFilterName,
+			},
+		},
+		{
+			desc:      "Invalid rule",
+			order:     "envoy.filters.http.wasm",
+			wantError: `invalid rule "envoy.filters.http.wasm"`,
+		},
+		{
+			desc:      "Invalid action",
+			order:     "envoy.filters.http.wasm=first",
+			wantError: `invalid action "first" of filter "envoy.filters.http.wasm"`,
+		},
+		{
+			desc:      "Filter to move before is not generated",
+			order:     "envoy.filters.http.wasm=before:envoy.filters.http.cors",
+			wantError: `filter "envoy.filters.http.cors" is not in the filter chain`,
+		},
+		{
+			desc:      "Router filter cannot be moved",
+			order:     "envoy.filters.http.router=before:envoy.filters.http.wasm",
+			wantError: `filter "envoy.filters.http.router" must be the last filter`,
+		},
+		{
+			desc:      "No filter after router filter",
+			order:     "envoy.filters.http.wasm=after:envoy.filters.http.router",
+			wantError: `filter "envoy.filters.http.wasm" cannot be after the router filter`,
+		},
+		{
+			desc:      "Authentication filter cannot be disabled",
+			order:     "envoy.filters.http.jwt_authn=disabled",
+			wantError: `filter "envoy.filters.http.jwt_authn" cannot be disabled`,
+		},
+		{
+			desc:      "Header sanitizer filter cannot be disabled",
+			order:     "com.google.espv2.filters.http.header_sanitizer=disabled",
+			wantError: `filter "com.google.espv2.filters.http.header_sanitizer" cannot be disabled`,
+		},
+		{
+			desc:      "Tenant sanitizer filter cannot be disabled",
+			order:     "com.google.espv2.filters.http.tenant_sanitizer=disabled",
+			wantError: `filter "com.google.espv2.filters.http.tenant_sanitizer" cannot be disabled`,
+		},
+		{
+			desc:      "Path rewrite filter cannot be disabled",
+			order:     "com.google.espv2.filters.http.path_rewrite=disabled",
+			wantError: `filter "com.google.espv2.filters.http.path_rewrite" cannot be disabled`,
+		},
+		{
+			desc:      "Service Control filter must be after jwt_authn",
+			order:     "com.google.espv2.filters.http.service_control=before:envoy.filters.http.jwt_authn",
+			wantError: `filter "envoy.filters.http.jwt_authn" must be before filter "com.google.espv2.filters.http.service_control"`,
+		},
+		{
+			desc:      "grpc-web filter must be before grpc transcoder",
+			order:     "envoy.filters.http.grpc_web=after:envoy.filters.http.grpc_json_transcoder",
+			wantError: `filter "envoy.filters.http.grpc_web" must be before filter "envoy.filters.http.grpc_json_transcoder"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			var gens []filtergen.FilterGenerator
+			for _, name := range defaultChain {
+				gens = append(gens, &fakeFilterGenerator{name: name})
+			}
+
+			gotGens, err := applyHttpFilterOrder(gens, tc.order)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("applyHttpFilterOrder() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyHttpFilterOrder() got error %v", err)
+			}
+
+			var gotFilters []string
+			for _, gen := range gotGens {
+				gotFilters = append(gotFilters, gen.FilterName())
+			}
+			if diff := cmp.Diff(tc.wantFilters, gotFilters); diff != "" {
+				t.Errorf("applyHttpFilterOrder() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewFilterGeneratorsFromOPConfigResponseCacheOrder(t *testing.T) {
+	rawDescriptor, err := proto.Marshal(&descpb.FileDescriptorSet{
+		File: []*descpb.FileDescriptorProto{
+			{
+				Name: proto.String("test_file_desciptor_name.proto"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal FileDescriptorSet: %v", err)
+	}
+	content, err := anypb.New(&smpb.ConfigFile{
+		FilePath:     "api_descriptor.pb",
+		FileContents: rawDescriptor,
+		FileType:     smpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal source file into any: %v", err)
+	}
+
+	serviceConfig := &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "GetShelf",
+					},
+				},
+			},
+		},
+		SourceInfo: &confpb.SourceInfo{
+			SourceFiles: []*anypb.Any{content},
+		},
+		Backend: &confpb.Backend{
+			Rules: []*confpb.BackendRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+					Address:  "grpcs://backend.test",
+					Authentication: &confpb.BackendRule_JwtAudience{
+						JwtAudience: "https://backend.test",
+					},
+				},
+			},
+		},
+		Control: &confpb.Control{
+			Environment: "servicecontrol.googleapis.com",
+		},
+	}
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.1:80"
+	opts.EnableResponseCache = true
+
+	gens, err := NewFilterGeneratorsFromOPConfig(serviceConfig, opts, MakeHTTPFilterGenFactories(filtergen.ServiceControlOPFactoryParams{}))
+	if err != nil {
+		t.Fatalf("NewFilterGeneratorsFromOPConfig() got error %v", err)
+	}
+
+	indexes := make(map[string]int)
+	for i, gen := range gens {
+		indexes[gen.FilterName()] = i
+	}
+	// The response cache filter is after the Service Control filter, and before
+	// the grpc transcoder and backend auth filters.
+	wantOrder := []string{
+		filtergen.ServiceControlFilterName,
+		filtergen.ResponseCacheFilterName,
+		filtergen.GRPCTranscoderFilterName,
+		filtergen.BackendAuthFilterName,
+	}
+	for i, name := range wantOrder {
+		if _, ok := indexes[name]; !ok {
+			t.Fatalf("filter %q is not generated", name)
+		}
+		if i > 0 && indexes[wantOrder[i-1]] >= indexes[name] {
+			t.Errorf("filter %q at %d, want it before filter %q at %d", wantOrder[i-1], indexes[wantOrder[i-1]], name, indexes[name])
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	acpb "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	envoytypepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+)
+
+const (
+	// The prefix of the runtime keys overriding the access log sample rates,
+	// followed by the consumer number or "default".
+	accessLogSampleRateRuntimeKeyPrefix = "access_log.consumer_sample_rate."
+
+	// The consumer matching all the consumers not listed in flag
+	// --access_log_consumer_sample_rates.
+	anyConsumer = "*"
+)
+
+// ConsumerSampleRate is the percentage of the requests of an API consumer
+// written to the access log.
+type ConsumerSampleRate struct {
+	// ConsumerNumber is the project number of the API consumer, or "*" for
+	// the other consumers.
+	ConsumerNumber string
+	Percent        float64
+}
+
+// ParseAccessLogConsumerSampleRates parses flag --access_log_consumer_sample_rates
+// in the format of "consumer_number1=percent1;consumer_number2=percent2".
+func ParseAccessLogConsumerSampleRates(s string) ([]ConsumerSampleRate, error) {
+	var rates []ConsumerSampleRate
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid consumer sample rate %q, must be in the format of consumer_number=percent", pair)
+		}
+		consumer, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if consumer != anyConsumer {
+			if _, err := strconv.ParseUint(consumer, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid consumer %q, must be a project number or %q", consumer, anyConsumer)
+			}
+		}
+		if seen[consumer] {
+			return nil, fmt.Errorf("consumer %q is specified more than once", consumer)
+		}
+		seen[consumer] = true
+
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid sample rate %q of consumer %q, must be a percentage in the range of [0, 100]", value, consumer)
+		}
+
+		rates = append(rates, ConsumerSampleRate{
+			ConsumerNumber: consumer,
+			Percent:        percent,
+		})
+	}
+	return rates, nil
+}
+
+// MakeAccessLogConsumerSampleFilter creates the access log filter sampling
+// the requests by the consumer number header set by the Service Control
+// filter. The consumers not listed are sampled by the rate of "*", or all
+// logged if it is not specified. It returns nil if there is no rate.
+//
+// The rates can be overridden at runtime by the keys
+// "access_log.consumer_sample_rate.<consumer_number>" and
+// "access_log.consumer_sample_rate.default".
+func MakeAccessLogConsumerSampleFilter(rates []ConsumerSampleRate, consumerNumberHeader string) *acpb.AccessLogFilter {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	var filters []*acpb.AccessLogFilter
+	var othersFilters []*acpb.AccessLogFilter
+	var othersRate *ConsumerSampleRate
+	for i, rate := range rates {
+		if rate.ConsumerNumber == anyConsumer {
+			othersRate = &rates[i]
+			continue
+		}
+
+		filters = append(filters, makeAccessLogAndFilter([]*acpb.AccessLogFilter{
+			makeConsumerHeaderFilter(consumerNumberHeader, rate.ConsumerNumber, false),
+			makeSampleRuntimeFilter(rate.ConsumerNumber, rate.Percent),
+		}))
+		othersFilters = append(othersFilters, makeConsumerHeaderFilter(consumerNumberHeader, rate.ConsumerNumber, true))
+	}
+
+	if othersRate != nil {
+		othersFilters = append(othersFilters, makeSampleRuntimeFilter("default", othersRate.Percent))
+	}
+	filters = append(filters, makeAccessLogAndFilter(othersFilters))
+
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_OrFilter{
+			OrFilter: &acpb.OrFilter{
+				Filters: filters,
+			},
+		},
+	}
+}
+
+// makeAccessLogAndFilter combines the filters, since an AndFilter requires at
+// least two of them.
+func makeAccessLogAndFilter(filters []*acpb.AccessLogFilter) *acpb.AccessLogFilter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_AndFilter{
+			AndFilter: &acpb.AndFilter{
+				Filters: filters,
+			},
+		},
+	}
+}
+
+func makeConsumerHeaderFilter(consumerNumberHeader, consumerNumber string, invert bool) *acpb.AccessLogFilter {
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_HeaderFilter{
+			HeaderFilter: &acpb.HeaderFilter{
+				Header: &routepb.HeaderMatcher{
+					Name: consumerNumberHeader,
+					HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+						StringMatch: &matcherpb.StringMatcher{
+							MatchPattern: &matcherpb.StringMatcher_Exact{
+								Exact: consumerNumber,
+							},
+						},
+					},
+					InvertMatch: invert,
+				},
+			},
+		},
+	}
+}
+
+func makeSampleRuntimeFilter(name string, percent float64) *acpb.AccessLogFilter {
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_RuntimeFilter{
+			RuntimeFilter: &acpb.RuntimeFilter{
+				RuntimeKey: accessLogSampleRateRuntimeKeyPrefix + name,
+				PercentSampled: &envoytypepb.FractionalPercent{
+					Numerator:   uint32(math.Round(percent * 10000)),
+					Denominator: envoytypepb.FractionalPercent_MILLION,
+				},
+				UseIndependentRandomness: true,
+			},
+		},
+	}
+}
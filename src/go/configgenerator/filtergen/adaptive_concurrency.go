@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	acpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/adaptive_concurrency/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// AdaptiveConcurrencyFilterName is the Envoy filter name for debug logging.
+	AdaptiveConcurrencyFilterName = "envoy.filters.http.adaptive_concurrency"
+
+	// The intervals required by Envoy if they are not set by the flags.
+	defaultConcurrencyUpdateInterval = 100 * time.Millisecond
+	defaultMinRttInterval            = 60 * time.Second
+)
+
+// AdaptiveConcurrencyGenerator limits the concurrent requests to the backends
+// by the gradient of the sampled latency against the minimum latency, so the
+// requests beyond the limit are rejected with 503 instead of being queued.
+//
+// Zero values of the tuning parameters use the defaults of Envoy.
+type AdaptiveConcurrencyGenerator struct {
+	SamplePercentile       float64
+	MaxConcurrencyLimit    uint32
+	UpdateInterval         time.Duration
+	MinRttInterval         time.Duration
+	MinRttRequestCount     uint32
+	MinRttBufferPercentage float64
+
+	NoopFilterGenerator
+}
+
+// NewAdaptiveConcurrencyFilterGensFromOPConfig creates an AdaptiveConcurrencyGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewAdaptiveConcurrencyFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableAdaptiveConcurrency {
+		glog.Info("Not adding adaptive concurrency filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	if opts.AdaptiveConcurrencySamplePercentile < 0 || opts.AdaptiveConcurrencySamplePercentile > 100 {
+		return nil, fmt.Errorf("invalid flag --adaptive_concurrency_sample_percentile %v, must be in [0, 100]", opts.AdaptiveConcurrencySamplePercentile)
+	}
+	if opts.AdaptiveConcurrencyBufferPercent < 0 || opts.AdaptiveConcurrencyBufferPercent > 100 {
+		return nil, fmt.Errorf("invalid flag --adaptive_concurrency_buffer_percent %v, must be in [0, 100]", opts.AdaptiveConcurrencyBufferPercent)
+	}
+	if opts.AdaptiveConcurrencyMaxLimit < 0 {
+		return nil, fmt.Errorf("invalid flag --adaptive_concurrency_max_limit %d, must be >= 0", opts.AdaptiveConcurrencyMaxLimit)
+	}
+	if opts.AdaptiveConcurrencyMinRttRequestCount < 0 {
+		return nil, fmt.Errorf("invalid flag --adaptive_concurrency_min_rtt_request_count %d, must be >= 0", opts.AdaptiveConcurrencyMinRttRequestCount)
+	}
+	if opts.AdaptiveConcurrencyUpdateInterval < 0 {
+		return nil, fmt.Errorf("invalid flag --adaptive_concurrency_update_interval %v, must be >= 0", opts.AdaptiveConcurrencyUpdateInterval)
+	}
+	if opts.AdaptiveConcurrencyMinRttInterval < 0 {
+		return nil, fmt.Errorf("invalid flag --adaptive_concurrency_min_rtt_interval %v, must be >= 0", opts.AdaptiveConcurrencyMinRttInterval)
+	}
+
+	return []FilterGenerator{
+		&AdaptiveConcurrencyGenerator{
+			SamplePercentile:       opts.AdaptiveConcurrencySamplePercentile,
+			MaxConcurrencyLimit:    uint32(opts.AdaptiveConcurrencyMaxLimit),
+			UpdateInterval:         opts.AdaptiveConcurrencyUpdateInterval,
+			MinRttInterval:         opts.AdaptiveConcurrencyMinRttInterval,
+			MinRttRequestCount:     uint32(opts.AdaptiveConcurrencyMinRttRequestCount),
+			MinRttBufferPercentage: opts.AdaptiveConcurrencyBufferPercent,
+		},
+	}, nil
+}
+
+func (g *AdaptiveConcurrencyGenerator) FilterName() string {
+	return AdaptiveConcurrencyFilterName
+}
+
+func (g *AdaptiveConcurrencyGenerator) GenFilterConfig() (proto.Message, error) {
+	updateInterval := g.UpdateInterval
+	if updateInterval == 0 {
+		updateInterval = defaultConcurrencyUpdateInterval
+	}
+	minRttInterval := g.MinRttInterval
+	if minRttInterval == 0 {
+		minRttInterval = defaultMinRttInterval
+	}
+
+	gradient := &acpb.GradientControllerConfig{
+		ConcurrencyLimitParams: &acpb.GradientControllerConfig_ConcurrencyLimitCalculationParams{
+			ConcurrencyUpdateInterval: durationpb.New(updateInterval),
+		},
+		MinRttCalcParams: &acpb.GradientControllerConfig_MinimumRTTCalculationParams{
+			Interval: durationpb.New(minRttInterval),
+		},
+	}
+	if g.SamplePercentile > 0 {
+		gradient.SampleAggregatePercentile = &typepb.Percent{Value: g.SamplePercentile}
+	}
+	if g.MaxConcurrencyLimit > 0 {
+		gradient.ConcurrencyLimitParams.MaxConcurrencyLimit = wrapperspb.UInt32(g.MaxConcurrencyLimit)
+	}
+	if g.MinRttRequestCount > 0 {
+		gradient.MinRttCalcParams.RequestCount = wrapperspb.UInt32(g.MinRttRequestCount)
+	}
+	if g.MinRttBufferPercentage > 0 {
+		gradient.MinRttCalcParams.Buffer = &typepb.Percent{Value: g.MinRttBufferPercentage}
+	}
+
+	return &acpb.AdaptiveConcurrency{
+		ConcurrencyControllerConfig: &acpb.AdaptiveConcurrency_GradientControllerConfig{
+			GradientControllerConfig: gradient,
+		},
+	}, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewAdaptiveConcurrencyFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:   "Disabled by default",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Generate with the required intervals by default",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableAdaptiveConcurrency: true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.adaptive_concurrency",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.adaptive_concurrency.v3.AdaptiveConcurrency",
+      "gradientControllerConfig":{
+         "concurrencyLimitParams":{
+            "concurrencyUpdateInterval":"0.100s"
+         },
+         "minRttCalcParams":{
+            "interval":"60s"
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate with the gradient parameters",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableAdaptiveConcurrency:             true,
+				AdaptiveConcurrencySamplePercentile:   90,
+				AdaptiveConcurrencyMaxLimit:           200,
+				AdaptiveConcurrencyUpdateInterval:     time.Second,
+				AdaptiveConcurrencyMinRttInterval:     30 * time.Second,
+				AdaptiveConcurrencyMinRttRequestCount: 20,
+				AdaptiveConcurrencyBufferPercent:      50,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.adaptive_concurrency",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.adaptive_concurrency.v3.AdaptiveConcurrency",
+      "gradientControllerConfig":{
+         "sampleAggregatePercentile":{
+            "value":90
+         },
+         "concurrencyLimitParams":{
+            "maxConcurrencyLimit":200,
+            "concurrencyUpdateInterval":"1s"
+         },
+         "minRttCalcParams":{
+            "interval":"30s",
+            "requestCount":20,
+            "buffer":{
+               "value":50
+            }
+         }
+      }
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewAdaptiveConcurrencyFilterGensFromOPConfig)
+	}
+}
+
+func TestNewAdaptiveConcurrencyFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Sample percentile out of range",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableAdaptiveConcurrency:           true,
+				AdaptiveConcurrencySamplePercentile: 101,
+			},
+			WantFactoryError: "invalid flag --adaptive_concurrency_sample_percentile 101, must be in [0, 100]",
+		},
+		{
+			Desc: "Negative max limit",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableAdaptiveConcurrency:   true,
+				AdaptiveConcurrencyMaxLimit: -1,
+			},
+			WantFactoryError: "invalid flag --adaptive_concurrency_max_limit -1, must be >= 0",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewAdaptiveConcurrencyFilterGensFromOPConfig)
+	}
+}
@@ -16,7 +16,9 @@ package filtergen
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
@@ -79,7 +81,7 @@ func NewBackendAuthFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts
 			DependencyErrorBehavior: opts.DependencyErrorBehavior,
 			BackendAuthCredentials:  opts.BackendAuthCredentials,
 			CORSOperationDelimiter:  opts.CorsOperationDelimiter,
-			AccessToken:             helpers.NewFilterAccessTokenConfigerFromOPConfig(opts),
+			AccessToken:             helpers.NewBackendAuthAccessTokenConfigerFromOPConfig(opts),
 		},
 	}, nil
 }
@@ -88,6 +90,10 @@ func (g *BackendAuthGenerator) FilterName() string {
 	return BackendAuthFilterName
 }
 
+func (g *BackendAuthGenerator) CustomEnvoyFeatures() []string {
+	return []string{fmt.Sprintf("backend authentication with ID tokens for %d operations (%d audiences)", len(g.AudienceBySelector), len(g.UniqueAudiences))}
+}
+
 // matchAudience matches the selector to the configured audience.
 // Accounts for CORS selectors.
 func (g *BackendAuthGenerator) matchAudience(selector string) (string, error) {
@@ -153,12 +159,20 @@ func (g *BackendAuthGenerator) GenFilterConfig() (proto.Message, error) {
 					Cluster: clustergen.IAMServerClusterName,
 					Timeout: durationpb.New(g.HttpRequestTimeout),
 				},
-				// Currently only support fetching access token from instance metadata
-				// server, not by service account file.
 				AccessToken:         g.AccessToken.MakeAccessTokenConfig(),
 				ServiceAccountEmail: g.BackendAuthCredentials.ServiceAccountEmail,
 				Delegates:           g.BackendAuthCredentials.Delegates,
 			}}
+	} else if g.AccessToken.ServiceAccountKey != "" {
+		// The token agent mints the identity tokens with the service account key,
+		// in the same format as the metadata server.
+		backendAuthConfig.IdTokenInfo = &bapb.FilterConfig_ImdsToken{
+			ImdsToken: &commonpb.HttpUri{
+				Uri:     fmt.Sprintf("http://%s:%v%s", util.LoopbackIPv4Addr, g.AccessToken.TokenAgentPort, util.TokenAgentBackendAuthIdentityTokenPath),
+				Cluster: clustergen.TokenAgentClusterName,
+				Timeout: durationpb.New(g.HttpRequestTimeout),
+			},
+		}
 	} else {
 		backendAuthConfig.IdTokenInfo = &bapb.FilterConfig_ImdsToken{
 			ImdsToken: &commonpb.HttpUri{
@@ -181,13 +195,23 @@ func GetJWTAudiencesBySelectorFromOPConfig(serviceConfig *servicepb.Service, opt
 	uniqueAudiences := make(map[string]bool)
 	audienceBySelector := make(map[string]string)
 
+	opTemplates, err := util.ParseSelectorMap(opts.OperationJwtAudienceTemplates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid flag --operation_jwt_audience_templates: %v", err)
+	}
+
 	for _, rule := range serviceConfig.GetBackend().GetRules() {
 		if util.ShouldSkipOPDiscoveryAPI(rule.GetSelector(), opts.AllowDiscoveryAPIs) {
 			glog.Warningf("Skip backend rule %q because discovery API is not supported.", rule.GetSelector())
 			continue
 		}
 
-		jwtAud, err := parseJwtAudFromBackendRule(rule)
+		template := opts.BackendJwtAudienceTemplate
+		if opTemplate, ok := opTemplates.Lookup(rule.GetSelector()); ok {
+			template = opTemplate
+		}
+
+		jwtAud, err := parseJwtAudFromBackendRule(rule, template)
 		if err != nil {
 			return nil, nil, fmt.Errorf("fail to parse JWT audience for backend rule %q: %v", rule.GetSelector(), err)
 		}
@@ -209,10 +233,12 @@ func GetJWTAudiencesBySelectorFromOPConfig(serviceConfig *servicepb.Service, opt
 	return audienceBySelector, uniqueAudiences, nil
 }
 
-// parseJwtAudFromBackendRule returns the correct JWT audience for the given BackendRule.
+// parseJwtAudFromBackendRule returns the correct JWT audience for the given
+// BackendRule. Without jwt_audience, the audience is made from the backend
+// address by the template, if not empty.
 //
 // Replaces ServiceInfo::determineBackendAuthJwtAud.
-func parseJwtAudFromBackendRule(r *servicepb.BackendRule) (string, error) {
+func parseJwtAudFromBackendRule(r *servicepb.BackendRule, template string) (string, error) {
 	//TODO(taoxuy): b/149334660 Check if the scopes for IAM include the path prefix
 	switch r.GetAuthentication().(type) {
 	case *servicepb.BackendRule_JwtAudience:
@@ -221,13 +247,16 @@ func parseJwtAudFromBackendRule(r *servicepb.BackendRule) (string, error) {
 		if r.GetDisableAuth() {
 			return "", nil
 		}
-		return BackendAddressToJWTAud(r.GetAddress())
 	default:
 		if r.Address == "" {
 			return "", nil
 		}
-		return BackendAddressToJWTAud(r.GetAddress())
 	}
+
+	if template != "" {
+		return BackendAddressToTemplatedJWTAud(r.GetAddress(), r.GetSelector(), template)
+	}
+	return BackendAddressToJWTAud(r.GetAddress())
 }
 
 // BackendAddressToJWTAud transforms the backend address into the proper JWT
@@ -250,3 +279,48 @@ func BackendAddressToJWTAud(address string) (string, error) {
 	}
 	return fmt.Sprintf("http://%s", hostname), nil
 }
+
+// jwtAudienceTemplatePlaceholder matches the placeholders of the JWT audience
+// templates, such as "{host}".
+var jwtAudienceTemplatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// BackendAddressToTemplatedJWTAud makes the JWT audience from the backend
+// address and the selector of the backend rule by the template, such as
+// "{scheme}://{host}{path}".
+//
+// As BackendAddressToJWTAud, the grpc/grpcs schemes are changed to http/https.
+func BackendAddressToTemplatedJWTAud(address, selector, template string) (string, error) {
+	scheme, hostname, port, path, err := util.ParseURI(address)
+	if err != nil {
+		return "", fmt.Errorf("error parsing backend address for JWT audience: %v", err)
+	}
+
+	_, useTLS, err := util.ParseBackendProtocol(scheme, "")
+	if err != nil {
+		return "", fmt.Errorf("error parsing backend protocol for JWT audience: %v", err)
+	}
+	audScheme := "http"
+	if useTLS {
+		audScheme = "https"
+	}
+
+	values := map[string]string{
+		"{scheme}":    audScheme,
+		"{host}":      hostname,
+		"{port}":      strconv.FormatUint(uint64(port), 10),
+		"{path}":      path,
+		"{operation}": selector,
+	}
+	var unknown string
+	aud := jwtAudienceTemplatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := values[placeholder]
+		if !ok && unknown == "" {
+			unknown = placeholder
+		}
+		return value
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown placeholder %q in JWT audience template %q", unknown, template)
+	}
+	return aud, nil
+}
@@ -65,6 +65,54 @@ func TestNewBackendAuthFilterGensFromOPConfig_GenConfig(t *testing.T) {
       "jwtAudienceList":["bar.com","foo.com"]
    }
 }
+`,
+			},
+		},
+		{
+			Desc: "Generate audiences from the backend addresses by the templates",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Selector:        "testapipb.foo",
+							Address:         "https://testapipb.com/foo",
+							PathTranslation: confpb.BackendRule_CONSTANT_ADDRESS,
+						},
+						{
+							Selector:        "testapipb.bar",
+							Address:         "grpc://bar.com:8080",
+							PathTranslation: confpb.BackendRule_APPEND_PATH_TO_ADDRESS,
+						},
+						{
+							Selector:        "testapipb.baz",
+							Address:         "https://testapipb.com/baz",
+							PathTranslation: confpb.BackendRule_CONSTANT_ADDRESS,
+							Authentication: &confpb.BackendRule_JwtAudience{
+								JwtAudience: "baz.com",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendJwtAudienceTemplate:    "{scheme}://{host}{path}",
+				OperationJwtAudienceTemplates: "testapipb.bar={scheme}://{host}:{port}/{operation}",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.backend_auth",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.backend_auth.FilterConfig",
+      "depErrorBehavior":"BLOCK_INIT_ON_ANY_ERROR",
+      "imdsToken":{
+          "cluster":"metadata-cluster",
+          "timeout":"30s",
+          "uri":"http://169.254.169.254/computeMetadata/v1/instance/service-accounts/default/identity"
+      },
+      "jwtAudienceList":["baz.com","http://bar.com:8080/testapipb.bar","https://testapipb.com/foo"]
+   }
+}
 `,
 			},
 		},
@@ -164,6 +212,97 @@ func TestNewBackendAuthFilterGensFromOPConfig_GenConfig(t *testing.T) {
       "jwtAudienceList":["bar.com"]
    }
 }
+`,
+			},
+		},
+		{
+			Desc: "Mint the identity tokens by token agent when the backend auth service account key is set",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Selector:        "testapipb.bar",
+							Address:         "https://testapipb.com/foo",
+							PathTranslation: confpb.BackendRule_CONSTANT_ADDRESS,
+							Authentication: &confpb.BackendRule_JwtAudience{
+								JwtAudience: "bar.com",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				ServiceControlServiceAccountKey: "/path/to/service-control-key.json",
+				BackendAuthServiceAccountKey:    "/path/to/backend-auth-key.json",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.backend_auth",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.backend_auth.FilterConfig",
+      "depErrorBehavior":"BLOCK_INIT_ON_ANY_ERROR",
+      "imdsToken":{
+          "cluster":"token-agent-cluster",
+          "timeout":"30s",
+          "uri":"http://127.0.0.1:8791/local/backend_auth/identity_token"
+      },
+      "jwtAudienceList":["bar.com"]
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Get the access token to call IAM by token agent when the service account key is set",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Selector:        "testapipb.bar",
+							Address:         "https://testapipb.com/foo",
+							PathTranslation: confpb.BackendRule_CONSTANT_ADDRESS,
+							Authentication: &confpb.BackendRule_JwtAudience{
+								JwtAudience: "bar.com",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					BackendAuthCredentials: &options.IAMCredentialsOptions{
+						ServiceAccountEmail: "service-account@google.com",
+						TokenKind:           options.IDToken,
+					},
+				},
+				ServiceAccountKey: "/path/to/key.json",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.backend_auth",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.backend_auth.FilterConfig",
+      "depErrorBehavior":"BLOCK_INIT_ON_ANY_ERROR",
+      "iamToken":{
+         "accessToken":{
+            "remoteToken":{
+               "cluster":"token-agent-cluster",
+               "timeout":"30s",
+               "uri":"http://127.0.0.1:8791/local/backend_auth/access_token"
+            }
+         },
+         "iamUri":{
+            "cluster":"iam-cluster",
+            "timeout":"30s",
+            "uri":"https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/service-account@google.com:generateIdToken"
+         },
+         "serviceAccountEmail":"service-account@google.com"
+      },
+      "jwtAudienceList":["bar.com"]
+   }
+}
 `,
 			},
 		},
@@ -426,6 +565,24 @@ func TestNewBackendAuthFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
 			},
 			WantFactoryError: `fail to parse JWT audience for backend rule`,
 		},
+		{
+			Desc: "Fail when the JWT audience template has an unknown placeholder",
+			ServiceConfigIn: &confpb.Service{
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Selector:        "testapipb.bar",
+							Address:         "https://testapipb.com/foo",
+							PathTranslation: confpb.BackendRule_CONSTANT_ADDRESS,
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendJwtAudienceTemplate: "https://{hostname}",
+			},
+			WantFactoryError: `unknown placeholder "{hostname}" in JWT audience template "https://{hostname}"`,
+		},
 	}
 
 	for _, tc := range testdata {
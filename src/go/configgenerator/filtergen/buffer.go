@@ -0,0 +1,149 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	bufferpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/buffer/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// BufferFilterName is the Envoy filter name for debug logging.
+	BufferFilterName = "envoy.filters.http.buffer"
+)
+
+// BufferGenerator rejects the requests with bodies larger than the limit
+// with 413 Payload Too Large, before they are sent to the backend.
+//
+// The whole request body is buffered, so the filter is disabled for the
+// client streaming methods.
+type BufferGenerator struct {
+	// MaxRequestBytes is the global limit, 0 if unlimited.
+	MaxRequestBytes uint32
+
+	// MaxRequestBytesBySelector overrides the limit per operation, 0 if
+	// unlimited.
+	MaxRequestBytesBySelector map[string]uint32
+
+	// StreamingSelectors are the client streaming methods.
+	StreamingSelectors map[string]bool
+
+	NoopFilterGenerator
+}
+
+// NewBufferFilterGensFromOPConfig creates a BufferGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewBufferFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.MaxRequestBytes == 0 && opts.OperationMaxRequestBytes == "" {
+		glog.Info("Not adding buffer filter gen because request body size is not limited.")
+		return nil, nil
+	}
+
+	maxRequestBytes, err := parseMaxRequestBytes(strconv.Itoa(opts.MaxRequestBytes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --max_request_bytes: %v", err)
+	}
+
+	opMaxRequestBytes, err := util.ParseSelectorMap(opts.OperationMaxRequestBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_max_request_bytes: %v", err)
+	}
+
+	maxRequestBytesBySelector := make(map[string]uint32)
+	streamingSelectors := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			if method.GetRequestStreaming() {
+				streamingSelectors[selector] = true
+			}
+
+			value, ok := opMaxRequestBytes.Lookup(selector)
+			if !ok {
+				continue
+			}
+			if maxRequestBytesBySelector[selector], err = parseMaxRequestBytes(value); err != nil {
+				return nil, fmt.Errorf("invalid flag --operation_max_request_bytes for operation %q: %v", selector, err)
+			}
+		}
+	}
+
+	return []FilterGenerator{
+		&BufferGenerator{
+			MaxRequestBytes:           maxRequestBytes,
+			MaxRequestBytesBySelector: maxRequestBytesBySelector,
+			StreamingSelectors:        streamingSelectors,
+		},
+	}, nil
+}
+
+func parseMaxRequestBytes(value string) (uint32, error) {
+	maxRequestBytes, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid number of bytes", value)
+	}
+	return uint32(maxRequestBytes), nil
+}
+
+func (g *BufferGenerator) FilterName() string {
+	return BufferFilterName
+}
+
+func (g *BufferGenerator) GenFilterConfig() (proto.Message, error) {
+	// The limit is required by Envoy. If there is no global limit, the filter
+	// is disabled per route for the operations without limit.
+	maxRequestBytes := g.MaxRequestBytes
+	if maxRequestBytes == 0 {
+		maxRequestBytes = math.MaxUint32
+	}
+	return &bufferpb.Buffer{
+		MaxRequestBytes: &wrapperspb.UInt32Value{Value: maxRequestBytes},
+	}, nil
+}
+
+func (g *BufferGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	maxRequestBytes, ok := g.MaxRequestBytesBySelector[selector]
+	if !ok {
+		maxRequestBytes = g.MaxRequestBytes
+	}
+
+	if maxRequestBytes == 0 || g.StreamingSelectors[selector] {
+		return &bufferpb.BufferPerRoute{
+			Override: &bufferpb.BufferPerRoute_Disabled{
+				Disabled: true,
+			},
+		}, nil
+	}
+	if maxRequestBytes == g.MaxRequestBytes {
+		return nil, nil
+	}
+	return &bufferpb.BufferPerRoute{
+		Override: &bufferpb.BufferPerRoute_Buffer{
+			Buffer: &bufferpb.Buffer{
+				MaxRequestBytes: &wrapperspb.UInt32Value{Value: maxRequestBytes},
+			},
+		},
+	}, nil
+}
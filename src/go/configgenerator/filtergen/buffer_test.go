@@ -0,0 +1,157 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var bufferTestServiceConfig = &servicepb.Service{
+	Apis: []*apipb.Api{
+		{
+			Name: "bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "CreateShelf",
+				},
+				{
+					Name: "UploadBook",
+				},
+				{
+					Name:             "StreamBooks",
+					RequestStreaming: true,
+				},
+			},
+		},
+	},
+}
+
+func TestNewBufferFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with global limit",
+			ServiceConfigIn: bufferTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestBytes: 1024,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.buffer",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.buffer.v3.Buffer",
+      "maxRequestBytes":1024
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op when request body size is not limited",
+			ServiceConfigIn: bufferTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewBufferFilterGensFromOPConfig)
+	}
+}
+
+func TestNewBufferFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Invalid per operation limit",
+			ServiceConfigIn: bufferTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationMaxRequestBytes: "bookstore.Bookstore.UploadBook=1MB",
+			},
+			WantFactoryError: `invalid flag --operation_max_request_bytes for operation "bookstore.Bookstore.UploadBook": "1MB" is not a valid number of bytes`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewBufferFilterGensFromOPConfig)
+	}
+}
+
+func TestBufferGenerator_GenPerRouteConfig(t *testing.T) {
+	testdata := []struct {
+		desc                     string
+		maxRequestBytes          int
+		operationMaxRequestBytes string
+		wantPerRouteConfigs      map[string]string
+	}{
+		{
+			desc:                     "Per operation limits override the global limit",
+			maxRequestBytes:          1024,
+			operationMaxRequestBytes: "bookstore.Bookstore.UploadBook=1048576;bookstore.Bookstore.Create*=0",
+			wantPerRouteConfigs: map[string]string{
+				"bookstore.Bookstore.UploadBook":  `{"buffer":{"maxRequestBytes":1048576}}`,
+				"bookstore.Bookstore.CreateShelf": `{"disabled":true}`,
+				"bookstore.Bookstore.StreamBooks": `{"disabled":true}`,
+				"ESPv2_Autogenerated_CORS_Root":   ``,
+			},
+		},
+		{
+			desc:                     "Limit only for some operations",
+			operationMaxRequestBytes: "bookstore.Bookstore.UploadBook=1048576",
+			wantPerRouteConfigs: map[string]string{
+				"bookstore.Bookstore.UploadBook":  `{"buffer":{"maxRequestBytes":1048576}}`,
+				"bookstore.Bookstore.CreateShelf": `{"disabled":true}`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.MaxRequestBytes = tc.maxRequestBytes
+			opts.OperationMaxRequestBytes = tc.operationMaxRequestBytes
+			gens, err := filtergen.NewBufferFilterGensFromOPConfig(bufferTestServiceConfig, opts)
+			if err != nil {
+				t.Fatalf("NewBufferFilterGensFromOPConfig() got error: %v", err)
+			}
+
+			for selector, want := range tc.wantPerRouteConfigs {
+				got, err := gens[0].GenPerRouteConfig(selector, nil)
+				if err != nil {
+					t.Fatalf("GenPerRouteConfig(%q) got error: %v", selector, err)
+				}
+				if want == "" {
+					if got != nil {
+						t.Errorf("GenPerRouteConfig(%q) got %v, want nil", selector, got)
+					}
+					continue
+				}
+
+				gotJson, err := util.ProtoToJson(got)
+				if err != nil {
+					t.Fatalf("GenPerRouteConfig(%q) got invalid config: %v", selector, err)
+				}
+				if err := util.JsonEqual(want, gotJson); err != nil {
+					t.Errorf("GenPerRouteConfig(%q) got unexpected config: %v", selector, err)
+				}
+			}
+		})
+	}
+}
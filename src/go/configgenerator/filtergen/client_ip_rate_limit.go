@@ -0,0 +1,82 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	ciprlpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/client_ip_rate_limit"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ClientIPRateLimitFilterName is the Envoy filter name for debug logging.
+	ClientIPRateLimitFilterName = "com.google.espv2.filters.http.client_ip_rate_limit"
+)
+
+// ClientIPRateLimitGenerator limits the requests of each client IP with a
+// token bucket, before the requests are authenticated.
+//
+// Envoy local rate limit filter cannot be used since its descriptors only
+// match the fixed client IPs.
+type ClientIPRateLimitGenerator struct {
+	TokensPerSecond uint32
+	MaxTokens       uint32
+
+	NoopFilterGenerator
+}
+
+// NewClientIPRateLimitFilterGensFromOPConfig creates a ClientIPRateLimitGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewClientIPRateLimitFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.ClientIPRateLimitBurst < 0 {
+		return nil, fmt.Errorf("invalid flag --client_ip_rate_limit_burst %d, must be >= 0", opts.ClientIPRateLimitBurst)
+	}
+	if opts.ClientIPRateLimitPerSecond <= 0 {
+		if opts.ClientIPRateLimitPerSecond < 0 {
+			return nil, fmt.Errorf("invalid flag --client_ip_rate_limit_per_second %d, must be >= 0", opts.ClientIPRateLimitPerSecond)
+		}
+		if opts.ClientIPRateLimitBurst != 0 {
+			return nil, fmt.Errorf("invalid flag --client_ip_rate_limit_burst, it requires flag --client_ip_rate_limit_per_second")
+		}
+		glog.Info("Not adding client IP rate limit filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&ClientIPRateLimitGenerator{
+			TokensPerSecond: uint32(opts.ClientIPRateLimitPerSecond),
+			MaxTokens:       uint32(opts.ClientIPRateLimitBurst),
+		},
+	}, nil
+}
+
+func (g *ClientIPRateLimitGenerator) FilterName() string {
+	return ClientIPRateLimitFilterName
+}
+
+func (g *ClientIPRateLimitGenerator) GenFilterConfig() (proto.Message, error) {
+	return &ciprlpb.FilterConfig{
+		TokensPerSecond: g.TokensPerSecond,
+		MaxTokens:       g.MaxTokens,
+	}, nil
+}
+
+func (g *ClientIPRateLimitGenerator) CustomEnvoyFeatures() []string {
+	return []string{fmt.Sprintf("rate limit of %d requests per second for each client IP (--client_ip_rate_limit_per_second)", g.TokensPerSecond)}
+}
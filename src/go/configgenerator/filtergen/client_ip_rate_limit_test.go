@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewClientIPRateLimitFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:   "Disabled by default",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Burst defaults to the rate",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClientIPRateLimitPerSecond: 10,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.client_ip_rate_limit",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.client_ip_rate_limit.FilterConfig",
+      "tokensPerSecond":10
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Rate and burst",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClientIPRateLimitPerSecond: 5,
+				ClientIPRateLimitBurst:     20,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.client_ip_rate_limit",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.client_ip_rate_limit.FilterConfig",
+      "tokensPerSecond":5,
+      "maxTokens":20
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewClientIPRateLimitFilterGensFromOPConfig)
+	}
+}
+
+func TestNewClientIPRateLimitFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Negative rate",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClientIPRateLimitPerSecond: -1,
+			},
+			WantFactoryError: "invalid flag --client_ip_rate_limit_per_second -1, must be >= 0",
+		},
+		{
+			Desc: "Negative burst",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClientIPRateLimitPerSecond: 1,
+				ClientIPRateLimitBurst:     -1,
+			},
+			WantFactoryError: "invalid flag --client_ip_rate_limit_burst -1, must be >= 0",
+		},
+		{
+			Desc: "Burst requires the rate",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClientIPRateLimitBurst: 10,
+			},
+			WantFactoryError: "invalid flag --client_ip_rate_limit_burst, it requires flag --client_ip_rate_limit_per_second",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewClientIPRateLimitFilterGensFromOPConfig)
+	}
+}
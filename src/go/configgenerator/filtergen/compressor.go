@@ -16,6 +16,7 @@ package filtergen
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -26,6 +27,7 @@ import (
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type CompressorType int
@@ -49,6 +51,12 @@ const (
 type CompressorGenerator struct {
 	compressorType CompressorType
 
+	// ESPv2 options
+	minContentLength uint32
+	contentTypes     []string
+	gzipLevel        int
+	brotliQuality    int
+
 	NoopFilterGenerator
 }
 
@@ -60,14 +68,42 @@ func NewCompressorFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts
 		return nil, nil
 	}
 
-	return []FilterGenerator{
-		&CompressorGenerator{
-			compressorType: GzipCompressor,
-		},
-		&CompressorGenerator{
-			compressorType: BrotliCompressor,
-		},
-	}, nil
+	if opts.ResponseCompressionMinLength < 0 {
+		return nil, fmt.Errorf("invalid flag --response_compression_min_length %d, must not be negative", opts.ResponseCompressionMinLength)
+	}
+	if opts.ResponseCompressionGzipLevel < 0 || opts.ResponseCompressionGzipLevel > 9 {
+		return nil, fmt.Errorf("invalid flag --response_compression_gzip_level %d, must be in [1, 9]", opts.ResponseCompressionGzipLevel)
+	}
+	if opts.ResponseCompressionBrotliQuality > 11 {
+		return nil, fmt.Errorf("invalid flag --response_compression_brotli_quality %d, must be in [0, 11]", opts.ResponseCompressionBrotliQuality)
+	}
+
+	var contentTypes []string
+	for _, contentType := range strings.Split(opts.ResponseCompressionContentTypes, ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			contentTypes = append(contentTypes, contentType)
+		}
+	}
+
+	var gens []FilterGenerator
+	for _, compressionType := range strings.Split(opts.ResponseCompressionTypes, ",") {
+		gen := &CompressorGenerator{
+			minContentLength: uint32(opts.ResponseCompressionMinLength),
+			contentTypes:     contentTypes,
+			gzipLevel:        opts.ResponseCompressionGzipLevel,
+			brotliQuality:    opts.ResponseCompressionBrotliQuality,
+		}
+		switch strings.TrimSpace(compressionType) {
+		case "gzip":
+			gen.compressorType = GzipCompressor
+		case "br":
+			gen.compressorType = BrotliCompressor
+		default:
+			return nil, fmt.Errorf("invalid flag --response_compression_types, unknown compression type %q", compressionType)
+		}
+		gens = append(gens, gen)
+	}
+	return gens, nil
 }
 
 func (g *CompressorGenerator) FilterName() string {
@@ -83,20 +119,38 @@ func (g *CompressorGenerator) GenFilterConfig() (proto.Message, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling %s Compressor config to Any: %v", name, err)
 	}
-	return &comppb.Compressor{
+	compressor := &comppb.Compressor{
 		CompressorLibrary: &corepb.TypedExtensionConfig{
 			Name:        name,
 			TypedConfig: ca,
 		},
-	}, nil
+	}
+	if g.minContentLength > 0 || len(g.contentTypes) > 0 {
+		commonConfig := &comppb.Compressor_CommonDirectionConfig{
+			ContentType: g.contentTypes,
+		}
+		if g.minContentLength > 0 {
+			commonConfig.MinContentLength = &wrapperspb.UInt32Value{Value: g.minContentLength}
+		}
+		compressor.ResponseDirectionConfig = &comppb.Compressor_ResponseDirectionConfig{
+			CommonConfig: commonConfig,
+		}
+	}
+	return compressor, nil
 }
 
 func (g *CompressorGenerator) getCompressorConfig() (proto.Message, string, error) {
 	switch g.compressorType {
 	case GzipCompressor:
-		return &gzippb.Gzip{}, EnvoyGzipCompressorName, nil
+		return &gzippb.Gzip{
+			CompressionLevel: gzippb.Gzip_CompressionLevel(g.gzipLevel),
+		}, EnvoyGzipCompressorName, nil
 	case BrotliCompressor:
-		return &brpb.Brotli{}, EnvoyBrotliCompressorName, nil
+		cfg := &brpb.Brotli{}
+		if g.brotliQuality >= 0 {
+			cfg.Quality = &wrapperspb.UInt32Value{Value: uint32(g.brotliQuality)}
+		}
+		return cfg, EnvoyBrotliCompressorName, nil
 	}
 	return nil, "", fmt.Errorf("unknown compressor type: %v", g.compressorType)
 }
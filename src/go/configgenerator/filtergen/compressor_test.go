@@ -57,6 +57,61 @@ func TestNewCompressorFilterGensFromOPConfig_GenConfig(t *testing.T) {
       }
    }
 }
+`,
+			},
+		},
+		{
+			Desc: "Generate with compression options",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression:        true,
+				ResponseCompressionTypes:         "br, gzip",
+				ResponseCompressionContentTypes:  "application/json, text/html",
+				ResponseCompressionMinLength:     1024,
+				ResponseCompressionGzipLevel:     9,
+				ResponseCompressionBrotliQuality: 5,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.compressor",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.compressor.v3.Compressor",
+      "compressorLibrary":{
+         "name":"envoy.compression.brotli.compressor",
+         "typedConfig":{
+            "@type":"type.googleapis.com/envoy.extensions.compression.brotli.compressor.v3.Brotli",
+            "quality":5
+         }
+      },
+      "responseDirectionConfig":{
+         "commonConfig":{
+            "contentType":["application/json","text/html"],
+            "minContentLength":1024
+         }
+      }
+   }
+}
+`,
+				`
+{
+   "name":"envoy.filters.http.compressor",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.compressor.v3.Compressor",
+      "compressorLibrary":{
+         "name":"envoy.compression.gzip.compressor",
+         "typedConfig":{
+            "@type":"type.googleapis.com/envoy.extensions.compression.gzip.compressor.v3.Gzip",
+            "compressionLevel":"COMPRESSION_LEVEL_9"
+         }
+      },
+      "responseDirectionConfig":{
+         "commonConfig":{
+            "contentType":["application/json","text/html"],
+            "minContentLength":1024
+         }
+      }
+   }
+}
 `,
 			},
 		},
@@ -73,3 +128,28 @@ func TestNewCompressorFilterGensFromOPConfig_GenConfig(t *testing.T) {
 		tc.RunTest(t, filtergen.NewCompressorFilterGensFromOPConfig)
 	}
 }
+
+func TestNewCompressorFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Unknown compression type",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression: true,
+				ResponseCompressionTypes:  "gzip,deflate",
+			},
+			WantFactoryError: `invalid flag --response_compression_types, unknown compression type "deflate"`,
+		},
+		{
+			Desc: "Invalid gzip compression level",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression:    true,
+				ResponseCompressionGzipLevel: 10,
+			},
+			WantFactoryError: "invalid flag --response_compression_gzip_level 10",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewCompressorFilterGensFromOPConfig)
+	}
+}
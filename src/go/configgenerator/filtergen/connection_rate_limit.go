@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	localratelimitpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/local_ratelimit/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// ConnectionRateLimitFilterName is the Envoy network filter limiting the
+	// rate of the new downstream connections.
+	ConnectionRateLimitFilterName = "envoy.filters.network.local_ratelimit"
+
+	connectionRateLimitStatPrefix = "ingress_connection_rate_limit"
+)
+
+// MakeConnectionRateLimitNetworkFilter creates the network filter closing the
+// new connections of the ingress listener beyond flag
+// --listener_connection_rate_limit per second, nil if it is not set.
+func MakeConnectionRateLimitNetworkFilter(opts options.ConfigGeneratorOptions) (*listenerpb.Filter, error) {
+	if opts.ListenerConnectionRateLimit == 0 {
+		return nil, nil
+	}
+	if opts.ListenerConnectionRateLimit < 0 {
+		return nil, fmt.Errorf("invalid flag --listener_connection_rate_limit %d, must be >= 0", opts.ListenerConnectionRateLimit)
+	}
+
+	return FilterConfigToNetworkFilter(&localratelimitpb.LocalRateLimit{
+		StatPrefix: connectionRateLimitStatPrefix,
+		TokenBucket: &typepb.TokenBucket{
+			MaxTokens:     uint32(opts.ListenerConnectionRateLimit),
+			TokensPerFill: wrapperspb.UInt32(uint32(opts.ListenerConnectionRateLimit)),
+			FillInterval:  durationpb.New(time.Second),
+		},
+	}, ConnectionRateLimitFilterName)
+}
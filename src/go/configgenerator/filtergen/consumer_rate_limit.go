@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitpb "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	localratelimitpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/local_ratelimit/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// ConsumerRateLimitFilterName is the Envoy filter name for debug logging.
+	ConsumerRateLimitFilterName = "envoy.filters.http.local_ratelimit"
+
+	// ConsumerNumberDescriptorKey and QuotaMetricDescriptorKey are the keys of
+	// the rate limit descriptor entries generated by the route rate limits.
+	ConsumerNumberDescriptorKey = "consumer_number"
+	QuotaMetricDescriptorKey    = "quota_metric"
+
+	consumerRateLimitStatPrefix = "consumer_rate_limit"
+)
+
+// ConsumerRateLimitGenerator enforces the quota limits of the API consumers
+// at the edge. The routes generate the rate limit descriptors of the consumer
+// number set by the Service Control filter and the quota metrics consumed by
+// the operations. It runs behind the Service Control filter.
+type ConsumerRateLimitGenerator struct {
+	Limits []options.ConsumerQuotaLimit
+
+	NoopFilterGenerator
+}
+
+// NewConsumerRateLimitFilterGensFromOPConfig creates a ConsumerRateLimitGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewConsumerRateLimitFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if len(opts.ConsumerQuotaLimits) == 0 {
+		glog.Info("Not adding consumer rate limit filter gen because there is no consumer quota limit.")
+		return nil, nil
+	}
+
+	for _, limit := range opts.ConsumerQuotaLimits {
+		if limit.Limit <= 0 || limit.Interval < time.Second || limit.Interval%time.Second != 0 {
+			return nil, fmt.Errorf("invalid quota limit %d per %v of metric %q for consumer %s, the limit must be positive and the interval must be whole seconds", limit.Limit, limit.Interval, limit.Metric, limit.ConsumerNumber)
+		}
+	}
+
+	return []FilterGenerator{
+		&ConsumerRateLimitGenerator{
+			Limits: opts.ConsumerQuotaLimits,
+		},
+	}, nil
+}
+
+func (g *ConsumerRateLimitGenerator) FilterName() string {
+	return ConsumerRateLimitFilterName
+}
+
+func (g *ConsumerRateLimitGenerator) GenFilterConfig() (proto.Message, error) {
+	limits := append([]options.ConsumerQuotaLimit{}, g.Limits...)
+	sort.SliceStable(limits, func(i, j int) bool {
+		if limits[i].ConsumerNumber != limits[j].ConsumerNumber {
+			return limits[i].ConsumerNumber < limits[j].ConsumerNumber
+		}
+		return limits[i].Metric < limits[j].Metric
+	})
+
+	var descriptors []*ratelimitpb.LocalRateLimitDescriptor
+	for _, limit := range limits {
+		descriptors = append(descriptors, &ratelimitpb.LocalRateLimitDescriptor{
+			Entries: []*ratelimitpb.RateLimitDescriptor_Entry{
+				{
+					Key:   ConsumerNumberDescriptorKey,
+					Value: limit.ConsumerNumber,
+				},
+				{
+					Key:   QuotaMetricDescriptorKey,
+					Value: limit.Metric,
+				},
+			},
+			TokenBucket: makeTokenBucket(limit.Limit, limit.Interval),
+		})
+	}
+
+	return &localratelimitpb.LocalRateLimit{
+		StatPrefix: consumerRateLimitStatPrefix,
+		// The requests without a descriptor are not limited. The intervals of
+		// the descriptors must be multiples of the interval of this bucket.
+		TokenBucket: makeTokenBucket(math.MaxUint32, time.Second),
+		FilterEnabled: &corepb.RuntimeFractionalPercent{
+			DefaultValue: &typepb.FractionalPercent{
+				Numerator:   100,
+				Denominator: typepb.FractionalPercent_HUNDRED,
+			},
+			RuntimeKey: consumerRateLimitStatPrefix + "_enabled",
+		},
+		FilterEnforced: &corepb.RuntimeFractionalPercent{
+			DefaultValue: &typepb.FractionalPercent{
+				Numerator:   100,
+				Denominator: typepb.FractionalPercent_HUNDRED,
+			},
+			RuntimeKey: consumerRateLimitStatPrefix + "_enforced",
+		},
+		Descriptors: descriptors,
+	}, nil
+}
+
+// ConsumerNumberHeaderFromOPConfig returns the request header holding the
+// project number of the API consumer set by the Service Control filter.
+func ConsumerNumberHeaderFromOPConfig(opts options.ConfigGeneratorOptions) string {
+	return opts.GeneratedHeaderPrefix + consumerNumberHeaderSuffix
+}
+
+func makeTokenBucket(tokens int64, interval time.Duration) *typepb.TokenBucket {
+	if tokens > math.MaxUint32 {
+		tokens = math.MaxUint32
+	}
+	return &typepb.TokenBucket{
+		MaxTokens:     uint32(tokens),
+		TokensPerFill: wrapperspb.UInt32(uint32(tokens)),
+		FillInterval:  durationpb.New(interval),
+	}
+}
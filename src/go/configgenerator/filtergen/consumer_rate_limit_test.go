@@ -0,0 +1,143 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewConsumerRateLimitFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:   "Not added without consumer quota limits",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc: "Generate descriptors sorted by consumer and metric",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerQuotaLimits: []options.ConsumerQuotaLimit{
+					{
+						ConsumerNumber: "987654",
+						Metric:         "bookstore.googleapis.com/read_requests",
+						Limit:          100000,
+						Interval:       24 * time.Hour,
+					},
+					{
+						ConsumerNumber: "123456",
+						Metric:         "bookstore.googleapis.com/read_requests",
+						Limit:          600,
+						Interval:       time.Minute,
+					},
+				},
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.local_ratelimit",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+      "statPrefix":"consumer_rate_limit",
+      "tokenBucket":{
+         "maxTokens":4294967295,
+         "tokensPerFill":4294967295,
+         "fillInterval":"1s"
+      },
+      "filterEnabled":{
+         "defaultValue":{
+            "numerator":100
+         },
+         "runtimeKey":"consumer_rate_limit_enabled"
+      },
+      "filterEnforced":{
+         "defaultValue":{
+            "numerator":100
+         },
+         "runtimeKey":"consumer_rate_limit_enforced"
+      },
+      "descriptors":[
+         {
+            "entries":[
+               {
+                  "key":"consumer_number",
+                  "value":"123456"
+               },
+               {
+                  "key":"quota_metric",
+                  "value":"bookstore.googleapis.com/read_requests"
+               }
+            ],
+            "tokenBucket":{
+               "maxTokens":600,
+               "tokensPerFill":600,
+               "fillInterval":"60s"
+            }
+         },
+         {
+            "entries":[
+               {
+                  "key":"consumer_number",
+                  "value":"987654"
+               },
+               {
+                  "key":"quota_metric",
+                  "value":"bookstore.googleapis.com/read_requests"
+               }
+            ],
+            "tokenBucket":{
+               "maxTokens":100000,
+               "tokensPerFill":100000,
+               "fillInterval":"86400s"
+            }
+         }
+      ]
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewConsumerRateLimitFilterGensFromOPConfig)
+	}
+}
+
+func TestNewConsumerRateLimitFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Interval is not whole seconds",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerQuotaLimits: []options.ConsumerQuotaLimit{
+					{
+						ConsumerNumber: "123456",
+						Metric:         "bookstore.googleapis.com/read_requests",
+						Limit:          10,
+						Interval:       500 * time.Millisecond,
+					},
+				},
+			},
+			WantFactoryError: `invalid quota limit 10 per 500ms of metric "bookstore.googleapis.com/read_requests" for consumer 123456`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewConsumerRateLimitFilterGensFromOPConfig)
+	}
+}
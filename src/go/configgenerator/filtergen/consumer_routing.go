@@ -0,0 +1,87 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ConsumerRoutingFilterName is the Envoy filter name for debug logging.
+	ConsumerRoutingFilterName = "com.google.espv2.filters.http.consumer_routing"
+
+	// ConsumerMetadataNamespace is the dynamic metadata namespace holding the
+	// API consumer resolved by the Service Control filter, matched by the
+	// routes to the consumer backends.
+	ConsumerMetadataNamespace = "com.google.espv2.consumer"
+
+	// ConsumerNumberMetadataKey is the dynamic metadata key of the project
+	// number of the API consumer.
+	ConsumerNumberMetadataKey = "consumer_number"
+)
+
+// ConsumerRoutingGenerator copies the consumer number set by the Service
+// Control filter into the dynamic metadata, so the routes to the consumer
+// backends are matched by it. It runs right before the router.
+type ConsumerRoutingGenerator struct {
+	ConsumerNumberHeader string
+
+	NoopFilterGenerator
+}
+
+// NewConsumerRoutingFilterGensFromOPConfig creates a ConsumerRoutingGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewConsumerRoutingFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	backends, err := clustergen.ParseConsumerBackendsFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if backends == nil {
+		glog.Info("Not adding consumer routing filter gen because there is no consumer backend.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&ConsumerRoutingGenerator{
+			ConsumerNumberHeader: opts.GeneratedHeaderPrefix + consumerNumberHeaderSuffix,
+		},
+	}, nil
+}
+
+func (g *ConsumerRoutingGenerator) FilterName() string {
+	return ConsumerRoutingFilterName
+}
+
+func (g *ConsumerRoutingGenerator) GenFilterConfig() (proto.Message, error) {
+	script := fmt.Sprintf(`function envoy_on_request(request_handle)
+  local consumer = request_handle:headers():get(%s)
+  if consumer ~= nil then
+    request_handle:streamInfo():dynamicMetadata():set(%s, %s, consumer)
+    request_handle:clearRouteCache()
+  end
+end
+`,
+		luaQuote(strings.ToLower(g.ConsumerNumberHeader)),
+		luaQuote(ConsumerMetadataNamespace),
+		luaQuote(ConsumerNumberMetadataKey))
+	return makeInlineLua(script), nil
+}
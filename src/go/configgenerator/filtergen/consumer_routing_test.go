@@ -0,0 +1,124 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	luapb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	"github.com/google/go-cmp/cmp"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+var consumerRoutingTestOpts = options.ConfigGeneratorOptions{
+	CommonOptions: options.CommonOptions{
+		GeneratedHeaderPrefix: "X-Endpoint-",
+	},
+	ConsumerBackends: `[{"name": "bigcorp", "backendAddress": "https://bigcorp.example.com", "consumerNumbers": ["123"]}]`,
+}
+
+func TestConsumerRoutingFilterGens_GenFilterConfig(t *testing.T) {
+	testData := []struct {
+		desc       string
+		factory    filtergen.FilterGeneratorOPFactory
+		wantName   string
+		wantScript string
+	}{
+		{
+			desc:     "Sanitizer removes the client-supplied headers",
+			factory:  filtergen.NewTenantSanitizerFilterGensFromOPConfig,
+			wantName: "com.google.espv2.filters.http.tenant_sanitizer",
+			wantScript: `function envoy_on_request(request_handle)
+  request_handle:headers():remove("x-endpoint-api-consumer-number")
+  request_handle:headers():remove("x-espv2-tenant-cluster")
+end
+`,
+		},
+		{
+			desc:     "Routing copies the consumer number into the dynamic metadata",
+			factory:  filtergen.NewConsumerRoutingFilterGensFromOPConfig,
+			wantName: "com.google.espv2.filters.http.consumer_routing",
+			wantScript: `function envoy_on_request(request_handle)
+  local consumer = request_handle:headers():get("x-endpoint-api-consumer-number")
+  if consumer ~= nil then
+    request_handle:streamInfo():dynamicMetadata():set("com.google.espv2.consumer", "consumer_number", consumer)
+    request_handle:clearRouteCache()
+  end
+end
+`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			gens, err := tc.factory(&servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			}, consumerRoutingTestOpts)
+			if err != nil {
+				t.Fatalf("factory got error: %v", err)
+			}
+			if len(gens) != 1 {
+				t.Fatalf("want 1 filter gen, got %v", len(gens))
+			}
+			if got := gens[0].FilterName(); got != tc.wantName {
+				t.Errorf("want filter name %q, got %q", tc.wantName, got)
+			}
+
+			config, err := gens[0].GenFilterConfig()
+			if err != nil {
+				t.Fatalf("GenFilterConfig() got error: %v", err)
+			}
+			gotScript := config.(*luapb.Lua).GetDefaultSourceCode().GetInlineString()
+			if diff := cmp.Diff(tc.wantScript, gotScript); diff != "" {
+				t.Errorf("GenFilterConfig() script diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConsumerRoutingFilterGens_Disabled(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:   "No-op without consumer backends",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewConsumerRoutingFilterGensFromOPConfig)
+	}
+}
+
+func TestNewConsumerRoutingFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Consumer number mapped to two backends",
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `[
+  {"name": "a", "backendAddress": "https://a.example.com", "consumerNumbers": ["123"]},
+  {"name": "b", "backendAddress": "https://b.example.com", "consumerNumbers": ["123"]}
+]`,
+			},
+			WantFactoryError: `invalid flag --consumer_backends, consumer number "123" is mapped to both "a" and "b"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewConsumerRoutingFilterGensFromOPConfig)
+	}
+}
@@ -17,11 +17,16 @@ package filtergen
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	corspb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
 	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	envoytypepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/proto"
@@ -31,12 +36,18 @@ import (
 const (
 	// CORSFilterName is the Envoy filter name for debug logging.
 	CORSFilterName = "envoy.filters.http.cors"
+
+	// grpcWebAllowHeaders are the request headers sent by gRPC-Web clients.
+	grpcWebAllowHeaders = "X-Grpc-Web,X-User-Agent,Grpc-Timeout,Content-Type"
+	// grpcWebExposeHeaders are the response headers read by gRPC-Web clients.
+	grpcWebExposeHeaders = "Grpc-Status,Grpc-Message,Grpc-Status-Details-Bin"
 )
 
 // CORSGenerator is a FilterGenerator to configure CORS config.
 type CORSGenerator struct {
 	Preset string
-	// AllowOrigin should only be set if preset=basic
+	// AllowOrigin should only be set if preset=basic. It is a comma separated
+	// list of origins.
 	AllowOrigin string
 	// AllowOriginRegex should only be set if preset=cors_with_regex
 	AllowOriginRegex string
@@ -46,31 +57,178 @@ type CORSGenerator struct {
 	ExposeHeaders    string
 	AllowCredentials bool
 
+	// OverridesBySelector overrides the policy per operation.
+	OverridesBySelector map[string]*CORSOperationOverrides
+
+	// UnmatchedPreflightOnly is set if the backend handles CORS, the filter is
+	// disabled for all the operations and only answers the preflight requests
+	// of the paths not in the service config.
+	UnmatchedPreflightOnly bool
+
 	NoopFilterGenerator
 }
 
+// CORSOperationOverrides overrides the CORS policy of an operation. The nil
+// fields are inherited from the global policy.
+type CORSOperationOverrides struct {
+	// AllowOrigins are the exact origins allowed, "*" allows any origin.
+	AllowOrigins     []string
+	AllowCredentials *bool
+	ExposeHeaders    *string
+}
+
 // NewCORSFilterGensFromOPConfig creates a CORSGenerator from
 // OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
 func NewCORSFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
-	if opts.CorsPreset == "" {
+	preset := CORSPresetFromOPConfig(serviceConfig, opts)
+	if preset == "" {
+		if opts.CorsAllowUnmatchedPreflight {
+			return nil, fmt.Errorf("flag --cors_allow_unmatched_preflight requires flag --cors_preset or allow_cors in the service config endpoints")
+		}
+		if opts.OperationCorsAllowOrigin != "" || opts.OperationCorsAllowCredentials != "" || opts.OperationCorsExposeHeaders != "" {
+			return nil, fmt.Errorf("flags --operation_cors_allow_origin, --operation_cors_allow_credentials and --operation_cors_expose_headers require flag --cors_preset")
+		}
 		glog.Infof("Not adding CORS filter gen because the feature is disabled by option, option is currently %q", opts.CorsPreset)
 		return nil, nil
 	}
 
+	overridesBySelector, err := ParseCORSOperationOverridesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	unmatchedPreflightOnly := IsCORSUnmatchedPreflightOnlyForOPConfig(serviceConfig, opts)
+	if unmatchedPreflightOnly && len(overridesBySelector) > 0 {
+		return nil, fmt.Errorf("per-operation CORS flags cannot be used when the backend handles CORS with flag --cors_allow_unmatched_preflight")
+	}
+
+	allowHeaders, exposeHeaders := opts.CorsAllowHeaders, opts.CorsExposeHeaders
+	if opts.EnableGrpcWeb {
+		allowHeaders = mergeHeaderLists(allowHeaders, grpcWebAllowHeaders)
+		exposeHeaders = mergeHeaderLists(exposeHeaders, grpcWebExposeHeaders)
+	}
+
 	return []FilterGenerator{
 		&CORSGenerator{
-			Preset:           opts.CorsPreset,
+			Preset:           preset,
 			AllowOrigin:      opts.CorsAllowOrigin,
 			AllowOriginRegex: opts.CorsAllowOriginRegex,
 			MaxAge:           opts.CorsMaxAge,
 			AllowMethods:     opts.CorsAllowMethods,
-			AllowHeaders:     opts.CorsAllowHeaders,
-			ExposeHeaders:    opts.CorsExposeHeaders,
+			AllowHeaders:     allowHeaders,
+			ExposeHeaders:    exposeHeaders,
 			AllowCredentials: opts.CorsAllowCredentials,
+
+			OverridesBySelector:    overridesBySelector,
+			UnmatchedPreflightOnly: unmatchedPreflightOnly,
 		},
 	}, nil
 }
 
+// ParseCORSOperationOverridesFromOPConfig parses the per-operation overrides
+// of the CORS policy. The operations without overrides are not in the map.
+func ParseCORSOperationOverridesFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]*CORSOperationOverrides, error) {
+	opAllowOrigin, err := util.ParseSelectorMap(opts.OperationCorsAllowOrigin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_cors_allow_origin: %v", err)
+	}
+	opAllowCredentials, err := util.ParseSelectorMap(opts.OperationCorsAllowCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_cors_allow_credentials: %v", err)
+	}
+	opExposeHeaders, err := util.ParseSelectorMap(opts.OperationCorsExposeHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_cors_expose_headers: %v", err)
+	}
+
+	overridesBySelector := make(map[string]*CORSOperationOverrides)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			overrides := &CORSOperationOverrides{}
+			hasOverrides := false
+
+			if value, ok := opAllowOrigin.Lookup(selector); ok {
+				overrides.AllowOrigins = SplitCORSOrigins(value)
+				if len(overrides.AllowOrigins) == 0 {
+					return nil, fmt.Errorf("invalid flag --operation_cors_allow_origin for operation %q: origins cannot be empty", selector)
+				}
+				hasOverrides = true
+			}
+			if value, ok := opAllowCredentials.Lookup(selector); ok {
+				allowCredentials, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid flag --operation_cors_allow_credentials for operation %q: %q must be true or false", selector, value)
+				}
+				overrides.AllowCredentials = &allowCredentials
+				hasOverrides = true
+			}
+			if value, ok := opExposeHeaders.Lookup(selector); ok {
+				exposeHeaders := value
+				if opts.EnableGrpcWeb {
+					exposeHeaders = mergeHeaderLists(exposeHeaders, grpcWebExposeHeaders)
+				}
+				overrides.ExposeHeaders = &exposeHeaders
+				hasOverrides = true
+			}
+
+			if hasOverrides {
+				overridesBySelector[selector] = overrides
+			}
+		}
+	}
+	return overridesBySelector, nil
+}
+
+// SplitCORSOrigins splits the comma separated list of origins.
+func SplitCORSOrigins(origins string) []string {
+	var split []string
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			split = append(split, origin)
+		}
+	}
+	return split
+}
+
+// CORSPresetFromOPConfig returns the CORS preset in use. gRPC-Web clients in
+// browsers require CORS, so it defaults to "basic" when gRPC-Web is enabled.
+// It also defaults to "basic" to answer the unmatched preflight requests when
+// the backend handles CORS.
+func CORSPresetFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) string {
+	if opts.CorsPreset == "" && (opts.EnableGrpcWeb || IsCORSUnmatchedPreflightOnlyForOPConfig(serviceConfig, opts)) {
+		return "basic"
+	}
+	return opts.CorsPreset
+}
+
+// IsCORSUnmatchedPreflightOnlyForOPConfig returns whether the backend handles
+// CORS and ESPv2 only answers the preflight requests of the paths not in the
+// service config, which would be rejected with 404 otherwise.
+func IsCORSUnmatchedPreflightOnlyForOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) bool {
+	return opts.CorsAllowUnmatchedPreflight && opts.CorsPreset == "" && IsAutoGenCORSRequiredForOPConfig(serviceConfig, opts)
+}
+
+// mergeHeaderLists appends the headers in `extra` missing from the comma
+// separated header list.
+func mergeHeaderLists(headers, extra string) string {
+	existing := make(map[string]bool)
+	for _, h := range strings.Split(headers, ",") {
+		existing[strings.ToLower(strings.TrimSpace(h))] = true
+	}
+
+	merged := headers
+	for _, h := range strings.Split(extra, ",") {
+		if existing[strings.ToLower(h)] {
+			continue
+		}
+		if merged != "" {
+			merged += ","
+		}
+		merged += h
+	}
+	return merged
+}
+
 func (g *CORSGenerator) FilterName() string {
 	return CORSFilterName
 }
@@ -80,6 +238,33 @@ func (g *CORSGenerator) GenFilterConfig() (proto.Message, error) {
 }
 
 func (g *CORSGenerator) GenPerHostConfig(vHostName string) (proto.Message, error) {
+	return g.makeCorsPolicy(nil)
+}
+
+// GenPerRouteConfig overrides the policy for the operations with overrides.
+// The route policy replaces the virtual host policy as a whole, so it is a
+// copy of the global policy with the overrides applied.
+func (g *CORSGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if g.UnmatchedPreflightOnly {
+		// The CORS requests of the operations are proxied to the backend.
+		return &corspb.CorsPolicy{
+			FilterEnabled: &corepb.RuntimeFractionalPercent{
+				DefaultValue: &envoytypepb.FractionalPercent{
+					Numerator:   0,
+					Denominator: envoytypepb.FractionalPercent_HUNDRED,
+				},
+			},
+		}, nil
+	}
+
+	overrides, ok := g.OverridesBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+	return g.makeCorsPolicy(overrides)
+}
+
+func (g *CORSGenerator) makeCorsPolicy(overrides *CORSOperationOverrides) (*corspb.CorsPolicy, error) {
 	policy := &corspb.CorsPolicy{
 		MaxAge:        strconv.Itoa(int(g.MaxAge.Seconds())),
 		AllowMethods:  g.AllowMethods,
@@ -90,15 +275,22 @@ func (g *CORSGenerator) GenPerHostConfig(vHostName string) (proto.Message, error
 		},
 	}
 
+	if overrides != nil {
+		if overrides.AllowCredentials != nil {
+			policy.AllowCredentials.Value = *overrides.AllowCredentials
+		}
+		if overrides.ExposeHeaders != nil {
+			policy.ExposeHeaders = *overrides.ExposeHeaders
+		}
+		if overrides.AllowOrigins != nil {
+			policy.AllowOriginStringMatch = makeExactOriginMatchers(overrides.AllowOrigins)
+			return policy, nil
+		}
+	}
+
 	switch g.Preset {
 	case "basic":
-		policy.AllowOriginStringMatch = []*matcherpb.StringMatcher{
-			{
-				MatchPattern: &matcherpb.StringMatcher_Exact{
-					Exact: g.AllowOrigin,
-				},
-			},
-		}
+		policy.AllowOriginStringMatch = makeExactOriginMatchers(SplitCORSOrigins(g.AllowOrigin))
 
 	case "cors_with_regex":
 		policy.AllowOriginStringMatch = []*matcherpb.StringMatcher{
@@ -117,3 +309,15 @@ func (g *CORSGenerator) GenPerHostConfig(vHostName string) (proto.Message, error
 
 	return policy, nil
 }
+
+func makeExactOriginMatchers(origins []string) []*matcherpb.StringMatcher {
+	var matchers []*matcherpb.StringMatcher
+	for _, origin := range origins {
+		matchers = append(matchers, &matcherpb.StringMatcher{
+			MatchPattern: &matcherpb.StringMatcher_Exact{
+				Exact: origin,
+			},
+		})
+	}
+	return matchers
+}
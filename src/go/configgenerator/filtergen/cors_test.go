@@ -20,6 +20,9 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
 )
 
 func TestNewCORSFilterGensFromOPConfig_GenConfig(t *testing.T) {
@@ -69,3 +72,218 @@ func TestNewCORSFilterGensFromOPConfig_GenConfig(t *testing.T) {
 		tc.RunTest(t, filtergen.NewCORSFilterGensFromOPConfig)
 	}
 }
+
+func TestNewCORSFilterGensFromOPConfig_GRPCWeb(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.EnableGrpcWeb = true
+
+	gens, err := filtergen.NewCORSFilterGensFromOPConfig(nil, opts)
+	if err != nil {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	perHostConfig, err := gens[0].GenPerHostConfig("backend")
+	if err != nil {
+		t.Fatalf("GenPerHostConfig() got error: %v", err)
+	}
+	gotJson, err := util.ProtoToJson(perHostConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantJson := `
+{
+  "allowCredentials": false,
+  "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization,X-Grpc-Web,Grpc-Timeout",
+  "allowMethods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+  "allowOriginStringMatch": [
+    {
+      "exact": "*"
+    }
+  ],
+  "exposeHeaders": "Content-Length,Content-Range,Grpc-Status,Grpc-Message,Grpc-Status-Details-Bin",
+  "maxAge": "1728000"
+}`
+	if err := util.JsonEqual(wantJson, gotJson); err != nil {
+		t.Errorf("GenPerHostConfig() got unexpected CORS policy: %v", err)
+	}
+}
+
+func TestNewCORSFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Per operation overrides require a CORS preset",
+			ServiceConfigIn: corsTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationCorsAllowOrigin: "bookstore.Bookstore.ListShelves=https://example.com",
+			},
+			WantFactoryError: "require flag --cors_preset",
+		},
+		{
+			Desc:            "Unmatched preflight requires a CORS preset or allow_cors",
+			ServiceConfigIn: corsTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsAllowUnmatchedPreflight: true,
+			},
+			WantFactoryError: "flag --cors_allow_unmatched_preflight requires flag --cors_preset or allow_cors",
+		},
+		{
+			Desc:            "Invalid per operation credentials",
+			ServiceConfigIn: corsTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:                    "basic",
+				OperationCorsAllowCredentials: "bookstore.Bookstore.ListShelves=yes please",
+			},
+			WantFactoryError: `invalid flag --operation_cors_allow_credentials for operation "bookstore.Bookstore.ListShelves"`,
+		},
+		{
+			Desc:            "Empty per operation origins",
+			ServiceConfigIn: corsTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:               "basic",
+				OperationCorsAllowOrigin: "bookstore.Bookstore.ListShelves= , ",
+			},
+			WantFactoryError: "origins cannot be empty",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewCORSFilterGensFromOPConfig)
+	}
+}
+
+var corsTestServiceConfig = &servicepb.Service{
+	Apis: []*apipb.Api{
+		{
+			Name: "bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "ListShelves",
+				},
+				{
+					Name: "CreateShelf",
+				},
+				{
+					Name: "DeleteShelf",
+				},
+			},
+		},
+	},
+}
+
+func TestCORSGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.CorsPreset = "basic"
+	opts.CorsAllowOrigin = "https://a.example.com, https://b.example.com"
+	opts.CorsAllowCredentials = true
+	opts.OperationCorsAllowOrigin = "bookstore.Bookstore.ListShelves=*"
+	opts.OperationCorsAllowCredentials = "bookstore.Bookstore.ListShelves=false"
+	opts.OperationCorsExposeHeaders = "bookstore.Bookstore.CreateShelf=X-Shelf-Id"
+
+	gens, err := filtergen.NewCORSFilterGensFromOPConfig(corsTestServiceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	wantPerRouteConfigs := map[string]string{
+		"bookstore.Bookstore.ListShelves": `
+{
+  "allowCredentials": false,
+  "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization",
+  "allowMethods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+  "allowOriginStringMatch": [
+    {
+      "exact": "*"
+    }
+  ],
+  "exposeHeaders": "Content-Length,Content-Range",
+  "maxAge": "1728000"
+}`,
+		"bookstore.Bookstore.CreateShelf": `
+{
+  "allowCredentials": true,
+  "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization",
+  "allowMethods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+  "allowOriginStringMatch": [
+    {
+      "exact": "https://a.example.com"
+    },
+    {
+      "exact": "https://b.example.com"
+    }
+  ],
+  "exposeHeaders": "X-Shelf-Id",
+  "maxAge": "1728000"
+}`,
+		"bookstore.Bookstore.DeleteShelf": ``,
+	}
+
+	for selector, want := range wantPerRouteConfigs {
+		got, err := gens[0].GenPerRouteConfig(selector, nil)
+		if err != nil {
+			t.Fatalf("GenPerRouteConfig(%q) got error: %v", selector, err)
+		}
+		if want == "" {
+			if got != nil {
+				t.Errorf("GenPerRouteConfig(%q) got %v, want nil", selector, got)
+			}
+			continue
+		}
+
+		gotJson, err := util.ProtoToJson(got)
+		if err != nil {
+			t.Fatalf("GenPerRouteConfig(%q) got invalid config: %v", selector, err)
+		}
+		if err := util.JsonEqual(want, gotJson); err != nil {
+			t.Errorf("GenPerRouteConfig(%q) got unexpected config: %v", selector, err)
+		}
+	}
+}
+
+func TestCORSGenerator_GenPerRouteConfig_UnmatchedPreflightOnly(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Endpoints: []*servicepb.Endpoint{
+			{
+				Name:      "bookstore.endpoints.project123.cloud.goog",
+				AllowCors: true,
+			},
+		},
+		Apis: corsTestServiceConfig.Apis,
+	}
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.CorsAllowUnmatchedPreflight = true
+
+	gens, err := filtergen.NewCORSFilterGensFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	got, err := gens[0].GenPerRouteConfig("bookstore.Bookstore.ListShelves", nil)
+	if err != nil {
+		t.Fatalf("GenPerRouteConfig() got error: %v", err)
+	}
+	gotJson, err := util.ProtoToJson(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantJson := `
+{
+  "filterEnabled": {
+    "defaultValue": {}
+  }
+}`
+	if err := util.JsonEqual(wantJson, gotJson); err != nil {
+		t.Errorf("GenPerRouteConfig() got unexpected CORS policy: %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	xdspb "github.com/cncf/xds/go/xds/core/v3"
+	xdsmatcherpb "github.com/cncf/xds/go/xds/type/matcher/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	crpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/custom_response/v3"
+	lrppb "github.com/envoyproxy/go-control-plane/envoy/extensions/http/custom_response/local_response_policy/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// CustomResponseFilterName is the Envoy filter name for debug logging.
+	CustomResponseFilterName = "envoy.filters.http.custom_response"
+
+	localReplyBodyOperator = "%LOCAL_REPLY_BODY%"
+	grpcMessageOperator    = "%RESP(grpc-message)%"
+
+	// grpcContentTypePrefix also matches gRPC-Web content types.
+	grpcContentTypePrefix = "application/grpc"
+)
+
+// transcodedHTTPStatusCodes are the HTTP status codes the gRPC-JSON
+// transcoder responds with for the gRPC status codes, the same as
+// Grpc::Utility::grpcToHttpStatus in Envoy. Codes not listed are 500.
+var transcodedHTTPStatusCodes = map[codes.Code]uint32{
+	codes.Canceled:           499,
+	codes.InvalidArgument:    400,
+	codes.DeadlineExceeded:   504,
+	codes.NotFound:           404,
+	codes.AlreadyExists:      409,
+	codes.PermissionDenied:   403,
+	codes.ResourceExhausted:  429,
+	codes.FailedPrecondition: 400,
+	codes.Aborted:            409,
+	codes.OutOfRange:         400,
+	codes.Unimplemented:      501,
+	codes.Unavailable:        503,
+	codes.Unauthenticated:    401,
+}
+
+// TranscodedHTTPStatusCode returns the HTTP status code the gRPC-JSON
+// transcoder responds with for the gRPC status code.
+func TranscodedHTTPStatusCode(code codes.Code) uint32 {
+	if httpCode, ok := transcodedHTTPStatusCodes[code]; ok {
+		return httpCode
+	}
+	return 500
+}
+
+// CustomResponseGenerator overrides the HTTP status code and body of the
+// transcoded gRPC error responses.
+//
+// The gRPC status is not matched directly, as it is only in the trailers of
+// most responses, and the transcoder removes it from the headers of the
+// trailers-only ones. The HTTP status code the transcoder responds with is
+// matched instead.
+type CustomResponseGenerator struct {
+	// StatusCodes maps the HTTP status codes of the transcoded gRPC errors to
+	// the overriding HTTP status codes.
+	StatusCodes map[uint32]uint32
+	// BodyTemplate is the optional JSON body of the mapped responses.
+	BodyTemplate *structpb.Struct
+
+	NoopFilterGenerator
+}
+
+// NewCustomResponseFilterGensFromOPConfig creates a CustomResponseGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewCustomResponseFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.GrpcErrorStatusCodes == "" {
+		glog.Infof("No gRPC error status codes mapping, skip custom response filter completely.")
+		return nil, nil
+	}
+
+	isGRPCSupportRequired, err := IsGRPCSupportRequiredForOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !isGRPCSupportRequired {
+		return nil, fmt.Errorf("flag --grpc_error_status_codes requires a gRPC backend")
+	}
+
+	grpcStatusCodes, err := ParseGrpcErrorStatusCodes(opts.GrpcErrorStatusCodes)
+	if err != nil {
+		return nil, err
+	}
+	statusCodes, err := mapTranscodedHTTPStatusCodes(grpcStatusCodes)
+	if err != nil {
+		return nil, err
+	}
+	bodyTemplate, err := ParseErrorResponseTemplate(opts.ErrorResponseTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return []FilterGenerator{
+		&CustomResponseGenerator{
+			StatusCodes:  statusCodes,
+			BodyTemplate: bodyTemplate,
+		},
+	}, nil
+}
+
+// ParseGrpcErrorStatusCodes parses the mapping from gRPC status codes, by name
+// or by number, to HTTP status codes, such as "NOT_FOUND=404;14=503".
+func ParseGrpcErrorStatusCodes(mapping string) (map[codes.Code]uint32, error) {
+	statusCodes := make(map[codes.Code]uint32)
+	for _, entry := range strings.Split(mapping, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid flag --grpc_error_status_codes, %q is not in the format of grpc_code=http_code", entry)
+		}
+
+		grpcCode := strings.TrimSpace(kv[0])
+		if _, err := strconv.Atoi(grpcCode); err != nil {
+			grpcCode = strconv.Quote(strings.ToUpper(grpcCode))
+		}
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(grpcCode)); err != nil {
+			return nil, fmt.Errorf("invalid flag --grpc_error_status_codes, unknown gRPC status code %q", kv[0])
+		}
+		if code == codes.OK {
+			return nil, fmt.Errorf("invalid flag --grpc_error_status_codes, gRPC status code OK cannot be mapped")
+		}
+
+		httpCode, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || httpCode < 100 || httpCode >= 600 {
+			return nil, fmt.Errorf("invalid flag --grpc_error_status_codes, HTTP status code %q should be a number in [100, 600)", kv[1])
+		}
+		statusCodes[code] = uint32(httpCode)
+	}
+	return statusCodes, nil
+}
+
+// mapTranscodedHTTPStatusCodes converts the mapping of gRPC status codes to the
+// mapping of the HTTP status codes the transcoder responds with. The gRPC status
+// codes transcoded to the same HTTP status code cannot be mapped differently.
+func mapTranscodedHTTPStatusCodes(grpcStatusCodes map[codes.Code]uint32) (map[uint32]uint32, error) {
+	var grpcCodes []codes.Code
+	for code := range grpcStatusCodes {
+		grpcCodes = append(grpcCodes, code)
+	}
+	sort.Slice(grpcCodes, func(i, j int) bool { return grpcCodes[i] < grpcCodes[j] })
+
+	statusCodes := make(map[uint32]uint32)
+	mappedBy := make(map[uint32]codes.Code)
+	for _, code := range grpcCodes {
+		transcoded := TranscodedHTTPStatusCode(code)
+		if httpCode, ok := statusCodes[transcoded]; ok && httpCode != grpcStatusCodes[code] {
+			return nil, fmt.Errorf("invalid flag --grpc_error_status_codes, gRPC status codes %v and %v are both transcoded to HTTP status code %d, they cannot be mapped to different HTTP status codes", mappedBy[transcoded], code, transcoded)
+		}
+		statusCodes[transcoded] = grpcStatusCodes[code]
+		mappedBy[transcoded] = code
+	}
+	return statusCodes, nil
+}
+
+// ParseErrorResponseTemplate parses the JSON object used as the error response
+// body. It returns nil if the template is empty.
+func ParseErrorResponseTemplate(template string) (*structpb.Struct, error) {
+	if template == "" {
+		return nil, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(template), &fields); err != nil {
+		return nil, fmt.Errorf("invalid flag --error_response_template, it should be a JSON object: %v", err)
+	}
+	body, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --error_response_template: %v", err)
+	}
+	return body, nil
+}
+
+func (g *CustomResponseGenerator) FilterName() string {
+	return CustomResponseFilterName
+}
+
+func (g *CustomResponseGenerator) GenFilterConfig() (proto.Message, error) {
+	var transcodedCodes []uint32
+	for code := range g.StatusCodes {
+		transcodedCodes = append(transcodedCodes, code)
+	}
+	sort.Slice(transcodedCodes, func(i, j int) bool { return transcodedCodes[i] < transcodedCodes[j] })
+
+	var matchers []*xdsmatcherpb.Matcher_MatcherList_FieldMatcher
+	for _, code := range transcodedCodes {
+		action, err := g.makeLocalResponsePolicy(g.StatusCodes[code])
+		if err != nil {
+			return nil, err
+		}
+
+		statusCode, err := makeResponseStatusCodePredicate(code)
+		if err != nil {
+			return nil, err
+		}
+		// Responses to native gRPC clients keep their content type, only the
+		// transcoded responses are overridden.
+		grpcContentType, err := makeResponseHeaderPredicate("content-type", &xdsmatcherpb.StringMatcher{
+			MatchPattern: &xdsmatcherpb.StringMatcher_Prefix{Prefix: grpcContentTypePrefix},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		matchers = append(matchers, &xdsmatcherpb.Matcher_MatcherList_FieldMatcher{
+			Predicate: &xdsmatcherpb.Matcher_MatcherList_Predicate{
+				MatchType: &xdsmatcherpb.Matcher_MatcherList_Predicate_AndMatcher{
+					AndMatcher: &xdsmatcherpb.Matcher_MatcherList_Predicate_PredicateList{
+						Predicate: []*xdsmatcherpb.Matcher_MatcherList_Predicate{
+							statusCode,
+							{
+								MatchType: &xdsmatcherpb.Matcher_MatcherList_Predicate_NotMatcher{
+									NotMatcher: grpcContentType,
+								},
+							},
+						},
+					},
+				},
+			},
+			OnMatch: &xdsmatcherpb.Matcher_OnMatch{
+				OnMatch: &xdsmatcherpb.Matcher_OnMatch_Action{
+					Action: action,
+				},
+			},
+		})
+	}
+
+	return &crpb.CustomResponse{
+		CustomResponseMatcher: &xdsmatcherpb.Matcher{
+			MatcherType: &xdsmatcherpb.Matcher_MatcherList_{
+				MatcherList: &xdsmatcherpb.Matcher_MatcherList{
+					Matchers: matchers,
+				},
+			},
+		},
+	}, nil
+}
+
+func (g *CustomResponseGenerator) makeLocalResponsePolicy(httpCode uint32) (*xdspb.TypedExtensionConfig, error) {
+	policy := &lrppb.LocalResponsePolicy{
+		StatusCode: &wrapperspb.UInt32Value{Value: httpCode},
+	}
+	if g.BodyTemplate != nil {
+		// The error message of the gRPC response is not the local reply body.
+		// It is usually empty, as the message is sent in the trailers.
+		body, err := replaceStructStrings(g.BodyTemplate, localReplyBodyOperator, grpcMessageOperator)
+		if err != nil {
+			return nil, err
+		}
+		policy.BodyFormat = &corepb.SubstitutionFormatString{
+			Format: &corepb.SubstitutionFormatString_JsonFormat{
+				JsonFormat: body,
+			},
+		}
+	}
+
+	a, err := anypb.New(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &xdspb.TypedExtensionConfig{
+		Name:        fmt.Sprintf("grpc_error_%d", httpCode),
+		TypedConfig: a,
+	}, nil
+}
+
+func makeResponseStatusCodePredicate(code uint32) (*xdsmatcherpb.Matcher_MatcherList_Predicate, error) {
+	input, err := anypb.New(&matcherpb.HttpResponseStatusCodeMatchInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &xdsmatcherpb.Matcher_MatcherList_Predicate{
+		MatchType: &xdsmatcherpb.Matcher_MatcherList_Predicate_SinglePredicate_{
+			SinglePredicate: &xdsmatcherpb.Matcher_MatcherList_Predicate_SinglePredicate{
+				Input: &xdspb.TypedExtensionConfig{
+					Name:        "status-code",
+					TypedConfig: input,
+				},
+				Matcher: &xdsmatcherpb.Matcher_MatcherList_Predicate_SinglePredicate_ValueMatch{
+					ValueMatch: &xdsmatcherpb.StringMatcher{
+						MatchPattern: &xdsmatcherpb.StringMatcher_Exact{Exact: strconv.Itoa(int(code))},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func makeResponseHeaderPredicate(header string, valueMatch *xdsmatcherpb.StringMatcher) (*xdsmatcherpb.Matcher_MatcherList_Predicate, error) {
+	input, err := anypb.New(&matcherpb.HttpResponseHeaderMatchInput{
+		HeaderName: header,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &xdsmatcherpb.Matcher_MatcherList_Predicate{
+		MatchType: &xdsmatcherpb.Matcher_MatcherList_Predicate_SinglePredicate_{
+			SinglePredicate: &xdsmatcherpb.Matcher_MatcherList_Predicate_SinglePredicate{
+				Input: &xdspb.TypedExtensionConfig{
+					Name:        header,
+					TypedConfig: input,
+				},
+				Matcher: &xdsmatcherpb.Matcher_MatcherList_Predicate_SinglePredicate_ValueMatch{
+					ValueMatch: valueMatch,
+				},
+			},
+		},
+	}, nil
+}
+
+// replaceStructStrings returns a copy of the struct with the old string
+// replaced by the new one in all the string values.
+func replaceStructStrings(s *structpb.Struct, old, new string) (*structpb.Struct, error) {
+	b, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.ReplaceAll(string(b), old, new)), &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
@@ -0,0 +1,230 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewCustomResponseFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc: "Map gRPC status codes by name and by number",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:        "grpc://127.0.0.1:80",
+				GrpcErrorStatusCodes:  "not_found=410; 14=502",
+				ErrorResponseTemplate: `{"error": {"status": "%RESPONSE_CODE%", "detail": "%LOCAL_REPLY_BODY%"}}`,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+  "name": "envoy.filters.http.custom_response",
+  "typedConfig": {
+    "@type": "type.googleapis.com/envoy.extensions.filters.http.custom_response.v3.CustomResponse",
+    "customResponseMatcher": {
+      "matcherList": {
+        "matchers": [
+          {
+            "onMatch": {
+              "action": {
+                "name": "grpc_error_410",
+                "typedConfig": {
+                  "@type": "type.googleapis.com/envoy.extensions.http.custom_response.local_response_policy.v3.LocalResponsePolicy",
+                  "bodyFormat": {
+                    "jsonFormat": {
+                      "error": {
+                        "detail": "%RESP(grpc-message)%",
+                        "status": "%RESPONSE_CODE%"
+                      }
+                    }
+                  },
+                  "statusCode": 410
+                }
+              }
+            },
+            "predicate": {
+              "andMatcher": {
+                "predicate": [
+                  {
+                    "singlePredicate": {
+                      "input": {
+                        "name": "status-code",
+                        "typedConfig": {
+                          "@type": "type.googleapis.com/envoy.type.matcher.v3.HttpResponseStatusCodeMatchInput"
+                        }
+                      },
+                      "valueMatch": {
+                        "exact": "404"
+                      }
+                    }
+                  },
+                  {
+                    "notMatcher": {
+                      "singlePredicate": {
+                        "input": {
+                          "name": "content-type",
+                          "typedConfig": {
+                            "@type": "type.googleapis.com/envoy.type.matcher.v3.HttpResponseHeaderMatchInput",
+                            "headerName": "content-type"
+                          }
+                        },
+                        "valueMatch": {
+                          "prefix": "application/grpc"
+                        }
+                      }
+                    }
+                  }
+                ]
+              }
+            }
+          },
+          {
+            "onMatch": {
+              "action": {
+                "name": "grpc_error_502",
+                "typedConfig": {
+                  "@type": "type.googleapis.com/envoy.extensions.http.custom_response.local_response_policy.v3.LocalResponsePolicy",
+                  "bodyFormat": {
+                    "jsonFormat": {
+                      "error": {
+                        "detail": "%RESP(grpc-message)%",
+                        "status": "%RESPONSE_CODE%"
+                      }
+                    }
+                  },
+                  "statusCode": 502
+                }
+              }
+            },
+            "predicate": {
+              "andMatcher": {
+                "predicate": [
+                  {
+                    "singlePredicate": {
+                      "input": {
+                        "name": "status-code",
+                        "typedConfig": {
+                          "@type": "type.googleapis.com/envoy.type.matcher.v3.HttpResponseStatusCodeMatchInput"
+                        }
+                      },
+                      "valueMatch": {
+                        "exact": "503"
+                      }
+                    }
+                  },
+                  {
+                    "notMatcher": {
+                      "singlePredicate": {
+                        "input": {
+                          "name": "content-type",
+                          "typedConfig": {
+                            "@type": "type.googleapis.com/envoy.type.matcher.v3.HttpResponseHeaderMatchInput",
+                            "headerName": "content-type"
+                          }
+                        },
+                        "valueMatch": {
+                          "prefix": "application/grpc"
+                        }
+                      }
+                    }
+                  }
+                ]
+              }
+            }
+          }
+        ]
+      }
+    }
+  }
+}
+`,
+			},
+		},
+		{
+			Desc: "No-op without status codes mapping",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:        "grpc://127.0.0.1:80",
+				ErrorResponseTemplate: `{"error": "%LOCAL_REPLY_BODY%"}`,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewCustomResponseFilterGensFromOPConfig)
+	}
+}
+
+func TestNewCustomResponseFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Status codes mapping for HTTP backend",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "http://127.0.0.1:80",
+				GrpcErrorStatusCodes: "NOT_FOUND=404",
+			},
+			WantFactoryError: "flag --grpc_error_status_codes requires a gRPC backend",
+		},
+		{
+			Desc: "Unknown gRPC status code",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "grpc://127.0.0.1:80",
+				GrpcErrorStatusCodes: "NOT_EXIST=404",
+			},
+			WantFactoryError: `invalid flag --grpc_error_status_codes, unknown gRPC status code "NOT_EXIST"`,
+		},
+		{
+			Desc: "gRPC status code OK",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "grpc://127.0.0.1:80",
+				GrpcErrorStatusCodes: "0=404",
+			},
+			WantFactoryError: "gRPC status code OK cannot be mapped",
+		},
+		{
+			Desc: "Invalid HTTP status code",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "grpc://127.0.0.1:80",
+				GrpcErrorStatusCodes: "NOT_FOUND=4040",
+			},
+			WantFactoryError: `HTTP status code "4040" should be a number in [100, 600)`,
+		},
+		{
+			Desc: "gRPC status codes transcoded to the same HTTP status code mapped differently",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "grpc://127.0.0.1:80",
+				GrpcErrorStatusCodes: "INVALID_ARGUMENT=422;FAILED_PRECONDITION=412",
+			},
+			WantFactoryError: "gRPC status codes InvalidArgument and FailedPrecondition are both transcoded to HTTP status code 400",
+		},
+		{
+			Desc: "Template is not a JSON object",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:        "grpc://127.0.0.1:80",
+				GrpcErrorStatusCodes:  "NOT_FOUND=404",
+				ErrorResponseTemplate: `["%LOCAL_REPLY_BODY%"]`,
+			},
+			WantFactoryError: "invalid flag --error_response_template, it should be a JSON object",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewCustomResponseFilterGensFromOPConfig)
+	}
+}
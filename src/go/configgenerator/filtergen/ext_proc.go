@@ -0,0 +1,176 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_proc/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// ExtProcFilterName is the Envoy filter name for debug logging.
+	ExtProcFilterName = "envoy.filters.http.ext_proc"
+
+	// The ext_proc processing modes.
+	extProcHeadersOnly = "headers_only"
+	extProcBuffered    = "buffered"
+	extProcDisabled    = "disabled"
+)
+
+// ExtProcGenerator sends the request and response headers, and optionally the
+// buffered bodies, to an external processing gRPC service which can modify
+// them.
+type ExtProcGenerator struct {
+	Timeout          time.Duration
+	FailureModeAllow bool
+
+	// ProcessingMode is the global processing mode, "headers_only" or
+	// "buffered".
+	ProcessingMode string
+
+	// ProcessingModeBySelector overrides the processing mode per operation,
+	// it may also be "disabled".
+	ProcessingModeBySelector map[string]string
+
+	NoopFilterGenerator
+}
+
+// NewExtProcFilterGensFromOPConfig creates a ExtProcGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewExtProcFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.ExtProcAddress == "" {
+		glog.Info("Not adding ext_proc filter gen because there is no ext_proc service.")
+		return nil, nil
+	}
+
+	if _, err := makeExtProcProcessingMode(opts.ExtProcProcessingMode); err != nil || opts.ExtProcProcessingMode == extProcDisabled {
+		return nil, fmt.Errorf("invalid flag --ext_proc_processing_mode, %q should be one of %q or %q", opts.ExtProcProcessingMode, extProcHeadersOnly, extProcBuffered)
+	}
+
+	opProcessingModes, err := util.ParseSelectorMap(opts.OperationExtProcProcessingModes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_ext_proc_processing_modes: %v", err)
+	}
+
+	processingModeBySelector := make(map[string]string)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			value, ok := opProcessingModes.Lookup(selector)
+			if !ok {
+				continue
+			}
+			if _, err := makeExtProcProcessingMode(value); err != nil {
+				return nil, fmt.Errorf("invalid flag --operation_ext_proc_processing_modes for operation %q: %v", selector, err)
+			}
+			processingModeBySelector[selector] = value
+		}
+	}
+
+	return []FilterGenerator{
+		&ExtProcGenerator{
+			Timeout:                  opts.ExtProcTimeout,
+			FailureModeAllow:         opts.ExtProcFailureModeAllow,
+			ProcessingMode:           opts.ExtProcProcessingMode,
+			ProcessingModeBySelector: processingModeBySelector,
+		},
+	}, nil
+}
+
+// makeExtProcProcessingMode returns nil for the "disabled" mode.
+func makeExtProcProcessingMode(mode string) (*extprocpb.ProcessingMode, error) {
+	switch mode {
+	case extProcHeadersOnly:
+		return &extprocpb.ProcessingMode{
+			RequestHeaderMode:  extprocpb.ProcessingMode_SEND,
+			ResponseHeaderMode: extprocpb.ProcessingMode_SEND,
+			RequestBodyMode:    extprocpb.ProcessingMode_NONE,
+			ResponseBodyMode:   extprocpb.ProcessingMode_NONE,
+		}, nil
+	case extProcBuffered:
+		return &extprocpb.ProcessingMode{
+			RequestHeaderMode:  extprocpb.ProcessingMode_SEND,
+			ResponseHeaderMode: extprocpb.ProcessingMode_SEND,
+			RequestBodyMode:    extprocpb.ProcessingMode_BUFFERED,
+			ResponseBodyMode:   extprocpb.ProcessingMode_BUFFERED,
+		}, nil
+	case extProcDisabled:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown processing mode %q, should be one of %q, %q or %q", mode, extProcHeadersOnly, extProcBuffered, extProcDisabled)
+	}
+}
+
+func (g *ExtProcGenerator) FilterName() string {
+	return ExtProcFilterName
+}
+
+func (g *ExtProcGenerator) GenFilterConfig() (proto.Message, error) {
+	processingMode, err := makeExtProcProcessingMode(g.ProcessingMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extprocpb.ExternalProcessor{
+		GrpcService: &corepb.GrpcService{
+			TargetSpecifier: &corepb.GrpcService_EnvoyGrpc_{
+				EnvoyGrpc: &corepb.GrpcService_EnvoyGrpc{
+					ClusterName: clustergen.ExtProcClusterName,
+				},
+			},
+		},
+		FailureModeAllow: g.FailureModeAllow,
+		ProcessingMode:   processingMode,
+		MessageTimeout:   durationpb.New(g.Timeout),
+	}, nil
+}
+
+func (g *ExtProcGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	mode, ok := g.ProcessingModeBySelector[selector]
+	if !ok || mode == g.ProcessingMode {
+		return nil, nil
+	}
+
+	if mode == extProcDisabled {
+		return &extprocpb.ExtProcPerRoute{
+			Override: &extprocpb.ExtProcPerRoute_Disabled{
+				Disabled: true,
+			},
+		}, nil
+	}
+
+	processingMode, err := makeExtProcProcessingMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	return &extprocpb.ExtProcPerRoute{
+		Override: &extprocpb.ExtProcPerRoute_Overrides{
+			Overrides: &extprocpb.ExtProcOverrides{
+				ProcessingMode: processingMode,
+			},
+		},
+	}, nil
+}
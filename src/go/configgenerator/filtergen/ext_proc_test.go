@@ -0,0 +1,193 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var extProcTestServiceConfig = &servicepb.Service{
+	Apis: []*apipb.Api{
+		{
+			Name: "bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "CreateBook",
+				},
+				{
+					Name: "GetBook",
+				},
+				{
+					Name: "ListBooks",
+				},
+			},
+		},
+	},
+}
+
+func TestNewExtProcFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with headers only processing mode",
+			ServiceConfigIn: extProcTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress: "grpc://127.0.0.1:9000",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.ext_proc",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.ext_proc.v3.ExternalProcessor",
+      "grpcService":{
+         "envoyGrpc":{
+            "clusterName":"ext-proc-cluster"
+         }
+      },
+      "messageTimeout":"0.200s",
+      "processingMode":{
+         "requestHeaderMode":"SEND",
+         "responseHeaderMode":"SEND"
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Generate with buffered processing mode",
+			ServiceConfigIn: extProcTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress:          "grpc://127.0.0.1:9000",
+				ExtProcTimeout:          time.Second,
+				ExtProcFailureModeAllow: true,
+				ExtProcProcessingMode:   "buffered",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.ext_proc",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.ext_proc.v3.ExternalProcessor",
+      "failureModeAllow":true,
+      "grpcService":{
+         "envoyGrpc":{
+            "clusterName":"ext-proc-cluster"
+         }
+      },
+      "messageTimeout":"1s",
+      "processingMode":{
+         "requestBodyMode":"BUFFERED",
+         "requestHeaderMode":"SEND",
+         "responseBodyMode":"BUFFERED",
+         "responseHeaderMode":"SEND"
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op without ext_proc address",
+			ServiceConfigIn: extProcTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewExtProcFilterGensFromOPConfig)
+	}
+}
+
+func TestNewExtProcFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Invalid global processing mode",
+			ServiceConfigIn: extProcTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress:        "grpc://127.0.0.1:9000",
+				ExtProcProcessingMode: "disabled",
+			},
+			WantFactoryError: `invalid flag --ext_proc_processing_mode, "disabled" should be one of "headers_only" or "buffered"`,
+		},
+		{
+			Desc:            "Invalid per operation processing mode",
+			ServiceConfigIn: extProcTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				ExtProcAddress:                  "grpc://127.0.0.1:9000",
+				OperationExtProcProcessingModes: "bookstore.Bookstore.GetBook=streamed",
+			},
+			WantFactoryError: `invalid flag --operation_ext_proc_processing_modes for operation "bookstore.Bookstore.GetBook": unknown processing mode "streamed"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewExtProcFilterGensFromOPConfig)
+	}
+}
+
+func TestExtProcGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.ExtProcAddress = "grpc://127.0.0.1:9000"
+	opts.OperationExtProcProcessingModes = "bookstore.Bookstore.CreateBook=buffered;bookstore.Bookstore.List*=disabled;bookstore.Bookstore.GetBook=headers_only"
+	gens, err := filtergen.NewExtProcFilterGensFromOPConfig(extProcTestServiceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewExtProcFilterGensFromOPConfig() got error: %v", err)
+	}
+
+	wantPerRouteConfigs := map[string]string{
+		"bookstore.Bookstore.CreateBook": `
+{
+   "overrides":{
+      "processingMode":{
+         "requestBodyMode":"BUFFERED",
+         "requestHeaderMode":"SEND",
+         "responseBodyMode":"BUFFERED",
+         "responseHeaderMode":"SEND"
+      }
+   }
+}`,
+		"bookstore.Bookstore.ListBooks": `{"disabled":true}`,
+		"bookstore.Bookstore.GetBook":   ``,
+	}
+	for selector, want := range wantPerRouteConfigs {
+		got, err := gens[0].GenPerRouteConfig(selector, nil)
+		if err != nil {
+			t.Fatalf("GenPerRouteConfig(%q) got error: %v", selector, err)
+		}
+		if want == "" {
+			if got != nil {
+				t.Errorf("GenPerRouteConfig(%q) got %v, want nil", selector, got)
+			}
+			continue
+		}
+
+		gotJson, err := util.ProtoToJson(got)
+		if err != nil {
+			t.Fatalf("GenPerRouteConfig(%q) got invalid config: %v", selector, err)
+		}
+		if err := util.JsonEqual(want, gotJson); err != nil {
+			t.Errorf("GenPerRouteConfig(%q) got unexpected config: %v", selector, err)
+		}
+	}
+}
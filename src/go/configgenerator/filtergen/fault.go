@@ -0,0 +1,240 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	commonfaultpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	faultpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	stringmatcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// FaultFilterName is the Envoy filter name for debug logging.
+	FaultFilterName = "envoy.filters.http.fault"
+)
+
+// FaultGenerator injects the aborts and the delays into the requests of the
+// operations, for chaos testing.
+//
+// The faults are only injected into the requests with the trigger header, so
+// the other requests of the operations are not affected.
+type FaultGenerator struct {
+	// TriggerHeader matches the requests the faults are injected into.
+	TriggerHeader *routepb.HeaderMatcher
+
+	// FaultsBySelector are the faults of the operations, without the trigger
+	// header.
+	FaultsBySelector map[string]*faultpb.HTTPFault
+
+	NoopFilterGenerator
+}
+
+// NewFaultFilterGensFromOPConfig creates a FaultGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewFaultFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.OperationFaultAbort == "" && opts.OperationFaultDelay == "" {
+		if opts.FaultTriggerHeader != "" {
+			return nil, fmt.Errorf("invalid flag --fault_trigger_header, it requires flag --operation_fault_abort or --operation_fault_delay")
+		}
+		glog.Info("Not adding fault filter gen because no fault is injected.")
+		return nil, nil
+	}
+	if opts.FaultTriggerHeader == "" {
+		return nil, fmt.Errorf("flags --operation_fault_abort and --operation_fault_delay require flag --fault_trigger_header")
+	}
+
+	triggerHeader, err := parseFaultTriggerHeader(opts.FaultTriggerHeader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --fault_trigger_header: %v", err)
+	}
+	opAborts, err := util.ParseSelectorMap(opts.OperationFaultAbort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_fault_abort: %v", err)
+	}
+	opDelays, err := util.ParseSelectorMap(opts.OperationFaultDelay)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_fault_delay: %v", err)
+	}
+
+	faultsBySelector := make(map[string]*faultpb.HTTPFault)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			fault := &faultpb.HTTPFault{}
+
+			if value, ok := opAborts.Lookup(selector); ok {
+				if fault.Abort, err = parseFaultAbort(value); err != nil {
+					return nil, fmt.Errorf("invalid flag --operation_fault_abort for operation %q: %v", selector, err)
+				}
+			}
+			if value, ok := opDelays.Lookup(selector); ok {
+				if fault.Delay, err = parseFaultDelay(value); err != nil {
+					return nil, fmt.Errorf("invalid flag --operation_fault_delay for operation %q: %v", selector, err)
+				}
+			}
+
+			if fault.Abort != nil || fault.Delay != nil {
+				faultsBySelector[selector] = fault
+			}
+		}
+	}
+	if len(faultsBySelector) == 0 {
+		glog.Warningf("Not adding fault filter gen because flags --operation_fault_abort and --operation_fault_delay do not match any operation.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&FaultGenerator{
+			TriggerHeader:    triggerHeader,
+			FaultsBySelector: faultsBySelector,
+		},
+	}, nil
+}
+
+// parseFaultTriggerHeader parses "name" into a matcher of the header present,
+// or "name=value" into a matcher of the exact header value.
+func parseFaultTriggerHeader(value string) (*routepb.HeaderMatcher, error) {
+	name, headerValue, hasValue := strings.Cut(value, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.HasPrefix(name, ":") {
+		return nil, fmt.Errorf("%q is not a valid header name", name)
+	}
+
+	if !hasValue {
+		return &routepb.HeaderMatcher{
+			Name: name,
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		}, nil
+	}
+	return &routepb.HeaderMatcher{
+		Name: name,
+		HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+			StringMatch: &stringmatcherpb.StringMatcher{
+				MatchPattern: &stringmatcherpb.StringMatcher_Exact{
+					Exact: headerValue,
+				},
+			},
+		},
+	}, nil
+}
+
+// parseFaultAbort parses "status" or "status@percent" into an abort of the
+// percent of the requests, all of them if the percent is omitted.
+func parseFaultAbort(value string) (*faultpb.FaultAbort, error) {
+	statusValue, percent, err := parseFaultPercent(value)
+	if err != nil {
+		return nil, err
+	}
+	status, err := strconv.ParseUint(statusValue, 10, 32)
+	if err != nil || status < 200 || status >= 600 {
+		return nil, fmt.Errorf("%q is not a valid HTTP status code, must be in [200, 600)", statusValue)
+	}
+
+	return &faultpb.FaultAbort{
+		ErrorType: &faultpb.FaultAbort_HttpStatus{
+			HttpStatus: uint32(status),
+		},
+		Percentage: percent,
+	}, nil
+}
+
+// parseFaultDelay parses "duration" or "duration@percent" into a fixed delay
+// of the percent of the requests, all of them if the percent is omitted.
+func parseFaultDelay(value string) (*commonfaultpb.FaultDelay, error) {
+	durationValue, percent, err := parseFaultPercent(value)
+	if err != nil {
+		return nil, err
+	}
+	delay, err := time.ParseDuration(durationValue)
+	if err != nil || delay <= 0 {
+		return nil, fmt.Errorf("%q is not a valid delay, must be a positive duration such as 2s", durationValue)
+	}
+
+	return &commonfaultpb.FaultDelay{
+		FaultDelaySecifier: &commonfaultpb.FaultDelay_FixedDelay{
+			FixedDelay: durationpb.New(delay),
+		},
+		Percentage: percent,
+	}, nil
+}
+
+// parseFaultPercent splits the optional "@percent" suffix of the fault. The
+// percent is in (0, 100] with up to 4 decimal places.
+func parseFaultPercent(value string) (string, *typepb.FractionalPercent, error) {
+	fault, percentValue, hasPercent := strings.Cut(value, "@")
+	if !hasPercent {
+		return fault, &typepb.FractionalPercent{
+			Numerator:   100,
+			Denominator: typepb.FractionalPercent_HUNDRED,
+		}, nil
+	}
+
+	percent, err := strconv.ParseFloat(percentValue, 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return "", nil, fmt.Errorf("%q is not a valid percent, must be in (0, 100]", percentValue)
+	}
+	if percent == math.Trunc(percent) {
+		return fault, &typepb.FractionalPercent{
+			Numerator:   uint32(percent),
+			Denominator: typepb.FractionalPercent_HUNDRED,
+		}, nil
+	}
+	return fault, &typepb.FractionalPercent{
+		Numerator:   uint32(math.Round(percent * 10000)),
+		Denominator: typepb.FractionalPercent_MILLION,
+	}, nil
+}
+
+func (g *FaultGenerator) FilterName() string {
+	return FaultFilterName
+}
+
+// GenFilterConfig injects no fault, the faults are only configured for the
+// routes of the operations.
+func (g *FaultGenerator) GenFilterConfig() (proto.Message, error) {
+	return &faultpb.HTTPFault{}, nil
+}
+
+func (g *FaultGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	fault, ok := g.FaultsBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+
+	// The per route config replaces the filter config, so it has the trigger
+	// header too.
+	return &faultpb.HTTPFault{
+		Abort:   fault.GetAbort(),
+		Delay:   fault.GetDelay(),
+		Headers: []*routepb.HeaderMatcher{g.TriggerHeader},
+	}, nil
+}
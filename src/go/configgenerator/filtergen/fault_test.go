@@ -0,0 +1,249 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var faultTestServiceConfig = &servicepb.Service{
+	Name: "bookstore.endpoints.project123.cloud.goog",
+	Apis: []*apipb.Api{
+		{
+			Name: "endpoints.examples.bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "Echo",
+				},
+				{
+					Name: "GetShelf",
+				},
+			},
+		},
+	},
+}
+
+func TestNewFaultFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Disabled by default",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc:            "Not added if no operation is matched",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "endpoints.examples.bookstore.Library.*=503",
+				FaultTriggerHeader:  "x-espv2-fault",
+			},
+		},
+		{
+			Desc:            "No fault is injected by the filter config",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "endpoints.examples.bookstore.Bookstore.Echo=503@10",
+				FaultTriggerHeader:  "x-espv2-fault",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.fault",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.fault.v3.HTTPFault"
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewFaultFilterGensFromOPConfig)
+	}
+}
+
+func TestNewFaultFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Faults require the trigger header",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultDelay: "*=2s",
+			},
+			WantFactoryError: "flags --operation_fault_abort and --operation_fault_delay require flag --fault_trigger_header",
+		},
+		{
+			Desc:            "Trigger header requires the faults",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				FaultTriggerHeader: "x-espv2-fault",
+			},
+			WantFactoryError: "invalid flag --fault_trigger_header, it requires flag --operation_fault_abort or --operation_fault_delay",
+		},
+		{
+			Desc:            "Pseudo header cannot trigger the faults",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "*=503",
+				FaultTriggerHeader:  ":authority=chaos.example.com",
+			},
+			WantFactoryError: `invalid flag --fault_trigger_header: ":authority" is not a valid header name`,
+		},
+		{
+			Desc:            "Invalid abort status",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "endpoints.examples.bookstore.Bookstore.Echo=100",
+				FaultTriggerHeader:  "x-espv2-fault",
+			},
+			WantFactoryError: `invalid flag --operation_fault_abort for operation "endpoints.examples.bookstore.Bookstore.Echo": "100" is not a valid HTTP status code, must be in [200, 600)`,
+		},
+		{
+			Desc:            "Invalid delay",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultDelay: "endpoints.examples.bookstore.Bookstore.Echo=2",
+				FaultTriggerHeader:  "x-espv2-fault",
+			},
+			WantFactoryError: `invalid flag --operation_fault_delay for operation "endpoints.examples.bookstore.Bookstore.Echo": "2" is not a valid delay, must be a positive duration such as 2s`,
+		},
+		{
+			Desc:            "Invalid percent",
+			ServiceConfigIn: faultTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationFaultDelay: "endpoints.examples.bookstore.Bookstore.Echo=2s@120",
+				FaultTriggerHeader:  "x-espv2-fault",
+			},
+			WantFactoryError: `invalid flag --operation_fault_delay for operation "endpoints.examples.bookstore.Bookstore.Echo": "120" is not a valid percent, must be in (0, 100]`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewFaultFilterGensFromOPConfig)
+	}
+}
+
+func TestFaultGenerator_GenPerRouteConfig(t *testing.T) {
+	testdata := []struct {
+		desc         string
+		opts         options.ConfigGeneratorOptions
+		selector     string
+		wantPerRoute string
+	}{
+		{
+			desc: "Abort of a percent of the requests with the trigger header",
+			opts: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "endpoints.examples.bookstore.Bookstore.Echo=503@10",
+				FaultTriggerHeader:  "X-ESPv2-Fault",
+			},
+			selector: "endpoints.examples.bookstore.Bookstore.Echo",
+			wantPerRoute: `
+{
+   "abort":{
+      "httpStatus":503,
+      "percentage":{
+         "numerator":10
+      }
+   },
+   "headers":[
+      {
+         "name":"x-espv2-fault",
+         "presentMatch":true
+      }
+   ]
+}`,
+		},
+		{
+			desc: "Abort and delay of the requests with the trigger header value",
+			opts: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "endpoints.examples.bookstore.Bookstore.Echo=503@10;*=429",
+				OperationFaultDelay: "*=2s@0.5",
+				FaultTriggerHeader:  "x-espv2-fault=chaos",
+			},
+			selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+			wantPerRoute: `
+{
+   "delay":{
+      "fixedDelay":"2s",
+      "percentage":{
+         "numerator":5000,
+         "denominator":"MILLION"
+      }
+   },
+   "abort":{
+      "httpStatus":429,
+      "percentage":{
+         "numerator":100
+      }
+   },
+   "headers":[
+      {
+         "name":"x-espv2-fault",
+         "stringMatch":{
+            "exact":"chaos"
+         }
+      }
+   ]
+}`,
+		},
+		{
+			desc: "No per route config for the operations without faults",
+			opts: options.ConfigGeneratorOptions{
+				OperationFaultAbort: "endpoints.examples.bookstore.Bookstore.Echo=503",
+				FaultTriggerHeader:  "x-espv2-fault",
+			},
+			selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			gens, err := filtergen.NewFaultFilterGensFromOPConfig(faultTestServiceConfig, tc.opts)
+			if err != nil {
+				t.Fatalf("NewFaultFilterGensFromOPConfig() got error: %v", err)
+			}
+			if len(gens) != 1 {
+				t.Fatalf("NewFaultFilterGensFromOPConfig() got %d filter gens, want 1", len(gens))
+			}
+
+			perRoute, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantPerRoute == "" {
+				if perRoute != nil {
+					t.Errorf("GenPerRouteConfig() got %v, want nil", perRoute)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(perRoute)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got invalid config: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantPerRoute, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
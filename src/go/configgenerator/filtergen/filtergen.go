@@ -54,6 +54,13 @@ type FilterGenerator interface {
 	// This method is called on all virtual hosts. Return (nil, nil) to indicate the
 	// filter does NOT require a per-host config for the given virtual host.
 	GenPerHostConfig(string) (proto.Message, error)
+
+	// CustomEnvoyFeatures describes the features of the generated config that
+	// require the custom filters of the ESPv2 build of Envoy, so they are not
+	// representable with stock Envoy xDS.
+	//
+	// Return nil if the filter is available in stock Envoy.
+	CustomEnvoyFeatures() []string
 }
 
 // FilterGeneratorOPFactory is the factory function to create an ordered slice
@@ -74,3 +81,7 @@ func (g *NoopFilterGenerator) GenPerRouteConfig(string, *httppattern.Pattern) (p
 func (g *NoopFilterGenerator) GenPerHostConfig(string) (proto.Message, error) {
 	return nil, nil
 }
+
+func (g *NoopFilterGenerator) CustomEnvoyFeatures() []string {
+	return nil
+}
@@ -51,3 +51,7 @@ func (g *GRPCMetadataScrubberGenerator) FilterName() string {
 func (g *GRPCMetadataScrubberGenerator) GenFilterConfig() (proto.Message, error) {
 	return &gmspb.FilterConfig{}, nil
 }
+
+func (g *GRPCMetadataScrubberGenerator) CustomEnvoyFeatures() []string {
+	return []string{"gRPC trailers kept for HTTP/1.1 clients (--enable_grpc_for_http1)"}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	scpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/service_control"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	// GrpcReflectionAllow proxies the gRPC server reflection calls to the
+	// local backend, GrpcReflectionDeny rejects them.
+	GrpcReflectionAllow = "allow"
+	GrpcReflectionDeny  = "deny"
+)
+
+// GrpcReflectionOperationName is the synthetic operation of the gRPC server
+// reflection calls allowed by flag --grpc_reflection.
+var GrpcReflectionOperationName = fmt.Sprintf("%s.%s_GrpcReflection", util.EspOperation, util.AutogeneratedOperationPrefix)
+
+// GrpcReflectionPaths are the gRPC paths of the server reflection services.
+var GrpcReflectionPaths = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// ParseGrpcReflectionJwtProvidersFromOPConfig returns the JWT providers of
+// flag --grpc_reflection_jwt_providers, any of which is required for the
+// allowed gRPC server reflection calls. Nil if no authentication is required.
+func ParseGrpcReflectionJwtProvidersFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) ([]string, error) {
+	if opts.GrpcReflectionJwtProviders == "" {
+		return nil, nil
+	}
+	if opts.GrpcReflection != GrpcReflectionAllow {
+		return nil, fmt.Errorf("flag --grpc_reflection_jwt_providers requires flag --grpc_reflection=%s", GrpcReflectionAllow)
+	}
+
+	knownProviders := make(map[string]bool)
+	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		knownProviders[provider.GetId()] = true
+	}
+
+	var providers []string
+	for _, provider := range strings.Split(opts.GrpcReflectionJwtProviders, ",") {
+		provider = strings.TrimSpace(provider)
+		if provider == "" {
+			continue
+		}
+		if !knownProviders[provider] {
+			return nil, fmt.Errorf("invalid flag --grpc_reflection_jwt_providers, provider %q is not an authentication provider of the service config", provider)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// GetGrpcReflectionRequirementFromOPConfig returns the Service Control
+// requirement for the allowed gRPC server reflection calls (if enabled). They
+// are neither checked nor reported.
+func GetGrpcReflectionRequirementFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) *scpb.Requirement {
+	if opts.GrpcReflection != GrpcReflectionAllow {
+		return nil
+	}
+
+	return &scpb.Requirement{
+		ServiceName:        serviceConfig.GetName(),
+		OperationName:      GrpcReflectionOperationName,
+		ApiName:            util.EspOperation,
+		SkipServiceControl: true,
+		ApiKey: &scpb.ApiKeyRequirement{
+			AllowWithoutApiKey: true,
+		},
+	}
+}
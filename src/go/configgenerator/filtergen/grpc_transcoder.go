@@ -64,6 +64,11 @@ type GRPCTranscoderGenerator struct {
 // NewGRPCTranscoderFilterGensFromOPConfig creates a GRPCTranscoderGenerator from
 // OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
 func NewGRPCTranscoderFilterGensFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.DisableTranscoding {
+		glog.Infof("Not adding transcoder filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
 	grpcGen, err := NewGRPCTranscoderFilterGenFromOPConfig(serviceConfig, opts, true)
 	if err != nil {
 		return nil, err
@@ -135,7 +140,9 @@ func NewGRPCTranscoderFilterGenFromOPConfig(serviceConfig *confpb.Service, opts
 			AlwaysPrintPrimitiveFields: opts.TranscodingAlwaysPrintPrimitiveFields,
 			AlwaysPrintEnumsAsInts:     opts.TranscodingAlwaysPrintEnumsAsInts,
 			PreserveProtoFieldNames:    opts.TranscodingPreserveProtoFieldNames,
-			StreamNewlineDelimited:     opts.TranscodingStreamNewLineDelimited,
+			// Server-Sent Events (stream_sse_style_delimited) are not available
+			// in the pinned go-control-plane v0.11.0 API, only NDJSON is supported.
+			StreamNewlineDelimited: opts.TranscodingStreamNewLineDelimited,
 		},
 	}, nil
 }
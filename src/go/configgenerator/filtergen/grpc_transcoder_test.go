@@ -353,6 +353,30 @@ func TestNewGRPCTranscoderFilterGensFromOPConfig_GenConfig(t *testing.T) {
 			},
 			WantFilterConfigs: nil,
 		},
+		{
+			Desc: "Not generate transcoder filter when transcoding is disabled",
+			ServiceConfigIn: &confpb.Service{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "foo",
+							},
+						},
+					},
+				},
+				SourceInfo: &confpb.SourceInfo{
+					SourceFiles: []*anypb.Any{content},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:     "grpc://127.0.0.0:80",
+				DisableTranscoding: true,
+			},
+			WantFilterConfigs: nil,
+		},
 		{
 			Desc: "Not generate transcoder filter when all backends at NOT gRPC",
 			ServiceConfigIn: &confpb.Service{
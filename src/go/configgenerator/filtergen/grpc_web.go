@@ -15,6 +15,8 @@
 package filtergen
 
 import (
+	"fmt"
+
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	grpcwebpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
 	"github.com/golang/glog"
@@ -39,6 +41,9 @@ func NewGRPCWebFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts opt
 		return nil, err
 	}
 	if !isGRPCSupportRequired {
+		if opts.EnableGrpcWeb {
+			return nil, fmt.Errorf("flag --enable_grpc_web requires a gRPC backend")
+		}
 		glog.Infof("gRPC support is NOT required, skip gRPC web filter completely.")
 		return nil, nil
 	}
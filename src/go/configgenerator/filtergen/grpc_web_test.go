@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewGRPCWebFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc: "Generate gRPC-Web filter for gRPC backend",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+				EnableGrpcWeb:  true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.grpc_web",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.grpc_web.v3.GrpcWeb"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "No-op for HTTP backend",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:80",
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewGRPCWebFilterGensFromOPConfig)
+	}
+}
+
+func TestNewGRPCWebFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "gRPC-Web is enabled for HTTP backend",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:80",
+				EnableGrpcWeb:  true,
+			},
+			WantFactoryError: "flag --enable_grpc_web requires a gRPC backend",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewGRPCWebFilterGensFromOPConfig)
+	}
+}
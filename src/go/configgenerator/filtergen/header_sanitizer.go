@@ -45,3 +45,7 @@ func (g *HeaderSanitizerGenerator) FilterName() string {
 func (g *HeaderSanitizerGenerator) GenFilterConfig() (proto.Message, error) {
 	return &hspb.FilterConfig{}, nil
 }
+
+func (g *HeaderSanitizerGenerator) CustomEnvoyFeatures() []string {
+	return []string{"HTTP method override by the x-http-method-override header"}
+}
@@ -38,6 +38,7 @@ const (
 type HealthCheckGenerator struct {
 	HealthzPath                  string
 	ShouldHealthCheckGrpcBackend bool
+	ShouldCheckBackendHealth     bool
 	LocalBackendClusterName      string
 
 	NoopFilterGenerator
@@ -55,6 +56,7 @@ func NewHealthCheckFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts
 		&HealthCheckGenerator{
 			HealthzPath:                  opts.Healthz,
 			ShouldHealthCheckGrpcBackend: opts.HealthCheckGrpcBackend,
+			ShouldCheckBackendHealth:     opts.HealthzCheckBackend,
 			LocalBackendClusterName:      clustergen.MakeLocalBackendClusterName(serviceConfig),
 		},
 	}, nil
@@ -87,7 +89,9 @@ func (g *HealthCheckGenerator) GenFilterConfig() (proto.Message, error) {
 		},
 	}
 
-	if g.ShouldHealthCheckGrpcBackend {
+	// The backend cluster health is determined by the gRPC health check and
+	// the outlier detection configured on the local backend cluster.
+	if g.ShouldHealthCheckGrpcBackend || g.ShouldCheckBackendHealth {
 		hcFilterConfig.ClusterMinHealthyPercentages = map[string]*envoytypepb.Percent{
 			g.LocalBackendClusterName: {Value: 100.0},
 		}
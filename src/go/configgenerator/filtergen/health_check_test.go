@@ -92,6 +92,34 @@ func TestNewHealthCheckFilterGensFromOPConfig_GenConfig(t *testing.T) {
               "backend-cluster-bookstore.endpoints.project123.cloud.goog_local": { "value": 100.0 }
           }
         }
+      }`,
+			},
+		},
+		{
+			Desc: "Success, generate health check filter reflecting backend health",
+			OptsIn: options.ConfigGeneratorOptions{
+				Healthz:             "healthz",
+				HealthzCheckBackend: true,
+			},
+			ServiceConfigIn: &confpb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			WantFilterConfigs: []string{
+				`{
+        "name": "envoy.filters.http.health_check",
+        "typedConfig": {
+          "@type":"type.googleapis.com/envoy.extensions.filters.http.health_check.v3.HealthCheck",
+          "passThroughMode":false,
+          "headers": [
+            {
+              "stringMatch":{"exact":"/healthz"},
+              "name":":path"
+            }
+          ],
+          "clusterMinHealthyPercentages": {
+              "backend-cluster-bookstore.endpoints.project123.cloud.goog_local": { "value": 100.0 }
+          }
+        }
       }`,
 			},
 		},
@@ -31,6 +31,7 @@ type FilterAccessTokenConfiger struct {
 	// Non-GCP deployment options.
 	ServiceAccountKey string
 	TokenAgentPort    uint
+	TokenAgentPath    string
 
 	// GCP deployment options.
 	MetadataURL string
@@ -38,11 +39,28 @@ type FilterAccessTokenConfiger struct {
 
 // NewFilterAccessTokenConfigerFromOPConfig creates a FilterAccessTokenConfiger from
 // OP service config + descriptor + ESPv2 options.
+//
+// The access token is used to call Service Control.
 func NewFilterAccessTokenConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *FilterAccessTokenConfiger {
 	return &FilterAccessTokenConfiger{
 		HttpRequestTimeout: opts.HttpRequestTimeout,
-		ServiceAccountKey:  opts.ServiceAccountKey,
+		ServiceAccountKey:  options.ServiceControlServiceAccountKey(opts),
+		TokenAgentPort:     opts.TokenAgentPort,
+		TokenAgentPath:     util.TokenAgentAccessTokenPath,
+		MetadataURL:        opts.MetadataURL,
+	}
+}
+
+// NewBackendAuthAccessTokenConfigerFromOPConfig creates a FilterAccessTokenConfiger from
+// OP service config + descriptor + ESPv2 options.
+//
+// The access token is used to mint the backend auth tokens.
+func NewBackendAuthAccessTokenConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *FilterAccessTokenConfiger {
+	return &FilterAccessTokenConfiger{
+		HttpRequestTimeout: opts.HttpRequestTimeout,
+		ServiceAccountKey:  options.BackendAuthServiceAccountKey(opts),
 		TokenAgentPort:     opts.TokenAgentPort,
+		TokenAgentPath:     util.TokenAgentBackendAuthAccessTokenPath,
 		MetadataURL:        opts.MetadataURL,
 	}
 }
@@ -56,7 +74,7 @@ func (c *FilterAccessTokenConfiger) MakeAccessTokenConfig() *commonpb.AccessToke
 			TokenType: &commonpb.AccessToken_RemoteToken{
 				RemoteToken: &commonpb.HttpUri{
 					// Use http://127.0.0.1:8791/local/access_token by default.
-					Uri:     fmt.Sprintf("http://%s:%v%s", util.LoopbackIPv4Addr, c.TokenAgentPort, util.TokenAgentAccessTokenPath),
+					Uri:     fmt.Sprintf("http://%s:%v%s", util.LoopbackIPv4Addr, c.TokenAgentPort, c.TokenAgentPath),
 					Cluster: clustergen.TokenAgentClusterName,
 					Timeout: durationpb.New(c.HttpRequestTimeout),
 				},
@@ -16,6 +16,9 @@ package filtergen
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/tracing"
@@ -24,10 +27,12 @@ import (
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	facpb "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
 	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tracingpb "github.com/envoyproxy/go-control-plane/envoy/type/tracing/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -48,10 +53,42 @@ type HTTPConnectionManagerGenerator struct {
 	DisallowEscapedSlashesInPath bool
 	AccessLogPath                string
 	AccessLogFormat              string
-	UnderscoresInHeaders         bool
 	EnableGrpcForHttp1           bool
 	TracingOptions               *options.TracingOptions
 
+	// UpgradeTypes are allowed for all the routes.
+	UpgradeTypes []string
+	// OperationUpgradeTypes are only allowed for the routes of some operations.
+	OperationUpgradeTypes []string
+
+	// Request header validation and response header casing.
+	HeadersWithUnderscoresAction corepb.HttpProtocolOptions_HeadersWithUnderscoresAction
+	MaxRequestHeadersCount       int
+	MaxRequestHeadersKb          int
+	HeaderKeyFormat              *corepb.Http1ProtocolOptions_HeaderKeyFormat
+
+	// AccessLogFilter samples the access log by the API consumers.
+	AccessLogFilter *acpb.AccessLogFilter
+
+	// Downstream connection lifetime, not set if 0.
+	IdleTimeout           time.Duration
+	MaxConnectionDuration time.Duration
+	DrainTimeout          time.Duration
+
+	// ErrorResponseTemplate overrides the JSON body of the local replies.
+	ErrorResponseTemplate *structpb.Struct
+
+	// DeploymentLabels are added to the traces as custom tags.
+	DeploymentLabels map[string]string
+
+	// ServerName overrides the Server response header if not empty.
+	ServerName                 string
+	ServerHeaderTransformation hcmpb.HttpConnectionManager_ServerHeaderTransformation
+
+	// Via is the value of the Via header, with the deployment labels
+	// substituted. Not added if empty.
+	Via string
+
 	NoopFilterGenerator
 }
 
@@ -65,6 +102,66 @@ func NewHTTPConnectionManagerGenFromOPConfig(serviceConfig *servicepb.Service, o
 		return nil, err
 	}
 
+	upgradeTypes, allUpgradeTypes, err := ParseAllUpgradeTypes(opts.UpgradeTypes, opts.OperationUpgradeTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	errorResponseTemplate, err := ParseErrorResponseTemplate(opts.ErrorResponseTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	headersWithUnderscoresAction, err := ParseHeadersWithUnderscoresAction(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxRequestHeadersCount < 0 {
+		return nil, fmt.Errorf("invalid flag --max_request_headers_count %d, must be >= 0", opts.MaxRequestHeadersCount)
+	}
+	if opts.MaxRequestHeadersKb < 0 || opts.MaxRequestHeadersKb > 8192 {
+		return nil, fmt.Errorf("invalid flag --max_request_headers_kb %d, must be in the range of [0, 8192]", opts.MaxRequestHeadersKb)
+	}
+
+	headerKeyFormat, err := util.CreateHeaderKeyFormat(opts.HeaderKeyFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --header_key_format: %v", err)
+	}
+
+	deploymentLabels, err := util.ParseDeploymentLabels(opts.DeploymentLabels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --deployment_labels: %v", err)
+	}
+
+	serverHeaderTransformation, err := ParseServerHeaderTransformation(opts.ServerHeaderTransformation)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(opts.ServerHeader, "\r\n") {
+		return nil, fmt.Errorf("invalid flag --server_header %q, must not contain line breaks", opts.ServerHeader)
+	}
+
+	via, err := MakeViaHeader(opts.ViaHeader, deploymentLabels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --via_header: %v", err)
+	}
+
+	if opts.DownstreamIdleTimeout < 0 {
+		return nil, fmt.Errorf("invalid flag --downstream_idle_timeout %v, must be >= 0", opts.DownstreamIdleTimeout)
+	}
+	if opts.DownstreamMaxConnectionDuration < 0 {
+		return nil, fmt.Errorf("invalid flag --downstream_max_connection_duration %v, must be >= 0", opts.DownstreamMaxConnectionDuration)
+	}
+	if opts.DownstreamDrainTimeout < 0 {
+		return nil, fmt.Errorf("invalid flag --downstream_drain_timeout %v, must be >= 0", opts.DownstreamDrainTimeout)
+	}
+
+	consumerSampleRates, err := ParseAccessLogConsumerSampleRates(opts.AccessLogConsumerSampleRates)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --access_log_consumer_sample_rates: %v", err)
+	}
+
 	return &HTTPConnectionManagerGenerator{
 		IsSchemeHeaderOverrideRequired: isSchemeHeaderOverrideRequired,
 		EnvoyUseRemoteAddress:          opts.EnvoyUseRemoteAddress,
@@ -74,23 +171,146 @@ func NewHTTPConnectionManagerGenFromOPConfig(serviceConfig *servicepb.Service, o
 		DisallowEscapedSlashesInPath:   opts.DisallowEscapedSlashesInPath,
 		AccessLogPath:                  opts.AccessLog,
 		AccessLogFormat:                opts.AccessLogFormat,
-		UnderscoresInHeaders:           opts.UnderscoresInHeaders,
+		AccessLogFilter:                MakeAccessLogConsumerSampleFilter(consumerSampleRates, opts.GeneratedHeaderPrefix+consumerNumberHeaderSuffix),
 		EnableGrpcForHttp1:             opts.EnableGrpcForHttp1,
 		TracingOptions:                 opts.TracingOptions,
+		UpgradeTypes:                   upgradeTypes,
+		OperationUpgradeTypes:          allUpgradeTypes[len(upgradeTypes):],
+		ErrorResponseTemplate:          errorResponseTemplate,
+		HeadersWithUnderscoresAction:   headersWithUnderscoresAction,
+		MaxRequestHeadersCount:         opts.MaxRequestHeadersCount,
+		MaxRequestHeadersKb:            opts.MaxRequestHeadersKb,
+		HeaderKeyFormat:                headerKeyFormat,
+		DeploymentLabels:               deploymentLabels,
+		ServerName:                     opts.ServerHeader,
+		ServerHeaderTransformation:     serverHeaderTransformation,
+		Via:                            via,
+		IdleTimeout:                    opts.DownstreamIdleTimeout,
+		MaxConnectionDuration:          opts.DownstreamMaxConnectionDuration,
+		DrainTimeout:                   opts.DownstreamDrainTimeout,
 	}, nil
 }
 
+// ParseHeadersWithUnderscoresAction returns the action for the headers with
+// underscores, which defaults to the flag --underscores_in_headers.
+func ParseHeadersWithUnderscoresAction(opts options.ConfigGeneratorOptions) (corepb.HttpProtocolOptions_HeadersWithUnderscoresAction, error) {
+	switch opts.HeadersWithUnderscoresAction {
+	case "":
+		if opts.UnderscoresInHeaders {
+			return corepb.HttpProtocolOptions_ALLOW, nil
+		}
+		return corepb.HttpProtocolOptions_REJECT_REQUEST, nil
+	case "allow":
+		return corepb.HttpProtocolOptions_ALLOW, nil
+	case "reject_request", "drop_header":
+		if opts.UnderscoresInHeaders {
+			return 0, fmt.Errorf("flag --underscores_in_headers cannot be used with flag --headers_with_underscores_action=%s", opts.HeadersWithUnderscoresAction)
+		}
+		if opts.HeadersWithUnderscoresAction == "drop_header" {
+			return corepb.HttpProtocolOptions_DROP_HEADER, nil
+		}
+		return corepb.HttpProtocolOptions_REJECT_REQUEST, nil
+	default:
+		return 0, fmt.Errorf(`invalid flag --headers_with_underscores_action %q, must be "allow", "reject_request" or "drop_header"`, opts.HeadersWithUnderscoresAction)
+	}
+}
+
+// ParseServerHeaderTransformation returns how the Server response header is
+// set. For "suppress", the backend Server header is passed through so it can
+// be removed by the route config.
+func ParseServerHeaderTransformation(transformation string) (hcmpb.HttpConnectionManager_ServerHeaderTransformation, error) {
+	switch transformation {
+	case "", "overwrite":
+		return hcmpb.HttpConnectionManager_OVERWRITE, nil
+	case "append_if_absent":
+		return hcmpb.HttpConnectionManager_APPEND_IF_ABSENT, nil
+	case "pass_through", "suppress":
+		return hcmpb.HttpConnectionManager_PASS_THROUGH, nil
+	default:
+		return 0, fmt.Errorf(`invalid flag --server_header_transformation %q, must be "overwrite", "append_if_absent", "pass_through" or "suppress"`, transformation)
+	}
+}
+
+// MakeViaHeader replaces the "{key}" placeholders of the Via header template
+// by the values of the deployment labels.
+func MakeViaHeader(template string, labels map[string]string) (string, error) {
+	var via strings.Builder
+	rest := template
+	for {
+		start := strings.Index(rest, "{")
+		if start == -1 {
+			via.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("unclosed placeholder in Via header %q", template)
+		}
+		key := rest[start+1 : start+end]
+		value, ok := labels[key]
+		if !ok {
+			return "", fmt.Errorf("unknown deployment label %q in Via header %q", key, template)
+		}
+		via.WriteString(rest[:start])
+		via.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+
+	if strings.ContainsAny(via.String(), "\r\n") {
+		return "", fmt.Errorf("invalid Via header %q, must not contain line breaks", via.String())
+	}
+	return via.String(), nil
+}
+
+// ParseAllUpgradeTypes returns the connection upgrade types allowed for all
+// the operations, and all the upgrade types allowed for any operation, which
+// start with the former.
+func ParseAllUpgradeTypes(globalUpgradeTypes, operationUpgradeTypes string) ([]string, []string, error) {
+	opUpgradeTypes, err := util.ParseSelectorMap(operationUpgradeTypes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid flag --operation_upgrade_types: %v", err)
+	}
+
+	upgradeTypes := ParseUpgradeTypes(globalUpgradeTypes)
+	allUpgradeTypes := append([]string{}, upgradeTypes...)
+	for _, types := range opUpgradeTypes.Values() {
+		for _, upgradeType := range ParseUpgradeTypes(types) {
+			if !containsString(allUpgradeTypes, upgradeType) {
+				allUpgradeTypes = append(allUpgradeTypes, upgradeType)
+			}
+		}
+	}
+	return upgradeTypes, allUpgradeTypes, nil
+}
+
+// ParseUpgradeTypes parses comma separated upgrade types. They are lower cased
+// as Envoy matches them case-insensitively.
+func ParseUpgradeTypes(types string) []string {
+	var upgradeTypes []string
+	for _, upgradeType := range strings.Split(types, ",") {
+		upgradeType = strings.ToLower(strings.TrimSpace(upgradeType))
+		if upgradeType != "" && !containsString(upgradeTypes, upgradeType) {
+			upgradeTypes = append(upgradeTypes, upgradeType)
+		}
+	}
+	return upgradeTypes
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *HTTPConnectionManagerGenerator) FilterName() string {
 	return HTTPConnectionManagerFilterName
 }
 
 func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error) {
 	httpConMgr := &hcmpb.HttpConnectionManager{
-		UpgradeConfigs: []*hcmpb.HttpConnectionManager_UpgradeConfig{
-			{
-				UpgradeType: "websocket",
-			},
-		},
 		CodecType:         hcmpb.HttpConnectionManager_AUTO,
 		StatPrefix:        util.StatPrefix,
 		UseRemoteAddress:  &wrapperspb.BoolValue{Value: g.EnvoyUseRemoteAddress},
@@ -101,6 +321,20 @@ func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error
 		MergeSlashes:  g.MergeSlashesInPath,
 	}
 
+	// Upgrades only allowed for some operations are disabled by default, and
+	// enabled by the route config.
+	for _, upgradeType := range g.UpgradeTypes {
+		httpConMgr.UpgradeConfigs = append(httpConMgr.UpgradeConfigs, &hcmpb.HttpConnectionManager_UpgradeConfig{
+			UpgradeType: upgradeType,
+		})
+	}
+	for _, upgradeType := range g.OperationUpgradeTypes {
+		httpConMgr.UpgradeConfigs = append(httpConMgr.UpgradeConfigs, &hcmpb.HttpConnectionManager_UpgradeConfig{
+			UpgradeType: upgradeType,
+			Enabled:     &wrapperspb.BoolValue{Value: false},
+		})
+	}
+
 	// Converting the error message for requests rejected by Envoy to JSON format:
 	//
 	//    {
@@ -108,19 +342,24 @@ func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error
 	//       "message": "the error message",
 	//    }
 	//
+	// unless overridden by the error response template.
+	localReplyBody := &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"code": {
+				Kind: &structpb.Value_StringValue{StringValue: "%RESPONSE_CODE%"},
+			},
+			"message": {
+				Kind: &structpb.Value_StringValue{StringValue: "%LOCAL_REPLY_BODY%"},
+			},
+		},
+	}
+	if g.ErrorResponseTemplate != nil {
+		localReplyBody = g.ErrorResponseTemplate
+	}
 	httpConMgr.LocalReplyConfig = &hcmpb.LocalReplyConfig{
 		BodyFormat: &corepb.SubstitutionFormatString{
 			Format: &corepb.SubstitutionFormatString_JsonFormat{
-				JsonFormat: &structpb.Struct{
-					Fields: map[string]*structpb.Value{
-						"code": {
-							Kind: &structpb.Value_StringValue{StringValue: "%RESPONSE_CODE%"},
-						},
-						"message": {
-							Kind: &structpb.Value_StringValue{StringValue: "%LOCAL_REPLY_BODY%"},
-						},
-					},
-				},
+				JsonFormat: localReplyBody,
 			},
 		},
 	}
@@ -152,7 +391,7 @@ func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error
 		httpConMgr.AccessLog = []*acpb.AccessLog{
 			{
 				Name:   util.AccessFileLogger,
-				Filter: nil,
+				Filter: g.AccessLogFilter,
 				ConfigType: &acpb.AccessLog_TypedConfig{
 					TypedConfig: serialized,
 				},
@@ -166,22 +405,52 @@ func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error
 		if err != nil {
 			return nil, err
 		}
-	}
 
-	if g.UnderscoresInHeaders {
-		httpConMgr.CommonHttpProtocolOptions = &corepb.HttpProtocolOptions{
-			HeadersWithUnderscoresAction: corepb.HttpProtocolOptions_ALLOW,
+		keys := make([]string, 0, len(g.DeploymentLabels))
+		for key := range g.DeploymentLabels {
+			keys = append(keys, key)
 		}
-	} else {
-		httpConMgr.CommonHttpProtocolOptions = &corepb.HttpProtocolOptions{
-			HeadersWithUnderscoresAction: corepb.HttpProtocolOptions_REJECT_REQUEST,
+		sort.Strings(keys)
+		for _, key := range keys {
+			httpConMgr.Tracing.CustomTags = append(httpConMgr.Tracing.CustomTags, &tracingpb.CustomTag{
+				Tag: key,
+				Type: &tracingpb.CustomTag_Literal_{
+					Literal: &tracingpb.CustomTag_Literal{
+						Value: g.DeploymentLabels[key],
+					},
+				},
+			})
 		}
 	}
 
-	if g.EnableGrpcForHttp1 {
-		// Retain gRPC trailers if downstream is using http1.
+	httpConMgr.CommonHttpProtocolOptions = &corepb.HttpProtocolOptions{
+		HeadersWithUnderscoresAction: g.HeadersWithUnderscoresAction,
+	}
+	if g.MaxRequestHeadersCount > 0 {
+		httpConMgr.CommonHttpProtocolOptions.MaxHeadersCount = &wrapperspb.UInt32Value{Value: uint32(g.MaxRequestHeadersCount)}
+	}
+	if g.MaxRequestHeadersKb > 0 {
+		httpConMgr.MaxRequestHeadersKb = &wrapperspb.UInt32Value{Value: uint32(g.MaxRequestHeadersKb)}
+	}
+	if g.IdleTimeout > 0 {
+		httpConMgr.CommonHttpProtocolOptions.IdleTimeout = durationpb.New(g.IdleTimeout)
+	}
+	if g.MaxConnectionDuration > 0 {
+		httpConMgr.CommonHttpProtocolOptions.MaxConnectionDuration = durationpb.New(g.MaxConnectionDuration)
+	}
+	if g.DrainTimeout > 0 {
+		httpConMgr.DrainTimeout = durationpb.New(g.DrainTimeout)
+	}
+
+	httpConMgr.ServerName = g.ServerName
+	httpConMgr.ServerHeaderTransformation = g.ServerHeaderTransformation
+	httpConMgr.Via = g.Via
+
+	if g.EnableGrpcForHttp1 || g.HeaderKeyFormat != nil {
 		httpConMgr.HttpProtocolOptions = &corepb.Http1ProtocolOptions{
-			EnableTrailers: true,
+			// Retain gRPC trailers if downstream is using http1.
+			EnableTrailers:  g.EnableGrpcForHttp1,
+			HeaderKeyFormat: g.HeaderKeyFormat,
 		}
 	}
 
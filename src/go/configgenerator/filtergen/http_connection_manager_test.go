@@ -16,6 +16,7 @@ package filtergen_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
@@ -30,6 +31,7 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 		{
 			Desc: "Generate HttpConMgr with default options",
 			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes: "websocket",
 				CommonOptions: options.CommonOptions{
 					TracingOptions: &options.TracingOptions{
 						DisableTracing: true,
@@ -68,6 +70,7 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 		{
 			Desc: "Generate HttpConMgr when accessLog is defined",
 			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:    "websocket",
 				AccessLog:       "/foo",
 				AccessLogFormat: "/bar",
 				CommonOptions: options.CommonOptions{
@@ -112,12 +115,123 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 	],
 	"useRemoteAddress": false
 }
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr when access log is sampled by consumers",
+			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:                 "websocket",
+				AccessLog:                    "/foo",
+				AccessLogConsumerSampleRates: "*=0.5;123456=100",
+				CommonOptions: options.CommonOptions{
+					GeneratedHeaderPrefix: "X-Endpoint-",
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"accessLog": [
+		{
+			"name": "envoy.access_loggers.file",
+			"filter": {
+				"orFilter": {
+					"filters": [
+						{
+							"andFilter": {
+								"filters": [
+									{
+										"headerFilter": {
+											"header": {
+												"name": "X-Endpoint-api-consumer-number",
+												"stringMatch": {
+													"exact": "123456"
+												}
+											}
+										}
+									},
+									{
+										"runtimeFilter": {
+											"runtimeKey": "access_log.consumer_sample_rate.123456",
+											"percentSampled": {
+												"numerator": 1000000,
+												"denominator": "MILLION"
+											},
+											"useIndependentRandomness": true
+										}
+									}
+								]
+							}
+						},
+						{
+							"andFilter": {
+								"filters": [
+									{
+										"headerFilter": {
+											"header": {
+												"name": "X-Endpoint-api-consumer-number",
+												"stringMatch": {
+													"exact": "123456"
+												},
+												"invertMatch": true
+											}
+										}
+									},
+									{
+										"runtimeFilter": {
+											"runtimeKey": "access_log.consumer_sample_rate.default",
+											"percentSampled": {
+												"numerator": 5000,
+												"denominator": "MILLION"
+											},
+											"useIndependentRandomness": true
+										}
+									}
+								]
+							}
+						}
+					]
+				}
+			},
+			"typedConfig": {
+				"@type": "type.googleapis.com/envoy.extensions.access_loggers.file.v3.FileAccessLog",
+				"path": "/foo"
+			}
+		}
+	],
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
 `,
 			},
 		},
 		{
 			Desc: "Generate HttpConMgr when tracing is enabled",
 			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes: "websocket",
 				CommonOptions: options.CommonOptions{
 					TracingOptions: &options.TracingOptions{
 						DisableTracing: false,
@@ -170,12 +284,87 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 	],
 	"useRemoteAddress": false
 }
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with deployment labels as tracing custom tags",
+			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:     "websocket",
+				DeploymentLabels: "gke_pod=pod-1;environment=prod",
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: false,
+						ProjectId:      "test-project",
+						SamplingRate:   1,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"tracing":{
+		"clientSampling":{},
+		"customTags":[
+			{
+				"literal":{
+					"value":"prod"
+				},
+				"tag":"environment"
+			},
+			{
+				"literal":{
+					"value":"pod-1"
+				},
+				"tag":"gke_pod"
+			}
+		],
+		"overallSampling":{
+			"value": 100
+		},
+		"provider":{
+			"name":"envoy.tracers.opencensus",
+			"typedConfig":{
+				 "@type":"type.googleapis.com/envoy.config.trace.v3.OpenCensusConfig",
+				 "stackdriverExporterEnabled":true,
+				 "stackdriverProjectId":"test-project",
+				 "traceConfig":{}
+			}
+		},
+		"randomSampling":{
+			"value": 100
+		}
+	},
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
 `,
 			},
 		},
 		{
 			Desc: "Generate HttpConMgr when UnderscoresInHeaders is defined",
 			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:         "websocket",
 				UnderscoresInHeaders: true,
 				CommonOptions: options.CommonOptions{
 					TracingOptions: &options.TracingOptions{
@@ -213,6 +402,7 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 		{
 			Desc: "Generate HttpConMgr when EnableGrpcForHttp1 is defined",
 			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:         "websocket",
 				EnableGrpcForHttp1:   true,
 				UnderscoresInHeaders: true,
 				CommonOptions: options.CommonOptions{
@@ -249,6 +439,320 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 `,
 			},
 		},
+		{
+			Desc: "Generate HttpConMgr with request header validation and header casing",
+			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:                 "websocket",
+				EnableGrpcForHttp1:           true,
+				HeadersWithUnderscoresAction: "drop_header",
+				MaxRequestHeadersCount:       50,
+				MaxRequestHeadersKb:          32,
+				HeaderKeyFormat:              "proper_case",
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "DROP_HEADER",
+		"maxHeadersCount": 50
+	},
+	"httpProtocolOptions": {
+		"enableTrailers": true,
+		"headerKeyFormat": {
+			"properCaseWords": {}
+		}
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"maxRequestHeadersKb": 32,
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with upgrades only allowed for some operations",
+			OptsIn: options.ConfigGeneratorOptions{
+				UpgradeTypes:          "websocket",
+				OperationUpgradeTypes: "bookstore.Bookstore.Tunnel=CONNECT,websocket;bookstore.Bookstore.GetShelf=",
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		},
+		{
+			"upgradeType": "connect",
+			"enabled": false
+		}
+	],
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with downstream connection lifetime",
+			OptsIn: options.ConfigGeneratorOptions{
+				DownstreamIdleTimeout:           5 * time.Minute,
+				DownstreamMaxConnectionDuration: time.Hour,
+				DownstreamDrainTimeout:          30 * time.Second,
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST",
+		"idleTimeout": "300s",
+		"maxConnectionDuration": "3600s"
+	},
+	"drainTimeout": "30s",
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with Server and Via headers",
+			OptsIn: options.ConfigGeneratorOptions{
+				ServerHeader:               "bookstore",
+				ServerHeaderTransformation: "append_if_absent",
+				ViaHeader:                  "1.1 espv2-{environment}-{region}",
+				DeploymentLabels:           "environment=prod;region=us-central1",
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"serverHeaderTransformation": "APPEND_IF_ABSENT",
+	"serverName": "bookstore",
+	"statPrefix": "ingress_http",
+	"useRemoteAddress": false,
+	"via": "1.1 espv2-prod-us-central1"
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr passing the Server header through to suppress it",
+			OptsIn: options.ConfigGeneratorOptions{
+				ServerHeaderTransformation: "suppress",
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"serverHeaderTransformation": "PASS_THROUGH",
+	"statPrefix": "ingress_http",
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, func(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
+			gen, err := filtergen.NewHTTPConnectionManagerGenFromOPConfig(serviceConfig, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			return []filtergen.FilterGenerator{
+				gen,
+			}, nil
+		})
+	}
+}
+
+func TestNewHTTPConnectionManagerGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Unknown headers with underscores action",
+			OptsIn: options.ConfigGeneratorOptions{
+				HeadersWithUnderscoresAction: "ignore",
+			},
+			WantFactoryError: `invalid flag --headers_with_underscores_action "ignore"`,
+		},
+		{
+			Desc: "Underscores in headers conflicts with the action",
+			OptsIn: options.ConfigGeneratorOptions{
+				UnderscoresInHeaders:         true,
+				HeadersWithUnderscoresAction: "drop_header",
+			},
+			WantFactoryError: "flag --underscores_in_headers cannot be used with flag --headers_with_underscores_action=drop_header",
+		},
+		{
+			Desc: "Max request headers size too large",
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestHeadersKb: 8193,
+			},
+			WantFactoryError: "invalid flag --max_request_headers_kb 8193",
+		},
+		{
+			Desc: "Unknown header key format",
+			OptsIn: options.ConfigGeneratorOptions{
+				HeaderKeyFormat: "upper_case",
+			},
+			WantFactoryError: `invalid flag --header_key_format: invalid header key format "upper_case"`,
+		},
+		{
+			Desc: "Invalid deployment label key",
+			OptsIn: options.ConfigGeneratorOptions{
+				DeploymentLabels: "Environment=prod",
+			},
+			WantFactoryError: `invalid flag --deployment_labels: invalid deployment label key "Environment"`,
+		},
+		{
+			Desc: "Invalid consumer of access log sample rate",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogConsumerSampleRates: "my-project=100",
+			},
+			WantFactoryError: `invalid flag --access_log_consumer_sample_rates: invalid consumer "my-project", must be a project number or "*"`,
+		},
+		{
+			Desc: "Access log sample rate out of range",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogConsumerSampleRates: "123456=200",
+			},
+			WantFactoryError: `invalid flag --access_log_consumer_sample_rates: invalid sample rate "200" of consumer "123456", must be a percentage in the range of [0, 100]`,
+		},
+		{
+			Desc: "Negative downstream max connection duration",
+			OptsIn: options.ConfigGeneratorOptions{
+				DownstreamMaxConnectionDuration: -time.Minute,
+			},
+			WantFactoryError: "invalid flag --downstream_max_connection_duration -1m0s, must be >= 0",
+		},
+		{
+			Desc: "Unknown Server header transformation",
+			OptsIn: options.ConfigGeneratorOptions{
+				ServerHeaderTransformation: "remove",
+			},
+			WantFactoryError: `invalid flag --server_header_transformation "remove"`,
+		},
+		{
+			Desc: "Line break in Server header",
+			OptsIn: options.ConfigGeneratorOptions{
+				ServerHeader: "bookstore\r\nx-injected: true",
+			},
+			WantFactoryError: "invalid flag --server_header",
+		},
+		{
+			Desc: "Unknown deployment label in Via header",
+			OptsIn: options.ConfigGeneratorOptions{
+				ViaHeader:        "1.1 espv2-{environment}",
+				DeploymentLabels: "team=payments",
+			},
+			WantFactoryError: `invalid flag --via_header: unknown deployment label "environment" in Via header "1.1 espv2-{environment}"`,
+		},
+		{
+			Desc: "Unclosed placeholder in Via header",
+			OptsIn: options.ConfigGeneratorOptions{
+				ViaHeader: "1.1 espv2-{environment",
+			},
+			WantFactoryError: `invalid flag --via_header: unclosed placeholder in Via header "1.1 espv2-{environment"`,
+		},
 	}
 
 	for _, tc := range testdata {
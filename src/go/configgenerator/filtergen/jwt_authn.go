@@ -51,6 +51,10 @@ type JwtAuthnGenerator struct {
 	// config.
 	AuthRequiredBySelector map[string]bool
 
+	// GrpcReflectionProviders are the providers required for the allowed gRPC
+	// server reflection calls, nil if no authentication is required.
+	GrpcReflectionProviders []string
+
 	// General options below.
 
 	HttpRequestTimeout    time.Duration
@@ -85,16 +89,40 @@ func NewJwtAuthnFilterGensFromOPConfig(serviceConfig *confpb.Service, opts optio
 		return nil, nil
 	}
 
+	if opts.ApiKeyBearerTokenPrefix != "" {
+		// The API keys sent as bearer tokens would be verified as JWTs.
+		for _, provider := range auth.GetProviders() {
+			fromHeaders, _, err := processJwtLocations(provider)
+			if err != nil {
+				return nil, err
+			}
+			for _, header := range fromHeaders {
+				if strings.EqualFold(header.GetName(), util.DefaultJwtHeaderNameAuthorization) {
+					return nil, fmt.Errorf("flag --api_key_bearer_token_prefix cannot be used with the provider (%v) extracting the JWT from the %s header, set the jwt_locations of the provider to the other locations", provider.GetId(), util.DefaultJwtHeaderNameAuthorization)
+				}
+			}
+		}
+	}
+
 	authRequiredBySelector, err := GetAuthRequiredSelectorsFromOPConfig(serviceConfig, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	grpcReflectionProviders, err := ParseGrpcReflectionJwtProvidersFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(grpcReflectionProviders) > 0 {
+		authRequiredBySelector[GrpcReflectionOperationName] = true
+	}
+
 	return []FilterGenerator{
 		&JwtAuthnGenerator{
 			ServiceName:                        serviceConfig.GetName(),
 			AuthConfig:                         auth,
 			AuthRequiredBySelector:             authRequiredBySelector,
+			GrpcReflectionProviders:            grpcReflectionProviders,
 			HttpRequestTimeout:                 opts.HttpRequestTimeout,
 			GeneratedHeaderPrefix:              opts.GeneratedHeaderPrefix,
 			JwksCacheDurationInS:               opts.JwksCacheDurationInS,
@@ -213,6 +241,15 @@ func (g *JwtAuthnGenerator) GenFilterConfig() (proto.Message, error) {
 			requirements[rule.GetSelector()] = makeJwtRequirement(rule.GetRequirements(), rule.GetAllowWithoutCredential())
 		}
 	}
+	if len(g.GrpcReflectionProviders) > 0 {
+		var grpcReflectionRequirements []*confpb.AuthRequirement
+		for _, provider := range g.GrpcReflectionProviders {
+			grpcReflectionRequirements = append(grpcReflectionRequirements, &confpb.AuthRequirement{
+				ProviderId: provider,
+			})
+		}
+		requirements[GrpcReflectionOperationName] = makeJwtRequirement(grpcReflectionRequirements, false)
+	}
 
 	return &jwtpb.JwtAuthentication{
 		Providers:      providers,
@@ -115,6 +115,101 @@ func TestNewJwtAuthnFilterGensFromOPConfig_GenConfig(t *testing.T) {
         }
     }
 }
+`,
+			},
+		},
+		{
+			Desc: "Success. Generate jwt authn filter with the provider required for gRPC reflection calls.",
+			ServiceConfigIn: &confpb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "testapi",
+						Methods: []*apipb.Method{
+							{
+								Name: "foo",
+							},
+						},
+					},
+				},
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider",
+							Issuer:  "issuer-0",
+							JwksUri: "https://fake-jwks.com?key=value",
+						},
+					},
+					Rules: []*confpb.AuthenticationRule{
+						{
+							Selector: "testapi.foo",
+							Requirements: []*confpb.AuthRequirement{
+								{
+									ProviderId: "auth_provider",
+								},
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					GeneratedHeaderPrefix: "X-Endpoint-",
+					HttpRequestTimeout:    30 * time.Second,
+				},
+				JwksCacheDurationInS:       300,
+				BackendAddress:             "grpc://127.0.0.1:8082",
+				GrpcReflection:             "allow",
+				GrpcReflectionJwtProviders: "auth_provider",
+			},
+			OptsMergeBehavior: mergo.WithOverwriteWithEmptyValue,
+			WantFilterConfigs: []string{
+				`{
+    "name": "envoy.filters.http.jwt_authn",
+    "typedConfig": {
+        "@type": "type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication",
+        "providers": {
+            "auth_provider": {
+                "audiences": [
+                    "https://bookstore.endpoints.project123.cloud.goog"
+                ],
+                "forward": true,
+                "forwardPayloadHeader": "X-Endpoint-API-UserInfo",
+                "fromHeaders": [
+                    {
+                        "name": "Authorization",
+                        "valuePrefix": "Bearer "
+                    },
+                    {
+                        "name": "X-Goog-Iap-Jwt-Assertion"
+                    }
+                ],
+                "fromParams": [
+                    "access_token"
+                ],
+                "issuer": "issuer-0",
+                "payloadInMetadata": "jwt_payloads",
+                "remoteJwks": {
+                    "cacheDuration": "300s",
+                    "httpUri": {
+                        "cluster": "jwt-provider-cluster-fake-jwks.com:443",
+                        "timeout": "30s",
+                        "uri": "https://fake-jwks.com?key=value"
+                    },
+                    "asyncFetch": {}
+                }
+            }
+        },
+        "requirementMap": {
+            "espv2_deployment.ESPv2_Autogenerated_GrpcReflection": {
+                "providerName": "auth_provider"
+            },
+            "testapi.foo": {
+                "providerName": "auth_provider"
+            }
+        }
+    }
+}
 `,
 			},
 		},
@@ -698,3 +793,61 @@ func TestNewJwtAuthnFilterGensFromOPConfig_GenConfig(t *testing.T) {
 		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
 	}
 }
+
+func TestNewJwtAuthnFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "API keys in the bearer tokens cannot be used with the default jwt locations",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider",
+							Issuer:  "issuer-0",
+							JwksUri: "https://fake-jwks.com",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				ApiKeyBearerTokenPrefix: "AIza",
+			},
+			WantFactoryError: "flag --api_key_bearer_token_prefix cannot be used with the provider (auth_provider) extracting the JWT from the Authorization header",
+		},
+		{
+			Desc: "API keys in the bearer tokens cannot be used with the jwt location of the Authorization header",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider",
+							Issuer:  "issuer-0",
+							JwksUri: "https://fake-jwks.com",
+							JwtLocations: []*confpb.JwtLocation{
+								{
+									In: &confpb.JwtLocation_Header{
+										Header: "x-jwt",
+									},
+								},
+								{
+									In: &confpb.JwtLocation_Header{
+										Header: "authorization",
+									},
+									ValuePrefix: "Bearer ",
+								},
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				ApiKeyBearerTokenPrefix: "AIza",
+			},
+			WantFactoryError: "flag --api_key_bearer_token_prefix cannot be used with the provider (auth_provider)",
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	luapb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// LuaFilterName is the Envoy filter name for debug logging.
+	LuaFilterName = "envoy.filters.http.lua"
+
+	luaScriptDisabled = "disabled"
+)
+
+// LuaGenerator runs user-provided Lua scripts globally or for specific
+// operations.
+type LuaGenerator struct {
+	// DefaultScript is the path of the script run for all the operations,
+	// empty if none.
+	DefaultScript string
+
+	// Scripts are the paths of the named scripts.
+	Scripts map[string]string
+
+	// ScriptBySelector overrides the script per operation by its name, it
+	// may also be "disabled".
+	ScriptBySelector map[string]string
+
+	NoopFilterGenerator
+}
+
+// NewLuaFilterGensFromOPConfig creates a LuaGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewLuaFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.LuaFilterScript == "" && opts.LuaScripts == "" {
+		if opts.OperationLuaScripts != "" {
+			return nil, fmt.Errorf("flag --operation_lua_scripts requires flag --lua_scripts or --lua_filter_script")
+		}
+		glog.Info("Not adding Lua filter gen because there is no Lua script.")
+		return nil, nil
+	}
+
+	scriptsByName, err := parseLuaScripts(opts.LuaScripts)
+	if err != nil {
+		return nil, err
+	}
+
+	opScripts, err := util.ParseSelectorMap(opts.OperationLuaScripts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_lua_scripts: %v", err)
+	}
+
+	scriptBySelector := make(map[string]string)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			name, ok := opScripts.Lookup(selector)
+			if !ok {
+				continue
+			}
+			if _, ok := scriptsByName[name]; !ok && name != luaScriptDisabled {
+				return nil, fmt.Errorf("invalid flag --operation_lua_scripts for operation %q: unknown Lua script %q", selector, name)
+			}
+			scriptBySelector[selector] = name
+		}
+	}
+
+	return []FilterGenerator{
+		&LuaGenerator{
+			DefaultScript:    opts.LuaFilterScript,
+			Scripts:          scriptsByName,
+			ScriptBySelector: scriptBySelector,
+		},
+	}, nil
+}
+
+// parseLuaScripts parses the named Lua scripts in the format of
+// "name1=path1;name2=path2".
+func parseLuaScripts(s string) (map[string]string, error) {
+	scriptsByName := make(map[string]string)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid flag --lua_scripts, %q is not in the format of name=path", entry)
+		}
+
+		name := strings.TrimSpace(kv[0])
+		if name == luaScriptDisabled {
+			return nil, fmt.Errorf("invalid flag --lua_scripts, %q is a reserved name", luaScriptDisabled)
+		}
+		if _, ok := scriptsByName[name]; ok {
+			return nil, fmt.Errorf("invalid flag --lua_scripts, duplicate script name %q", name)
+		}
+		scriptsByName[name] = strings.TrimSpace(kv[1])
+	}
+	return scriptsByName, nil
+}
+
+func (g *LuaGenerator) FilterName() string {
+	return LuaFilterName
+}
+
+func (g *LuaGenerator) GenFilterConfig() (proto.Message, error) {
+	config := &luapb.Lua{}
+	if g.DefaultScript != "" {
+		config.DefaultSourceCode = makeFileDataSource(g.DefaultScript)
+	}
+	if len(g.Scripts) > 0 {
+		config.SourceCodes = make(map[string]*corepb.DataSource)
+		for name, path := range g.Scripts {
+			config.SourceCodes[name] = makeFileDataSource(path)
+		}
+	}
+	return config, nil
+}
+
+func (g *LuaGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	name, ok := g.ScriptBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+
+	if name == luaScriptDisabled {
+		return &luapb.LuaPerRoute{
+			Override: &luapb.LuaPerRoute_Disabled{
+				Disabled: true,
+			},
+		}, nil
+	}
+	return &luapb.LuaPerRoute{
+		Override: &luapb.LuaPerRoute_Name{
+			Name: name,
+		},
+	}, nil
+}
+
+func makeFileDataSource(path string) *corepb.DataSource {
+	return &corepb.DataSource{
+		Specifier: &corepb.DataSource_Filename{
+			Filename: path,
+		},
+	}
+}
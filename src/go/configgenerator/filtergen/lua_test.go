@@ -0,0 +1,161 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var luaTestServiceConfig = &servicepb.Service{
+	Apis: []*apipb.Api{
+		{
+			Name: "bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "GetBook",
+				},
+				{
+					Name: "GetLegacyBook",
+				},
+			},
+		},
+	},
+}
+
+func TestNewLuaFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with default and named scripts",
+			ServiceConfigIn: luaTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				LuaFilterScript: "/etc/espv2/default.lua",
+				LuaScripts:      "legacy=/etc/espv2/legacy.lua",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.lua",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua",
+      "defaultSourceCode":{
+         "filename":"/etc/espv2/default.lua"
+      },
+      "sourceCodes":{
+         "legacy":{
+            "filename":"/etc/espv2/legacy.lua"
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op without Lua scripts",
+			ServiceConfigIn: luaTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewLuaFilterGensFromOPConfig)
+	}
+}
+
+func TestNewLuaFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Per operation scripts without scripts",
+			ServiceConfigIn: luaTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationLuaScripts: "bookstore.Bookstore.GetBook=disabled",
+			},
+			WantFactoryError: "flag --operation_lua_scripts requires flag --lua_scripts or --lua_filter_script",
+		},
+		{
+			Desc:            "Script without path",
+			ServiceConfigIn: luaTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				LuaScripts: "legacy",
+			},
+			WantFactoryError: `invalid flag --lua_scripts, "legacy" is not in the format of name=path`,
+		},
+		{
+			Desc:            "Reserved script name",
+			ServiceConfigIn: luaTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				LuaScripts: "disabled=/etc/espv2/legacy.lua",
+			},
+			WantFactoryError: `invalid flag --lua_scripts, "disabled" is a reserved name`,
+		},
+		{
+			Desc:            "Unknown script for operation",
+			ServiceConfigIn: luaTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				LuaScripts:          "legacy=/etc/espv2/legacy.lua",
+				OperationLuaScripts: "bookstore.Bookstore.GetBook=remap",
+			},
+			WantFactoryError: `invalid flag --operation_lua_scripts for operation "bookstore.Bookstore.GetBook": unknown Lua script "remap"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewLuaFilterGensFromOPConfig)
+	}
+}
+
+func TestLuaGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.LuaFilterScript = "/etc/espv2/default.lua"
+	opts.LuaScripts = "legacy=/etc/espv2/legacy.lua"
+	opts.OperationLuaScripts = "bookstore.Bookstore.GetLegacy*=legacy;bookstore.Bookstore.GetBook=disabled"
+	gens, err := filtergen.NewLuaFilterGensFromOPConfig(luaTestServiceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewLuaFilterGensFromOPConfig() got error: %v", err)
+	}
+
+	wantPerRouteConfigs := map[string]string{
+		"bookstore.Bookstore.GetLegacyBook": `{"name":"legacy"}`,
+		"bookstore.Bookstore.GetBook":       `{"disabled":true}`,
+		"ESPv2_Autogenerated_CORS_Root":     ``,
+	}
+	for selector, want := range wantPerRouteConfigs {
+		got, err := gens[0].GenPerRouteConfig(selector, nil)
+		if err != nil {
+			t.Fatalf("GenPerRouteConfig(%q) got error: %v", selector, err)
+		}
+		if want == "" {
+			if got != nil {
+				t.Errorf("GenPerRouteConfig(%q) got %v, want nil", selector, got)
+			}
+			continue
+		}
+
+		gotJson, err := util.ProtoToJson(got)
+		if err != nil {
+			t.Fatalf("GenPerRouteConfig(%q) got invalid config: %v", selector, err)
+		}
+		if err := util.JsonEqual(want, gotJson); err != nil {
+			t.Errorf("GenPerRouteConfig(%q) got unexpected config: %v", selector, err)
+		}
+	}
+}
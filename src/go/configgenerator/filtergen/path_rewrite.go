@@ -77,6 +77,28 @@ func (g *PathRewriteGenerator) GenFilterConfig() (proto.Message, error) {
 	return &prpb.FilterConfig{}, nil
 }
 
+// CustomEnvoyFeatures reports the operations translating their paths, as the
+// path templates of CONSTANT_ADDRESS are not supported by stock Envoy.
+func (g *PathRewriteGenerator) CustomEnvoyFeatures() []string {
+	var appendPath, constantAddress int
+	for _, info := range g.TranslationInfoBySelector {
+		if info.TranslationType == confpb.BackendRule_CONSTANT_ADDRESS {
+			constantAddress++
+		} else {
+			appendPath++
+		}
+	}
+
+	var features []string
+	if appendPath > 0 {
+		features = append(features, fmt.Sprintf("path translation APPEND_PATH_TO_ADDRESS for %d operations", appendPath))
+	}
+	if constantAddress > 0 {
+		features = append(features, fmt.Sprintf("path translation CONSTANT_ADDRESS for %d operations", constantAddress))
+	}
+	return features
+}
+
 // matchTranslationInfo matches the selector to the configured info.
 // Accounts for CORS selectors.
 func (g *PathRewriteGenerator) matchTranslationInfo(selector string) (TranslationInfo, error) {
@@ -0,0 +1,200 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacconfigpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// RBACFilterName is the Envoy filter name for debug logging.
+	RBACFilterName = "envoy.filters.http.rbac"
+
+	clientIPPolicyName = "client_ip"
+)
+
+// RBACGenerator rejects the requests with 403 Forbidden unless the client IP
+// is in the allowed CIDRs and not in the denied CIDRs.
+//
+// The client IP is the remote address, or the original client IP from the
+// x-forwarded-for header if --envoy_use_remote_address and
+// --envoy_xff_num_trusted_hops are set.
+type RBACGenerator struct {
+	// Rules are the global rules, nil if all the clients are allowed.
+	Rules *rbacconfigpb.RBAC
+
+	// RulesBySelector overrides the rules per operation, nil if all the
+	// clients are allowed for the operation.
+	RulesBySelector map[string]*rbacconfigpb.RBAC
+
+	NoopFilterGenerator
+}
+
+// NewRBACFilterGensFromOPConfig creates a RBACGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewRBACFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.AllowedClientIps == "" && opts.DeniedClientIps == "" && opts.OperationAllowedClientIps == "" && opts.OperationDeniedClientIps == "" {
+		glog.Info("Not adding RBAC filter gen because client IPs are not restricted.")
+		return nil, nil
+	}
+
+	allowed, err := util.ParseCIDRRanges(opts.AllowedClientIps)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --allowed_client_ips: %v", err)
+	}
+	denied, err := util.ParseCIDRRanges(opts.DeniedClientIps)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --denied_client_ips: %v", err)
+	}
+
+	opAllowed, err := util.ParseSelectorMap(opts.OperationAllowedClientIps)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_allowed_client_ips: %v", err)
+	}
+	opDenied, err := util.ParseSelectorMap(opts.OperationDeniedClientIps)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_denied_client_ips: %v", err)
+	}
+
+	rulesBySelector := make(map[string]*rbacconfigpb.RBAC)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			allowedValue, allowedOk := opAllowed.Lookup(selector)
+			deniedValue, deniedOk := opDenied.Lookup(selector)
+			if !allowedOk && !deniedOk {
+				continue
+			}
+
+			opAllowedRanges, opDeniedRanges := allowed, denied
+			if allowedOk {
+				if opAllowedRanges, err = util.ParseCIDRRanges(allowedValue); err != nil {
+					return nil, fmt.Errorf("invalid flag --operation_allowed_client_ips for operation %q: %v", selector, err)
+				}
+			}
+			if deniedOk {
+				if opDeniedRanges, err = util.ParseCIDRRanges(deniedValue); err != nil {
+					return nil, fmt.Errorf("invalid flag --operation_denied_client_ips for operation %q: %v", selector, err)
+				}
+			}
+			rulesBySelector[selector] = makeClientIPRules(opAllowedRanges, opDeniedRanges)
+		}
+	}
+
+	return []FilterGenerator{
+		&RBACGenerator{
+			Rules:           makeClientIPRules(allowed, denied),
+			RulesBySelector: rulesBySelector,
+		},
+	}, nil
+}
+
+// makeClientIPRules returns nil if all the clients are allowed.
+func makeClientIPRules(allowed, denied []*corepb.CidrRange) *rbacconfigpb.RBAC {
+	var ids []*rbacconfigpb.Principal
+	if len(allowed) > 0 {
+		ids = append(ids, makeRemoteIPPrincipal(allowed))
+	}
+	if len(denied) > 0 {
+		ids = append(ids, &rbacconfigpb.Principal{
+			Identifier: &rbacconfigpb.Principal_NotId{
+				NotId: makeRemoteIPPrincipal(denied),
+			},
+		})
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	principal := ids[0]
+	if len(ids) > 1 {
+		principal = &rbacconfigpb.Principal{
+			Identifier: &rbacconfigpb.Principal_AndIds{
+				AndIds: &rbacconfigpb.Principal_Set{
+					Ids: ids,
+				},
+			},
+		}
+	}
+
+	return &rbacconfigpb.RBAC{
+		Action: rbacconfigpb.RBAC_ALLOW,
+		Policies: map[string]*rbacconfigpb.Policy{
+			clientIPPolicyName: {
+				Permissions: []*rbacconfigpb.Permission{
+					{
+						Rule: &rbacconfigpb.Permission_Any{
+							Any: true,
+						},
+					},
+				},
+				Principals: []*rbacconfigpb.Principal{
+					principal,
+				},
+			},
+		},
+	}
+}
+
+func makeRemoteIPPrincipal(ranges []*corepb.CidrRange) *rbacconfigpb.Principal {
+	var ids []*rbacconfigpb.Principal
+	for _, r := range ranges {
+		ids = append(ids, &rbacconfigpb.Principal{
+			Identifier: &rbacconfigpb.Principal_RemoteIp{
+				RemoteIp: r,
+			},
+		})
+	}
+	return &rbacconfigpb.Principal{
+		Identifier: &rbacconfigpb.Principal_OrIds{
+			OrIds: &rbacconfigpb.Principal_Set{
+				Ids: ids,
+			},
+		},
+	}
+}
+
+func (g *RBACGenerator) FilterName() string {
+	return RBACFilterName
+}
+
+func (g *RBACGenerator) GenFilterConfig() (proto.Message, error) {
+	// Without rules, all the requests are allowed.
+	return &rbacpb.RBAC{
+		Rules: g.Rules,
+	}, nil
+}
+
+func (g *RBACGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	rules, ok := g.RulesBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+	return &rbacpb.RBACPerRoute{
+		Rbac: &rbacpb.RBAC{
+			Rules: rules,
+		},
+	}, nil
+}
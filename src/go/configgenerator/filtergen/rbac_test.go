@@ -0,0 +1,245 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var rbacTestServiceConfig = &servicepb.Service{
+	Apis: []*apipb.Api{
+		{
+			Name: "bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "ListShelves",
+				},
+				{
+					Name: "DeleteShelf",
+				},
+			},
+		},
+	},
+}
+
+func TestNewRBACFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with allowed and denied client IPs",
+			ServiceConfigIn: rbacTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				AllowedClientIps: "10.0.0.0/8",
+				DeniedClientIps:  "10.0.0.1",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.rbac",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC",
+      "rules":{
+         "policies":{
+            "client_ip":{
+               "permissions":[
+                  {
+                     "any":true
+                  }
+               ],
+               "principals":[
+                  {
+                     "andIds":{
+                        "ids":[
+                           {
+                              "orIds":{
+                                 "ids":[
+                                    {
+                                       "remoteIp":{
+                                          "addressPrefix":"10.0.0.0",
+                                          "prefixLen":8
+                                       }
+                                    }
+                                 ]
+                              }
+                           },
+                           {
+                              "notId":{
+                                 "orIds":{
+                                    "ids":[
+                                       {
+                                          "remoteIp":{
+                                             "addressPrefix":"10.0.0.1",
+                                             "prefixLen":32
+                                          }
+                                       }
+                                    ]
+                                 }
+                              }
+                           }
+                        ]
+                     }
+                  }
+               ]
+            }
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Allow all the clients globally when only operations are restricted",
+			ServiceConfigIn: rbacTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationAllowedClientIps: "bookstore.Bookstore.DeleteShelf=192.168.0.0/16",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.rbac",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op when client IPs are not restricted",
+			ServiceConfigIn: rbacTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewRBACFilterGensFromOPConfig)
+	}
+}
+
+func TestNewRBACFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Invalid global CIDR",
+			ServiceConfigIn: rbacTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				AllowedClientIps: "10.0.0.0/33",
+			},
+			WantFactoryError: `invalid flag --allowed_client_ips`,
+		},
+		{
+			Desc:            "Invalid per operation CIDR",
+			ServiceConfigIn: rbacTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationDeniedClientIps: "bookstore.Bookstore.DeleteShelf=localhost",
+			},
+			WantFactoryError: `invalid flag --operation_denied_client_ips for operation "bookstore.Bookstore.DeleteShelf"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewRBACFilterGensFromOPConfig)
+	}
+}
+
+func TestRBACGenerator_GenPerRouteConfig(t *testing.T) {
+	testdata := []struct {
+		desc                      string
+		deniedClientIps           string
+		operationAllowedClientIps string
+		operationDeniedClientIps  string
+		wantPerRouteConfigs       map[string]string
+	}{
+		{
+			desc:                      "Per operation allowed client IPs keep the global denied client IPs",
+			deniedClientIps:           "10.0.0.1",
+			operationAllowedClientIps: "bookstore.Bookstore.Delete*=10.0.0.0/8",
+			wantPerRouteConfigs: map[string]string{
+				"bookstore.Bookstore.DeleteShelf": `
+{
+   "rbac":{
+      "rules":{
+         "policies":{
+            "client_ip":{
+               "permissions":[{"any":true}],
+               "principals":[
+                  {
+                     "andIds":{
+                        "ids":[
+                           {"orIds":{"ids":[{"remoteIp":{"addressPrefix":"10.0.0.0","prefixLen":8}}]}},
+                           {"notId":{"orIds":{"ids":[{"remoteIp":{"addressPrefix":"10.0.0.1","prefixLen":32}}]}}}
+                        ]
+                     }
+                  }
+               ]
+            }
+         }
+      }
+   }
+}`,
+				"bookstore.Bookstore.ListShelves": ``,
+			},
+		},
+		{
+			desc:                     "Empty per operation list allows all the clients",
+			deniedClientIps:          "10.0.0.1",
+			operationDeniedClientIps: "bookstore.Bookstore.ListShelves=",
+			wantPerRouteConfigs: map[string]string{
+				"bookstore.Bookstore.ListShelves": `{"rbac":{}}`,
+				"bookstore.Bookstore.DeleteShelf": ``,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.DeniedClientIps = tc.deniedClientIps
+			opts.OperationAllowedClientIps = tc.operationAllowedClientIps
+			opts.OperationDeniedClientIps = tc.operationDeniedClientIps
+			gens, err := filtergen.NewRBACFilterGensFromOPConfig(rbacTestServiceConfig, opts)
+			if err != nil {
+				t.Fatalf("NewRBACFilterGensFromOPConfig() got error: %v", err)
+			}
+
+			for selector, want := range tc.wantPerRouteConfigs {
+				got, err := gens[0].GenPerRouteConfig(selector, nil)
+				if err != nil {
+					t.Fatalf("GenPerRouteConfig(%q) got error: %v", selector, err)
+				}
+				if want == "" {
+					if got != nil {
+						t.Errorf("GenPerRouteConfig(%q) got %v, want nil", selector, got)
+					}
+					continue
+				}
+
+				gotJson, err := util.ProtoToJson(got)
+				if err != nil {
+					t.Fatalf("GenPerRouteConfig(%q) got invalid config: %v", selector, err)
+				}
+				if err := util.JsonEqual(want, gotJson); err != nil {
+					t.Errorf("GenPerRouteConfig(%q) got unexpected config: %v", selector, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	luapb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	"github.com/golang/glog"
+	ahpb "google.golang.org/genproto/googleapis/api/annotations"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	typepb "google.golang.org/genproto/protobuf/ptype"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// RequestValidationFilterName is the name of the Lua filter validating the
+	// requests. It is not LuaFilterName, so the validation scripts do not
+	// override the user-provided Lua scripts of the routes.
+	RequestValidationFilterName = "com.google.espv2.filters.http.request_validation"
+)
+
+// queryParamRule is the validation of a query parameter bound to a field of
+// the request message.
+type queryParamRule struct {
+	// Names are the field name and its JSON name, the transcoding accepts
+	// both.
+	Names    []string
+	Required bool
+
+	// Kind is the kind of the values checked by the script, empty if any
+	// value is valid.
+	Kind       string
+	EnumValues []string
+}
+
+// requestValidationRule is the validation of the requests of an HTTP rule.
+type requestValidationRule struct {
+	QueryParams  []*queryParamRule
+	BodyRequired bool
+}
+
+// RequestValidationGenerator rejects the invalid requests with a Lua script
+// per route generated from the request message type of the operation.
+type RequestValidationGenerator struct {
+	// RulesByRoute are the validations keyed by requestValidationRouteKey.
+	RulesByRoute map[string]*requestValidationRule
+
+	NoopFilterGenerator
+}
+
+// NewRequestValidationFilterGensFromOPConfig creates a RequestValidationGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewRequestValidationFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableRequestValidation {
+		glog.Info("Not adding request validation filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	typesByName := make(map[string]*typepb.Type)
+	for _, t := range serviceConfig.GetTypes() {
+		typesByName[t.GetName()] = t
+	}
+	enumsByName := make(map[string]*typepb.Enum)
+	for _, e := range serviceConfig.GetEnums() {
+		enumsByName[e.GetName()] = e
+	}
+
+	requestTypeBySelector := make(map[string]*typepb.Type)
+	apiNames := GetAPINamesSetFromOPConfig(serviceConfig, opts)
+	for _, api := range serviceConfig.GetApis() {
+		if !apiNames[api.GetName()] {
+			continue
+		}
+		for _, method := range api.GetMethods() {
+			if method.GetRequestTypeUrl() == util.HttpBodyTypeUrl {
+				continue
+			}
+			if t, ok := typesByName[strings.TrimPrefix(method.GetRequestTypeUrl(), util.TypeUrlPrefix)]; ok {
+				requestTypeBySelector[MethodToSelector(api, method)] = t
+			}
+		}
+	}
+
+	rulesByRoute := make(map[string]*requestValidationRule)
+	for _, httpRule := range serviceConfig.GetHttp().GetRules() {
+		requestType, ok := requestTypeBySelector[httpRule.GetSelector()]
+		if !ok {
+			continue
+		}
+		for _, binding := range append([]*ahpb.HttpRule{httpRule}, httpRule.GetAdditionalBindings()...) {
+			httpMethod, path, ok := httpRuleMethodAndPath(binding)
+			if !ok {
+				continue
+			}
+			rule, err := makeRequestValidationRule(requestType, enumsByName, path, binding.GetBody())
+			if err != nil {
+				return nil, fmt.Errorf("fail to generate request validation for operation %q: %v", httpRule.GetSelector(), err)
+			}
+			if rule != nil {
+				rulesByRoute[requestValidationRouteKey(httpRule.GetSelector(), httpMethod, path)] = rule
+			}
+		}
+	}
+
+	return []FilterGenerator{
+		&RequestValidationGenerator{
+			RulesByRoute: rulesByRoute,
+		},
+	}, nil
+}
+
+func requestValidationRouteKey(selector string, httpMethod string, path string) string {
+	return fmt.Sprintf("%s %s %s", selector, httpMethod, path)
+}
+
+func httpRuleMethodAndPath(rule *ahpb.HttpRule) (string, string, bool) {
+	switch rule.GetPattern().(type) {
+	case *ahpb.HttpRule_Get:
+		return util.GET, rule.GetGet(), true
+	case *ahpb.HttpRule_Put:
+		return util.PUT, rule.GetPut(), true
+	case *ahpb.HttpRule_Post:
+		return util.POST, rule.GetPost(), true
+	case *ahpb.HttpRule_Delete:
+		return util.DELETE, rule.GetDelete(), true
+	case *ahpb.HttpRule_Patch:
+		return util.PATCH, rule.GetPatch(), true
+	case *ahpb.HttpRule_Custom:
+		return rule.GetCustom().GetKind(), rule.GetCustom().GetPath(), true
+	default:
+		return "", "", false
+	}
+}
+
+// makeRequestValidationRule returns the validation of the requests of the
+// HTTP rule, nil if there is nothing to validate.
+func makeRequestValidationRule(requestType *typepb.Type, enumsByName map[string]*typepb.Enum, path string, body string) (*requestValidationRule, error) {
+	uriTemplate, err := httppattern.ParseUriTemplate(path)
+	if err != nil {
+		return nil, err
+	}
+	pathFields := make(map[string]bool)
+	for _, v := range uriTemplate.Variables {
+		if len(v.FieldPath) > 0 {
+			pathFields[v.FieldPath[0]] = true
+		}
+	}
+
+	rule := &requestValidationRule{}
+	for _, field := range requestType.GetFields() {
+		if pathFields[field.GetName()] || pathFields[field.GetJsonName()] {
+			continue
+		}
+		required := field.GetCardinality() == typepb.Field_CARDINALITY_REQUIRED
+		if body != "" && (body == "*" || body == field.GetName() || body == field.GetJsonName()) {
+			rule.BodyRequired = rule.BodyRequired || required
+			continue
+		}
+
+		param := &queryParamRule{
+			Names:    []string{field.GetName()},
+			Required: required,
+		}
+		if field.GetJsonName() != "" && field.GetJsonName() != field.GetName() {
+			param.Names = append(param.Names, field.GetJsonName())
+		}
+
+		switch field.GetKind() {
+		case typepb.Field_TYPE_MESSAGE, typepb.Field_TYPE_GROUP:
+			// The fields of the nested messages are not validated.
+			continue
+		case typepb.Field_TYPE_INT32, typepb.Field_TYPE_INT64, typepb.Field_TYPE_SINT32, typepb.Field_TYPE_SINT64, typepb.Field_TYPE_SFIXED32, typepb.Field_TYPE_SFIXED64:
+			param.Kind = "integer"
+		case typepb.Field_TYPE_UINT32, typepb.Field_TYPE_UINT64, typepb.Field_TYPE_FIXED32, typepb.Field_TYPE_FIXED64:
+			param.Kind = "unsigned"
+		case typepb.Field_TYPE_FLOAT, typepb.Field_TYPE_DOUBLE:
+			param.Kind = "number"
+		case typepb.Field_TYPE_BOOL:
+			param.Kind = "bool"
+		case typepb.Field_TYPE_ENUM:
+			enum, ok := enumsByName[strings.TrimPrefix(field.GetTypeUrl(), util.TypeUrlPrefix)]
+			if !ok {
+				return nil, fmt.Errorf("enum type %q of field %q is not found", field.GetTypeUrl(), field.GetName())
+			}
+			param.Kind = "enum"
+			for _, value := range enum.GetEnumvalue() {
+				param.EnumValues = append(param.EnumValues, value.GetName())
+			}
+		}
+
+		if param.Required || param.Kind != "" {
+			rule.QueryParams = append(rule.QueryParams, param)
+		}
+	}
+
+	if len(rule.QueryParams) == 0 && !rule.BodyRequired {
+		return nil, nil
+	}
+	return rule, nil
+}
+
+func (g *RequestValidationGenerator) FilterName() string {
+	return RequestValidationFilterName
+}
+
+// GenFilterConfig generates a Lua filter without a default script, the
+// validation scripts are per route.
+func (g *RequestValidationGenerator) GenFilterConfig() (proto.Message, error) {
+	return &luapb.Lua{}, nil
+}
+
+func (g *RequestValidationGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if httpRule == nil || httpRule.UriTemplate == nil {
+		return nil, nil
+	}
+	rule, ok := g.RulesByRoute[requestValidationRouteKey(selector, httpRule.HttpMethod, httpRule.Origin)]
+	if !ok {
+		return nil, nil
+	}
+
+	return &luapb.LuaPerRoute{
+		Override: &luapb.LuaPerRoute_SourceCode{
+			SourceCode: &corepb.DataSource{
+				Specifier: &corepb.DataSource_InlineString{
+					InlineString: makeRequestValidationScript(rule),
+				},
+			},
+		},
+	}, nil
+}
+
+// makeRequestValidationScript renders the rule as Lua tables followed by the
+// script validating the requests with them.
+func makeRequestValidationScript(rule *requestValidationRule) string {
+	var b strings.Builder
+	b.WriteString("local params = {\n")
+	for _, param := range rule.QueryParams {
+		fmt.Fprintf(&b, "  {names = {%s}, required = %t, kind = %s", luaStringList(param.Names), param.Required, luaString(param.Kind))
+		if len(param.EnumValues) > 0 {
+			b.WriteString(", enum = {")
+			for i, value := range param.EnumValues {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "[%s] = true", luaString(value))
+			}
+			b.WriteString("}")
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "local body_required = %t\n", rule.BodyRequired)
+	b.WriteString(requestValidationScript)
+	return b.String()
+}
+
+func luaStringList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = luaString(value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// luaString quotes the string as a Lua string literal, escaping the
+// non-printable bytes in the decimal form supported by LuaJIT.
+func luaString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// requestValidationScript validates the requests with the tables of
+// makeRequestValidationScript. The parameter names are identifiers, so they
+// need no escaping in the JSON error messages.
+const requestValidationScript = `
+local function unescape(s)
+  s = string.gsub(s, "+", " ")
+  return (string.gsub(s, "%%(%x%x)", function(h) return string.char(tonumber(h, 16)) end))
+end
+
+local function reject(request_handle, message)
+  request_handle:respond(
+    {[":status"] = "400", ["content-type"] = "application/json"},
+    string.format('{"code":400,"message":"%s"}', message))
+end
+
+local function valid(param, value)
+  if param.kind == "integer" then
+    return string.match(value, "^-?%d+$") ~= nil
+  elseif param.kind == "unsigned" then
+    return string.match(value, "^%d+$") ~= nil
+  elseif param.kind == "number" then
+    return tonumber(value) ~= nil or value == "NaN" or value == "Infinity" or value == "-Infinity"
+  elseif param.kind == "bool" then
+    return value == "true" or value == "false"
+  elseif param.kind == "enum" then
+    return param.enum[value] ~= nil or string.match(value, "^-?%d+$") ~= nil
+  end
+  return true
+end
+
+function envoy_on_request(request_handle)
+  local path = request_handle:headers():get(":path") or ""
+  local query = {}
+  local i = string.find(path, "?", 1, true)
+  if i ~= nil then
+    for pair in string.gmatch(string.sub(path, i + 1), "[^&]+") do
+      local key, value = string.match(pair, "^([^=]*)=?(.*)$")
+      key = unescape(key)
+      query[key] = query[key] or {}
+      table.insert(query[key], unescape(value))
+    end
+  end
+
+  for _, param in ipairs(params) do
+    local values = nil
+    for _, name in ipairs(param.names) do
+      values = values or query[name]
+    end
+    if values == nil then
+      if param.required then
+        reject(request_handle, "missing required query parameter " .. param.names[1])
+        return
+      end
+    else
+      for _, value in ipairs(values) do
+        if not valid(param, value) then
+          reject(request_handle, "invalid value of query parameter " .. param.names[1])
+          return
+        end
+      end
+    end
+  end
+
+  if body_required then
+    local body = request_handle:body()
+    if body == nil or body:length() == 0 then
+      reject(request_handle, "missing required request body")
+      return
+    end
+  end
+end
+`
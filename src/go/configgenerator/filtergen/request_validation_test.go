@@ -0,0 +1,323 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	luapb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	ahpb "google.golang.org/genproto/googleapis/api/annotations"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+	typepb "google.golang.org/genproto/protobuf/ptype"
+)
+
+var requestValidationTestServiceConfig = &servicepb.Service{
+	Name: "bookstore.endpoints.project123.cloud.goog",
+	Apis: []*apipb.Api{
+		{
+			Name: "endpoints.examples.bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name:           "ListBooks",
+					RequestTypeUrl: "type.googleapis.com/ListBooksRequest",
+				},
+				{
+					Name:           "CreateBook",
+					RequestTypeUrl: "type.googleapis.com/CreateBookRequest",
+				},
+				{
+					Name:           "DeleteBook",
+					RequestTypeUrl: "type.googleapis.com/DeleteBookRequest",
+				},
+			},
+		},
+	},
+	Http: &ahpb.Http{
+		Rules: []*ahpb.HttpRule{
+			{
+				Selector: "endpoints.examples.bookstore.Bookstore.ListBooks",
+				Pattern: &ahpb.HttpRule_Get{
+					Get: "/shelves/{shelf_id}/books",
+				},
+			},
+			{
+				Selector: "endpoints.examples.bookstore.Bookstore.CreateBook",
+				Pattern: &ahpb.HttpRule_Post{
+					Post: "/shelves/{shelf_id}/books",
+				},
+				Body: "book",
+			},
+			{
+				Selector: "endpoints.examples.bookstore.Bookstore.DeleteBook",
+				Pattern: &ahpb.HttpRule_Delete{
+					Delete: "/shelves/{shelf_id}/books/{book_id}",
+				},
+			},
+		},
+	},
+	Types: []*typepb.Type{
+		{
+			Name: "ListBooksRequest",
+			Fields: []*typepb.Field{
+				{
+					Name:        "shelf_id",
+					JsonName:    "shelfId",
+					Kind:        typepb.Field_TYPE_INT64,
+					Cardinality: typepb.Field_CARDINALITY_REQUIRED,
+				},
+				{
+					Name:        "page_size",
+					JsonName:    "pageSize",
+					Kind:        typepb.Field_TYPE_UINT32,
+					Cardinality: typepb.Field_CARDINALITY_REQUIRED,
+				},
+				{
+					Name:        "genre",
+					JsonName:    "genre",
+					Kind:        typepb.Field_TYPE_ENUM,
+					Cardinality: typepb.Field_CARDINALITY_OPTIONAL,
+					TypeUrl:     "type.googleapis.com/Genre",
+				},
+				{
+					Name:        "author",
+					JsonName:    "author",
+					Kind:        typepb.Field_TYPE_STRING,
+					Cardinality: typepb.Field_CARDINALITY_OPTIONAL,
+				},
+			},
+		},
+		{
+			Name: "CreateBookRequest",
+			Fields: []*typepb.Field{
+				{
+					Name:        "shelf_id",
+					JsonName:    "shelfId",
+					Kind:        typepb.Field_TYPE_INT64,
+					Cardinality: typepb.Field_CARDINALITY_REQUIRED,
+				},
+				{
+					Name:        "book",
+					JsonName:    "book",
+					Kind:        typepb.Field_TYPE_MESSAGE,
+					Cardinality: typepb.Field_CARDINALITY_REQUIRED,
+					TypeUrl:     "type.googleapis.com/Book",
+				},
+			},
+		},
+		{
+			Name: "DeleteBookRequest",
+			Fields: []*typepb.Field{
+				{
+					Name:        "shelf_id",
+					JsonName:    "shelfId",
+					Kind:        typepb.Field_TYPE_INT64,
+					Cardinality: typepb.Field_CARDINALITY_REQUIRED,
+				},
+				{
+					Name:        "book_id",
+					JsonName:    "bookId",
+					Kind:        typepb.Field_TYPE_INT64,
+					Cardinality: typepb.Field_CARDINALITY_REQUIRED,
+				},
+			},
+		},
+	},
+	Enums: []*typepb.Enum{
+		{
+			Name: "Genre",
+			Enumvalue: []*typepb.EnumValue{
+				{
+					Name: "FICTION",
+				},
+				{
+					Name:   "HISTORY",
+					Number: 1,
+				},
+			},
+		},
+	},
+}
+
+func TestNewRequestValidationFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate Lua filter without default script",
+			ServiceConfigIn: requestValidationTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableRequestValidation: true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.request_validation",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op when request validation is disabled",
+			ServiceConfigIn: requestValidationTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewRequestValidationFilterGensFromOPConfig)
+	}
+}
+
+func TestNewRequestValidationFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name:           "ListBooks",
+						RequestTypeUrl: "type.googleapis.com/ListBooksRequest",
+					},
+				},
+			},
+		},
+		Http: &ahpb.Http{
+			Rules: []*ahpb.HttpRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListBooks",
+					Pattern: &ahpb.HttpRule_Get{
+						Get: "/books",
+					},
+				},
+			},
+		},
+		Types: []*typepb.Type{
+			{
+				Name: "ListBooksRequest",
+				Fields: []*typepb.Field{
+					{
+						Name:    "genre",
+						Kind:    typepb.Field_TYPE_ENUM,
+						TypeUrl: "type.googleapis.com/Genre",
+					},
+				},
+			},
+		},
+	}
+
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Unknown enum type",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableRequestValidation: true,
+			},
+			WantFactoryError: `fail to generate request validation for operation "endpoints.examples.bookstore.Bookstore.ListBooks": enum type "type.googleapis.com/Genre" of field "genre" is not found`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewRequestValidationFilterGensFromOPConfig)
+	}
+}
+
+func TestRequestValidationGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.EnableRequestValidation = true
+	gens, err := filtergen.NewRequestValidationFilterGensFromOPConfig(requestValidationTestServiceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewRequestValidationFilterGensFromOPConfig() got error: %v", err)
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		httpMethod string
+		path       string
+		wantTables string
+	}{
+		{
+			desc:       "Query parameters are validated, path parameters are not",
+			selector:   "endpoints.examples.bookstore.Bookstore.ListBooks",
+			httpMethod: "GET",
+			path:       "/shelves/{shelf_id}/books",
+			wantTables: `local params = {
+  {names = {"page_size", "pageSize"}, required = true, kind = "unsigned"},
+  {names = {"genre"}, required = false, kind = "enum", enum = {["FICTION"] = true, ["HISTORY"] = true}},
+}
+local body_required = false
+`,
+		},
+		{
+			desc:       "Required body field",
+			selector:   "endpoints.examples.bookstore.Bookstore.CreateBook",
+			httpMethod: "POST",
+			path:       "/shelves/{shelf_id}/books",
+			wantTables: `local params = {
+}
+local body_required = true
+`,
+		},
+		{
+			desc:       "Nothing to validate",
+			selector:   "endpoints.examples.bookstore.Bookstore.DeleteBook",
+			httpMethod: "DELETE",
+			path:       "/shelves/{shelf_id}/books/{book_id}",
+		},
+		{
+			desc:       "Route of another HTTP rule",
+			selector:   "endpoints.examples.bookstore.Bookstore.ListBooks",
+			httpMethod: "POST",
+			path:       "/endpoints.examples.bookstore.Bookstore/ListBooks",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			uriTemplate, err := httppattern.ParseUriTemplate(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := gens[0].GenPerRouteConfig(tc.selector, &httppattern.Pattern{
+				HttpMethod:  tc.httpMethod,
+				UriTemplate: uriTemplate,
+			})
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantTables == "" {
+				if got != nil {
+					t.Errorf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			script := got.(*luapb.LuaPerRoute).GetSourceCode().GetInlineString()
+			if !strings.HasPrefix(script, tc.wantTables) {
+				t.Errorf("GenPerRouteConfig() got script:\n%s\nwant prefix:\n%s", script, tc.wantTables)
+			}
+			if !strings.Contains(script, "function envoy_on_request(request_handle)") {
+				t.Errorf("GenPerRouteConfig() got script without envoy_on_request:\n%s", script)
+			}
+		})
+	}
+}
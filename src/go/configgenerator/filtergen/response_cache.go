@@ -0,0 +1,130 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	cachepb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cache/v3"
+	simplecachepb "github.com/envoyproxy/go-control-plane/envoy/extensions/http/cache/simple_http_cache/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// ResponseCacheFilterName is the Envoy filter name for debug logging.
+	ResponseCacheFilterName = "envoy.filters.http.cache"
+)
+
+// ResponseCacheGenerator caches the responses of the GET requests in the
+// memory of Envoy, as allowed by their Cache-Control headers.
+//
+// The requests with the Authorization header are never served from the
+// cache, as required by RFC 7234. The cache is keyed by the URL only, so the
+// operations requiring a JWT or an API key must not have a TTL.
+type ResponseCacheGenerator struct {
+	// MaxBodyBytes is the largest response body cached, 0 if unlimited.
+	MaxBodyBytes uint32
+
+	NoopFilterGenerator
+}
+
+// NewResponseCacheFilterGensFromOPConfig creates a ResponseCacheGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewResponseCacheFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableResponseCache {
+		if opts.OperationResponseCacheTtl != "" {
+			return nil, fmt.Errorf("invalid flag --operation_response_cache_ttl, it requires flag --enable_response_cache")
+		}
+		glog.Info("Not adding response cache filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+	if opts.ResponseCacheMaxBodyBytes < 0 {
+		return nil, fmt.Errorf("invalid flag --response_cache_max_body_bytes %d, must be >= 0", opts.ResponseCacheMaxBodyBytes)
+	}
+	if err := validateResponseCacheTtls(serviceConfig, opts); err != nil {
+		return nil, err
+	}
+
+	return []FilterGenerator{
+		&ResponseCacheGenerator{
+			MaxBodyBytes: uint32(opts.ResponseCacheMaxBodyBytes),
+		},
+	}, nil
+}
+
+// validateResponseCacheTtls rejects the TTLs of the operations requiring a JWT
+// or an API key, whose responses would be served to the other callers from
+// the cache.
+func validateResponseCacheTtls(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) error {
+	if opts.OperationResponseCacheTtl == "" {
+		return nil
+	}
+	opTtls, err := util.ParseSelectorMap(opts.OperationResponseCacheTtl)
+	if err != nil {
+		return fmt.Errorf("invalid flag --operation_response_cache_ttl: %v", err)
+	}
+
+	authRequiredSelectors, err := GetAuthRequiredSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return err
+	}
+	usageRulesBySelector := GetUsageRulesBySelectorFromOPConfig(serviceConfig, opts)
+	apiKeyChecked := !opts.SkipServiceControlFilter && serviceConfig.GetControl().GetEnvironment() != ""
+
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			value, ok := opTtls.Lookup(selector)
+			if !ok {
+				continue
+			}
+			// The invalid TTLs are reported by the route generator.
+			if ttl, err := time.ParseDuration(value); err != nil || ttl <= 0 {
+				continue
+			}
+
+			if authRequiredSelectors[selector] {
+				return fmt.Errorf("invalid flag --operation_response_cache_ttl, operation %q requires a JWT, its responses cannot be shared by the callers", selector)
+			}
+			usageRule := usageRulesBySelector[selector]
+			if apiKeyChecked && !usageRule.GetSkipServiceControl() && !usageRule.GetAllowUnregisteredCalls() {
+				return fmt.Errorf("invalid flag --operation_response_cache_ttl, operation %q requires an API key, its responses cannot be shared by the callers", selector)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *ResponseCacheGenerator) FilterName() string {
+	return ResponseCacheFilterName
+}
+
+func (g *ResponseCacheGenerator) GenFilterConfig() (proto.Message, error) {
+	storage, err := anypb.New(&simplecachepb.SimpleHttpCacheConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachepb.CacheConfig{
+		TypedConfig:  storage,
+		MaxBodyBytes: g.MaxBodyBytes,
+	}, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func TestNewResponseCacheFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Disabled by default",
+			ServiceConfigIn: &servicepb.Service{},
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc:            "Responses are cached in memory",
+			ServiceConfigIn: &servicepb.Service{},
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCache: true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.cache",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.cache.v3.CacheConfig",
+      "typedConfig":{
+         "@type":"type.googleapis.com/envoy.extensions.http.cache.simple_http_cache.v3.SimpleHttpCacheConfig"
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Large response bodies are not cached",
+			ServiceConfigIn: &servicepb.Service{},
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCache:       true,
+				ResponseCacheMaxBodyBytes: 65536,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.cache",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.cache.v3.CacheConfig",
+      "typedConfig":{
+         "@type":"type.googleapis.com/envoy.extensions.http.cache.simple_http_cache.v3.SimpleHttpCacheConfig"
+      },
+      "maxBodyBytes":65536
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Operations allowing unregistered calls may have a TTL",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name:    "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{{Name: "ListShelves"}},
+					},
+				},
+				Control: &servicepb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+				Usage: &servicepb.Usage{
+					Rules: []*servicepb.UsageRule{
+						{
+							Selector:               "endpoints.examples.bookstore.Bookstore.ListShelves",
+							AllowUnregisteredCalls: true,
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCache:       true,
+				OperationResponseCacheTtl: "*=60s",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.cache",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.cache.v3.CacheConfig",
+      "typedConfig":{
+         "@type":"type.googleapis.com/envoy.extensions.http.cache.simple_http_cache.v3.SimpleHttpCacheConfig"
+      }
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewResponseCacheFilterGensFromOPConfig)
+	}
+}
+
+func TestNewResponseCacheFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Operation TTLs require the response cache",
+			ServiceConfigIn: &servicepb.Service{},
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationResponseCacheTtl: "*=60s",
+			},
+			WantFactoryError: "invalid flag --operation_response_cache_ttl, it requires flag --enable_response_cache",
+		},
+		{
+			Desc:            "Negative max body bytes",
+			ServiceConfigIn: &servicepb.Service{},
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCache:       true,
+				ResponseCacheMaxBodyBytes: -1,
+			},
+			WantFactoryError: "invalid flag --response_cache_max_body_bytes -1, must be >= 0",
+		},
+		{
+			Desc: "Operations requiring a JWT cannot have a TTL",
+			ServiceConfigIn: &servicepb.Service{
+				Apis: []*apipb.Api{
+					{
+						Name:    "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{{Name: "ListShelves"}},
+					},
+				},
+				Authentication: &servicepb.Authentication{
+					Rules: []*servicepb.AuthenticationRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+							Requirements: []*servicepb.AuthRequirement{
+								{ProviderId: "auth0"},
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCache:       true,
+				OperationResponseCacheTtl: "*=60s",
+			},
+			WantFactoryError: `invalid flag --operation_response_cache_ttl, operation "endpoints.examples.bookstore.Bookstore.ListShelves" requires a JWT`,
+		},
+		{
+			Desc: "Operations requiring an API key cannot have a TTL",
+			ServiceConfigIn: &servicepb.Service{
+				Apis: []*apipb.Api{
+					{
+						Name:    "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{{Name: "ListShelves"}},
+					},
+				},
+				Control: &servicepb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCache:       true,
+				OperationResponseCacheTtl: "endpoints.examples.bookstore.Bookstore.ListShelves=60s",
+			},
+			WantFactoryError: `invalid flag --operation_response_cache_ttl, operation "endpoints.examples.bookstore.Bookstore.ListShelves" requires an API key`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewResponseCacheFilterGensFromOPConfig)
+	}
+}
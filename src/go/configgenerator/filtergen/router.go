@@ -38,7 +38,9 @@ type RouterGenerator struct {
 func NewRouterFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
 	return []FilterGenerator{
 		&RouterGenerator{
-			SuppressEnvoyHeaders: opts.SuppressEnvoyHeaders,
+			// The router filter sets the header x-envoy-expected-rq-timeout-ms
+			// only if the x-envoy- headers are not suppressed.
+			SuppressEnvoyHeaders: opts.SuppressEnvoyHeaders && !opts.PropagateRequestTimeout,
 			StartChildSpan:       !opts.CommonOptions.TracingOptions.DisableTracing,
 		},
 	}, nil
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -73,12 +74,19 @@ type ServiceControlGenerator struct {
 	MinStreamReportIntervalMs   uint64
 	ComputePlatformOverride     string
 
+	// DeploymentLabels are attached to the reports.
+	DeploymentLabels map[string]string
+
 	// Service control configs.
 	MethodRequirements       []*scpb.Requirement
 	CallingConfig            *scpb.ServiceControlCallingConfig
 	GCPAttributes            *scpb.GcpAttributes
 	EnableApiKeyUidReporting bool
 
+	// MaxConcurrentRequestsPerConsumer is the max in-flight requests of each
+	// API key, 0 if unlimited.
+	MaxConcurrentRequestsPerConsumer uint32
+
 	NoopFilterGenerator
 }
 
@@ -116,32 +124,43 @@ func NewServiceControlFilterGensFromOPConfig(serviceConfig *confpb.Service, opts
 		return nil, err
 	}
 
+	deploymentLabels, err := util.ParseDeploymentLabels(opts.DeploymentLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ConsumerMaxConcurrentRequests < 0 {
+		return nil, fmt.Errorf("invalid flag --consumer_max_concurrent_requests %d, must be >= 0", opts.ConsumerMaxConcurrentRequests)
+	}
+
 	return []FilterGenerator{
 		&ServiceControlGenerator{
-			ServiceName:                 serviceConfig.GetName(),
-			ServiceConfigID:             serviceConfig.GetId(),
-			ProducerProjectID:           serviceConfig.GetProducerProjectId(),
-			ServiceConfig:               serviceConfig,
-			GRPCSupportRequired:         grpcSupportRequired,
-			ServiceControlURI:           scURL,
-			CallCredentials:             opts.ServiceControlCredentials,
-			AccessToken:                 helpers.NewFilterAccessTokenConfigerFromOPConfig(opts),
-			DisableTracing:              opts.CommonOptions.TracingOptions.DisableTracing,
-			TracingProjectID:            opts.CommonOptions.TracingOptions.ProjectId,
-			HttpRequestTimeout:          opts.HttpRequestTimeout,
-			GeneratedHeaderPrefix:       opts.GeneratedHeaderPrefix,
-			IAMURL:                      opts.IamURL,
-			DependencyErrorBehavior:     opts.DependencyErrorBehavior,
-			ClientIPFromForwardedHeader: opts.ClientIPFromForwardedHeader,
-			LogRequestHeaders:           opts.LogRequestHeaders,
-			LogResponseHeaders:          opts.LogResponseHeaders,
-			LogJwtPayloads:              opts.LogJwtPayloads,
-			MinStreamReportIntervalMs:   opts.MinStreamReportIntervalMs,
-			ComputePlatformOverride:     opts.ComputePlatformOverride,
-			MethodRequirements:          requirements,
-			CallingConfig:               MakeSCCallingConfigFromOPConfig(opts),
-			GCPAttributes:               params.GCPAttributes,
-			EnableApiKeyUidReporting:    opts.ServiceControlEnableApiKeyUidReporting,
+			ServiceName:                      serviceConfig.GetName(),
+			ServiceConfigID:                  serviceConfig.GetId(),
+			ProducerProjectID:                serviceConfig.GetProducerProjectId(),
+			ServiceConfig:                    serviceConfig,
+			GRPCSupportRequired:              grpcSupportRequired,
+			ServiceControlURI:                scURL,
+			CallCredentials:                  opts.ServiceControlCredentials,
+			AccessToken:                      helpers.NewFilterAccessTokenConfigerFromOPConfig(opts),
+			DisableTracing:                   opts.CommonOptions.TracingOptions.DisableTracing,
+			TracingProjectID:                 opts.CommonOptions.TracingOptions.ProjectId,
+			HttpRequestTimeout:               opts.HttpRequestTimeout,
+			GeneratedHeaderPrefix:            opts.GeneratedHeaderPrefix,
+			IAMURL:                           opts.IamURL,
+			DependencyErrorBehavior:          opts.DependencyErrorBehavior,
+			ClientIPFromForwardedHeader:      opts.ClientIPFromForwardedHeader,
+			LogRequestHeaders:                opts.LogRequestHeaders,
+			LogResponseHeaders:               opts.LogResponseHeaders,
+			LogJwtPayloads:                   opts.LogJwtPayloads,
+			MinStreamReportIntervalMs:        opts.MinStreamReportIntervalMs,
+			ComputePlatformOverride:          opts.ComputePlatformOverride,
+			DeploymentLabels:                 deploymentLabels,
+			MethodRequirements:               requirements,
+			CallingConfig:                    MakeSCCallingConfigFromOPConfig(opts),
+			GCPAttributes:                    params.GCPAttributes,
+			EnableApiKeyUidReporting:         opts.ServiceControlEnableApiKeyUidReporting,
+			MaxConcurrentRequestsPerConsumer: uint32(opts.ConsumerMaxConcurrentRequests),
 		},
 	}, nil
 }
@@ -150,6 +169,39 @@ func (g *ServiceControlGenerator) FilterName() string {
 	return ServiceControlFilterName
 }
 
+// CustomEnvoyFeatures reports the Service Control API features of the
+// operations, none of them is supported by stock Envoy.
+func (g *ServiceControlGenerator) CustomEnvoyFeatures() []string {
+	var checked, apiKeys, quotas, concurrencyLimits int
+	for _, requirement := range g.MethodRequirements {
+		if requirement.GetSkipServiceControl() {
+			continue
+		}
+		checked++
+		if !requirement.GetApiKey().GetAllowWithoutApiKey() {
+			apiKeys++
+		}
+		if len(requirement.GetMetricCosts()) > 0 {
+			quotas++
+		}
+		if requirement.GetMaxConcurrentRequestsPerConsumer() != nil {
+			concurrencyLimits++
+		}
+	}
+
+	features := []string{fmt.Sprintf("Service Control check and report for %d operations", checked)}
+	if apiKeys > 0 {
+		features = append(features, fmt.Sprintf("API key validation for %d operations", apiKeys))
+	}
+	if quotas > 0 {
+		features = append(features, fmt.Sprintf("quota enforcement for %d operations", quotas))
+	}
+	if g.MaxConcurrentRequestsPerConsumer > 0 || concurrencyLimits > 0 {
+		features = append(features, "concurrency limits per API key (--consumer_max_concurrent_requests)")
+	}
+	return features
+}
+
 func (g *ServiceControlGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
 	return &scpb.PerRouteFilterConfig{
 		OperationName: selector,
@@ -208,9 +260,10 @@ func (g *ServiceControlGenerator) GenFilterConfig() (proto.Message, error) {
 			Cluster: clustergen.ServiceControlClusterName,
 			Timeout: durationpb.New(g.HttpRequestTimeout),
 		},
-		GeneratedHeaderPrefix:    g.GeneratedHeaderPrefix,
-		Requirements:             g.MethodRequirements,
-		EnableApiKeyUidReporting: g.EnableApiKeyUidReporting,
+		GeneratedHeaderPrefix:            g.GeneratedHeaderPrefix,
+		Requirements:                     g.MethodRequirements,
+		EnableApiKeyUidReporting:         g.EnableApiKeyUidReporting,
+		MaxConcurrentRequestsPerConsumer: g.MaxConcurrentRequestsPerConsumer,
 	}
 
 	accessTokenConfig := g.AccessToken.MakeAccessTokenConfig()
@@ -244,6 +297,12 @@ func (g *ServiceControlGenerator) GenFilterConfig() (proto.Message, error) {
 		}
 		filterConfig.GcpAttributes.Platform = g.ComputePlatformOverride
 	}
+	if len(g.DeploymentLabels) > 0 {
+		if filterConfig.GcpAttributes == nil {
+			filterConfig.GcpAttributes = &scpb.GcpAttributes{}
+		}
+		filterConfig.GcpAttributes.DeploymentLabels = g.DeploymentLabels
+	}
 
 	depErrorBehaviorEnum, err := ParseDepErrorBehavior(g.DependencyErrorBehavior)
 	if err != nil {
@@ -278,6 +337,7 @@ func MakeSCCallingConfigFromOPConfig(opts options.ConfigGeneratorOptions) *scpb.
 	if opts.ScReportRetries > -1 {
 		setting.ReportRetries = &wrapperspb.UInt32Value{Value: uint32(opts.ScReportRetries)}
 	}
+	setting.CompressReports = opts.ScCompressReports
 	return setting
 }
 
@@ -349,6 +409,21 @@ func MakeMethodRequirementsFromOPConfig(serviceConfig *confpb.Service, opts opti
 		requirements = append(requirements, healthzRequirement)
 	}
 
+	defaultBackendRequirement := GetDefaultBackendRequirementFromOPConfig(serviceConfig, opts)
+	if defaultBackendRequirement != nil {
+		requirements = append(requirements, defaultBackendRequirement)
+	}
+
+	staticPathRequirement := GetStaticPathRequirementFromOPConfig(serviceConfig, opts)
+	if staticPathRequirement != nil {
+		requirements = append(requirements, staticPathRequirement)
+	}
+
+	grpcReflectionRequirement := GetGrpcReflectionRequirementFromOPConfig(serviceConfig, opts)
+	if grpcReflectionRequirement != nil {
+		requirements = append(requirements, grpcReflectionRequirement)
+	}
+
 	return requirements, nil
 }
 
@@ -356,6 +431,14 @@ func GetQuotaAndUsageRequirementsFromOPConfig(serviceConfig *confpb.Service, opt
 	var requirements []*scpb.Requirement
 
 	metricCostsBySelector := GetQuotaMetricCostsFromOPConfig(serviceConfig, opts)
+	metricCostOverrides, err := ParseQuotaMetricCostOverridesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	maxConcurrentRequestsOverrides, err := util.ParseSelectorMap(opts.OperationConsumerMaxConcurrentRequests)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_consumer_max_concurrent_requests: %v", err)
+	}
 	usageRulesBySelector := GetUsageRulesBySelectorFromOPConfig(serviceConfig, opts)
 	apiKeySystemParamsBySelector := GetAPIKeySystemParametersBySelectorFromOPConfig(serviceConfig, opts)
 
@@ -378,6 +461,18 @@ func GetQuotaAndUsageRequirementsFromOPConfig(serviceConfig *confpb.Service, opt
 			if ok {
 				requirement.MetricCosts = metricCosts
 			}
+			if overrides, ok := metricCostOverrides.Lookup(selector); ok {
+				// Already validated by ParseQuotaMetricCostOverridesFromOPConfig.
+				costs, _ := parseMetricCosts(overrides)
+				requirement.MetricCosts = overrideMetricCosts(requirement.MetricCosts, costs)
+			}
+			if value, ok := maxConcurrentRequestsOverrides.Lookup(selector); ok {
+				maxConcurrentRequests, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("invalid flag --operation_consumer_max_concurrent_requests, %q for operation %q must be a non-negative number", value, selector)
+				}
+				requirement.MaxConcurrentRequestsPerConsumer = wrapperspb.UInt32(uint32(maxConcurrentRequests))
+			}
 
 			if usageRule, ok := usageRulesBySelector[selector]; ok {
 				requirement.SkipServiceControl = usageRule.GetSkipServiceControl()
@@ -395,6 +490,7 @@ func GetQuotaAndUsageRequirementsFromOPConfig(serviceConfig *confpb.Service, opt
 				}
 				requirement.ApiKey.Locations = ExtractAPIKeyLocations(apiKeySystemParams)
 			}
+			addAuthorizationAPIKeyLocations(requirement, opts)
 
 			requirements = append(requirements, requirement)
 		}
@@ -434,6 +530,89 @@ func GetQuotaMetricCostsFromOPConfig(serviceConfig *confpb.Service, opts options
 	return metricCostsBySelector
 }
 
+// ParseQuotaMetricCostOverridesFromOPConfig parses the quota metric costs of
+// flag --operation_quota_metric_costs, validating the metrics are used by the
+// quota limits.
+func ParseQuotaMetricCostOverridesFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (*util.SelectorMap, error) {
+	overrides, err := util.ParseSelectorMap(opts.OperationQuotaMetricCosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_quota_metric_costs: %v", err)
+	}
+
+	limitMetrics := make(map[string]bool)
+	for _, limit := range serviceConfig.GetQuota().GetLimits() {
+		limitMetrics[limit.GetMetric()] = true
+	}
+	for _, value := range overrides.Values() {
+		costs, err := parseMetricCosts(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag --operation_quota_metric_costs: %v", err)
+		}
+		for name := range costs {
+			if !limitMetrics[name] {
+				return nil, fmt.Errorf("invalid flag --operation_quota_metric_costs, metric %q is not used by any quota limit of the service config", name)
+			}
+		}
+	}
+	return overrides, nil
+}
+
+// parseMetricCosts parses the metric costs in the format of
+// "metric1:cost1,metric2:cost2".
+func parseMetricCosts(s string) (map[string]int64, error) {
+	costs := make(map[string]int64)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("metric cost %q must be in the format of metric:cost", pair)
+		}
+		name, costStr := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if name == "" {
+			return nil, fmt.Errorf("metric cost %q has an empty metric", pair)
+		}
+		cost, err := strconv.ParseInt(costStr, 10, 64)
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("metric cost %q must have an integer cost >= 0", pair)
+		}
+		costs[name] = cost
+	}
+	return costs, nil
+}
+
+// overrideMetricCosts returns the metric costs with the costs of the overridden
+// metrics replaced, sorted by the metric names. The metrics of cost 0 are
+// removed.
+func overrideMetricCosts(metricCosts []*scpb.MetricCost, overrides map[string]int64) []*scpb.MetricCost {
+	costs := make(map[string]int64)
+	for _, metricCost := range metricCosts {
+		costs[metricCost.GetName()] = metricCost.GetCost()
+	}
+	for name, cost := range overrides {
+		costs[name] = cost
+	}
+
+	var result []*scpb.MetricCost
+	for name, cost := range costs {
+		if cost == 0 {
+			continue
+		}
+		result = append(result, &scpb.MetricCost{
+			Name: name,
+			Cost: cost,
+		})
+	}
+
+	// To keep tests from breaking due to map ordering.
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result
+}
+
 // GetAutoGeneratedCORSRequirementsFromOPConfig returns the Service Control requirements
 // for all auto-generated CORS methods (if enabled).
 //
@@ -492,6 +671,98 @@ func GetHealthzRequirementFromOPConfig(serviceConfig *confpb.Service, opts optio
 	}
 }
 
+// DefaultBackendOperationName is the synthetic operation of the requests
+// matching no operation, which are proxied to the default backend.
+var DefaultBackendOperationName = fmt.Sprintf("%s.%s_DefaultBackend", util.EspOperation, util.AutogeneratedOperationPrefix)
+
+// GetDefaultBackendRequirementFromOPConfig returns the Service Control
+// requirement for the requests proxied to the default backend (if enabled).
+// They are reported, but not checked as no API key is required.
+func GetDefaultBackendRequirementFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) *scpb.Requirement {
+	if opts.DefaultBackendAddress == "" {
+		return nil
+	}
+
+	return &scpb.Requirement{
+		ServiceName:   serviceConfig.GetName(),
+		OperationName: DefaultBackendOperationName,
+		ApiName:       util.EspOperation,
+		ApiKey: &scpb.ApiKeyRequirement{
+			AllowWithoutApiKey: true,
+		},
+	}
+}
+
+// StaticPathOperationName is the synthetic operation of the requests to the
+// static paths, which are answered directly by Envoy.
+var StaticPathOperationName = fmt.Sprintf("%s.%s_StaticPath", util.EspOperation, util.AutogeneratedOperationPrefix)
+
+// GetStaticPathRequirementFromOPConfig returns the Service Control requirement
+// for the requests to the static paths (if enabled). They are neither checked
+// nor reported.
+func GetStaticPathRequirementFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) *scpb.Requirement {
+	if opts.StaticPaths == "" {
+		return nil
+	}
+
+	return &scpb.Requirement{
+		ServiceName:        serviceConfig.GetName(),
+		OperationName:      StaticPathOperationName,
+		ApiName:            util.EspOperation,
+		SkipServiceControl: true,
+		ApiKey: &scpb.ApiKeyRequirement{
+			AllowWithoutApiKey: true,
+		},
+	}
+}
+
+// addAuthorizationAPIKeyLocations adds the API key locations in the
+// Authorization header enabled by the options after the locations of the
+// requirement, which default to the default locations of the Service Control
+// filter.
+func addAuthorizationAPIKeyLocations(requirement *scpb.Requirement, opts options.ConfigGeneratorOptions) {
+	if !opts.ApiKeyFromBasicAuthUsername && opts.ApiKeyBearerTokenPrefix == "" {
+		return
+	}
+
+	if requirement.ApiKey == nil {
+		requirement.ApiKey = &scpb.ApiKeyRequirement{}
+	}
+	if len(requirement.ApiKey.Locations) == 0 {
+		requirement.ApiKey.Locations = []*scpb.ApiKeyLocation{
+			{
+				Key: &scpb.ApiKeyLocation_Query{
+					Query: "key",
+				},
+			},
+			{
+				Key: &scpb.ApiKeyLocation_Query{
+					Query: "api_key",
+				},
+			},
+			{
+				Key: &scpb.ApiKeyLocation_Header{
+					Header: "x-api-key",
+				},
+			},
+		}
+	}
+	if opts.ApiKeyFromBasicAuthUsername {
+		requirement.ApiKey.Locations = append(requirement.ApiKey.Locations, &scpb.ApiKeyLocation{
+			Key: &scpb.ApiKeyLocation_BasicAuthUsername{
+				BasicAuthUsername: true,
+			},
+		})
+	}
+	if opts.ApiKeyBearerTokenPrefix != "" {
+		requirement.ApiKey.Locations = append(requirement.ApiKey.Locations, &scpb.ApiKeyLocation{
+			Key: &scpb.ApiKeyLocation_BearerTokenPrefix{
+				BearerTokenPrefix: opts.ApiKeyBearerTokenPrefix,
+			},
+		})
+	}
+}
+
 // ExtractAPIKeyLocations extracts the locations of API Keys from the system parameters
 // into the corresponding SC filter config proto.
 //
@@ -31,6 +31,7 @@ import (
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	apipb "google.golang.org/genproto/protobuf/api"
 	"google.golang.org/protobuf/testing/protocmp"
+	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestNewServiceControlFilterGensFromOPConfig_GenConfig(t *testing.T) {
@@ -237,10 +238,13 @@ func TestNewServiceControlFilterGensFromOPConfig_GenConfig(t *testing.T) {
 					LogJwtPayloads:                         "my-payload",
 					MinStreamReportIntervalMs:              8000,
 					ComputePlatformOverride:                "ESPv2(Cloud Run)",
+					DeploymentLabels:                       "cloud_run_service=bookstore;cloud_run_revision=bookstore-00001",
 					ScCheckTimeoutMs:                       5020,
 					ScQuotaRetries:                         8,
+					ScCompressReports:                      true,
 					ServiceControlNetworkFailOpen:          false,
 					ServiceControlEnableApiKeyUidReporting: false,
+					ConsumerMaxConcurrentRequests:          16,
 				},
 				WantFilterConfigs: []string{`
 {
@@ -250,6 +254,10 @@ func TestNewServiceControlFilterGensFromOPConfig_GenConfig(t *testing.T) {
       "depErrorBehavior":"ALWAYS_INIT",
       "generatedHeaderPrefix":"X-Test-Header-",
       "gcpAttributes":{
+         "deploymentLabels":{
+            "cloud_run_revision":"bookstore-00001",
+            "cloud_run_service":"bookstore"
+         },
          "platform":"ESPv2(Cloud Run)",
          "projectId":"cloudesf-tenant-project",
          "zone":"us-central1c"
@@ -259,8 +267,10 @@ func TestNewServiceControlFilterGensFromOPConfig_GenConfig(t *testing.T) {
          "timeout":"120s",
          "uri":"http://169.254.169.254/computeMetadata/v1/instance/service-accounts/default/token"
       },
+      "maxConcurrentRequestsPerConsumer":16,
       "scCallingConfig":{
          "checkTimeoutMs":5020,
+         "compressReports":true,
          "networkFailOpen":true,
          "quotaRetries":8
       },
@@ -737,6 +747,144 @@ func TestMakeMethodRequirementsFromOPConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "Methods with quota metric cost overrides",
+			serviceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Id:   "2019-03-02r0",
+				Control: &servicepb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+				Apis: []*apipb.Api{
+					{
+						Name:    "google.library.Bookstore",
+						Version: "2.0.0",
+						Methods: []*apipb.Method{
+							{
+								Name: "GetShelves",
+							},
+							{
+								Name: "GetBooks",
+							},
+							{
+								Name: "CreateBook",
+							},
+						},
+					},
+				},
+				Quota: &servicepb.Quota{
+					Limits: []*servicepb.QuotaLimit{
+						{
+							Name:   "limit_a",
+							Metric: "metric_a",
+						},
+						{
+							Name:   "limit_b",
+							Metric: "metric_b",
+						},
+					},
+					MetricRules: []*servicepb.MetricRule{
+						{
+							Selector: "google.library.Bookstore.GetBooks",
+							MetricCosts: map[string]int64{
+								"metric_a": 2,
+								"metric_b": 1,
+							},
+						},
+						{
+							Selector: "google.library.Bookstore.CreateBook",
+							MetricCosts: map[string]int64{
+								"metric_a": 1,
+							},
+						},
+					},
+				},
+			},
+			optsIn: options.ConfigGeneratorOptions{
+				OperationQuotaMetricCosts: "google.library.Bookstore.GetBooks=metric_a:5,metric_b:0;google.library.Bookstore.Get*=metric_b:3",
+			},
+			wantRequirements: []*scpb.Requirement{
+				{
+					ServiceName:   "bookstore.endpoints.project123.cloud.goog",
+					OperationName: "google.library.Bookstore.GetShelves",
+					ApiName:       "google.library.Bookstore",
+					ApiVersion:    "2.0.0",
+					MetricCosts: []*scpb.MetricCost{
+						{
+							Name: "metric_b",
+							Cost: 3,
+						},
+					},
+				},
+				{
+					ServiceName:   "bookstore.endpoints.project123.cloud.goog",
+					OperationName: "google.library.Bookstore.GetBooks",
+					ApiName:       "google.library.Bookstore",
+					ApiVersion:    "2.0.0",
+					MetricCosts: []*scpb.MetricCost{
+						{
+							Name: "metric_a",
+							Cost: 5,
+						},
+					},
+				},
+				{
+					ServiceName:   "bookstore.endpoints.project123.cloud.goog",
+					OperationName: "google.library.Bookstore.CreateBook",
+					ApiName:       "google.library.Bookstore",
+					ApiVersion:    "2.0.0",
+					MetricCosts: []*scpb.MetricCost{
+						{
+							Name: "metric_a",
+							Cost: 1,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Methods with max concurrent requests overrides",
+			serviceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Id:   "2019-03-02r0",
+				Control: &servicepb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+				Apis: []*apipb.Api{
+					{
+						Name:    "google.library.Bookstore",
+						Version: "2.0.0",
+						Methods: []*apipb.Method{
+							{
+								Name: "GetShelves",
+							},
+							{
+								Name: "CreateBook",
+							},
+						},
+					},
+				},
+			},
+			optsIn: options.ConfigGeneratorOptions{
+				OperationConsumerMaxConcurrentRequests: "google.library.Bookstore.Create*=2;google.library.Bookstore.GetShelves=0",
+			},
+			wantRequirements: []*scpb.Requirement{
+				{
+					ServiceName:                      "bookstore.endpoints.project123.cloud.goog",
+					OperationName:                    "google.library.Bookstore.GetShelves",
+					ApiName:                          "google.library.Bookstore",
+					ApiVersion:                       "2.0.0",
+					MaxConcurrentRequestsPerConsumer: wrapperspb.UInt32(0),
+				},
+				{
+					ServiceName:                      "bookstore.endpoints.project123.cloud.goog",
+					OperationName:                    "google.library.Bookstore.CreateBook",
+					ApiName:                          "google.library.Bookstore",
+					ApiVersion:                       "2.0.0",
+					MaxConcurrentRequestsPerConsumer: wrapperspb.UInt32(2),
+				},
+			},
+		},
 		{
 			desc: "Methods with usage rules",
 			serviceConfigIn: &servicepb.Service{
@@ -999,6 +1147,150 @@ func TestMakeMethodRequirementsFromOPConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "Methods with static paths",
+			serviceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Id:   "2019-03-02r0",
+				Control: &servicepb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+				Apis: []*apipb.Api{
+					{
+						Name:    "google.library.Bookstore",
+						Version: "2.0.0",
+						Methods: []*apipb.Method{
+							{
+								Name: "GetShelves",
+							},
+						},
+					},
+				},
+			},
+			optsIn: options.ConfigGeneratorOptions{
+				StaticPaths: `{"/robots.txt": {"body": "User-agent: *"}}`,
+			},
+			wantRequirements: []*scpb.Requirement{
+				{
+					ServiceName:   "bookstore.endpoints.project123.cloud.goog",
+					OperationName: "google.library.Bookstore.GetShelves",
+					ApiName:       "google.library.Bookstore",
+					ApiVersion:    "2.0.0",
+				},
+				{
+					ServiceName:        "bookstore.endpoints.project123.cloud.goog",
+					OperationName:      "espv2_deployment.ESPv2_Autogenerated_StaticPath",
+					ApiName:            "espv2_deployment",
+					SkipServiceControl: true,
+					ApiKey: &scpb.ApiKeyRequirement{
+						AllowWithoutApiKey: true,
+					},
+				},
+			},
+		},
+		{
+			desc: "Methods with API keys in the Authorization header",
+			serviceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Id:   "2019-03-02r0",
+				Control: &servicepb.Control{
+					Environment: "servicecontrol.googleapis.com",
+				},
+				Apis: []*apipb.Api{
+					{
+						Name:    "google.library.Bookstore",
+						Version: "2.0.0",
+						Methods: []*apipb.Method{
+							{
+								Name: "GetShelves",
+							},
+							{
+								Name: "GetBooks",
+							},
+						},
+					},
+				},
+				SystemParameters: &servicepb.SystemParameters{
+					Rules: []*servicepb.SystemParameterRule{
+						{
+							Selector: "google.library.Bookstore.GetBooks",
+							Parameters: []*servicepb.SystemParameter{
+								{
+									Name:       "api_key",
+									HttpHeader: "x-key",
+								},
+							},
+						},
+					},
+				},
+			},
+			optsIn: options.ConfigGeneratorOptions{
+				ApiKeyFromBasicAuthUsername: true,
+				ApiKeyBearerTokenPrefix:     "AIza",
+			},
+			wantRequirements: []*scpb.Requirement{
+				{
+					ServiceName:   "bookstore.endpoints.project123.cloud.goog",
+					OperationName: "google.library.Bookstore.GetShelves",
+					ApiName:       "google.library.Bookstore",
+					ApiVersion:    "2.0.0",
+					ApiKey: &scpb.ApiKeyRequirement{
+						Locations: []*scpb.ApiKeyLocation{
+							{
+								Key: &scpb.ApiKeyLocation_Query{
+									Query: "key",
+								},
+							},
+							{
+								Key: &scpb.ApiKeyLocation_Query{
+									Query: "api_key",
+								},
+							},
+							{
+								Key: &scpb.ApiKeyLocation_Header{
+									Header: "x-api-key",
+								},
+							},
+							{
+								Key: &scpb.ApiKeyLocation_BasicAuthUsername{
+									BasicAuthUsername: true,
+								},
+							},
+							{
+								Key: &scpb.ApiKeyLocation_BearerTokenPrefix{
+									BearerTokenPrefix: "AIza",
+								},
+							},
+						},
+					},
+				},
+				{
+					ServiceName:   "bookstore.endpoints.project123.cloud.goog",
+					OperationName: "google.library.Bookstore.GetBooks",
+					ApiName:       "google.library.Bookstore",
+					ApiVersion:    "2.0.0",
+					ApiKey: &scpb.ApiKeyRequirement{
+						Locations: []*scpb.ApiKeyLocation{
+							{
+								Key: &scpb.ApiKeyLocation_Header{
+									Header: "x-key",
+								},
+							},
+							{
+								Key: &scpb.ApiKeyLocation_BasicAuthUsername{
+									BasicAuthUsername: true,
+								},
+							},
+							{
+								Key: &scpb.ApiKeyLocation_BearerTokenPrefix{
+									BearerTokenPrefix: "AIza",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testdata {
@@ -1014,3 +1306,68 @@ func TestMakeMethodRequirementsFromOPConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeMethodRequirementsFromOPConfig_BadInput(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "google.library.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "GetBooks",
+					},
+				},
+			},
+		},
+		Quota: &servicepb.Quota{
+			Limits: []*servicepb.QuotaLimit{
+				{
+					Name:   "limit_a",
+					Metric: "metric_a",
+				},
+			},
+		},
+	}
+
+	testdata := []struct {
+		desc                                   string
+		operationQuotaMetricCosts              string
+		operationConsumerMaxConcurrentRequests string
+		wantError                              string
+	}{
+		{
+			desc:                      "missing cost",
+			operationQuotaMetricCosts: "*.GetBooks=metric_a",
+			wantError:                 `metric cost "metric_a" must be in the format of metric:cost`,
+		},
+		{
+			desc:                      "negative cost",
+			operationQuotaMetricCosts: "*.GetBooks=metric_a:-1",
+			wantError:                 `metric cost "metric_a:-1" must have an integer cost >= 0`,
+		},
+		{
+			desc:                      "unknown metric",
+			operationQuotaMetricCosts: "*.GetBooks=metric_b:1",
+			wantError:                 `metric "metric_b" is not used by any quota limit of the service config`,
+		},
+		{
+			desc:                                   "negative max concurrent requests",
+			operationConsumerMaxConcurrentRequests: "*.GetBooks=-1",
+			wantError:                              `invalid flag --operation_consumer_max_concurrent_requests, "-1" for operation "google.library.Bookstore.GetBooks" must be a non-negative number`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.ConfigGeneratorOptions{
+				OperationQuotaMetricCosts:              tc.operationQuotaMetricCosts,
+				OperationConsumerMaxConcurrentRequests: tc.operationConsumerMaxConcurrentRequests,
+			}
+			_, err := filtergen.MakeMethodRequirementsFromOPConfig(serviceConfig, opts)
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("MakeMethodRequirementsFromOPConfig() got error %v, want error containing %q", err, tc.wantError)
+			}
+		})
+	}
+}
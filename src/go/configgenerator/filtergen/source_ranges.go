@@ -0,0 +1,87 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacconfigpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	networkrbacpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+)
+
+const (
+	// SourceRangesFilterName is the Envoy network filter closing the
+	// downstream connections from outside the allowed source ranges.
+	SourceRangesFilterName = "envoy.filters.network.rbac"
+
+	sourceRangesStatPrefix = "ingress_source_ranges"
+	sourceRangesPolicyName = "source_ranges"
+)
+
+// MakeSourceRangesNetworkFilter creates the network filter closing the
+// connections of the ingress listener from the peers outside flag
+// --allowed_source_ranges, nil if it is not set.
+//
+// The peer is the direct remote address of the connection, so the connections
+// are rejected before any request is read, regardless of the x-forwarded-for
+// header.
+func MakeSourceRangesNetworkFilter(opts options.ConfigGeneratorOptions) (*listenerpb.Filter, error) {
+	ranges, err := util.ParseCIDRRanges(opts.AllowedSourceRanges)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --allowed_source_ranges: %v", err)
+	}
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+
+	var ids []*rbacconfigpb.Principal
+	for _, r := range ranges {
+		ids = append(ids, &rbacconfigpb.Principal{
+			Identifier: &rbacconfigpb.Principal_DirectRemoteIp{
+				DirectRemoteIp: r,
+			},
+		})
+	}
+
+	return FilterConfigToNetworkFilter(&networkrbacpb.RBAC{
+		StatPrefix: sourceRangesStatPrefix,
+		Rules: &rbacconfigpb.RBAC{
+			Action: rbacconfigpb.RBAC_ALLOW,
+			Policies: map[string]*rbacconfigpb.Policy{
+				sourceRangesPolicyName: {
+					Permissions: []*rbacconfigpb.Permission{
+						{
+							Rule: &rbacconfigpb.Permission_Any{
+								Any: true,
+							},
+						},
+					},
+					Principals: []*rbacconfigpb.Principal{
+						{
+							Identifier: &rbacconfigpb.Principal_OrIds{
+								OrIds: &rbacconfigpb.Principal_Set{
+									Ids: ids,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, SourceRangesFilterName)
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/config/common/matcher/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tapconfigpb "github.com/envoyproxy/go-control-plane/envoy/config/tap/v3"
+	commontappb "github.com/envoyproxy/go-control-plane/envoy/extensions/common/tap/v3"
+	tappb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/tap/v3"
+	stringmatcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// TapFilterName is the Envoy filter name for debug logging.
+	TapFilterName = "envoy.filters.http.tap"
+
+	// queryStringRegex matches the optional query string of the :path header.
+	queryStringRegex = `(\?.*)?`
+)
+
+// TapGenerator captures the requests and the responses with the Envoy tap
+// filter.
+//
+// The tap filter has no per-route config, so the requests of the operations
+// are matched by their HTTP methods and paths, the same as the routes.
+type TapGenerator struct {
+	// HTTPPatternsBySelector are the HTTP patterns of the tapped operations.
+	// Empty if the tap is configured from the Envoy admin.
+	HTTPPatternsBySelector map[string][]*httppattern.Pattern
+
+	OutputPathPrefix                   string
+	AdminConfigId                      string
+	MaxBufferedBytes                   uint32
+	DisallowColonInWildcardPathSegment bool
+
+	NoopFilterGenerator
+}
+
+// NewTapFilterGensFromOPConfig creates a TapGenerator from OP service config
+// + ESPv2 options, with the HTTP patterns of the operations parsed as the
+// routes.
+func NewTapFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions, httpPatternsBySelector map[string][]*httppattern.Pattern) ([]FilterGenerator, error) {
+	if opts.OperationTapEnabled == "" && opts.TapAdminConfigId == "" {
+		if opts.TapOutputPathPrefix != "" {
+			return nil, fmt.Errorf("invalid flag --tap_output_path_prefix, it requires flag --operation_tap_enabled")
+		}
+		glog.Info("Not adding tap filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+	if opts.OperationTapEnabled != "" && opts.TapAdminConfigId != "" {
+		return nil, fmt.Errorf("flags --operation_tap_enabled and --tap_admin_config_id cannot be used together")
+	}
+	if opts.TapMaxBufferedBytes < 0 {
+		return nil, fmt.Errorf("invalid flag --tap_max_buffered_bytes %d, must be >= 0", opts.TapMaxBufferedBytes)
+	}
+
+	gen := &TapGenerator{
+		OutputPathPrefix:                   opts.TapOutputPathPrefix,
+		AdminConfigId:                      opts.TapAdminConfigId,
+		MaxBufferedBytes:                   uint32(opts.TapMaxBufferedBytes),
+		DisallowColonInWildcardPathSegment: opts.DisallowColonInWildcardPathSegment,
+	}
+	if opts.TapAdminConfigId != "" {
+		return []FilterGenerator{gen}, nil
+	}
+
+	if opts.TapOutputPathPrefix == "" {
+		return nil, fmt.Errorf("flag --operation_tap_enabled requires flag --tap_output_path_prefix")
+	}
+	opEnabled, err := util.ParseSelectorMap(opts.OperationTapEnabled)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_tap_enabled: %v", err)
+	}
+
+	gen.HTTPPatternsBySelector = make(map[string][]*httppattern.Pattern)
+	for selector, httpPatterns := range httpPatternsBySelector {
+		value, ok := opEnabled.Lookup(selector)
+		if !ok {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag --operation_tap_enabled, %q for operation %q must be true or false", value, selector)
+		}
+		if enabled {
+			gen.HTTPPatternsBySelector[selector] = httpPatterns
+		}
+	}
+	if len(gen.HTTPPatternsBySelector) == 0 {
+		glog.Warningf("Not adding tap filter gen because flag --operation_tap_enabled does not enable any operation.")
+		return nil, nil
+	}
+	return []FilterGenerator{gen}, nil
+}
+
+func (g *TapGenerator) FilterName() string {
+	return TapFilterName
+}
+
+func (g *TapGenerator) GenFilterConfig() (proto.Message, error) {
+	if g.AdminConfigId != "" {
+		return &tappb.Tap{
+			CommonConfig: &commontappb.CommonExtensionConfig{
+				ConfigType: &commontappb.CommonExtensionConfig_AdminConfig{
+					AdminConfig: &commontappb.AdminConfig{
+						ConfigId: g.AdminConfigId,
+					},
+				},
+			},
+		}, nil
+	}
+
+	outputConfig := &tapconfigpb.OutputConfig{
+		Sinks: []*tapconfigpb.OutputSink{
+			{
+				OutputSinkType: &tapconfigpb.OutputSink_FilePerTap{
+					FilePerTap: &tapconfigpb.FilePerTapSink{
+						PathPrefix: g.OutputPathPrefix,
+					},
+				},
+			},
+		},
+	}
+	if g.MaxBufferedBytes > 0 {
+		outputConfig.MaxBufferedRxBytes = wrapperspb.UInt32(g.MaxBufferedBytes)
+		outputConfig.MaxBufferedTxBytes = wrapperspb.UInt32(g.MaxBufferedBytes)
+	}
+
+	return &tappb.Tap{
+		CommonConfig: &commontappb.CommonExtensionConfig{
+			ConfigType: &commontappb.CommonExtensionConfig_StaticConfig{
+				StaticConfig: &tapconfigpb.TapConfig{
+					Match:        g.makeOperationsMatch(),
+					OutputConfig: outputConfig,
+				},
+			},
+		},
+	}, nil
+}
+
+// makeOperationsMatch matches the requests of any of the HTTP patterns of the
+// tapped operations, sorted so the config is stable.
+func (g *TapGenerator) makeOperationsMatch() *matcherpb.MatchPredicate {
+	var selectors []string
+	for selector := range g.HTTPPatternsBySelector {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	var rules []*matcherpb.MatchPredicate
+	for _, selector := range selectors {
+		for _, httpPattern := range g.HTTPPatternsBySelector[selector] {
+			rules = append(rules, &matcherpb.MatchPredicate{
+				Rule: &matcherpb.MatchPredicate_HttpRequestHeadersMatch{
+					HttpRequestHeadersMatch: &matcherpb.HttpHeadersMatch{
+						Headers: g.makeHTTPPatternHeaderMatchers(httpPattern),
+					},
+				},
+			})
+		}
+	}
+
+	if len(rules) == 1 {
+		return rules[0]
+	}
+	return &matcherpb.MatchPredicate{
+		Rule: &matcherpb.MatchPredicate_OrMatch{
+			OrMatch: &matcherpb.MatchPredicate_MatchSet{
+				Rules: rules,
+			},
+		},
+	}
+}
+
+// makeHTTPPatternHeaderMatchers matches the :method and the :path headers of
+// the HTTP pattern. The :path header has the query string, unlike the path
+// matched by the routes.
+func (g *TapGenerator) makeHTTPPatternHeaderMatchers(httpPattern *httppattern.Pattern) []*routepb.HeaderMatcher {
+	pathRegex := strings.TrimSuffix(httpPattern.UriTemplate.Regex(g.DisallowColonInWildcardPathSegment), "$") + queryStringRegex + "$"
+	headers := []*routepb.HeaderMatcher{
+		{
+			Name: ":path",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &stringmatcherpb.StringMatcher{
+					MatchPattern: &stringmatcherpb.StringMatcher_SafeRegex{
+						SafeRegex: &stringmatcherpb.RegexMatcher{
+							Regex: pathRegex,
+						},
+					},
+				},
+			},
+		},
+	}
+	if httpPattern.HttpMethod != httppattern.HttpMethodWildCard {
+		headers = append(headers, &routepb.HeaderMatcher{
+			Name: ":method",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &stringmatcherpb.StringMatcher{
+					MatchPattern: &stringmatcherpb.StringMatcher_Exact{
+						Exact: httpPattern.HttpMethod,
+					},
+				},
+			},
+		})
+	}
+	return headers
+}
@@ -0,0 +1,302 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var tapTestServiceConfig = &servicepb.Service{
+	Name: "bookstore.endpoints.project123.cloud.goog",
+	Apis: []*apipb.Api{
+		{
+			Name: "endpoints.examples.bookstore.Bookstore",
+			Methods: []*apipb.Method{
+				{
+					Name: "Echo",
+				},
+				{
+					Name: "GetShelf",
+				},
+			},
+		},
+	},
+	Http: &annotationspb.Http{
+		Rules: []*annotationspb.HttpRule{
+			{
+				Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+				Pattern: &annotationspb.HttpRule_Post{
+					Post: "/echo",
+				},
+			},
+			{
+				Selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+				Pattern: &annotationspb.HttpRule_Get{
+					Get: "/shelves/{shelf}",
+				},
+			},
+		},
+	},
+}
+
+// newTapFilterGensFromOPConfig parses the HTTP patterns the same as the
+// filter generator factory of configgenerator.
+func newTapFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
+	var httpPatternsBySelector map[string][]*httppattern.Pattern
+	if opts.OperationTapEnabled != "" {
+		var err error
+		if httpPatternsBySelector, err = routegen.ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig, opts); err != nil {
+			return nil, err
+		}
+	}
+	return filtergen.NewTapFilterGensFromOPConfig(serviceConfig, opts, httpPatternsBySelector)
+}
+
+func TestNewTapFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Disabled by default",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn:          options.ConfigGeneratorOptions{},
+		},
+		{
+			Desc:            "Not added if no operation is enabled",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationTapEnabled: "endpoints.examples.bookstore.Bookstore.*=false",
+				TapOutputPathPrefix: "/tmp/espv2_tap",
+			},
+		},
+		{
+			Desc:            "Operations are captured to the files",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationTapEnabled: "endpoints.examples.bookstore.Bookstore.Echo=false;endpoints.examples.bookstore.Bookstore.*=true",
+				TapOutputPathPrefix: "/tmp/espv2_tap",
+				TapMaxBufferedBytes: 4096,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.tap",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.tap.v3.Tap",
+      "commonConfig":{
+         "staticConfig":{
+            "match":{
+               "httpRequestHeadersMatch":{
+                  "headers":[
+                     {
+                        "name":":path",
+                        "stringMatch":{
+                           "safeRegex":{
+                              "regex":"^/shelves/[^\\/]+\\/?(\\?.*)?$"
+                           }
+                        }
+                     },
+                     {
+                        "name":":method",
+                        "stringMatch":{
+                           "exact":"GET"
+                        }
+                     }
+                  ]
+               }
+            },
+            "outputConfig":{
+               "sinks":[
+                  {
+                     "filePerTap":{
+                        "pathPrefix":"/tmp/espv2_tap"
+                     }
+                  }
+               ],
+               "maxBufferedRxBytes":4096,
+               "maxBufferedTxBytes":4096
+            }
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Multiple operations are matched by any of their HTTP patterns",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationTapEnabled: "endpoints.examples.bookstore.Bookstore.*=true",
+				TapOutputPathPrefix: "/tmp/espv2_tap",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.tap",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.tap.v3.Tap",
+      "commonConfig":{
+         "staticConfig":{
+            "match":{
+               "orMatch":{
+                  "rules":[
+                     {
+                        "httpRequestHeadersMatch":{
+                           "headers":[
+                              {
+                                 "name":":path",
+                                 "stringMatch":{
+                                    "safeRegex":{
+                                       "regex":"^/echo\\/?(\\?.*)?$"
+                                    }
+                                 }
+                              },
+                              {
+                                 "name":":method",
+                                 "stringMatch":{
+                                    "exact":"POST"
+                                 }
+                              }
+                           ]
+                        }
+                     },
+                     {
+                        "httpRequestHeadersMatch":{
+                           "headers":[
+                              {
+                                 "name":":path",
+                                 "stringMatch":{
+                                    "safeRegex":{
+                                       "regex":"^/shelves/[^\\/]+\\/?(\\?.*)?$"
+                                    }
+                                 }
+                              },
+                              {
+                                 "name":":method",
+                                 "stringMatch":{
+                                    "exact":"GET"
+                                 }
+                              }
+                           ]
+                        }
+                     }
+                  ]
+               }
+            },
+            "outputConfig":{
+               "sinks":[
+                  {
+                     "filePerTap":{
+                        "pathPrefix":"/tmp/espv2_tap"
+                     }
+                  }
+               ]
+            }
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Configured from the Envoy admin",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				TapAdminConfigId: "espv2",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.tap",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.tap.v3.Tap",
+      "commonConfig":{
+         "adminConfig":{
+            "configId":"espv2"
+         }
+      }
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, newTapFilterGensFromOPConfig)
+	}
+}
+
+func TestNewTapFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Operations and admin cannot be used together",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationTapEnabled: "*=true",
+				TapOutputPathPrefix: "/tmp/espv2_tap",
+				TapAdminConfigId:    "espv2",
+			},
+			WantFactoryError: "flags --operation_tap_enabled and --tap_admin_config_id cannot be used together",
+		},
+		{
+			Desc:            "Operations require the output path prefix",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationTapEnabled: "*=true",
+			},
+			WantFactoryError: "flag --operation_tap_enabled requires flag --tap_output_path_prefix",
+		},
+		{
+			Desc:            "Output path prefix requires the operations",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				TapOutputPathPrefix: "/tmp/espv2_tap",
+			},
+			WantFactoryError: "invalid flag --tap_output_path_prefix, it requires flag --operation_tap_enabled",
+		},
+		{
+			Desc:            "Invalid operation value",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				OperationTapEnabled: "endpoints.examples.bookstore.Bookstore.Echo=yes",
+				TapOutputPathPrefix: "/tmp/espv2_tap",
+			},
+			WantFactoryError: `invalid flag --operation_tap_enabled, "yes" for operation "endpoints.examples.bookstore.Bookstore.Echo" must be true or false`,
+		},
+		{
+			Desc:            "Negative max buffered bytes",
+			ServiceConfigIn: tapTestServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				TapAdminConfigId:    "espv2",
+				TapMaxBufferedBytes: -1,
+			},
+			WantFactoryError: "invalid flag --tap_max_buffered_bytes -1, must be >= 0",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, newTapFilterGensFromOPConfig)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	luapb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// TenantSanitizerFilterName is the Envoy filter name for debug logging.
+	TenantSanitizerFilterName = "com.google.espv2.filters.http.tenant_sanitizer"
+
+	// TenantRoutingFilterName is the Envoy filter name for debug logging.
+	TenantRoutingFilterName = "com.google.espv2.filters.http.tenant_routing"
+
+	// TenantClusterHeader is the internal request header holding the backend
+	// cluster of the tenant the request is routed to.
+	TenantClusterHeader = "x-espv2-tenant-cluster"
+
+	// The suffix of the header set by the Service Control filter with the
+	// project number of the API consumer.
+	consumerNumberHeaderSuffix = "api-consumer-number"
+
+	jwtAuthnFilterMetadataNamespace = "envoy.filters.http.jwt_authn"
+)
+
+// TenantSanitizerGenerator removes the client-supplied headers used by
+// tenant and consumer routing and the consumer rate limits, so the backend
+// and the quota cannot be chosen by the clients. It runs before the
+// authentication filters.
+type TenantSanitizerGenerator struct {
+	ConsumerNumberHeader string
+
+	NoopFilterGenerator
+}
+
+// TenantRoutingGenerator chooses the backend cluster of the tenant from the
+// consumer number set by the Service Control filter or the claim of the JWT
+// verified by the JWT authn filter. It runs right before the router.
+type TenantRoutingGenerator struct {
+	ConsumerNumberHeader    string
+	LocalBackendClusterName string
+	Mapping                 *clustergen.TenantMapping
+
+	// HostByTenant is the hostname of the backend of the tenant.
+	HostByTenant map[string]string
+
+	NoopFilterGenerator
+}
+
+// NewTenantSanitizerFilterGensFromOPConfig creates a TenantSanitizerGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewTenantSanitizerFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	mapping, err := clustergen.ParseTenantMappingFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	consumerBackends, err := clustergen.ParseConsumerBackendsFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil && consumerBackends == nil && len(opts.ConsumerQuotaLimits) == 0 {
+		glog.Info("Not adding tenant sanitizer filter gen because there is no tenant mapping, consumer backend or consumer quota limit.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&TenantSanitizerGenerator{
+			ConsumerNumberHeader: opts.GeneratedHeaderPrefix + consumerNumberHeaderSuffix,
+		},
+	}, nil
+}
+
+// NewTenantRoutingFilterGensFromOPConfig creates a TenantRoutingGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewTenantRoutingFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	mapping, err := clustergen.ParseTenantMappingFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		glog.Info("Not adding tenant routing filter gen because there is no tenant mapping.")
+		return nil, nil
+	}
+
+	hostByTenant := make(map[string]string)
+	for _, tenant := range mapping.Tenants {
+		_, hostname, _, _, err := util.ParseURI(tenant.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of tenant %q: %v", tenant.Name, err)
+		}
+		hostByTenant[tenant.Name] = hostname
+	}
+
+	return []FilterGenerator{
+		&TenantRoutingGenerator{
+			ConsumerNumberHeader:    opts.GeneratedHeaderPrefix + consumerNumberHeaderSuffix,
+			LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
+			Mapping:                 mapping,
+			HostByTenant:            hostByTenant,
+		},
+	}, nil
+}
+
+func (g *TenantSanitizerGenerator) FilterName() string {
+	return TenantSanitizerFilterName
+}
+
+func (g *TenantSanitizerGenerator) GenFilterConfig() (proto.Message, error) {
+	script := fmt.Sprintf(`function envoy_on_request(request_handle)
+  request_handle:headers():remove(%s)
+  request_handle:headers():remove(%s)
+end
+`, luaQuote(strings.ToLower(g.ConsumerNumberHeader)), luaQuote(TenantClusterHeader))
+	return makeInlineLua(script), nil
+}
+
+func (g *TenantRoutingGenerator) FilterName() string {
+	return TenantRoutingFilterName
+}
+
+func (g *TenantRoutingGenerator) GenFilterConfig() (proto.Message, error) {
+	clusterByConsumer := make(map[string]string)
+	clusterByClaim := make(map[string]string)
+	hostByCluster := make(map[string]string)
+	for _, tenant := range g.Mapping.Tenants {
+		cluster := clustergen.TenantClusterName(tenant.Name)
+		for _, consumer := range tenant.ConsumerNumbers {
+			clusterByConsumer[consumer] = cluster
+		}
+		for _, value := range tenant.JwtClaimValues {
+			clusterByClaim[value] = cluster
+		}
+		hostByCluster[cluster] = g.HostByTenant[tenant.Name]
+	}
+
+	script := fmt.Sprintf(`local cluster_by_consumer = %s
+local cluster_by_claim = %s
+local host_by_cluster = %s
+
+function envoy_on_request(request_handle)
+  local headers = request_handle:headers()
+  local cluster = cluster_by_consumer[headers:get(%s) or ""]
+  if cluster == nil and %s ~= "" then
+    local metadata = request_handle:streamInfo():dynamicMetadata():get(%s)
+    local payload = metadata and metadata[%s]
+    local value = payload and payload[%s]
+    if type(value) == "string" then
+      cluster = cluster_by_claim[value]
+    end
+  end
+  if cluster == nil then
+    headers:replace(%s, %s)
+  else
+    headers:replace(%s, cluster)
+    headers:replace(":authority", host_by_cluster[cluster])
+  end
+  request_handle:clearRouteCache()
+end
+`,
+		luaTable(clusterByConsumer),
+		luaTable(clusterByClaim),
+		luaTable(hostByCluster),
+		luaQuote(strings.ToLower(g.ConsumerNumberHeader)),
+		luaQuote(g.Mapping.JwtClaim),
+		luaQuote(jwtAuthnFilterMetadataNamespace),
+		luaQuote(util.JwtPayloadMetadataName),
+		luaQuote(g.Mapping.JwtClaim),
+		luaQuote(TenantClusterHeader),
+		luaQuote(g.LocalBackendClusterName),
+		luaQuote(TenantClusterHeader))
+	return makeInlineLua(script), nil
+}
+
+func makeInlineLua(script string) *luapb.Lua {
+	return &luapb.Lua{
+		DefaultSourceCode: &corepb.DataSource{
+			Specifier: &corepb.DataSource_InlineString{
+				InlineString: script,
+			},
+		},
+	}
+}
+
+// luaTable returns the Lua table constructor of the string map, sorted by
+// the keys so the generated script is stable.
+func luaTable(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("{")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n  [%s] = %s,", luaQuote(k), luaQuote(m[k]))
+	}
+	if len(keys) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// luaQuote returns the Lua string literal of the string. The bytes other than
+// the printable ASCII are written as decimal escapes, which all Lua versions
+// support.
+func luaQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
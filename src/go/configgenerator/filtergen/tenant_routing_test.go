@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	luapb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	"github.com/google/go-cmp/cmp"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+var tenantRoutingTestOpts = options.ConfigGeneratorOptions{
+	CommonOptions: options.CommonOptions{
+		GeneratedHeaderPrefix: "X-Endpoint-",
+	},
+	TenantMapping: `
+{
+  "jwtClaim": "org",
+  "tenants": [
+    {"name": "acme", "backendAddress": "https://acme.example.com", "consumerNumbers": ["123", "456"]},
+    {"name": "globex", "backendAddress": "http://globex.internal:8080", "jwtClaimValues": ["globex\"inc"]}
+  ]
+}`,
+}
+
+func TestTenantRoutingFilterGens_GenFilterConfig(t *testing.T) {
+	testData := []struct {
+		desc       string
+		factory    filtergen.FilterGeneratorOPFactory
+		wantName   string
+		wantScript string
+	}{
+		{
+			desc:     "Sanitizer removes the client-supplied headers",
+			factory:  filtergen.NewTenantSanitizerFilterGensFromOPConfig,
+			wantName: "com.google.espv2.filters.http.tenant_sanitizer",
+			wantScript: `function envoy_on_request(request_handle)
+  request_handle:headers():remove("x-endpoint-api-consumer-number")
+  request_handle:headers():remove("x-espv2-tenant-cluster")
+end
+`,
+		},
+		{
+			desc:     "Routing chooses the tenant cluster",
+			factory:  filtergen.NewTenantRoutingFilterGensFromOPConfig,
+			wantName: "com.google.espv2.filters.http.tenant_routing",
+			wantScript: `local cluster_by_consumer = {
+  ["123"] = "backend-cluster-tenant-acme",
+  ["456"] = "backend-cluster-tenant-acme",
+}
+local cluster_by_claim = {
+  ["globex\"inc"] = "backend-cluster-tenant-globex",
+}
+local host_by_cluster = {
+  ["backend-cluster-tenant-acme"] = "acme.example.com",
+  ["backend-cluster-tenant-globex"] = "globex.internal",
+}
+
+function envoy_on_request(request_handle)
+  local headers = request_handle:headers()
+  local cluster = cluster_by_consumer[headers:get("x-endpoint-api-consumer-number") or ""]
+  if cluster == nil and "org" ~= "" then
+    local metadata = request_handle:streamInfo():dynamicMetadata():get("envoy.filters.http.jwt_authn")
+    local payload = metadata and metadata["jwt_payloads"]
+    local value = payload and payload["org"]
+    if type(value) == "string" then
+      cluster = cluster_by_claim[value]
+    end
+  end
+  if cluster == nil then
+    headers:replace("x-espv2-tenant-cluster", "backend-cluster-bookstore.endpoints.project123.cloud.goog_local")
+  else
+    headers:replace("x-espv2-tenant-cluster", cluster)
+    headers:replace(":authority", host_by_cluster[cluster])
+  end
+  request_handle:clearRouteCache()
+end
+`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			gens, err := tc.factory(&servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			}, tenantRoutingTestOpts)
+			if err != nil {
+				t.Fatalf("factory got error: %v", err)
+			}
+			if len(gens) != 1 {
+				t.Fatalf("want 1 filter gen, got %v", len(gens))
+			}
+			if got := gens[0].FilterName(); got != tc.wantName {
+				t.Errorf("want filter name %q, got %q", tc.wantName, got)
+			}
+
+			config, err := gens[0].GenFilterConfig()
+			if err != nil {
+				t.Fatalf("GenFilterConfig() got error: %v", err)
+			}
+			gotScript := config.(*luapb.Lua).GetDefaultSourceCode().GetInlineString()
+			if diff := cmp.Diff(tc.wantScript, gotScript); diff != "" {
+				t.Errorf("GenFilterConfig() script diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTenantRoutingFilterGens_Disabled(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:   "No-op without tenant mapping",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewTenantSanitizerFilterGensFromOPConfig)
+		tc.RunTest(t, filtergen.NewTenantRoutingFilterGensFromOPConfig)
+	}
+}
+
+func TestNewTenantRoutingFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Both mapping file and metadata attribute",
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMappingFile: "/etc/espv2/tenants.json",
+				TenantMapping:     `{"tenants": []}`,
+			},
+			WantFactoryError: "flag --tenant_mapping_file cannot be used with flag --tenant_mapping_metadata_attribute",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewTenantRoutingFilterGensFromOPConfig)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	wasmfilterpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	wasmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// WasmFilterName is the Envoy filter name for debug logging.
+	WasmFilterName = "envoy.filters.http.wasm"
+
+	wasmPluginName  = "espv2_wasm"
+	wasmRuntime     = "envoy.wasm.runtime.v8"
+	wasmHttpsPrefix = "https://"
+
+	// wasmFetchTimeout is the timeout to fetch the remote WASM filter module.
+	wasmFetchTimeout = 10 * time.Second
+)
+
+// WasmGenerator loads a user-provided WASM HTTP filter module.
+type WasmGenerator struct {
+	// URI is the local file path or HTTPS URL of the module.
+	URI string
+	// Sha256 is the checksum of the remote module.
+	Sha256 string
+	// Config is the optional configuration passed to the module.
+	Config string
+
+	NoopFilterGenerator
+}
+
+// NewWasmFilterGensFromOPConfig creates a WasmGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewWasmFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.WasmFilterURI == "" {
+		glog.Info("Not adding WASM filter gen because no WASM filter module is provided.")
+		return nil, nil
+	}
+
+	if strings.Contains(opts.WasmFilterURI, "://") && !strings.HasPrefix(opts.WasmFilterURI, wasmHttpsPrefix) {
+		return nil, fmt.Errorf("invalid flag --wasm_filter_uri, %q should be a local file path or a HTTPS URL", opts.WasmFilterURI)
+	}
+	if strings.HasPrefix(opts.WasmFilterURI, wasmHttpsPrefix) && opts.WasmFilterSha256 == "" {
+		return nil, fmt.Errorf("flag --wasm_filter_sha256 is required for the HTTPS URL of flag --wasm_filter_uri")
+	}
+
+	return []FilterGenerator{
+		&WasmGenerator{
+			URI:    opts.WasmFilterURI,
+			Sha256: opts.WasmFilterSha256,
+			Config: opts.WasmFilterConfig,
+		},
+	}, nil
+}
+
+func (g *WasmGenerator) FilterName() string {
+	return WasmFilterName
+}
+
+func (g *WasmGenerator) GenFilterConfig() (proto.Message, error) {
+	code := &corepb.AsyncDataSource{
+		Specifier: &corepb.AsyncDataSource_Local{
+			Local: &corepb.DataSource{
+				Specifier: &corepb.DataSource_Filename{
+					Filename: g.URI,
+				},
+			},
+		},
+	}
+	if strings.HasPrefix(g.URI, wasmHttpsPrefix) {
+		code = &corepb.AsyncDataSource{
+			Specifier: &corepb.AsyncDataSource_Remote{
+				Remote: &corepb.RemoteDataSource{
+					HttpUri: &corepb.HttpUri{
+						Uri: g.URI,
+						HttpUpstreamType: &corepb.HttpUri_Cluster{
+							Cluster: clustergen.WasmClusterName,
+						},
+						Timeout: durationpb.New(wasmFetchTimeout),
+					},
+					Sha256: g.Sha256,
+				},
+			},
+		}
+	}
+
+	pluginConfig := &wasmpb.PluginConfig{
+		Name: wasmPluginName,
+		Vm: &wasmpb.PluginConfig_VmConfig{
+			VmConfig: &wasmpb.VmConfig{
+				VmId:    wasmPluginName,
+				Runtime: wasmRuntime,
+				Code:    code,
+			},
+		},
+	}
+	if g.Config != "" {
+		configuration, err := anypb.New(wrapperspb.String(g.Config))
+		if err != nil {
+			return nil, err
+		}
+		pluginConfig.Configuration = configuration
+	}
+
+	return &wasmfilterpb.Wasm{
+		Config: pluginConfig,
+	}, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewWasmFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc: "Generate with local WASM filter module",
+			OptsIn: options.ConfigGeneratorOptions{
+				WasmFilterURI:    "/etc/espv2/filter.wasm",
+				WasmFilterConfig: `{"header":"x-team"}`,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.wasm",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm",
+      "config":{
+         "configuration":{
+            "@type":"type.googleapis.com/google.protobuf.StringValue",
+            "value":"{\"header\":\"x-team\"}"
+         },
+         "name":"espv2_wasm",
+         "vmConfig":{
+            "code":{
+               "local":{
+                  "filename":"/etc/espv2/filter.wasm"
+               }
+            },
+            "runtime":"envoy.wasm.runtime.v8",
+            "vmId":"espv2_wasm"
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate with remote WASM filter module",
+			OptsIn: options.ConfigGeneratorOptions{
+				WasmFilterURI:    "https://example.com/filter.wasm",
+				WasmFilterSha256: "abc123",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.wasm",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm",
+      "config":{
+         "name":"espv2_wasm",
+         "vmConfig":{
+            "code":{
+               "remote":{
+                  "httpUri":{
+                     "cluster":"wasm-cluster",
+                     "timeout":"10s",
+                     "uri":"https://example.com/filter.wasm"
+                  },
+                  "sha256":"abc123"
+               }
+            },
+            "runtime":"envoy.wasm.runtime.v8",
+            "vmId":"espv2_wasm"
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:   "No-op without WASM filter module",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewWasmFilterGensFromOPConfig)
+	}
+}
+
+func TestNewWasmFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "HTTP URL is not supported",
+			OptsIn: options.ConfigGeneratorOptions{
+				WasmFilterURI: "http://example.com/filter.wasm",
+			},
+			WantFactoryError: `invalid flag --wasm_filter_uri, "http://example.com/filter.wasm" should be a local file path or a HTTPS URL`,
+		},
+		{
+			Desc: "HTTPS URL without checksum",
+			OptsIn: options.ConfigGeneratorOptions{
+				WasmFilterURI: "https://example.com/filter.wasm",
+			},
+			WantFactoryError: "flag --wasm_filter_sha256 is required",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewWasmFilterGensFromOPConfig)
+	}
+}
@@ -16,7 +16,10 @@ package configgenerator
 
 import (
 	"fmt"
+	"net/http"
+	"sort"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
 	sc "github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
@@ -24,11 +27,22 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	routerpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	tlsinspectorpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
 	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/anypb"
 	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+const (
+	tcpProxyFilterName     = "envoy.filters.network.tcp_proxy"
+	tlsInspectorFilterName = "envoy.filters.listener.tls_inspector"
+)
+
 // MakeListeners provides dynamic listeners for Envoy
 func MakeListeners(serviceInfo *sc.ServiceInfo, scParams filtergen.ServiceControlOPFactoryParams) ([]*listenerpb.Listener, error) {
 	filterGenFactories := MakeHTTPFilterGenFactories(scParams)
@@ -52,7 +66,30 @@ func MakeListeners(serviceInfo *sc.ServiceInfo, scParams filtergen.ServiceContro
 	if err != nil {
 		return nil, err
 	}
-	return []*listenerpb.Listener{listener}, nil
+	listeners := []*listenerpb.Listener{listener}
+
+	if serviceInfo.Options.HttpRedirectPort != 0 {
+		redirectListener, err := MakeHttpRedirectListener(serviceInfo.Options)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, redirectListener)
+	}
+
+	if serviceInfo.Options.HealthPort != 0 {
+		healthCheckListener, err := MakeHealthCheckListener(serviceInfo.ServiceConfig(), serviceInfo.Options)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, healthCheckListener)
+	}
+
+	tcpProxyListeners, err := MakeTcpProxyListeners(serviceInfo.Options)
+	if err != nil {
+		return nil, err
+	}
+	listeners = append(listeners, tcpProxyListeners...)
+	return listeners, nil
 }
 
 // MakeHttpFilterConfigs generates all enabled HTTP filter configs and returns them (ordered list).
@@ -120,11 +157,28 @@ func MakeListener(opts options.ConfigGeneratorOptions, httpFilterGenerators []fi
 		return nil, err
 	}
 
-	filterChain := &listenerpb.FilterChain{
-		Filters: []*listenerpb.Filter{
-			networkFilterConfig,
-		},
+	filterChain := &listenerpb.FilterChain{}
+
+	// Source ranges filter is the first so the connections from outside the
+	// allowed ranges are neither counted by the rate limit nor processed.
+	sourceRangesFilterConfig, err := filtergen.MakeSourceRangesNetworkFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+	if sourceRangesFilterConfig != nil {
+		filterChain.Filters = append(filterChain.Filters, sourceRangesFilterConfig)
+	}
+
+	// Connection rate limit filter is before the HCM so the connections are
+	// closed before any request is processed.
+	rateLimitFilterConfig, err := filtergen.MakeConnectionRateLimitNetworkFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+	if rateLimitFilterConfig != nil {
+		filterChain.Filters = append(filterChain.Filters, rateLimitFilterConfig)
 	}
+	filterChain.Filters = append(filterChain.Filters, networkFilterConfig)
 
 	if opts.SslServerCertPath != "" {
 		transportSocket, err := util.CreateDownstreamTransportSocket(
@@ -163,3 +217,267 @@ func MakeListener(opts options.ConfigGeneratorOptions, httpFilterGenerators []fi
 
 	return listener, nil
 }
+
+var redirectResponseCodes = map[int]routepb.RedirectAction_RedirectResponseCode{
+	301: routepb.RedirectAction_MOVED_PERMANENTLY,
+	302: routepb.RedirectAction_FOUND,
+	303: routepb.RedirectAction_SEE_OTHER,
+	307: routepb.RedirectAction_TEMPORARY_REDIRECT,
+	308: routepb.RedirectAction_PERMANENT_REDIRECT,
+}
+
+// MakeHttpRedirectListener provides a plain HTTP listener for Envoy, which
+// redirects all the requests to the HTTPS ingress listener.
+func MakeHttpRedirectListener(opts options.ConfigGeneratorOptions) (*listenerpb.Listener, error) {
+	if opts.SslServerCertPath == "" {
+		return nil, fmt.Errorf("flag --http_redirect_port requires flag --ssl_server_cert_path")
+	}
+	if opts.HttpRedirectPort == opts.ListenerPort {
+		return nil, fmt.Errorf("flag --http_redirect_port must be different from flag --listener_port, got %v", opts.HttpRedirectPort)
+	}
+	responseCode, ok := redirectResponseCodes[opts.HttpRedirectResponseCode]
+	if !ok {
+		return nil, fmt.Errorf("invalid HTTP redirect response code %v, must be one of 301, 302, 303, 307 and 308", opts.HttpRedirectResponseCode)
+	}
+
+	redirect := &routepb.RedirectAction{
+		SchemeRewriteSpecifier: &routepb.RedirectAction_HttpsRedirect{
+			HttpsRedirect: true,
+		},
+		ResponseCode: responseCode,
+		// The redirects point to the HTTPS listener unless the flag is set, such
+		// as when a load balancer in front maps the ports.
+		PortRedirect: uint32(opts.ListenerPort),
+	}
+	if opts.HttpsRedirectPort != 0 {
+		redirect.PortRedirect = uint32(opts.HttpsRedirectPort)
+	}
+
+	routerFilter, err := filtergen.FilterConfigToHTTPFilter(&routerpb.Router{
+		SuppressEnvoyHeaders: opts.SuppressEnvoyHeaders,
+	}, filtergen.RouterFilterName)
+	if err != nil {
+		return nil, err
+	}
+
+	hcmConfig := &hcmpb.HttpConnectionManager{
+		StatPrefix:  util.HttpRedirectStatPrefix,
+		HttpFilters: []*hcmpb.HttpFilter{routerFilter},
+		RouteSpecifier: &hcmpb.HttpConnectionManager_RouteConfig{
+			RouteConfig: &routepb.RouteConfiguration{
+				Name: "http_redirect_route",
+				VirtualHosts: []*routepb.VirtualHost{
+					{
+						Name:    "http_redirect",
+						Domains: []string{"*"},
+						Routes: []*routepb.Route{
+							{
+								Match: &routepb.RouteMatch{
+									PathSpecifier: &routepb.RouteMatch_Prefix{
+										Prefix: "/",
+									},
+								},
+								Action: &routepb.Route_Redirect{
+									Redirect: redirect,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	networkFilterConfig, err := filtergen.FilterConfigToNetworkFilter(hcmConfig, filtergen.HTTPConnectionManagerFilterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listenerpb.Listener{
+		Name: util.HttpRedirectListenerName,
+		Address: &corepb.Address{
+			Address: &corepb.Address_SocketAddress{
+				SocketAddress: &corepb.SocketAddress{
+					Address: opts.ListenerAddress,
+					PortSpecifier: &corepb.SocketAddress_PortValue{
+						PortValue: uint32(opts.HttpRedirectPort),
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerpb.FilterChain{
+			{
+				Filters: []*listenerpb.Filter{
+					networkFilterConfig,
+				},
+			},
+		},
+	}, nil
+}
+
+// MakeHealthCheckListener provides a plain HTTP listener for Envoy, which only
+// serves the health check. The requests skip the authentication and Service
+// Control filters of the ingress listener.
+func MakeHealthCheckListener(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (*listenerpb.Listener, error) {
+	if opts.Healthz == "" {
+		return nil, fmt.Errorf("flag --health_port requires flag --healthz")
+	}
+	if opts.HealthPort == opts.ListenerPort || opts.HealthPort == opts.HttpRedirectPort {
+		return nil, fmt.Errorf("flag --health_port must be different from flag --listener_port and --http_redirect_port, got %v", opts.HealthPort)
+	}
+
+	healthCheckGens, err := filtergen.NewHealthCheckFilterGensFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	httpFilters, err := MakeHttpFilterConfigs(healthCheckGens)
+	if err != nil {
+		return nil, err
+	}
+
+	routerFilter, err := filtergen.FilterConfigToHTTPFilter(&routerpb.Router{
+		SuppressEnvoyHeaders: opts.SuppressEnvoyHeaders,
+	}, filtergen.RouterFilterName)
+	if err != nil {
+		return nil, err
+	}
+	httpFilters = append(httpFilters, routerFilter)
+
+	hcmConfig := &hcmpb.HttpConnectionManager{
+		StatPrefix:  util.HealthCheckStatPrefix,
+		HttpFilters: httpFilters,
+		RouteSpecifier: &hcmpb.HttpConnectionManager_RouteConfig{
+			RouteConfig: &routepb.RouteConfiguration{
+				Name: "health_check_route",
+				VirtualHosts: []*routepb.VirtualHost{
+					{
+						Name:    "health_check",
+						Domains: []string{"*"},
+						Routes: []*routepb.Route{
+							{
+								// The health check filter responds to the
+								// health check path, all the others are not found.
+								Match: &routepb.RouteMatch{
+									PathSpecifier: &routepb.RouteMatch_Prefix{
+										Prefix: "/",
+									},
+								},
+								Action: &routepb.Route_DirectResponse{
+									DirectResponse: &routepb.DirectResponseAction{
+										Status: http.StatusNotFound,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	networkFilterConfig, err := filtergen.FilterConfigToNetworkFilter(hcmConfig, filtergen.HTTPConnectionManagerFilterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listenerpb.Listener{
+		Name: util.HealthCheckListenerName,
+		Address: &corepb.Address{
+			Address: &corepb.Address_SocketAddress{
+				SocketAddress: &corepb.SocketAddress{
+					Address: opts.ListenerAddress,
+					PortSpecifier: &corepb.SocketAddress_PortValue{
+						PortValue: uint32(opts.HealthPort),
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerpb.FilterChain{
+			{
+				Filters: []*listenerpb.Filter{
+					networkFilterConfig,
+				},
+			},
+		},
+	}, nil
+}
+
+// MakeTcpProxyListeners provides a TCP proxy listener for Envoy per port of
+// the TCP proxy routes, which tunnel the non-HTTP traffic to the destinations.
+// The TLS connections are routed by the server name (SNI) without terminating
+// TLS.
+func MakeTcpProxyListeners(opts options.ConfigGeneratorOptions) ([]*listenerpb.Listener, error) {
+	routes, err := clustergen.ParseTcpProxyRoutesFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []uint32
+	routesByPort := make(map[uint32][]*clustergen.TcpProxyRoute)
+	for _, route := range routes {
+		if _, ok := routesByPort[route.Port]; !ok {
+			ports = append(ports, route.Port)
+		}
+		routesByPort[route.Port] = append(routesByPort[route.Port], route)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+
+	var listeners []*listenerpb.Listener
+	for _, port := range ports {
+		statPrefix := fmt.Sprintf("%s_%d", util.TcpProxyStatPrefix, port)
+		listener := &listenerpb.Listener{
+			Name: fmt.Sprintf("%s_%d", util.TcpProxyListenerName, port),
+			Address: &corepb.Address{
+				Address: &corepb.Address_SocketAddress{
+					SocketAddress: &corepb.SocketAddress{
+						Address: opts.ListenerAddress,
+						PortSpecifier: &corepb.SocketAddress_PortValue{
+							PortValue: port,
+						},
+					},
+				},
+			},
+		}
+
+		needsTLSInspector := false
+		for _, route := range routesByPort[port] {
+			tcpProxyFilter, err := filtergen.FilterConfigToNetworkFilter(&tcppb.TcpProxy{
+				StatPrefix: statPrefix,
+				ClusterSpecifier: &tcppb.TcpProxy_Cluster{
+					Cluster: route.ClusterName(),
+				},
+			}, tcpProxyFilterName)
+			if err != nil {
+				return nil, err
+			}
+
+			filterChain := &listenerpb.FilterChain{
+				Filters: []*listenerpb.Filter{tcpProxyFilter},
+			}
+			if route.ServerName != "" {
+				needsTLSInspector = true
+				filterChain.FilterChainMatch = &listenerpb.FilterChainMatch{
+					ServerNames: []string{route.ServerName},
+				}
+			}
+			listener.FilterChains = append(listener.FilterChains, filterChain)
+		}
+
+		if needsTLSInspector {
+			tlsInspector, err := anypb.New(&tlsinspectorpb.TlsInspector{})
+			if err != nil {
+				return nil, err
+			}
+			listener.ListenerFilters = []*listenerpb.ListenerFilter{
+				{
+					Name: tlsInspectorFilterName,
+					ConfigType: &listenerpb.ListenerFilter_TypedConfig{
+						TypedConfig: tlsInspector,
+					},
+				},
+			}
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
@@ -13,12 +13,14 @@
 package configgenerator
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
@@ -437,3 +439,574 @@ func TestMakeListeners(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeListenersWithConnectionRateLimit(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.CommonOptions.TracingOptions.DisableTracing = true
+	opts.ListenerConnectionRateLimit = 100
+	fakeServiceInfo, err := configinfo.NewServiceInfoFromServiceConfig(&confpb.Service{
+		Name: testProjectName,
+		Apis: []*apipb.Api{
+			{
+				Name: testApiName,
+				Methods: []*apipb.Method{
+					{
+						Name: "CreateShelf",
+					},
+				},
+			},
+		},
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listeners, err := MakeListeners(fakeServiceInfo, filtergen.ServiceControlOPFactoryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := listeners[0].GetFilterChains()[0].GetFilters()
+	if len(filters) != 2 {
+		t.Fatalf("want 2 network filters, got %v", filters)
+	}
+	gotFilter, err := util.ProtoToJson(filters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFilter := `
+{
+  "name": "envoy.filters.network.local_ratelimit",
+  "typedConfig": {
+    "@type": "type.googleapis.com/envoy.extensions.filters.network.local_ratelimit.v3.LocalRateLimit",
+    "statPrefix": "ingress_connection_rate_limit",
+    "tokenBucket": {
+      "fillInterval": "1s",
+      "maxTokens": 100,
+      "tokensPerFill": 100
+    }
+  }
+}`
+	if err := util.JsonEqual(wantFilter, gotFilter); err != nil {
+		t.Errorf("MakeListeners got the connection rate limit filter diff, \n %v", err)
+	}
+	if got := filters[1].GetName(); got != filtergen.HTTPConnectionManagerFilterName {
+		t.Errorf("want the HTTP connection manager after the connection rate limit filter, got %q", got)
+	}
+}
+
+func TestMakeListenersWithAllowedSourceRanges(t *testing.T) {
+	testData := []struct {
+		desc                string
+		allowedSourceRanges string
+		connectionRateLimit int
+		wantFilterNames     []string
+		wantFilter          string
+		wantError           string
+	}{
+		{
+			desc:                "Source ranges filter is before the connection rate limit",
+			allowedSourceRanges: "10.0.0.0/8, 192.168.1.1,::1",
+			connectionRateLimit: 100,
+			wantFilterNames: []string{
+				filtergen.SourceRangesFilterName,
+				filtergen.ConnectionRateLimitFilterName,
+				filtergen.HTTPConnectionManagerFilterName,
+			},
+			wantFilter: `
+{
+  "name": "envoy.filters.network.rbac",
+  "typedConfig": {
+    "@type": "type.googleapis.com/envoy.extensions.filters.network.rbac.v3.RBAC",
+    "rules": {
+      "policies": {
+        "source_ranges": {
+          "permissions": [
+            {
+              "any": true
+            }
+          ],
+          "principals": [
+            {
+              "orIds": {
+                "ids": [
+                  {
+                    "directRemoteIp": {
+                      "addressPrefix": "10.0.0.0",
+                      "prefixLen": 8
+                    }
+                  },
+                  {
+                    "directRemoteIp": {
+                      "addressPrefix": "192.168.1.1",
+                      "prefixLen": 32
+                    }
+                  },
+                  {
+                    "directRemoteIp": {
+                      "addressPrefix": "::1",
+                      "prefixLen": 128
+                    }
+                  }
+                ]
+              }
+            }
+          ]
+        }
+      }
+    },
+    "statPrefix": "ingress_source_ranges"
+  }
+}`,
+		},
+		{
+			desc: "No source ranges filter by default",
+			wantFilterNames: []string{
+				filtergen.HTTPConnectionManagerFilterName,
+			},
+		},
+		{
+			desc:                "Invalid CIDR",
+			allowedSourceRanges: "10.0.0.0/33",
+			wantError:           "invalid flag --allowed_source_ranges",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.CommonOptions.TracingOptions.DisableTracing = true
+			opts.AllowedSourceRanges = tc.allowedSourceRanges
+			opts.ListenerConnectionRateLimit = tc.connectionRateLimit
+			fakeServiceInfo, err := configinfo.NewServiceInfoFromServiceConfig(&confpb.Service{
+				Name: testProjectName,
+				Apis: []*apipb.Api{
+					{
+						Name: testApiName,
+						Methods: []*apipb.Method{
+							{
+								Name: "CreateShelf",
+							},
+						},
+					},
+				},
+			}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			listeners, err := MakeListeners(fakeServiceInfo, filtergen.ServiceControlOPFactoryParams{})
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MakeListeners got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filters := listeners[0].GetFilterChains()[0].GetFilters()
+			var gotFilterNames []string
+			for _, filter := range filters {
+				gotFilterNames = append(gotFilterNames, filter.GetName())
+			}
+			if diff := cmp.Diff(tc.wantFilterNames, gotFilterNames); diff != "" {
+				t.Fatalf("MakeListeners got network filters diff (-want +got):\n%s", diff)
+			}
+
+			if tc.wantFilter == "" {
+				return
+			}
+			gotFilter, err := util.ProtoToJson(filters[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := util.JsonEqual(tc.wantFilter, gotFilter); err != nil {
+				t.Errorf("MakeListeners got the source ranges filter diff, \n %v", err)
+			}
+		})
+	}
+}
+
+func TestMakeHttpRedirectListener(t *testing.T) {
+	testdata := []struct {
+		desc                     string
+		sslServerCertPath        string
+		httpRedirectResponseCode int
+		httpsRedirectPort        int
+		wantListener             string
+		wantError                string
+	}{
+		{
+			desc:                     "Success, redirect to the listener port if the HTTPS port is 0",
+			sslServerCertPath:        "/etc/endpoints/ssl",
+			httpRedirectResponseCode: 301,
+			wantListener: `
+{
+  "address": {
+    "socketAddress": {
+      "address": "0.0.0.0",
+      "portValue": 8081
+    }
+  },
+  "filterChains": [
+    {
+      "filters": [
+        {
+          "name": "envoy.filters.network.http_connection_manager",
+          "typedConfig": {
+            "@type": "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+            "httpFilters": [
+              {
+                "name": "envoy.filters.http.router",
+                "typedConfig": {
+                  "@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+                  "suppressEnvoyHeaders": true
+                }
+              }
+            ],
+            "routeConfig": {
+              "name": "http_redirect_route",
+              "virtualHosts": [
+                {
+                  "domains": ["*"],
+                  "name": "http_redirect",
+                  "routes": [
+                    {
+                      "match": {
+                        "prefix": "/"
+                      },
+                      "redirect": {
+                        "httpsRedirect": true,
+                        "portRedirect": 8080
+                      }
+                    }
+                  ]
+                }
+              ]
+            },
+            "statPrefix": "http_redirect"
+          }
+        }
+      ]
+    }
+  ],
+  "name": "http_redirect_listener"
+}`,
+		},
+		{
+			desc:                     "Success, redirect to a custom HTTPS port with 308",
+			sslServerCertPath:        "/etc/endpoints/ssl",
+			httpRedirectResponseCode: 308,
+			httpsRedirectPort:        8443,
+			wantListener: `
+{
+  "address": {
+    "socketAddress": {
+      "address": "0.0.0.0",
+      "portValue": 8081
+    }
+  },
+  "filterChains": [
+    {
+      "filters": [
+        {
+          "name": "envoy.filters.network.http_connection_manager",
+          "typedConfig": {
+            "@type": "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+            "httpFilters": [
+              {
+                "name": "envoy.filters.http.router",
+                "typedConfig": {
+                  "@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+                  "suppressEnvoyHeaders": true
+                }
+              }
+            ],
+            "routeConfig": {
+              "name": "http_redirect_route",
+              "virtualHosts": [
+                {
+                  "domains": ["*"],
+                  "name": "http_redirect",
+                  "routes": [
+                    {
+                      "match": {
+                        "prefix": "/"
+                      },
+                      "redirect": {
+                        "httpsRedirect": true,
+                        "portRedirect": 8443,
+                        "responseCode": "PERMANENT_REDIRECT"
+                      }
+                    }
+                  ]
+                }
+              ]
+            },
+            "statPrefix": "http_redirect"
+          }
+        }
+      ]
+    }
+  ],
+  "name": "http_redirect_listener"
+}`,
+		},
+		{
+			desc:                     "Failure, no SSL server cert",
+			httpRedirectResponseCode: 301,
+			wantError:                "flag --http_redirect_port requires flag --ssl_server_cert_path",
+		},
+		{
+			desc:                     "Failure, invalid response code",
+			sslServerCertPath:        "/etc/endpoints/ssl",
+			httpRedirectResponseCode: 200,
+			wantError:                "invalid HTTP redirect response code 200, must be one of 301, 302, 303, 307 and 308",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.SslServerCertPath = tc.sslServerCertPath
+			opts.HttpRedirectPort = 8081
+			opts.HttpRedirectResponseCode = tc.httpRedirectResponseCode
+			opts.HttpsRedirectPort = tc.httpsRedirectPort
+
+			listener, err := MakeHttpRedirectListener(opts)
+			if tc.wantError != "" {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("want error %q, got error %v", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotListener, err := util.ProtoToJson(listener)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := util.JsonEqual(tc.wantListener, gotListener); err != nil {
+				t.Errorf("MakeHttpRedirectListener failed, \n %v", err)
+			}
+		})
+	}
+}
+
+func TestMakeHealthCheckListener(t *testing.T) {
+	testdata := []struct {
+		desc         string
+		healthz      string
+		healthPort   int
+		wantListener string
+		wantError    string
+	}{
+		{
+			desc:       "Success, only the health check is served",
+			healthz:    "healthz",
+			healthPort: 8090,
+			wantListener: `
+{
+  "address": {
+    "socketAddress": {
+      "address": "0.0.0.0",
+      "portValue": 8090
+    }
+  },
+  "filterChains": [
+    {
+      "filters": [
+        {
+          "name": "envoy.filters.network.http_connection_manager",
+          "typedConfig": {
+            "@type": "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+            "httpFilters": [
+              {
+                "name": "envoy.filters.http.health_check",
+                "typedConfig": {
+                  "@type": "type.googleapis.com/envoy.extensions.filters.http.health_check.v3.HealthCheck",
+                  "headers": [
+                    {
+                      "name": ":path",
+                      "stringMatch": {
+                        "exact": "/healthz"
+                      }
+                    }
+                  ],
+                  "passThroughMode": false
+                }
+              },
+              {
+                "name": "envoy.filters.http.router",
+                "typedConfig": {
+                  "@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+                  "suppressEnvoyHeaders": true
+                }
+              }
+            ],
+            "routeConfig": {
+              "name": "health_check_route",
+              "virtualHosts": [
+                {
+                  "domains": ["*"],
+                  "name": "health_check",
+                  "routes": [
+                    {
+                      "directResponse": {
+                        "status": 404
+                      },
+                      "match": {
+                        "prefix": "/"
+                      }
+                    }
+                  ]
+                }
+              ]
+            },
+            "statPrefix": "health_check"
+          }
+        }
+      ]
+    }
+  ],
+  "name": "health_check_listener"
+}`,
+		},
+		{
+			desc:       "Failure, no healthz",
+			healthPort: 8090,
+			wantError:  "flag --health_port requires flag --healthz",
+		},
+		{
+			desc:       "Failure, same port as the listener",
+			healthz:    "healthz",
+			healthPort: 8080,
+			wantError:  "flag --health_port must be different from flag --listener_port and --http_redirect_port, got 8080",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.Healthz = tc.healthz
+			opts.HealthPort = tc.healthPort
+
+			listener, err := MakeHealthCheckListener(&confpb.Service{Name: testProjectName}, opts)
+			if tc.wantError != "" {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("want error %q, got error %v", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotListener, err := util.ProtoToJson(listener)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := util.JsonEqual(tc.wantListener, gotListener); err != nil {
+				t.Errorf("MakeHealthCheckListener failed, \n %v", err)
+			}
+		})
+	}
+}
+
+func TestMakeTcpProxyListeners(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.TcpProxyRoutes = "8883/*.example.com=mqtt-tls-broker:8883;1883=mqtt-broker:1883;8883=mqtt-default:8883"
+
+	listeners, err := MakeTcpProxyListeners(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantListeners := []string{`
+{
+  "address": {
+    "socketAddress": {
+      "address": "0.0.0.0",
+      "portValue": 1883
+    }
+  },
+  "filterChains": [
+    {
+      "filters": [
+        {
+          "name": "envoy.filters.network.tcp_proxy",
+          "typedConfig": {
+            "@type": "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+            "cluster": "tcp-proxy-cluster-mqtt-broker:1883",
+            "statPrefix": "tcp_proxy_1883"
+          }
+        }
+      ]
+    }
+  ],
+  "name": "tcp_proxy_listener_1883"
+}`, `
+{
+  "address": {
+    "socketAddress": {
+      "address": "0.0.0.0",
+      "portValue": 8883
+    }
+  },
+  "filterChains": [
+    {
+      "filterChainMatch": {
+        "serverNames": ["*.example.com"]
+      },
+      "filters": [
+        {
+          "name": "envoy.filters.network.tcp_proxy",
+          "typedConfig": {
+            "@type": "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+            "cluster": "tcp-proxy-cluster-mqtt-tls-broker:8883",
+            "statPrefix": "tcp_proxy_8883"
+          }
+        }
+      ]
+    },
+    {
+      "filters": [
+        {
+          "name": "envoy.filters.network.tcp_proxy",
+          "typedConfig": {
+            "@type": "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+            "cluster": "tcp-proxy-cluster-mqtt-default:8883",
+            "statPrefix": "tcp_proxy_8883"
+          }
+        }
+      ]
+    }
+  ],
+  "listenerFilters": [
+    {
+      "name": "envoy.filters.listener.tls_inspector",
+      "typedConfig": {
+        "@type": "type.googleapis.com/envoy.extensions.filters.listener.tls_inspector.v3.TlsInspector"
+      }
+    }
+  ],
+  "name": "tcp_proxy_listener_8883"
+}`}
+
+	if len(listeners) != len(wantListeners) {
+		t.Fatalf("MakeTcpProxyListeners got %d listeners, want %d", len(listeners), len(wantListeners))
+	}
+	for i, listener := range listeners {
+		gotListener, err := util.ProtoToJson(listener)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := util.JsonEqual(wantListeners[i], gotListener); err != nil {
+			t.Errorf("MakeTcpProxyListeners failed at listener %d, \n %v", i, err)
+		}
+	}
+}
@@ -27,17 +27,28 @@ import (
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 const (
 	routeName       = "local_route"
 	virtualHostName = "backend"
+
+	// DeploymentLabelsMetadataNamespace is the route metadata namespace of the
+	// deployment labels, used by the access log format
+	// "%METADATA(ROUTE:com.google.espv2.deployment:KEY)%".
+	DeploymentLabelsMetadataNamespace = "com.google.espv2.deployment"
 )
 
 // MakeRouteGenFactories creates the route generator factories (in order).
 func MakeRouteGenFactories() []routegen.RouteGeneratorOPFactory {
 	return []routegen.RouteGeneratorOPFactory{
+		routegen.NewAdminRouteGenFromOPConfig,
+		routegen.NewMaintenanceRouteGenFromOPConfig,
+		routegen.NewStaticPathsRouteGenFromOPConfig,
+		routegen.NewGrpcReflectionRouteGenFromOPConfig,
+		routegen.NewRedirectRouteGenFromOPConfig,
 		routegen.NewProxyBackendRouteGenFromOPConfig,
 		routegen.NewProxyCORSRouteGenFromOPConfig,
 		routegen.NewDirectResponseHealthCheckRouteGenFromOPConfig,
@@ -48,6 +59,8 @@ func MakeRouteGenFactories() []routegen.RouteGeneratorOPFactory {
 				routegen.NewDirectResponseHealthCheckRouteGenFromOPConfig,
 			})
 		},
+		routegen.NewPassthroughRouteGenFromOPConfig,
+		routegen.NewDefaultBackendRouteGenFromOPConfig,
 		routegen.NewDenyAllRouteGenFromOPConfig,
 	}
 }
@@ -71,6 +84,13 @@ func MakeRouteConfig(opts options.ConfigGeneratorOptions, filterGenerators []fil
 		return nil, err
 	}
 	host.Routes = backendRoutes
+	if opts.MergeDuplicateRoutes {
+		host.Routes = mergeDuplicateRoutes(host.Routes)
+	}
+
+	if err := addDeploymentLabelsMetadata(opts, host.Routes); err != nil {
+		return nil, err
+	}
 
 	requestHeaders, err := makeRequestHeadersToAdd(opts)
 	if err != nil {
@@ -81,14 +101,50 @@ func MakeRouteConfig(opts options.ConfigGeneratorOptions, filterGenerators []fil
 		return nil, err
 	}
 
-	return &routepb.RouteConfiguration{
+	routeConfig := &routepb.RouteConfiguration{
 		Name: routeName,
 		VirtualHosts: []*routepb.VirtualHost{
 			host,
 		},
 		RequestHeadersToAdd:  requestHeaders,
 		ResponseHeadersToAdd: responseHeaders,
-	}, nil
+	}
+
+	// The HTTP connection manager passes the Server header of the backends
+	// through, so it is only removed here.
+	if opts.ServerHeaderTransformation == "suppress" {
+		routeConfig.ResponseHeadersToRemove = []string{"server"}
+	}
+	return routeConfig, nil
+}
+
+// addDeploymentLabelsMetadata adds the deployment labels to the metadata of
+// all the routes, so they can be written to the access logs.
+func addDeploymentLabelsMetadata(opts options.ConfigGeneratorOptions, routes []*routepb.Route) error {
+	labels, err := util.ParseDeploymentLabels(opts.DeploymentLabels)
+	if err != nil {
+		return fmt.Errorf("invalid flag --deployment_labels: %v", err)
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]*structpb.Value)
+	for key, value := range labels {
+		fields[key] = structpb.NewStringValue(value)
+	}
+	for _, route := range routes {
+		if route.Metadata == nil {
+			route.Metadata = &corepb.Metadata{}
+		}
+		if route.Metadata.FilterMetadata == nil {
+			route.Metadata.FilterMetadata = make(map[string]*structpb.Struct)
+		}
+		route.Metadata.FilterMetadata[DeploymentLabelsMetadataNamespace] = &structpb.Struct{
+			Fields: fields,
+		}
+	}
+	return nil
 }
 
 func makeHeaders(headers string, a bool) ([]*corepb.HeaderValueOption, error) {
@@ -180,15 +236,22 @@ func makeRouteTable(filterGens []filtergen.FilterGenerator, routeGens []routegen
 			return nil, fmt.Errorf("fail to create config for the route type %q: %v", routeGen.RouteType(), err)
 		}
 
-		wrapper := &routepb.VirtualHost{
-			Routes: routes,
+		// Converting all the routes to JSON takes longer than generating them
+		// for the large service configs, so they are only logged verbosely.
+		glog.Infof("adding %d routes of type %q to route table", len(routes), routeGen.RouteType())
+		if glog.V(1) {
+			wrapper := &routepb.VirtualHost{
+				Routes: routes,
+			}
+			if redactor, ok := routeGen.(routegen.RedactingRouteGenerator); ok {
+				wrapper.Routes = redactor.RedactRoutes(routes)
+			}
+			jsonStr, err := util.ProtoToJson(wrapper)
+			if err != nil {
+				return nil, fmt.Errorf("fail to convert proto to JSON for route type %q: %v", routeGen.RouteType(), err)
+			}
+			glog.Infof("routes of type %q: %v", routeGen.RouteType(), jsonStr)
 		}
-		jsonStr, err := util.ProtoToJson(wrapper)
-		if err != nil {
-			return nil, fmt.Errorf("fail to convert proto to JSON for route type %q: %v", routeGen.RouteType(), err)
-		}
-
-		glog.Infof("adding routes of type %q to route table : %v", routeGen.RouteType(), jsonStr)
 		allRoutes = append(allRoutes, routes...)
 	}
 	return allRoutes, nil
@@ -35,6 +35,7 @@ import (
 	apipb "google.golang.org/genproto/protobuf/api"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -5106,6 +5107,103 @@ func TestHeadersToAdd(t *testing.T) {
 	}
 }
 
+func TestSuppressServerHeader(t *testing.T) {
+	testData := []struct {
+		desc                       string
+		serverHeaderTransformation string
+		wantHeadersToRemove        []string
+	}{
+		{
+			desc: "Server header is overwritten by default",
+		},
+		{
+			desc:                       "Server header of the backend is passed through",
+			serverHeaderTransformation: "pass_through",
+		},
+		{
+			desc:                       "Server header is removed from all the responses",
+			serverHeaderTransformation: "suppress",
+			wantHeadersToRemove:        []string{"server"},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.ServerHeaderTransformation = tc.serverHeaderTransformation
+
+			gotRoute, err := makeRouteConfigWithDefaults(&servicepb.Service{Name: "test-api"}, opts, nil)
+			if err != nil {
+				t.Fatalf("MakeRouteConfig got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantHeadersToRemove, gotRoute.ResponseHeadersToRemove); diff != "" {
+				t.Errorf("MakeRouteConfig ResponseHeadersToRemove diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeploymentLabelsMetadata(t *testing.T) {
+	testData := []struct {
+		desc             string
+		deploymentLabels string
+		wantError        string
+		wantMetadata     *structpb.Struct
+	}{
+		{
+			desc: "no deployment labels",
+		},
+		{
+			desc:             "deployment labels are added to all routes",
+			deploymentLabels: "gke_cluster=cluster-1;environment=prod",
+			wantMetadata: &structpb.Struct{
+				Fields: map[string]*structpb.Value{
+					"gke_cluster": structpb.NewStringValue("cluster-1"),
+					"environment": structpb.NewStringValue("prod"),
+				},
+			},
+		},
+		{
+			desc:             "invalid deployment labels",
+			deploymentLabels: "environment",
+			wantError:        "invalid flag --deployment_labels",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.DeploymentLabels = tc.deploymentLabels
+
+			fakeServiceConfig := &servicepb.Service{
+				Name: "test-api",
+			}
+
+			gotRoute, err := makeRouteConfigWithDefaults(fakeServiceConfig, opts, nil)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("expected err: %v, got: %v", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MakeRouteConfig got error: %v", err)
+			}
+
+			routes := gotRoute.VirtualHosts[0].Routes
+			if len(routes) == 0 {
+				t.Fatalf("MakeRouteConfig got no routes")
+			}
+			for _, route := range routes {
+				got := route.GetMetadata().GetFilterMetadata()[DeploymentLabelsMetadataNamespace]
+				if diff := cmp.Diff(tc.wantMetadata, got, protocmp.Transform()); diff != "" {
+					t.Errorf("route %q metadata diff (-want +got):\n%s", route.Name, diff)
+				}
+			}
+		})
+	}
+}
+
 // Used to generate a oversize cors origin regex or a oversize uri template.
 func getOverSizeRegexForTest() string {
 	overSizeRegex := ""
@@ -0,0 +1,150 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configgenerator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// MergedRoutesMetadataNamespace is the route metadata namespace listing the
+// operations of the routes merged by flag --merge_duplicate_routes, under
+// key "operations".
+const MergedRoutesMetadataNamespace = "com.google.espv2.merged_routes"
+
+// mergeDuplicateRoutes merges the adjacent routes that only differ by the
+// operation name and the path into one route, whose regex matches the paths
+// of all of them.
+//
+// Only the adjacent routes are merged, so the merged route is matched for the
+// same requests as the routes it replaces. The routes matching by prefix, and
+// the merges exceeding the regex program size of Envoy, are kept as is.
+func mergeDuplicateRoutes(routes []*routepb.Route) []*routepb.Route {
+	var merged []*routepb.Route
+	var last *routepb.Route
+	var lastPaths []string
+	var lastOperations []string
+
+	flush := func() {
+		if last == nil {
+			return
+		}
+		if len(lastPaths) > 1 {
+			last.Match.PathSpecifier = &routepb.RouteMatch_SafeRegex{
+				SafeRegex: &matcherpb.RegexMatcher{
+					Regex: joinPathRegexes(lastPaths),
+				},
+			}
+			addMergedOperationsMetadata(last, lastOperations)
+		}
+		merged = append(merged, last)
+	}
+
+	for _, route := range routes {
+		path, ok := pathRegex(route)
+		if ok && last != nil && len(lastPaths) > 0 && sameRouteExceptOperationAndPath(last, route) {
+			paths := append(append([]string{}, lastPaths...), path)
+			if err := util.ValidateRegexProgramSize(joinPathRegexes(paths), util.GoogleRE2MaxProgramSize); err == nil {
+				lastPaths = paths
+				lastOperations = append(lastOperations, route.GetName())
+				continue
+			}
+		}
+
+		flush()
+		last = route
+		lastPaths = nil
+		lastOperations = []string{route.GetName()}
+		if ok {
+			lastPaths = []string{path}
+		}
+	}
+	flush()
+
+	if len(merged) < len(routes) {
+		glog.Infof("merged %d duplicate routes into %d routes", len(routes), len(merged))
+	}
+	return merged
+}
+
+// pathRegex returns the path matcher of the route as a regex without the
+// anchors, or false if the route does not match by exact path or regex.
+func pathRegex(route *routepb.Route) (string, bool) {
+	switch specifier := route.GetMatch().GetPathSpecifier().(type) {
+	case *routepb.RouteMatch_Path:
+		return regexp.QuoteMeta(specifier.Path), true
+	case *routepb.RouteMatch_SafeRegex:
+		// The regexes of the routes always match the whole path, so the
+		// anchors are optional.
+		regex := specifier.SafeRegex.GetRegex()
+		if strings.HasPrefix(regex, "^") && strings.HasSuffix(regex, "$") && !strings.HasSuffix(regex, `\$`) {
+			regex = regex[1 : len(regex)-1]
+		}
+		return "(" + regex + ")", true
+	default:
+		return "", false
+	}
+}
+
+func joinPathRegexes(paths []string) string {
+	return "^(" + strings.Join(paths, "|") + ")$"
+}
+
+// sameRouteExceptOperationAndPath returns true if the routes only differ by
+// their names, the span names and the paths.
+func sameRouteExceptOperationAndPath(a, b *routepb.Route) bool {
+	return proto.Equal(withoutOperationAndPath(a), withoutOperationAndPath(b))
+}
+
+func withoutOperationAndPath(route *routepb.Route) *routepb.Route {
+	route = proto.Clone(route).(*routepb.Route)
+	route.Name = ""
+	if route.Decorator != nil {
+		route.Decorator.Operation = ""
+	}
+	if route.Match != nil {
+		route.Match.PathSpecifier = nil
+	}
+	return route
+}
+
+func addMergedOperationsMetadata(route *routepb.Route, operations []string) {
+	values := make([]*structpb.Value, 0, len(operations))
+	for _, operation := range operations {
+		values = append(values, structpb.NewStringValue(operation))
+	}
+
+	if route.Metadata == nil {
+		route.Metadata = &corepb.Metadata{}
+	}
+	if route.Metadata.FilterMetadata == nil {
+		route.Metadata.FilterMetadata = make(map[string]*structpb.Struct)
+	}
+	route.Metadata.FilterMetadata[MergedRoutesMetadataNamespace] = &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"operations": structpb.NewListValue(&structpb.ListValue{
+				Values: values,
+			}),
+		},
+	}
+}
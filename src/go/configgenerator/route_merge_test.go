@@ -0,0 +1,185 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configgenerator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func makeTestRoute(operation, cluster string, match *routepb.RouteMatch) *routepb.Route {
+	return &routepb.Route{
+		Name:  operation,
+		Match: match,
+		Action: &routepb.Route_Route{
+			Route: &routepb.RouteAction{
+				ClusterSpecifier: &routepb.RouteAction_Cluster{
+					Cluster: cluster,
+				},
+			},
+		},
+		Decorator: &routepb.Decorator{
+			Operation: "ingress " + operation,
+		},
+	}
+}
+
+func pathMatch(path string) *routepb.RouteMatch {
+	return &routepb.RouteMatch{
+		PathSpecifier: &routepb.RouteMatch_Path{
+			Path: path,
+		},
+	}
+}
+
+func regexMatch(regex string) *routepb.RouteMatch {
+	return &routepb.RouteMatch{
+		PathSpecifier: &routepb.RouteMatch_SafeRegex{
+			SafeRegex: &matcherpb.RegexMatcher{
+				Regex: regex,
+			},
+		},
+	}
+}
+
+func mergedOperationsMetadata(operations ...string) *corepb.Metadata {
+	var values []*structpb.Value
+	for _, operation := range operations {
+		values = append(values, structpb.NewStringValue(operation))
+	}
+	return &corepb.Metadata{
+		FilterMetadata: map[string]*structpb.Struct{
+			MergedRoutesMetadataNamespace: {
+				Fields: map[string]*structpb.Value{
+					"operations": structpb.NewListValue(&structpb.ListValue{
+						Values: values,
+					}),
+				},
+			},
+		},
+	}
+}
+
+func TestMergeDuplicateRoutes(t *testing.T) {
+	testData := []struct {
+		desc       string
+		routes     []*routepb.Route
+		wantRoutes []*routepb.Route
+	}{
+		{
+			desc: "Adjacent routes differing by operation and path are merged",
+			routes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend", pathMatch("/a")),
+				makeTestRoute("api.GetB", "backend", regexMatch(`^/b/[^\/]+\/?$`)),
+				makeTestRoute("api.GetC", "backend", pathMatch("/c.json")),
+			},
+			wantRoutes: []*routepb.Route{
+				func() *routepb.Route {
+					r := makeTestRoute("api.GetA", "backend", regexMatch(`^(/a|(/b/[^\/]+\/?)|/c\.json)$`))
+					r.Metadata = mergedOperationsMetadata("api.GetA", "api.GetB", "api.GetC")
+					return r
+				}(),
+			},
+		},
+		{
+			desc: "Routes to different clusters are not merged",
+			routes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend-1", pathMatch("/a")),
+				makeTestRoute("api.GetB", "backend-2", pathMatch("/b")),
+			},
+			wantRoutes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend-1", pathMatch("/a")),
+				makeTestRoute("api.GetB", "backend-2", pathMatch("/b")),
+			},
+		},
+		{
+			desc: "Routes that are not adjacent are not merged, to keep the match order",
+			routes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend-1", pathMatch("/a")),
+				makeTestRoute("api.GetB", "backend-2", pathMatch("/b")),
+				makeTestRoute("api.GetC", "backend-1", pathMatch("/c")),
+			},
+			wantRoutes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend-1", pathMatch("/a")),
+				makeTestRoute("api.GetB", "backend-2", pathMatch("/b")),
+				makeTestRoute("api.GetC", "backend-1", pathMatch("/c")),
+			},
+		},
+		{
+			desc: "Prefix routes are not merged",
+			routes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend", &routepb.RouteMatch{
+					PathSpecifier: &routepb.RouteMatch_Prefix{
+						Prefix: "/",
+					},
+				}),
+				makeTestRoute("api.GetB", "backend", pathMatch("/b")),
+			},
+			wantRoutes: []*routepb.Route{
+				makeTestRoute("api.GetA", "backend", &routepb.RouteMatch{
+					PathSpecifier: &routepb.RouteMatch_Prefix{
+						Prefix: "/",
+					},
+				}),
+				makeTestRoute("api.GetB", "backend", pathMatch("/b")),
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := mergeDuplicateRoutes(tc.routes)
+			if diff := cmp.Diff(tc.wantRoutes, got, protocmp.Transform()); diff != "" {
+				t.Errorf("mergeDuplicateRoutes() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicateRoutes_RegexProgramSize(t *testing.T) {
+	var routes []*routepb.Route
+	for i := 0; i < 2000; i++ {
+		routes = append(routes, makeTestRoute(fmt.Sprintf("api.Get%d", i), "backend", pathMatch(fmt.Sprintf("/shelves/%d/books/%d", i, i*7919))))
+	}
+
+	got := mergeDuplicateRoutes(routes)
+	if len(got) <= 1 || len(got) >= len(routes) {
+		t.Fatalf("mergeDuplicateRoutes() got %d routes, want the %d routes split by the regex program size", len(got), len(routes))
+	}
+
+	var operations int
+	for _, route := range got {
+		merged := route.GetMetadata().GetFilterMetadata()[MergedRoutesMetadataNamespace]
+		if merged == nil {
+			operations++
+			continue
+		}
+		if regex := route.GetMatch().GetSafeRegex().GetRegex(); !strings.HasPrefix(regex, "^(/shelves/") {
+			t.Errorf("mergeDuplicateRoutes() route %q got regex %q", route.GetName(), regex)
+		}
+		operations += len(merged.GetFields()["operations"].GetListValue().GetValues())
+	}
+	if operations != len(routes) {
+		t.Errorf("mergeDuplicateRoutes() got %d merged operations, want %d", operations, len(routes))
+	}
+}
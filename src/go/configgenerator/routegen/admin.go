@@ -0,0 +1,197 @@
+package routegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// minAdminTokenLength is the least length of the admin token. The token is
+	// compared by the Envoy header matcher, which is not constant-time, so it
+	// must be long enough not to be guessed.
+	minAdminTokenLength = 16
+
+	redactedAdminToken = "REDACTED"
+)
+
+var (
+	// safeAdminPaths are the read-only Envoy admin endpoints that can be
+	// exposed on the main listener. Others, such as "/config_dump", "/certs"
+	// and "/clusters", leak the secrets and backend addresses in the config,
+	// including the admin token itself.
+	safeAdminPaths = []string{
+		"/ready",
+		"/server_info",
+		"/stats",
+		"/stats/prometheus",
+	}
+)
+
+// AdminRouteGenerator is a RouteGenerator that creates routes to expose a
+// read-only subset of the Envoy admin interface on the main listener.
+type AdminRouteGenerator struct {
+	PathPrefix  string
+	Paths       []string
+	TokenHeader string
+	Token       string
+
+	*NoopRouteGenerator
+}
+
+// NewAdminRouteGenFromOPConfig creates AdminRouteGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewAdminRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if opts.AdminReadOnlyPaths == "" {
+		glog.Info("Not adding admin routes because no admin path is exposed.")
+		return nil, nil
+	}
+
+	if opts.AdminTokenPath == "" || opts.AdminTokenHeader == "" {
+		return nil, fmt.Errorf("flag --admin_read_only_paths requires flag --admin_token_path and --admin_token_header")
+	}
+	if !strings.HasPrefix(opts.AdminPathPrefix, "/") || strings.HasSuffix(opts.AdminPathPrefix, "/") {
+		return nil, fmt.Errorf("invalid flag --admin_path_prefix, %q should start with / and not end with /", opts.AdminPathPrefix)
+	}
+
+	var paths []string
+	for _, path := range strings.Split(opts.AdminReadOnlyPaths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid flag --admin_read_only_paths, admin path %q should start with /", path)
+		}
+		if !isSafeAdminPath(path) {
+			return nil, fmt.Errorf("invalid flag --admin_read_only_paths, admin path %q cannot be exposed, must be one of %v", path, safeAdminPaths)
+		}
+		paths = append(paths, path)
+	}
+
+	token, err := readAdminToken(opts.AdminTokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AdminRouteGenerator{
+		PathPrefix:  opts.AdminPathPrefix,
+		Paths:       paths,
+		TokenHeader: opts.AdminTokenHeader,
+		Token:       token,
+	}, nil
+}
+
+// readAdminToken reads the admin token from the file of flag
+// --admin_token_path. The errors do not contain the token.
+func readAdminToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("fail to read the admin token file of flag --admin_token_path: %v", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if len(token) < minAdminTokenLength {
+		return "", fmt.Errorf("invalid flag --admin_token_path, the admin token in %q must be at least %d characters", path, minAdminTokenLength)
+	}
+	return token, nil
+}
+
+func isSafeAdminPath(path string) bool {
+	for _, safePath := range safeAdminPaths {
+		if path == safePath {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *AdminRouteGenerator) RouteType() string {
+	return "admin_routes"
+}
+
+// RedactRoutes implements interface RedactingRouteGenerator, the admin token
+// in the header matchers is redacted.
+func (g *AdminRouteGenerator) RedactRoutes(routes []*routepb.Route) []*routepb.Route {
+	var redacted []*routepb.Route
+	for _, route := range routes {
+		route = proto.Clone(route).(*routepb.Route)
+		for _, header := range route.GetMatch().GetHeaders() {
+			if header.GetName() == g.TokenHeader {
+				header.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
+					StringMatch: &matcherpb.StringMatcher{
+						MatchPattern: &matcherpb.StringMatcher_Exact{
+							Exact: redactedAdminToken,
+						},
+					},
+				}
+			}
+		}
+		redacted = append(redacted, route)
+	}
+	return redacted
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+//
+// The admin routes have no per-route filter config, they are not operations
+// of the API.
+func (g *AdminRouteGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	var routes []*routepb.Route
+	for _, path := range g.Paths {
+		routes = append(routes, &routepb.Route{
+			Match: &routepb.RouteMatch{
+				PathSpecifier: &routepb.RouteMatch_Path{
+					Path: g.PathPrefix + path,
+				},
+				Headers: []*routepb.HeaderMatcher{
+					{
+						Name: ":method",
+						HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+							StringMatch: &matcherpb.StringMatcher{
+								MatchPattern: &matcherpb.StringMatcher_Exact{
+									Exact: util.GET,
+								},
+							},
+						},
+					},
+					{
+						Name: g.TokenHeader,
+						HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+							StringMatch: &matcherpb.StringMatcher{
+								MatchPattern: &matcherpb.StringMatcher_Exact{
+									Exact: g.Token,
+								},
+							},
+						},
+					},
+				},
+			},
+			Action: &routepb.Route_Route{
+				Route: &routepb.RouteAction{
+					ClusterSpecifier: &routepb.RouteAction_Cluster{
+						Cluster: clustergen.AdminClusterName,
+					},
+					PrefixRewrite: path,
+				},
+			},
+			// The admin token is not forwarded to the admin interface.
+			RequestHeadersToRemove: []string{g.TokenHeader},
+			Decorator: &routepb.Decorator{
+				Operation: fmt.Sprintf("%s %s_Admin", util.SpanNamePrefix, util.AutogeneratedOperationPrefix),
+			},
+		})
+	}
+	return routes, nil
+}
@@ -0,0 +1,205 @@
+package routegen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const testAdminToken = "espv2-admin-secret-token"
+
+// writeAdminToken writes the admin token into a file, returning its path.
+func writeAdminToken(t *testing.T, token string) string {
+	path := filepath.Join(t.TempDir(), "admin_token")
+	if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewAdminRouteGenFromOPConfig(t *testing.T) {
+	// The trailing newline of the token file is trimmed.
+	tokenPath := writeAdminToken(t, testAdminToken+"\n")
+
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn:         options.ConfigGeneratorOptions{},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "read-only admin paths with token",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats, /ready",
+				AdminTokenPath:     tokenPath,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_Admin"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          },
+          {
+            "name":"x-espv2-admin-token",
+            "stringMatch":{
+              "exact":"espv2-admin-secret-token"
+            }
+          }
+        ],
+        "path":"/espv2_admin/stats"
+      },
+      "requestHeadersToRemove":[
+        "x-espv2-admin-token"
+      ],
+      "route":{
+        "cluster":"admin-cluster",
+        "prefixRewrite":"/stats"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_Admin"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          },
+          {
+            "name":"x-espv2-admin-token",
+            "stringMatch":{
+              "exact":"espv2-admin-secret-token"
+            }
+          }
+        ],
+        "path":"/espv2_admin/ready"
+      },
+      "requestHeadersToRemove":[
+        "x-espv2-admin-token"
+      ],
+      "route":{
+        "cluster":"admin-cluster",
+        "prefixRewrite":"/ready"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewAdminRouteGenFromOPConfig)
+	}
+}
+
+func TestNewAdminRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	tokenPath := writeAdminToken(t, testAdminToken)
+	shortTokenPath := writeAdminToken(t, "secret")
+
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "admin token is required",
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats",
+			},
+			WantFactoryError: "flag --admin_read_only_paths requires flag --admin_token_path",
+		},
+		{
+			Desc: "admin token file does not exist",
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats",
+				AdminTokenPath:     "/does/not/exist",
+			},
+			WantFactoryError: "fail to read the admin token file of flag --admin_token_path",
+		},
+		{
+			Desc: "admin token is too short",
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats",
+				AdminTokenPath:     shortTokenPath,
+			},
+			WantFactoryError: "must be at least 16 characters",
+		},
+		{
+			Desc: "mutating admin path cannot be exposed",
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats,/quitquitquit",
+				AdminTokenPath:     tokenPath,
+			},
+			WantFactoryError: `invalid flag --admin_read_only_paths, admin path "/quitquitquit" cannot be exposed`,
+		},
+		{
+			Desc: "admin path leaking the config cannot be exposed",
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats,/config_dump",
+				AdminTokenPath:     tokenPath,
+			},
+			WantFactoryError: `invalid flag --admin_read_only_paths, admin path "/config_dump" cannot be exposed`,
+		},
+		{
+			Desc: "invalid path prefix",
+			OptsIn: options.ConfigGeneratorOptions{
+				AdminReadOnlyPaths: "/stats",
+				AdminPathPrefix:    "/admin/",
+				AdminTokenPath:     tokenPath,
+			},
+			WantFactoryError: `invalid flag --admin_path_prefix`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewAdminRouteGenFromOPConfig)
+	}
+}
+
+func TestAdminRouteGeneratorRedactRoutes(t *testing.T) {
+	gen, err := routegen.NewAdminRouteGenFromOPConfig(&servicepb.Service{}, options.ConfigGeneratorOptions{
+		AdminReadOnlyPaths: "/stats",
+		AdminPathPrefix:    "/espv2_admin",
+		AdminTokenHeader:   "x-espv2-admin-token",
+		AdminTokenPath:     writeAdminToken(t, testAdminToken),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, err := gen.GenRouteConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := gen.(routegen.RedactingRouteGenerator).RedactRoutes(routes)
+	tokenMatch := func(routes []*routepb.Route) string {
+		return routes[0].GetMatch().GetHeaders()[1].GetStringMatch().GetExact()
+	}
+	if got, want := tokenMatch(redacted), "REDACTED"; got != want {
+		t.Errorf("got redacted token %q, want %q", got, want)
+	}
+	if got := tokenMatch(routes); got != testAdminToken {
+		t.Errorf("the generated routes are modified, got token %q, want %q", got, testAdminToken)
+	}
+}
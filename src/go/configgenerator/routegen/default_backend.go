@@ -0,0 +1,85 @@
+package routegen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// DefaultBackendGenerator is a RouteGenerator that proxies the requests
+// matching no operation to the default backend, instead of rejecting them.
+type DefaultBackendGenerator struct {
+	HTTPPattern     *httppattern.Pattern
+	HostRewrite     string
+	BackendRouteGen *helpers.BackendRouteGenerator
+
+	*NoopRouteGenerator
+}
+
+// NewDefaultBackendRouteGenFromOPConfig creates DefaultBackendGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewDefaultBackendRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if opts.DefaultBackendAddress == "" {
+		glog.Info("Not adding default backend route because default backend address is not specified.")
+		return nil, nil
+	}
+	if opts.StrictRouteAllowlist {
+		return nil, fmt.Errorf("flag --default_backend_address cannot be used with flag --strict_route_allowlist")
+	}
+
+	_, hostname, _, _, err := util.ParseURI(opts.DefaultBackendAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --default_backend_address: %v", err)
+	}
+
+	uriTemplate, err := httppattern.ParseUriTemplate("/**")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DefaultBackendGenerator{
+		HTTPPattern: &httppattern.Pattern{
+			HttpMethod:  httppattern.HttpMethodWildCard,
+			UriTemplate: uriTemplate,
+		},
+		HostRewrite:     hostname,
+		BackendRouteGen: helpers.NewBackendRouteGeneratorFromOPConfig(opts),
+	}, nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *DefaultBackendGenerator) RouteType() string {
+	return "default_backend_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+func (g *DefaultBackendGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	methodCfg := &helpers.MethodCfg{
+		OperationName:      filtergen.DefaultBackendOperationName,
+		BackendClusterName: clustergen.DefaultBackendClusterName,
+		HostRewrite:        g.HostRewrite,
+		Deadline:           util.DefaultResponseDeadline,
+		HTTPPattern:        g.HTTPPattern,
+	}
+
+	return g.BackendRouteGen.GenRoutesForMethod(methodCfg, filterGens)
+}
+
+// AffectedHTTPPatterns implements interface RouteGenerator.
+func (g *DefaultBackendGenerator) AffectedHTTPPatterns() httppattern.MethodSlice {
+	return httppattern.MethodSlice{
+		{
+			Pattern:   g.HTTPPattern,
+			Operation: filtergen.DefaultBackendOperationName,
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestNewDefaultBackendRouteGenFromOPConfig(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn:         options.ConfigGeneratorOptions{},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "unmatched requests to the default backend",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				DefaultBackendAddress: "https://legacy.example.com",
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_DefaultBackend"
+      },
+      "match":{
+        "safeRegex":{
+          "regex":"^/.*\\/?$"
+        }
+      },
+      "name":"espv2_deployment.ESPv2_Autogenerated_DefaultBackend",
+      "route":{
+        "cluster":"backend-cluster-default",
+        "hostRewriteLiteral":"legacy.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewDefaultBackendRouteGenFromOPConfig)
+	}
+}
+
+func TestNewDefaultBackendRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "strict route allowlist conflicts",
+			OptsIn: options.ConfigGeneratorOptions{
+				DefaultBackendAddress: "https://legacy.example.com",
+				StrictRouteAllowlist:  true,
+			},
+			WantFactoryError: "flag --default_backend_address cannot be used with flag --strict_route_allowlist",
+		},
+		{
+			Desc: "invalid address",
+			OptsIn: options.ConfigGeneratorOptions{
+				DefaultBackendAddress: "legacy.example.com:abc",
+			},
+			WantFactoryError: "invalid flag --default_backend_address",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewDefaultBackendRouteGenFromOPConfig)
+	}
+}
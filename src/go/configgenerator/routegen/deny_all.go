@@ -9,6 +9,7 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 )
 
@@ -21,6 +22,10 @@ type DenyAllGenerator struct {
 // from OP service config + ESPv2 options.
 // It is a RouteGeneratorOPFactory.
 func NewDenyAllRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if opts.DefaultBackendAddress != "" {
+		glog.Info("Not adding deny all route because the default backend receives the unmatched requests.")
+		return nil, nil
+	}
 	return &DenyAllGenerator{}, nil
 }
 
@@ -3,22 +3,28 @@ package routegen
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // DirectResponseCORSGenerator is a RouteGenerator to configure CORS routes.
 type DirectResponseCORSGenerator struct {
 	Preset string
-	// AllowOrigin should only be set if preset=basic
+	// AllowOrigin should only be set if preset=basic. It is a comma separated
+	// list of origins.
 	AllowOrigin string
 	// AllowOriginRegex should only be set if preset=cors_with_regex
 	AllowOriginRegex string
@@ -27,6 +33,13 @@ type DirectResponseCORSGenerator struct {
 	// CORS policies to.
 	LocalBackendClusterName string
 
+	// OverridesBySelector overrides the CORS policy per operation.
+	OverridesBySelector map[string]*filtergen.CORSOperationOverrides
+	// OverrideHTTPPatterns are the HTTP patterns of the operations with
+	// overrides, their preflight requests are routed separately.
+	OverrideHTTPPatterns               httppattern.MethodSlice
+	DisallowColonInWildcardPathSegment bool
+
 	*NoopRouteGenerator
 }
 
@@ -34,16 +47,44 @@ type DirectResponseCORSGenerator struct {
 // from OP service config + ESPv2 options.
 // It is a RouteGeneratorOPFactory.
 func NewDirectResponseCORSRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
-	if opts.CorsPreset == "" {
+	preset := filtergen.CORSPresetFromOPConfig(serviceConfig, opts)
+	if preset == "" {
 		glog.Infof("Not adding Direct Response CORS route gen because the feature is disabled by option, option is currently %q", opts.CorsPreset)
 		return nil, nil
 	}
 
+	overridesBySelector, err := filtergen.ParseCORSOperationOverridesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	overrideHTTPPatterns := httppattern.MethodSlice{}
+	if len(overridesBySelector) > 0 {
+		httpPatternsBySelector, err := ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig, opts)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse http patterns from OP config: %v", err)
+		}
+		for selector := range httpPatternsBySelector {
+			if _, ok := overridesBySelector[selector]; !ok {
+				delete(httpPatternsBySelector, selector)
+			}
+		}
+
+		httpPatterns, err := sortHttpPatterns(httpPatternsBySelector)
+		if err != nil {
+			return nil, fmt.Errorf("fail to sort http patterns: %v", err)
+		}
+		overrideHTTPPatterns = *httpPatterns
+	}
+
 	return &DirectResponseCORSGenerator{
-		Preset:                  opts.CorsPreset,
-		AllowOrigin:             opts.CorsAllowOrigin,
-		AllowOriginRegex:        opts.CorsAllowOriginRegex,
-		LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
+		Preset:                             preset,
+		AllowOrigin:                        opts.CorsAllowOrigin,
+		AllowOriginRegex:                   opts.CorsAllowOriginRegex,
+		LocalBackendClusterName:            clustergen.MakeLocalBackendClusterName(serviceConfig),
+		OverridesBySelector:                overridesBySelector,
+		OverrideHTTPPatterns:               overrideHTTPPatterns,
+		DisallowColonInWildcardPathSegment: opts.DisallowColonInWildcardPathSegment,
 	}, nil
 }
 
@@ -55,7 +96,12 @@ func (g *DirectResponseCORSGenerator) RouteType() string {
 // GenRouteConfig implements interface RouteGenerator.
 //
 // Forked from `route_generator.go: makeRouteCors()
-func (g *DirectResponseCORSGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+func (g *DirectResponseCORSGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	routes, err := g.genOperationPreflightCorsRoutes(filterGens)
+	if err != nil {
+		return nil, err
+	}
+
 	originMatcher := &routepb.HeaderMatcher{
 		Name: "origin",
 	}
@@ -75,28 +121,130 @@ func (g *DirectResponseCORSGenerator) GenRouteConfig([]filtergen.FilterGenerator
 		return nil, fmt.Errorf(`cors_preset must be either "basic" or "cors_with_regex"`)
 	}
 
-	return []*routepb.Route{
+	return append(routes,
 		genPreflightCorsRoute(g.LocalBackendClusterName, originMatcher),
 		genPreflightCorsMissingHeadersRoute(),
-	}, nil
+	), nil
+}
+
+// genOperationPreflightCorsRoutes generates the preflight routes of the
+// operations with overrides, so the preflight requests are answered with the
+// operation CORS policy instead of the virtual host one.
+func (g *DirectResponseCORSGenerator) genOperationPreflightCorsRoutes(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	if len(g.OverrideHTTPPatterns) == 0 {
+		return nil, nil
+	}
+
+	var corsFilterGen filtergen.FilterGenerator
+	for _, filterGen := range filterGens {
+		if filterGen.FilterName() == filtergen.CORSFilterName {
+			corsFilterGen = filterGen
+		}
+	}
+	if corsFilterGen == nil {
+		return nil, fmt.Errorf("per-operation CORS policies require the CORS filter")
+	}
+
+	var routes []*routepb.Route
+	for _, httpPattern := range g.OverrideHTTPPatterns {
+		selector := httpPattern.Operation
+		overrides := g.OverridesBySelector[selector]
+
+		originMatcher := &routepb.HeaderMatcher{
+			Name: "origin",
+		}
+		switch {
+		case overrides.AllowOrigins != nil:
+			if err := fillBasicOriginMatcher(originMatcher, strings.Join(overrides.AllowOrigins, ",")); err != nil {
+				return nil, fmt.Errorf("fail to fill origin matcher for operation %q: %v", selector, err)
+			}
+		case g.Preset == "basic":
+			if err := fillBasicOriginMatcher(originMatcher, g.AllowOrigin); err != nil {
+				return nil, fmt.Errorf("fail to fill basic origin matcher: %v", err)
+			}
+		default:
+			if err := fillRegexOriginMatcher(originMatcher, g.AllowOriginRegex); err != nil {
+				return nil, fmt.Errorf("fail to fill regex origin matcher: %v", err)
+			}
+		}
+
+		requestMethodMatcher := &routepb.HeaderMatcher{
+			Name: "access-control-request-method",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		}
+		if httpPattern.HttpMethod != httppattern.HttpMethodWildCard {
+			requestMethodMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Exact{
+						Exact: httpPattern.HttpMethod,
+					},
+				},
+			}
+		}
+
+		policy, err := corsFilterGen.GenPerRouteConfig(selector, httpPattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("fail to generate CORS policy for operation %q: %v", selector, err)
+		}
+		policyAny, err := anypb.New(policy)
+		if err != nil {
+			return nil, fmt.Errorf("fail to marshal CORS policy to Any for operation %q: %v", selector, err)
+		}
+
+		routeMatchers, err := helpers.MakeRouteMatchers(httpPattern.Pattern, g.DisallowColonInWildcardPathSegment)
+		if err != nil {
+			return nil, fmt.Errorf("fail to make preflight route matchers for operation %q: %v", selector, err)
+		}
+		for _, routeMatcher := range routeMatchers {
+			route := genPreflightCorsRoute(g.LocalBackendClusterName, originMatcher)
+			route.Match.PathSpecifier = routeMatcher.PathSpecifier
+			route.Match.Headers[2] = requestMethodMatcher
+			route.TypedPerFilterConfig = map[string]*anypb.Any{
+				filtergen.CORSFilterName: policyAny,
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
 }
 
 func fillBasicOriginMatcher(originMatcher *routepb.HeaderMatcher, allowOrigin string) error {
-	if allowOrigin == "" {
+	origins := filtergen.SplitCORSOrigins(allowOrigin)
+	if len(origins) == 0 {
 		return fmt.Errorf("cors_allow_origin cannot be empty when cors_preset=basic")
 	}
 
-	if allowOrigin == "*" {
-		originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_PresentMatch{
-			PresentMatch: true,
+	var quotedOrigins []string
+	for _, origin := range origins {
+		if origin == "*" {
+			originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			}
+			return nil
+		}
+		quotedOrigins = append(quotedOrigins, regexp.QuoteMeta(origin))
+	}
+
+	if len(origins) == 1 {
+		originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
+			StringMatch: &matcherpb.StringMatcher{
+				MatchPattern: &matcherpb.StringMatcher_Exact{
+					Exact: origins[0],
+				},
+			},
 		}
 		return nil
 	}
 
+	// Any of the origins.
 	originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
 		StringMatch: &matcherpb.StringMatcher{
-			MatchPattern: &matcherpb.StringMatcher_Exact{
-				Exact: allowOrigin,
+			MatchPattern: &matcherpb.StringMatcher_SafeRegex{
+				SafeRegex: &matcherpb.RegexMatcher{
+					Regex: strings.Join(quotedOrigins, "|"),
+				},
 			},
 		},
 	}
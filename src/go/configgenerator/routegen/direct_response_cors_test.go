@@ -4,11 +4,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/imdario/mergo"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
 )
 
 func TestNewCORSRouteGenFromOPConfig(t *testing.T) {
@@ -217,6 +220,79 @@ func TestNewCORSRouteGenFromOPConfig(t *testing.T) {
 			}
 		}
 	]
+}
+			`,
+		},
+		{
+			Desc: "unmatched preflight routes when the backend handles cors",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Endpoints: []*servicepb.Endpoint{
+					{
+						Name:      "bookstore.endpoints.project123.cloud.goog",
+						AllowCors: true,
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsAllowUnmatchedPreflight: true,
+				CorsAllowOrigin:             "http://example.com",
+			},
+			WantHostConfig: `
+{
+	"routes": [
+		{
+			"decorator": {
+				"operation": "ingress"
+			},
+			"match": {
+				"headers": [
+					{
+						"name": ":method",
+						"stringMatch": {
+							"exact": "OPTIONS"
+						}
+					},
+					{
+						"name": "origin",
+						"stringMatch": {
+							"exact": "http://example.com"
+						}
+					},
+					{
+						"name": "access-control-request-method",
+						"presentMatch": true
+					}
+				],
+				"prefix": "/"
+			},
+			"route": {
+				"cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+			}
+		},
+		{
+			"decorator": {
+				"operation": "ingress"
+			},
+			"directResponse": {
+				"body": {
+					"inlineString": "The CORS preflight request is missing one (or more) of the following required headers [Origin, Access-Control-Request-Method] or has an unmatched Origin header."
+				},
+				"status": 400
+			},
+			"match": {
+				"headers": [
+					{
+						"name": ":method",
+						"stringMatch": {
+							"exact": "OPTIONS"
+						}
+					}
+				],
+				"prefix": "/"
+			}
+		}
+	]
 }
 			`,
 		},
@@ -309,3 +385,209 @@ func makeOverSizeRegexForTest() string {
 	}
 	return overSizeRegex
 }
+
+func TestNewCORSRouteGenFromOPConfig_OperationOverrides(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListShelves",
+					},
+					{
+						Name: "CreateShelf",
+					},
+				},
+			},
+		},
+		Http: &annotationspb.Http{
+			Rules: []*annotationspb.HttpRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+					Pattern: &annotationspb.HttpRule_Get{
+						Get: "/shelves",
+					},
+				},
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.CreateShelf",
+					Pattern: &annotationspb.HttpRule_Post{
+						Post: "/shelves",
+					},
+				},
+			},
+		},
+	}
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.CorsPreset = "basic"
+	opts.CorsAllowOrigin = "https://a.example.com,https://b.example.com"
+	opts.CorsAllowMethods = "GET,POST"
+	opts.CorsMaxAge = 2 * time.Minute
+	opts.OperationCorsAllowOrigin = "endpoints.examples.bookstore.Bookstore.CreateShelf=https://admin.example.com"
+
+	filterGens, err := filtergen.NewCORSFilterGensFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+	}
+
+	tc := routegentest.SuccessOPTestCase{
+		Desc:            "preflight routes for the operations with overrides",
+		ServiceConfigIn: serviceConfig,
+		OptsIn:          opts,
+		FilterGens:      filterGens,
+		WantHostConfig: `
+{
+  "routes": [
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "origin",
+            "stringMatch": {
+              "exact": "https://admin.example.com"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          }
+        ],
+        "path": "/shelves"
+      },
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      },
+      "typedPerFilterConfig": {
+        "envoy.filters.http.cors": {
+          "@type": "type.googleapis.com/envoy.extensions.filters.http.cors.v3.CorsPolicy",
+          "allowCredentials": false,
+          "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization",
+          "allowMethods": "GET,POST",
+          "allowOriginStringMatch": [
+            {
+              "exact": "https://admin.example.com"
+            }
+          ],
+          "exposeHeaders": "Content-Length,Content-Range",
+          "maxAge": "120"
+        }
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "origin",
+            "stringMatch": {
+              "exact": "https://admin.example.com"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          }
+        ],
+        "path": "/shelves/"
+      },
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      },
+      "typedPerFilterConfig": {
+        "envoy.filters.http.cors": {
+          "@type": "type.googleapis.com/envoy.extensions.filters.http.cors.v3.CorsPolicy",
+          "allowCredentials": false,
+          "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization",
+          "allowMethods": "GET,POST",
+          "allowOriginStringMatch": [
+            {
+              "exact": "https://admin.example.com"
+            }
+          ],
+          "exposeHeaders": "Content-Length,Content-Range",
+          "maxAge": "120"
+        }
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "origin",
+            "stringMatch": {
+              "safeRegex": {
+                "regex": "https://a\\.example\\.com|https://b\\.example\\.com"
+              }
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "presentMatch": true
+          }
+        ],
+        "prefix": "/"
+      },
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "The CORS preflight request is missing one (or more) of the following required headers [Origin, Access-Control-Request-Method] or has an unmatched Origin header."
+        },
+        "status": 400
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          }
+        ],
+        "prefix": "/"
+      }
+    }
+  ]
+}
+`,
+	}
+	tc.RunTest(t, routegen.NewDirectResponseCORSRouteGenFromOPConfig)
+}
@@ -0,0 +1,119 @@
+package routegen
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// GrpcReflectionGenerator is a RouteGenerator that explicitly allows or
+// denies the gRPC server reflection calls, whether or not the reflection
+// service is an API of the service config.
+//
+// The allowed calls are proxied to the local backend, they are not reported
+// to Service Control, and may require a JWT. The denied calls are rejected
+// with PERMISSION_DENIED.
+type GrpcReflectionGenerator struct {
+	Allow bool
+
+	LocalBackendClusterName string
+	BackendRouteGen         *helpers.BackendRouteGenerator
+
+	*NoopRouteGenerator
+}
+
+// NewGrpcReflectionRouteGenFromOPConfig creates GrpcReflectionGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewGrpcReflectionRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if _, err := filtergen.ParseGrpcReflectionJwtProvidersFromOPConfig(serviceConfig, opts); err != nil {
+		return nil, err
+	}
+
+	switch opts.GrpcReflection {
+	case "":
+		glog.Info("Not adding gRPC reflection routes because the gRPC reflection calls are not explicitly allowed or denied.")
+		return nil, nil
+	case filtergen.GrpcReflectionDeny:
+		return &GrpcReflectionGenerator{}, nil
+	case filtergen.GrpcReflectionAllow:
+		isGRPC, err := util.IsBackendGRPC(opts.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("fail to check local backend address: %v", err)
+		}
+		if !isGRPC {
+			return nil, fmt.Errorf("flag --grpc_reflection=%s requires a gRPC local backend, got --backend=%s", filtergen.GrpcReflectionAllow, opts.BackendAddress)
+		}
+
+		return &GrpcReflectionGenerator{
+			Allow:                   true,
+			LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
+			BackendRouteGen:         helpers.NewBackendRouteGeneratorFromOPConfig(opts),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid flag --grpc_reflection %q, must be %q or %q", opts.GrpcReflection, filtergen.GrpcReflectionAllow, filtergen.GrpcReflectionDeny)
+	}
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *GrpcReflectionGenerator) RouteType() string {
+	return "grpc_reflection_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+func (g *GrpcReflectionGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	var routes []*routepb.Route
+	for _, path := range filtergen.GrpcReflectionPaths {
+		if !g.Allow {
+			route := &routepb.Route{
+				Match: &routepb.RouteMatch{
+					PathSpecifier: &routepb.RouteMatch_Path{
+						Path: path,
+					},
+				},
+				Decorator: &routepb.Decorator{
+					Operation: fmt.Sprintf("%s %s_GrpcReflection", util.SpanNamePrefix, util.AutogeneratedOperationPrefix),
+				},
+			}
+			// The local reply is translated to grpc-status PERMISSION_DENIED.
+			response := &helpers.StaticResponse{
+				Status: http.StatusForbidden,
+				Body:   "gRPC server reflection is disabled",
+			}
+			response.ApplyTo(route)
+			routes = append(routes, route)
+			continue
+		}
+
+		uriTemplate, err := httppattern.ParseUriTemplate(path)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse gRPC reflection path %q: %v", path, err)
+		}
+		methodCfg := &helpers.MethodCfg{
+			OperationName:      filtergen.GrpcReflectionOperationName,
+			BackendClusterName: g.LocalBackendClusterName,
+			Deadline:           util.DefaultResponseDeadline,
+			IsStreaming:        true,
+			IsGrpc:             true,
+			HTTPPattern: &httppattern.Pattern{
+				HttpMethod:  util.POST,
+				UriTemplate: uriTemplate,
+			},
+		}
+		methodRoutes, err := g.BackendRouteGen.GenRoutesForMethod(methodCfg, filterGens)
+		if err != nil {
+			return nil, fmt.Errorf("fail to make routes for gRPC reflection path %q: %v", path, err)
+		}
+		routes = append(routes, methodRoutes...)
+	}
+	return routes, nil
+}
@@ -0,0 +1,239 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestNewGrpcReflectionRouteGenFromOPConfig(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "Not explicitly allowed or denied by default",
+			ServiceConfigIn: &confpb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "gRPC reflection calls are denied",
+			ServiceConfigIn: &confpb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				GrpcReflection: "deny",
+			},
+			WantHostConfig: `
+{
+  "routes": [
+    {
+      "decorator": {
+        "operation": "ingress ESPv2_Autogenerated_GrpcReflection"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "gRPC server reflection is disabled"
+        },
+        "status": 403
+      },
+      "match": {
+        "path": "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress ESPv2_Autogenerated_GrpcReflection"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "gRPC server reflection is disabled"
+        },
+        "status": 403
+      },
+      "match": {
+        "path": "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "gRPC reflection calls are allowed to the gRPC local backend",
+			ServiceConfigIn: &confpb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:8082",
+				GrpcReflection: "allow",
+			},
+			WantHostConfig: `
+{
+  "routes": [
+    {
+      "decorator": {
+        "operation": "ingress ESPv2_Autogenerated_GrpcReflection"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          }
+        ],
+        "path": "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+      },
+      "name": "espv2_deployment.ESPv2_Autogenerated_GrpcReflection",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout": "15s",
+        "retryPolicy": {
+          "numRetries": 1,
+          "retryOn": "reset,connect-failure,refused-stream"
+        },
+        "timeout": "0s"
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress ESPv2_Autogenerated_GrpcReflection"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          }
+        ],
+        "path": "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo/"
+      },
+      "name": "espv2_deployment.ESPv2_Autogenerated_GrpcReflection",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout": "15s",
+        "retryPolicy": {
+          "numRetries": 1,
+          "retryOn": "reset,connect-failure,refused-stream"
+        },
+        "timeout": "0s"
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress ESPv2_Autogenerated_GrpcReflection"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          }
+        ],
+        "path": "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+      },
+      "name": "espv2_deployment.ESPv2_Autogenerated_GrpcReflection",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout": "15s",
+        "retryPolicy": {
+          "numRetries": 1,
+          "retryOn": "reset,connect-failure,refused-stream"
+        },
+        "timeout": "0s"
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress ESPv2_Autogenerated_GrpcReflection"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          }
+        ],
+        "path": "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo/"
+      },
+      "name": "espv2_deployment.ESPv2_Autogenerated_GrpcReflection",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout": "15s",
+        "retryPolicy": {
+          "numRetries": 1,
+          "retryOn": "reset,connect-failure,refused-stream"
+        },
+        "timeout": "0s"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewGrpcReflectionRouteGenFromOPConfig)
+	}
+}
+
+func TestNewGrpcReflectionRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Unknown gRPC reflection mode",
+			OptsIn: options.ConfigGeneratorOptions{
+				GrpcReflection: "enable",
+			},
+			WantFactoryError: `invalid flag --grpc_reflection "enable", must be "allow" or "deny"`,
+		},
+		{
+			Desc: "Allowed gRPC reflection calls require a gRPC local backend",
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:8082",
+				GrpcReflection: "allow",
+			},
+			WantFactoryError: "flag --grpc_reflection=allow requires a gRPC local backend",
+		},
+		{
+			Desc: "JWT providers require the gRPC reflection calls to be allowed",
+			OptsIn: options.ConfigGeneratorOptions{
+				GrpcReflection:             "deny",
+				GrpcReflectionJwtProviders: "firebase",
+			},
+			WantFactoryError: "flag --grpc_reflection_jwt_providers requires flag --grpc_reflection=allow",
+		},
+		{
+			Desc: "Unknown JWT provider",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id: "firebase",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:             "grpc://127.0.0.1:8082",
+				GrpcReflection:             "allow",
+				GrpcReflectionJwtProviders: "firebase,auth0",
+			},
+			WantFactoryError: `invalid flag --grpc_reflection_jwt_providers, provider "auth0" is not an authentication provider of the service config`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewGrpcReflectionRouteGenFromOPConfig)
+	}
+}
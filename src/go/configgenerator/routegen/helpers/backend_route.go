@@ -11,6 +11,7 @@ import (
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // BackendRouteGenerator generates routes that forward request to the backend.
@@ -21,9 +22,24 @@ import (
 type BackendRouteGenerator struct {
 	DisallowColonInWildcardPathSegment bool
 	RetryCfg                           *RouteRetryConfiger
+	HedgeCfg                           *RouteHedgeConfiger
 	HSTSCfg                            *RouteHSTSConfiger
+	SecurityHeadersCfg                 *RouteSecurityHeadersConfiger
 	OperationNameCfg                   *RouteOperationNameConfiger
 	DeadlineCfg                        *RouteDeadlineConfiger
+	UpgradeCfg                         *RouteUpgradeConfiger
+	StreamingDownloadCfg               *RouteStreamingDownloadConfiger
+	WasmCfg                            *RouteWasmConfiger
+	StatsCfg                           *RouteStatsConfiger
+	MaintenanceCfg                     *RouteMaintenanceConfiger
+	ConsumerCfg                        *RouteConsumerConfiger
+	VersionCfg                         *RouteVersionConfiger
+	GrpcMetadataCfg                    *RouteGrpcMetadataConfiger
+	DeprecationCfg                     *RouteDeprecationConfiger
+	TimeoutPropagationCfg              *RouteTimeoutPropagationConfiger
+	ResponseHeadersCfg                 *RouteResponseHeadersConfiger
+	ConsumerRateLimitCfg               *RouteConsumerRateLimitConfiger
+	ResponseCacheCfg                   *RouteResponseCacheConfiger
 }
 
 // NewBackendRouteGeneratorFromOPConfig creates a BackendRouteGenerator from
@@ -32,9 +48,24 @@ func NewBackendRouteGeneratorFromOPConfig(opts options.ConfigGeneratorOptions) *
 	return &BackendRouteGenerator{
 		DisallowColonInWildcardPathSegment: opts.DisallowColonInWildcardPathSegment,
 		RetryCfg:                           NewRouteRetryConfigerFromOPConfig(opts),
+		HedgeCfg:                           NewRouteHedgeConfigerFromOPConfig(opts),
 		HSTSCfg:                            NewRouteHSTSConfigerFromOPConfig(opts),
+		SecurityHeadersCfg:                 NewRouteSecurityHeadersConfigerFromOPConfig(opts),
 		OperationNameCfg:                   NewRouteOperationNameConfigerFromOPConfig(opts),
 		DeadlineCfg:                        NewRouteDeadlineConfigerFromOPConfig(opts),
+		UpgradeCfg:                         NewRouteUpgradeConfigerFromOPConfig(opts),
+		StreamingDownloadCfg:               NewRouteStreamingDownloadConfigerFromOPConfig(opts),
+		WasmCfg:                            NewRouteWasmConfigerFromOPConfig(opts),
+		StatsCfg:                           NewRouteStatsConfigerFromOPConfig(opts),
+		MaintenanceCfg:                     NewRouteMaintenanceConfigerFromOPConfig(opts),
+		ConsumerCfg:                        NewRouteConsumerConfigerFromOPConfig(opts),
+		VersionCfg:                         NewRouteVersionConfigerFromOPConfig(opts),
+		GrpcMetadataCfg:                    NewRouteGrpcMetadataConfigerFromOPConfig(opts),
+		DeprecationCfg:                     NewRouteDeprecationConfigerFromOPConfig(opts),
+		TimeoutPropagationCfg:              NewRouteTimeoutPropagationConfigerFromOPConfig(opts),
+		ResponseHeadersCfg:                 NewRouteResponseHeadersConfigerFromOPConfig(opts),
+		ConsumerRateLimitCfg:               NewRouteConsumerRateLimitConfigerFromOPConfig(opts),
+		ResponseCacheCfg:                   NewRouteResponseCacheConfigerFromOPConfig(opts),
 	}
 }
 
@@ -47,6 +78,35 @@ type MethodCfg struct {
 	Deadline           time.Duration
 	IsStreaming        bool
 	HTTPPattern        *httppattern.Pattern
+
+	// ClusterHeader is set if the backend cluster is chosen by the value of
+	// the request header instead of BackendClusterName.
+	ClusterHeader string
+
+	// RouteByConsumer is set if the requests of the named API consumers are
+	// routed to their dedicated backend pools.
+	RouteByConsumer bool
+
+	// RouteByVersion is set if the requests are routed to the backends of the
+	// API versions in the version request header.
+	RouteByVersion bool
+
+	// IsGrpc is set if the route matches the gRPC path of the method.
+	IsGrpc bool
+
+	// IsDeprecated is set if the operation is deprecated in the service config.
+	IsDeprecated bool
+
+	// QuotaMetrics are the quota metrics consumed by the operation.
+	QuotaMetrics []string
+
+	// AutoHostRewrite is set if the Host header is rewritten to the hostname
+	// of the chosen backend endpoint instead of HostRewrite.
+	AutoHostRewrite bool
+
+	// IsResponseStreaming and ResponseTypeUrl are only set for gRPC methods.
+	IsResponseStreaming bool
+	ResponseTypeUrl     string
 }
 
 // GenRoutesForMethod generates the route config for the given URI template.
@@ -70,17 +130,35 @@ func (r *BackendRouteGenerator) GenRoutesForMethod(methodCfg *MethodCfg, filterG
 				Cluster: methodCfg.BackendClusterName,
 			},
 		}
+		if methodCfg.ClusterHeader != "" {
+			routeAction.ClusterSpecifier = &routepb.RouteAction_ClusterHeader{
+				ClusterHeader: methodCfg.ClusterHeader,
+			}
+		}
 
-		if methodCfg.HostRewrite != "" {
+		if methodCfg.AutoHostRewrite {
+			routeAction.HostRewriteSpecifier = &routepb.RouteAction_AutoHostRewrite{
+				AutoHostRewrite: wrapperspb.Bool(true),
+			}
+		} else if methodCfg.HostRewrite != "" {
 			routeAction.HostRewriteSpecifier = &routepb.RouteAction_HostRewriteLiteral{
 				HostRewriteLiteral: methodCfg.HostRewrite,
 			}
 		}
 
-		MaybeAddDeadlines(r.DeadlineCfg, routeAction, methodCfg.Deadline, methodCfg.IsStreaming)
-		if err := MaybeAddRetryPolicy(r.RetryCfg, routeAction); err != nil {
+		if err := MaybeAddDeadlines(r.DeadlineCfg, routeAction, methodCfg); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddUpgradeConfigs(r.UpgradeCfg, routeAction, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddRetryPolicy(r.RetryCfg, routeAction, methodCfg); err != nil {
 			return nil, err
 		}
+		if err := MaybeAddHedgePolicy(r.HedgeCfg, routeAction, methodCfg); err != nil {
+			return nil, err
+		}
+		MaybeAddConsumerRateLimits(r.ConsumerRateLimitCfg, routeAction, methodCfg)
 
 		perFilterConfig, err := makePerRouteFilterConfig(methodCfg.OperationName, methodCfg.HTTPPattern, filterGens)
 		if err != nil {
@@ -101,9 +179,49 @@ func (r *BackendRouteGenerator) GenRoutesForMethod(methodCfg *MethodCfg, filterG
 			TypedPerFilterConfig: perFilterConfig,
 		}
 
+		MaybeAddTimeoutPropagation(r.TimeoutPropagationCfg, route)
+		if err := MaybeRemoveResponseHeaders(r.ResponseHeadersCfg, route, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddResponseCacheTtl(r.ResponseCacheCfg, route, methodCfg); err != nil {
+			return nil, err
+		}
 		MaybeAddHSTSHeader(r.HSTSCfg, route)
+		if err := MaybeAddSecurityHeaders(r.SecurityHeadersCfg, route, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
 		MaybeAddOperationNameHeader(r.OperationNameCfg, route, methodCfg.OperationName)
+		if err := MaybeAddGrpcMetadata(r.GrpcMetadataCfg, route, methodCfg); err != nil {
+			return nil, err
+		}
+		MaybeAddStatPrefix(r.StatsCfg, route, methodCfg.OperationName)
+		if err := MaybeAddDeprecation(r.DeprecationCfg, route, methodCfg); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddStreamingDownloadConfig(r.StreamingDownloadCfg, route, methodCfg); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddWasmMetadata(r.WasmCfg, route, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddMaintenanceResponse(r.MaintenanceCfg, route, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
 
+		if methodCfg.RouteByConsumer {
+			consumerRoutes, err := MakeConsumerRoutes(r.ConsumerCfg, route)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, consumerRoutes...)
+		}
+		if methodCfg.RouteByVersion {
+			versionRoutes, err := MakeVersionRoutes(r.VersionCfg, route)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, versionRoutes...)
+		}
 		routes = append(routes, route)
 	}
 
@@ -0,0 +1,98 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// RouteConsumerConfiger is a helper to route the requests of the named API
+// consumers to their dedicated backend pools.
+type RouteConsumerConfiger struct {
+	// ConsumerBackends is the JSON array of flag --consumer_backends.
+	ConsumerBackends string
+}
+
+// NewRouteConsumerConfigerFromOPConfig creates a RouteConsumerConfiger from
+// ESPv2 options.
+func NewRouteConsumerConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteConsumerConfiger {
+	if opts.ConsumerBackends == "" {
+		return nil
+	}
+
+	return &RouteConsumerConfiger{
+		ConsumerBackends: opts.ConsumerBackends,
+	}
+}
+
+// MakeConsumerRoutes creates a copy of the route for each consumer backend,
+// matching the consumer numbers in the dynamic metadata set by the consumer
+// routing filter. The copies must be placed before the route.
+func MakeConsumerRoutes(c *RouteConsumerConfiger, route *routepb.Route) ([]*routepb.Route, error) {
+	if c == nil || route.GetRoute() == nil {
+		return nil, nil
+	}
+
+	backends, err := clustergen.ParseConsumerBackends(c.ConsumerBackends)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []*routepb.Route
+	for _, backend := range backends {
+		_, hostname, _, _, err := util.ParseURI(backend.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of consumer backend %q: %v", backend.Name, err)
+		}
+
+		consumerRoute := proto.Clone(route).(*routepb.Route)
+		consumerRoute.Match.DynamicMetadata = append(consumerRoute.Match.DynamicMetadata, makeConsumerNumberMatcher(backend.ConsumerNumbers))
+
+		routeAction := consumerRoute.GetRoute()
+		routeAction.ClusterSpecifier = &routepb.RouteAction_Cluster{
+			Cluster: clustergen.ConsumerClusterName(backend.Name),
+		}
+		routeAction.HostRewriteSpecifier = &routepb.RouteAction_HostRewriteLiteral{
+			HostRewriteLiteral: hostname,
+		}
+		routes = append(routes, consumerRoute)
+	}
+	return routes, nil
+}
+
+func makeConsumerNumberMatcher(consumerNumbers []string) *matcherpb.MetadataMatcher {
+	quoted := make([]string, 0, len(consumerNumbers))
+	for _, consumer := range consumerNumbers {
+		quoted = append(quoted, regexp.QuoteMeta(consumer))
+	}
+
+	return &matcherpb.MetadataMatcher{
+		Filter: filtergen.ConsumerMetadataNamespace,
+		Path: []*matcherpb.MetadataMatcher_PathSegment{
+			{
+				Segment: &matcherpb.MetadataMatcher_PathSegment_Key{
+					Key: filtergen.ConsumerNumberMetadataKey,
+				},
+			},
+		},
+		Value: &matcherpb.ValueMatcher{
+			MatchPattern: &matcherpb.ValueMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_SafeRegex{
+						SafeRegex: &matcherpb.RegexMatcher{
+							Regex: fmt.Sprintf("^(%s)$", strings.Join(quoted, "|")),
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// RouteConsumerRateLimitConfiger is a helper to generate the rate limit
+// descriptors of the consumer quota limits enforced by the consumer rate
+// limit filter.
+type RouteConsumerRateLimitConfiger struct {
+	ConsumerNumberHeader string
+
+	// LimitedMetrics are the quota metrics with any consumer quota limit.
+	LimitedMetrics map[string]bool
+}
+
+// NewRouteConsumerRateLimitConfigerFromOPConfig creates a RouteConsumerRateLimitConfiger from
+// ESPv2 options.
+func NewRouteConsumerRateLimitConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteConsumerRateLimitConfiger {
+	if len(opts.ConsumerQuotaLimits) == 0 {
+		return nil
+	}
+
+	limitedMetrics := make(map[string]bool)
+	for _, limit := range opts.ConsumerQuotaLimits {
+		limitedMetrics[limit.Metric] = true
+	}
+
+	return &RouteConsumerRateLimitConfiger{
+		ConsumerNumberHeader: filtergen.ConsumerNumberHeaderFromOPConfig(opts),
+		LimitedMetrics:       limitedMetrics,
+	}
+}
+
+// MaybeAddConsumerRateLimits adds a rate limit to the route action for each
+// limited quota metric consumed by the operation, generating the descriptor
+// of the consumer number and the metric. No descriptor is generated for the
+// requests without a consumer number.
+func MaybeAddConsumerRateLimits(c *RouteConsumerRateLimitConfiger, routeAction *routepb.RouteAction, methodCfg *MethodCfg) {
+	if c == nil {
+		return
+	}
+
+	for _, metric := range methodCfg.QuotaMetrics {
+		if !c.LimitedMetrics[metric] {
+			continue
+		}
+
+		routeAction.RateLimits = append(routeAction.RateLimits, &routepb.RateLimit{
+			Actions: []*routepb.RateLimit_Action{
+				{
+					ActionSpecifier: &routepb.RateLimit_Action_RequestHeaders_{
+						RequestHeaders: &routepb.RateLimit_Action_RequestHeaders{
+							HeaderName:    c.ConsumerNumberHeader,
+							DescriptorKey: filtergen.ConsumerNumberDescriptorKey,
+						},
+					},
+				},
+				{
+					ActionSpecifier: &routepb.RateLimit_Action_GenericKey_{
+						GenericKey: &routepb.RateLimit_Action_GenericKey{
+							DescriptorKey:   filtergen.QuotaMetricDescriptorKey,
+							DescriptorValue: metric,
+						},
+					},
+				},
+			},
+		})
+	}
+}
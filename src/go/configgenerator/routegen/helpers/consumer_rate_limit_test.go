@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestMaybeAddConsumerRateLimits(t *testing.T) {
+	limits := []options.ConsumerQuotaLimit{
+		{
+			ConsumerNumber: "123456",
+			Metric:         "bookstore.googleapis.com/read_requests",
+			Limit:          600,
+			Interval:       time.Minute,
+		},
+	}
+
+	testdata := []struct {
+		desc           string
+		opts           options.ConfigGeneratorOptions
+		quotaMetrics   []string
+		wantRateLimits []*routepb.RateLimit
+	}{
+		{
+			desc:         "No rate limit without consumer quota limits",
+			quotaMetrics: []string{"bookstore.googleapis.com/read_requests"},
+		},
+		{
+			desc: "Rate limits of the limited quota metrics",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					GeneratedHeaderPrefix: "X-Endpoint-",
+				},
+				ConsumerQuotaLimits: limits,
+			},
+			quotaMetrics: []string{"bookstore.googleapis.com/read_requests", "bookstore.googleapis.com/write_requests"},
+			wantRateLimits: []*routepb.RateLimit{
+				{
+					Actions: []*routepb.RateLimit_Action{
+						{
+							ActionSpecifier: &routepb.RateLimit_Action_RequestHeaders_{
+								RequestHeaders: &routepb.RateLimit_Action_RequestHeaders{
+									HeaderName:    "X-Endpoint-api-consumer-number",
+									DescriptorKey: "consumer_number",
+								},
+							},
+						},
+						{
+							ActionSpecifier: &routepb.RateLimit_Action_GenericKey_{
+								GenericKey: &routepb.RateLimit_Action_GenericKey{
+									DescriptorKey:   "quota_metric",
+									DescriptorValue: "bookstore.googleapis.com/read_requests",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "No rate limit for the operation consuming no limited metric",
+			opts: options.ConfigGeneratorOptions{
+				ConsumerQuotaLimits: limits,
+			},
+			quotaMetrics: []string{"bookstore.googleapis.com/write_requests"},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			routeAction := &routepb.RouteAction{}
+			MaybeAddConsumerRateLimits(NewRouteConsumerRateLimitConfigerFromOPConfig(tc.opts), routeAction, &MethodCfg{
+				OperationName: "bookstore.Bookstore.GetShelf",
+				QuotaMetrics:  tc.quotaMetrics,
+			})
+
+			if diff := cmp.Diff(tc.wantRateLimits, routeAction.RateLimits, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddConsumerRateLimits() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
@@ -13,25 +14,56 @@ import (
 // backend routes.
 type RouteDeadlineConfiger struct {
 	GlobalStreamIdleTimeout time.Duration
+
+	// OperationRequestTimeouts overrides the deadlines of the operations from
+	// the service config.
+	OperationRequestTimeouts string
 }
 
 // NewRouteDeadlineConfigerFromOPConfig creates a RouteDeadlineConfiger from
 // ESPv2 options.
 func NewRouteDeadlineConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteDeadlineConfiger {
 	return &RouteDeadlineConfiger{
-		GlobalStreamIdleTimeout: opts.StreamIdleTimeout,
+		GlobalStreamIdleTimeout:  opts.StreamIdleTimeout,
+		OperationRequestTimeouts: opts.OperationRequestTimeouts,
 	}
 }
 
 // MaybeAddDeadlines adds the generated deadline config to the route action.
-func MaybeAddDeadlines(c *RouteDeadlineConfiger, routeAction *routepb.RouteAction, deadline time.Duration, isStreaming bool) {
+func MaybeAddDeadlines(c *RouteDeadlineConfiger, routeAction *routepb.RouteAction, methodCfg *MethodCfg) error {
 	if c == nil {
-		return
+		return nil
+	}
+
+	deadline, err := c.MakeRequestTimeout(methodCfg.OperationName, methodCfg.Deadline)
+	if err != nil {
+		return fmt.Errorf("fail to create deadlines for routeAction: %v", err)
 	}
 
-	newDeadline, idleTimeout := c.CalcIdleTimeout(deadline, isStreaming)
+	newDeadline, idleTimeout := c.CalcIdleTimeout(deadline, methodCfg.IsStreaming)
 	routeAction.Timeout = durationpb.New(newDeadline)
 	routeAction.IdleTimeout = durationpb.New(idleTimeout)
+	return nil
+}
+
+// MakeRequestTimeout returns the request timeout of the operation overridden
+// by flag --operation_request_timeouts, or the deadline from the service
+// config if not overridden.
+func (c *RouteDeadlineConfiger) MakeRequestTimeout(operation string, deadline time.Duration) (time.Duration, error) {
+	opRequestTimeouts, err := util.ParseSelectorMap(c.OperationRequestTimeouts)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flag --operation_request_timeouts: %v", err)
+	}
+	value, ok := opRequestTimeouts.Lookup(operation)
+	if !ok {
+		return deadline, nil
+	}
+
+	requestTimeout, err := time.ParseDuration(value)
+	if err != nil || requestTimeout <= 0 {
+		return 0, fmt.Errorf("invalid request timeout %q for operation %q, must be a positive duration", value, operation)
+	}
+	return requestTimeout, nil
 }
 
 // CalcIdleTimeout will return the correct idle timeout based on method properties.
@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+const (
+	deprecationHeaderKey = "Deprecation"
+	sunsetHeaderKey      = "Sunset"
+
+	// DeprecatedStatPrefix prefixes the route statistics of the deprecated
+	// operations, so their hits can be tracked.
+	DeprecatedStatPrefix = "deprecated."
+)
+
+// RouteDeprecationConfiger is a helper to signal the deprecation of the
+// operations to the clients with the Deprecation and Sunset response headers,
+// and to count their requests in the route statistics.
+type RouteDeprecationConfiger struct {
+	// OperationDeprecations is the selector map of the sunset dates of the
+	// operations, or "true" and "false" to override the deprecation in the
+	// service config.
+	OperationDeprecations string
+}
+
+// NewRouteDeprecationConfigerFromOPConfig creates a RouteDeprecationConfiger from
+// ESPv2 options.
+func NewRouteDeprecationConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteDeprecationConfiger {
+	if !opts.EnableDeprecationHeaders && opts.OperationDeprecations == "" {
+		return nil
+	}
+
+	return &RouteDeprecationConfiger{
+		OperationDeprecations: opts.OperationDeprecations,
+	}
+}
+
+// MaybeAddDeprecation adds the deprecation headers and the deprecated stat
+// prefix to the route of a deprecated operation. It must be called after the
+// operation stat prefix is added.
+func MaybeAddDeprecation(c *RouteDeprecationConfiger, route *routepb.Route, methodCfg *MethodCfg) error {
+	if c == nil {
+		return nil
+	}
+
+	deprecated, sunset, err := c.LookupDeprecation(methodCfg.OperationName, methodCfg.IsDeprecated)
+	if err != nil {
+		return err
+	}
+	if !deprecated {
+		return nil
+	}
+
+	route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, &corepb.HeaderValueOption{
+		Header: &corepb.HeaderValue{
+			Key:   deprecationHeaderKey,
+			Value: "true",
+		},
+		AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+	})
+	if !sunset.IsZero() {
+		route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, &corepb.HeaderValueOption{
+			Header: &corepb.HeaderValue{
+				Key:   sunsetHeaderKey,
+				Value: sunset.UTC().Format(http.TimeFormat),
+			},
+			AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+		})
+	}
+	route.StatPrefix = DeprecatedStatPrefix + methodCfg.OperationName
+	return nil
+}
+
+// LookupDeprecation returns whether the operation is deprecated and its sunset
+// date, zero if unknown. The flag overrides the deprecation in the service
+// config.
+func (c *RouteDeprecationConfiger) LookupDeprecation(operation string, isDeprecated bool) (bool, time.Time, error) {
+	opDeprecations, err := util.ParseSelectorMap(c.OperationDeprecations)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid flag --operation_deprecations: %v", err)
+	}
+	value, ok := opDeprecations.Lookup(operation)
+	if !ok {
+		return isDeprecated, time.Time{}, nil
+	}
+
+	if deprecated, err := strconv.ParseBool(value); err == nil {
+		return deprecated, time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if sunset, err := time.Parse(layout, value); err == nil {
+			return true, sunset, nil
+		}
+	}
+	return false, time.Time{}, fmt.Errorf("invalid flag --operation_deprecations, %q for operation %q must be true, false or a sunset date such as 2025-06-30", value, operation)
+}
@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestMaybeAddDeprecation(t *testing.T) {
+	deprecationHeader := &corepb.HeaderValueOption{
+		Header: &corepb.HeaderValue{
+			Key:   "Deprecation",
+			Value: "true",
+		},
+		AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+	}
+
+	testdata := []struct {
+		desc           string
+		opts           options.ConfigGeneratorOptions
+		isDeprecated   bool
+		wantHeaders    []*corepb.HeaderValueOption
+		wantStatPrefix string
+		wantError      string
+	}{
+		{
+			desc:         "Deprecation headers are disabled by default",
+			isDeprecated: true,
+		},
+		{
+			desc: "Operation deprecated in the service config",
+			opts: options.ConfigGeneratorOptions{
+				EnableDeprecationHeaders: true,
+			},
+			isDeprecated:   true,
+			wantHeaders:    []*corepb.HeaderValueOption{deprecationHeader},
+			wantStatPrefix: "deprecated.bookstore.Bookstore.GetShelf",
+		},
+		{
+			desc: "Operation not deprecated",
+			opts: options.ConfigGeneratorOptions{
+				EnableDeprecationHeaders: true,
+			},
+		},
+		{
+			desc: "Operation deprecated with a sunset date",
+			opts: options.ConfigGeneratorOptions{
+				OperationDeprecations: "bookstore.Bookstore.Get*=2025-06-30",
+			},
+			wantHeaders: []*corepb.HeaderValueOption{
+				deprecationHeader,
+				{
+					Header: &corepb.HeaderValue{
+						Key:   "Sunset",
+						Value: "Mon, 30 Jun 2025 00:00:00 GMT",
+					},
+					AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+				},
+			},
+			wantStatPrefix: "deprecated.bookstore.Bookstore.GetShelf",
+		},
+		{
+			desc: "Operation deprecated with an RFC 3339 sunset time",
+			opts: options.ConfigGeneratorOptions{
+				OperationDeprecations: "bookstore.Bookstore.GetShelf=2025-06-30T12:00:00-07:00",
+			},
+			wantHeaders: []*corepb.HeaderValueOption{
+				deprecationHeader,
+				{
+					Header: &corepb.HeaderValue{
+						Key:   "Sunset",
+						Value: "Mon, 30 Jun 2025 19:00:00 GMT",
+					},
+					AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+				},
+			},
+			wantStatPrefix: "deprecated.bookstore.Bookstore.GetShelf",
+		},
+		{
+			desc: "Flag overrides the deprecation in the service config",
+			opts: options.ConfigGeneratorOptions{
+				EnableDeprecationHeaders: true,
+				OperationDeprecations:    "bookstore.Bookstore.GetShelf=false",
+			},
+			isDeprecated: true,
+		},
+		{
+			desc: "Invalid sunset date",
+			opts: options.ConfigGeneratorOptions{
+				OperationDeprecations: "bookstore.Bookstore.GetShelf=next year",
+			},
+			wantError: `invalid flag --operation_deprecations, "next year" for operation "bookstore.Bookstore.GetShelf" must be true, false or a sunset date`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			methodCfg := &MethodCfg{
+				OperationName: "bookstore.Bookstore.GetShelf",
+				IsDeprecated:  tc.isDeprecated,
+			}
+
+			err := MaybeAddDeprecation(NewRouteDeprecationConfigerFromOPConfig(tc.opts), route, methodCfg)
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddDeprecation() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeAddDeprecation() got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantHeaders, route.ResponseHeadersToAdd, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddDeprecation() diff in headers (-want +got):\n%s", diff)
+			}
+			if route.StatPrefix != tc.wantStatPrefix {
+				t.Errorf("MaybeAddDeprecation() got stat prefix %q, want %q", route.StatPrefix, tc.wantStatPrefix)
+			}
+		})
+	}
+}
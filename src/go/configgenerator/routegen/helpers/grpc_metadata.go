@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	consumerNumberHeaderSuffix = "api-consumer-number"
+)
+
+// RouteGrpcMetadataConfiger is a helper to inject the static or templated
+// gRPC metadata into the requests of the gRPC routes.
+type RouteGrpcMetadataConfiger struct {
+	// Metadata is the ';' separated key=value pairs of flag --grpc_metadata.
+	Metadata              string
+	GeneratedHeaderPrefix string
+}
+
+// NewRouteGrpcMetadataConfigerFromOPConfig creates a RouteGrpcMetadataConfiger from
+// ESPv2 options.
+func NewRouteGrpcMetadataConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteGrpcMetadataConfiger {
+	if opts.GrpcMetadata == "" {
+		return nil
+	}
+
+	return &RouteGrpcMetadataConfiger{
+		Metadata:              opts.GrpcMetadata,
+		GeneratedHeaderPrefix: opts.GeneratedHeaderPrefix,
+	}
+}
+
+// MaybeAddGrpcMetadata adds the gRPC metadata to the request headers of the
+// route if it is a gRPC route. It must be called after the operation name
+// header is added.
+func MaybeAddGrpcMetadata(c *RouteGrpcMetadataConfiger, route *routepb.Route, methodCfg *MethodCfg) error {
+	if c == nil || !methodCfg.IsGrpc {
+		return nil
+	}
+
+	headers, err := c.MakeGrpcMetadata(methodCfg.OperationName)
+	if err != nil {
+		return err
+	}
+	route.RequestHeadersToAdd = append(route.RequestHeadersToAdd, headers...)
+	return nil
+}
+
+// MakeGrpcMetadata creates the request headers of the gRPC metadata for the
+// operation. The values may contain the following templates:
+//   - {consumer_number}: the project number of the API consumer validated by
+//     Service Control, empty if there is none.
+//   - {operation}: the selector of the operation.
+//   - {client_ip}: the IP address of the downstream client.
+func (c *RouteGrpcMetadataConfiger) MakeGrpcMetadata(operation string) ([]*corepb.HeaderValueOption, error) {
+	templates := map[string]string{
+		"{consumer_number}": fmt.Sprintf("%%REQ(%s)%%", strings.ToLower(c.GeneratedHeaderPrefix+consumerNumberHeaderSuffix)),
+		"{operation}":       escapeHeaderFormatter(operation),
+		"{client_ip}":       "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+	}
+
+	var headers []*corepb.HeaderValueOption
+	for _, kv := range strings.Split(c.Metadata, ";") {
+		if kv == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid flag --grpc_metadata, %q should be in key=value format", kv)
+		}
+		if err := validateGrpcMetadataKey(key); err != nil {
+			return nil, fmt.Errorf("invalid flag --grpc_metadata: %v", err)
+		}
+
+		value = escapeHeaderFormatter(value)
+		for template, formatter := range templates {
+			value = strings.ReplaceAll(value, template, formatter)
+		}
+
+		headers = append(headers, &corepb.HeaderValueOption{
+			Header: &corepb.HeaderValue{
+				Key:   key,
+				Value: value,
+			},
+			Append: &wrapperspb.BoolValue{
+				Value: false,
+			},
+		})
+	}
+	return headers, nil
+}
+
+// validateGrpcMetadataKey checks the key is a valid ASCII gRPC metadata key
+// which is not reserved by gRPC.
+func validateGrpcMetadataKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("metadata key cannot be empty")
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("metadata key %q must only contain lowercase letters, digits, '-', '_' and '.'", key)
+		}
+	}
+	if strings.HasPrefix(key, "grpc-") {
+		return fmt.Errorf("metadata key %q is reserved by gRPC", key)
+	}
+	if strings.HasSuffix(key, "-bin") {
+		return fmt.Errorf("binary metadata key %q is not supported", key)
+	}
+	return nil
+}
+
+// escapeHeaderFormatter escapes the '%' of the literal header value, which
+// starts the command operators of the Envoy header formatter.
+func escapeHeaderFormatter(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
@@ -0,0 +1,117 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMaybeAddGrpcMetadata(t *testing.T) {
+	testdata := []struct {
+		desc        string
+		metadata    string
+		isGrpc      bool
+		wantHeaders []*corepb.HeaderValueOption
+		wantError   string
+	}{
+		{
+			desc:   "Disabled by default",
+			isGrpc: true,
+		},
+		{
+			desc:     "HTTP routes are skipped",
+			metadata: "x-api-gateway=espv2",
+		},
+		{
+			desc:     "Static and templated metadata",
+			metadata: "x-api-gateway=espv2;x-consumer=project-{consumer_number};x-op={operation};x-rate=100%",
+			isGrpc:   true,
+			wantHeaders: []*corepb.HeaderValueOption{
+				{
+					Header: &corepb.HeaderValue{Key: "x-api-gateway", Value: "espv2"},
+					Append: &wrapperspb.BoolValue{Value: false},
+				},
+				{
+					Header: &corepb.HeaderValue{Key: "x-consumer", Value: "project-%REQ(x-endpoint-api-consumer-number)%"},
+					Append: &wrapperspb.BoolValue{Value: false},
+				},
+				{
+					Header: &corepb.HeaderValue{Key: "x-op", Value: "bookstore.Bookstore.GetShelf"},
+					Append: &wrapperspb.BoolValue{Value: false},
+				},
+				{
+					Header: &corepb.HeaderValue{Key: "x-rate", Value: "100%%"},
+					Append: &wrapperspb.BoolValue{Value: false},
+				},
+			},
+		},
+		{
+			desc:     "Client IP template",
+			metadata: "x-client-ip={client_ip}",
+			isGrpc:   true,
+			wantHeaders: []*corepb.HeaderValueOption{
+				{
+					Header: &corepb.HeaderValue{Key: "x-client-ip", Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%"},
+					Append: &wrapperspb.BoolValue{Value: false},
+				},
+			},
+		},
+		{
+			desc:      "Not in key=value format",
+			metadata:  "x-api-gateway",
+			isGrpc:    true,
+			wantError: `invalid flag --grpc_metadata, "x-api-gateway" should be in key=value format`,
+		},
+		{
+			desc:      "Uppercase key",
+			metadata:  "X-Api-Gateway=espv2",
+			isGrpc:    true,
+			wantError: `metadata key "X-Api-Gateway" must only contain lowercase letters`,
+		},
+		{
+			desc:      "Reserved key",
+			metadata:  "grpc-timeout=1S",
+			isGrpc:    true,
+			wantError: `metadata key "grpc-timeout" is reserved by gRPC`,
+		},
+		{
+			desc:      "Binary key",
+			metadata:  "x-trace-bin=AAAA",
+			isGrpc:    true,
+			wantError: `binary metadata key "x-trace-bin" is not supported`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.GeneratedHeaderPrefix = "X-Endpoint-"
+			opts.GrpcMetadata = tc.metadata
+
+			route := &routepb.Route{}
+			err := MaybeAddGrpcMetadata(NewRouteGrpcMetadataConfigerFromOPConfig(opts), route, &MethodCfg{
+				OperationName: "bookstore.Bookstore.GetShelf",
+				IsGrpc:        tc.isGrpc,
+			})
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddGrpcMetadata() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MaybeAddGrpcMetadata() got error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantHeaders, route.RequestHeadersToAdd, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddGrpcMetadata() request headers diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RouteHedgeConfiger is a helper to hedge the idempotent requests to the
+// backend. When an attempt does not respond within the per-try timeout,
+// another attempt is sent without cancelling it and the first response wins.
+type RouteHedgeConfiger struct {
+	HedgeGetRequests bool
+
+	// OperationHedgeDelays opts the operations into hedging with their own
+	// initial hedge delay, which overrides the per-try timeout.
+	OperationHedgeDelays string
+}
+
+// NewRouteHedgeConfigerFromOPConfig creates a RouteHedgeConfiger from
+// ESPv2 options.
+func NewRouteHedgeConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteHedgeConfiger {
+	if !opts.BackendHedgeGetRequests && opts.OperationHedgeDelays == "" {
+		return nil
+	}
+
+	return &RouteHedgeConfiger{
+		HedgeGetRequests:     opts.BackendHedgeGetRequests,
+		OperationHedgeDelays: opts.OperationHedgeDelays,
+	}
+}
+
+// MaybeAddHedgePolicy adds the generated hedge policy to the route action if
+// the method is hedged. It must be called after the retry policy is added, as
+// the hedged attempts are bounded by its per-try timeout and number of
+// retries.
+func MaybeAddHedgePolicy(c *RouteHedgeConfiger, routeAction *routepb.RouteAction, methodCfg *MethodCfg) error {
+	if c == nil {
+		return nil
+	}
+
+	hedgeDelay, err := c.MakeHedgeDelay(methodCfg.OperationName)
+	if err != nil {
+		return fmt.Errorf("fail to create hedge policy for routeAction: %v", err)
+	}
+
+	retryPolicy := routeAction.GetRetryPolicy()
+	switch {
+	case hedgeDelay > 0:
+		if retryPolicy.GetNumRetries().GetValue() == 0 {
+			return fmt.Errorf("fail to create hedge policy for operation %q: flag --operation_hedge_delays requires flag --backend_retry_num > 0", methodCfg.OperationName)
+		}
+		retryPolicy.PerTryTimeout = durationpb.New(hedgeDelay)
+	case c.IsHedgedGet(methodCfg):
+		if retryPolicy.GetPerTryTimeout() == nil {
+			return fmt.Errorf("fail to create hedge policy for operation %q: flag --backend_hedge_get_requests requires flag --backend_per_try_timeout", methodCfg.OperationName)
+		}
+		if retryPolicy.GetNumRetries().GetValue() == 0 {
+			return fmt.Errorf("fail to create hedge policy for operation %q: flag --backend_hedge_get_requests requires flag --backend_retry_num > 0", methodCfg.OperationName)
+		}
+	default:
+		return nil
+	}
+
+	routeAction.HedgePolicy = &routepb.HedgePolicy{
+		HedgeOnPerTryTimeout: true,
+	}
+	return nil
+}
+
+// MakeHedgeDelay returns the initial hedge delay of the operation opted into
+// hedging, or 0 if it is not opted in.
+func (c *RouteHedgeConfiger) MakeHedgeDelay(operation string) (time.Duration, error) {
+	opHedgeDelays, err := util.ParseSelectorMap(c.OperationHedgeDelays)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flag --operation_hedge_delays: %v", err)
+	}
+	value, ok := opHedgeDelays.Lookup(operation)
+	if !ok {
+		return 0, nil
+	}
+
+	hedgeDelay, err := time.ParseDuration(value)
+	if err != nil || hedgeDelay <= 0 {
+		return 0, fmt.Errorf("invalid hedge delay %q for operation %q, must be a positive duration", value, operation)
+	}
+	return hedgeDelay, nil
+}
+
+// IsHedgedGet returns whether the requests of the method are hedged by flag
+// --backend_hedge_get_requests. Only the GET methods are, as they are
+// idempotent.
+func (c *RouteHedgeConfiger) IsHedgedGet(methodCfg *MethodCfg) bool {
+	return c.HedgeGetRequests && methodCfg.HTTPPattern != nil && methodCfg.HTTPPattern.HttpMethod == util.GET
+}
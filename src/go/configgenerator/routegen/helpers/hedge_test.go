@@ -0,0 +1,160 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMaybeAddHedgePolicy(t *testing.T) {
+	testdata := []struct {
+		desc              string
+		opts              options.ConfigGeneratorOptions
+		httpMethod        string
+		wantHedgePolicy   *routepb.HedgePolicy
+		wantPerTryTimeout *durationpb.Duration
+		wantError         string
+	}{
+		{
+			desc: "Hedging is disabled by default",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:      1,
+				BackendPerTryTimeout: time.Second,
+			},
+			httpMethod: "GET",
+		},
+		{
+			desc: "GET requests are hedged on the per-try timeout",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:         1,
+				BackendPerTryTimeout:    time.Second,
+				BackendHedgeGetRequests: true,
+			},
+			httpMethod: "GET",
+			wantHedgePolicy: &routepb.HedgePolicy{
+				HedgeOnPerTryTimeout: true,
+			},
+			wantPerTryTimeout: durationpb.New(time.Second),
+		},
+		{
+			desc: "POST requests are not hedged",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:         1,
+				BackendPerTryTimeout:    time.Second,
+				BackendHedgeGetRequests: true,
+			},
+			httpMethod: "POST",
+		},
+		{
+			desc: "Hedging requires the per-try timeout",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:         1,
+				BackendHedgeGetRequests: true,
+			},
+			httpMethod: "GET",
+			wantError:  "flag --backend_hedge_get_requests requires flag --backend_per_try_timeout",
+		},
+		{
+			desc: "Hedging requires the retries",
+			opts: options.ConfigGeneratorOptions{
+				BackendPerTryTimeout:    time.Second,
+				BackendHedgeGetRequests: true,
+			},
+			httpMethod: "GET",
+			wantError:  "flag --backend_hedge_get_requests requires flag --backend_retry_num > 0",
+		},
+		{
+			desc: "Opted-in operation is hedged with its hedge delay",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:      2,
+				BackendPerTryTimeout: time.Second,
+				OperationHedgeDelays: "bookstore.Bookstore.ListShelves=200ms;bookstore.Bookstore.Get*=50ms",
+			},
+			httpMethod: "POST",
+			wantHedgePolicy: &routepb.HedgePolicy{
+				HedgeOnPerTryTimeout: true,
+			},
+			wantPerTryTimeout: durationpb.New(50 * time.Millisecond),
+		},
+		{
+			desc: "Other operations are not hedged",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:      1,
+				OperationHedgeDelays: "bookstore.Bookstore.ListShelves=200ms",
+			},
+			httpMethod: "GET",
+		},
+		{
+			desc: "Hedge delay overrides the GET hedging",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:         1,
+				BackendHedgeGetRequests: true,
+				OperationHedgeDelays:    "bookstore.Bookstore.GetShelf=100ms",
+			},
+			httpMethod: "GET",
+			wantHedgePolicy: &routepb.HedgePolicy{
+				HedgeOnPerTryTimeout: true,
+			},
+			wantPerTryTimeout: durationpb.New(100 * time.Millisecond),
+		},
+		{
+			desc: "Opted-in operation requires the retries",
+			opts: options.ConfigGeneratorOptions{
+				OperationHedgeDelays: "bookstore.Bookstore.GetShelf=100ms",
+			},
+			httpMethod: "GET",
+			wantError:  "flag --operation_hedge_delays requires flag --backend_retry_num > 0",
+		},
+		{
+			desc: "Invalid hedge delay",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:      1,
+				OperationHedgeDelays: "bookstore.Bookstore.GetShelf=soon",
+			},
+			httpMethod: "GET",
+			wantError:  `invalid hedge delay "soon" for operation "bookstore.Bookstore.GetShelf"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			methodCfg := &MethodCfg{
+				OperationName: "bookstore.Bookstore.GetShelf",
+				HTTPPattern: &httppattern.Pattern{
+					HttpMethod: tc.httpMethod,
+				},
+			}
+			routeAction := &routepb.RouteAction{}
+			if err := MaybeAddRetryPolicy(NewRouteRetryConfigerFromOPConfig(tc.opts), routeAction, methodCfg); err != nil {
+				t.Fatalf("MaybeAddRetryPolicy() got error: %v", err)
+			}
+
+			err := MaybeAddHedgePolicy(NewRouteHedgeConfigerFromOPConfig(tc.opts), routeAction, methodCfg)
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddHedgePolicy() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeAddHedgePolicy() got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantHedgePolicy, routeAction.HedgePolicy, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddHedgePolicy() diff (-want +got):\n%s", diff)
+			}
+			if tc.wantHedgePolicy != nil {
+				if diff := cmp.Diff(tc.wantPerTryTimeout, routeAction.GetRetryPolicy().GetPerTryTimeout(), protocmp.Transform()); diff != "" {
+					t.Errorf("MaybeAddHedgePolicy() per-try timeout diff (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
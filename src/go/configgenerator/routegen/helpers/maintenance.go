@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+const (
+	// DefaultMaintenanceResponse is served in the maintenance mode if flag
+	// --maintenance_response is not set.
+	DefaultMaintenanceResponse = `{"status": 503, "body": "The service is temporarily unavailable for maintenance."}`
+)
+
+// ParseMaintenanceResponse parses the static response served in the
+// maintenance mode from flag --maintenance_response.
+func ParseMaintenanceResponse(response string) (*StaticResponse, error) {
+	if response == "" {
+		response = DefaultMaintenanceResponse
+	}
+
+	r, err := ParseStaticResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --maintenance_response: %v", err)
+	}
+	return r, nil
+}
+
+// RouteMaintenanceConfiger is a helper to serve the maintenance response
+// instead of calling the backend for the operations under maintenance.
+type RouteMaintenanceConfiger struct {
+	// Operations are the comma separated selectors of the operations under
+	// maintenance, which may contain "*" wildcards.
+	Operations string
+	Response   string
+}
+
+// NewRouteMaintenanceConfigerFromOPConfig creates a RouteMaintenanceConfiger from
+// ESPv2 options.
+func NewRouteMaintenanceConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteMaintenanceConfiger {
+	if opts.MaintenanceOperations == "" {
+		return nil
+	}
+
+	return &RouteMaintenanceConfiger{
+		Operations: opts.MaintenanceOperations,
+		Response:   opts.MaintenanceResponse,
+	}
+}
+
+// MaybeAddMaintenanceResponse replaces the action of the route with the
+// maintenance response if the operation is under maintenance. It must be
+// called after the other response headers are added to the route.
+func MaybeAddMaintenanceResponse(c *RouteMaintenanceConfiger, route *routepb.Route, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	underMaintenance, err := c.IsUnderMaintenance(operation)
+	if err != nil {
+		return err
+	}
+	if !underMaintenance {
+		return nil
+	}
+
+	response, err := ParseMaintenanceResponse(c.Response)
+	if err != nil {
+		return err
+	}
+	response.ApplyTo(route)
+	return nil
+}
+
+// IsUnderMaintenance returns whether the operation matches any selector of
+// flag --maintenance_operations.
+func (c *RouteMaintenanceConfiger) IsUnderMaintenance(operation string) (bool, error) {
+	for _, selector := range strings.Split(c.Operations, ",") {
+		selector = strings.TrimSpace(selector)
+		if selector == "" {
+			continue
+		}
+		matched, err := path.Match(selector, operation)
+		if err != nil {
+			return false, fmt.Errorf("invalid flag --maintenance_operations, invalid selector %q: %v", selector, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+const cacheControlHeaderKey = "Cache-Control"
+
+// RouteResponseCacheConfiger is a helper to add the TTLs of the GET operations
+// to their Cache-Control response headers, so their responses are cached by
+// the response cache filter for the TTL of the operation.
+type RouteResponseCacheConfiger struct {
+	// OperationResponseCacheTtl is the selector map of the TTLs of the cached
+	// responses, 0 to disable the cache of the operation.
+	OperationResponseCacheTtl string
+}
+
+// NewRouteResponseCacheConfigerFromOPConfig creates a RouteResponseCacheConfiger from
+// ESPv2 options.
+func NewRouteResponseCacheConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteResponseCacheConfiger {
+	if opts.OperationResponseCacheTtl == "" {
+		return nil
+	}
+
+	return &RouteResponseCacheConfiger{
+		OperationResponseCacheTtl: opts.OperationResponseCacheTtl,
+	}
+}
+
+// MaybeAddResponseCacheTtl adds the TTL of a GET operation to the
+// Cache-Control response header of its route as s-maxage, which only applies
+// to the shared caches. It is appended to the directives of the backend, so
+// "private" and "no-store" still prevent caching. A TTL of 0 replaces the
+// header with "no-store". The header is sent to the clients too.
+func MaybeAddResponseCacheTtl(c *RouteResponseCacheConfiger, route *routepb.Route, methodCfg *MethodCfg) error {
+	if c == nil || methodCfg.HTTPPattern == nil || methodCfg.HTTPPattern.HttpMethod != http.MethodGet {
+		return nil
+	}
+
+	ttl, ok, err := c.LookupTtl(methodCfg.OperationName)
+	if err != nil || !ok {
+		return err
+	}
+
+	cacheControl := "no-store"
+	appendAction := corepb.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD
+	if ttl > 0 {
+		cacheControl = fmt.Sprintf("s-maxage=%d", int64(ttl/time.Second))
+		appendAction = corepb.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD
+	}
+	route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, &corepb.HeaderValueOption{
+		Header: &corepb.HeaderValue{
+			Key:   cacheControlHeaderKey,
+			Value: cacheControl,
+		},
+		AppendAction: appendAction,
+	})
+	return nil
+}
+
+// LookupTtl returns the TTL of the cached responses of the operation, or
+// false if the Cache-Control headers of the backend are not overridden.
+func (c *RouteResponseCacheConfiger) LookupTtl(operation string) (time.Duration, bool, error) {
+	opTtls, err := util.ParseSelectorMap(c.OperationResponseCacheTtl)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid flag --operation_response_cache_ttl: %v", err)
+	}
+	value, ok := opTtls.Lookup(operation)
+	if !ok {
+		return 0, false, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 || ttl%time.Second != 0 {
+		return 0, false, fmt.Errorf("invalid flag --operation_response_cache_ttl, %q for operation %q must be a duration of whole seconds such as 60s", value, operation)
+	}
+	return ttl, true, nil
+}
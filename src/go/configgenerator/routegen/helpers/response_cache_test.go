@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestMaybeAddResponseCacheTtl(t *testing.T) {
+	testdata := []struct {
+		desc        string
+		opts        options.ConfigGeneratorOptions
+		httpMethod  string
+		wantHeaders []*corepb.HeaderValueOption
+		wantError   string
+	}{
+		{
+			desc:       "Cache-Control is not overridden by default",
+			httpMethod: "GET",
+		},
+		{
+			desc: "TTL of the operation is appended to the Cache-Control of the backend",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseCacheTtl: "bookstore.Bookstore.Get*=5m",
+			},
+			httpMethod: "GET",
+			wantHeaders: []*corepb.HeaderValueOption{
+				{
+					Header: &corepb.HeaderValue{
+						Key:   "Cache-Control",
+						Value: "s-maxage=300",
+					},
+					AppendAction: corepb.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD,
+				},
+			},
+		},
+		{
+			desc: "Cache disabled for the operation",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseCacheTtl: "bookstore.Bookstore.GetShelf=0s;*=60s",
+			},
+			httpMethod: "GET",
+			wantHeaders: []*corepb.HeaderValueOption{
+				{
+					Header: &corepb.HeaderValue{
+						Key:   "Cache-Control",
+						Value: "no-store",
+					},
+					AppendAction: corepb.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+				},
+			},
+		},
+		{
+			desc: "Only the GET routes are cached",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseCacheTtl: "*=60s",
+			},
+			httpMethod: "POST",
+		},
+		{
+			desc: "TTL must be whole seconds",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseCacheTtl: "bookstore.Bookstore.GetShelf=1500ms",
+			},
+			httpMethod: "GET",
+			wantError:  `invalid flag --operation_response_cache_ttl, "1500ms" for operation "bookstore.Bookstore.GetShelf" must be a duration of whole seconds`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			methodCfg := &MethodCfg{
+				OperationName: "bookstore.Bookstore.GetShelf",
+				HTTPPattern: &httppattern.Pattern{
+					HttpMethod: tc.httpMethod,
+				},
+			}
+
+			err := MaybeAddResponseCacheTtl(NewRouteResponseCacheConfigerFromOPConfig(tc.opts), route, methodCfg)
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddResponseCacheTtl() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeAddResponseCacheTtl() got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantHeaders, route.ResponseHeadersToAdd, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddResponseCacheTtl() diff in headers (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// RouteResponseHeadersConfiger is a helper to remove the response headers of
+// the operations, such as the ones flagged by the compliance scans as
+// information disclosure.
+type RouteResponseHeadersConfiger struct {
+	// SuppressUpstreamServiceTime removes x-envoy-upstream-service-time from
+	// all the operations.
+	SuppressUpstreamServiceTime bool
+
+	// OperationResponseHeadersToRemove is the selector map of the comma
+	// separated response headers removed per operation.
+	OperationResponseHeadersToRemove string
+}
+
+// NewRouteResponseHeadersConfigerFromOPConfig creates a RouteResponseHeadersConfiger from
+// ESPv2 options.
+func NewRouteResponseHeadersConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteResponseHeadersConfiger {
+	if !opts.SuppressUpstreamServiceTime && opts.OperationResponseHeadersToRemove == "" {
+		return nil
+	}
+
+	return &RouteResponseHeadersConfiger{
+		SuppressUpstreamServiceTime:      opts.SuppressUpstreamServiceTime,
+		OperationResponseHeadersToRemove: opts.OperationResponseHeadersToRemove,
+	}
+}
+
+// MaybeRemoveResponseHeaders adds the response headers of the operation to
+// the headers removed by the route, skipping the ones already removed.
+func MaybeRemoveResponseHeaders(c *RouteResponseHeadersConfiger, route *routepb.Route, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	headers, err := c.MakeResponseHeadersToRemove(operation)
+	if err != nil {
+		return err
+	}
+
+	removed := make(map[string]bool)
+	for _, header := range route.ResponseHeadersToRemove {
+		removed[strings.ToLower(header)] = true
+	}
+	for _, header := range headers {
+		if removed[header] {
+			continue
+		}
+		removed[header] = true
+		route.ResponseHeadersToRemove = append(route.ResponseHeadersToRemove, header)
+	}
+	return nil
+}
+
+// MakeResponseHeadersToRemove returns the lower case response headers removed
+// from the operation.
+func (c *RouteResponseHeadersConfiger) MakeResponseHeadersToRemove(operation string) ([]string, error) {
+	var headers []string
+	if c.SuppressUpstreamServiceTime {
+		headers = append(headers, upstreamServiceTimeHeader)
+	}
+
+	opHeaders, err := util.ParseSelectorMap(c.OperationResponseHeadersToRemove)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_response_headers_to_remove: %v", err)
+	}
+	value, ok := opHeaders.Lookup(operation)
+	if !ok {
+		return headers, nil
+	}
+
+	for _, header := range strings.Split(value, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		// Envoy does not allow removing the pseudo headers.
+		if strings.HasPrefix(header, ":") {
+			return nil, fmt.Errorf("invalid flag --operation_response_headers_to_remove, pseudo header %q of operation %q cannot be removed", header, operation)
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
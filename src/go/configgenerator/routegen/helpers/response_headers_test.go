@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMaybeRemoveResponseHeaders(t *testing.T) {
+	testdata := []struct {
+		desc                string
+		opts                options.ConfigGeneratorOptions
+		propagateTimeout    bool
+		wantHeadersToRemove []string
+		wantError           string
+	}{
+		{
+			desc: "No response headers are removed by default",
+		},
+		{
+			desc: "Upstream service time is removed from all the operations",
+			opts: options.ConfigGeneratorOptions{
+				SuppressUpstreamServiceTime: true,
+			},
+			wantHeadersToRemove: []string{"x-envoy-upstream-service-time"},
+		},
+		{
+			desc: "Response headers are removed per operation",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseHeadersToRemove: "bookstore.Bookstore.Get*= X-Envoy-Upstream-Service-Time ,x-powered-by;bookstore.Bookstore.GetShelf=server",
+			},
+			wantHeadersToRemove: []string{"x-envoy-upstream-service-time", "x-powered-by"},
+		},
+		{
+			desc: "Other operations are not affected",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseHeadersToRemove: "bookstore.Bookstore.ListShelves=x-powered-by",
+			},
+		},
+		{
+			desc: "Headers already removed by the timeout propagation are skipped",
+			opts: options.ConfigGeneratorOptions{
+				SuppressEnvoyHeaders:             true,
+				PropagateRequestTimeout:          true,
+				SuppressUpstreamServiceTime:      true,
+				OperationResponseHeadersToRemove: "bookstore.Bookstore.GetShelf=x-envoy-upstream-service-time",
+			},
+			propagateTimeout:    true,
+			wantHeadersToRemove: []string{"x-envoy-upstream-service-time"},
+		},
+		{
+			desc: "Pseudo headers cannot be removed",
+			opts: options.ConfigGeneratorOptions{
+				OperationResponseHeadersToRemove: "bookstore.Bookstore.GetShelf=:status",
+			},
+			wantError: `pseudo header ":status" of operation "bookstore.Bookstore.GetShelf" cannot be removed`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{
+				Action: &routepb.Route_Route{
+					Route: &routepb.RouteAction{},
+				},
+			}
+			if tc.propagateTimeout {
+				MaybeAddTimeoutPropagation(NewRouteTimeoutPropagationConfigerFromOPConfig(tc.opts), route)
+			}
+
+			err := MaybeRemoveResponseHeaders(NewRouteResponseHeadersConfigerFromOPConfig(tc.opts), route, "bookstore.Bookstore.GetShelf")
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeRemoveResponseHeaders() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeRemoveResponseHeaders() got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantHeadersToRemove, route.ResponseHeadersToRemove); diff != "" {
+				t.Errorf("MaybeRemoveResponseHeaders() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
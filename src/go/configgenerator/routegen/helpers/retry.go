@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
@@ -22,6 +23,9 @@ type RouteRetryConfiger struct {
 	RetryNum           uint
 	RetryOnStatusCodes string
 	PerTryTimeout      time.Duration
+
+	// OperationPerTryTimeouts overrides PerTryTimeout for the operations.
+	OperationPerTryTimeouts string
 }
 
 // NewRouteRetryConfigerFromOPConfig creates a RouteRetryConfiger from
@@ -32,11 +36,15 @@ func NewRouteRetryConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *Rou
 		RetryNum:           opts.BackendRetryNum,
 		RetryOnStatusCodes: opts.BackendRetryOnStatusCodes,
 		PerTryTimeout:      opts.BackendPerTryTimeout,
+
+		OperationPerTryTimeouts: opts.OperationPerTryTimeouts,
 	}
 }
 
 // MaybeAddRetryPolicy adds the generated Retry config to the route action.
-func MaybeAddRetryPolicy(c *RouteRetryConfiger, routeAction *routepb.RouteAction) error {
+// It must be called after the deadlines are added, as the per-try timeout of
+// the operation must be less than its response timeout.
+func MaybeAddRetryPolicy(c *RouteRetryConfiger, routeAction *routepb.RouteAction, methodCfg *MethodCfg) error {
 	if c == nil {
 		return nil
 	}
@@ -46,10 +54,41 @@ func MaybeAddRetryPolicy(c *RouteRetryConfiger, routeAction *routepb.RouteAction
 		return fmt.Errorf("fail to create backend retry policy for routeAction: %v", err)
 	}
 
+	perTryTimeout, err := c.MakeOperationPerTryTimeout(methodCfg.OperationName)
+	if err != nil {
+		return fmt.Errorf("fail to create backend retry policy for routeAction: %v", err)
+	}
+	if perTryTimeout > 0 {
+		// Streaming routes have no response timeout.
+		if timeout := routeAction.GetTimeout().AsDuration(); timeout > 0 && perTryTimeout >= timeout {
+			return fmt.Errorf("per-try timeout %v of operation %q must be less than its response timeout %v", perTryTimeout, methodCfg.OperationName, timeout)
+		}
+		retryPolicy.PerTryTimeout = durationpb.New(perTryTimeout)
+	}
+
 	routeAction.RetryPolicy = retryPolicy
 	return nil
 }
 
+// MakeOperationPerTryTimeout returns the per-try timeout of the operation set
+// by flag --operation_per_try_timeouts, or 0 if not set.
+func (c *RouteRetryConfiger) MakeOperationPerTryTimeout(operation string) (time.Duration, error) {
+	opPerTryTimeouts, err := util.ParseSelectorMap(c.OperationPerTryTimeouts)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flag --operation_per_try_timeouts: %v", err)
+	}
+	value, ok := opPerTryTimeouts.Lookup(operation)
+	if !ok {
+		return 0, nil
+	}
+
+	perTryTimeout, err := time.ParseDuration(value)
+	if err != nil || perTryTimeout <= 0 {
+		return 0, fmt.Errorf("invalid per-try timeout %q for operation %q, must be a positive duration", value, operation)
+	}
+	return perTryTimeout, nil
+}
+
 // MakeRetryConfig creates the backend retry config.
 //
 // Forked from `service_info.go` and `route_generator.go`
@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMaybeAddOperationTimeouts(t *testing.T) {
+	testdata := []struct {
+		desc              string
+		opts              options.ConfigGeneratorOptions
+		deadline          time.Duration
+		isStreaming       bool
+		wantTimeout       *durationpb.Duration
+		wantPerTryTimeout *durationpb.Duration
+		wantError         string
+	}{
+		{
+			desc: "Global per-try timeout and deadline by default",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:      1,
+				BackendPerTryTimeout: 5 * time.Second,
+			},
+			deadline:          20 * time.Second,
+			wantTimeout:       durationpb.New(20 * time.Second),
+			wantPerTryTimeout: durationpb.New(5 * time.Second),
+		},
+		{
+			desc: "Request timeout wraps two per-try timeouts of the operation",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:          1,
+				BackendPerTryTimeout:     5 * time.Second,
+				OperationRequestTimeouts: "bookstore.Bookstore.GetShelf=60s",
+				OperationPerTryTimeouts:  "bookstore.Bookstore.*=25s",
+			},
+			deadline:          20 * time.Second,
+			wantTimeout:       durationpb.New(60 * time.Second),
+			wantPerTryTimeout: durationpb.New(25 * time.Second),
+		},
+		{
+			desc: "Other operations are not overridden",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:          1,
+				OperationRequestTimeouts: "bookstore.Bookstore.ListShelves=60s",
+				OperationPerTryTimeouts:  "bookstore.Bookstore.ListShelves=25s",
+			},
+			deadline:    20 * time.Second,
+			wantTimeout: durationpb.New(20 * time.Second),
+		},
+		{
+			desc: "Streaming operations have no response timeout to bound the per-try timeout",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:          1,
+				OperationRequestTimeouts: "bookstore.Bookstore.GetShelf=60s",
+				OperationPerTryTimeouts:  "bookstore.Bookstore.GetShelf=90s",
+			},
+			isStreaming:       true,
+			wantTimeout:       durationpb.New(0),
+			wantPerTryTimeout: durationpb.New(90 * time.Second),
+		},
+		{
+			desc: "Per-try timeout must be less than the response timeout",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:         1,
+				OperationPerTryTimeouts: "bookstore.Bookstore.GetShelf=20s",
+			},
+			deadline:  20 * time.Second,
+			wantError: `per-try timeout 20s of operation "bookstore.Bookstore.GetShelf" must be less than its response timeout 20s`,
+		},
+		{
+			desc: "Per-try timeout must be less than the default response timeout",
+			opts: options.ConfigGeneratorOptions{
+				BackendRetryNum:         1,
+				OperationPerTryTimeouts: "bookstore.Bookstore.GetShelf=30s",
+			},
+			wantError: `per-try timeout 30s of operation "bookstore.Bookstore.GetShelf" must be less than its response timeout 15s`,
+		},
+		{
+			desc: "Invalid request timeout",
+			opts: options.ConfigGeneratorOptions{
+				OperationRequestTimeouts: "bookstore.Bookstore.GetShelf=-1s",
+			},
+			wantError: `invalid request timeout "-1s" for operation "bookstore.Bookstore.GetShelf"`,
+		},
+		{
+			desc: "Invalid per-try timeout",
+			opts: options.ConfigGeneratorOptions{
+				OperationPerTryTimeouts: "bookstore.Bookstore.GetShelf=soon",
+			},
+			wantError: `invalid per-try timeout "soon" for operation "bookstore.Bookstore.GetShelf"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			methodCfg := &MethodCfg{
+				OperationName: "bookstore.Bookstore.GetShelf",
+				Deadline:      tc.deadline,
+				IsStreaming:   tc.isStreaming,
+			}
+			routeAction := &routepb.RouteAction{}
+			err := MaybeAddDeadlines(NewRouteDeadlineConfigerFromOPConfig(tc.opts), routeAction, methodCfg)
+			if err == nil {
+				err = MaybeAddRetryPolicy(NewRouteRetryConfigerFromOPConfig(tc.opts), routeAction, methodCfg)
+			}
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantTimeout, routeAction.Timeout, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddDeadlines() timeout diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantPerTryTimeout, routeAction.RetryPolicy.PerTryTimeout, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddRetryPolicy() per-try timeout diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
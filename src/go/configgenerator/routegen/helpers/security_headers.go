@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// defaultSecurityHeaders are the security headers added by flag
+// --enable_security_headers, suitable for the API responses.
+var defaultSecurityHeaders = map[string]string{
+	"Strict-Transport-Security": "max-age=31536000; includeSubdomains",
+	"X-Content-Type-Options":    "nosniff",
+	"X-Frame-Options":           "DENY",
+	"Referrer-Policy":           "no-referrer",
+	"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+}
+
+// RouteSecurityHeadersConfiger is a helper to add the security headers to the
+// responses of the operations, unless the backend already sets them.
+type RouteSecurityHeadersConfiger struct {
+	// SecurityHeaders is the JSON object overriding the values of the default
+	// security headers, an empty value removes the header.
+	SecurityHeaders string
+
+	// OperationSecurityHeadersEnabled is the selector map to opt operations
+	// out of the security headers.
+	OperationSecurityHeadersEnabled string
+}
+
+// NewRouteSecurityHeadersConfigerFromOPConfig creates a RouteSecurityHeadersConfiger from
+// ESPv2 options.
+func NewRouteSecurityHeadersConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteSecurityHeadersConfiger {
+	if !opts.EnableSecurityHeaders {
+		return nil
+	}
+
+	return &RouteSecurityHeadersConfiger{
+		SecurityHeaders:                 opts.SecurityHeaders,
+		OperationSecurityHeadersEnabled: opts.OperationSecurityHeadersEnabled,
+	}
+}
+
+// MaybeAddSecurityHeaders adds the security headers to the response headers of
+// the route, skipping the headers it already adds. It must be called after the
+// HSTS header is added.
+func MaybeAddSecurityHeaders(c *RouteSecurityHeadersConfiger, route *routepb.Route, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	headers, err := c.MakeSecurityHeaders(operation)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for _, header := range route.ResponseHeadersToAdd {
+		existing[textproto.CanonicalMIMEHeaderKey(header.GetHeader().GetKey())] = true
+	}
+	for _, header := range headers {
+		if existing[header.GetHeader().GetKey()] {
+			continue
+		}
+		route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, header)
+	}
+	return nil
+}
+
+// MakeSecurityHeaders creates the security headers of the operation, sorted by
+// name. It returns nil if the operation opts out of them.
+func (c *RouteSecurityHeadersConfiger) MakeSecurityHeaders(operation string) ([]*corepb.HeaderValueOption, error) {
+	opEnabled, err := util.ParseSelectorMap(c.OperationSecurityHeadersEnabled)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_security_headers_enabled: %v", err)
+	}
+	if value, ok := opEnabled.Lookup(operation); ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag --operation_security_headers_enabled, %q for operation %q must be true or false", value, operation)
+		}
+		if !enabled {
+			return nil, nil
+		}
+	}
+
+	values := make(map[string]string)
+	for name, value := range defaultSecurityHeaders {
+		values[name] = value
+	}
+	if strings.TrimSpace(c.SecurityHeaders) != "" {
+		overrides := make(map[string]string)
+		if err := json.Unmarshal([]byte(c.SecurityHeaders), &overrides); err != nil {
+			return nil, fmt.Errorf("invalid flag --security_headers, must be a JSON object of header names to values: %v", err)
+		}
+		for name, value := range overrides {
+			if strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("invalid flag --security_headers, header name cannot be empty")
+			}
+			values[textproto.CanonicalMIMEHeaderKey(name)] = value
+		}
+	}
+
+	var names []string
+	for name, value := range values {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var headers []*corepb.HeaderValueOption
+	for _, name := range names {
+		headers = append(headers, &corepb.HeaderValueOption{
+			Header: &corepb.HeaderValue{
+				Key:   name,
+				Value: values[name],
+			},
+			AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+		})
+	}
+	return headers, nil
+}
@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func makeSecurityHeader(key, value string) *corepb.HeaderValueOption {
+	return &corepb.HeaderValueOption{
+		Header: &corepb.HeaderValue{
+			Key:   key,
+			Value: value,
+		},
+		AppendAction: corepb.HeaderValueOption_ADD_IF_ABSENT,
+	}
+}
+
+func TestMaybeAddSecurityHeaders(t *testing.T) {
+	hstsHeader := &corepb.HeaderValueOption{
+		Header: &corepb.HeaderValue{
+			Key:   headerKey,
+			Value: headerValue,
+		},
+	}
+
+	testdata := []struct {
+		desc        string
+		opts        options.ConfigGeneratorOptions
+		enableHSTS  bool
+		wantHeaders []*corepb.HeaderValueOption
+		wantError   string
+	}{
+		{
+			desc: "Security headers are disabled by default",
+		},
+		{
+			desc: "Default security headers",
+			opts: options.ConfigGeneratorOptions{
+				EnableSecurityHeaders: true,
+			},
+			wantHeaders: []*corepb.HeaderValueOption{
+				makeSecurityHeader("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'"),
+				makeSecurityHeader("Referrer-Policy", "no-referrer"),
+				makeSecurityHeader("Strict-Transport-Security", "max-age=31536000; includeSubdomains"),
+				makeSecurityHeader("X-Content-Type-Options", "nosniff"),
+				makeSecurityHeader("X-Frame-Options", "DENY"),
+			},
+		},
+		{
+			desc: "Security headers are overridden, removed and added",
+			opts: options.ConfigGeneratorOptions{
+				EnableSecurityHeaders: true,
+				SecurityHeaders:       `{"x-frame-options": "SAMEORIGIN", "Content-Security-Policy": "", "Referrer-Policy": "", "Strict-Transport-Security": "", "Permissions-Policy": "camera=()"}`,
+			},
+			wantHeaders: []*corepb.HeaderValueOption{
+				makeSecurityHeader("Permissions-Policy", "camera=()"),
+				makeSecurityHeader("X-Content-Type-Options", "nosniff"),
+				makeSecurityHeader("X-Frame-Options", "SAMEORIGIN"),
+			},
+		},
+		{
+			desc: "HSTS header is not duplicated",
+			opts: options.ConfigGeneratorOptions{
+				EnableSecurityHeaders: true,
+				SecurityHeaders:       `{"X-Frame-Options": "", "Content-Security-Policy": "", "Referrer-Policy": "", "X-Content-Type-Options": ""}`,
+			},
+			enableHSTS: true,
+			wantHeaders: []*corepb.HeaderValueOption{
+				hstsHeader,
+			},
+		},
+		{
+			desc: "Operation opts out of the security headers",
+			opts: options.ConfigGeneratorOptions{
+				EnableSecurityHeaders:           true,
+				OperationSecurityHeadersEnabled: "bookstore.Bookstore.Get*=false;*=true",
+			},
+		},
+		{
+			desc: "Invalid security headers",
+			opts: options.ConfigGeneratorOptions{
+				EnableSecurityHeaders: true,
+				SecurityHeaders:       `["X-Frame-Options"]`,
+			},
+			wantError: "invalid flag --security_headers, must be a JSON object of header names to values",
+		},
+		{
+			desc: "Invalid operation opt-out",
+			opts: options.ConfigGeneratorOptions{
+				EnableSecurityHeaders:           true,
+				OperationSecurityHeadersEnabled: "bookstore.Bookstore.GetShelf=no",
+			},
+			wantError: `invalid flag --operation_security_headers_enabled, "no" for operation "bookstore.Bookstore.GetShelf" must be true or false`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			if tc.enableHSTS {
+				MaybeAddHSTSHeader(&RouteHSTSConfiger{}, route)
+			}
+
+			err := MaybeAddSecurityHeaders(NewRouteSecurityHeadersConfigerFromOPConfig(tc.opts), route, "bookstore.Bookstore.GetShelf")
+			if err != nil {
+				if tc.wantError == "" || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("MaybeAddSecurityHeaders() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if tc.wantError != "" {
+				t.Fatalf("MaybeAddSecurityHeaders() got no error, want error %q", tc.wantError)
+			}
+
+			if diff := cmp.Diff(tc.wantHeaders, route.ResponseHeadersToAdd, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddSecurityHeaders() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// StaticResponse is a response served directly by Envoy from the route
+// config, without calling the backend.
+type StaticResponse struct {
+	Status  uint32            `json:"status"`
+	Body    string            `json:"body"`
+	Headers map[string]string `json:"headers"`
+}
+
+// ParseStaticResponse parses the static response from a JSON object, such as
+// '{"status": 503, "body": "Down for maintenance", "headers": {"retry-after": "3600"}}'.
+func ParseStaticResponse(s string) (*StaticResponse, error) {
+	r := &StaticResponse{}
+	if err := json.Unmarshal([]byte(s), r); err != nil {
+		return nil, fmt.Errorf("invalid static response, it should be a JSON object: %v", err)
+	}
+	if r.Status < 200 || r.Status >= 600 {
+		return nil, fmt.Errorf("invalid static response status %d, must be in [200, 600)", r.Status)
+	}
+	for key := range r.Headers {
+		if key == "" {
+			return nil, fmt.Errorf("invalid static response, header name cannot be empty")
+		}
+	}
+	return r, nil
+}
+
+// ApplyTo replaces the action of the route with the static response.
+func (r *StaticResponse) ApplyTo(route *routepb.Route) {
+	directResponse := &routepb.DirectResponseAction{
+		Status: r.Status,
+	}
+	if r.Body != "" {
+		directResponse.Body = &corepb.DataSource{
+			Specifier: &corepb.DataSource_InlineString{
+				InlineString: r.Body,
+			},
+		}
+	}
+	route.Action = &routepb.Route_DirectResponse{
+		DirectResponse: directResponse,
+	}
+
+	// Sort by the header names so the generated config is stable.
+	keys := make([]string, 0, len(r.Headers))
+	for key := range r.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, &corepb.HeaderValueOption{
+			Header: &corepb.HeaderValue{
+				Key:   key,
+				Value: r.Headers[key],
+			},
+			AppendAction: corepb.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+}
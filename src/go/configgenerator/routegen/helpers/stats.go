@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// RouteStatsConfiger is a helper to emit the per-route statistics of the
+// operations, named by the operation selector instead of the path.
+type RouteStatsConfiger struct{}
+
+// NewRouteStatsConfigerFromOPConfig creates a RouteStatsConfiger from
+// ESPv2 options.
+func NewRouteStatsConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteStatsConfiger {
+	if !opts.EnableOperationStats {
+		return nil
+	}
+	return &RouteStatsConfiger{}
+}
+
+// MaybeAddStatPrefix adds the operation stat prefix to the route. All the
+// routes of an operation share the same statistics.
+func MaybeAddStatPrefix(c *RouteStatsConfiger, route *routepb.Route, operation string) {
+	if c == nil {
+		return
+	}
+
+	route.StatPrefix = operation
+}
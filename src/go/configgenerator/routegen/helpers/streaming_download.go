@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// RouteStreamingDownloadConfiger is a helper to limit the buffer of the
+// streaming downloads, so their responses are flow controlled instead of
+// being buffered in memory.
+type RouteStreamingDownloadConfiger struct {
+	BufferLimitBytes            uint32
+	OperationStreamingDownloads string
+}
+
+// NewRouteStreamingDownloadConfigerFromOPConfig creates a
+// RouteStreamingDownloadConfiger from ESPv2 options.
+func NewRouteStreamingDownloadConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteStreamingDownloadConfiger {
+	if opts.StreamingDownloadBufferLimitBytes <= 0 {
+		return nil
+	}
+
+	return &RouteStreamingDownloadConfiger{
+		BufferLimitBytes:            uint32(opts.StreamingDownloadBufferLimitBytes),
+		OperationStreamingDownloads: opts.OperationStreamingDownloads,
+	}
+}
+
+// MaybeAddStreamingDownloadConfig limits the buffer of the route if the
+// method is a streaming download.
+func MaybeAddStreamingDownloadConfig(c *RouteStreamingDownloadConfiger, route *routepb.Route, methodCfg *MethodCfg) error {
+	if c == nil {
+		return nil
+	}
+
+	isStreamingDownload, err := c.IsStreamingDownload(methodCfg)
+	if err != nil {
+		return fmt.Errorf("fail to check streaming download for route: %v", err)
+	}
+	if isStreamingDownload {
+		route.PerRequestBufferLimitBytes = &wrapperspb.UInt32Value{Value: c.BufferLimitBytes}
+	}
+	return nil
+}
+
+// IsStreamingDownload returns whether the method is a streaming download. By
+// default, only the server streaming methods responding google.api.HttpBody
+// are.
+func (c *RouteStreamingDownloadConfiger) IsStreamingDownload(methodCfg *MethodCfg) (bool, error) {
+	opStreamingDownloads, err := util.ParseSelectorMap(c.OperationStreamingDownloads)
+	if err != nil {
+		return false, fmt.Errorf("invalid flag --operation_streaming_downloads: %v", err)
+	}
+	value, ok := opStreamingDownloads.Lookup(methodCfg.OperationName)
+	if !ok {
+		return methodCfg.IsResponseStreaming && methodCfg.ResponseTypeUrl == util.HttpBodyTypeUrl, nil
+	}
+
+	isStreamingDownload, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid streaming download %q for operation %q, must be true or false", value, methodCfg.OperationName)
+	}
+	if isStreamingDownload && !methodCfg.IsResponseStreaming {
+		return false, fmt.Errorf("operation %q is not server streaming, it cannot be a streaming download", methodCfg.OperationName)
+	}
+	return isStreamingDownload, nil
+}
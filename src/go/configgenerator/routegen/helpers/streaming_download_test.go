@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMaybeAddStreamingDownloadConfig(t *testing.T) {
+	opts := options.ConfigGeneratorOptions{
+		StreamingDownloadBufferLimitBytes: 1024,
+		OperationStreamingDownloads:       "bookstore.Bookstore.DownloadLogs=false;bookstore.Bookstore.Stream*=true",
+	}
+
+	testdata := []struct {
+		desc      string
+		methodCfg *MethodCfg
+		wantRoute *routepb.Route
+	}{
+		{
+			desc: "Server streaming HttpBody is a streaming download by default",
+			methodCfg: &MethodCfg{
+				OperationName:       "bookstore.Bookstore.DownloadBook",
+				IsResponseStreaming: true,
+				ResponseTypeUrl:     util.HttpBodyTypeUrl,
+			},
+			wantRoute: &routepb.Route{
+				PerRequestBufferLimitBytes: &wrapperspb.UInt32Value{Value: 1024},
+			},
+		},
+		{
+			desc: "Unary HttpBody is not a streaming download",
+			methodCfg: &MethodCfg{
+				OperationName:   "bookstore.Bookstore.GetBookCover",
+				ResponseTypeUrl: util.HttpBodyTypeUrl,
+			},
+			wantRoute: &routepb.Route{},
+		},
+		{
+			desc: "Streaming download is disabled for the operation",
+			methodCfg: &MethodCfg{
+				OperationName:       "bookstore.Bookstore.DownloadLogs",
+				IsResponseStreaming: true,
+				ResponseTypeUrl:     util.HttpBodyTypeUrl,
+			},
+			wantRoute: &routepb.Route{},
+		},
+		{
+			desc: "Streaming download is enabled for the operations by wildcard selector",
+			methodCfg: &MethodCfg{
+				OperationName:       "bookstore.Bookstore.StreamShelves",
+				IsResponseStreaming: true,
+				ResponseTypeUrl:     "type.googleapis.com/bookstore.Shelf",
+			},
+			wantRoute: &routepb.Route{
+				PerRequestBufferLimitBytes: &wrapperspb.UInt32Value{Value: 1024},
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			if err := MaybeAddStreamingDownloadConfig(NewRouteStreamingDownloadConfigerFromOPConfig(opts), route, tc.methodCfg); err != nil {
+				t.Fatalf("MaybeAddStreamingDownloadConfig() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantRoute, route, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddStreamingDownloadConfig() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMaybeAddStreamingDownloadConfig_BadInput(t *testing.T) {
+	opts := options.ConfigGeneratorOptions{
+		StreamingDownloadBufferLimitBytes: 1024,
+		OperationStreamingDownloads:       "bookstore.Bookstore.GetBookCover=true",
+	}
+
+	err := MaybeAddStreamingDownloadConfig(NewRouteStreamingDownloadConfigerFromOPConfig(opts), &routepb.Route{}, &MethodCfg{
+		OperationName:   "bookstore.Bookstore.GetBookCover",
+		ResponseTypeUrl: util.HttpBodyTypeUrl,
+	})
+	if want := "it cannot be a streaming download"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MaybeAddStreamingDownloadConfig() got error %v, want error containing %q", err, want)
+	}
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// The response header added by the router filter once it is not
+	// suppressed to send the expected request timeout to the backends.
+	upstreamServiceTimeHeader = "x-envoy-upstream-service-time"
+)
+
+// RouteTimeoutPropagationConfiger is a helper to propagate the remaining
+// deadline of the routes to the backends, in the header
+// x-envoy-expected-rq-timeout-ms set by the router filter, and in the header
+// grpc-timeout for the gRPC requests.
+type RouteTimeoutPropagationConfiger struct {
+	// SuppressEnvoyHeaders removes the other x-envoy- headers of the router
+	// filter, which no longer suppresses them.
+	SuppressEnvoyHeaders bool
+}
+
+// NewRouteTimeoutPropagationConfigerFromOPConfig creates a RouteTimeoutPropagationConfiger from
+// ESPv2 options.
+func NewRouteTimeoutPropagationConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteTimeoutPropagationConfiger {
+	if !opts.PropagateRequestTimeout {
+		return nil
+	}
+
+	return &RouteTimeoutPropagationConfiger{
+		SuppressEnvoyHeaders: opts.SuppressEnvoyHeaders,
+	}
+}
+
+// MaybeAddTimeoutPropagation configures the route to rewrite the grpc-timeout
+// header with the remaining deadline. The client grpc-timeout can shorten the
+// deadline of the route but not extend it. It must be called after the
+// deadline is added.
+func MaybeAddTimeoutPropagation(c *RouteTimeoutPropagationConfiger, route *routepb.Route) {
+	if c == nil {
+		return
+	}
+
+	routeAction := route.GetRoute()
+	timeout := routeAction.GetTimeout()
+	if timeout == nil {
+		// Zero means the grpc-timeout is not limited.
+		timeout = durationpb.New(0)
+	}
+	routeAction.MaxStreamDuration = &routepb.RouteAction_MaxStreamDuration{
+		GrpcTimeoutHeaderMax: timeout,
+	}
+
+	if c.SuppressEnvoyHeaders {
+		route.ResponseHeadersToRemove = append(route.ResponseHeadersToRemove, upstreamServiceTimeHeader)
+	}
+}
@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMaybeAddTimeoutPropagation(t *testing.T) {
+	testdata := []struct {
+		desc      string
+		opts      options.ConfigGeneratorOptions
+		timeout   *durationpb.Duration
+		wantRoute *routepb.Route
+	}{
+		{
+			desc:    "Timeout propagation is disabled by default",
+			timeout: durationpb.New(5 * time.Second),
+			wantRoute: &routepb.Route{
+				Action: &routepb.Route_Route{
+					Route: &routepb.RouteAction{
+						Timeout: durationpb.New(5 * time.Second),
+					},
+				},
+			},
+		},
+		{
+			desc: "grpc-timeout is limited by the route deadline",
+			opts: options.ConfigGeneratorOptions{
+				PropagateRequestTimeout: true,
+			},
+			timeout: durationpb.New(5 * time.Second),
+			wantRoute: &routepb.Route{
+				Action: &routepb.Route_Route{
+					Route: &routepb.RouteAction{
+						Timeout: durationpb.New(5 * time.Second),
+						MaxStreamDuration: &routepb.RouteAction_MaxStreamDuration{
+							GrpcTimeoutHeaderMax: durationpb.New(5 * time.Second),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "grpc-timeout is not limited for the streaming routes",
+			opts: options.ConfigGeneratorOptions{
+				PropagateRequestTimeout: true,
+			},
+			wantRoute: &routepb.Route{
+				Action: &routepb.Route_Route{
+					Route: &routepb.RouteAction{
+						MaxStreamDuration: &routepb.RouteAction_MaxStreamDuration{
+							GrpcTimeoutHeaderMax: durationpb.New(0),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Upstream service time header stays suppressed",
+			opts: options.ConfigGeneratorOptions{
+				PropagateRequestTimeout: true,
+				SuppressEnvoyHeaders:    true,
+			},
+			timeout: durationpb.New(5 * time.Second),
+			wantRoute: &routepb.Route{
+				Action: &routepb.Route_Route{
+					Route: &routepb.RouteAction{
+						Timeout: durationpb.New(5 * time.Second),
+						MaxStreamDuration: &routepb.RouteAction_MaxStreamDuration{
+							GrpcTimeoutHeaderMax: durationpb.New(5 * time.Second),
+						},
+					},
+				},
+				ResponseHeadersToRemove: []string{
+					"x-envoy-upstream-service-time",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{
+				Action: &routepb.Route_Route{
+					Route: &routepb.RouteAction{
+						Timeout: tc.timeout,
+					},
+				},
+			}
+
+			MaybeAddTimeoutPropagation(NewRouteTimeoutPropagationConfigerFromOPConfig(tc.opts), route)
+
+			if diff := cmp.Diff(tc.wantRoute, route, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddTimeoutPropagation() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// RouteUpgradeConfiger is a helper to override the connection upgrades and
+// their idle timeout per operation.
+type RouteUpgradeConfiger struct {
+	UpgradeTypes          string
+	OperationUpgradeTypes string
+	OperationIdleTimeouts string
+}
+
+// NewRouteUpgradeConfigerFromOPConfig creates a RouteUpgradeConfiger from
+// ESPv2 options.
+func NewRouteUpgradeConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteUpgradeConfiger {
+	if opts.OperationUpgradeTypes == "" && opts.OperationUpgradeIdleTimeouts == "" {
+		return nil
+	}
+
+	return &RouteUpgradeConfiger{
+		UpgradeTypes:          opts.UpgradeTypes,
+		OperationUpgradeTypes: opts.OperationUpgradeTypes,
+		OperationIdleTimeouts: opts.OperationUpgradeIdleTimeouts,
+	}
+}
+
+// MaybeAddUpgradeConfigs adds the generated upgrade config for the operation
+// to the route action. It must be called after the deadlines are added.
+func MaybeAddUpgradeConfigs(c *RouteUpgradeConfiger, routeAction *routepb.RouteAction, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	upgradeConfigs, err := c.MakeUpgradeConfigs(operation)
+	if err != nil {
+		return fmt.Errorf("fail to create upgrade configs for routeAction: %v", err)
+	}
+	routeAction.UpgradeConfigs = upgradeConfigs
+
+	idleTimeout, err := c.MakeIdleTimeout(operation)
+	if err != nil {
+		return fmt.Errorf("fail to create upgrade idle timeout for routeAction: %v", err)
+	}
+	if idleTimeout > 0 {
+		routeAction.IdleTimeout = durationpb.New(idleTimeout)
+	}
+	return nil
+}
+
+// MakeUpgradeConfigs creates the upgrade configs for the operation, which
+// enable its upgrade types and disable all the others. It returns nil if the
+// upgrades are not overridden for the operation.
+func (c *RouteUpgradeConfiger) MakeUpgradeConfigs(operation string) ([]*routepb.RouteAction_UpgradeConfig, error) {
+	opUpgradeTypes, err := util.ParseSelectorMap(c.OperationUpgradeTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --operation_upgrade_types: %v", err)
+	}
+	types, ok := opUpgradeTypes.Lookup(operation)
+	if !ok {
+		return nil, nil
+	}
+
+	_, allUpgradeTypes, err := filtergen.ParseAllUpgradeTypes(c.UpgradeTypes, c.OperationUpgradeTypes)
+	if err != nil {
+		return nil, err
+	}
+	enabledTypes := make(map[string]bool)
+	for _, upgradeType := range filtergen.ParseUpgradeTypes(types) {
+		enabledTypes[upgradeType] = true
+	}
+
+	var upgradeConfigs []*routepb.RouteAction_UpgradeConfig
+	for _, upgradeType := range allUpgradeTypes {
+		upgradeConfigs = append(upgradeConfigs, &routepb.RouteAction_UpgradeConfig{
+			UpgradeType: upgradeType,
+			Enabled:     &wrapperspb.BoolValue{Value: enabledTypes[upgradeType]},
+		})
+	}
+	return upgradeConfigs, nil
+}
+
+// MakeIdleTimeout returns the idle timeout overridden for the operation, or 0
+// if it is not overridden.
+func (c *RouteUpgradeConfiger) MakeIdleTimeout(operation string) (time.Duration, error) {
+	opIdleTimeouts, err := util.ParseSelectorMap(c.OperationIdleTimeouts)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flag --operation_upgrade_idle_timeouts: %v", err)
+	}
+	value, ok := opIdleTimeouts.Lookup(operation)
+	if !ok {
+		return 0, nil
+	}
+
+	idleTimeout, err := time.ParseDuration(value)
+	if err != nil || idleTimeout <= 0 {
+		return 0, fmt.Errorf("invalid idle timeout %q for operation %q, must be a positive duration", value, operation)
+	}
+	return idleTimeout, nil
+}
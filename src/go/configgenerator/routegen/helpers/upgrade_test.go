@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMaybeAddUpgradeConfigs(t *testing.T) {
+	opts := options.ConfigGeneratorOptions{
+		UpgradeTypes:                 "websocket",
+		OperationUpgradeTypes:        "bookstore.Bookstore.Tunnel=CONNECT;bookstore.Bookstore.Get*=",
+		OperationUpgradeIdleTimeouts: "bookstore.Bookstore.Tunnel=1h",
+	}
+
+	testdata := []struct {
+		desc            string
+		operation       string
+		wantRouteAction *routepb.RouteAction
+	}{
+		{
+			desc:      "Upgrades and idle timeout are overridden",
+			operation: "bookstore.Bookstore.Tunnel",
+			wantRouteAction: &routepb.RouteAction{
+				IdleTimeout: durationpb.New(time.Hour),
+				UpgradeConfigs: []*routepb.RouteAction_UpgradeConfig{
+					{
+						UpgradeType: "websocket",
+						Enabled:     &wrapperspb.BoolValue{Value: false},
+					},
+					{
+						UpgradeType: "connect",
+						Enabled:     &wrapperspb.BoolValue{Value: true},
+					},
+				},
+			},
+		},
+		{
+			desc:      "All upgrades are disabled by wildcard selector",
+			operation: "bookstore.Bookstore.GetShelf",
+			wantRouteAction: &routepb.RouteAction{
+				IdleTimeout: durationpb.New(time.Minute),
+				UpgradeConfigs: []*routepb.RouteAction_UpgradeConfig{
+					{
+						UpgradeType: "websocket",
+						Enabled:     &wrapperspb.BoolValue{Value: false},
+					},
+					{
+						UpgradeType: "connect",
+						Enabled:     &wrapperspb.BoolValue{Value: false},
+					},
+				},
+			},
+		},
+		{
+			desc:      "Operation without overrides is not changed",
+			operation: "bookstore.Bookstore.ListShelves",
+			wantRouteAction: &routepb.RouteAction{
+				IdleTimeout: durationpb.New(time.Minute),
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			routeAction := &routepb.RouteAction{
+				IdleTimeout: durationpb.New(time.Minute),
+			}
+			if err := MaybeAddUpgradeConfigs(NewRouteUpgradeConfigerFromOPConfig(opts), routeAction, tc.operation); err != nil {
+				t.Fatalf("MaybeAddUpgradeConfigs() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantRouteAction, routeAction, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddUpgradeConfigs() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMaybeAddUpgradeConfigs_BadInput(t *testing.T) {
+	opts := options.ConfigGeneratorOptions{
+		OperationUpgradeIdleTimeouts: "bookstore.Bookstore.Tunnel=forever",
+	}
+
+	err := MaybeAddUpgradeConfigs(NewRouteUpgradeConfigerFromOPConfig(opts), &routepb.RouteAction{}, "bookstore.Bookstore.Tunnel")
+	if want := `invalid idle timeout "forever"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MaybeAddUpgradeConfigs() got error %v, want error containing %q", err, want)
+	}
+}
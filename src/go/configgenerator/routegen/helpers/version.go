@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// RouteVersionConfiger is a helper to route the requests to the backends of
+// the API versions in the version request header.
+type RouteVersionConfiger struct {
+	// VersionBackends is the JSON object of flag --version_backends.
+	VersionBackends string
+}
+
+// NewRouteVersionConfigerFromOPConfig creates a RouteVersionConfiger from
+// ESPv2 options.
+func NewRouteVersionConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteVersionConfiger {
+	if opts.VersionBackends == "" {
+		return nil
+	}
+
+	return &RouteVersionConfiger{
+		VersionBackends: opts.VersionBackends,
+	}
+}
+
+// MakeVersionRoutes creates a copy of the route for each API version,
+// matching the version in the request header. The copies must be placed
+// before the route.
+func MakeVersionRoutes(c *RouteVersionConfiger, route *routepb.Route) ([]*routepb.Route, error) {
+	if c == nil || route.GetRoute() == nil {
+		return nil, nil
+	}
+
+	backends, err := clustergen.ParseVersionBackends(c.VersionBackends)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []*routepb.Route
+	for _, backend := range backends.Versions {
+		_, hostname, _, _, err := util.ParseURI(backend.BackendAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend address of version %q: %v", backend.Version, err)
+		}
+
+		versionRoute := proto.Clone(route).(*routepb.Route)
+		versionRoute.Match.Headers = append(versionRoute.Match.Headers, &routepb.HeaderMatcher{
+			Name: backends.Header,
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Exact{
+						Exact: backend.Version,
+					},
+				},
+			},
+		})
+
+		routeAction := versionRoute.GetRoute()
+		routeAction.ClusterSpecifier = &routepb.RouteAction_Cluster{
+			Cluster: clustergen.VersionClusterName(backend.Version),
+		}
+		routeAction.HostRewriteSpecifier = &routepb.RouteAction_HostRewriteLiteral{
+			HostRewriteLiteral: hostname,
+		}
+		routes = append(routes, versionRoute)
+	}
+	return routes, nil
+}
@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// RouteWasmConfiger is a helper to enable or disable the WASM filter per
+// operation. Envoy does not support per-route config for the WASM filter, so
+// the route metadata tells the WASM filter module whether to process the
+// request.
+type RouteWasmConfiger struct {
+	OperationWasmFilterEnabled string
+}
+
+// NewRouteWasmConfigerFromOPConfig creates a RouteWasmConfiger from ESPv2
+// options.
+func NewRouteWasmConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteWasmConfiger {
+	if opts.WasmFilterURI == "" || opts.OperationWasmFilterEnabled == "" {
+		return nil
+	}
+
+	return &RouteWasmConfiger{
+		OperationWasmFilterEnabled: opts.OperationWasmFilterEnabled,
+	}
+}
+
+// MaybeAddWasmMetadata adds the "enabled" field to the WASM filter metadata
+// of the route if the operation overrides it.
+func MaybeAddWasmMetadata(c *RouteWasmConfiger, route *routepb.Route, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	opEnabled, err := util.ParseSelectorMap(c.OperationWasmFilterEnabled)
+	if err != nil {
+		return fmt.Errorf("invalid flag --operation_wasm_filter_enabled: %v", err)
+	}
+	value, ok := opEnabled.Lookup(operation)
+	if !ok {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid flag --operation_wasm_filter_enabled, %q for operation %q must be true or false", value, operation)
+	}
+
+	if route.Metadata == nil {
+		route.Metadata = &corepb.Metadata{}
+	}
+	if route.Metadata.FilterMetadata == nil {
+		route.Metadata.FilterMetadata = make(map[string]*structpb.Struct)
+	}
+	route.Metadata.FilterMetadata[filtergen.WasmFilterName] = &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"enabled": structpb.NewBoolValue(enabled),
+		},
+	}
+	return nil
+}
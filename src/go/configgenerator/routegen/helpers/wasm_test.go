@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMaybeAddWasmMetadata(t *testing.T) {
+	opts := options.ConfigGeneratorOptions{
+		WasmFilterURI:              "/etc/espv2/filter.wasm",
+		OperationWasmFilterEnabled: "bookstore.Bookstore.GetBook=false;bookstore.Bookstore.*=true",
+	}
+
+	testdata := []struct {
+		desc      string
+		operation string
+		wantRoute *routepb.Route
+	}{
+		{
+			desc:      "WASM filter is disabled for the operation",
+			operation: "bookstore.Bookstore.GetBook",
+			wantRoute: &routepb.Route{
+				Metadata: &corepb.Metadata{
+					FilterMetadata: map[string]*structpb.Struct{
+						"envoy.filters.http.wasm": {
+							Fields: map[string]*structpb.Value{
+								"enabled": structpb.NewBoolValue(false),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:      "WASM filter is enabled for the operations by wildcard selector",
+			operation: "bookstore.Bookstore.ListBooks",
+			wantRoute: &routepb.Route{
+				Metadata: &corepb.Metadata{
+					FilterMetadata: map[string]*structpb.Struct{
+						"envoy.filters.http.wasm": {
+							Fields: map[string]*structpb.Value{
+								"enabled": structpb.NewBoolValue(true),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:      "No metadata for the operations without override",
+			operation: "library.Library.GetBook",
+			wantRoute: &routepb.Route{},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			if err := MaybeAddWasmMetadata(NewRouteWasmConfigerFromOPConfig(opts), route, tc.operation); err != nil {
+				t.Fatalf("MaybeAddWasmMetadata() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantRoute, route, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddWasmMetadata() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
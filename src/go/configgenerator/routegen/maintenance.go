@@ -0,0 +1,64 @@
+package routegen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// MaintenanceGenerator is a RouteGenerator that serves the maintenance
+// response for all the requests, so the backends can be taken down.
+//
+// The health checks are still answered by the health check filter, and the
+// admin routes still work.
+type MaintenanceGenerator struct {
+	Response *helpers.StaticResponse
+
+	*NoopRouteGenerator
+}
+
+// NewMaintenanceRouteGenFromOPConfig creates MaintenanceGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewMaintenanceRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if !opts.MaintenanceMode {
+		glog.Info("Not adding maintenance route because the maintenance mode is disabled.")
+		return nil, nil
+	}
+
+	response, err := helpers.ParseMaintenanceResponse(opts.MaintenanceResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaintenanceGenerator{
+		Response: response,
+	}, nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *MaintenanceGenerator) RouteType() string {
+	return "maintenance"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+func (g *MaintenanceGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	route := &routepb.Route{
+		Match: &routepb.RouteMatch{
+			PathSpecifier: &routepb.RouteMatch_Prefix{
+				Prefix: "/",
+			},
+		},
+		Decorator: &routepb.Decorator{
+			Operation: fmt.Sprintf("%s Maintenance", util.SpanNamePrefix),
+		},
+	}
+	g.Response.ApplyTo(route)
+	return []*routepb.Route{route}, nil
+}
@@ -0,0 +1,133 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestNewMaintenanceRouteGenFromOPConfig(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn:         options.ConfigGeneratorOptions{},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "default maintenance response for all the requests",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				MaintenanceMode: true,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Maintenance"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"The service is temporarily unavailable for maintenance."
+        },
+        "status":503
+      },
+      "match":{
+        "prefix":"/"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "custom maintenance response with headers",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				MaintenanceMode:     true,
+				MaintenanceResponse: `{"status": 200, "body": "{\"maintenance\": true}", "headers": {"retry-after": "3600", "content-type": "application/json"}}`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Maintenance"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"{\"maintenance\": true}"
+        },
+        "status":200
+      },
+      "match":{
+        "prefix":"/"
+      },
+      "responseHeadersToAdd":[
+        {
+          "appendAction":"OVERWRITE_IF_EXISTS_OR_ADD",
+          "header":{
+            "key":"content-type",
+            "value":"application/json"
+          }
+        },
+        {
+          "appendAction":"OVERWRITE_IF_EXISTS_OR_ADD",
+          "header":{
+            "key":"retry-after",
+            "value":"3600"
+          }
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewMaintenanceRouteGenFromOPConfig)
+	}
+}
+
+func TestNewMaintenanceRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "maintenance response is not JSON",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				MaintenanceMode:     true,
+				MaintenanceResponse: "down",
+			},
+			WantFactoryError: "invalid flag --maintenance_response: invalid static response, it should be a JSON object",
+		},
+		{
+			Desc: "maintenance response status is out of range",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				MaintenanceMode:     true,
+				MaintenanceResponse: `{"status": 99}`,
+			},
+			WantFactoryError: "invalid static response status 99, must be in [200, 600)",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewMaintenanceRouteGenFromOPConfig)
+	}
+}
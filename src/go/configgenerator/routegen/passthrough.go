@@ -0,0 +1,87 @@
+package routegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// PassthroughRouteGenerator is a RouteGenerator that proxies the requests
+// under the explicit passthrough path prefixes to the local backend, when the
+// requests not matching any operation are otherwise rejected.
+type PassthroughRouteGenerator struct {
+	PathPrefixes            []string
+	LocalBackendClusterName string
+
+	*NoopRouteGenerator
+}
+
+// NewPassthroughRouteGenFromOPConfig creates PassthroughRouteGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewPassthroughRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if opts.PassthroughPathPrefixes == "" {
+		glog.Info("Not adding passthrough routes because no passthrough path prefix is configured.")
+		return nil, nil
+	}
+	if !opts.StrictRouteAllowlist {
+		return nil, fmt.Errorf("flag --passthrough_path_prefixes requires flag --strict_route_allowlist")
+	}
+
+	var prefixes []string
+	for _, prefix := range strings.Split(opts.PassthroughPathPrefixes, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid flag --passthrough_path_prefixes, path prefix %q should start with /", prefix)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	return &PassthroughRouteGenerator{
+		PathPrefixes:            prefixes,
+		LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
+	}, nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *PassthroughRouteGenerator) RouteType() string {
+	return "passthrough_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+//
+// The passthrough routes have no per-route filter config, they are not
+// operations of the API.
+func (g *PassthroughRouteGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	var routes []*routepb.Route
+	for _, prefix := range g.PathPrefixes {
+		routes = append(routes, &routepb.Route{
+			Match: &routepb.RouteMatch{
+				PathSpecifier: &routepb.RouteMatch_Prefix{
+					Prefix: prefix,
+				},
+			},
+			Action: &routepb.Route_Route{
+				Route: &routepb.RouteAction{
+					ClusterSpecifier: &routepb.RouteAction_Cluster{
+						Cluster: g.LocalBackendClusterName,
+					},
+				},
+			},
+			Decorator: &routepb.Decorator{
+				Operation: fmt.Sprintf("%s %s_Passthrough", util.SpanNamePrefix, util.AutogeneratedOperationPrefix),
+			},
+		})
+	}
+	return routes, nil
+}
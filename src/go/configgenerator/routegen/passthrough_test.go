@@ -0,0 +1,89 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestNewPassthroughRouteGenFromOPConfig(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn:         options.ConfigGeneratorOptions{},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "passthrough path prefixes to the local backend",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				StrictRouteAllowlist:    true,
+				PassthroughPathPrefixes: "/static/, /assets/",
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_Passthrough"
+      },
+      "match":{
+        "prefix":"/static/"
+      },
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_Passthrough"
+      },
+      "match":{
+        "prefix":"/assets/"
+      },
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewPassthroughRouteGenFromOPConfig)
+	}
+}
+
+func TestNewPassthroughRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "strict route allowlist is required",
+			OptsIn: options.ConfigGeneratorOptions{
+				PassthroughPathPrefixes: "/static/",
+			},
+			WantFactoryError: "flag --passthrough_path_prefixes requires flag --strict_route_allowlist",
+		},
+		{
+			Desc: "invalid path prefix",
+			OptsIn: options.ConfigGeneratorOptions{
+				StrictRouteAllowlist:    true,
+				PassthroughPathPrefixes: "static/",
+			},
+			WantFactoryError: `invalid flag --passthrough_path_prefixes, path prefix "static/" should start with /`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewPassthroughRouteGenFromOPConfig)
+	}
+}
@@ -3,9 +3,11 @@ package routegen
 import (
 	"fmt"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
@@ -19,8 +21,15 @@ type ProxyBackendGenerator struct {
 	BackendClusterBySelector map[string]*BackendClusterSpecifier
 	DeadlineBySelector       map[string]*DeadlineSpecifier
 	MethodBySelector         map[string]*apipb.Method
+	DeprecatedSelectors      map[string]bool
+	QuotaMetricsBySelector   map[string][]string
 	BackendRouteGen          *helpers.BackendRouteGenerator
 
+	// TenantRoutingClusterName is the local backend cluster, its routes are
+	// routed to the backend of the tenant chosen by the tenant routing filter.
+	// Empty if tenant routing is disabled.
+	TenantRoutingClusterName string
+
 	*NoopRouteGenerator
 }
 
@@ -43,12 +52,20 @@ func NewProxyBackendRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts
 		return nil, fmt.Errorf("fail to parse backend cluster specifiers from OP config: %v", err)
 	}
 
+	var tenantRoutingClusterName string
+	if opts.TenantMappingFile != "" || opts.TenantMapping != "" {
+		tenantRoutingClusterName = clustergen.MakeLocalBackendClusterName(serviceConfig)
+	}
+
 	return &ProxyBackendGenerator{
 		HTTPPatterns:             *httpPatterns,
 		BackendClusterBySelector: backendClusterBySelector,
 		DeadlineBySelector:       ParseDeadlineSelectorFromOPConfig(serviceConfig, opts),
 		MethodBySelector:         ParseMethodBySelectorFromOPConfig(serviceConfig),
+		DeprecatedSelectors:      ParseDeprecatedSelectorsFromOPConfig(serviceConfig),
+		QuotaMetricsBySelector:   ParseQuotaMetricsBySelectorFromOPConfig(serviceConfig, opts),
 		BackendRouteGen:          helpers.NewBackendRouteGeneratorFromOPConfig(opts),
+		TenantRoutingClusterName: tenantRoutingClusterName,
 	}, nil
 }
 
@@ -58,60 +75,93 @@ func (g *ProxyBackendGenerator) RouteType() string {
 }
 
 // GenRouteConfig implements interface RouteGenerator.
+//
+// The routes of the HTTP patterns are generated in parallel, as the service
+// configs with tens of thousands of operations take long to generate, and
+// merged in the order of the sorted HTTP patterns.
 func (g *ProxyBackendGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	routesByPattern := make([][]*routepb.Route, len(g.HTTPPatterns))
+	if err := util.ParallelFor(len(g.HTTPPatterns), func(i int) error {
+		routes, err := g.genRoutesForHTTPPattern(g.HTTPPatterns[i], filterGens)
+		routesByPattern[i] = routes
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
 	var routes []*routepb.Route
-	for _, httpPattern := range g.HTTPPatterns {
-		selector := httpPattern.Operation
+	for _, patternRoutes := range routesByPattern {
+		routes = append(routes, patternRoutes...)
+	}
+	return routes, nil
+}
 
-		method, ok := g.MethodBySelector[selector]
-		if !ok {
-			return nil, fmt.Errorf("could not find any API method for selector %q", selector)
-		}
+// genRoutesForHTTPPattern generates the routes of one HTTP pattern. It only
+// reads the generator, so it is safe to call concurrently.
+func (g *ProxyBackendGenerator) genRoutesForHTTPPattern(httpPattern *httppattern.Method, filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	selector := httpPattern.Operation
 
-		backendCluster, ok := g.BackendClusterBySelector[selector]
-		if !ok {
-			return nil, fmt.Errorf("could not find any backend cluster for selector %q", selector)
-		}
+	method, ok := g.MethodBySelector[selector]
+	if !ok {
+		return nil, fmt.Errorf("could not find any API method for selector %q", selector)
+	}
 
-		deadlineSpecifier, ok := g.DeadlineBySelector[selector]
-		if !ok {
-			deadlineSpecifier = &DeadlineSpecifier{
-				Deadline: 0,
-			}
-		}
+	backendCluster, ok := g.BackendClusterBySelector[selector]
+	if !ok {
+		return nil, fmt.Errorf("could not find any backend cluster for selector %q", selector)
+	}
 
-		methodCfg := &helpers.MethodCfg{
-			OperationName:      selector,
-			BackendClusterName: backendCluster.Name,
-			HostRewrite:        backendCluster.HostName,
-			Deadline:           deadlineSpecifier.Deadline,
-			IsStreaming:        method.GetRequestStreaming() || method.GetResponseStreaming(),
-			HTTPPattern:        httpPattern.Pattern,
+	deadlineSpecifier, ok := g.DeadlineBySelector[selector]
+	if !ok {
+		deadlineSpecifier = &DeadlineSpecifier{
+			Deadline: 0,
 		}
+	}
 
-		if backendCluster.HTTPBackend != nil {
-			// Special support for HTTP backend.
-			isGrpc, err := httpPattern.IsGRPCPathForOperation(selector)
-			if err != nil {
-				return nil, err
-			}
-
-			if !isGrpc {
-				methodCfg.BackendClusterName = backendCluster.HTTPBackend.Name
-				methodCfg.HostRewrite = backendCluster.HTTPBackend.HostName
-				methodCfg.Deadline = deadlineSpecifier.HTTPBackendDeadline
-				methodCfg.IsStreaming = false
-			}
-		}
+	methodCfg := &helpers.MethodCfg{
+		OperationName:       selector,
+		BackendClusterName:  backendCluster.Name,
+		HostRewrite:         backendCluster.HostName,
+		AutoHostRewrite:     backendCluster.AutoHostRewrite,
+		Deadline:            deadlineSpecifier.Deadline,
+		IsStreaming:         method.GetRequestStreaming() || method.GetResponseStreaming(),
+		IsResponseStreaming: method.GetResponseStreaming(),
+		ResponseTypeUrl:     method.GetResponseTypeUrl(),
+		HTTPPattern:         httpPattern.Pattern,
+		RouteByConsumer:     true,
+		RouteByVersion:      true,
+		IsDeprecated:        g.DeprecatedSelectors[selector],
+		QuotaMetrics:        g.QuotaMetricsBySelector[selector],
+	}
 
-		methodRoutes, err := g.BackendRouteGen.GenRoutesForMethod(methodCfg, filterGens)
-		if err != nil {
-			return nil, fmt.Errorf("fail to generate routes for operation %q with HTTP pattern %q: %v", selector, httpPattern.String(), err)
+	isGrpc, err := httpPattern.IsGRPCPathForOperation(selector)
+	if err != nil {
+		return nil, err
+	}
+	methodCfg.IsGrpc = isGrpc
+
+	if backendCluster.HTTPBackend != nil {
+		// Special support for HTTP backend.
+		if !isGrpc {
+			methodCfg.BackendClusterName = backendCluster.HTTPBackend.Name
+			methodCfg.HostRewrite = backendCluster.HTTPBackend.HostName
+			methodCfg.AutoHostRewrite = backendCluster.HTTPBackend.AutoHostRewrite
+			methodCfg.Deadline = deadlineSpecifier.HTTPBackendDeadline
+			methodCfg.IsStreaming = false
+			methodCfg.IsResponseStreaming = false
+			methodCfg.ResponseTypeUrl = ""
 		}
-		routes = append(routes, methodRoutes...)
 	}
 
-	return routes, nil
+	if g.TenantRoutingClusterName != "" && methodCfg.BackendClusterName == g.TenantRoutingClusterName {
+		methodCfg.ClusterHeader = filtergen.TenantClusterHeader
+	}
+
+	methodRoutes, err := g.BackendRouteGen.GenRoutesForMethod(methodCfg, filterGens)
+	if err != nil {
+		return nil, fmt.Errorf("fail to generate routes for operation %q with HTTP pattern %q: %v", selector, httpPattern.String(), err)
+	}
+	return methodRoutes, nil
 }
 
 // AffectedHTTPPatterns implements interface RouteGenerator.
@@ -136,6 +186,15 @@ func (g *ProxyBackendGenerator) CloneConfigsBySelector(from string, to string) {
 	if ok {
 		g.MethodBySelector[to] = method
 	}
+
+	if g.DeprecatedSelectors[from] {
+		g.DeprecatedSelectors[to] = true
+	}
+
+	metrics, ok := g.QuotaMetricsBySelector[from]
+	if ok {
+		g.QuotaMetricsBySelector[to] = metrics
+	}
 }
 
 // sortHttpPatterns implements go/esp-v2-route-match-ordering-implementation.
@@ -1,6 +1,7 @@
 package routegen_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
@@ -96,6 +97,784 @@ func TestNewBackendRouteGenFromOPConfig(t *testing.T) {
     }
   ]
 }
+`,
+		},
+		{
+			Desc: "Strict route allowlist skips the catch-all operation",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "Echo",
+							},
+							{
+								Name: "CatchAll",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/echo",
+							},
+						},
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.CatchAll",
+							Pattern: &annotationspb.HttpRule_Custom{
+								Custom: &annotationspb.CustomHttpPattern{
+									Path: "/**",
+									Kind: "*",
+								},
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				StrictRouteAllowlist: true,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "Tenant routing for local backend",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "Echo",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/echo",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				TenantMapping: `{"tenants": [{"name": "acme", "backendAddress": "https://acme.example.com", "consumerNumbers": ["123"]}]}`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "clusterHeader":"x-espv2-tenant-cluster",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "clusterHeader":"x-espv2-tenant-cluster",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "Per-operation stats for local backend",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "Echo",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/echo",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					EnableOperationStats: true,
+				},
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "statPrefix":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "statPrefix":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "Named consumers are routed to their backend pools by the dynamic metadata",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "Echo",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/echo",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				ConsumerBackends: `[{"name": "bigcorp", "backendAddress": "https://bigcorp.example.com", "consumerNumbers": ["123", "456"]}]`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "dynamicMetadata":[
+          {
+            "filter":"com.google.espv2.consumer",
+            "path":[
+              {
+                "key":"consumer_number"
+              }
+            ],
+            "value":{
+              "stringMatch":{
+                "safeRegex":{
+                  "regex":"^(123|456)$"
+                }
+              }
+            }
+          }
+        ],
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-consumer-bigcorp",
+        "hostRewriteLiteral":"bigcorp.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "dynamicMetadata":[
+          {
+            "filter":"com.google.espv2.consumer",
+            "path":[
+              {
+                "key":"consumer_number"
+              }
+            ],
+            "value":{
+              "stringMatch":{
+                "safeRegex":{
+                  "regex":"^(123|456)$"
+                }
+              }
+            }
+          }
+        ],
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-consumer-bigcorp",
+        "hostRewriteLiteral":"bigcorp.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "Requests are routed to the backends of the API versions in the header",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "Echo",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/echo",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				VersionBackends: `
+{
+  "header": "X-Api-Version",
+  "versions": [
+    {"version": "v2", "backendAddress": "https://v2.example.com"},
+    {"version": "v3", "backendAddress": "https://v3.example.com"}
+  ]
+}`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          },
+          {
+            "name":"x-api-version",
+            "stringMatch":{
+              "exact":"v2"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-version-v2",
+        "hostRewriteLiteral":"v2.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          },
+          {
+            "name":"x-api-version",
+            "stringMatch":{
+              "exact":"v3"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-version-v3",
+        "hostRewriteLiteral":"v3.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          },
+          {
+            "name":"x-api-version",
+            "stringMatch":{
+              "exact":"v2"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-version-v2",
+        "hostRewriteLiteral":"v2.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          },
+          {
+            "name":"x-api-version",
+            "stringMatch":{
+              "exact":"v3"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-version-v3",
+        "hostRewriteLiteral":"v3.example.com",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			Desc: "Operations under maintenance are answered with the maintenance response",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "Echo",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/echo",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				MaintenanceOperations: "endpoints.examples.bookstore.Bookstore.E*",
+				MaintenanceResponse:   `{"status": 503, "body": "Down for maintenance", "headers": {"retry-after": "3600"}}`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"Down for maintenance"
+        },
+        "status":503
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "responseHeadersToAdd":[
+        {
+          "appendAction":"OVERWRITE_IF_EXISTS_OR_ADD",
+          "header":{
+            "key":"retry-after",
+            "value":"3600"
+          }
+        }
+      ]
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"Down for maintenance"
+        },
+        "status":503
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/echo/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "responseHeadersToAdd":[
+        {
+          "appendAction":"OVERWRITE_IF_EXISTS_OR_ADD",
+          "header":{
+            "key":"retry-after",
+            "value":"3600"
+          }
+        }
+      ]
+    }
+  ]
+}
 `,
 		},
 		{
@@ -1798,3 +2577,49 @@ func TestNewBackendRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
 		tc.RunTest(t, routegen.NewProxyBackendRouteGenFromOPConfig)
 	}
 }
+
+func TestProxyBackendGenRouteConfig_Order(t *testing.T) {
+	api := &apipb.Api{
+		Name: "endpoints.examples.bookstore.Bookstore",
+	}
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{api},
+		Http: &annotationspb.Http{},
+	}
+	for i := 0; i < 500; i++ {
+		name := fmt.Sprintf("Get%d", i)
+		api.Methods = append(api.Methods, &apipb.Method{
+			Name: name,
+		})
+		serviceConfig.Http.Rules = append(serviceConfig.Http.Rules, &annotationspb.HttpRule{
+			Selector: "endpoints.examples.bookstore.Bookstore." + name,
+			Pattern: &annotationspb.HttpRule_Get{
+				Get: fmt.Sprintf("/shelves/%d/{shelf}", i),
+			},
+		})
+	}
+
+	gen, err := routegen.NewProxyBackendRouteGenFromOPConfig(serviceConfig, options.DefaultConfigGeneratorOptions())
+	if err != nil {
+		t.Fatalf("NewProxyBackendRouteGenFromOPConfig() got error: %v", err)
+	}
+
+	// The routes generated in parallel are in the order of the sorted HTTP
+	// patterns, one route per pattern.
+	patterns := gen.AffectedHTTPPatterns()
+	for run := 0; run < 3; run++ {
+		routes, err := gen.GenRouteConfig(nil)
+		if err != nil {
+			t.Fatalf("GenRouteConfig() got error: %v", err)
+		}
+		if len(routes) != len(patterns) {
+			t.Fatalf("GenRouteConfig() got %d routes, want %d", len(routes), len(patterns))
+		}
+		for i, route := range routes {
+			if route.GetName() != patterns[i].Operation {
+				t.Fatalf("GenRouteConfig() route %d got operation %q, want %q", i, route.GetName(), patterns[i].Operation)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package routegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// RedirectRule redirects the requests matching the exact path or the path
+// prefix.
+type RedirectRule struct {
+	Path       string `json:"path"`
+	PathPrefix string `json:"pathPrefix"`
+
+	// PathRedirect replaces the path matching Path.
+	PathRedirect string `json:"pathRedirect"`
+	// PrefixRewrite replaces the prefix matching PathPrefix.
+	PrefixRewrite string `json:"prefixRewrite"`
+
+	Host   string `json:"host"`
+	Scheme string `json:"scheme"`
+
+	// Code is 301 or 308, defaults to 301.
+	Code int `json:"code"`
+}
+
+// RedirectGenerator is a RouteGenerator that redirects the requests by the
+// redirect rules, such as from the legacy "/api/v1" to "/v1". The requests
+// never reach the backend.
+type RedirectGenerator struct {
+	Rules []*RedirectRule
+
+	*NoopRouteGenerator
+}
+
+// NewRedirectRouteGenFromOPConfig creates RedirectGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewRedirectRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if opts.RedirectRules == "" {
+		glog.Info("Not adding redirect routes because no redirect rule is specified.")
+		return nil, nil
+	}
+
+	var rules []*RedirectRule
+	if err := json.Unmarshal([]byte(opts.RedirectRules), &rules); err != nil {
+		return nil, fmt.Errorf("invalid flag --redirect_rules, it should be a JSON array of redirect rules: %v", err)
+	}
+	for i, rule := range rules {
+		if err := validateRedirectRule(rule); err != nil {
+			return nil, fmt.Errorf("invalid flag --redirect_rules, redirect rule at index %d: %v", i, err)
+		}
+	}
+
+	return &RedirectGenerator{
+		Rules: rules,
+	}, nil
+}
+
+func validateRedirectRule(rule *RedirectRule) error {
+	if rule == nil {
+		return fmt.Errorf("rule cannot be null")
+	}
+
+	switch {
+	case rule.Path != "" && rule.PathPrefix != "":
+		return fmt.Errorf("only one of path and pathPrefix can be specified")
+	case rule.Path != "":
+		if !strings.HasPrefix(rule.Path, "/") {
+			return fmt.Errorf("path %q should start with /", rule.Path)
+		}
+		if rule.PrefixRewrite != "" {
+			return fmt.Errorf("prefixRewrite can only be used with pathPrefix, use pathRedirect instead")
+		}
+	case rule.PathPrefix != "":
+		if !strings.HasPrefix(rule.PathPrefix, "/") {
+			return fmt.Errorf("pathPrefix %q should start with /", rule.PathPrefix)
+		}
+		if rule.PathRedirect != "" {
+			return fmt.Errorf("pathRedirect can only be used with path, use prefixRewrite instead")
+		}
+	default:
+		return fmt.Errorf("one of path and pathPrefix must be specified")
+	}
+
+	if rule.PathRedirect == "" && rule.PrefixRewrite == "" && rule.Host == "" && rule.Scheme == "" {
+		return fmt.Errorf("at least one of pathRedirect, prefixRewrite, host and scheme must be specified")
+	}
+	if rule.Scheme != "" && rule.Scheme != "http" && rule.Scheme != "https" {
+		return fmt.Errorf("scheme %q should be http or https", rule.Scheme)
+	}
+	if rule.Code != 0 && rule.Code != http.StatusMovedPermanently && rule.Code != http.StatusPermanentRedirect {
+		return fmt.Errorf("code %d should be 301 or 308", rule.Code)
+	}
+	return nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *RedirectGenerator) RouteType() string {
+	return "redirect_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+//
+// The redirect routes have no per-route filter config, they are not
+// operations of the API.
+func (g *RedirectGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	var routes []*routepb.Route
+	for _, rule := range g.Rules {
+		match := &routepb.RouteMatch{}
+		redirect := &routepb.RedirectAction{
+			HostRedirect: rule.Host,
+			ResponseCode: routepb.RedirectAction_MOVED_PERMANENTLY,
+		}
+		if rule.Path != "" {
+			match.PathSpecifier = &routepb.RouteMatch_Path{
+				Path: rule.Path,
+			}
+			if rule.PathRedirect != "" {
+				redirect.PathRewriteSpecifier = &routepb.RedirectAction_PathRedirect{
+					PathRedirect: rule.PathRedirect,
+				}
+			}
+		} else {
+			match.PathSpecifier = &routepb.RouteMatch_Prefix{
+				Prefix: rule.PathPrefix,
+			}
+			if rule.PrefixRewrite != "" {
+				redirect.PathRewriteSpecifier = &routepb.RedirectAction_PrefixRewrite{
+					PrefixRewrite: rule.PrefixRewrite,
+				}
+			}
+		}
+		if rule.Scheme != "" {
+			redirect.SchemeRewriteSpecifier = &routepb.RedirectAction_SchemeRedirect{
+				SchemeRedirect: rule.Scheme,
+			}
+		}
+		if rule.Code == http.StatusPermanentRedirect {
+			redirect.ResponseCode = routepb.RedirectAction_PERMANENT_REDIRECT
+		}
+
+		routes = append(routes, &routepb.Route{
+			Match: match,
+			Action: &routepb.Route_Redirect{
+				Redirect: redirect,
+			},
+			Decorator: &routepb.Decorator{
+				Operation: fmt.Sprintf("%s %s_Redirect", util.SpanNamePrefix, util.AutogeneratedOperationPrefix),
+			},
+		})
+	}
+	return routes, nil
+}
@@ -0,0 +1,129 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestNewRedirectRouteGenFromOPConfig(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn:         options.ConfigGeneratorOptions{},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "prefix rewrite and host redirect with scheme",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				RedirectRules: `[
+  {"pathPrefix": "/api/v1/", "prefixRewrite": "/v1/"},
+  {"path": "/docs", "pathRedirect": "/", "host": "docs.example.com", "scheme": "https", "code": 308}
+]`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_Redirect"
+      },
+      "match":{
+        "prefix":"/api/v1/"
+      },
+      "redirect":{
+        "prefixRewrite":"/v1/"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_Redirect"
+      },
+      "match":{
+        "path":"/docs"
+      },
+      "redirect":{
+        "hostRedirect":"docs.example.com",
+        "pathRedirect":"/",
+        "responseCode":"PERMANENT_REDIRECT",
+        "schemeRedirect":"https"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewRedirectRouteGenFromOPConfig)
+	}
+}
+
+func TestNewRedirectRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "redirect rules are not a JSON array",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				RedirectRules: `{"pathPrefix": "/api/v1/"}`,
+			},
+			WantFactoryError: "invalid flag --redirect_rules, it should be a JSON array of redirect rules",
+		},
+		{
+			Desc: "both path and path prefix",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				RedirectRules: `[{"path": "/a", "pathPrefix": "/b", "host": "example.com"}]`,
+			},
+			WantFactoryError: "redirect rule at index 0: only one of path and pathPrefix can be specified",
+		},
+		{
+			Desc: "prefix rewrite with exact path",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				RedirectRules: `[{"path": "/a", "prefixRewrite": "/b"}]`,
+			},
+			WantFactoryError: "prefixRewrite can only be used with pathPrefix",
+		},
+		{
+			Desc: "nothing to redirect",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				RedirectRules: `[{"pathPrefix": "/a"}]`,
+			},
+			WantFactoryError: "at least one of pathRedirect, prefixRewrite, host and scheme must be specified",
+		},
+		{
+			Desc: "temporary redirect code",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				RedirectRules: `[{"pathPrefix": "/a", "host": "example.com", "code": 302}]`,
+			},
+			WantFactoryError: "code 302 should be 301 or 308",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewRedirectRouteGenFromOPConfig)
+	}
+}
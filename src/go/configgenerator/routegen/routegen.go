@@ -45,6 +45,14 @@ type RouteGenerator interface {
 	AffectedHTTPPatterns() httppattern.MethodSlice
 }
 
+// RedactingRouteGenerator is implemented by the RouteGenerators whose routes
+// carry secrets, such as tokens in the header matchers.
+type RedactingRouteGenerator interface {
+	// RedactRoutes returns copies of the routes with the secrets redacted, for
+	// logging.
+	RedactRoutes([]*routepb.Route) []*routepb.Route
+}
+
 // RouteGeneratorOPFactory is the factory function to create an ordered slice
 // of RouteGenerator from One Platform config.
 type RouteGeneratorOPFactory func(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error)
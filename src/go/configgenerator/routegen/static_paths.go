@@ -0,0 +1,134 @@
+package routegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// StaticPath is the inline response of a static path.
+type StaticPath struct {
+	// Status defaults to 200.
+	Status      uint32 `json:"status"`
+	Body        string `json:"body"`
+	ContentType string `json:"contentType"`
+}
+
+// StaticPathsGenerator is a RouteGenerator that answers the GET requests to
+// the static paths, such as "/robots.txt" and "/favicon.ico", directly from
+// the route config. They never reach the backend and are not reported to
+// Service Control.
+type StaticPathsGenerator struct {
+	Paths    []string
+	Patterns map[string]*httppattern.Pattern
+	Response map[string]*helpers.StaticResponse
+
+	// The routes of static paths must contain a backend cluster, though the
+	// requests never reach it.
+	LocalBackendClusterName string
+	BackendRouteGen         *helpers.BackendRouteGenerator
+
+	*NoopRouteGenerator
+}
+
+// NewStaticPathsRouteGenFromOPConfig creates StaticPathsGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewStaticPathsRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if opts.StaticPaths == "" {
+		glog.Info("Not adding static path routes because no static path is specified.")
+		return nil, nil
+	}
+
+	staticPaths := make(map[string]*StaticPath)
+	if err := json.Unmarshal([]byte(opts.StaticPaths), &staticPaths); err != nil {
+		return nil, fmt.Errorf("invalid flag --static_paths, it should be a JSON object of path to response: %v", err)
+	}
+
+	g := &StaticPathsGenerator{
+		Patterns:                make(map[string]*httppattern.Pattern),
+		Response:                make(map[string]*helpers.StaticResponse),
+		LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
+		BackendRouteGen:         helpers.NewBackendRouteGeneratorFromOPConfig(opts),
+	}
+	for path, staticPath := range staticPaths {
+		if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "{}*?#") {
+			return nil, fmt.Errorf("invalid flag --static_paths, path %q should start with / and not contain wildcards, variables, query or fragment", path)
+		}
+		if staticPath == nil {
+			return nil, fmt.Errorf("invalid flag --static_paths, path %q has no response", path)
+		}
+
+		uriTemplate, err := httppattern.ParseUriTemplate(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag --static_paths, path %q: %v", path, err)
+		}
+
+		response := &helpers.StaticResponse{
+			Status: staticPath.Status,
+			Body:   staticPath.Body,
+		}
+		if response.Status == 0 {
+			response.Status = http.StatusOK
+		}
+		if response.Status < 200 || response.Status >= 600 {
+			return nil, fmt.Errorf("invalid flag --static_paths, path %q has invalid status %d, must be in [200, 600)", path, response.Status)
+		}
+		if staticPath.ContentType != "" {
+			response.Headers = map[string]string{
+				"content-type": staticPath.ContentType,
+			}
+		}
+
+		g.Paths = append(g.Paths, path)
+		g.Patterns[path] = &httppattern.Pattern{
+			HttpMethod:  util.GET,
+			UriTemplate: uriTemplate,
+		}
+		g.Response[path] = response
+	}
+
+	// Sort the paths so the generated config is stable.
+	sort.Strings(g.Paths)
+	return g, nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *StaticPathsGenerator) RouteType() string {
+	return "static_path_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+func (g *StaticPathsGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	var staticPathRoutes []*routepb.Route
+	for _, path := range g.Paths {
+		methodCfg := &helpers.MethodCfg{
+			OperationName:      filtergen.StaticPathOperationName,
+			BackendClusterName: g.LocalBackendClusterName,
+			Deadline:           util.DefaultResponseDeadline,
+			HTTPPattern:        g.Patterns[path],
+		}
+
+		routes, err := g.BackendRouteGen.GenRoutesForMethod(methodCfg, filterGens)
+		if err != nil {
+			return nil, fmt.Errorf("fail to make routes for static path %q: %v", path, err)
+		}
+		for _, route := range routes {
+			g.Response[path].ApplyTo(route)
+		}
+		staticPathRoutes = append(staticPathRoutes, routes...)
+	}
+	return staticPathRoutes, nil
+}
@@ -0,0 +1,185 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestNewStaticPathsRouteGenFromOPConfig(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "disabled by default",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn:         options.ConfigGeneratorOptions{},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc: "robots.txt with content type and favicon without body",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				StaticPaths: `{"/robots.txt": {"body": "User-agent: *\nDisallow: /", "contentType": "text/plain"}, "/favicon.ico": {"status": 204}}`,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_StaticPath"
+      },
+      "directResponse":{
+        "status":204
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/favicon.ico"
+      },
+      "name":"espv2_deployment.ESPv2_Autogenerated_StaticPath"
+    },
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_StaticPath"
+      },
+      "directResponse":{
+        "status":204
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/favicon.ico/"
+      },
+      "name":"espv2_deployment.ESPv2_Autogenerated_StaticPath"
+    },
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_StaticPath"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"User-agent: *\nDisallow: /"
+        },
+        "status":200
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/robots.txt"
+      },
+      "name":"espv2_deployment.ESPv2_Autogenerated_StaticPath",
+      "responseHeadersToAdd":[
+        {
+          "appendAction":"OVERWRITE_IF_EXISTS_OR_ADD",
+          "header":{
+            "key":"content-type",
+            "value":"text/plain"
+          }
+        }
+      ]
+    },
+    {
+      "decorator":{
+        "operation":"ingress ESPv2_Autogenerated_StaticPath"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"User-agent: *\nDisallow: /"
+        },
+        "status":200
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/robots.txt/"
+      },
+      "name":"espv2_deployment.ESPv2_Autogenerated_StaticPath",
+      "responseHeadersToAdd":[
+        {
+          "appendAction":"OVERWRITE_IF_EXISTS_OR_ADD",
+          "header":{
+            "key":"content-type",
+            "value":"text/plain"
+          }
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewStaticPathsRouteGenFromOPConfig)
+	}
+}
+
+func TestNewStaticPathsRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []routegentest.FactoryErrorOPTestCase{
+		{
+			Desc: "static paths are not JSON",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				StaticPaths: "/robots.txt",
+			},
+			WantFactoryError: "invalid flag --static_paths, it should be a JSON object of path to response",
+		},
+		{
+			Desc: "static path with wildcard",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				StaticPaths: `{"/static/*": {"body": "hello"}}`,
+			},
+			WantFactoryError: `invalid flag --static_paths, path "/static/*" should start with / and not contain wildcards`,
+		},
+		{
+			Desc: "static path with invalid status",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				StaticPaths: `{"/robots.txt": {"status": 700}}`,
+			},
+			WantFactoryError: `invalid flag --static_paths, path "/robots.txt" has invalid status 700, must be in [200, 600)`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewStaticPathsRouteGenFromOPConfig)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	clusterhelpers "github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
@@ -45,6 +46,11 @@ type BackendClusterSpecifier struct {
 	// HTTPBackend is filled in if the backend rule has an associated HTTP backend.
 	// In this case, all HTTP routes must redirect to this backend.
 	HTTPBackend *BackendClusterSpecifier
+
+	// AutoHostRewrite is set if the remote cluster has failover or regional
+	// backends, so the Host header is rewritten to the hostname of the chosen
+	// backend.
+	AutoHostRewrite bool
 }
 
 // ParseBackendClusterBySelectorFromOPConfig parses the service config into a
@@ -55,12 +61,35 @@ func ParseBackendClusterBySelectorFromOPConfig(serviceConfig *servicepb.Service,
 	selectors := ParseSelectorsFromOPConfig(serviceConfig, opts)
 	backendRuleBySelector := PrecomputeBackendRuleBySelectorFromOPConfig(serviceConfig, opts)
 
+	failovers, err := clusterhelpers.ParseBackendFailoverAddresses(opts.BackendFailoverAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --backend_failover_addresses: %v", err)
+	}
+	regionalBackends, err := clustergen.ParseBackendRegionalAddresses(opts.BackendRegionalAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --backend_regional_addresses: %v", err)
+	}
+
+	// The Host header of the remote clusters with endpoints of other
+	// hostnames is rewritten to the hostname of the chosen endpoint.
+	autoHostRewriteClusterNames := make(map[string]bool)
+	for address := range failovers {
+		autoHostRewriteClusterNames[clustergen.RemoteAddressToClusterName(address)] = true
+	}
+	for address := range regionalBackends {
+		autoHostRewriteClusterNames[clustergen.RemoteAddressToClusterName(address)] = true
+	}
+
 	backendClusterBySelector := make(map[string]*BackendClusterSpecifier)
 	for _, selector := range selectors {
 		clusterSpecifier, err := determineBackendClusterForSelector(selector, backendRuleBySelector, serviceConfig, opts)
 		if err != nil {
 			return nil, fmt.Errorf("error determining backend cluster for operation %q: %v", selector, err)
 		}
+		clusterSpecifier.AutoHostRewrite = autoHostRewriteClusterNames[clusterSpecifier.Name]
+		if clusterSpecifier.HTTPBackend != nil {
+			clusterSpecifier.HTTPBackend.AutoHostRewrite = autoHostRewriteClusterNames[clusterSpecifier.HTTPBackend.Name]
+		}
 		backendClusterBySelector[selector] = clusterSpecifier
 	}
 
@@ -69,6 +98,21 @@ func ParseBackendClusterBySelectorFromOPConfig(serviceConfig *servicepb.Service,
 
 // First return value is normal backend cluster.
 // Second one is the HTTP backend (if supported).
+// isBackendGRPCForSelector returns true if the operation is served by a gRPC
+// backend without an HTTP backend, so its http rules need transcoding.
+func isBackendGRPCForSelector(selector string, backendRuleBySelector map[string]*servicepb.BackendRule, opts options.ConfigGeneratorOptions) (bool, error) {
+	address := opts.BackendAddress
+	if backendRule, ok := backendRuleBySelector[selector]; ok && !opts.EnableBackendAddressOverride {
+		if clustergen.IsHTTPBackendEnabled(backendRule) != nil {
+			return false, nil
+		}
+		if backendRule.GetAddress() != "" {
+			address = backendRule.GetAddress()
+		}
+	}
+	return util.IsBackendGRPC(address)
+}
+
 func determineBackendClusterForSelector(selector string, backendRuleBySelector map[string]*servicepb.BackendRule, serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (*BackendClusterSpecifier, error) {
 	localCluster := &BackendClusterSpecifier{
 		Name: clustergen.MakeLocalBackendClusterName(serviceConfig),
@@ -140,6 +184,12 @@ func PrecomputeBackendRuleBySelectorFromOPConfig(serviceConfig *servicepb.Servic
 	return backendRuleBySelector
 }
 
+// isCatchAllPattern returns true if the http pattern matches all the paths,
+// such as the operations of the OpenAPI `x-google-allow: all`.
+func isCatchAllPattern(pattern *httppattern.Pattern) bool {
+	return len(pattern.Segments) == 1 && pattern.Segments[0] == "**" && pattern.Verb == ""
+}
+
 // ParseHTTPPatternsBySelectorFromOPConfig parses the service config into a list
 // of internal HTTP pattern representations, keyed by OP selector.
 //
@@ -157,6 +207,11 @@ func ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig *servicepb.Service, o
 		return nil, fmt.Errorf("fail to compute snake_case to camelCase field mappings: %v", err)
 	}
 
+	var backendRuleBySelector map[string]*servicepb.BackendRule
+	if opts.DisableTranscoding {
+		backendRuleBySelector = PrecomputeBackendRuleBySelectorFromOPConfig(serviceConfig, opts)
+	}
+
 	httpPatternsBySelector := make(map[string][]*httppattern.Pattern)
 	for _, selector := range selectors {
 		rule, ok := httpRuleBySelector[selector]
@@ -164,6 +219,17 @@ func ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig *servicepb.Service, o
 			continue
 		}
 
+		if opts.DisableTranscoding {
+			isGRPC, err := isBackendGRPCForSelector(selector, backendRuleBySelector, opts)
+			if err != nil {
+				return nil, fmt.Errorf("fail to check backend protocol for operation %q: %v", selector, err)
+			}
+			if isGRPC {
+				glog.Infof("Skip http rules for operation %q because flag --disable_transcoding is set and it has a gRPC backend.", selector)
+				continue
+			}
+		}
+
 		snakeToJson, ok := selectorToJsonMappings[selector]
 		if !ok {
 			// No mappings is OK.
@@ -174,7 +240,11 @@ func ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig *servicepb.Service, o
 		if err != nil {
 			return nil, fmt.Errorf("fail to process http rule for operation %q: %v", selector, err)
 		}
-		httpPatternsBySelector[selector] = append(httpPatternsBySelector[selector], pattern)
+		if opts.StrictRouteAllowlist && isCatchAllPattern(pattern) {
+			glog.Warningf("Skip http rule %v %v for operation %q because flag --strict_route_allowlist rejects the catch-all paths.", pattern.HttpMethod, pattern.Origin, selector)
+		} else {
+			httpPatternsBySelector[selector] = append(httpPatternsBySelector[selector], pattern)
+		}
 
 		// additional_bindings cannot be nested inside themselves according to
 		// https://aip.dev/127. Service Management will enforce this restriction
@@ -185,6 +255,10 @@ func ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig *servicepb.Service, o
 			if err != nil {
 				return nil, fmt.Errorf("fail to process http rule's additional_binding at index %d for operation %q: %v", i, selector, err)
 			}
+			if opts.StrictRouteAllowlist && isCatchAllPattern(additionalPattern) {
+				glog.Warningf("Skip http rule's additional_binding %v %v for operation %q because flag --strict_route_allowlist rejects the catch-all paths.", additionalPattern.HttpMethod, additionalPattern.Origin, selector)
+				continue
+			}
 			httpPatternsBySelector[selector] = append(httpPatternsBySelector[selector], additionalPattern)
 		}
 	}
@@ -308,6 +382,37 @@ type DeadlineSpecifier struct {
 	HTTPBackendDeadline time.Duration
 }
 
+// ParseDeprecatedSelectorsFromOPConfig returns the set of the selectors
+// deprecated by the documentation rules with a deprecation description. The
+// rule selector may end with "*" to match the selectors with its prefix.
+func ParseDeprecatedSelectorsFromOPConfig(serviceConfig *servicepb.Service) map[string]bool {
+	methodBySelector := ParseMethodBySelectorFromOPConfig(serviceConfig)
+	deprecatedSelectors := make(map[string]bool)
+	for _, rule := range serviceConfig.GetDocumentation().GetRules() {
+		if rule.GetDeprecationDescription() == "" {
+			continue
+		}
+		for selector := range methodBySelector {
+			if rule.GetSelector() == selector || (strings.HasSuffix(rule.GetSelector(), "*") && strings.HasPrefix(selector, strings.TrimSuffix(rule.GetSelector(), "*"))) {
+				deprecatedSelectors[selector] = true
+			}
+		}
+	}
+	return deprecatedSelectors
+}
+
+// ParseQuotaMetricsBySelectorFromOPConfig returns the names of the quota
+// metrics consumed by the selectors, sorted.
+func ParseQuotaMetricsBySelectorFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) map[string][]string {
+	quotaMetricsBySelector := make(map[string][]string)
+	for selector, metricCosts := range filtergen.GetQuotaMetricCostsFromOPConfig(serviceConfig, opts) {
+		for _, metricCost := range metricCosts {
+			quotaMetricsBySelector[selector] = append(quotaMetricsBySelector[selector], metricCost.GetName())
+		}
+	}
+	return quotaMetricsBySelector
+}
+
 // ParseDeadlineSelectorFromOPConfig parses deadline by selector.
 // Only contains selectors that have backend rules.
 //
@@ -731,6 +731,72 @@ func TestParseHTTPPatternsBySelectorFromOPConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "grpc_service_http_rule_transcoding_disabled",
+			serviceConfig: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "ListShelves",
+							},
+							{
+								Name: "CreateShelf",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/v1/shelves",
+							},
+						},
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.CreateShelf",
+							Pattern: &annotationspb.HttpRule_Post{
+								Post: "/v2/shelves",
+							},
+							Body: "shelf",
+						},
+					},
+				},
+				Backend: &servicepb.Backend{
+					Rules: []*servicepb.BackendRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.CreateShelf",
+							Address:  "https://mybackend.com",
+						},
+					},
+				},
+			},
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress:     "grpc://127.0.0.1:80",
+				DisableTranscoding: true,
+			},
+			want: map[string][]*httppattern.Pattern{
+				"endpoints.examples.bookstore.Bookstore.ListShelves": {
+					{
+						HttpMethod:  util.POST,
+						UriTemplate: parseUriTemplate(t, "/endpoints.examples.bookstore.Bookstore/ListShelves"),
+					},
+				},
+				"endpoints.examples.bookstore.Bookstore.CreateShelf": {
+					{
+						HttpMethod:  util.POST,
+						UriTemplate: parseUriTemplate(t, "/v2/shelves"),
+					},
+					{
+						HttpMethod:  util.POST,
+						UriTemplate: parseUriTemplate(t, "/endpoints.examples.bookstore.Bookstore/CreateShelf"),
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testdata {
@@ -921,6 +987,92 @@ func TestParseDeadlineSelectorFromOPConfig(t *testing.T) {
 	}
 }
 
+func TestParseDeprecatedSelectorsFromOPConfig(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListShelves",
+					},
+					{
+						Name: "GetShelf",
+					},
+				},
+			},
+			{
+				Name: "endpoints.examples.bookstore.v1.Library",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListBooks",
+					},
+					{
+						Name: "GetBook",
+					},
+				},
+			},
+		},
+		Documentation: &servicepb.Documentation{
+			Rules: []*servicepb.DocumentationRule{
+				{
+					Selector:               "endpoints.examples.bookstore.Bookstore.GetShelf",
+					DeprecationDescription: "Use ListShelves instead.",
+				},
+				{
+					Selector:               "endpoints.examples.bookstore.v1.*",
+					DeprecationDescription: "Use v2 instead.",
+				},
+				{
+					Selector:    "endpoints.examples.bookstore.Bookstore.ListShelves",
+					Description: "Not deprecated.",
+				},
+			},
+		},
+	}
+
+	want := map[string]bool{
+		"endpoints.examples.bookstore.Bookstore.GetShelf":   true,
+		"endpoints.examples.bookstore.v1.Library.ListBooks": true,
+		"endpoints.examples.bookstore.v1.Library.GetBook":   true,
+	}
+	got := ParseDeprecatedSelectorsFromOPConfig(serviceConfig)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseDeprecatedSelectorsFromOPConfig() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseQuotaMetricsBySelectorFromOPConfig(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Quota: &servicepb.Quota{
+			MetricRules: []*servicepb.MetricRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.CreateShelf",
+					MetricCosts: map[string]int64{
+						"write_requests": 2,
+						"read_requests":  1,
+					},
+				},
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+					MetricCosts: map[string]int64{
+						"read_requests": 1,
+					},
+				},
+			},
+		},
+	}
+
+	want := map[string][]string{
+		"endpoints.examples.bookstore.Bookstore.CreateShelf": {"read_requests", "write_requests"},
+		"endpoints.examples.bookstore.Bookstore.ListShelves": {"read_requests"},
+	}
+	got := ParseQuotaMetricsBySelectorFromOPConfig(serviceConfig, options.ConfigGeneratorOptions{})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseQuotaMetricsBySelectorFromOPConfig() diff (-want +got):\n%s", diff)
+	}
+}
+
 func TestComputeSnakeToJsonMapping(t *testing.T) {
 	testdata := []struct {
 		desc          string
@@ -0,0 +1,196 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configinfo
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The levels of the backend rules inherited with flag
+// --backend_rule_inheritance, from the least specific to the most specific.
+const (
+	// backendRuleLevelService is the rule of selector "*".
+	backendRuleLevelService = iota
+	// backendRuleLevelApi is the rule of an API, such as "pkg.Api.*".
+	backendRuleLevelApi
+	// backendRuleLevelGroup is the rule of the other wildcard selectors, such
+	// as "pkg.Api.GetShelf*" for the operations under a path.
+	backendRuleLevelGroup
+	// backendRuleLevelOperation is the rule of an operation.
+	backendRuleLevelOperation
+)
+
+var backendRuleLevelNames = map[int]string{
+	backendRuleLevelService:   "service",
+	backendRuleLevelApi:       "API",
+	backendRuleLevelGroup:     "path",
+	backendRuleLevelOperation: "operation",
+}
+
+type leveledBackendRule struct {
+	rule  *confpb.BackendRule
+	level int
+}
+
+// applyBackendRuleInheritance returns a copy of the service config with one
+// backend rule for each operation, merged from all the backend rules matching
+// it. The fields set by the more specific rules, such as the deadline and the
+// jwt_audience of an operation, override the ones of the less specific rules.
+// The matching rules of the same level must not set different values.
+func applyBackendRuleInheritance(serviceConfig *confpb.Service) (*confpb.Service, error) {
+	apiNames := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		apiNames[api.GetName()] = true
+	}
+
+	var rules []*leveledBackendRule
+	for _, r := range serviceConfig.GetBackend().GetRules() {
+		if _, err := path.Match(r.GetSelector(), ""); err != nil {
+			return nil, fmt.Errorf("invalid selector %q of backend rule: %v", r.GetSelector(), err)
+		}
+		rules = append(rules, &leveledBackendRule{
+			rule:  r,
+			level: backendRuleLevel(r.GetSelector(), apiNames),
+		})
+	}
+	if len(rules) == 0 {
+		return serviceConfig, nil
+	}
+
+	var mergedRules []*confpb.BackendRule
+	operations := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			operation := fmt.Sprintf("%s.%s", api.GetName(), method.GetName())
+			operations[operation] = true
+
+			var matched []*leveledBackendRule
+			for _, r := range rules {
+				if ok, _ := path.Match(r.rule.GetSelector(), operation); ok {
+					matched = append(matched, r)
+				}
+			}
+			if len(matched) == 0 {
+				continue
+			}
+
+			merged, err := mergeBackendRules(operation, matched)
+			if err != nil {
+				return nil, err
+			}
+			mergedRules = append(mergedRules, merged)
+		}
+	}
+
+	// The rules of the operations not in the APIs, such as the skipped
+	// discovery APIs, are kept as is.
+	for _, r := range rules {
+		if r.level == backendRuleLevelOperation && !operations[r.rule.GetSelector()] {
+			mergedRules = append(mergedRules, r.rule)
+		} else if r.level != backendRuleLevelOperation && !matchesAnyOperation(r.rule.GetSelector(), operations) {
+			glog.Warningf("Backend rule with selector %q does not match any operation.", r.rule.GetSelector())
+		}
+	}
+
+	serviceConfig = proto.Clone(serviceConfig).(*confpb.Service)
+	serviceConfig.Backend.Rules = mergedRules
+	return serviceConfig, nil
+}
+
+func backendRuleLevel(selector string, apiNames map[string]bool) int {
+	switch {
+	case selector == "*":
+		return backendRuleLevelService
+	case strings.HasSuffix(selector, ".*") && apiNames[strings.TrimSuffix(selector, ".*")]:
+		return backendRuleLevelApi
+	case strings.ContainsAny(selector, "*?["):
+		return backendRuleLevelGroup
+	default:
+		return backendRuleLevelOperation
+	}
+}
+
+func matchesAnyOperation(selector string, operations map[string]bool) bool {
+	for operation := range operations {
+		if ok, _ := path.Match(selector, operation); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeBackendRules merges the backend rules matching the operation from the
+// least specific to the most specific.
+func mergeBackendRules(operation string, matched []*leveledBackendRule) (*confpb.BackendRule, error) {
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].level < matched[j].level
+	})
+
+	merged := &confpb.BackendRule{}
+	for i, r := range matched {
+		for _, other := range matched[:i] {
+			if other.level != r.level {
+				continue
+			}
+			if field := conflictingBackendRuleField(other.rule, r.rule); field != "" {
+				return nil, fmt.Errorf("backend rules %q and %q of the %s level set different %s for operation %q",
+					other.rule.GetSelector(), r.rule.GetSelector(), backendRuleLevelNames[r.level], field, operation)
+			}
+		}
+		proto.Merge(merged, r.rule)
+	}
+	merged.Selector = operation
+	return merged, nil
+}
+
+// conflictingBackendRuleField returns the name of the first field, or oneof,
+// set to different values by the backend rules, or empty if none.
+func conflictingBackendRuleField(a, b *confpb.BackendRule) string {
+	ma, mb := a.ProtoReflect(), b.ProtoReflect()
+	var conflict string
+	ma.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Name() == "selector" {
+			return true
+		}
+		if oneof := fd.ContainingOneof(); oneof != nil {
+			if other := mb.WhichOneof(oneof); other != nil && other != fd {
+				conflict = string(oneof.Name())
+				return false
+			}
+		}
+		if mb.Has(fd) && !proto.Equal(backendRuleField(ma, fd), backendRuleField(mb, fd)) {
+			conflict = string(fd.Name())
+			return false
+		}
+		return true
+	})
+	return conflict
+}
+
+// backendRuleField returns a backend rule with only the field of m set, to
+// compare the field values.
+func backendRuleField(m protoreflect.Message, fd protoreflect.FieldDescriptor) *confpb.BackendRule {
+	r := &confpb.BackendRule{}
+	r.ProtoReflect().Set(fd, m.Get(fd))
+	return r
+}
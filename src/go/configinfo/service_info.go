@@ -32,6 +32,7 @@ import (
 	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	typepb "google.golang.org/genproto/protobuf/ptype"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
 )
 
@@ -94,6 +95,17 @@ func NewServiceInfoFromServiceConfig(serviceConfig *confpb.Service, opts options
 	if len(serviceConfig.GetApis()) == 0 {
 		return nil, fmt.Errorf("service config must have one api at least")
 	}
+	if opts.BackendRuleInheritance {
+		var err error
+		serviceConfig, err = applyBackendRuleInheritance(serviceConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend rules with flag --backend_rule_inheritance: %v", err)
+		}
+	}
+	serviceConfig, err := applyBackendAddressOverrides(serviceConfig, opts.BackendAddressOverrides)
+	if err != nil {
+		return nil, err
+	}
 
 	serviceInfo := &ServiceInfo{
 		Name:                             serviceConfig.GetName(),
@@ -202,6 +214,33 @@ func (s *ServiceInfo) buildBackendFromAddress(address string) error {
 	return nil
 }
 
+// applyBackendAddressOverrides returns a copy of the service config with the
+// addresses of the backend rules replaced by flag --backend_address_overrides,
+// so all the generators see the overridden addresses.
+func applyBackendAddressOverrides(serviceConfig *confpb.Service, overrides string) (*confpb.Service, error) {
+	if overrides == "" {
+		return serviceConfig, nil
+	}
+	addressByOperation, err := util.ParseSelectorMap(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --backend_address_overrides: %v", err)
+	}
+	for _, address := range addressByOperation.Values() {
+		if _, _, _, _, err := util.ParseURI(address); err != nil {
+			return nil, fmt.Errorf("invalid flag --backend_address_overrides, address %q: %v", address, err)
+		}
+	}
+
+	serviceConfig = proto.Clone(serviceConfig).(*confpb.Service)
+	for _, r := range serviceConfig.GetBackend().GetRules() {
+		if address, ok := addressByOperation.Lookup(r.GetSelector()); ok {
+			glog.Infof("Override the backend address of operation %q from %q to %q.", r.GetSelector(), r.GetAddress(), address)
+			r.Address = address
+		}
+	}
+	return serviceConfig, nil
+}
+
 // Returns the pointer of the ServiceConfig that this API belongs to.
 func (s *ServiceInfo) ServiceConfig() *confpb.Service {
 	return s.serviceConfig
@@ -222,7 +261,7 @@ func (s *ServiceInfo) processEmptyJwksUriByOpenID() error {
 			}
 
 			glog.Infof("jwks_uri is empty for provider (%v), using OpenID Connect Discovery protocol", provider.Id)
-			jwksUriByOpenID, err := util.ResolveJwksUriUsingOpenID(provider.GetIssuer())
+			jwksUriByOpenID, err := util.ResolveJwksUriUsingOpenID(provider.GetIssuer(), options.OidcDiscoveryFetchOptions(s.Options))
 			if err != nil {
 				return fmt.Errorf("error processing authentication provider (%v): failed OpenID Connect Discovery protocol: %v", provider.Id, err)
 			} else {
@@ -303,7 +342,7 @@ func (s *ServiceInfo) addGrpcHttpRules() error {
 }
 
 func (s *ServiceInfo) processAccessToken() {
-	if s.Options.ServiceAccountKey != "" {
+	if options.ServiceControlServiceAccountKey(s.Options) != "" {
 		s.AccessToken = &commonpb.AccessToken{
 			TokenType: &commonpb.AccessToken_RemoteToken{
 				RemoteToken: &commonpb.HttpUri{
@@ -3067,6 +3067,292 @@ func TestBackendAddressOverride(t *testing.T) {
 	}
 }
 
+func TestBackendAddressOverrides(t *testing.T) {
+	testData := []struct {
+		desc                    string
+		backendAddressOverrides string
+		// Map of selector to the expected backend cluster.
+		wantedMethodBackendCluster map[string]string
+		wantError                  string
+	}{
+		{
+			desc: "No override",
+			wantedMethodBackendCluster: map[string]string{
+				"abc.com.a": "backend-cluster-abc.com:443",
+				"abc.com.b": "backend-cluster-abc.com:443",
+			},
+		},
+		{
+			desc:                    "Override the address of one operation",
+			backendAddressOverrides: "abc.com.a=https://staging.abc.com/a",
+			wantedMethodBackendCluster: map[string]string{
+				"abc.com.a": "backend-cluster-staging.abc.com:443",
+				"abc.com.b": "backend-cluster-abc.com:443",
+			},
+		},
+		{
+			desc:                    "The first matching selector applies",
+			backendAddressOverrides: "abc.com.b=https://b.staging.abc.com;abc.com.*=https://staging.abc.com:8443",
+			wantedMethodBackendCluster: map[string]string{
+				"abc.com.a": "backend-cluster-staging.abc.com:8443",
+				"abc.com.b": "backend-cluster-b.staging.abc.com:443",
+			},
+		},
+		{
+			desc:                    "Invalid mapping",
+			backendAddressOverrides: "abc.com.a",
+			wantError:               `invalid flag --backend_address_overrides: invalid selector mapping "abc.com.a"`,
+		},
+		{
+			desc:                    "Invalid address",
+			backendAddressOverrides: "abc.com.a=https://staging.abc.com:port",
+			wantError:               `invalid flag --backend_address_overrides, address "https://staging.abc.com:port"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			fakeServiceConfig := &confpb.Service{
+				Name: "echo.endpoints",
+				Apis: []*apipb.Api{
+					{
+						Name: "abc.com",
+						Methods: []*apipb.Method{
+							{
+								Name: "a",
+							},
+							{
+								Name: "b",
+							},
+						},
+					},
+				},
+				Backend: &confpb.Backend{
+					Rules: []*confpb.BackendRule{
+						{
+							Address:  "https://abc.com/a",
+							Selector: "abc.com.a",
+						},
+						{
+							Address:  "https://abc.com/b",
+							Selector: "abc.com.b",
+						},
+					},
+				},
+			}
+			originalServiceConfig := proto.Clone(fakeServiceConfig)
+
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.BackendAddressOverrides = tc.backendAddressOverrides
+			s, err := NewServiceInfoFromServiceConfig(fakeServiceConfig, opts)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error not expected, got: %v", err)
+			}
+
+			for operation, wantBackendCluster := range tc.wantedMethodBackendCluster {
+				if gotBackendCluster := s.Methods[operation].BackendInfo.ClusterName; gotBackendCluster != wantBackendCluster {
+					t.Errorf("Backend cluster name of %q not expected, got: %v, want: %v", operation, gotBackendCluster, wantBackendCluster)
+				}
+			}
+			if !proto.Equal(fakeServiceConfig, originalServiceConfig) {
+				t.Errorf("the input service config should not be modified")
+			}
+		})
+	}
+}
+
+func TestBackendRuleInheritance(t *testing.T) {
+	testData := []struct {
+		desc         string
+		backendRules []*confpb.BackendRule
+		// Map of selector to the expected backend info.
+		wantBackendInfo map[string]*backendInfo
+		wantError       string
+	}{
+		{
+			desc: "Operation overrides path, which overrides API",
+			backendRules: []*confpb.BackendRule{
+				{
+					Selector: "abc.com.*",
+					Address:  "https://abc.com",
+					Deadline: 10,
+					Authentication: &confpb.BackendRule_JwtAudience{
+						JwtAudience: "api-audience",
+					},
+				},
+				{
+					Selector: "abc.com.Shelf*",
+					Deadline: 20,
+				},
+				{
+					Selector: "abc.com.ShelfDelete",
+					Authentication: &confpb.BackendRule_JwtAudience{
+						JwtAudience: "operation-audience",
+					},
+				},
+			},
+			wantBackendInfo: map[string]*backendInfo{
+				"abc.com.Book": {
+					ClusterName: "backend-cluster-abc.com:443",
+					Hostname:    "abc.com",
+					Port:        443,
+					Deadline:    10 * time.Second,
+					IdleTimeout: 300 * time.Second,
+					JwtAudience: "api-audience",
+				},
+				"abc.com.ShelfGet": {
+					ClusterName: "backend-cluster-abc.com:443",
+					Hostname:    "abc.com",
+					Port:        443,
+					Deadline:    20 * time.Second,
+					IdleTimeout: 300 * time.Second,
+					JwtAudience: "api-audience",
+				},
+				"abc.com.ShelfDelete": {
+					ClusterName: "backend-cluster-abc.com:443",
+					Hostname:    "abc.com",
+					Port:        443,
+					Deadline:    20 * time.Second,
+					IdleTimeout: 300 * time.Second,
+					JwtAudience: "operation-audience",
+				},
+			},
+		},
+		{
+			desc: "Service level rule applies to all operations",
+			backendRules: []*confpb.BackendRule{
+				{
+					Selector: "*",
+					Address:  "https://abc.com",
+					Authentication: &confpb.BackendRule_DisableAuth{
+						DisableAuth: true,
+					},
+				},
+				{
+					Selector: "abc.com.Book",
+					Address:  "https://book.abc.com",
+				},
+			},
+			wantBackendInfo: map[string]*backendInfo{
+				"abc.com.Book": {
+					ClusterName: "backend-cluster-book.abc.com:443",
+					Hostname:    "book.abc.com",
+					Port:        443,
+					Deadline:    15 * time.Second,
+					IdleTimeout: 300 * time.Second,
+				},
+				"abc.com.ShelfGet": {
+					ClusterName: "backend-cluster-abc.com:443",
+					Hostname:    "abc.com",
+					Port:        443,
+					Deadline:    15 * time.Second,
+					IdleTimeout: 300 * time.Second,
+				},
+			},
+		},
+		{
+			desc: "Conflicting rules of the same level",
+			backendRules: []*confpb.BackendRule{
+				{
+					Selector: "abc.com.Shelf*",
+					Deadline: 20,
+				},
+				{
+					Selector: "abc.com.*Get",
+					Deadline: 30,
+				},
+			},
+			wantError: `backend rules "abc.com.Shelf*" and "abc.com.*Get" of the path level set different deadline for operation "abc.com.ShelfGet"`,
+		},
+		{
+			desc: "Conflicting authentication of the same level",
+			backendRules: []*confpb.BackendRule{
+				{
+					Selector: "abc.com.Book",
+					Authentication: &confpb.BackendRule_JwtAudience{
+						JwtAudience: "audience",
+					},
+				},
+				{
+					Selector: "abc.com.Book",
+					Authentication: &confpb.BackendRule_DisableAuth{
+						DisableAuth: true,
+					},
+				},
+			},
+			wantError: `backend rules "abc.com.Book" and "abc.com.Book" of the operation level set different authentication for operation "abc.com.Book"`,
+		},
+		{
+			desc: "Invalid selector",
+			backendRules: []*confpb.BackendRule{
+				{
+					Selector: "abc.com.[",
+					Address:  "https://abc.com",
+				},
+			},
+			wantError: `invalid backend rules with flag --backend_rule_inheritance: invalid selector "abc.com.["`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			fakeServiceConfig := &confpb.Service{
+				Name: "echo.endpoints",
+				Apis: []*apipb.Api{
+					{
+						Name: "abc.com",
+						Methods: []*apipb.Method{
+							{
+								Name: "Book",
+							},
+							{
+								Name: "ShelfGet",
+							},
+							{
+								Name: "ShelfDelete",
+							},
+						},
+					},
+				},
+				Backend: &confpb.Backend{
+					Rules: tc.backendRules,
+				},
+			}
+			originalServiceConfig := proto.Clone(fakeServiceConfig)
+
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.BackendRuleInheritance = true
+			s, err := NewServiceInfoFromServiceConfig(fakeServiceConfig, opts)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error not expected, got: %v", err)
+			}
+
+			for operation, wantBackendInfo := range tc.wantBackendInfo {
+				wantBackendInfo.RetryOns = opts.BackendRetryOns
+				wantBackendInfo.RetryNum = opts.BackendRetryNum
+				if diff := cmp.Diff(wantBackendInfo, s.Methods[operation].BackendInfo); diff != "" {
+					t.Errorf("backend info of %q diff (-want +got):\n%s", operation, diff)
+				}
+			}
+			if !proto.Equal(fakeServiceConfig, originalServiceConfig) {
+				t.Errorf("the input service config should not be modified")
+			}
+		})
+	}
+}
+
 func TestProcessQuota(t *testing.T) {
 	testData := []struct {
 		desc              string
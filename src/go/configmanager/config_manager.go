@@ -16,12 +16,18 @@ package configmanager
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
@@ -40,6 +46,10 @@ import (
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 var (
@@ -54,6 +64,17 @@ var (
 					GCP metadata server will not be called to fetch access token, and
 					following flags will be ignored; --service_config_id, --service,
 					--rollout_strategy`)
+	K8sConfigMap = flag.String("k8s_config_map", "", `the Kubernetes ConfigMap holding the service config json, in the format of namespace/name.
+					When this flag is used, the ConfigMap is fetched from the Kubernetes API server
+					using the pod service account, and is polled for changes every --check_rollout_interval.
+					Following flags will be ignored; --service_config_id, --service, --rollout_strategy`)
+	K8sConfigMapKey = flag.String("k8s_config_map_key", "service.json", `the key of the service config json in the ConfigMap specified by --k8s_config_map`)
+	K8sAPIServerURL = flag.String("k8s_api_server_url", util.KubernetesAPIServerURL, `url of the Kubernetes API server, used with --k8s_config_map`)
+
+	TranscodingProtoDescriptorURL = flag.String("transcoding_proto_descriptor_url", "", `HTTPS or GCS url, such as "gs://bucket/api_descriptor.pb", of the proto descriptor used by gRPC-JSON transcoding.
+					When this flag is used, the proto descriptor in the service config is replaced, and the descriptor is checked for changes
+					every --transcoding_proto_descriptor_refresh_interval, so the new RPCs are transcoded without a redeployment.`)
+	TranscodingProtoDescriptorRefreshInterval = flag.Duration("transcoding_proto_descriptor_refresh_interval", 5*time.Minute, `the interval to check the changes of the proto descriptor specified by --transcoding_proto_descriptor_url`)
 )
 
 // Config Manager handles service configuration fetching and updating.
@@ -68,8 +89,36 @@ type ConfigManager struct {
 	metadataFetcher         *metadata.MetadataFetcher
 	serviceConfigFetcher    *sc.ServiceConfigFetcher
 	rolloutIdChangeDetector *sc.RolloutIdChangeDetector
+	configMapFetcher        *sc.ConfigMapFetcher
+	descriptorFetcher       *sc.DescriptorFetcher
+	consumerQuotaFetcher    *sc.ConsumerQuotaFetcher
 
+	// mu serializes applying the service config from the change detectors.
+	mu               sync.Mutex
 	curServiceConfig *confpb.Service
+
+	// protoDescriptor replaces the proto descriptor in the service config if
+	// it is fetched from --transcoding_proto_descriptor_url.
+	protoDescriptor        []byte
+	protoDescriptorVersion string
+
+	// jwksReadiness and configFreshness are used by the readiness endpoint.
+	jwksReadiness   jwksReadiness
+	configFreshness configFreshness
+
+	// jwksResolver resolves the JWKS hosts if --jwks_resolve_at_startup is
+	// set. jwksResolveVersion is the number of the changes of the resolved
+	// addresses, used to version the snapshots.
+	jwksResolver       *jwksHostResolver
+	jwksResolveVersion int
+
+	// replayCount is the number of the replayed snapshots, used to version
+	// them.
+	replayCount int
+
+	// snapshotHash is the content hash of the served snapshot, to skip
+	// pushing identical snapshots.
+	snapshotHash string
 }
 
 // NewConfigManager creates new instance of Config Manager.
@@ -81,6 +130,50 @@ func NewConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGenerator
 	}
 	m.cache = cache.NewSnapshotCache(true, m, m)
 
+	// If an exported snapshot is replayed, serve it without fetching the
+	// service config.
+	if opts.SnapshotReplayFile != "" {
+		if err := m.replaySnapshotFile(opts.SnapshotReplayFile); err != nil {
+			return nil, fmt.Errorf("fail to replay the snapshot from %v, %v", opts.SnapshotReplayFile, err)
+		}
+
+		glog.Infof("create new Config Manager replaying the snapshot exported at %v", opts.SnapshotReplayFile)
+		return m, nil
+	}
+
+	if opts.TenantMappingMetadataAttribute != "" {
+		if mf == nil {
+			return nil, fmt.Errorf("flag --tenant_mapping_metadata_attribute requires the metadata server")
+		}
+		mapping, err := mf.FetchInstanceAttribute(opts.TenantMappingMetadataAttribute)
+		if err != nil {
+			return nil, fmt.Errorf("fail to fetch the tenant mapping from metadata attribute %q, %v", opts.TenantMappingMetadataAttribute, err)
+		}
+		m.envoyConfigOptions.TenantMapping = mapping
+	}
+
+	if opts.QuotaOverrideConsumers != "" {
+		client, err := httpsClient(opts)
+		if err != nil {
+			return nil, fmt.Errorf("fail to init httpsClient: %v", err)
+		}
+		m.consumerQuotaFetcher = sc.NewConsumerQuotaFetcher(client, opts.ServiceConsumerManagementURL, accessTokenFunc(mf, opts, options.ServiceManagementServiceAccountKey(opts)))
+	}
+
+	if opts.JwksResolveAtStartup {
+		if opts.JwksResolveTTL <= 0 {
+			return nil, fmt.Errorf("invalid flag --jwks_resolve_ttl %v, must be > 0", opts.JwksResolveTTL)
+		}
+		m.jwksResolver = newJwksHostResolver(opts.JwksResolveTTL, net.DefaultResolver.LookupHost)
+		m.setJwksResolveRefreshTimer(opts.JwksResolveTTL)
+	}
+
+	if *TranscodingProtoDescriptorURL != "" {
+		if err := m.fetchProtoDescriptor(*TranscodingProtoDescriptorURL, mf, opts); err != nil {
+			return nil, fmt.Errorf("fail to fetch the startup proto descriptor, %v", err)
+		}
+	}
+
 	// If service config is provided as a file, just use it and disable managed rollout
 	if *ServicePath != "" {
 		// Following flags will not be used
@@ -102,6 +195,16 @@ func NewConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGenerator
 		return m, nil
 	}
 
+	// If service config is provided by a Kubernetes ConfigMap, poll it and disable managed rollout
+	if *K8sConfigMap != "" {
+		if err := m.fetchAndPollConfigMap(*K8sConfigMap, opts); err != nil {
+			return nil, fmt.Errorf("fail to fetch and apply the startup service config from ConfigMap, %v", err)
+		}
+
+		glog.Infof("create new Config Manager from Kubernetes ConfigMap %v", *K8sConfigMap)
+		return m, nil
+	}
+
 	m.serviceName = *ServiceName
 	checkMetadata := *CheckMetadata
 	var err error
@@ -132,20 +235,14 @@ func NewConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGenerator
 	// accessToken is unavailable from imds and --service_account_key must be
 	// set to generate accessToken.
 	// The inverse is not true. We can still use IMDS on GCP when service account key is specified.
-	if mf == nil && opts.ServiceAccountKey == "" && !opts.EnableApplicationDefaultCredentials {
-		return nil, fmt.Errorf("if flag --non_gcp is specified, flag --service_account_key or --enable_application_default_credentials must be specified")
-	}
-
-	accessToken := func() (string, time.Duration, error) {
-		if opts.EnableApplicationDefaultCredentials {
-			return tokengenerator.GenerateApplicationDefaultCredentialsToken()
+	if mf == nil && !opts.EnableApplicationDefaultCredentials {
+		if options.ServiceManagementServiceAccountKey(opts) == "" || options.ServiceControlServiceAccountKey(opts) == "" {
+			return nil, fmt.Errorf("if flag --non_gcp is specified, flag --service_account_key or --enable_application_default_credentials must be specified")
 		}
-		if opts.ServiceAccountKey != "" {
-			return tokengenerator.GenerateAccessTokenFromFile(opts.ServiceAccountKey)
-		}
-		return mf.FetchAccessToken()
 	}
 
+	accessToken := accessTokenFunc(mf, opts, options.ServiceManagementServiceAccountKey(opts))
+
 	client, err := httpsClient(opts)
 	if err != nil {
 		return nil, fmt.Errorf("fail to init httpsClient: %v", err)
@@ -183,15 +280,22 @@ func NewConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGenerator
 	}
 
 	if rolloutStrategy == util.ManagedRolloutStrategy {
-		m.rolloutIdChangeDetector = sc.NewRolloutIdChangeDetector(client, opts.ServiceControlURL, m.serviceName, accessToken)
+		m.configFreshness.checked(nil, time.Now())
+		m.rolloutIdChangeDetector = sc.NewRolloutIdChangeDetector(client, opts.ServiceControlURL, m.serviceName, accessTokenFunc(mf, opts, options.ServiceControlServiceAccountKey(opts)))
+		m.rolloutIdChangeDetector.SetCheckResultCallback(func(err error) {
+			m.configFreshness.checked(err, time.Now())
+		})
 		m.rolloutIdChangeDetector.SetDetectRolloutIdChangeTimer(*checkNewRolloutInterval, func() {
 			latestConfigId, err := m.serviceConfigFetcher.LoadConfigIdFromRollouts()
 			if err != nil {
+				m.configFreshness.applied(err)
 				glog.Errorf("error occurred when getting configId by fetching rollout, %v", err)
 				return
 			}
 
-			if err = m.fetchAndApplyServiceConfig(latestConfigId); err != nil {
+			err = m.fetchAndApplyServiceConfig(latestConfigId)
+			m.configFreshness.applied(err)
+			if err != nil {
 				glog.Errorf("error occurred when fetching and applying new service config, %v", err)
 			}
 		})
@@ -202,9 +306,54 @@ func NewConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGenerator
 	return m, nil
 }
 
+// accessTokenFunc returns the function to get the access token, from the
+// service account key file if specified.
+func accessTokenFunc(mf *metadata.MetadataFetcher, opts options.ConfigGeneratorOptions, serviceAccountKey string) util.GetAccessTokenFunc {
+	return func() (string, time.Duration, error) {
+		if opts.EnableApplicationDefaultCredentials {
+			return tokengenerator.GenerateApplicationDefaultCredentialsToken()
+		}
+		if serviceAccountKey != "" {
+			return tokengenerator.GenerateAccessTokenFromFile(serviceAccountKey)
+		}
+		if mf == nil {
+			return "", 0, fmt.Errorf("flag --service_account_key or --enable_application_default_credentials must be specified on a non-gcp deployment")
+		}
+		return mf.FetchAccessToken()
+	}
+}
+
+// fetchProtoDescriptor fetches the proto descriptor used by gRPC-JSON
+// transcoding, and re-applies the current service config whenever it changes.
+func (m *ConfigManager) fetchProtoDescriptor(url string, mf *metadata.MetadataFetcher, opts options.ConfigGeneratorOptions) error {
+	client, err := httpsClient(opts)
+	if err != nil {
+		return fmt.Errorf("fail to init httpsClient: %v", err)
+	}
+
+	m.descriptorFetcher = sc.NewDescriptorFetcher(client, url, accessTokenFunc(mf, opts, options.ServiceManagementServiceAccountKey(opts)))
+	m.protoDescriptor, m.protoDescriptorVersion, err = m.descriptorFetcher.FetchDescriptor()
+	if err != nil {
+		return err
+	}
+
+	m.descriptorFetcher.SetDetectDescriptorChangeTimer(*TranscodingProtoDescriptorRefreshInterval, func(descriptor []byte, version string) {
+		m.mu.Lock()
+		m.protoDescriptor, m.protoDescriptorVersion = descriptor, version
+		serviceConfig := m.curServiceConfig
+		m.mu.Unlock()
+
+		glog.Infof("proto descriptor changed to version %v", version)
+		if err := m.applyServiceConfig(serviceConfig); err != nil {
+			glog.Errorf("error occurred when applying new proto descriptor, %v", err)
+		}
+	})
+	return nil
+}
+
 func (m *ConfigManager) fetchAndApplyServiceConfig(latestConfigId string) error {
-	if latestConfigId == m.curConfigId() {
-		glog.Infof("no new configuration to load for service %v, current configuration Id %v", m.serviceName, m.curConfigId())
+	if curConfigId := m.curConfigId(); latestConfigId == curConfigId {
+		glog.Infof("no new configuration to load for service %v, current configuration Id %v", m.serviceName, curConfigId)
 		return nil
 	}
 
@@ -231,13 +380,94 @@ func (m *ConfigManager) readAndApplyServiceConfig(servicePath string) error {
 	return m.applyServiceConfig(serviceConfig)
 }
 
+func (m *ConfigManager) fetchAndPollConfigMap(namespacedName string, opts options.ConfigGeneratorOptions) error {
+	parts := strings.Split(namespacedName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf(`invalid ConfigMap %q, must be in the format of "namespace/name"`, namespacedName)
+	}
+
+	client, err := httpsClientWithRootCerts(util.KubernetesServiceAccountCAPath, opts.HttpRequestTimeout)
+	if err != nil {
+		return fmt.Errorf("fail to init Kubernetes httpsClient: %v", err)
+	}
+
+	accessToken := func() (string, time.Duration, error) {
+		// Projected service account tokens are rotated by kubelet, so always
+		// read the latest one.
+		token, err := ioutil.ReadFile(util.KubernetesServiceAccountTokenPath)
+		if err != nil {
+			return "", 0, err
+		}
+		return strings.TrimSpace(string(token)), 0, nil
+	}
+
+	m.configMapFetcher = sc.NewConfigMapFetcher(client, *K8sAPIServerURL, parts[0], parts[1], *K8sConfigMapKey, accessToken)
+	serviceConfig, resourceVersion, err := m.configMapFetcher.FetchConfig()
+	if err != nil {
+		return err
+	}
+	if err := m.applyConfigMapServiceConfig(serviceConfig, resourceVersion); err != nil {
+		return err
+	}
+	m.configMapFetcher.SetAppliedResourceVersion(resourceVersion)
+
+	m.configMapFetcher.SetDetectConfigMapChangeTimer(*checkNewRolloutInterval, m.applyConfigMapServiceConfig)
+	return nil
+}
+
+func (m *ConfigManager) applyConfigMapServiceConfig(serviceConfig *confpb.Service, resourceVersion string) error {
+	// The config id is used as the snapshot version. Service configs managed
+	// in a ConfigMap usually have no id, so use the ConfigMap resource version
+	// to make sure Envoy picks up the changes.
+	if serviceConfig.Id == "" {
+		serviceConfig.Id = resourceVersion
+	}
+
+	m.mu.Lock()
+	m.serviceName = serviceConfig.GetName()
+	m.mu.Unlock()
+	return m.applyServiceConfig(serviceConfig)
+}
+
 func (m *ConfigManager) applyServiceConfig(serviceConfig *confpb.Service) error {
 	if serviceConfig == nil {
 		return fmt.Errorf("applid service config is empty")
 	}
 
+	// The quota overrides and the JWKS hosts are fetched before locking, so
+	// the readiness handler is not blocked by the network calls.
+	var quotaLimits []options.ConsumerQuotaLimit
+	if m.consumerQuotaFetcher != nil {
+		var err error
+		if quotaLimits, err = m.fetchConsumerQuotaLimits(); err != nil {
+			return err
+		}
+	}
+	var jwksAddresses map[string][]string
+	if m.jwksResolver != nil {
+		var err error
+		if jwksAddresses, err = m.resolveJwksHosts(serviceConfig); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	var err error
 	m.curServiceConfig = serviceConfig
+	if m.protoDescriptor != nil {
+		serviceConfig, err = replaceProtoDescriptor(serviceConfig, m.protoDescriptor)
+		if err != nil {
+			return fmt.Errorf("fail to replace proto descriptor, %s", err)
+		}
+	}
+	if m.consumerQuotaFetcher != nil {
+		m.envoyConfigOptions.ConsumerQuotaLimits = quotaLimits
+	}
+	if m.jwksResolver != nil {
+		m.envoyConfigOptions.JwksResolvedAddresses = jwksAddresses
+	}
 	m.serviceInfo, err = configinfo.NewServiceInfoFromServiceConfig(serviceConfig, m.envoyConfigOptions)
 	if err != nil {
 		return fmt.Errorf("fail to initialize ServiceInfo, %s", err)
@@ -256,16 +486,57 @@ func (m *ConfigManager) applyServiceConfig(serviceConfig *confpb.Service) error
 			// May still be empty if metadata fetch fails.
 			m.serviceInfo.Options.CommonOptions.TracingOptions.ProjectId = attrs.ProjectId
 		}
+
+		labels, err := mergeDeploymentLabels(m.metadataFetcher.FetchDeploymentLabels(), m.serviceInfo.Options.DeploymentLabels)
+		if err != nil {
+			return err
+		}
+		m.serviceInfo.Options.DeploymentLabels = util.FormatDeploymentLabels(labels)
 	}
 
-	snapshot, err := m.makeSnapshot()
+	snapshot, hash, err := m.makeSnapshot()
 	if err != nil {
 		return fmt.Errorf("fail to make a snapshot, %s", err)
 	}
-	return m.cache.SetSnapshot(context.Background(), m.envoyConfigOptions.Node, snapshot)
+	if hash == m.snapshotHash {
+		glog.Infof("skip pushing the snapshot for config %v, identical to the served one", m.curConfigIdLocked())
+		return nil
+	}
+	if err := m.cache.SetSnapshot(context.Background(), m.envoyConfigOptions.Node, snapshot); err != nil {
+		return err
+	}
+	m.snapshotHash = hash
+	m.jwksReadiness.requestRefresh()
+	return nil
+}
+
+// fetchConsumerQuotaLimits fetches the quota overrides of the consumers in
+// flag --quota_override_consumers, so they are refreshed with each service
+// config.
+func (m *ConfigManager) fetchConsumerQuotaLimits() ([]options.ConsumerQuotaLimit, error) {
+	m.mu.Lock()
+	serviceName := m.serviceName
+	m.mu.Unlock()
+
+	var limits []options.ConsumerQuotaLimit
+	for _, consumer := range strings.Split(m.envoyConfigOptions.QuotaOverrideConsumers, ",") {
+		consumer = strings.TrimSpace(consumer)
+		if consumer == "" {
+			continue
+		}
+
+		consumerLimits, err := m.consumerQuotaFetcher.FetchConsumerQuotaLimits(serviceName, consumer)
+		if err != nil {
+			return nil, fmt.Errorf("fail to fetch the quota overrides of consumer %s, %v", consumer, err)
+		}
+		limits = append(limits, consumerLimits...)
+	}
+	return limits, nil
 }
 
-func (m *ConfigManager) makeSnapshot() (*cache.Snapshot, error) {
+// makeSnapshot makes the snapshot from the service info, versioned by the
+// content hash of its resources, which is also returned.
+func (m *ConfigManager) makeSnapshot() (*cache.Snapshot, string, error) {
 	m.Infof("making configuration for api: %v", m.serviceInfo.Name)
 
 	var clusterResources, listenerResources []types.Resource
@@ -273,11 +544,11 @@ func (m *ConfigManager) makeSnapshot() (*cache.Snapshot, error) {
 	clusterGensFactories := gen.GetESPv2ClusterGenFactories()
 	gens, err := gen.NewClusterGeneratorsFromOPConfig(m.serviceInfo.ServiceConfig(), m.serviceInfo.Options, clusterGensFactories)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	clusters, err := gen.MakeClusters(gens)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	for i := range clusters {
 		clusterResources = append(clusterResources, clusters[i])
@@ -286,30 +557,126 @@ func (m *ConfigManager) makeSnapshot() (*cache.Snapshot, error) {
 	m.Infof("adding Listeners configuration for api: %v", m.serviceInfo.Name)
 	listeners, err := gen.MakeListeners(m.serviceInfo, m.scParams)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	for _, lis := range listeners {
 		listenerResources = append(listenerResources, lis)
 	}
 
-	snapshot, err := cache.NewSnapshot(m.curConfigId(), map[rsrc.Type][]types.Resource{
+	resources := map[rsrc.Type][]types.Resource{
 		rsrc.ListenerType: listenerResources,
 		rsrc.ClusterType:  clusterResources,
-	})
+	}
+	hash, err := snapshotHash(resources)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	snapshot, err := cache.NewSnapshot(fmt.Sprintf("%s-%.12s", m.snapshotVersion(), hash), resources)
+	if err != nil {
+		return nil, "", err
 	}
 	m.Infof("Envoy Dynamic Configuration is cached for service: %v", m.serviceName)
-	return snapshot, nil
+	return snapshot, hash, nil
 }
 
+// snapshotHash returns the hex SHA-256 of the resources, sorted by the types
+// and the names so it only changes with the content.
+//
+// The resources are hashed in JSON, as the deterministic binary marshaling
+// keeps the bytes of the Any fields, such as the typed_per_filter_config of
+// the routes, which are marshaled with the map entries in random order. The
+// JSON output is only stable within the process, which is all the hashes are
+// compared in.
+func snapshotHash(resources map[rsrc.Type][]types.Resource) (string, error) {
+	typeURLs := make([]string, 0, len(resources))
+	for typeURL := range resources {
+		typeURLs = append(typeURLs, typeURL)
+	}
+	sort.Strings(typeURLs)
+
+	h := sha256.New()
+	marshaler := protojson.MarshalOptions{}
+	for _, typeURL := range typeURLs {
+		sorted := append([]types.Resource{}, resources[typeURL]...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return cache.GetResourceName(sorted[i]) < cache.GetResourceName(sorted[j])
+		})
+
+		h.Write([]byte(typeURL))
+		for _, resource := range sorted {
+			b, err := marshaler.Marshal(resource)
+			if err != nil {
+				return "", fmt.Errorf("fail to marshal resource %q: %v", cache.GetResourceName(resource), err)
+			}
+			// Length prefixed, so the boundaries of the resources are hashed.
+			h.Write([]byte(fmt.Sprintf("%d:", len(b))))
+			h.Write(b)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// curConfigId returns the id of the current service config. It is called by
+// the readiness handler while the rollouts apply new service configs.
 func (m *ConfigManager) curConfigId() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.curConfigIdLocked()
+}
+
+// curConfigIdLocked is curConfigId with m.mu held.
+func (m *ConfigManager) curConfigIdLocked() string {
 	if m.curServiceConfig == nil {
 		return ""
 	}
 	return m.curServiceConfig.Id
 }
 
+// snapshotVersion is the config id, suffixed by the proto descriptor version
+// if it is fetched separately, and by the number of the changes of the
+// addresses resolved by --jwks_resolve_at_startup. The snapshots are further
+// suffixed by their content hash.
+func (m *ConfigManager) snapshotVersion() string {
+	version := m.curConfigIdLocked()
+	if m.protoDescriptorVersion != "" {
+		version = fmt.Sprintf("%s-%.12s", version, m.protoDescriptorVersion)
+	}
+	if m.jwksResolveVersion > 0 {
+		version = fmt.Sprintf("%s-jwks-%d", version, m.jwksResolveVersion)
+	}
+	return version
+}
+
+// replaceProtoDescriptor returns a copy of the service config with its proto
+// descriptor replaced.
+func replaceProtoDescriptor(serviceConfig *confpb.Service, descriptor []byte) (*confpb.Service, error) {
+	descriptorFile, err := anypb.New(&smpb.ConfigFile{
+		FilePath:     "api_descriptor.pb",
+		FileContents: descriptor,
+		FileType:     smpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig = proto.Clone(serviceConfig).(*confpb.Service)
+	if serviceConfig.SourceInfo == nil {
+		serviceConfig.SourceInfo = &confpb.SourceInfo{}
+	}
+
+	sourceFiles := []*anypb.Any{descriptorFile}
+	for _, sourceFile := range serviceConfig.SourceInfo.SourceFiles {
+		configFile := &smpb.ConfigFile{}
+		if err := sourceFile.UnmarshalTo(configFile); err == nil && configFile.GetFileType() == smpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO {
+			continue
+		}
+		sourceFiles = append(sourceFiles, sourceFile)
+	}
+	serviceConfig.SourceInfo.SourceFiles = sourceFiles
+	return serviceConfig, nil
+}
+
 func (m *ConfigManager) ID(node *corepb.Node) string {
 	return node.GetId()
 }
@@ -336,7 +703,20 @@ func (m *ConfigManager) Errorf(format string, args ...interface{}) { glog.Errorf
 func (m *ConfigManager) Cache() cache.Cache { return m.cache }
 
 func httpsClient(opts options.ConfigGeneratorOptions) (*http.Client, error) {
-	caCert, err := ioutil.ReadFile(opts.SslSidestreamClientRootCertsPath)
+	client, err := httpsClientWithRootCerts(opts.SslSidestreamClientRootCertsPath, opts.HttpRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.DisableHttpFetchCache {
+		// Service Management honors the ETags, so the unchanged service configs
+		// and rollouts are not transferred again.
+		client.Transport = util.NewConditionalCacheTransport(client.Transport)
+	}
+	return client, nil
+}
+
+func httpsClientWithRootCerts(rootCertsPath string, timeout time.Duration) (*http.Client, error) {
+	caCert, err := ioutil.ReadFile(rootCertsPath)
 	if err != nil {
 		return nil, err
 	}
@@ -348,6 +728,21 @@ func httpsClient(opts options.ConfigGeneratorOptions) (*http.Client, error) {
 				RootCAs: caCertPool,
 			},
 		},
-		Timeout: opts.HttpRequestTimeout,
+		Timeout: timeout,
 	}, nil
 }
+
+// mergeDeploymentLabels merges the labels detected from the platform with the
+// labels of flag --deployment_labels, which override the detected ones.
+func mergeDeploymentLabels(detected map[string]string, flagLabels string) (map[string]string, error) {
+	labels, err := util.ParseDeploymentLabels(flagLabels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flag --deployment_labels: %v", err)
+	}
+	for key, value := range detected {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
+	return labels, nil
+}
@@ -34,13 +34,17 @@ import (
 	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/google/go-cmp/cmp"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	servicecontrolpb "google.golang.org/genproto/googleapis/api/servicecontrol/v1"
 	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
+	apipb "google.golang.org/genproto/protobuf/api"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
@@ -132,7 +136,7 @@ func TestFetchListeners(t *testing.T) {
 				if err != nil {
 					t.Fatal(err)
 				}
-				if version != testdata.TestFetchListenersConfigID {
+				if trimSnapshotHash(version) != testdata.TestFetchListenersConfigID {
 					t.Fatalf("snapshot cache fetch got version: %v, want: %v", version, testdata.TestFetchListenersConfigID)
 				}
 				if !proto.Equal(resp.GetRequest(), req) {
@@ -375,7 +379,7 @@ func TestFixedModeDynamicRouting(t *testing.T) {
 			continue
 		}
 
-		if version != testdata.TestFetchListenersConfigID {
+		if trimSnapshotHash(version) != testdata.TestFetchListenersConfigID {
 			t.Errorf("Test Desc(%d): %s, snapshot cache fetch got version: %v, want: %v", i, tc.desc, version, testdata.TestFetchListenersConfigID)
 			continue
 		}
@@ -554,7 +558,7 @@ func TestServiceConfigAutoUpdate(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if version != oldConfigID {
+		if trimSnapshotHash(version) != oldConfigID {
 			t.Errorf("Test Desc: %s, snapshot cache fetch got version: %v, want: %v", tc.desc, version, oldConfigID)
 		}
 		if !proto.Equal(respInterface.GetRequest(), req) {
@@ -584,8 +588,10 @@ func TestServiceConfigAutoUpdate(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if version != newConfigID || configManager.curConfigId() != newConfigID {
-			t.Errorf("Test Desc: %s, snapshot cache fetch got version: %v, want: %v", tc.desc, version, newConfigID)
+		// The new config generates the same resources, so the served
+		// snapshot is kept.
+		if trimSnapshotHash(version) != oldConfigID || configManager.curConfigId() != newConfigID {
+			t.Errorf("Test Desc: %s, snapshot cache fetch got version: %v, config id: %v, want: %v, %v", tc.desc, version, configManager.curConfigId(), oldConfigID, newConfigID)
 		}
 
 		if !proto.Equal(respInterface.GetRequest(), req) {
@@ -712,3 +718,163 @@ func setFlags(service, serviceConfigId, rolloutStrategy, checkRolloutInterval, s
 	_ = flag.Set("check_rollout_interval", checkRolloutInterval)
 	_ = flag.Set("service_json_path", serviceJsonPath)
 }
+
+func TestMergeDeploymentLabels(t *testing.T) {
+	testData := []struct {
+		desc       string
+		detected   map[string]string
+		flagLabels string
+		wantLabels map[string]string
+		wantError  string
+	}{
+		{
+			desc:       "detected labels only",
+			detected:   map[string]string{"gce_instance": "instance-1"},
+			wantLabels: map[string]string{"gce_instance": "instance-1"},
+		},
+		{
+			desc:       "flag labels override the detected ones",
+			detected:   map[string]string{"gce_instance": "instance-1", "gke_pod": "pod-1"},
+			flagLabels: "gke_pod=pod-2;environment=prod",
+			wantLabels: map[string]string{"gce_instance": "instance-1", "gke_pod": "pod-2", "environment": "prod"},
+		},
+		{
+			desc:       "invalid flag labels",
+			flagLabels: "environment",
+			wantError:  "invalid flag --deployment_labels",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := mergeDeploymentLabels(tc.detected, tc.flagLabels)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("want error %q, got: %v", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantLabels, got); diff != "" {
+				t.Errorf("labels diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// trimSnapshotHash returns the version of the snapshot without the content
+// hash suffix.
+func trimSnapshotHash(version string) string {
+	if i := strings.LastIndex(version, "-"); i != -1 {
+		return version[:i]
+	}
+	return version
+}
+
+func TestApplyServiceConfigSkipsIdenticalSnapshots(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "http://127.0.0.1:8082"
+	opts.SkipServiceControlFilter = true
+	opts.TracingOptions = &options.TracingOptions{
+		DisableTracing: true,
+	}
+
+	m := &ConfigManager{
+		envoyConfigOptions: opts,
+	}
+	m.cache = cache.NewSnapshotCache(true, m, m)
+
+	serviceConfig := &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Id:   "2023-01-01r0",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "Echo",
+					},
+				},
+			},
+		},
+	}
+	servedVersion := func() string {
+		snapshot, err := m.cache.GetSnapshot(opts.Node)
+		if err != nil {
+			t.Fatalf("GetSnapshot() got error: %v", err)
+		}
+		return snapshot.GetVersion(resource.ClusterType)
+	}
+
+	if err := m.applyServiceConfig(serviceConfig); err != nil {
+		t.Fatalf("applyServiceConfig() got error: %v", err)
+	}
+	firstVersion := servedVersion()
+	if trimSnapshotHash(firstVersion) != "2023-01-01r0" || len(firstVersion) != len("2023-01-01r0-")+12 {
+		t.Fatalf("got version %q, want the config id suffixed by the content hash", firstVersion)
+	}
+
+	// The same content under a new config id is not pushed.
+	sameContent := proto.Clone(serviceConfig).(*confpb.Service)
+	sameContent.Id = "2023-01-02r0"
+	if err := m.applyServiceConfig(sameContent); err != nil {
+		t.Fatalf("applyServiceConfig() got error: %v", err)
+	}
+	if got := servedVersion(); got != firstVersion {
+		t.Errorf("identical snapshot got version %q, want the served version %q", got, firstVersion)
+	}
+
+	newContent := proto.Clone(sameContent).(*confpb.Service)
+	newContent.Id = "2023-01-03r0"
+	newContent.Apis[0].Methods = append(newContent.Apis[0].Methods, &apipb.Method{Name: "Simpleget"})
+	newContent.Http = &annotationspb.Http{
+		Rules: []*annotationspb.HttpRule{
+			{
+				Selector: "endpoints.examples.bookstore.Bookstore.Simpleget",
+				Pattern: &annotationspb.HttpRule_Get{
+					Get: "/simpleget",
+				},
+			},
+		},
+	}
+	if err := m.applyServiceConfig(newContent); err != nil {
+		t.Fatalf("applyServiceConfig() got error: %v", err)
+	}
+	if got := servedVersion(); trimSnapshotHash(got) != "2023-01-03r0" {
+		t.Errorf("changed snapshot got version %q, want config id %q", got, "2023-01-03r0")
+	}
+}
+
+func TestSnapshotHash(t *testing.T) {
+	clusterA := &clusterpb.Cluster{Name: "cluster-a"}
+	clusterB := &clusterpb.Cluster{Name: "cluster-b"}
+
+	hash, err := snapshotHash(map[resource.Type][]types.Resource{
+		resource.ClusterType: {clusterA, clusterB},
+	})
+	if err != nil {
+		t.Fatalf("snapshotHash() got error: %v", err)
+	}
+
+	reordered, err := snapshotHash(map[resource.Type][]types.Resource{
+		resource.ClusterType: {clusterB, clusterA},
+	})
+	if err != nil {
+		t.Fatalf("snapshotHash() got error: %v", err)
+	}
+	if reordered != hash {
+		t.Errorf("hash of reordered resources got %q, want %q", reordered, hash)
+	}
+
+	changed, err := snapshotHash(map[resource.Type][]types.Resource{
+		resource.ClusterType: {clusterA, &clusterpb.Cluster{Name: "cluster-c"}},
+	})
+	if err != nil {
+		t.Fatalf("snapshotHash() got error: %v", err)
+	}
+	if changed == hash {
+		t.Errorf("hash of changed resources got %q, want a different hash", changed)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+const (
+	// RoutesSummaryPath is the admin endpoint listing the served routes as a
+	// table of the operation, the match, the action and the filters.
+	RoutesSummaryPath = "/configmanager/routes"
+
+	// ClustersSummaryPath is the admin endpoint listing the served clusters.
+	ClustersSummaryPath = "/configmanager/clusters"
+)
+
+// WriteRoutesSummary writes the routes of the served snapshot as a table, in
+// the order Envoy matches them, so the operators can tell which route, if
+// any, a request is matched to.
+func (m *ConfigManager) WriteRoutesSummary(w io.Writer) error {
+	listeners, err := m.servedResources(rsrc.ListenerType)
+	if err != nil {
+		return err
+	}
+	return writeRoutesSummary(w, listeners)
+}
+
+// WriteClustersSummary writes the clusters of the served snapshot as a table.
+func (m *ConfigManager) WriteClustersSummary(w io.Writer) error {
+	clusters, err := m.servedResources(rsrc.ClusterType)
+	if err != nil {
+		return err
+	}
+	return writeClustersSummary(w, clusters)
+}
+
+// servedResources returns the resources of the served snapshot sorted by the
+// names.
+func (m *ConfigManager) servedResources(typeURL rsrc.Type) ([]types.Resource, error) {
+	snapshot, err := m.cache.GetSnapshot(m.envoyConfigOptions.Node)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot is not loaded: %v", err)
+	}
+
+	resourcesByName := snapshot.GetResources(typeURL)
+	var names []string
+	for name := range resourcesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var resources []types.Resource
+	for _, name := range names {
+		resources = append(resources, resourcesByName[name])
+	}
+	return resources, nil
+}
+
+func writeRoutesSummary(w io.Writer, listeners []types.Resource) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LISTENER\tVIRTUAL HOST\tOPERATION\tMETHOD\tPATH\tACTION\tFILTERS")
+	for _, resource := range listeners {
+		listener, ok := resource.(*listenerpb.Listener)
+		if !ok {
+			continue
+		}
+
+		hcms, err := httpConnectionManagers(listener)
+		if err != nil {
+			return err
+		}
+		for _, hcm := range hcms {
+			for _, host := range hcm.GetRouteConfig().GetVirtualHosts() {
+				for _, route := range host.GetRoutes() {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+						listener.GetName(),
+						host.GetName(),
+						orDash(route.GetName()),
+						orDash(routeMethod(route.GetMatch())),
+						routePath(route.GetMatch()),
+						routeAction(route),
+						orDash(strings.Join(routeFilters(route), ",")))
+				}
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// httpConnectionManagers returns the HTTP connection managers of the listener
+// with the routes inlined, skipping the other network filters.
+func httpConnectionManagers(listener *listenerpb.Listener) ([]*hcmpb.HttpConnectionManager, error) {
+	var hcms []*hcmpb.HttpConnectionManager
+	for _, chain := range listener.GetFilterChains() {
+		for _, filter := range chain.GetFilters() {
+			config := filter.GetTypedConfig()
+			if config == nil || !config.MessageIs(&hcmpb.HttpConnectionManager{}) {
+				continue
+			}
+			hcm := &hcmpb.HttpConnectionManager{}
+			if err := config.UnmarshalTo(hcm); err != nil {
+				return nil, fmt.Errorf("fail to unmarshal the HTTP connection manager of listener %q: %v", listener.GetName(), err)
+			}
+			hcms = append(hcms, hcm)
+		}
+	}
+	return hcms, nil
+}
+
+func routeMethod(match *routepb.RouteMatch) string {
+	for _, header := range match.GetHeaders() {
+		if header.GetName() == ":method" {
+			if exact := header.GetStringMatch().GetExact(); exact != "" {
+				return exact
+			}
+			return header.GetStringMatch().GetSafeRegex().GetRegex()
+		}
+	}
+	return ""
+}
+
+func routePath(match *routepb.RouteMatch) string {
+	switch specifier := match.GetPathSpecifier().(type) {
+	case *routepb.RouteMatch_Path:
+		return specifier.Path
+	case *routepb.RouteMatch_Prefix:
+		return specifier.Prefix + "*"
+	case *routepb.RouteMatch_SafeRegex:
+		return "regex " + specifier.SafeRegex.GetRegex()
+	case *routepb.RouteMatch_PathSeparatedPrefix:
+		return specifier.PathSeparatedPrefix + "/*"
+	default:
+		return "-"
+	}
+}
+
+func routeAction(route *routepb.Route) string {
+	switch action := route.GetAction().(type) {
+	case *routepb.Route_Route:
+		switch cluster := action.Route.GetClusterSpecifier().(type) {
+		case *routepb.RouteAction_Cluster:
+			return "cluster " + cluster.Cluster
+		case *routepb.RouteAction_ClusterHeader:
+			return "cluster from header " + cluster.ClusterHeader
+		case *routepb.RouteAction_WeightedClusters:
+			var names []string
+			for _, c := range cluster.WeightedClusters.GetClusters() {
+				names = append(names, fmt.Sprintf("%s:%d", c.GetName(), c.GetWeight().GetValue()))
+			}
+			return "clusters " + strings.Join(names, ",")
+		default:
+			return "route"
+		}
+	case *routepb.Route_DirectResponse:
+		return fmt.Sprintf("respond %d", action.DirectResponse.GetStatus())
+	case *routepb.Route_Redirect:
+		return "redirect"
+	default:
+		return "-"
+	}
+}
+
+// routeFilters returns the sorted names of the filters configured for the
+// route.
+func routeFilters(route *routepb.Route) []string {
+	var filters []string
+	for name := range route.GetTypedPerFilterConfig() {
+		filters = append(filters, name)
+	}
+	sort.Strings(filters)
+	return filters
+}
+
+func writeClustersSummary(w io.Writer, clusters []types.Resource) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tTYPE\tENDPOINTS\tTLS\tCONNECT TIMEOUT")
+	for _, resource := range clusters {
+		cluster, ok := resource.(*clusterpb.Cluster)
+		if !ok {
+			continue
+		}
+
+		tls := "no"
+		if cluster.GetTransportSocket() != nil || len(cluster.GetTransportSocketMatches()) > 0 {
+			tls = "yes"
+		}
+		timeout := "-"
+		if cluster.GetConnectTimeout() != nil {
+			timeout = cluster.GetConnectTimeout().AsDuration().String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			cluster.GetName(),
+			clusterType(cluster),
+			orDash(strings.Join(clusterEndpoints(cluster), ",")),
+			tls,
+			timeout)
+	}
+	return tw.Flush()
+}
+
+func clusterType(cluster *clusterpb.Cluster) string {
+	if cluster.GetClusterType() != nil {
+		return cluster.GetClusterType().GetName()
+	}
+	return cluster.GetType().String()
+}
+
+func clusterEndpoints(cluster *clusterpb.Cluster) []string {
+	var endpoints []string
+	for _, localityEndpoints := range cluster.GetLoadAssignment().GetEndpoints() {
+		for _, lbEndpoint := range localityEndpoints.GetLbEndpoints() {
+			address := lbEndpoint.GetEndpoint().GetAddress()
+			if pipe := address.GetPipe(); pipe != nil {
+				endpoints = append(endpoints, "unix:"+pipe.GetPath())
+				continue
+			}
+			socket := address.GetSocketAddress()
+			endpoints = append(endpoints, net.JoinHostPort(socket.GetAddress(), strconv.Itoa(int(socket.GetPortValue()))))
+		}
+	}
+	return endpoints
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func TestSnapshotAdminHandler_Summaries(t *testing.T) {
+	hcm, err := anypb.New(&hcmpb.HttpConnectionManager{
+		RouteSpecifier: &hcmpb.HttpConnectionManager_RouteConfig{
+			RouteConfig: &routepb.RouteConfiguration{
+				VirtualHosts: []*routepb.VirtualHost{
+					{
+						Name:    "backend",
+						Domains: []string{"*"},
+						Routes: []*routepb.Route{
+							{
+								Name: "bookstore.Bookstore.GetShelf",
+								Match: &routepb.RouteMatch{
+									PathSpecifier: &routepb.RouteMatch_SafeRegex{
+										SafeRegex: &matcherpb.RegexMatcher{
+											Regex: `^/shelves/[^\/]+\/?$`,
+										},
+									},
+									Headers: []*routepb.HeaderMatcher{
+										{
+											Name: ":method",
+											HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+												StringMatch: &matcherpb.StringMatcher{
+													MatchPattern: &matcherpb.StringMatcher_Exact{
+														Exact: "GET",
+													},
+												},
+											},
+										},
+									},
+								},
+								Action: &routepb.Route_Route{
+									Route: &routepb.RouteAction{
+										ClusterSpecifier: &routepb.RouteAction_Cluster{
+											Cluster: "backend-cluster-bookstore_local",
+										},
+									},
+								},
+								TypedPerFilterConfig: map[string]*anypb.Any{
+									"com.google.espv2.filters.http.service_control": {},
+									"com.google.espv2.filters.http.path_rewrite":    {},
+								},
+							},
+							{
+								Match: &routepb.RouteMatch{
+									PathSpecifier: &routepb.RouteMatch_Prefix{
+										Prefix: "/",
+									},
+								},
+								Action: &routepb.Route_DirectResponse{
+									DirectResponse: &routepb.DirectResponseAction{
+										Status: http.StatusNotFound,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The other network filters are skipped.
+	other, err := anypb.New(&emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := &listenerpb.Listener{
+		Name: "ingress_listener",
+		FilterChains: []*listenerpb.FilterChain{
+			{
+				Filters: []*listenerpb.Filter{
+					{
+						Name:       "other",
+						ConfigType: &listenerpb.Filter_TypedConfig{TypedConfig: other},
+					},
+					{
+						Name:       "envoy.filters.network.http_connection_manager",
+						ConfigType: &listenerpb.Filter_TypedConfig{TypedConfig: hcm},
+					},
+				},
+			},
+		},
+	}
+	cluster := &clusterpb.Cluster{
+		Name:                 "backend-cluster-bookstore_local",
+		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STATIC},
+		ConnectTimeout:       durationpb.New(20 * time.Second),
+		LoadAssignment:       util.CreateLoadAssignment("127.0.0.1", 8082),
+	}
+
+	m := newSnapshotAdminTestConfigManager(t, options.DefaultConfigGeneratorOptions())
+
+	// The summaries fail until a snapshot is served.
+	resp := httptest.NewRecorder()
+	m.MakeSnapshotAdminHandler().ServeHTTP(resp, httptest.NewRequest("GET", RoutesSummaryPath, nil))
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("routes summary without snapshot got status code %v, want 500", resp.Code)
+	}
+
+	snapshot, err := cache.NewSnapshot("test-config-id", map[rsrc.Type][]types.Resource{
+		rsrc.ListenerType: {listener},
+		rsrc.ClusterType:  {cluster},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.cache.SetSnapshot(context.Background(), "test-node", snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path     string
+		wantBody string
+	}{
+		{
+			path: RoutesSummaryPath,
+			wantBody: `LISTENER          VIRTUAL HOST  OPERATION                     METHOD  PATH                        ACTION                                   FILTERS
+ingress_listener  backend       bookstore.Bookstore.GetShelf  GET     regex ^/shelves/[^\/]+\/?$  cluster backend-cluster-bookstore_local  com.google.espv2.filters.http.path_rewrite,com.google.espv2.filters.http.service_control
+ingress_listener  backend       -                             -       /*                          respond 404                              -
+`,
+		},
+		{
+			path: ClustersSummaryPath,
+			wantBody: `CLUSTER                          TYPE    ENDPOINTS       TLS  CONNECT TIMEOUT
+backend-cluster-bookstore_local  STATIC  127.0.0.1:8082  no   20s
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			m.MakeSnapshotAdminHandler().ServeHTTP(resp, httptest.NewRequest("GET", tc.path, nil))
+			if resp.Code != http.StatusOK {
+				t.Fatalf("got status code %v, want 200: %s", resp.Code, resp.Body.String())
+			}
+			if diff := cmp.Diff(tc.wantBody, resp.Body.String()); diff != "" {
+				t.Errorf("summary diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
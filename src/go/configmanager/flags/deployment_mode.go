@@ -0,0 +1,121 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flags
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+// The deployment modes of flag --deployment_mode.
+const (
+	// DeploymentModeEdge is ESPv2 accepting the connections of the clients
+	// directly, without any proxy in front of it.
+	DeploymentModeEdge = "edge"
+
+	// DeploymentModeSidecar is ESPv2 running next to the backend, which is
+	// reached on the loopback address, behind a load balancer.
+	DeploymentModeSidecar = "sidecar"
+
+	// DeploymentModeGateway is ESPv2 running standalone in front of the
+	// remote backends, behind a load balancer.
+	DeploymentModeGateway = "gateway"
+)
+
+// deploymentModeDefault is the default of an option selected by a deployment
+// mode, by the flag of the option.
+type deploymentModeDefault struct {
+	flag  string
+	apply func(opts *options.ConfigGeneratorOptions)
+}
+
+// deploymentModeDefaults are the defaults of the options of each deployment
+// mode. CORS is not enabled by any mode, since its origins are specific to
+// each API.
+var deploymentModeDefaults = map[string][]deploymentModeDefault{
+	DeploymentModeEdge: {
+		// The x-forwarded-for header is set by the clients, so it is not
+		// trusted.
+		{"envoy_use_remote_address", func(opts *options.ConfigGeneratorOptions) { opts.EnvoyUseRemoteAddress = true }},
+		{"envoy_xff_num_trusted_hops", func(opts *options.ConfigGeneratorOptions) { opts.EnvoyXffNumTrustedHops = 0 }},
+		{"ssl_minimum_protocol", func(opts *options.ConfigGeneratorOptions) { opts.SslMinimumProtocol = "TLSv1.2" }},
+		// The idle connections of the clients are not kept for the default hour.
+		{"downstream_idle_timeout", func(opts *options.ConfigGeneratorOptions) { opts.DownstreamIdleTimeout = 5 * time.Minute }},
+		{"healthz", func(opts *options.ConfigGeneratorOptions) { opts.Healthz = "healthz" }},
+	},
+	DeploymentModeSidecar: {
+		// The backend on the loopback address accepts the connections at once
+		// unless it is down.
+		{"backend_cluster_connect_timeout", func(opts *options.ConfigGeneratorOptions) { opts.BackendClusterConnectTimeout = time.Second }},
+		{"healthz", func(opts *options.ConfigGeneratorOptions) { opts.Healthz = "healthz" }},
+	},
+	DeploymentModeGateway: {
+		{"backend_cluster_connect_timeout", func(opts *options.ConfigGeneratorOptions) { opts.BackendClusterConnectTimeout = 5 * time.Second }},
+		{"healthz", func(opts *options.ConfigGeneratorOptions) { opts.Healthz = "healthz" }},
+	},
+}
+
+// applyDeploymentMode sets the options not set by their flags to the defaults
+// of the deployment mode, and validates the options incompatible with it.
+// setFlags are the names of the flags set in the command line.
+func applyDeploymentMode(opts *options.ConfigGeneratorOptions, setFlags map[string]bool) error {
+	if opts.DeploymentMode == "" {
+		return nil
+	}
+	defaults, ok := deploymentModeDefaults[opts.DeploymentMode]
+	if !ok {
+		return fmt.Errorf("invalid flag --deployment_mode %q, must be one of %q, %q or %q", opts.DeploymentMode, DeploymentModeEdge, DeploymentModeSidecar, DeploymentModeGateway)
+	}
+
+	for _, d := range defaults {
+		if !setFlags[d.flag] {
+			d.apply(opts)
+		}
+	}
+
+	switch opts.DeploymentMode {
+	case DeploymentModeEdge:
+		if opts.SslServerCertPath == "" {
+			return fmt.Errorf("flag --deployment_mode=edge requires flag --ssl_server_cert_path, since the clients connect to ESPv2 directly")
+		}
+		if opts.EnvoyXffNumTrustedHops != 0 {
+			return fmt.Errorf("flag --envoy_xff_num_trusted_hops cannot be used with --deployment_mode=edge, since there is no trusted proxy in front of ESPv2")
+		}
+	case DeploymentModeSidecar:
+		if !isLoopbackBackend(opts.BackendAddress) {
+			return fmt.Errorf("flag --deployment_mode=sidecar requires flag --backend_address on the loopback address or a unix socket, got %q", opts.BackendAddress)
+		}
+	}
+	return nil
+}
+
+func isLoopbackBackend(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "unix" {
+		return true
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
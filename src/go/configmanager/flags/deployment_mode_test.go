@@ -0,0 +1,174 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flags
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeploymentModeDefaultsFlags(t *testing.T) {
+	for mode, defaults := range deploymentModeDefaults {
+		for _, d := range defaults {
+			if flag.Lookup(d.flag) == nil {
+				t.Errorf("deployment mode %q sets the default of unknown flag --%s", mode, d.flag)
+			}
+		}
+	}
+}
+
+func TestApplyDeploymentMode(t *testing.T) {
+	testData := []struct {
+		desc     string
+		optsIn   func(opts *options.ConfigGeneratorOptions)
+		setFlags map[string]bool
+		wantOpts func(opts *options.ConfigGeneratorOptions)
+	}{
+		{
+			desc:     "No deployment mode",
+			optsIn:   func(opts *options.ConfigGeneratorOptions) {},
+			wantOpts: func(opts *options.ConfigGeneratorOptions) {},
+		},
+		{
+			desc: "Edge",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeEdge
+				opts.SslServerCertPath = "/etc/espv2/tls"
+			},
+			setFlags: map[string]bool{
+				"ssl_server_cert_path": true,
+			},
+			wantOpts: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeEdge
+				opts.SslServerCertPath = "/etc/espv2/tls"
+				opts.EnvoyUseRemoteAddress = true
+				opts.EnvoyXffNumTrustedHops = 0
+				opts.SslMinimumProtocol = "TLSv1.2"
+				opts.DownstreamIdleTimeout = 5 * time.Minute
+				opts.Healthz = "healthz"
+			},
+		},
+		{
+			desc: "Sidecar keeps the flags set explicitly",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeSidecar
+				opts.BackendAddress = "grpc://localhost:9000"
+				opts.Healthz = "/ready"
+			},
+			setFlags: map[string]bool{
+				"backend_address": true,
+				"healthz":         true,
+			},
+			wantOpts: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeSidecar
+				opts.BackendAddress = "grpc://localhost:9000"
+				opts.Healthz = "/ready"
+				opts.BackendClusterConnectTimeout = time.Second
+			},
+		},
+		{
+			desc: "Gateway",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeGateway
+			},
+			wantOpts: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeGateway
+				opts.BackendClusterConnectTimeout = 5 * time.Second
+				opts.Healthz = "healthz"
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			tc.optsIn(&opts)
+			wantOpts := options.DefaultConfigGeneratorOptions()
+			tc.wantOpts(&wantOpts)
+
+			if err := applyDeploymentMode(&opts, tc.setFlags); err != nil {
+				t.Fatalf("applyDeploymentMode() got error: %v", err)
+			}
+			if diff := cmp.Diff(wantOpts, opts); diff != "" {
+				t.Errorf("applyDeploymentMode() got unexpected options, diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyDeploymentMode_BadInput(t *testing.T) {
+	testData := []struct {
+		desc      string
+		optsIn    func(opts *options.ConfigGeneratorOptions)
+		setFlags  map[string]bool
+		wantError string
+	}{
+		{
+			desc: "Unknown deployment mode",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = "cluster"
+			},
+			wantError: `invalid flag --deployment_mode "cluster", must be one of "edge", "sidecar" or "gateway"`,
+		},
+		{
+			desc: "Edge requires TLS",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeEdge
+			},
+			wantError: "flag --deployment_mode=edge requires flag --ssl_server_cert_path",
+		},
+		{
+			desc: "Edge trusts no proxy",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeEdge
+				opts.SslServerCertPath = "/etc/espv2/tls"
+				opts.EnvoyXffNumTrustedHops = 1
+			},
+			setFlags: map[string]bool{
+				"ssl_server_cert_path":       true,
+				"envoy_xff_num_trusted_hops": true,
+			},
+			wantError: "flag --envoy_xff_num_trusted_hops cannot be used with --deployment_mode=edge",
+		},
+		{
+			desc: "Sidecar requires a loopback backend",
+			optsIn: func(opts *options.ConfigGeneratorOptions) {
+				opts.DeploymentMode = DeploymentModeSidecar
+				opts.BackendAddress = "https://backend.example.com"
+			},
+			setFlags: map[string]bool{
+				"backend_address": true,
+			},
+			wantError: `flag --deployment_mode=sidecar requires flag --backend_address on the loopback address or a unix socket, got "https://backend.example.com"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			tc.optsIn(&opts)
+
+			err := applyDeploymentMode(&opts, tc.setFlags)
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("applyDeploymentMode() got error %v, want error containing %q", err, tc.wantError)
+			}
+		})
+	}
+}
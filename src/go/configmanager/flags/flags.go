@@ -29,23 +29,99 @@ var (
 	// These flags are kept in sync with options.ConfigGeneratorOptions.
 	defaults = options.DefaultConfigGeneratorOptions()
 
+	DeploymentMode = flag.String("deployment_mode", defaults.DeploymentMode, `Select the defaults of the flags for the deployment, one of "edge", "sidecar" or "gateway".
+                      "edge" is for ESPv2 accepting the connections of the clients directly. It trusts no x-forwarded-for
+                      header, requires TLS 1.2 and --ssl_server_cert_path, and closes the idle client connections in 5m.
+                      "sidecar" is for ESPv2 next to the backend behind a load balancer. It requires --backend_address
+                      on the loopback address and connects to it with a timeout of 1s. "gateway" is for ESPv2 in front
+                      of the remote backends behind a load balancer, and connects to them with a timeout of 5s.
+                      All the modes serve the health checks at "/healthz". The flags set explicitly are not changed.`)
+
 	// Cors related configurations.
 	CorsAllowCredentials   = flag.Bool("cors_allow_credentials", defaults.CorsAllowCredentials, "whether include the Access-Control-Allow-Credentials header with the value true in responses or not")
 	CorsAllowHeaders       = flag.String("cors_allow_headers", defaults.CorsAllowHeaders, "set Access-Control-Allow-Headers to the specified HTTP headers")
 	CorsAllowMethods       = flag.String("cors_allow_methods", defaults.CorsAllowMethods, "set Access-Control-Allow-Methods to the specified HTTP methods")
-	CorsAllowOrigin        = flag.String("cors_allow_origin", defaults.CorsAllowOrigin, "set Access-Control-Allow-Origin to a specific origin")
+	CorsAllowOrigin        = flag.String("cors_allow_origin", defaults.CorsAllowOrigin, "set Access-Control-Allow-Origin to a specific origin, or to one of the comma separated origins")
 	CorsAllowOriginRegex   = flag.String("cors_allow_origin_regex", defaults.CorsAllowOriginRegex, "set Access-Control-Allow-Origin to a regular expression")
 	CorsExposeHeaders      = flag.String("cors_expose_headers", defaults.CorsExposeHeaders, "set Access-Control-Expose-Headers to the specified headers")
 	CorsMaxAge             = flag.Duration("cors_max_age", defaults.CorsMaxAge, "set Access-Control-Max-Age response header for CORS preflight request.")
 	CorsPreset             = flag.String("cors_preset", defaults.CorsPreset, `enable CORS support, must be either "basic" or "cors_with_regex"`)
 	CorsOperationDelimiter = flag.String("cors_operation_delimiter", defaults.CorsOperationDelimiter, "Delimiter for cors operations")
 
+	CorsAllowUnmatchedPreflight = flag.Bool("cors_allow_unmatched_preflight", defaults.CorsAllowUnmatchedPreflight,
+		`When allow_cors is set in the service config endpoints, the backend handles CORS and the preflight requests for the paths
+                      not in the service config are rejected with 404. If true, ESPv2 answers these preflight requests with the policy of the
+                      --cors_* flags, --cors_preset defaults to "basic". The other CORS requests are still handled by the backend.`)
+
+	OperationCorsAllowOrigin = flag.String("operation_cors_allow_origin", defaults.OperationCorsAllowOrigin,
+		`Per-operation allowed CORS origins, in the format of "selector1=origin1,origin2;selector2=*". The origins are matched exactly,
+                      replacing the origins of --cors_allow_origin or --cors_allow_origin_regex for the operation. Requires --cors_preset.`)
+	OperationCorsAllowCredentials = flag.String("operation_cors_allow_credentials", defaults.OperationCorsAllowCredentials,
+		`Per-operation override of --cors_allow_credentials, in the format of "selector1=true;selector2=false". Requires --cors_preset.`)
+	OperationCorsExposeHeaders = flag.String("operation_cors_expose_headers", defaults.OperationCorsExposeHeaders,
+		`Per-operation override of --cors_expose_headers, in the format of "selector1=Header-A,Header-B;selector2=Header-C". Requires --cors_preset.`)
+
 	// Backend routing configurations.
 	BackendDnsLookupFamily = flag.String("backend_dns_lookup_family", defaults.BackendDnsLookupFamily, `Define the dns lookup family for all backends. The options are "auto", "v4only", "v6only", "v4preferred" and "all". The default is "v4preferred". "auto" is a legacy name, it behaves as "v6preferred".`)
 
+	// Deny-by-default routing configurations.
+	StrictRouteAllowlist = flag.Bool("strict_route_allowlist", defaults.StrictRouteAllowlist, `Only route the requests matching the paths of the operations defined in the service config.
+                      The catch-all operations, such as the ones of the OpenAPI "x-google-allow: all", are not routed,
+                      so the unmatched requests are rejected with 404 instead of proxied to the backend.`)
+	PassthroughPathPrefixes = flag.String("passthrough_path_prefixes", defaults.PassthroughPathPrefixes, `Comma separated path prefixes, such as "/static/,/assets/", proxied to the backend
+                      without ESPv2 filters although they match no operation. Requires --strict_route_allowlist.`)
+
+	DefaultBackendAddress = flag.String("default_backend_address", defaults.DefaultBackendAddress, `The backend URI, such as "https://legacy.example.com", receiving the requests matching no operation
+                      instead of rejecting them with 404. They are reported to service control under the operation
+                      "espv2_deployment.ESPv2_Autogenerated_DefaultBackend". Cannot be used with --strict_route_allowlist.`)
+
+	TenantMappingFile = flag.String("tenant_mapping_file", defaults.TenantMappingFile, `Path of the JSON file mapping the API consumers to the tenants with their own backends, such as
+                      {"jwtClaim": "tenant", "tenants": [{"name": "acme", "backendAddress": "https://acme.example.com",
+                      "consumerNumbers": ["123456"], "jwtClaimValues": ["acme"]}]}. The requests of the operations served by
+                      the local backend are routed to the backend of the tenant, identified by the consumer number of the
+                      API key validated by service control or by the claim of the verified JWT.`)
+	TenantMappingMetadataAttribute = flag.String("tenant_mapping_metadata_attribute", defaults.TenantMappingMetadataAttribute, `Instance metadata attribute holding the tenant mapping JSON in the format of --tenant_mapping_file.
+                      It is fetched from the metadata server on startup.`)
+	ConsumerBackends = flag.String("consumer_backends", defaults.ConsumerBackends, `JSON array of the dedicated backend pools of the named API consumers, such as
+                      [{"name": "bigcorp", "backendAddress": "https://bigcorp.example.com", "consumerNumbers": ["123456"]}].
+                      The requests of the operations served by the backends in the service config are routed to the
+                      pool of the consumer number of the API key validated by service control.`)
+	VersionBackends = flag.String("version_backends", defaults.VersionBackends, `JSON object mapping the values of the API version request header to the backends serving the versions, such as
+                      {"header": "Accept-Version", "versions": [{"version": "v2", "backendAddress": "https://v2.example.com"}]}.
+                      The requests of the operations served by the backends in the service config are routed to the
+                      backend of the version in the header, or to the backend in the service config if the version is
+                      not listed. The header defaults to "Accept-Version".`)
+
 	// Envoy specific configurations.
 	ClusterConnectTimeout = flag.Duration("cluster_connect_timeout", defaults.ClusterConnectTimeout, "cluster connect timeout in seconds")
 
+	// Backend connection configurations.
+	BackendClusterConnectTimeout = flag.Duration("backend_cluster_connect_timeout", defaults.BackendClusterConnectTimeout, `The connect timeout of the backend clusters.
+                      If not set, --cluster_connect_timeout is used.`)
+	BackendTcpKeepaliveTime = flag.Duration("backend_tcp_keepalive_time", defaults.BackendTcpKeepaliveTime, `Idle time of the backend connections before the TCP keepalive probes are sent,
+                      in whole seconds. If none of the --backend_tcp_keepalive flags is set, TCP keepalive is not enabled.`)
+	BackendTcpKeepaliveInterval = flag.Duration("backend_tcp_keepalive_interval", defaults.BackendTcpKeepaliveInterval, `Interval between the TCP keepalive probes of the backend connections, in whole seconds.
+                      If not set, the OS default is used.`)
+	BackendTcpKeepaliveProbes = flag.Int("backend_tcp_keepalive_probes", defaults.BackendTcpKeepaliveProbes, `Maximum number of unanswered TCP keepalive probes before the backend connections are dropped.
+                      If not set, the OS default is used.`)
+	BackendHappyEyeballs = flag.Bool("backend_happy_eyeballs", defaults.BackendHappyEyeballs, `Resolve both the IPv4 and IPv6 addresses of the backends, and race the connection attempts with
+                      the Happy Eyeballs algorithm. Cannot be used with --backend_dns_lookup_family other than "v4preferred" or "all".`)
+
+	BackendHttp2KeepaliveInterval = flag.Duration("backend_http2_keepalive_interval", defaults.BackendHttp2KeepaliveInterval, `Interval between the HTTP/2 PINGs sent on the idle connections of the gRPC and HTTP/2 backends,
+                      so the connections silently dropped by the load balancers in between are detected before use.
+                      If not set, the default is 30s.`)
+	BackendHttp2KeepaliveTimeout = flag.Duration("backend_http2_keepalive_timeout", defaults.BackendHttp2KeepaliveTimeout, `Time to wait for the HTTP/2 PING response of the gRPC and HTTP/2 backends before the
+                      connections are closed. If not set, the default is 10s.`)
+	BackendHttp2KeepaliveIntervalJitter = flag.Float64("backend_http2_keepalive_interval_jitter", defaults.BackendHttp2KeepaliveIntervalJitter, `Percent of --backend_http2_keepalive_interval added as random jitter to each interval, in the
+                      range of [0, 100]. If not set, the Envoy default of 15 is used.`)
+
+	BackendProtocolSelection = flag.String("backend_protocol_selection", defaults.BackendProtocolSelection, `Choose the protocol of the HTTP backends serving both HTTP/1.1 and HTTP/2 (h2c) on one port,
+                      such as "backend.example.com:443=auto;127.0.0.1:8082=downstream", keyed by the "hostname:port"
+                      of the backend addresses. The keys may contain wildcards and the first match wins.
+                      "downstream" uses the protocol of the client connection, and "auto" chooses HTTP/2 or HTTP/1.1
+                      by the TLS ALPN negotiation, only for the https backends. The gRPC backends always
+                      use HTTP/2. If not set, the protocol of the backend address is used.`)
+
 	// Network related configurations.
 	BackendAddress               = flag.String("backend_address", defaults.BackendAddress, `The application server URI to which ESPv2 proxies requests.`)
 	ListenerAddress              = flag.String("listener_address", defaults.ListenerAddress, "listener socket ip address")
@@ -53,9 +129,43 @@ var (
 	ServiceControlURL            = flag.String("service_control_url", defaults.ServiceControlURL, "url of service control server")
 	EnableBackendAddressOverride = flag.Bool("enable_backend_address_override", defaults.EnableBackendAddressOverride, "Allow the --backend flag to override the backend.rule.address for all operations.")
 
+	ServiceConsumerManagementURL = flag.String("service_consumer_management_url", defaults.ServiceConsumerManagementURL, "url of service consumer management server")
+	QuotaOverrideConsumers       = flag.String("quota_override_consumers", defaults.QuotaOverrideConsumers, `Comma separated project numbers of the API consumers, whose quota overrides
+	are fetched from Service Consumer Management with each service config and enforced at the edge as local rate limits of the
+	requests to the operations consuming the quota metrics, such as "123456789,987654321". Only the rate quotas overridden
+	by the service producer without dimensions are enforced.`)
+
+	BackendAddressOverrides = flag.String("backend_address_overrides", defaults.BackendAddressOverrides, `Override the x-google-backend addresses of the operations, in the format of "selector1=address1;selector2=address2",
+                      so the same service config can be deployed to environments with different backends. The selector may contain "*" wildcards,
+                      the first matching selector applies. Unlike --enable_backend_address_override, the operations keep their remote backends.`)
+
+	BackendRuleInheritance = flag.Bool("backend_rule_inheritance", defaults.BackendRuleInheritance, `Inherit the x-google-backend settings of the backend rules with wildcard selectors, so they are not duplicated for every operation.
+                      The backend rule of an operation is merged from the rules matching it: the rule of "*", then the rule of its API such as "pkg.Api.*",
+                      then the other wildcard rules such as the operations under a path, then its own rule. The fields set by a more specific rule,
+                      such as the deadline and the jwt_audience, override the ones of the less specific rules. The matching rules of the same level
+                      setting different values are rejected as conflicts.`)
+
+	BackendFailoverAddresses = flag.String("backend_failover_addresses", defaults.BackendFailoverAddresses, `Failover backends of the x-google-backend addresses, in the format of "primary_address1=failover_address1;primary_address2=failover_address2".
+                      The failover backend is added to the cluster of the primary backend as a lower priority level, and only receives requests
+                      once all the hosts of the primary backend are ejected as unhealthy, e.g. during a regional Cloud Run outage.
+                      The failover backend must use the same scheme as the primary one and accept the same backend auth audience.`)
+
+	BackendRegionalAddresses = flag.String("backend_regional_addresses", defaults.BackendRegionalAddresses, `Regional backends of the x-google-backend addresses, in the format of "address1=near_address1,far_address1;address2=near_address2,far_address2".
+                      The backend address is served by an aggregate cluster over the clusters of the near and far regional backends. The traffic prefers
+                      the near region, and spills over to the far region once the hosts of the near region are ejected for failing, e.g. under load.
+                      The regional backends must use the same scheme as the backend address and accept the same backend auth audience.`)
+
 	ListenerPort = flag.Int("listener_port", defaults.ListenerPort, "listener port")
 	Healthz      = flag.String("healthz", defaults.Healthz, "path for health check of ESPv2 proxy itself")
 
+	HealthPort = flag.Int("health_port", defaults.HealthPort, `If not 0, ESPv2 also listens for plain HTTP on this port, only serving the health check of "--healthz",
+                      so the health checks of the internal load balancers neither require API keys or JWTs nor are reported to
+                      Service Control. The other requests to this port are rejected with 404. Default is 0, which disables the port.`)
+	TcpProxyRoutes = flag.String("tcp_proxy_routes", defaults.TcpProxyRoutes, `Tunnel the non-HTTP traffic, such as MQTT, through ESPv2 with a TCP proxy listener per port, in the format of
+                      "PORT[/SERVER_NAME]=HOST:PORT;...", such as "1883=mqtt-broker:1883;8883/mqtt.example.com=mqtt-tls-broker:8883".
+                      With a server name, only the TLS connections of the SNI are routed, without terminating TLS. The server
+                      name may start with a "*." wildcard. The ports must be different from the ports of the HTTP listeners.`)
+
 	// Health check grpc backend related flags.
 	HealthCheckOperation                    = flag.String("health_check_operation", defaults.HealthCheckOperation, `Specify the health check operation name.`)
 	HealthCheckAutogeneratedOperationPrefix = flag.String("health_check_autogenerated_operation_prefix", defaults.HealthCheckAutogeneratedOperationPrefix, `Specify the health check autogenerated operation prefix.`)
@@ -66,6 +176,69 @@ var (
                       It only applies when the flag "--health_check_grpc_backend" is used.`)
 	HealthCheckGrpcBackendNoTrafficInterval = flag.Duration("health_check_grpc_backend_no_traffic_interval", defaults.HealthCheckGrpcBackendNoTrafficInterval, `Specify the checking interval to call the backend gRPC Health service
                       when at start up or the backend did not have any traffic. Default is 60 seconds. It only applies when the flag "--health_check_grpc_backend" is used.`)
+	HealthzCheckBackend = flag.Bool("healthz_check_backend", defaults.HealthzCheckBackend, `If true, the health check specified by the flag "--healthz" fails when the backend specified by the flag "--backend_address" is unhealthy.
+                      The backend is unhealthy when it keeps failing requests, or when the gRPC health check fails if the flag "--health_check_grpc_backend" is used.`)
+	HealthzBackendEvaluationWindow = flag.Duration("healthz_backend_evaluation_window", defaults.HealthzBackendEvaluationWindow, `Specify the window to evaluate the backend health. A failing backend is reported unhealthy for this window before it is evaluated again.
+                      Default is 30 seconds. It only applies when the flag "--healthz_check_backend" is used.`)
+	HealthzBackendConsecutiveFailures = flag.Uint("healthz_backend_consecutive_failures", defaults.HealthzBackendConsecutiveFailures, `Specify the number of consecutive failed requests, either 5xx responses or connection failures, after which the backend is reported unhealthy.
+                      Default is 5. It only applies when the flag "--healthz_check_backend" is used.`)
+
+	// Active health check of backend clusters related flags.
+	BackendHealthCheckPath = flag.String("backend_health_check_path", defaults.BackendHealthCheckPath, `If set, ESPv2 periodically sends HTTP GET requests with this path to the backends, both the one specified by the flag "--backend_address"
+                      and the dynamic routing ones, and stops routing to the backends failing the health check. Default is empty, which disables HTTP health check.`)
+	BackendHealthCheckGrpc = flag.Bool("backend_health_check_grpc", defaults.BackendHealthCheckGrpc, `If true, ESPv2 periodically checks the gRPC Health service of the gRPC backends, both the one specified by the flag "--backend_address"
+                      and the dynamic routing ones. Other backends are checked with the flag "--backend_health_check_path" if it is set.`)
+	BackendHealthCheckInterval         = flag.Duration("backend_health_check_interval", defaults.BackendHealthCheckInterval, `Specify the interval of the backend active health check. Default is 5 seconds.`)
+	BackendHealthCheckTimeout          = flag.Duration("backend_health_check_timeout", defaults.BackendHealthCheckTimeout, `Specify the timeout of each backend active health check. Default is 1 second.`)
+	BackendHealthCheckHealthyThreshold = flag.Uint("backend_health_check_healthy_threshold", defaults.BackendHealthCheckHealthyThreshold, `Specify the number of successful backend active health checks
+                      before an unhealthy backend is marked healthy. Default is 2.`)
+	BackendHealthCheckUnhealthyThreshold = flag.Uint("backend_health_check_unhealthy_threshold", defaults.BackendHealthCheckUnhealthyThreshold, `Specify the number of failed backend active health checks
+                      before a healthy backend is marked unhealthy. Default is 3.`)
+	BackendHealthCheckExpectedStatuses = flag.String("backend_health_check_expected_statuses", defaults.BackendHealthCheckExpectedStatuses, `Specify the HTTP status codes considered healthy by the backend HTTP health check,
+                      as a comma separated list of codes or inclusive ranges, such as "200,300-399". Default is empty, which only accepts 200.`)
+
+	// Readiness related flags.
+	ReadinessPort = flag.Uint("readiness_port", defaults.ReadinessPort, `Port that configmanager uses to serve the readiness endpoint "/ready".
+                      The endpoint reports whether the Envoy snapshot is loaded, the JWKS of every auth provider is reachable (or cached), and optionally the backend is connectable.
+                      Default is 0, which disables the endpoint.`)
+	ReadinessCheckBackend = flag.Bool("readiness_check_backend", defaults.ReadinessCheckBackend, `If true, the readiness endpoint also checks that a TCP connection can be established to the backend specified by the flag "--backend_address".`)
+	ReadinessCheckTimeout = flag.Duration("readiness_check_timeout", defaults.ReadinessCheckTimeout, `Timeout for each dependency check done by the readiness endpoint. Default is 5 seconds.`)
+	ReadinessMaxConfigAge = flag.Duration("readiness_max_config_age", defaults.ReadinessMaxConfigAge, `If set, the readiness endpoint fails when the served service config has not been confirmed as the latest rollout for longer than this age,
+                      so the stale proxies are rotated out. Only applies to "--rollout_strategy=managed". Default is 0, which disables the check.`)
+	ReadinessMaxRolloutFetchFailures = flag.Int("readiness_max_rollout_fetch_failures", defaults.ReadinessMaxRolloutFetchFailures, `If set, the readiness endpoint fails when fetching the latest rollout or its service config fails this number of consecutive times.
+                      Only applies to "--rollout_strategy=managed". Default is 0, which disables the check.`)
+
+	// Startup related flags.
+	StartupTimeout = flag.Duration("startup_timeout", defaults.StartupTimeout, `Fail the config manager with an error if the first snapshot cannot be generated within the timeout,
+                      such as when the service config or the metadata server is unreachable. Default is 0, which waits indefinitely.`)
+	RequireBackendDnsResolution = flag.Bool("require_backend_dns_resolution", defaults.RequireBackendDnsResolution, `If true, the config manager fails at startup if the hostnames of the backends cannot be resolved.
+                      The resolution is included in --startup_timeout.`)
+
+	// Snapshot debugging related flags.
+	SnapshotAdminPort = flag.Uint("snapshot_admin_port", defaults.SnapshotAdminPort, `Port on the loopback address that configmanager uses to serve the snapshot admin endpoints,
+                      "GET /snapshot/export" to download the served xDS snapshot as a tarball and "POST /snapshot/import"
+                      to upload an exported tarball in the replay mode, and "GET /configmanager/routes" and
+                      "GET /configmanager/clusters" to list the served routes and clusters as tables.
+                      Default is 0, which disables the endpoints.`)
+	SnapshotReplayFile = flag.String("snapshot_replay_file", defaults.SnapshotReplayFile, `Path of a tarball exported from "/snapshot/export". When this flag is used, configmanager serves the
+                      snapshot in the tarball instead of generating it from the service config, and allows "/snapshot/import".`)
+
+	// Metadata server stub related flags.
+	MetadataStubFile = flag.String("metadata_stub_file", defaults.MetadataStubFile, `Path of a JSON file of the static metadata values, such as {"project/project-id": "my-project"}, keyed by the paths
+                      relative to "/computeMetadata/v1/". When this flag is used, configmanager serves the values as a metadata server stub
+                      on the loopback address and overrides --metadata_url with it, for local development outside GCP.`)
+	MetadataStubPort = flag.Uint("metadata_stub_port", defaults.MetadataStubPort, `Port on the loopback address that configmanager uses to serve the metadata server stub of --metadata_stub_file.
+                      Default is 0, which picks an unused port.`)
+
+	// Config manager gRPC server related flags.
+	GrpcHealthPort = flag.Uint("grpc_health_port", defaults.GrpcHealthPort, `Port that configmanager uses to serve the grpc.health.v1 service over TCP, such as for the Kubernetes gRPC probes.
+                      The service is always served on --ads_named_pipe together with the ADS. Default is 0, which disables the TCP port.`)
+	EnableGrpcReflection = flag.Bool("enable_grpc_reflection", defaults.EnableGrpcReflection, `Enable the gRPC server reflection service on --ads_named_pipe, so tools such as grpcurl can list and call the ADS and health services.`)
+
+	HttpRedirectPort = flag.Int("http_redirect_port", defaults.HttpRedirectPort, `If not 0, ESPv2 also listens for plain HTTP on this port and redirects all the requests to HTTPS.
+                      It only applies when the flag "--ssl_server_cert_path" is used.`)
+	HttpRedirectResponseCode = flag.Int("http_redirect_response_code", defaults.HttpRedirectResponseCode, `The response code of the HTTP to HTTPS redirect, one of 301, 302, 303, 307 and 308. Default is 301.`)
+	HttpsRedirectPort        = flag.Int("https_redirect_port", defaults.HttpsRedirectPort, `The port the HTTP to HTTPS redirect points to. Default is 0, which uses the port of "--listener_port".`)
 
 	SslServerCertPath                = flag.String("ssl_server_cert_path", defaults.SslServerCertPath, "Path to the certificate and key that ESPv2 uses to act as a HTTPS server")
 	SslServerCipherSuites            = flag.String("ssl_server_cipher_suites", defaults.SslServerCipherSuites, "Cipher suites to use for downstream connections as a comma-separated list.")
@@ -89,6 +262,28 @@ var (
          For example --append_response_headers=key1=value1;key2=value2. If a header is already in the response, the new value will be append.`)
 	EnableOperationNameHeader = flag.Bool("enable_operation_name_header", defaults.EnableOperationNameHeader, "If enabled, the operation name for the matched route will be sent to the upstream as a request header.")
 
+	GrpcMetadata = flag.String("grpc_metadata", defaults.GrpcMetadata, `Add gRPC metadata to the requests of the gRPC methods before sent to the upstream backend. Multiple metadata are separated by ';'.
+         For example --grpc_metadata=x-api-gateway=espv2;x-consumer={consumer_number}. The values may use the templates {consumer_number}
+         for the consumer number validated by service control, {operation} for the operation name and {client_ip} for the client IP.`)
+
+	EnableSecurityHeaders = flag.Bool("enable_security_headers", defaults.EnableSecurityHeaders, `Add the security headers Strict-Transport-Security, X-Content-Type-Options, X-Frame-Options, Referrer-Policy and
+         Content-Security-Policy to the responses of the operations, unless the backend already sets them.`)
+	SecurityHeaders = flag.String("security_headers", defaults.SecurityHeaders, `A JSON object overriding the values of the security headers added by --enable_security_headers, such as
+         {"X-Frame-Options": "SAMEORIGIN", "Content-Security-Policy": ""}. An empty value removes the header, a new name adds the header.`)
+	OperationSecurityHeadersEnabled = flag.String("operation_security_headers_enabled", defaults.OperationSecurityHeadersEnabled, `Enable or disable the security headers per operation, in the format of "selector1=false;selector2=true".
+         The selector may contain "*" wildcards, the first matching selector applies.`)
+
+	SuppressUpstreamServiceTime = flag.Bool("suppress_upstream_service_time", defaults.SuppressUpstreamServiceTime, `Remove the x-envoy-upstream-service-time response header from all the operations, while keeping the other
+         x-envoy- headers enabled by --suppress_envoy_headers=false.`)
+	OperationResponseHeadersToRemove = flag.String("operation_response_headers_to_remove", defaults.OperationResponseHeadersToRemove, `Comma separated response headers removed from the responses per operation, in the format of
+         "selector1=x-envoy-upstream-service-time,x-powered-by;selector2=". The selector may contain "*" wildcards, the first matching selector applies.`)
+
+	EnableDeprecationHeaders = flag.Bool("enable_deprecation_headers", defaults.EnableDeprecationHeaders, `Add the "Deprecation: true" response header to the operations deprecated by the documentation rules of the service config.
+         The requests of the deprecated operations are counted in the route statistics "vhost.backend.route.deprecated.<operation>.*".`)
+	OperationDeprecations = flag.String("operation_deprecations", defaults.OperationDeprecations, `Mark the operations deprecated, in the format of "selector1=2025-06-30;selector2=true;selector3=false".
+         A date, or an RFC 3339 time, is the sunset date of the operation sent in the "Sunset" response header, in addition to "Deprecation: true".
+         "false" overrides the deprecation in the service config. The selector may contain "*" wildcards, the first matching selector applies.`)
+
 	// Flags for non_gcp deployment.
 	ServiceAccountKey = flag.String("service_account_key", defaults.ServiceAccountKey, `Use the service account key JSON file to access the service control and the
 	service management.  You can also set {creds_key} environment variable to the location of the service account credentials JSON file. If the option is
@@ -96,11 +291,36 @@ var (
 	TokenAgentPort                      = flag.Uint("token_agent_port", defaults.TokenAgentPort, "Port that configmanager use to setup server to provide envoy with access token using service account credential, for accessing servicecontrol.")
 	EnableApplicationDefaultCredentials = flag.Bool("enable_application_default_credentials", defaults.EnableApplicationDefaultCredentials, "Config Manager will use application default credentials if available.")
 
+	EnableTokenService = flag.Bool("enable_token_service", defaults.EnableTokenService, `Serve the cached tokens to the other containers of the pod on 127.0.0.1:--token_agent_port, "GET /v1/access_token" for the access token
+                      and "GET /v1/identity_token?audience=AUDIENCE" for the identity tokens, with the metrics at "GET /debug/vars".
+                      The tokens are of --service_account_key if specified, otherwise of the metadata server.`)
+
+	ServiceManagementServiceAccountKey = flag.String("service_management_service_account_key", defaults.ServiceManagementServiceAccountKey, `Use the service account key JSON file to fetch the service config
+                      from the service management. Defaults to --service_account_key.`)
+	ServiceControlServiceAccountKey = flag.String("service_control_service_account_key", defaults.ServiceControlServiceAccountKey, `Use the service account key JSON file to access the service control,
+                      including the rollout checks of --rollout_strategy=managed. Defaults to --service_account_key.`)
+	BackendAuthServiceAccountKey = flag.String("backend_auth_service_account_key", defaults.BackendAuthServiceAccountKey, `Use the service account key JSON file to mint the identity tokens of the backend auth,
+                      or the access token to call IAM if --backend_auth_credentials is specified. Defaults to --service_account_key.`)
+	BackendJwtAudienceTemplate = flag.String("backend_jwt_audience_template", defaults.BackendJwtAudienceTemplate, `The template of the JWT audience of the backend auth for the backend rules without "jwt_audience", such as "{scheme}://{host}{path}".
+                      The placeholders are replaced by the parts of the backend address: {scheme} is "http" or "https", {host}, {port} and {path},
+                      and {operation} by the selector of the backend rule. By default, the audience is "{scheme}://{host}".`)
+	OperationJwtAudienceTemplates = flag.String("operation_jwt_audience_templates", defaults.OperationJwtAudienceTemplates, `Override --backend_jwt_audience_template per operation, in the format of "selector1=https://{host};selector2={scheme}://{host}{path}".
+                      The selector may contain "*" wildcards, the first matching selector applies.`)
+
 	// Flags for external calls.
 	DisableOidcDiscovery = flag.Bool("disable_oidc_discovery", defaults.DisableOidcDiscovery, `Disable OpenID Connect Discovery. 
   When disabled, config generator will not make external calls to determine the JWKS URI, 
 	but the 'jwks_uri' field must not be empty in any authentication provider. 
 	This should be disabled when the URLs configured by the API Producer cannot be trusted.`)
+	OidcDiscoveryTimeout                  = flag.Duration("oidc_discovery_timeout", defaults.OidcDiscoveryTimeout, "Timeout of each attempt to fetch the OpenID Connect discovery document.")
+	OidcDiscoveryMaxRedirects             = flag.Int("oidc_discovery_max_redirects", defaults.OidcDiscoveryMaxRedirects, "Maximum number of redirects followed when fetching the OpenID Connect discovery document or the JWKS for the readiness checks.")
+	OidcDiscoveryNumRetries               = flag.Int("oidc_discovery_num_retries", defaults.OidcDiscoveryNumRetries, "Number of retries when fetching the OpenID Connect discovery document fails with a network error, 429 or 5xx.")
+	OidcDiscoveryRetryBackOffBaseInterval = flag.Duration("oidc_discovery_retry_backoff_base_interval", defaults.OidcDiscoveryRetryBackOffBaseInterval, "Base interval of the exponential backoff between the retries to fetch the OpenID Connect discovery document.")
+	OidcDiscoveryRetryBackOffMaxInterval  = flag.Duration("oidc_discovery_retry_backoff_max_interval", defaults.OidcDiscoveryRetryBackOffMaxInterval, "Maximum interval of the exponential backoff between the retries to fetch the OpenID Connect discovery document.")
+	DisableHttpFetchCache                 = flag.Bool("disable_http_fetch_cache", defaults.DisableHttpFetchCache, `Disable the caching of the remote fetches, such as the service configs, the rollouts
+                      and the OpenID Connect discovery documents. When enabled, the cached responses
+                      are revalidated with the ETag and Last-Modified validators, and unchanged
+                      content is not transferred again.`)
 	DependencyErrorBehavior = flag.String("dependency_error_behavior", defaults.DependencyErrorBehavior,
 		`The behavior all Envoy filter will adhere to when waiting for external dependencies during filter config.
 						Value must match the enum espv2.api.envoy.v12.http.common.DependencyErrorBehavior.`)
@@ -112,6 +332,10 @@ var (
 	https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log#default-format-string
 	For the detailed format grammar, please refer to the following document.
 	https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log#format-strings`)
+	AccessLogConsumerSampleRates = flag.String("access_log_consumer_sample_rates", defaults.AccessLogConsumerSampleRates, `Percentages of the requests written to the access log per API consumer,
+	in the format of "consumer_number1=percent1;consumer_number2=percent2". The consumers are identified by the project numbers
+	resolved by the Service Control check. "*" sets the rate of the other consumers, which are all logged if it is not specified.
+	Example, "*=0;123456789=100" only logs the requests of the consumer 123456789. Requires flag --access_log.`)
 
 	EnvoyUseRemoteAddress  = flag.Bool("envoy_use_remote_address", defaults.EnvoyUseRemoteAddress, "Envoy HttpConnectionManager configuration, please refer to envoy documentation for detailed information.")
 	EnvoyXffNumTrustedHops = flag.Int("envoy_xff_num_trusted_hops", defaults.EnvoyXffNumTrustedHops, "Envoy HttpConnectionManager configuration, please refer to envoy documentation for detailed information.")
@@ -126,19 +350,72 @@ var (
 
 	SuppressEnvoyHeaders = flag.Bool("suppress_envoy_headers", defaults.SuppressEnvoyHeaders, `Do not add any additional x-envoy- headers to requests or responses. This only affects the router filter
 	generated *x-envoy-* headers, other Envoy filters and the HTTP connection manager may continue to set x-envoy- headers.`)
+
+	PropagateRequestTimeout = flag.Bool("propagate_request_timeout", defaults.PropagateRequestTimeout, `Propagate the remaining deadline of the operations to the backends in the header x-envoy-expected-rq-timeout-ms,
+	and in the header grpc-timeout for the gRPC requests, so the backends can propagate the deadlines. The grpc-timeout of the
+	clients can shorten the deadlines but not extend them. The other x-envoy- headers stay suppressed by flag --suppress_envoy_headers.`)
+
 	UnderscoresInHeaders         = flag.Bool("underscores_in_headers", defaults.UnderscoresInHeaders, `When true, ESPv2 allows HTTP headers name has underscore and pass it through. Otherwise, rejects the request.`)
+	HeadersWithUnderscoresAction = flag.String("headers_with_underscores_action", defaults.HeadersWithUnderscoresAction, `The action for the HTTP headers with underscores in the name: "allow", "reject_request" or "drop_header".
+                      If not set, it is "allow" with --underscores_in_headers and "reject_request" otherwise.`)
+	MaxRequestHeadersCount = flag.Int("max_request_headers_count", defaults.MaxRequestHeadersCount, `Maximum number of request headers. Requests with more headers are rejected with 431.
+                      If not set, the Envoy default of 100 is used.`)
+	MaxRequestHeadersKb = flag.Int("max_request_headers_kb", defaults.MaxRequestHeadersKb, `Maximum size of the request headers in KiB, up to 8192. Requests with larger headers are rejected with 431.
+                      If not set, the Envoy default of 60 KiB is used.`)
+	HeaderKeyFormat = flag.String("header_key_format", defaults.HeaderKeyFormat, `The casing of the HTTP/1 header names sent to the clients and the HTTP/1 backends: "proper_case" or "preserve_case".
+                      If not set, the header names are lower cased.`)
 	NormalizePath                = flag.Bool("normalize_path", defaults.NormalizePath, `Normalizes the path according to RFC 3986 before processing requests.`)
 	MergeSlashesInPath           = flag.Bool("merge_slashes_in_path", defaults.MergeSlashesInPath, `Determines if adjacent slashes in the path are merged into one before processing requests.`)
 	DisallowEscapedSlashesInPath = flag.Bool("disallow_escaped_slashes_in_path", defaults.DisallowEscapedSlashesInPath, `Determines if [%2F, %2f, %2C, %2c] characters in the path are disallowed.`)
 
+	DownstreamIdleTimeout = flag.Duration("downstream_idle_timeout", defaults.DownstreamIdleTimeout, `The time a downstream connection may stay open without any active request, such as "5m".
+                      If not set, the Envoy default of 1 hour is used.`)
+	DownstreamMaxConnectionDuration = flag.Duration("downstream_max_connection_duration", defaults.DownstreamMaxConnectionDuration, `The maximum lifetime of a downstream connection, such as "1h". Once reached, the connection is drained:
+                      HTTP/1 connections are closed after the active request and HTTP/2 connections receive a GOAWAY, so long lived
+                      keep-alive clients and load balancers reconnect and are rebalanced. If not set, the connections live forever.`)
+	DownstreamDrainTimeout = flag.Duration("downstream_drain_timeout", defaults.DownstreamDrainTimeout, `The time between the GOAWAY sent to an HTTP/2 connection being drained and its close, so the in-flight requests can finish.
+                      If not set, the Envoy default of 5 seconds is used.`)
+
+	ServerHeader               = flag.String("server_header", defaults.ServerHeader, `The value of the Server response header. If not set, "envoy" is used.`)
+	ServerHeaderTransformation = flag.String("server_header_transformation", defaults.ServerHeaderTransformation, `How the Server response header is set: "overwrite" always sets it to the value of
+                      --server_header, "append_if_absent" only sets it if the backend did not, "pass_through" keeps
+                      the one of the backend, and "suppress" removes it from all the responses. Defaults to "overwrite".`)
+	ViaHeader = flag.String("via_header", defaults.ViaHeader, `The value of the Via header added to the requests and the responses, such as "1.1 espv2-{environment}".
+                      The "{key}" placeholders are replaced by the deployment labels of --deployment_labels and the
+                      ones detected from the platform. If not set, no Via header is added.`)
+	MergeDuplicateRoutes = flag.Bool("merge_duplicate_routes", defaults.MergeDuplicateRoutes, `If true, the adjacent routes that only differ by the operation name and the path are merged into one route
+                      matching all their paths, to reduce the route table size and the Envoy memory of the service configs
+                      with many similar paths. The merged route is named after its first operation, and lists all of them
+                      in the route metadata "com.google.espv2.merged_routes". The routes with per-operation settings, such as
+                      the Service Control or JWT authentication ones, are not merged.`)
+
 	ServiceControlNetworkFailOpen = flag.Bool("service_control_network_fail_open", defaults.ServiceControlNetworkFailOpen, ` In case of network failures when connecting to Google service control,
         the requests will be allowed if this flag is on. The default is on.`)
 	ServiceControlEnableApiKeyUidReporting = flag.Bool("service_control_enable_api_key_uid_reporting", defaults.ServiceControlEnableApiKeyUidReporting, ` If true, reports api_key_uid instead of api_key in ServiceControl report.`)
+	ApiKeyFromBasicAuthUsername            = flag.Bool("api_key_from_basic_auth_username", defaults.ApiKeyFromBasicAuthUsername, `If true, the username of the Basic authentication in the Authorization header, such as "Basic base64(key:)",
+                      is also used as the API key, after the API key locations of the operation.`)
+	ApiKeyBearerTokenPrefix = flag.String("api_key_bearer_token_prefix", defaults.ApiKeyBearerTokenPrefix, `If set, the bearer tokens with the prefix in the Authorization header, such as "AIza", are also used as the API keys,
+                      after the API key locations of the operation. The other bearer tokens, such as JWTs, are ignored. The Authorization header with
+                      the API key is not forwarded to the backend. It cannot be used with the authentication providers extracting the JWTs from the
+                      Authorization header, including the ones with the default jwt_locations.`)
 
 	EnableGrpcForHttp1 = flag.Bool("enable_grpc_for_http1", defaults.EnableGrpcForHttp1, `Enable gRPC when the downstream is HTTP/1.1. The default is on.`)
+	EnableGrpcWeb      = flag.Bool("enable_grpc_web", defaults.EnableGrpcWeb, `Enable browser clients to call the gRPC backends with gRPC-Web. It requires a gRPC backend.
+                      CORS is enabled with the flag "--cors_preset=basic" if no CORS preset is specified, and the gRPC-Web headers are added to the allowed and exposed CORS headers.`)
 
 	ConnectionBufferLimitBytes = flag.Int("connection_buffer_limit_bytes", defaults.ConnectionBufferLimitBytes, `Configure the maximum amount of data that is buffered for each request/response body. 
 			If not provided, Envoy will decide the default value.`)
+	ListenerConnectionRateLimit = flag.Int("listener_connection_rate_limit", defaults.ListenerConnectionRateLimit, `The maximum number of new downstream connections per second accepted by the ingress listener.
+                      The connections beyond the limit are closed immediately. 0 means unlimited.`)
+	AllowedSourceRanges = flag.String("allowed_source_ranges", defaults.AllowedSourceRanges, `Comma separated CIDRs or IP addresses of the peers allowed to connect to the ingress listener, such as "10.0.0.0/8,192.168.1.1".
+                      The connections from other peers are closed before any request is read. Unlike --allowed_client_ips, the peer is
+                      always the direct remote address of the connection, never the x-forwarded-for header. By default, all the peers are allowed.`)
+	ClientIPRateLimitPerSecond = flag.Int("client_ip_rate_limit_per_second", defaults.ClientIPRateLimitPerSecond, `The requests per second allowed from each client IP, such as a first line of defense for
+                      the login endpoints. The requests beyond the limit are rejected with 429. Like --allowed_client_ips,
+                      the client IP is read from the x-forwarded-for header if it is trusted by --envoy_xff_num_trusted_hops.
+                      The requests are counted by each Envoy instance. 0 means unlimited.`)
+	ClientIPRateLimitBurst = flag.Int("client_ip_rate_limit_burst", defaults.ClientIPRateLimitBurst, `The max burst of the requests of each client IP allowed by --client_ip_rate_limit_per_second.
+                      0 means the same as --client_ip_rate_limit_per_second.`)
 
 	DisableJwksAsyncFetch      = flag.Bool("disable_jwks_async_fetch", defaults.DisableJwksAsyncFetch, `When the feature is enabled, JWKS is fetched before processing any requests. When disabled, JWKS is fetched on-demand when processing the requests.`)
 	JwksAsyncFetchFastListener = flag.Bool("jwks_async_fetch_fast_listener", defaults.JwksAsyncFetchFastListener, `Only apply when --disable_jwks_async_fetch flag is not set. This flag determines if the envoy will wait for jwks_async_fetch to complete before binding the listener port. If false, it will wait. Default is false.`)
@@ -153,25 +430,68 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
 
 	DisableJwtAudienceServiceNameCheck = flag.Bool("disable_jwt_audience_service_name_check", defaults.DisableJwtAudienceServiceNameCheck, `Normally JWT "aud" field is checked against audiences specified in OpenAPI "x-google-audiences" field. This flag changes the behaviour when the "x-google-audiences" is not specified. When the "x-google-audiences" is not specified, normally the service name is used to check the JWT "aud" field.  If this flag is true, the service name is not used, JWT "aud" field will not be checked.`)
 
+	JwksResolveAtStartup = flag.Bool("jwks_resolve_at_startup", defaults.JwksResolveAtStartup, `Resolve the hostnames of the JWKS URIs and the issuers of the authentication providers when generating the config,
+                      and connect to the JWKS providers by the resolved IP addresses, so Envoy does not depend on DNS at runtime.
+                      The addresses are refreshed every --jwks_resolve_ttl, and the last resolved addresses are kept if DNS fails.`)
+	JwksResolveTTL = flag.Duration("jwks_resolve_ttl", defaults.JwksResolveTTL, `The interval to refresh the addresses resolved by --jwks_resolve_at_startup.`)
+
 	ScCheckTimeoutMs  = flag.Int("service_control_check_timeout_ms", defaults.ScCheckTimeoutMs, `Set the timeout in millisecond for service control Check request. Must be > 0 and the default is 1000 if not set.`)
 	ScQuotaTimeoutMs  = flag.Int("service_control_quota_timeout_ms", defaults.ScQuotaTimeoutMs, `Set the timeout in millisecond for service control Quota request. Must be > 0 and the default is 1000 if not set.`)
 	ScReportTimeoutMs = flag.Int("service_control_report_timeout_ms", defaults.ScReportTimeoutMs, `Set the timeout in millisecond for service control Report request. Must be > 0 and the default is 2000 if not set.`)
 
+	ScCompressReports = flag.Bool("service_control_compress_reports", defaults.ScCompressReports, `If true, the bodies of the service control Report requests are compressed with gzip, reducing the egress of the high-QPS deployments.
+                      The sizes before and after the compression are recorded in the report_uncompressed_bytes and report_compressed_bytes stats.`)
+
 	ScCheckRetries  = flag.Int("service_control_check_retries", defaults.ScCheckRetries, `Set the retry times for service control Check request. Must be >= 0 and the default is 3 if not set.`)
 	ScQuotaRetries  = flag.Int("service_control_quota_retries", defaults.ScQuotaRetries, `Set the retry times for service control Quota request. Must be >= 0 and the default is 1 if not set.`)
 	ScReportRetries = flag.Int("service_control_report_retries", defaults.ScReportRetries, `Set the retry times for service control Report request. Must be >= 0 and the default is 5 if not set.`)
 
+	OperationQuotaMetricCosts = flag.String("operation_quota_metric_costs", defaults.OperationQuotaMetricCosts, `Override the quota metric costs of the operations without re-publishing the service config,
+                      such as "*.ListShelves=read-requests:2;*.CreateShelf=write-requests:0,read-requests:1".
+                      The selectors may contain wildcards and the first match wins. The costs of the
+                      metrics not listed for the operation are kept from the service config. The metrics
+                      must be used by the quota limits of the service config. A cost of 0 stops charging
+                      the metric to the operation.`)
+	ConsumerMaxConcurrentRequests = flag.Int("consumer_max_concurrent_requests", defaults.ConsumerMaxConcurrentRequests, `The max in-flight requests of each API key, the requests beyond it are rejected with 429.
+                      The requests without API keys are not limited. 0 means unlimited. The requests are
+                      counted by each Envoy instance.`)
+	OperationConsumerMaxConcurrentRequests = flag.String("operation_consumer_max_concurrent_requests", defaults.OperationConsumerMaxConcurrentRequests, `Override the max in-flight requests of each API key for the operations, such as
+                      "*.CreateShelf=2;*.ListShelves=0". The selectors may contain wildcards and the first
+                      match wins. The requests of the overridden operations are counted per operation,
+                      separately from the --consumer_max_concurrent_requests. 0 means unlimited.`)
+
 	ComputePlatformOverride = flag.String("compute_platform_override", defaults.ComputePlatformOverride, "the overridden platform where the proxy is running at")
 
+	DeploymentLabels = flag.String("deployment_labels", defaults.DeploymentLabels, `The labels of the deployment, "key=value" pairs separated by ";", such as
+                      "environment=prod;team=payments". The keys must start with a lowercase letter
+                      and only contain lowercase letters, digits and '_'. The labels detected from the
+                      platform (Cloud Run service and revision, GKE cluster, namespace and pod, GCE
+                      instance) are added automatically, and the labels of this flag override the
+                      detected ones of the same keys. The labels are attached to the Service Control
+                      reports, as custom tags to the traces, and as the route metadata
+                      "com.google.espv2.deployment" to the access logs, such as
+                      "%METADATA(ROUTE:com.google.espv2.deployment:gke_pod)%".`)
+
 	// Flags for testing purpose. They are not exposed to the user via start_proxy.py
 	SkipJwtAuthnFilter       = flag.Bool("skip_jwt_authn_filter", defaults.SkipJwtAuthnFilter, "skip jwt authn filter, for test purpose")
 	SkipServiceControlFilter = flag.Bool("skip_service_control_filter", defaults.SkipServiceControlFilter, "skip service control filter, for test purpose")
 	StreamIdleTimeout        = flag.Duration("stream_idle_timeout_test_only", defaults.StreamIdleTimeout, "The amount of time HTTP/2 streams can exist without any activity. "+
 		"Set `deadline` in the service config to override this global value on a per-route basis.")
 
+	UpgradeTypes          = flag.String("upgrade_types", defaults.UpgradeTypes, `Comma separated connection upgrade types, such as "websocket" and "CONNECT", allowed for all the operations. Default is "websocket".`)
+	OperationUpgradeTypes = flag.String("operation_upgrade_types", defaults.OperationUpgradeTypes, `Override the connection upgrade types allowed per operation, in the format of "selector1=type1,type2;selector2=type3".
+                      The selector may contain "*" wildcards, the first matching selector applies. An empty type list disallows all the upgrades for the operation.`)
+	OperationUpgradeIdleTimeouts = flag.String("operation_upgrade_idle_timeouts", defaults.OperationUpgradeIdleTimeouts, `Override the idle timeout per operation for long lived upgraded connections, such as websockets,
+                      in the format of "selector1=1h;selector2=30m". The selector may contain "*" wildcards, the first matching selector applies.`)
+
+	StreamingDownloadBufferLimitBytes = flag.Int("streaming_download_buffer_limit_bytes", defaults.StreamingDownloadBufferLimitBytes, `The buffer limit in bytes of the streaming downloads, which are the server streaming methods responding google.api.HttpBody.
+                      The backend is paused by flow control once the buffer is full, so large downloads are not buffered in memory. Default is 65536.`)
+	OperationStreamingDownloads = flag.String("operation_streaming_downloads", defaults.OperationStreamingDownloads, `Override whether the server streaming methods are streaming downloads per operation, in the format of "selector1=true;selector2=false".
+                      The selector may contain "*" wildcards, the first matching selector applies. By default, the server streaming methods responding google.api.HttpBody are streaming downloads.`)
+
 	TranscodingAlwaysPrintPrimitiveFields         = flag.Bool("transcoding_always_print_primitive_fields", defaults.TranscodingAlwaysPrintPrimitiveFields, "Whether to always print primitive fields for grpc-json transcoding")
 	TranscodingAlwaysPrintEnumsAsInts             = flag.Bool("transcoding_always_print_enums_as_ints", defaults.TranscodingAlwaysPrintPrimitiveFields, "Whether to always print enums as ints for grpc-json transcoding")
-	TranscodingStreamNewLineDelimited             = flag.Bool("transcoding_stream_newline_delimited", defaults.TranscodingStreamNewLineDelimited, "If true, use new line delimiter to separate response streaming messages. If false, all response streaming messages will be transcoded into a JSON array.")
+	TranscodingStreamNewLineDelimited             = flag.Bool("transcoding_stream_newline_delimited", defaults.TranscodingStreamNewLineDelimited, "If true, use new line delimiter to separate response streaming messages, so clients can consume newline delimited JSON (NDJSON) incrementally. If false, all response streaming messages will be transcoded into a JSON array.")
 	TranscodingPreserveProtoFieldNames            = flag.Bool("transcoding_preserve_proto_field_names", defaults.TranscodingPreserveProtoFieldNames, "Whether to preserve proto field names for grpc-json transcoding")
 	TranscodingIgnoreQueryParameters              = flag.String("transcoding_ignore_query_parameters", defaults.TranscodingIgnoreQueryParameters, "A list of query parameters(separated by comma) to be ignored for transcoding method mapping in grpc-json transcoding.")
 	TranscodingIgnoreUnknownQueryParameters       = flag.Bool("transcoding_ignore_unknown_query_parameters", defaults.TranscodingIgnoreUnknownQueryParameters, "Whether to ignore query parameters that cannot be mapped to a corresponding protobuf field in grpc-json transcoding.")
@@ -187,6 +507,25 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
 
 	[1](https://github.com/googleapis/googleapis/blob/master/google/api/http.proto#L226-L231)`)
 
+	DisableTranscoding = flag.Bool("disable_transcoding", defaults.DisableTranscoding, `Disable the gRPC-JSON transcoder. The operations served by gRPC backends only accept native gRPC requests,
+                      their http rules in the service config are ignored, e.g. if they are only published for documentation.
+                      The operations served by HTTP backends keep their http rules.`)
+
+	GrpcReflection = flag.String("grpc_reflection", defaults.GrpcReflection, `Explicitly allow or deny the gRPC server reflection calls through the proxy, whether or not the reflection service is in the service config:
+                      "allow" proxies them to the gRPC backend without reporting them to Service Control, e.g. for grpcurl in dev environments,
+                      "deny" rejects them with PERMISSION_DENIED, e.g. in prod environments. If not set, they are handled as any other call.`)
+	GrpcReflectionJwtProviders = flag.String("grpc_reflection_jwt_providers", defaults.GrpcReflectionJwtProviders, `Comma separated authentication provider ids of the service config. If set with --grpc_reflection=allow,
+                      the gRPC server reflection calls require a JWT issued by any of the providers.`)
+
+	GrpcErrorStatusCodes = flag.String("grpc_error_status_codes", defaults.GrpcErrorStatusCodes, `Override the HTTP status codes of transcoded gRPC error responses, in the format of "NOT_FOUND=404;14=503".
+                      The gRPC status codes can be specified by name or by number. Native gRPC clients are not affected.
+                      The responses are matched by the HTTP status code the transcoder responds with, such as 404 for NOT_FOUND, so the gRPC status codes
+                      transcoded to the same HTTP status code cannot be mapped differently, and the ESPv2 error responses with that status code are also overridden.`)
+	ErrorResponseTemplate = flag.String("error_response_template", defaults.ErrorResponseTemplate, `A JSON object used as the body of the error responses generated by ESPv2 and of the transcoded gRPC errors mapped by --grpc_error_status_codes,
+                      such as '{"error": {"status": "%RESPONSE_CODE%", "detail": "%LOCAL_REPLY_BODY%"}}'. The values may contain Envoy command operators,
+                      "%LOCAL_REPLY_BODY%" is the error message. For the transcoded gRPC errors, it is the "grpc-message" response header,
+                      which is usually empty as the gRPC message is sent in the trailers. By default, the body is '{"code": "%RESPONSE_CODE%", "message": "%LOCAL_REPLY_BODY%"}'.`)
+
 	BackendRetryOns = flag.String("backend_retry_ons", defaults.BackendRetryOns,
 		`The conditions under which ESPv2 does retry on the backends. One or more
         retryOn conditions can be specified by comma-separated list. The default
@@ -211,9 +550,141 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
         addition to the status codes enabled for retry through other retry
         policies set in "--backend_retry_ons".
         The format is a comma-delimited String, like "501, 503`)
+	BackendRetryBudgetPercent = flag.Float64("backend_retry_budget_percent", defaults.BackendRetryBudgetPercent, `The percent of the active requests to a backend cluster allowed to be retries, in (0, 100], such as 20.
+                      The retries beyond the budget are not sent, which protects the backends from retry storms. By default, there is no retry budget.`)
+	BackendRetryBudgetMinConcurrency = flag.Uint("backend_retry_budget_min_concurrency", defaults.BackendRetryBudgetMinConcurrency, `The number of concurrent retries to a backend cluster always allowed by --backend_retry_budget_percent. By default, 3 retries are allowed.`)
+	BackendHedgeGetRequests          = flag.Bool("backend_hedge_get_requests", defaults.BackendHedgeGetRequests, `If true, the GET requests that do not respond within --backend_per_try_timeout are hedged: another attempt is sent to the backend
+                      without cancelling the outstanding one, and the first response is used. The attempts are bounded by --backend_retry_num. Requires --backend_per_try_timeout.`)
+	OperationHedgeDelays = flag.String("operation_hedge_delays", defaults.OperationHedgeDelays, `Opt the operations into request hedging with the initial hedge delay, in the format of "selector1=50ms;selector2=200ms".
+                      The hedge delay overrides --backend_per_try_timeout for the operation. Only latency-critical idempotent operations should be hedged.
+                      The selector may contain "*" wildcards, the first matching selector applies. The attempts are bounded by --backend_retry_num.`)
+	OperationRequestTimeouts = flag.String("operation_request_timeouts", defaults.OperationRequestTimeouts, `Override the response timeouts of the operations, in the format of "selector1=60s;selector2=10s".
+                      The timeout overrides the "deadline" in the "x-google-backend" extension, and bounds the full response to the client
+                      including all the retries. The selector may contain "*" wildcards, the first matching selector applies.`)
+	OperationPerTryTimeouts = flag.String("operation_per_try_timeouts", defaults.OperationPerTryTimeouts, `Override --backend_per_try_timeout for the operations, in the format of "selector1=25s;selector2=3s".
+                      The per-try timeout bounds each attempt to the backend, and must be less than the response timeout of the operation,
+                      so a 60s response timeout can wrap two 25s attempts with --backend_retry_num=1. The selector may contain "*" wildcards,
+                      the first matching selector applies.`)
+	BackendSlowStartWindow = flag.Duration("backend_slow_start_window", defaults.BackendSlowStartWindow, `The window to ramp up the traffic share of the newly added endpoints of the remote backends, such as 30s.
+                      The remote backend clusters resolve all the addresses of the backend hostname, so the new instances of a scaled backend
+                      are not immediately sent the full traffic share. By default, there is no slow start.`)
+	BackendSlowStartAggression = flag.Float64("backend_slow_start_aggression", defaults.BackendSlowStartAggression, `The non-linearity of the traffic ramp up of --backend_slow_start_window, larger values ramp up faster at the start of the window.
+                      By default, 1.0 is used for a linear ramp up.`)
 
 	EnableResponseCompression = flag.Bool("enable_response_compression", defaults.EnableResponseCompression, `Enable gzip,br compression for response data. The default is disabled.`)
 
+	AllowedClientIps = flag.String("allowed_client_ips", defaults.AllowedClientIps, `Comma separated CIDRs or IP addresses of the clients allowed to call all the operations, such as "10.0.0.0/8,192.168.1.1".
+                      Other clients are rejected with 403 Forbidden. The client IP is the remote address, or the original client IP from the x-forwarded-for header
+                      if --envoy_use_remote_address is set. By default, all the clients are allowed.`)
+	DeniedClientIps           = flag.String("denied_client_ips", defaults.DeniedClientIps, `Comma separated CIDRs or IP addresses of the clients rejected with 403 Forbidden for all the operations.`)
+	OperationAllowedClientIps = flag.String("operation_allowed_client_ips", defaults.OperationAllowedClientIps, `Override the CIDRs or IP addresses of the allowed clients per operation, in the format of "selector1=10.0.0.0/8,::1;selector2=".
+                      The selector may contain "*" wildcards, the first matching selector applies. An empty list allows all the clients.`)
+	OperationDeniedClientIps = flag.String("operation_denied_client_ips", defaults.OperationDeniedClientIps, `Override the CIDRs or IP addresses of the denied clients per operation, in the format of "selector1=10.0.0.0/8,::1;selector2=".
+                      The selector may contain "*" wildcards, the first matching selector applies. An empty list denies no clients.`)
+
+	ExtProcAddress = flag.String("ext_proc_address", defaults.ExtProcAddress, `The address of an external processing (ext_proc) gRPC service, such as "grpc://127.0.0.1:9000" or "grpcs://ext-proc.example.com".
+                      The service can inspect and modify the requests and responses. By default, no ext_proc service is called.`)
+	ExtProcTimeout          = flag.Duration("ext_proc_timeout", defaults.ExtProcTimeout, `The timeout for each message sent to the ext_proc service. Default is 200ms.`)
+	ExtProcFailureModeAllow = flag.Bool("ext_proc_failure_mode_allow", defaults.ExtProcFailureModeAllow, `If true, the requests continue when the ext_proc service fails or times out. By default, they are rejected.`)
+	ExtProcProcessingMode   = flag.String("ext_proc_processing_mode", defaults.ExtProcProcessingMode, `The parts of the requests and responses sent to the ext_proc service, one of "headers_only" or "buffered".
+                      With "buffered", the bodies are buffered and sent along with the headers. Default is "headers_only".`)
+	OperationExtProcProcessingModes = flag.String("operation_ext_proc_processing_modes", defaults.OperationExtProcProcessingModes, `Override the ext_proc processing mode per operation, in the format of "selector1=buffered;selector2=disabled".
+                      The selector may contain "*" wildcards, the first matching selector applies. "disabled" skips the ext_proc service.`)
+
+	WasmFilterURI = flag.String("wasm_filter_uri", defaults.WasmFilterURI, `The local file path or HTTPS URL of a WASM HTTP filter module, such as "/etc/espv2/filter.wasm" or "https://example.com/filter.wasm".
+                      By default, no WASM filter is loaded.`)
+	WasmFilterSha256 = flag.String("wasm_filter_sha256", defaults.WasmFilterSha256, `The SHA-256 checksum of the WASM filter module, required if --wasm_filter_uri is a HTTPS URL.`)
+	WasmFilterConfig = flag.String("wasm_filter_config", defaults.WasmFilterConfig, `The configuration passed to the WASM filter module as a string.`)
+	WasmFilterBefore = flag.String("wasm_filter_before", defaults.WasmFilterBefore, `The name of the filter the WASM filter is inserted before, such as "envoy.filters.http.jwt_authn".
+                      Default is "envoy.filters.http.router", the WASM filter is the last filter before the routing.`)
+	OperationWasmFilterEnabled = flag.String("operation_wasm_filter_enabled", defaults.OperationWasmFilterEnabled, `Enable or disable the WASM filter per operation, in the format of "selector1=false;selector2=true".
+                      The selector may contain "*" wildcards, the first matching selector applies. The value is set as the "enabled" field of the
+                      "envoy.filters.http.wasm" route metadata, the WASM filter module should skip the requests of the disabled operations.`)
+	HttpFilterOrder = flag.String("http_filter_order", defaults.HttpFilterOrder, `Move or disable the generated HTTP filters, applied in order after --wasm_filter_before, in the format of
+                      "envoy.filters.http.wasm=before:envoy.filters.http.jwt_authn;envoy.filters.http.compressor=disabled".
+                      A filter is moved right "before:" or "after:" another generated filter, or removed with "disabled". The filters not generated are ignored.
+                      The orders breaking the dependencies between the filters are rejected, the router filter stays the last one, and the
+                      header sanitizer, tenant sanitizer, authentication, Service Control, backend authentication, path rewrite and router
+                      filters cannot be disabled.`)
+
+	LuaFilterScript = flag.String("lua_filter_script", defaults.LuaFilterScript, `The path of a Lua script file run for all the operations, such as "/etc/espv2/remap_headers.lua".
+                      The script defines the envoy_on_request and envoy_on_response functions of the Envoy Lua filter. By default, no Lua script is run.`)
+	LuaScripts          = flag.String("lua_scripts", defaults.LuaScripts, `The named Lua script files which can be run for specific operations, in the format of "name1=/etc/espv2/a.lua;name2=/etc/espv2/b.lua".`)
+	OperationLuaScripts = flag.String("operation_lua_scripts", defaults.OperationLuaScripts, `Override the Lua script per operation by the name from --lua_scripts, in the format of "selector1=name1;selector2=disabled".
+                      The selector may contain "*" wildcards, the first matching selector applies. "disabled" skips the Lua scripts.`)
+
+	EnableRequestValidation = flag.Bool("enable_request_validation", defaults.EnableRequestValidation, `Validate the requests by the request message types of the operations in the service config, such as those generated from the OpenAPI parameters.
+                      The requests missing a required query parameter or body, or with a query parameter not matching the field type or enum values, are rejected with 400
+                      before they reach the backends. gRPC requests are not validated.`)
+
+	AdminReadOnlyPaths = flag.String("admin_read_only_paths", defaults.AdminReadOnlyPaths, `Comma separated paths of the envoy admin interface exposed on the main listener, such as "/stats,/ready,/server_info".
+                      Only GET requests with the token of --admin_token_path are allowed, and only "/ready", "/server_info", "/stats" and "/stats/prometheus" can be exposed.`)
+	AdminPathPrefix  = flag.String("admin_path_prefix", defaults.AdminPathPrefix, `The path prefix of the admin paths exposed on the main listener. Default is "/espv2_admin", such as "/espv2_admin/stats".`)
+	AdminTokenHeader = flag.String("admin_token_header", defaults.AdminTokenHeader, `The request header carrying the token to access the admin paths exposed on the main listener.`)
+	AdminTokenPath   = flag.String("admin_token_path", defaults.AdminTokenPath, `The file holding the token to access the admin paths exposed on the main listener, required by --admin_read_only_paths.
+                      The token must be at least 16 characters. It is read again with each service config.`)
+
+	MaintenanceMode       = flag.Bool("maintenance_mode", defaults.MaintenanceMode, `If true, all the requests are answered with the --maintenance_response instead of calling the backends. The health checks and the admin paths still work.`)
+	MaintenanceOperations = flag.String("maintenance_operations", defaults.MaintenanceOperations, `Comma separated selectors of the operations answered with the --maintenance_response instead of calling the backends,
+                      such as "endpoints.examples.bookstore.Bookstore.CreateShelf,endpoints.examples.bookstore.Bookstore.Delete*". The selector may contain "*" wildcards.`)
+	MaintenanceResponse = flag.String("maintenance_response", defaults.MaintenanceResponse, `The static response served in the maintenance mode, a JSON object with the status, body and headers,
+                      such as '{"status": 503, "body": "Down for maintenance", "headers": {"retry-after": "3600", "content-type": "text/plain"}}'.
+                      By default, the status is 503 with a short body.`)
+	StaticPaths = flag.String("static_paths", defaults.StaticPaths, `A JSON object of the paths whose GET requests are answered with an inline response, without calling the backend or Service Control,
+                      such as '{"/robots.txt": {"body": "User-agent: *\nDisallow: /", "contentType": "text/plain"}, "/favicon.ico": {"status": 204}}'.
+                      The status defaults to 200. The paths cannot contain wildcards or variables.`)
+	RedirectRules = flag.String("redirect_rules", defaults.RedirectRules, `A JSON array of the rules to redirect the requests by the exact "path" or the "pathPrefix", without calling the backend,
+                      such as '[{"pathPrefix": "/api/v1/", "prefixRewrite": "/v1/"}, {"path": "/docs", "host": "docs.example.com", "scheme": "https", "code": 308}]'.
+                      The "pathRedirect" replaces the exact path, the "prefixRewrite" replaces the path prefix. The code is 301 or 308, defaults to 301.`)
+
+	MaxRequestBytes = flag.Int("max_request_bytes", defaults.MaxRequestBytes, `The maximum size in bytes of the request bodies, larger requests are rejected with 413 Payload Too Large before they are sent to the backend.
+                      The request bodies are buffered, except for the client streaming methods. Default is 0, unlimited.`)
+	OperationMaxRequestBytes = flag.String("operation_max_request_bytes", defaults.OperationMaxRequestBytes, `Override the maximum size in bytes of the request bodies per operation, in the format of "selector1=1048576;selector2=0".
+                      The selector may contain "*" wildcards, the first matching selector applies. 0 is unlimited.`)
+
+	EnableAdaptiveConcurrency             = flag.Bool("enable_adaptive_concurrency", defaults.EnableAdaptiveConcurrency, `Enable the adaptive concurrency filter, which limits the concurrent requests by the latency of the backends and rejects the requests beyond the limit with 503.`)
+	AdaptiveConcurrencySamplePercentile   = flag.Float64("adaptive_concurrency_sample_percentile", defaults.AdaptiveConcurrencySamplePercentile, `The percentile of the sampled latency compared against the minimum latency. By default, p50 is used.`)
+	AdaptiveConcurrencyMaxLimit           = flag.Int("adaptive_concurrency_max_limit", defaults.AdaptiveConcurrencyMaxLimit, `The upper bound of the concurrency limit. By default, 1000 is used.`)
+	AdaptiveConcurrencyUpdateInterval     = flag.Duration("adaptive_concurrency_update_interval", defaults.AdaptiveConcurrencyUpdateInterval, `The interval to recalculate the concurrency limit from the sampled latency. By default, 100ms is used.`)
+	AdaptiveConcurrencyMinRttInterval     = flag.Duration("adaptive_concurrency_min_rtt_interval", defaults.AdaptiveConcurrencyMinRttInterval, `The interval to remeasure the minimum latency of the backends. By default, 60s is used.`)
+	AdaptiveConcurrencyMinRttRequestCount = flag.Int("adaptive_concurrency_min_rtt_request_count", defaults.AdaptiveConcurrencyMinRttRequestCount, `The number of requests sampled to measure the minimum latency. By default, 50 is used.`)
+	AdaptiveConcurrencyBufferPercent      = flag.Float64("adaptive_concurrency_buffer_percent", defaults.AdaptiveConcurrencyBufferPercent, `The percentage added to the minimum latency to tolerate the natural latency variance. By default, 25 is used.`)
+
+	OperationTapEnabled = flag.String("operation_tap_enabled", defaults.OperationTapEnabled, `Capture the requests and the responses of the operations with the Envoy tap filter, in the format of "selector1=true;selector2=false".
+                      The selector may contain "*" wildcards, the first matching selector applies. The captures are written to the files
+                      of --tap_output_path_prefix. Use it temporarily for debugging, the captures include the credentials of the requests.`)
+	TapOutputPathPrefix = flag.String("tap_output_path_prefix", defaults.TapOutputPathPrefix, `The path prefix of the files the captures of --operation_tap_enabled are written to, one file per request,
+                      such as "/tmp/espv2_tap".`)
+	TapAdminConfigId = flag.String("tap_admin_config_id", defaults.TapAdminConfigId, `Enable the Envoy tap filter configured from the "/tap" endpoint of the Envoy admin with this config id,
+                      which streams the captures of the requests matched by the posted tap config to the admin client.
+                      Cannot be used with --operation_tap_enabled.`)
+	TapMaxBufferedBytes = flag.Int("tap_max_buffered_bytes", defaults.TapMaxBufferedBytes, `The maximum bytes captured of each request and response body, the rest is truncated. By default, 1024 bytes are captured.`)
+
+	OperationFaultAbort = flag.String("operation_fault_abort", defaults.OperationFaultAbort, `Abort the requests of the operations with an HTTP status code for chaos testing, in the format of "selector1=503@10;selector2=429".
+                      The optional "@percent" is the percentage of the requests aborted, all of them by default. The selector may contain "*" wildcards,
+                      the first matching selector applies. Only the requests with the header of --fault_trigger_header are aborted.`)
+	OperationFaultDelay = flag.String("operation_fault_delay", defaults.OperationFaultDelay, `Delay the requests of the operations for chaos testing, in the format of "selector1=2s@50;selector2=500ms".
+                      The optional "@percent" is the percentage of the requests delayed, all of them by default. The selector may contain "*" wildcards,
+                      the first matching selector applies. Only the requests with the header of --fault_trigger_header are delayed.`)
+	FaultTriggerHeader = flag.String("fault_trigger_header", defaults.FaultTriggerHeader, `The header of the requests the faults of --operation_fault_abort and --operation_fault_delay are injected into,
+                      in the format of "name" to match any value, or "name=value" to match the value exactly. Required by the fault flags.`)
+
+	EnableResponseCache = flag.Bool("enable_response_cache", defaults.EnableResponseCache, `Enable the response cache in the memory of Envoy for the GET requests, as allowed by the Cache-Control headers of the responses.
+                      The requests with the Authorization header from the clients are not served from the cache. The cache is checked after the
+                      Service Control filter, before the gRPC transcoding and the backend authentication.`)
+	ResponseCacheMaxBodyBytes = flag.Int("response_cache_max_body_bytes", defaults.ResponseCacheMaxBodyBytes, `The largest response body cached by --enable_response_cache, 0 if unlimited.`)
+	OperationResponseCacheTtl = flag.String("operation_response_cache_ttl", defaults.OperationResponseCacheTtl, `The TTLs of the cached responses of the GET operations, in the format of "selector1=60s;selector2=0s".
+                      The TTL is added to the Cache-Control headers of the responses as s-maxage, so the "private" and "no-store" directives of the
+                      backend still prevent caching. 0s replaces the Cache-Control headers with "no-store" to disable the cache of the operation.
+                      The selector may contain "*" wildcards, the first matching selector applies. The cache is keyed by the URL only, so the
+                      operations requiring a JWT or an API key cannot have a TTL.`)
+
+	ResponseCompressionTypes         = flag.String("response_compression_types", defaults.ResponseCompressionTypes, `Comma separated response compression types in the order of preference, must be "gzip" or "br". Default is "gzip,br".`)
+	ResponseCompressionContentTypes  = flag.String("response_compression_content_types", defaults.ResponseCompressionContentTypes, `Comma separated content types of the responses to compress, such as "application/json,text/html". By default, the common text content types are compressed.`)
+	ResponseCompressionMinLength     = flag.Int("response_compression_min_length", defaults.ResponseCompressionMinLength, `The minimum length in bytes of the responses to compress. By default, the responses of at least 30 bytes are compressed.`)
+	ResponseCompressionGzipLevel     = flag.Int("response_compression_gzip_level", defaults.ResponseCompressionGzipLevel, `The gzip compression level, from 1 (best speed) to 9 (best compression). By default, the zlib default level is used.`)
+	ResponseCompressionBrotliQuality = flag.Int("response_compression_brotli_quality", defaults.ResponseCompressionBrotliQuality, `The brotli compression quality, from 0 (best speed) to 11 (best compression). By default, quality 3 is used.`)
+
 	ClientIPFromForwardedHeader = flag.Bool("client_ip_from_forwarded_header", defaults.ClientIPFromForwardedHeader, `If true, extract client ip from "forwarded" header. The default false.`)
 
 	// BackendClusterMaxRequests is the maximum active requests allowed in a backend cluster.
@@ -225,11 +696,18 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
 func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 	opts := options.ConfigGeneratorOptions{
 		CommonOptions:                                 commonflags.DefaultCommonOptionsFromFlags(),
+		DeploymentMode:                                *DeploymentMode,
 		BackendAddress:                                *BackendAddress,
 		EnableBackendAddressOverride:                  *EnableBackendAddressOverride,
+		BackendAddressOverrides:                       *BackendAddressOverrides,
+		BackendRuleInheritance:                        *BackendRuleInheritance,
+		BackendFailoverAddresses:                      *BackendFailoverAddresses,
+		BackendRegionalAddresses:                      *BackendRegionalAddresses,
 		AccessLog:                                     *AccessLog,
 		AccessLogFormat:                               *AccessLogFormat,
+		AccessLogConsumerSampleRates:                  *AccessLogConsumerSampleRates,
 		ComputePlatformOverride:                       *ComputePlatformOverride,
+		DeploymentLabels:                              *DeploymentLabels,
 		CorsAllowCredentials:                          *CorsAllowCredentials,
 		CorsAllowHeaders:                              *CorsAllowHeaders,
 		CorsAllowMethods:                              *CorsAllowMethods,
@@ -239,12 +717,46 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		CorsMaxAge:                                    *CorsMaxAge,
 		CorsPreset:                                    *CorsPreset,
 		CorsOperationDelimiter:                        *CorsOperationDelimiter,
+		CorsAllowUnmatchedPreflight:                   *CorsAllowUnmatchedPreflight,
+		OperationCorsAllowOrigin:                      *OperationCorsAllowOrigin,
+		OperationCorsAllowCredentials:                 *OperationCorsAllowCredentials,
+		OperationCorsExposeHeaders:                    *OperationCorsExposeHeaders,
 		BackendDnsLookupFamily:                        *BackendDnsLookupFamily,
+		StrictRouteAllowlist:                          *StrictRouteAllowlist,
+		PassthroughPathPrefixes:                       *PassthroughPathPrefixes,
+		DefaultBackendAddress:                         *DefaultBackendAddress,
+		TenantMappingFile:                             *TenantMappingFile,
+		TenantMappingMetadataAttribute:                *TenantMappingMetadataAttribute,
+		ConsumerBackends:                              *ConsumerBackends,
+		VersionBackends:                               *VersionBackends,
 		ClusterConnectTimeout:                         *ClusterConnectTimeout,
+		BackendClusterConnectTimeout:                  *BackendClusterConnectTimeout,
+		BackendTcpKeepaliveTime:                       *BackendTcpKeepaliveTime,
+		BackendTcpKeepaliveInterval:                   *BackendTcpKeepaliveInterval,
+		BackendTcpKeepaliveProbes:                     *BackendTcpKeepaliveProbes,
+		BackendHappyEyeballs:                          *BackendHappyEyeballs,
+		BackendHttp2KeepaliveInterval:                 *BackendHttp2KeepaliveInterval,
+		BackendHttp2KeepaliveTimeout:                  *BackendHttp2KeepaliveTimeout,
+		BackendHttp2KeepaliveIntervalJitter:           *BackendHttp2KeepaliveIntervalJitter,
+		BackendProtocolSelection:                      *BackendProtocolSelection,
 		StreamIdleTimeout:                             *StreamIdleTimeout,
+		DownstreamIdleTimeout:                         *DownstreamIdleTimeout,
+		DownstreamMaxConnectionDuration:               *DownstreamMaxConnectionDuration,
+		DownstreamDrainTimeout:                        *DownstreamDrainTimeout,
+		ServerHeader:                                  *ServerHeader,
+		ServerHeaderTransformation:                    *ServerHeaderTransformation,
+		ViaHeader:                                     *ViaHeader,
+		MergeDuplicateRoutes:                          *MergeDuplicateRoutes,
+		UpgradeTypes:                                  *UpgradeTypes,
+		OperationUpgradeTypes:                         *OperationUpgradeTypes,
+		StreamingDownloadBufferLimitBytes:             *StreamingDownloadBufferLimitBytes,
+		OperationStreamingDownloads:                   *OperationStreamingDownloads,
+		OperationUpgradeIdleTimeouts:                  *OperationUpgradeIdleTimeouts,
 		ListenerAddress:                               *ListenerAddress,
 		ServiceManagementURL:                          *ServiceManagementURL,
 		ServiceControlURL:                             *ServiceControlURL,
+		ServiceConsumerManagementURL:                  *ServiceConsumerManagementURL,
+		QuotaOverrideConsumers:                        *QuotaOverrideConsumers,
 		ListenerPort:                                  *ListenerPort,
 		Healthz:                                       *Healthz,
 		HealthCheckOperation:                          *HealthCheckOperation,
@@ -253,11 +765,39 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		HealthCheckGrpcBackendService:                 *HealthCheckGrpcBackendService,
 		HealthCheckGrpcBackendInterval:                *HealthCheckGrpcBackendInterval,
 		HealthCheckGrpcBackendNoTrafficInterval:       *HealthCheckGrpcBackendNoTrafficInterval,
+		HealthzCheckBackend:                           *HealthzCheckBackend,
+		HealthzBackendEvaluationWindow:                *HealthzBackendEvaluationWindow,
+		HealthzBackendConsecutiveFailures:             *HealthzBackendConsecutiveFailures,
+		BackendHealthCheckPath:                        *BackendHealthCheckPath,
+		BackendHealthCheckGrpc:                        *BackendHealthCheckGrpc,
+		BackendHealthCheckInterval:                    *BackendHealthCheckInterval,
+		BackendHealthCheckTimeout:                     *BackendHealthCheckTimeout,
+		BackendHealthCheckHealthyThreshold:            *BackendHealthCheckHealthyThreshold,
+		BackendHealthCheckUnhealthyThreshold:          *BackendHealthCheckUnhealthyThreshold,
+		BackendHealthCheckExpectedStatuses:            *BackendHealthCheckExpectedStatuses,
+		ReadinessPort:                                 *ReadinessPort,
+		StartupTimeout:                                *StartupTimeout,
+		RequireBackendDnsResolution:                   *RequireBackendDnsResolution,
+		SnapshotAdminPort:                             *SnapshotAdminPort,
+		MetadataStubFile:                              *MetadataStubFile,
+		MetadataStubPort:                              *MetadataStubPort,
+		SnapshotReplayFile:                            *SnapshotReplayFile,
+		GrpcHealthPort:                                *GrpcHealthPort,
+		EnableGrpcReflection:                          *EnableGrpcReflection,
+		ReadinessCheckBackend:                         *ReadinessCheckBackend,
+		ReadinessCheckTimeout:                         *ReadinessCheckTimeout,
+		ReadinessMaxConfigAge:                         *ReadinessMaxConfigAge,
+		ReadinessMaxRolloutFetchFailures:              *ReadinessMaxRolloutFetchFailures,
 		SslSidestreamClientRootCertsPath:              *SslSidestreamClientRootCertsPath,
 		SslBackendClientCertPath:                      *SslBackendClientCertPath,
 		SslBackendClientRootCertsPath:                 *SslBackendClientRootCertsPath,
 		SslBackendClientCipherSuites:                  *SslBackendClientCipherSuites,
 		SslServerCertPath:                             *SslServerCertPath,
+		HealthPort:                                    *HealthPort,
+		TcpProxyRoutes:                                *TcpProxyRoutes,
+		HttpRedirectPort:                              *HttpRedirectPort,
+		HttpRedirectResponseCode:                      *HttpRedirectResponseCode,
+		HttpsRedirectPort:                             *HttpsRedirectPort,
 		SslServerCipherSuites:                         *SslServerCipherSuites,
 		SslServerRootCertPath:                         *SslServerRootCertsPath,
 		SslMinimumProtocol:                            *SslMinimumProtocol,
@@ -269,10 +809,30 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		AddResponseHeaders:                            *AddResponseHeaders,
 		AppendResponseHeaders:                         *AppendResponseHeaders,
 		EnableOperationNameHeader:                     *EnableOperationNameHeader,
+		GrpcMetadata:                                  *GrpcMetadata,
+		EnableSecurityHeaders:                         *EnableSecurityHeaders,
+		SecurityHeaders:                               *SecurityHeaders,
+		OperationSecurityHeadersEnabled:               *OperationSecurityHeadersEnabled,
+		SuppressUpstreamServiceTime:                   *SuppressUpstreamServiceTime,
+		OperationResponseHeadersToRemove:              *OperationResponseHeadersToRemove,
+		EnableDeprecationHeaders:                      *EnableDeprecationHeaders,
+		OperationDeprecations:                         *OperationDeprecations,
 		ServiceAccountKey:                             *ServiceAccountKey,
+		EnableTokenService:                            *EnableTokenService,
+		ServiceManagementServiceAccountKey:            *ServiceManagementServiceAccountKey,
+		ServiceControlServiceAccountKey:               *ServiceControlServiceAccountKey,
+		BackendAuthServiceAccountKey:                  *BackendAuthServiceAccountKey,
+		BackendJwtAudienceTemplate:                    *BackendJwtAudienceTemplate,
+		OperationJwtAudienceTemplates:                 *OperationJwtAudienceTemplates,
 		TokenAgentPort:                                *TokenAgentPort,
 		EnableApplicationDefaultCredentials:           *EnableApplicationDefaultCredentials,
 		DisableOidcDiscovery:                          *DisableOidcDiscovery,
+		OidcDiscoveryTimeout:                          *OidcDiscoveryTimeout,
+		OidcDiscoveryMaxRedirects:                     *OidcDiscoveryMaxRedirects,
+		OidcDiscoveryNumRetries:                       *OidcDiscoveryNumRetries,
+		OidcDiscoveryRetryBackOffBaseInterval:         *OidcDiscoveryRetryBackOffBaseInterval,
+		OidcDiscoveryRetryBackOffMaxInterval:          *OidcDiscoveryRetryBackOffMaxInterval,
+		DisableHttpFetchCache:                         *DisableHttpFetchCache,
 		DependencyErrorBehavior:                       *DependencyErrorBehavior,
 		SkipJwtAuthnFilter:                            *SkipJwtAuthnFilter,
 		SkipServiceControlFilter:                      *SkipServiceControlFilter,
@@ -283,14 +843,26 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		LogResponseHeaders:                            *LogResponseHeaders,
 		MinStreamReportIntervalMs:                     *MinStreamReportIntervalMs,
 		SuppressEnvoyHeaders:                          *SuppressEnvoyHeaders,
+		PropagateRequestTimeout:                       *PropagateRequestTimeout,
 		UnderscoresInHeaders:                          *UnderscoresInHeaders,
+		HeadersWithUnderscoresAction:                  *HeadersWithUnderscoresAction,
+		MaxRequestHeadersCount:                        *MaxRequestHeadersCount,
+		MaxRequestHeadersKb:                           *MaxRequestHeadersKb,
+		HeaderKeyFormat:                               *HeaderKeyFormat,
 		NormalizePath:                                 *NormalizePath,
 		MergeSlashesInPath:                            *MergeSlashesInPath,
 		DisallowEscapedSlashesInPath:                  *DisallowEscapedSlashesInPath,
 		ServiceControlNetworkFailOpen:                 *ServiceControlNetworkFailOpen,
 		ServiceControlEnableApiKeyUidReporting:        *ServiceControlEnableApiKeyUidReporting,
+		ApiKeyFromBasicAuthUsername:                   *ApiKeyFromBasicAuthUsername,
+		ApiKeyBearerTokenPrefix:                       *ApiKeyBearerTokenPrefix,
 		EnableGrpcForHttp1:                            *EnableGrpcForHttp1,
+		EnableGrpcWeb:                                 *EnableGrpcWeb,
 		ConnectionBufferLimitBytes:                    *ConnectionBufferLimitBytes,
+		ListenerConnectionRateLimit:                   *ListenerConnectionRateLimit,
+		AllowedSourceRanges:                           *AllowedSourceRanges,
+		ClientIPRateLimitPerSecond:                    *ClientIPRateLimitPerSecond,
+		ClientIPRateLimitBurst:                        *ClientIPRateLimitBurst,
 		DisableJwksAsyncFetch:                         *DisableJwksAsyncFetch,
 		JwksAsyncFetchFastListener:                    *JwksAsyncFetchFastListener,
 		JwksCacheDurationInS:                          *JwksCacheDurationInS,
@@ -300,16 +872,30 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		JwtPadForwardPayloadHeader:                    *JwtPatForwardPayloadHeader,
 		JwtCacheSize:                                  *JwtCacheSize,
 		DisableJwtAudienceServiceNameCheck:            *DisableJwtAudienceServiceNameCheck,
+		JwksResolveAtStartup:                          *JwksResolveAtStartup,
+		JwksResolveTTL:                                *JwksResolveTTL,
 		BackendRetryOns:                               *BackendRetryOns,
 		BackendRetryNum:                               *BackendRetryNum,
 		BackendPerTryTimeout:                          *BackendPerTryTimeout,
 		BackendRetryOnStatusCodes:                     *BackendRetryOnStatusCodes,
+		BackendRetryBudgetPercent:                     *BackendRetryBudgetPercent,
+		BackendRetryBudgetMinConcurrency:              *BackendRetryBudgetMinConcurrency,
+		BackendSlowStartWindow:                        *BackendSlowStartWindow,
+		BackendSlowStartAggression:                    *BackendSlowStartAggression,
+		BackendHedgeGetRequests:                       *BackendHedgeGetRequests,
+		OperationHedgeDelays:                          *OperationHedgeDelays,
+		OperationRequestTimeouts:                      *OperationRequestTimeouts,
+		OperationPerTryTimeouts:                       *OperationPerTryTimeouts,
 		ScCheckTimeoutMs:                              *ScCheckTimeoutMs,
 		ScQuotaTimeoutMs:                              *ScQuotaTimeoutMs,
 		ScReportTimeoutMs:                             *ScReportTimeoutMs,
 		ScCheckRetries:                                *ScCheckRetries,
 		ScQuotaRetries:                                *ScQuotaRetries,
 		ScReportRetries:                               *ScReportRetries,
+		ScCompressReports:                             *ScCompressReports,
+		OperationQuotaMetricCosts:                     *OperationQuotaMetricCosts,
+		ConsumerMaxConcurrentRequests:                 *ConsumerMaxConcurrentRequests,
+		OperationConsumerMaxConcurrentRequests:        *OperationConsumerMaxConcurrentRequests,
 		BackendClusterMaxRequests:                     *BackendClusterMaxRequests,
 		TranscodingAlwaysPrintPrimitiveFields:         *TranscodingAlwaysPrintPrimitiveFields,
 		TranscodingAlwaysPrintEnumsAsInts:             *TranscodingAlwaysPrintEnumsAsInts,
@@ -320,14 +906,79 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		TranscodingQueryParametersDisableUnescapePlus: *TranscodingQueryParametersDisableUnescapePlus,
 		TranscodingMatchUnregisteredCustomVerb:        *TranscodingMatchUnregisteredCustomVerb,
 		TranscodingCaseInsensitiveEnumParsing:         *TranscodingCaseInsensitiveEnumParsing,
+		DisableTranscoding:                            *DisableTranscoding,
+		GrpcReflection:                                *GrpcReflection,
+		GrpcReflectionJwtProviders:                    *GrpcReflectionJwtProviders,
 		EnableResponseCompression:                     *EnableResponseCompression,
+		ResponseCompressionTypes:                      *ResponseCompressionTypes,
+		AllowedClientIps:                              *AllowedClientIps,
+		DeniedClientIps:                               *DeniedClientIps,
+		OperationAllowedClientIps:                     *OperationAllowedClientIps,
+		OperationDeniedClientIps:                      *OperationDeniedClientIps,
+		MaxRequestBytes:                               *MaxRequestBytes,
+		EnableAdaptiveConcurrency:                     *EnableAdaptiveConcurrency,
+		AdaptiveConcurrencySamplePercentile:           *AdaptiveConcurrencySamplePercentile,
+		AdaptiveConcurrencyMaxLimit:                   *AdaptiveConcurrencyMaxLimit,
+		AdaptiveConcurrencyUpdateInterval:             *AdaptiveConcurrencyUpdateInterval,
+		AdaptiveConcurrencyMinRttInterval:             *AdaptiveConcurrencyMinRttInterval,
+		AdaptiveConcurrencyMinRttRequestCount:         *AdaptiveConcurrencyMinRttRequestCount,
+		AdaptiveConcurrencyBufferPercent:              *AdaptiveConcurrencyBufferPercent,
+		OperationTapEnabled:                           *OperationTapEnabled,
+		TapOutputPathPrefix:                           *TapOutputPathPrefix,
+		TapAdminConfigId:                              *TapAdminConfigId,
+		TapMaxBufferedBytes:                           *TapMaxBufferedBytes,
+		OperationFaultAbort:                           *OperationFaultAbort,
+		OperationFaultDelay:                           *OperationFaultDelay,
+		FaultTriggerHeader:                            *FaultTriggerHeader,
+		EnableResponseCache:                           *EnableResponseCache,
+		ResponseCacheMaxBodyBytes:                     *ResponseCacheMaxBodyBytes,
+		OperationResponseCacheTtl:                     *OperationResponseCacheTtl,
+		ExtProcAddress:                                *ExtProcAddress,
+		ExtProcTimeout:                                *ExtProcTimeout,
+		ExtProcFailureModeAllow:                       *ExtProcFailureModeAllow,
+		ExtProcProcessingMode:                         *ExtProcProcessingMode,
+		OperationExtProcProcessingModes:               *OperationExtProcProcessingModes,
+		WasmFilterURI:                                 *WasmFilterURI,
+		WasmFilterSha256:                              *WasmFilterSha256,
+		WasmFilterConfig:                              *WasmFilterConfig,
+		WasmFilterBefore:                              *WasmFilterBefore,
+		HttpFilterOrder:                               *HttpFilterOrder,
+		OperationWasmFilterEnabled:                    *OperationWasmFilterEnabled,
+		LuaFilterScript:                               *LuaFilterScript,
+		LuaScripts:                                    *LuaScripts,
+		OperationLuaScripts:                           *OperationLuaScripts,
+		EnableRequestValidation:                       *EnableRequestValidation,
+		AdminReadOnlyPaths:                            *AdminReadOnlyPaths,
+		AdminPathPrefix:                               *AdminPathPrefix,
+		AdminTokenHeader:                              *AdminTokenHeader,
+		AdminTokenPath:                                *AdminTokenPath,
+		MaintenanceMode:                               *MaintenanceMode,
+		MaintenanceOperations:                         *MaintenanceOperations,
+		MaintenanceResponse:                           *MaintenanceResponse,
+		StaticPaths:                                   *StaticPaths,
+		RedirectRules:                                 *RedirectRules,
+		OperationMaxRequestBytes:                      *OperationMaxRequestBytes,
+		ResponseCompressionContentTypes:               *ResponseCompressionContentTypes,
+		ResponseCompressionMinLength:                  *ResponseCompressionMinLength,
+		ResponseCompressionGzipLevel:                  *ResponseCompressionGzipLevel,
+		ResponseCompressionBrotliQuality:              *ResponseCompressionBrotliQuality,
 		ClientIPFromForwardedHeader:                   *ClientIPFromForwardedHeader,
+		GrpcErrorStatusCodes:                          *GrpcErrorStatusCodes,
+		ErrorResponseTemplate:                         *ErrorResponseTemplate,
 
 		// These options are not for ESPv2 users. They are overridden internally.
 		APIAllowList:       []string{},
 		AllowDiscoveryAPIs: false,
 	}
 
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	if err := applyDeploymentMode(&opts, setFlags); err != nil {
+		glog.Exitf("%v", err)
+	}
+
 	glog.Infof("Config Generator options: %+v", opts)
 	return opts
 }
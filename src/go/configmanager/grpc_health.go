@@ -0,0 +1,37 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"google.golang.org/grpc/health"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// AdsServiceName is the gRPC service name of the Envoy ADS, checked by
+	// the gRPC health service.
+	AdsServiceName = "envoy.service.discovery.v3.AggregatedDiscoveryService"
+)
+
+// NewHealthServer creates the grpc.health.v1 service of the config manager.
+// Both the overall status and the ADS are SERVING, since the config manager is
+// created with the startup snapshot. Call Shutdown() on the returned server
+// to report NOT_SERVING when the config manager is stopping.
+func NewHealthServer() *health.Server {
+	s := health.NewServer()
+	s.SetServingStatus(AdsServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s
+}
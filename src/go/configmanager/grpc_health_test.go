@@ -0,0 +1,68 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewHealthServer(t *testing.T) {
+	s := NewHealthServer()
+
+	testData := []struct {
+		desc       string
+		service    string
+		shutdown   bool
+		wantStatus healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			desc:       "Overall status is serving",
+			service:    "",
+			wantStatus: healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			desc:       "ADS is serving",
+			service:    AdsServiceName,
+			wantStatus: healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			desc:       "ADS is not serving after shutdown",
+			service:    AdsServiceName,
+			shutdown:   true,
+			wantStatus: healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.shutdown {
+				s.Shutdown()
+			}
+
+			resp, err := s.Check(context.Background(), &healthpb.HealthCheckRequest{
+				Service: tc.service,
+			})
+			if err != nil {
+				t.Fatalf("Check() got error: %v", err)
+			}
+			if resp.GetStatus() != tc.wantStatus {
+				t.Errorf("want status %v, got %v", tc.wantStatus, resp.GetStatus())
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// jwksHostResolver resolves the hostnames of the JWKS providers for flag
+// --jwks_resolve_at_startup, and caches the addresses for --jwks_resolve_ttl.
+type jwksHostResolver struct {
+	ttl        time.Duration
+	lookupHost lookupHostFunc
+	now        func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*jwksResolvedHost
+}
+
+type jwksResolvedHost struct {
+	addresses  []string
+	resolvedAt time.Time
+}
+
+func newJwksHostResolver(ttl time.Duration, lookupHost lookupHostFunc) *jwksHostResolver {
+	return &jwksHostResolver{
+		ttl:        ttl,
+		lookupHost: lookupHost,
+		now:        time.Now,
+		hosts:      make(map[string]*jwksResolvedHost),
+	}
+}
+
+// resolve returns the IPv4 addresses of the hosts, resolving the ones not
+// cached or expired. If refresh is set, all the hosts are resolved again. The
+// last resolved addresses are kept if a host fails to be resolved again.
+func (r *jwksHostResolver) resolve(ctx context.Context, hosts []string, refresh bool) (map[string][]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	addresses := make(map[string][]string)
+	for _, host := range hosts {
+		cached, ok := r.hosts[host]
+		if ok && !refresh && r.now().Sub(cached.resolvedAt) < r.ttl {
+			addresses[host] = cached.addresses
+			continue
+		}
+
+		resolved, err := r.lookupIPv4(ctx, host)
+		if err != nil {
+			if !ok {
+				return nil, fmt.Errorf("fail to resolve JWKS host %q required by --jwks_resolve_at_startup: %v", host, err)
+			}
+			glog.Warningf("fail to refresh the addresses of JWKS host %q, keeping the last resolved %v: %v", host, cached.addresses, err)
+			addresses[host] = cached.addresses
+			continue
+		}
+
+		if ok && !reflect.DeepEqual(cached.addresses, resolved) {
+			glog.Infof("addresses of JWKS host %q changed from %v to %v", host, cached.addresses, resolved)
+		}
+		r.hosts[host] = &jwksResolvedHost{
+			addresses:  resolved,
+			resolvedAt: r.now(),
+		}
+		addresses[host] = resolved
+	}
+	return addresses, nil
+}
+
+// lookupIPv4 returns the sorted IPv4 addresses of the host, as the JWT
+// provider clusters only look up IPv4 addresses.
+func (r *jwksHostResolver) lookupIPv4(ctx context.Context, host string) ([]string, error) {
+	resolved, err := r.lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, address := range resolved {
+		if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no IPv4 address in %v", resolved)
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// jwksHostsFromServiceConfig returns the sorted hostnames of the JWKS URIs
+// and the URL issuers of the authentication providers. The IP addresses are
+// skipped.
+func jwksHostsFromServiceConfig(serviceConfig *confpb.Service) []string {
+	hostSet := make(map[string]bool)
+	addHost := func(uri string) {
+		_, hostname, _, _, err := util.ParseURI(uri)
+		if err != nil || hostname == "" || net.ParseIP(hostname) != nil {
+			return
+		}
+		hostSet[hostname] = true
+	}
+
+	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		if provider.GetJwksUri() != "" {
+			addHost(provider.GetJwksUri())
+		}
+		// The issuers of the email format, such as the service accounts, are
+		// not hosts.
+		if issuer := provider.GetIssuer(); strings.HasPrefix(issuer, "https://") || strings.HasPrefix(issuer, "http://") {
+			addHost(issuer)
+		}
+	}
+
+	var hosts []string
+	for host := range hostSet {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// resolveJwksHosts resolves the JWKS hosts of the service config, for the
+// options of the config generator.
+func (m *ConfigManager) resolveJwksHosts(serviceConfig *confpb.Service) (map[string][]string, error) {
+	return m.jwksResolver.resolve(context.Background(), jwksHostsFromServiceConfig(serviceConfig), false)
+}
+
+// setJwksResolveRefreshTimer resolves the JWKS hosts again every
+// --jwks_resolve_ttl, and re-applies the current service config whenever the
+// addresses change.
+func (m *ConfigManager) setJwksResolveRefreshTimer(ttl time.Duration) {
+	go func() {
+		glog.Infof("start refreshing the addresses of JWKS hosts every %v", ttl)
+		ticker := time.NewTicker(ttl)
+
+		for range ticker.C {
+			m.mu.Lock()
+			serviceConfig := m.curServiceConfig
+			lastAddresses := m.envoyConfigOptions.JwksResolvedAddresses
+			m.mu.Unlock()
+			if serviceConfig == nil {
+				continue
+			}
+
+			addresses, err := m.jwksResolver.resolve(context.Background(), jwksHostsFromServiceConfig(serviceConfig), true)
+			if err != nil {
+				glog.Errorf("error occurred when refreshing the addresses of JWKS hosts, %v", err)
+				continue
+			}
+			if reflect.DeepEqual(addresses, lastAddresses) {
+				continue
+			}
+
+			m.mu.Lock()
+			m.jwksResolveVersion++
+			m.mu.Unlock()
+			if err := m.applyServiceConfig(serviceConfig); err != nil {
+				glog.Errorf("error occurred when applying new addresses of JWKS hosts, %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestJwksHostsFromServiceConfig(t *testing.T) {
+	serviceConfig := &confpb.Service{
+		Authentication: &confpb.Authentication{
+			Providers: []*confpb.AuthProvider{
+				{
+					Id:      "google_service_account",
+					Issuer:  "service-account@project.iam.gserviceaccount.com",
+					JwksUri: "https://www.googleapis.com/service_accounts/v1/jwk/service-account@project.iam.gserviceaccount.com",
+				},
+				{
+					Id:     "openid",
+					Issuer: "https://accounts.google.com",
+				},
+				{
+					Id:      "ip",
+					Issuer:  "issuer",
+					JwksUri: "http://10.0.0.1:8080/pkey",
+				},
+				{
+					Id:      "duplicated",
+					Issuer:  "https://www.googleapis.com",
+					JwksUri: "https://www.googleapis.com/oauth2/v3/certs",
+				},
+			},
+		},
+	}
+
+	want := []string{"accounts.google.com", "www.googleapis.com"}
+	if diff := cmp.Diff(want, jwksHostsFromServiceConfig(serviceConfig)); diff != "" {
+		t.Errorf("jwksHostsFromServiceConfig() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestJwksHostResolver(t *testing.T) {
+	lookupResults := map[string][]string{
+		"jwks.com": {"10.0.0.2", "2001:db8::1", "10.0.0.1"},
+		"ipv6.com": {"2001:db8::1"},
+	}
+	lookupCount := 0
+	lookupHost := func(ctx context.Context, host string) ([]string, error) {
+		lookupCount++
+		if addresses, ok := lookupResults[host]; ok {
+			return addresses, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+
+	now := time.Unix(0, 0)
+	r := newJwksHostResolver(time.Minute, lookupHost)
+	r.now = func() time.Time { return now }
+
+	got, err := r.resolve(context.Background(), []string{"jwks.com"}, false)
+	if err != nil {
+		t.Fatalf("resolve() got error %v", err)
+	}
+	want := map[string][]string{"jwks.com": {"10.0.0.1", "10.0.0.2"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("resolve() diff (-want +got):\n%s", diff)
+	}
+
+	// The cached addresses are used within the TTL.
+	lookupResults["jwks.com"] = []string{"10.0.0.3"}
+	now = now.Add(30 * time.Second)
+	if got, _ := r.resolve(context.Background(), []string{"jwks.com"}, false); cmp.Diff(want, got) != "" || lookupCount != 1 {
+		t.Errorf("resolve() within TTL got %v with %d lookups, want cached %v", got, lookupCount, want)
+	}
+
+	// The addresses are resolved again once expired.
+	now = now.Add(time.Minute)
+	want = map[string][]string{"jwks.com": {"10.0.0.3"}}
+	if got, _ := r.resolve(context.Background(), []string{"jwks.com"}, false); cmp.Diff(want, got) != "" {
+		t.Errorf("resolve() after TTL got %v, want %v", got, want)
+	}
+
+	// The last resolved addresses are kept if the refresh fails.
+	delete(lookupResults, "jwks.com")
+	if got, err := r.resolve(context.Background(), []string{"jwks.com"}, true); err != nil || cmp.Diff(want, got) != "" {
+		t.Errorf("resolve() failing refresh got %v, %v, want %v", got, err, want)
+	}
+
+	// The hosts never resolved fail.
+	for _, host := range []string{"unknown.com", "ipv6.com"} {
+		if _, err := r.resolve(context.Background(), []string{host}, false); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("fail to resolve JWKS host %q", host)) {
+			t.Errorf("resolve(%q) got error %v, want resolution failure", host, err)
+		}
+	}
+}
@@ -27,12 +27,16 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configmanager"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configmanager/flags"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/metadata"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/tokengenerator"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/golang/glog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 
 	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	xds "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
@@ -43,13 +47,36 @@ func main() {
 	// Allows shutting down downstream servers gracefully.
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if opts.MetadataStubFile != "" {
+		if opts.NonGCP {
+			glog.Exitf("flag --metadata_stub_file cannot be used with flag --non_gcp")
+		}
+		values, err := metadata.ReadStubFile(opts.MetadataStubFile)
+		if err != nil {
+			glog.Exitf("fail to initialize metadata server stub: %v", err)
+		}
+
+		// Listen before the metadata fetches, only reachable from the same host.
+		stubLis, err := net.Listen("tcp", fmt.Sprintf("%s:%v", util.LoopbackIPv4Addr, opts.MetadataStubPort))
+		if err != nil {
+			glog.Exitf("metadata server stub failed to listen: %v", err)
+		}
+		go func() {
+			if err := http.Serve(stubLis, metadata.MakeStubHandler(values)); err != nil {
+				glog.Errorf("metadata server stub fail to serve: %v", err)
+			}
+		}()
+		opts.MetadataURL = fmt.Sprintf("http://%s", stubLis.Addr())
+		glog.Infof("serving metadata server stub of %s at %s", opts.MetadataStubFile, opts.MetadataURL)
+	}
+
 	var mf *metadata.MetadataFetcher
 	if !opts.NonGCP {
 		glog.Info("running on GCP, initializing metadata fetcher")
 		mf = metadata.NewMetadataFetcher(opts.CommonOptions)
 	}
 
-	m, err := configmanager.NewConfigManager(mf, opts)
+	m, err := configmanager.StartConfigManager(mf, opts)
 	if err != nil {
 		glog.Exitf("fail to initialize config manager: %v", err)
 	}
@@ -63,6 +90,31 @@ func main() {
 	// Register Envoy discovery services.
 	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, server)
 
+	// Register the health and reflection services for the orchestration and
+	// debugging tools.
+	healthServer := configmanager.NewHealthServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if opts.EnableGrpcReflection {
+		reflection.Register(grpcServer)
+	}
+
+	var healthGrpcServer *grpc.Server
+	if opts.GrpcHealthPort != 0 {
+		// Only the health service is served over TCP, the ADS stays on the
+		// named pipe.
+		healthLis, err := net.Listen("tcp", fmt.Sprintf(":%v", opts.GrpcHealthPort))
+		if err != nil {
+			glog.Exitf("gRPC health server failed to listen: %v", err)
+		}
+		healthGrpcServer = grpc.NewServer()
+		healthpb.RegisterHealthServer(healthGrpcServer, healthServer)
+		go func() {
+			if err := healthGrpcServer.Serve(healthLis); err != nil {
+				glog.Errorf("gRPC health server fail to serve: %v", err)
+			}
+		}()
+	}
+
 	glog.Infof("config manager server is running at %s .......\n", lis.Addr())
 
 	// Handle signals gracefully
@@ -72,15 +124,39 @@ func main() {
 	go func() {
 		sig := <-signalChan
 		glog.Warningf("Server got signal %v, stopping", sig)
+		healthServer.Shutdown()
 		cancel()
 		grpcServer.Stop()
+		if healthGrpcServer != nil {
+			healthGrpcServer.Stop()
+		}
 	}()
 
-	if opts.ServiceAccountKey != "" {
+	var tokenService *tokengenerator.TokenService
+	if opts.EnableTokenService {
+		if opts.ServiceAccountKey != "" {
+			tokenService = tokengenerator.NewTokenServiceFromFile(opts.ServiceAccountKey)
+		} else if mf != nil {
+			tokenService = &tokengenerator.TokenService{
+				AccessToken:   mf.FetchAccessToken,
+				IdentityToken: mf.FetchIdentityJWTToken,
+			}
+		} else {
+			glog.Exitf("flag --enable_token_service requires flag --service_account_key if flag --non_gcp is specified")
+		}
+	}
+
+	serviceControlKey, backendAuthKey := options.ServiceControlServiceAccountKey(opts), options.BackendAuthServiceAccountKey(opts)
+	if serviceControlKey != "" || backendAuthKey != "" || tokenService != nil {
 		// Setup token agent server
-		r := tokengenerator.MakeTokenAgentHandler(opts.ServiceAccountKey)
+		r := tokengenerator.MakeTokenAgentHandler(serviceControlKey, backendAuthKey, tokenService)
+		addr := fmt.Sprintf(":%v", opts.TokenAgentPort)
+		if tokenService != nil {
+			// The tokens are only served to the containers sharing the pod network.
+			addr = fmt.Sprintf("127.0.0.1:%v", opts.TokenAgentPort)
+		}
 		go func() {
-			err := http.ListenAndServe(fmt.Sprintf(":%v", opts.TokenAgentPort), r)
+			err := http.ListenAndServe(addr, r)
 
 			if err != nil {
 				glog.Errorf("token agent fail to serve: %v", err)
@@ -90,6 +166,31 @@ func main() {
 
 	}
 
+	if opts.ReadinessPort != 0 {
+		// Setup readiness server
+		m.SetJwksReadinessRefreshTimer()
+		r := m.MakeReadinessHandler()
+		go func() {
+			err := http.ListenAndServe(fmt.Sprintf(":%v", opts.ReadinessPort), r)
+
+			if err != nil {
+				glog.Errorf("readiness server fail to serve: %v", err)
+			}
+		}()
+	}
+
+	if opts.SnapshotAdminPort != 0 {
+		// Setup snapshot admin server, only reachable from the same host.
+		r := m.MakeSnapshotAdminHandler()
+		go func() {
+			err := http.ListenAndServe(fmt.Sprintf("127.0.0.1:%v", opts.SnapshotAdminPort), r)
+
+			if err != nil {
+				glog.Errorf("snapshot admin server fail to serve: %v", err)
+			}
+		}()
+	}
+
 	if err := grpcServer.Serve(lis); err != nil {
 		glog.Exitf("Server fail to serve: %v", err)
 	}
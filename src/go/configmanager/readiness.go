@@ -0,0 +1,404 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+const (
+	readinessCheckSnapshot = "snapshot"
+	readinessCheckJwks     = "jwks"
+	readinessCheckBackend  = "backend"
+	readinessCheckConfig   = "config"
+)
+
+// ReadinessCheck is the result of a single dependency check.
+type ReadinessCheck struct {
+	Name    string `json:"name"`
+	Target  string `json:"target,omitempty"`
+	Ready   bool   `json:"ready"`
+	Cached  bool   `json:"cached,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReadinessStatus is the JSON payload returned by the readiness endpoint.
+type ReadinessStatus struct {
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// jwksReadiness holds the results of the JWKS checks. The JWKS are fetched
+// in the background every --jwks_cache_duration_in_s, the interval Envoy
+// refetches them, so the readiness probes do not send requests to the
+// identity providers.
+type jwksReadiness struct {
+	mu sync.Mutex
+	// results are the last checks of the providers, valid once checked is
+	// set.
+	results []ReadinessCheck
+	checked bool
+	// lastFetched is the last successful fetch of each JWKS uri, so a
+	// transient outage of a provider does not flip readiness while Envoy
+	// still holds a valid copy of the keys.
+	lastFetched map[string]time.Time
+	// refresh requests an immediate refresh, such as when the service config
+	// changes. It is guarded by the mutex of ConfigManager.
+	refresh chan struct{}
+}
+
+func (c *jwksReadiness) setFetched(uri string, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastFetched == nil {
+		c.lastFetched = make(map[string]time.Time)
+	}
+	c.lastFetched[uri] = t
+}
+
+func (c *jwksReadiness) getFetched(uri string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.lastFetched[uri]
+	return t, ok
+}
+
+func (c *jwksReadiness) setResults(results []ReadinessCheck) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = results
+	c.checked = true
+}
+
+func (c *jwksReadiness) getResults() ([]ReadinessCheck, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ReadinessCheck(nil), c.results...), c.checked
+}
+
+// requestRefresh asks the background loop to refresh the checks, without
+// blocking if a refresh is already pending.
+func (c *jwksReadiness) requestRefresh() {
+	if c.refresh == nil {
+		return
+	}
+	select {
+	case c.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// configFreshness tracks the checks of the latest rollout by the managed
+// rollout strategy, so the config managers serving a stale service config
+// fail readiness.
+type configFreshness struct {
+	mu                  sync.Mutex
+	lastChecked         time.Time
+	consecutiveFailures int
+	// applyFailed is set if the service config of the latest rollout fails to
+	// be fetched or applied, so the served one is stale.
+	applyFailed bool
+}
+
+func (c *configFreshness) applied(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyFailed = err != nil
+}
+
+func (c *configFreshness) checked(err error, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || c.applyFailed {
+		c.consecutiveFailures++
+		return
+	}
+	c.lastChecked = t
+	c.consecutiveFailures = 0
+}
+
+func (c *configFreshness) get() (time.Time, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastChecked, c.consecutiveFailures
+}
+
+// CheckReadiness runs all the dependency checks and returns their results.
+func (m *ConfigManager) CheckReadiness() *ReadinessStatus {
+	status := &ReadinessStatus{
+		Ready: true,
+	}
+
+	status.Checks = append(status.Checks, m.checkSnapshot())
+	status.Checks = append(status.Checks, m.checkJwksProviders()...)
+	if m.envoyConfigOptions.ReadinessCheckBackend {
+		status.Checks = append(status.Checks, m.checkBackend())
+	}
+	if check, ok := m.checkConfigFreshness(); ok {
+		status.Checks = append(status.Checks, check)
+	}
+
+	for _, check := range status.Checks {
+		if !check.Ready {
+			status.Ready = false
+		}
+	}
+	return status
+}
+
+func (m *ConfigManager) checkSnapshot() ReadinessCheck {
+	check := ReadinessCheck{
+		Name:   readinessCheckSnapshot,
+		Target: m.envoyConfigOptions.Node,
+	}
+
+	snapshot, err := m.cache.GetSnapshot(m.envoyConfigOptions.Node)
+	if err != nil {
+		check.Message = fmt.Sprintf("snapshot is not loaded: %v", err)
+		return check
+	}
+
+	check.Ready = true
+	check.Message = fmt.Sprintf("snapshot version %q is loaded", snapshot.GetVersion(rsrc.ListenerType))
+	return check
+}
+
+// checkJwksProviders returns the JWKS checks of the last background refresh,
+// without fetching them.
+func (m *ConfigManager) checkJwksProviders() []ReadinessCheck {
+	checks, ok := m.jwksReadiness.getResults()
+	if !ok {
+		return []ReadinessCheck{
+			{
+				Name:    readinessCheckJwks,
+				Message: "jwks of the authentication providers are not checked yet",
+			},
+		}
+	}
+	return checks
+}
+
+// authProviders returns the authentication providers of the current service
+// config.
+func (m *ConfigManager) authProviders() []*confpb.AuthProvider {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.curServiceConfig.GetAuthentication().GetProviders()
+}
+
+// SetJwksReadinessRefreshTimer fetches the JWKS of the authentication
+// providers for the readiness endpoint, then again every
+// --jwks_cache_duration_in_s and whenever the service config changes.
+func (m *ConfigManager) SetJwksReadinessRefreshTimer() {
+	interval := time.Duration(m.envoyConfigOptions.JwksCacheDurationInS) * time.Second
+	if interval <= 0 {
+		interval = time.Duration(options.DefaultConfigGeneratorOptions().JwksCacheDurationInS) * time.Second
+	}
+
+	m.mu.Lock()
+	if m.jwksReadiness.refresh == nil {
+		m.jwksReadiness.refresh = make(chan struct{}, 1)
+	}
+	refresh := m.jwksReadiness.refresh
+	m.mu.Unlock()
+
+	go func() {
+		glog.Infof("start checking the jwks of the authentication providers every %v", interval)
+		ticker := time.NewTicker(interval)
+		for {
+			m.refreshJwksReadiness()
+			select {
+			case <-ticker.C:
+			case <-refresh:
+			}
+		}
+	}()
+}
+
+// refreshJwksReadiness fetches the JWKS of the authentication providers and
+// caches the checks for the readiness endpoint.
+func (m *ConfigManager) refreshJwksReadiness() {
+	var results []ReadinessCheck
+	for _, provider := range m.authProviders() {
+		results = append(results, m.checkJwksProvider(provider))
+	}
+	m.jwksReadiness.setResults(results)
+}
+
+func (m *ConfigManager) checkJwksProvider(provider *confpb.AuthProvider) ReadinessCheck {
+	check := ReadinessCheck{
+		Name:   readinessCheckJwks,
+		Target: provider.GetId(),
+	}
+
+	jwksUri := provider.GetJwksUri()
+	if jwksUri == "" {
+		if m.envoyConfigOptions.DisableOidcDiscovery {
+			check.Message = "jwks_uri is empty and OpenID Connect Discovery is disabled"
+			return check
+		}
+
+		var err error
+		jwksUri, err = util.ResolveJwksUriUsingOpenID(provider.GetIssuer(), options.OidcDiscoveryFetchOptions(m.envoyConfigOptions))
+		if err != nil {
+			check.Message = fmt.Sprintf("fail to resolve jwks_uri: %v", err)
+			return check
+		}
+	}
+
+	if err := m.fetchJwks(jwksUri); err != nil {
+		lastFetched, ok := m.jwksReadiness.getFetched(jwksUri)
+		cacheDuration := time.Duration(m.envoyConfigOptions.JwksCacheDurationInS) * time.Second
+		if ok && time.Since(lastFetched) < cacheDuration {
+			check.Ready = true
+			check.Cached = true
+			check.Message = fmt.Sprintf("fail to fetch %s, using the copy fetched at %v: %v", jwksUri, lastFetched.Format(time.RFC3339), err)
+		} else {
+			check.Message = fmt.Sprintf("fail to fetch %s: %v", jwksUri, err)
+		}
+		return check
+	}
+
+	m.jwksReadiness.setFetched(jwksUri, time.Now())
+	check.Ready = true
+	return check
+}
+
+func (m *ConfigManager) fetchJwks(jwksUri string) error {
+	// The checks are refreshed periodically, so the failed fetches are not
+	// retried.
+	body, err := util.FetchRemoteContent(jwksUri, util.RemoteContentOptions{
+		Timeout:      m.envoyConfigOptions.ReadinessCheckTimeout,
+		MaxRedirects: m.envoyConfigOptions.OidcDiscoveryMaxRedirects,
+	})
+	if err != nil {
+		return err
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("jwks is not a valid json")
+	}
+	return nil
+}
+
+func (m *ConfigManager) checkBackend() ReadinessCheck {
+	backendAddress := m.envoyConfigOptions.BackendAddress
+	check := ReadinessCheck{
+		Name:   readinessCheckBackend,
+		Target: backendAddress,
+	}
+
+	network, address := "tcp", ""
+	if strings.HasPrefix(backendAddress, "unix://") {
+		network, address = "unix", strings.TrimPrefix(backendAddress, "unix://")
+	} else {
+		var err error
+		address, err = util.ExtractAddressFromURI(backendAddress)
+		if err != nil {
+			check.Message = err.Error()
+			return check
+		}
+	}
+
+	conn, err := net.DialTimeout(network, address, m.envoyConfigOptions.ReadinessCheckTimeout)
+	if err != nil {
+		check.Message = fmt.Sprintf("fail to connect to backend: %v", err)
+		return check
+	}
+	_ = conn.Close()
+
+	check.Ready = true
+	return check
+}
+
+// checkConfigFreshness checks the served service config against flags
+// --readiness_max_config_age and --readiness_max_rollout_fetch_failures. It
+// returns false if neither is set, or the config is not checked by the managed
+// rollout strategy.
+func (m *ConfigManager) checkConfigFreshness() (ReadinessCheck, bool) {
+	maxAge := m.envoyConfigOptions.ReadinessMaxConfigAge
+	maxFailures := m.envoyConfigOptions.ReadinessMaxRolloutFetchFailures
+	lastChecked, failures := m.configFreshness.get()
+	if (maxAge <= 0 && maxFailures <= 0) || lastChecked.IsZero() {
+		return ReadinessCheck{}, false
+	}
+
+	check := ReadinessCheck{
+		Name:   readinessCheckConfig,
+		Target: m.curConfigId(),
+	}
+
+	age := time.Since(lastChecked).Round(time.Second)
+	if maxAge > 0 && age > maxAge {
+		check.Message = fmt.Sprintf("service config was last confirmed as the latest rollout %v ago, exceeding --readiness_max_config_age %v", age, maxAge)
+		return check, true
+	}
+	if maxFailures > 0 && failures >= maxFailures {
+		check.Message = fmt.Sprintf("fetching the latest rollout failed %d consecutive times, reaching --readiness_max_rollout_fetch_failures %d", failures, maxFailures)
+		return check, true
+	}
+
+	check.Ready = true
+	return check, true
+}
+
+// MakeReadinessHandler creates the readiness handler for Kubernetes readiness
+// probes.
+//
+// It follows the following scheme:
+// Request: GET /ready.
+// Response: 200 if all the checks pass, otherwise 503, with a JSON payload
+// in the format of ReadinessStatus.
+func (m *ConfigManager) MakeReadinessHandler() http.Handler {
+	r := mux.NewRouter()
+
+	r.Path(util.ReadinessPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := m.CheckReadiness()
+
+		body, err := json.Marshal(status)
+		if err != nil {
+			glog.Errorf("readiness endpoint fail to marshal status: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !status.Ready {
+			glog.Warningf("readiness check failed: %s", body)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if status.Ready {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write(body)
+	})
+
+	return r
+}
@@ -0,0 +1,452 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/google/go-cmp/cmp"
+
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys": []}`))
+	}))
+	defer jwksServer.Close()
+
+	brokenJwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer brokenJwksServer.Close()
+
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fail to listen: %v", err)
+	}
+	defer backend.Close()
+
+	closedBackend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fail to listen: %v", err)
+	}
+	closedBackendAddress := closedBackend.Addr().String()
+	closedBackend.Close()
+
+	testCases := []struct {
+		desc           string
+		skipSnapshot   bool
+		skipJwksCheck  bool
+		jwksUri        string
+		cachedJwks     bool
+		checkBackend   bool
+		backendAddress string
+		// Config freshness checked by the managed rollout strategy.
+		maxConfigAge            time.Duration
+		maxRolloutFetchFailures int
+		configCheckedAgo        time.Duration
+		rolloutFetchFailures    int
+		wantStatusCode          int
+		wantReadiness           []ReadinessCheck
+		wantReadyStatus         bool
+	}{
+		{
+			desc:           "snapshot is not loaded",
+			skipSnapshot:   true,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Message: "snapshot is not loaded: no snapshot found for node test-node",
+				},
+			},
+		},
+		{
+			desc:            "snapshot is loaded, no auth providers",
+			wantStatusCode:  http.StatusOK,
+			wantReadyStatus: true,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+			},
+		},
+		{
+			desc:           "jwks are not checked yet",
+			jwksUri:        jwksServer.URL,
+			skipJwksCheck:  true,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:    readinessCheckJwks,
+					Message: "jwks of the authentication providers are not checked yet",
+				},
+			},
+		},
+		{
+			desc:            "jwks provider is reachable",
+			jwksUri:         jwksServer.URL,
+			wantStatusCode:  http.StatusOK,
+			wantReadyStatus: true,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:   readinessCheckJwks,
+					Target: "test-provider",
+					Ready:  true,
+				},
+			},
+		},
+		{
+			desc:           "jwks provider is not reachable",
+			jwksUri:        brokenJwksServer.URL,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:    readinessCheckJwks,
+					Target:  "test-provider",
+					Message: "fail to fetch " + brokenJwksServer.URL + ": fetching " + brokenJwksServer.URL + " returns not 200 OK: 500 Internal Server Error",
+				},
+			},
+		},
+		{
+			desc:            "jwks provider is not reachable, but jwks is cached",
+			jwksUri:         brokenJwksServer.URL,
+			cachedJwks:      true,
+			wantStatusCode:  http.StatusOK,
+			wantReadyStatus: true,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:   readinessCheckJwks,
+					Target: "test-provider",
+					Ready:  true,
+					Cached: true,
+				},
+			},
+		},
+		{
+			desc:            "backend is connectable",
+			checkBackend:    true,
+			backendAddress:  "http://" + backend.Addr().String(),
+			wantStatusCode:  http.StatusOK,
+			wantReadyStatus: true,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:   readinessCheckBackend,
+					Target: "http://" + backend.Addr().String(),
+					Ready:  true,
+				},
+			},
+		},
+		{
+			desc:           "backend is not connectable",
+			checkBackend:   true,
+			backendAddress: "http://" + closedBackendAddress,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:   readinessCheckBackend,
+					Target: "http://" + closedBackendAddress,
+				},
+			},
+		},
+		{
+			desc:            "config freshness is not checked without managed rollout",
+			maxConfigAge:    time.Minute,
+			wantStatusCode:  http.StatusOK,
+			wantReadyStatus: true,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+			},
+		},
+		{
+			desc:                    "config is fresh",
+			maxConfigAge:            time.Minute,
+			maxRolloutFetchFailures: 3,
+			configCheckedAgo:        10 * time.Second,
+			rolloutFetchFailures:    2,
+			wantStatusCode:          http.StatusOK,
+			wantReadyStatus:         true,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:   readinessCheckConfig,
+					Target: "test-config-id",
+					Ready:  true,
+				},
+			},
+		},
+		{
+			desc:             "config is older than the max age",
+			maxConfigAge:     time.Minute,
+			configCheckedAgo: 2 * time.Minute,
+			wantStatusCode:   http.StatusServiceUnavailable,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:    readinessCheckConfig,
+					Target:  "test-config-id",
+					Message: "service config was last confirmed as the latest rollout 2m0s ago, exceeding --readiness_max_config_age 1m0s",
+				},
+			},
+		},
+		{
+			desc:                    "rollout fetches fail too many times",
+			maxRolloutFetchFailures: 3,
+			configCheckedAgo:        10 * time.Second,
+			rolloutFetchFailures:    3,
+			wantStatusCode:          http.StatusServiceUnavailable,
+			wantReadiness: []ReadinessCheck{
+				{
+					Name:    readinessCheckSnapshot,
+					Target:  "test-node",
+					Ready:   true,
+					Message: `snapshot version "test-config-id" is loaded`,
+				},
+				{
+					Name:    readinessCheckConfig,
+					Target:  "test-config-id",
+					Message: "fetching the latest rollout failed 3 consecutive times, reaching --readiness_max_rollout_fetch_failures 3",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.Node = "test-node"
+			opts.ReadinessCheckTimeout = time.Second
+			opts.ReadinessCheckBackend = tc.checkBackend
+			opts.BackendAddress = tc.backendAddress
+			opts.ReadinessMaxConfigAge = tc.maxConfigAge
+			opts.ReadinessMaxRolloutFetchFailures = tc.maxRolloutFetchFailures
+
+			m := &ConfigManager{
+				envoyConfigOptions: opts,
+				curServiceConfig: &confpb.Service{
+					Id: "test-config-id",
+				},
+			}
+			m.cache = cache.NewSnapshotCache(true, m, m)
+
+			if tc.jwksUri != "" {
+				m.curServiceConfig.Authentication = &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "test-provider",
+							JwksUri: tc.jwksUri,
+						},
+					},
+				}
+			}
+			if tc.cachedJwks {
+				m.jwksReadiness.setFetched(tc.jwksUri, time.Now())
+			}
+			if !tc.skipJwksCheck {
+				m.refreshJwksReadiness()
+			}
+			if tc.configCheckedAgo > 0 {
+				m.configFreshness.checked(nil, time.Now().Add(-tc.configCheckedAgo))
+			}
+			for i := 0; i < tc.rolloutFetchFailures; i++ {
+				m.configFreshness.checked(fmt.Errorf("fail to fetch new rollout id"), time.Now())
+			}
+
+			if !tc.skipSnapshot {
+				snapshot, err := cache.NewSnapshot(m.curConfigId(), map[rsrc.Type][]types.Resource{
+					rsrc.ListenerType: {},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := m.cache.SetSnapshot(context.Background(), opts.Node, snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			resp := httptest.NewRecorder()
+			m.MakeReadinessHandler().ServeHTTP(resp, httptest.NewRequest("GET", util.ReadinessPath, nil))
+
+			if resp.Code != tc.wantStatusCode {
+				t.Errorf("got status code %v, want %v", resp.Code, tc.wantStatusCode)
+			}
+
+			var got ReadinessStatus
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Fatalf("fail to unmarshal response body %s: %v", resp.Body.String(), err)
+			}
+			if got.Ready != tc.wantReadyStatus {
+				t.Errorf("got ready %v, want %v", got.Ready, tc.wantReadyStatus)
+			}
+
+			// Messages of the failed backend and cached jwks checks contain
+			// timestamps or OS specific errors, only compare their presence.
+			for i := range got.Checks {
+				if (got.Checks[i].Name == readinessCheckBackend && !got.Checks[i].Ready) || got.Checks[i].Cached {
+					if got.Checks[i].Message == "" {
+						t.Errorf("check %v should have a message", got.Checks[i].Name)
+					}
+					got.Checks[i].Message = ""
+				}
+			}
+			if diff := cmp.Diff(tc.wantReadiness, got.Checks); diff != "" {
+				t.Errorf("readiness checks diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// Run with -race, the readiness handler reads the config id while the
+// rollouts apply new service configs.
+func TestReadinessHandlerDuringRollout(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "http://127.0.0.1:8082"
+	opts.SkipServiceControlFilter = true
+	opts.TracingOptions = &options.TracingOptions{
+		DisableTracing: true,
+	}
+	opts.ReadinessMaxConfigAge = time.Minute
+
+	m := &ConfigManager{
+		envoyConfigOptions: opts,
+	}
+	m.cache = cache.NewSnapshotCache(true, m, m)
+	m.configFreshness.checked(nil, time.Now())
+
+	const rollouts = 20
+	serviceConfig := func(i int) *confpb.Service {
+		return &confpb.Service{
+			Name: "bookstore.endpoints.project123.cloud.goog",
+			Id:   fmt.Sprintf("2023-01-01r%d", i),
+			Apis: []*apipb.Api{
+				{
+					Name: "endpoints.examples.bookstore.Bookstore",
+					Methods: []*apipb.Method{
+						{
+							Name: "Echo",
+						},
+					},
+				},
+			},
+		}
+	}
+	if err := m.applyServiceConfig(serviceConfig(0)); err != nil {
+		t.Fatalf("applyServiceConfig() got error: %v", err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			resp := httptest.NewRecorder()
+			m.MakeReadinessHandler().ServeHTTP(resp, httptest.NewRequest("GET", util.ReadinessPath, nil))
+			if resp.Code != http.StatusOK {
+				t.Errorf("got status code %v during the rollout, want %v: %s", resp.Code, http.StatusOK, resp.Body.String())
+				return
+			}
+		}
+	}()
+
+	for i := 1; i < rollouts; i++ {
+		if err := m.applyServiceConfig(serviceConfig(i)); err != nil {
+			t.Errorf("applyServiceConfig() got error: %v", err)
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if got, want := m.curConfigId(), fmt.Sprintf("2023-01-01r%d", rollouts-1); got != want {
+		t.Errorf("got config id %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+const (
+	// SnapshotExportPath is the admin endpoint to download the served
+	// snapshot as a tarball.
+	SnapshotExportPath = "/snapshot/export"
+
+	// SnapshotImportPath is the admin endpoint to upload an exported
+	// snapshot in the replay mode.
+	SnapshotImportPath = "/snapshot/import"
+
+	snapshotServiceConfigFile = "service_config.json"
+)
+
+// snapshotFileByType is the file of each xDS resource type in the exported
+// tarball. The file is a DiscoveryResponse in JSON.
+var snapshotFileByType = map[rsrc.Type]string{
+	rsrc.ListenerType: "listeners.json",
+	rsrc.ClusterType:  "clusters.json",
+}
+
+// ExportSnapshot writes the served snapshot as a gzipped tarball, along with
+// the service config it is generated from.
+func (m *ConfigManager) ExportSnapshot(w io.Writer) error {
+	snapshot, err := m.cache.GetSnapshot(m.envoyConfigOptions.Node)
+	if err != nil {
+		return fmt.Errorf("snapshot is not loaded: %v", err)
+	}
+
+	marshaler := protojson.MarshalOptions{Indent: "  "}
+	files := make(map[string][]byte)
+	for typeURL, name := range snapshotFileByType {
+		resp := &discoverypb.DiscoveryResponse{
+			VersionInfo: snapshot.GetVersion(typeURL),
+			TypeUrl:     typeURL,
+		}
+
+		resources := snapshot.GetResources(typeURL)
+		var resourceNames []string
+		for resourceName := range resources {
+			resourceNames = append(resourceNames, resourceName)
+		}
+		sort.Strings(resourceNames)
+		for _, resourceName := range resourceNames {
+			resource, err := anypb.New(resources[resourceName])
+			if err != nil {
+				return fmt.Errorf("fail to marshal resource %q: %v", resourceName, err)
+			}
+			resp.Resources = append(resp.Resources, resource)
+		}
+
+		if files[name], err = marshaler.Marshal(resp); err != nil {
+			return fmt.Errorf("fail to marshal %s: %v", name, err)
+		}
+	}
+
+	m.mu.Lock()
+	serviceConfig := m.curServiceConfig
+	m.mu.Unlock()
+	if serviceConfig != nil {
+		if files[snapshotServiceConfigFile], err = marshaler.Marshal(serviceConfig); err != nil {
+			return fmt.Errorf("fail to marshal the service config: %v", err)
+		}
+	}
+
+	return writeTarball(w, files)
+}
+
+func writeTarball(w io.Writer, files map[string][]byte) error {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(files[name])),
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// ReadSnapshotTarball reads the snapshot from the tarball written by
+// ExportSnapshot. The snapshot is versioned by the exported version with the
+// given suffix, so Envoy applies it even if the version is already served.
+func ReadSnapshotTarball(r io.Reader, versionSuffix string) (*cache.Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot is not a gzipped tarball: %v", err)
+	}
+	tr := tar.NewReader(gr)
+
+	typeByFile := make(map[string]rsrc.Type)
+	for typeURL, name := range snapshotFileByType {
+		typeByFile[name] = typeURL
+	}
+
+	version := ""
+	resources := make(map[rsrc.Type][]types.Resource)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fail to read the snapshot tarball: %v", err)
+		}
+
+		typeURL, ok := typeByFile[header.Name]
+		if !ok {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read %s: %v", header.Name, err)
+		}
+
+		resp := &discoverypb.DiscoveryResponse{}
+		if err := protojson.Unmarshal(content, resp); err != nil {
+			return nil, fmt.Errorf("fail to unmarshal %s: %v", header.Name, err)
+		}
+		if resp.GetTypeUrl() != typeURL {
+			return nil, fmt.Errorf("%s has resource type %q, want %q", header.Name, resp.GetTypeUrl(), typeURL)
+		}
+		version = resp.GetVersionInfo()
+
+		resources[typeURL] = []types.Resource{}
+		for _, resource := range resp.GetResources() {
+			msg, err := resource.UnmarshalNew()
+			if err != nil {
+				return nil, fmt.Errorf("fail to unmarshal resource of %s: %v", header.Name, err)
+			}
+			resources[typeURL] = append(resources[typeURL], msg.(types.Resource))
+		}
+	}
+
+	for typeURL, name := range snapshotFileByType {
+		if _, ok := resources[typeURL]; !ok {
+			return nil, fmt.Errorf("snapshot tarball has no %s", name)
+		}
+	}
+
+	snapshot, err := cache.NewSnapshot(version+versionSuffix, resources)
+	if err != nil {
+		return nil, err
+	}
+	if err := snapshot.Consistent(); err != nil {
+		return nil, fmt.Errorf("snapshot is inconsistent: %v", err)
+	}
+	return snapshot, nil
+}
+
+// replaySnapshot serves the exported snapshot instead of the one generated
+// from the service config.
+func (m *ConfigManager) replaySnapshot(r io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replayCount++
+	snapshot, err := ReadSnapshotTarball(r, fmt.Sprintf("-replay-%d", m.replayCount))
+	if err != nil {
+		return err
+	}
+	// The next generated snapshot is always pushed to replace the replayed one.
+	m.snapshotHash = ""
+	return m.cache.SetSnapshot(context.Background(), m.envoyConfigOptions.Node, snapshot)
+}
+
+func (m *ConfigManager) replaySnapshotFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.replaySnapshot(f)
+}
+
+// MakeSnapshotAdminHandler creates the admin handler to export and import the
+// snapshots for debugging.
+//
+// It follows the following scheme:
+// Request: GET /snapshot/export.
+// Response: 200 with the served snapshot as a gzipped tarball.
+// Request: POST /snapshot/import with an exported tarball as the body.
+// Response: 200 if the snapshot is served, 403 if the config manager is not
+// in the replay mode of flag --snapshot_replay_file.
+// Request: GET /configmanager/routes or GET /configmanager/clusters.
+// Response: 200 with a plain text table of the served routes or clusters.
+func (m *ConfigManager) MakeSnapshotAdminHandler() http.Handler {
+	r := mux.NewRouter()
+
+	r.Path(SnapshotExportPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := m.ExportSnapshot(&buf); err != nil {
+			glog.Errorf("snapshot admin fail to export the snapshot: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="espv2-snapshot.tar.gz"`)
+		_, _ = w.Write(buf.Bytes())
+	})
+
+	r.Path(SnapshotImportPath).Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.envoyConfigOptions.SnapshotReplayFile == "" {
+			http.Error(w, "snapshot import is only allowed in the replay mode of flag --snapshot_replay_file", http.StatusForbidden)
+			return
+		}
+		if err := m.replaySnapshot(r.Body); err != nil {
+			glog.Errorf("snapshot admin fail to import the snapshot: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		glog.Infof("snapshot admin imported a snapshot for replay")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.Path(RoutesSummaryPath).Methods("GET").HandlerFunc(makeSummaryHandler(m.WriteRoutesSummary))
+	r.Path(ClustersSummaryPath).Methods("GET").HandlerFunc(makeSummaryHandler(m.WriteClustersSummary))
+
+	return r
+}
+
+func makeSummaryHandler(write func(io.Writer) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := write(&buf); err != nil {
+			glog.Errorf("snapshot admin fail to summarize the snapshot: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	}
+}
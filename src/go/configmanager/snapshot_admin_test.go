@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func newSnapshotAdminTestConfigManager(t *testing.T, opts options.ConfigGeneratorOptions) *ConfigManager {
+	opts.Node = "test-node"
+	m := &ConfigManager{
+		envoyConfigOptions: opts,
+		curServiceConfig: &confpb.Service{
+			Name: "bookstore.endpoints.project123.cloud.goog",
+			Id:   "test-config-id",
+		},
+	}
+	m.cache = cache.NewSnapshotCache(true, m, m)
+	return m
+}
+
+func TestSnapshotAdminHandler_ExportAndReplay(t *testing.T) {
+	wantCluster := &clusterpb.Cluster{
+		Name:                 "backend-cluster-bookstore_local",
+		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_STATIC},
+		LoadAssignment:       util.CreateLoadAssignment("127.0.0.1", 8082),
+	}
+	wantListener := &listenerpb.Listener{
+		Name: "ingress_listener",
+	}
+
+	exporter := newSnapshotAdminTestConfigManager(t, options.DefaultConfigGeneratorOptions())
+	snapshot, err := cache.NewSnapshot("test-config-id", map[rsrc.Type][]types.Resource{
+		rsrc.ListenerType: {wantListener},
+		rsrc.ClusterType:  {wantCluster},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.cache.SetSnapshot(context.Background(), "test-node", snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	exporter.MakeSnapshotAdminHandler().ServeHTTP(resp, httptest.NewRequest("GET", SnapshotExportPath, nil))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("export got status code %v, want 200: %s", resp.Code, resp.Body.String())
+	}
+	tarball := resp.Body.Bytes()
+
+	// Import is rejected outside of the replay mode.
+	resp = httptest.NewRecorder()
+	exporter.MakeSnapshotAdminHandler().ServeHTTP(resp, httptest.NewRequest("POST", SnapshotImportPath, bytes.NewReader(tarball)))
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("import without replay mode got status code %v, want 403", resp.Code)
+	}
+
+	// Start the replay mode from the exported tarball, then import it again.
+	replayFile := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	if err := ioutil.WriteFile(replayFile, tarball, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.Node = "test-node"
+	opts.SnapshotReplayFile = replayFile
+	replayer, err := NewConfigManager(nil, opts)
+	if err != nil {
+		t.Fatalf("NewConfigManager() in replay mode got error: %v", err)
+	}
+
+	resp = httptest.NewRecorder()
+	replayer.MakeSnapshotAdminHandler().ServeHTTP(resp, httptest.NewRequest("POST", SnapshotImportPath, bytes.NewReader(tarball)))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("import got status code %v, want 200: %s", resp.Code, resp.Body.String())
+	}
+
+	got, err := replayer.cache.GetSnapshot("test-node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := got.GetVersion(rsrc.ClusterType); version != "test-config-id-replay-2" {
+		t.Errorf("got replayed snapshot version %q, want %q", version, "test-config-id-replay-2")
+	}
+	if gotCluster := got.GetResources(rsrc.ClusterType)[wantCluster.Name]; !proto.Equal(gotCluster, wantCluster) {
+		t.Errorf("got replayed cluster %v, want %v", gotCluster, wantCluster)
+	}
+	if gotListener := got.GetResources(rsrc.ListenerType)[wantListener.Name]; !proto.Equal(gotListener, wantListener) {
+		t.Errorf("got replayed listener %v, want %v", gotListener, wantListener)
+	}
+}
+
+func TestSnapshotAdminHandler_BadImport(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.SnapshotReplayFile = "/tmp/snapshot.tar.gz"
+	m := newSnapshotAdminTestConfigManager(t, opts)
+
+	var empty bytes.Buffer
+	if err := writeTarball(&empty, map[string][]byte{"listeners.json": []byte(`{"typeUrl": "` + rsrc.ListenerType + `"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		desc string
+		body []byte
+	}{
+		{
+			desc: "Not a tarball",
+			body: []byte("not a tarball"),
+		},
+		{
+			desc: "Missing clusters",
+			body: empty.Bytes(),
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			m.MakeSnapshotAdminHandler().ServeHTTP(resp, httptest.NewRequest("POST", SnapshotImportPath, bytes.NewReader(tc.body)))
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("got status code %v, want 400", resp.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/metadata"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/golang/glog"
+
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+const (
+	backendClusterNamePrefix = "backend-cluster-"
+)
+
+// lookupHostFunc resolves the host, overridden in tests.
+type lookupHostFunc func(ctx context.Context, host string) ([]string, error)
+
+// StartConfigManager creates the Config Manager with the first snapshot, and
+// runs the startup checks of flags --startup_timeout and
+// --require_backend_dns_resolution.
+func StartConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGeneratorOptions) (*ConfigManager, error) {
+	ctx := context.Background()
+	if opts.StartupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.StartupTimeout)
+		defer cancel()
+	}
+
+	type result struct {
+		m   *ConfigManager
+		err error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		m, err := NewConfigManager(mf, opts)
+		if err == nil && opts.RequireBackendDnsResolution {
+			err = m.verifyBackendDnsResolution(ctx, net.DefaultResolver.LookupHost)
+		}
+		resultChan <- result{m: m, err: err}
+	}()
+
+	select {
+	case r := <-resultChan:
+		return r.m, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("fail to generate the first snapshot within --startup_timeout %v, check the access to the service config and the metadata server", opts.StartupTimeout)
+	}
+}
+
+// verifyBackendDnsResolution verifies the hostnames of the backend clusters in
+// the current snapshot are resolved.
+func (m *ConfigManager) verifyBackendDnsResolution(ctx context.Context, lookupHost lookupHostFunc) error {
+	snapshot, err := m.cache.GetSnapshot(m.envoyConfigOptions.Node)
+	if err != nil {
+		return fmt.Errorf("snapshot is not loaded: %v", err)
+	}
+	return verifyBackendClustersDnsResolution(ctx, snapshot.GetResources(rsrc.ClusterType), lookupHost)
+}
+
+func verifyBackendClustersDnsResolution(ctx context.Context, clusters map[string]types.Resource, lookupHost lookupHostFunc) error {
+	hosts := make(map[string]string)
+	for name, resource := range clusters {
+		cluster, ok := resource.(*clusterpb.Cluster)
+		if !ok || !strings.HasPrefix(name, backendClusterNamePrefix) {
+			continue
+		}
+		switch cluster.GetType() {
+		case clusterpb.Cluster_LOGICAL_DNS, clusterpb.Cluster_STRICT_DNS:
+		default:
+			continue
+		}
+
+		for _, localityEndpoints := range cluster.GetLoadAssignment().GetEndpoints() {
+			for _, endpoint := range localityEndpoints.GetLbEndpoints() {
+				if host := endpoint.GetEndpoint().GetAddress().GetSocketAddress().GetAddress(); host != "" {
+					hosts[host] = name
+				}
+			}
+		}
+	}
+
+	var sortedHosts []string
+	for host := range hosts {
+		sortedHosts = append(sortedHosts, host)
+	}
+	sort.Strings(sortedHosts)
+
+	var failures []string
+	for _, host := range sortedHosts {
+		start := time.Now()
+		if _, err := lookupHost(ctx, host); err != nil {
+			failures = append(failures, fmt.Sprintf("%q of cluster %q: %v", host, hosts[host], err))
+			continue
+		}
+		glog.Infof("backend host %q of cluster %q is resolved in %v", host, hosts[host], time.Since(start))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("fail to resolve the backend hosts required by --require_backend_dns_resolution: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/metadata"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+)
+
+func TestStartConfigManager_StartupTimeout(t *testing.T) {
+	setFlags("test-service", "test-config-id", util.FixedRolloutStrategy, "100s", "")
+	defer setFlags("", "", util.FixedRolloutStrategy, "60s", "")
+
+	// The service config is never returned until the test ends.
+	hang := make(chan struct{})
+	mockConfig := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer mockConfig.Close()
+	defer close(hang)
+	util.FetchConfigURL = func(serviceManagementUrl, serviceName, configId string) string {
+		return mockConfig.URL
+	}
+
+	mockMetadataServer := util.InitMockServerFromPathResp(map[string]string{
+		util.AccessTokenPath: `{"access_token": "ya29.new", "expires_in":3599, "token_type":"Bearer"}`,
+	})
+	defer mockMetadataServer.Close()
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.StartupTimeout = 100 * time.Millisecond
+
+	_, err := StartConfigManager(metadata.NewMockMetadataFetcher(mockMetadataServer.URL, time.Now()), opts)
+	wantErr := "fail to generate the first snapshot within --startup_timeout 100ms"
+	if err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("want error containing %q, got %v", wantErr, err)
+	}
+}
+
+func TestVerifyBackendClustersDnsResolution(t *testing.T) {
+	makeCluster := func(name string, clusterType clusterpb.Cluster_DiscoveryType, host string) *clusterpb.Cluster {
+		return &clusterpb.Cluster{
+			Name:                 name,
+			ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterType},
+			LoadAssignment:       util.CreateLoadAssignment(host, 443),
+		}
+	}
+	lookupHost := func(ctx context.Context, host string) ([]string, error) {
+		if strings.HasSuffix(host, ".invalid") {
+			return nil, fmt.Errorf("no such host")
+		}
+		return []string{"10.0.0.1"}, nil
+	}
+
+	testData := []struct {
+		desc     string
+		clusters []*clusterpb.Cluster
+		wantErr  string
+	}{
+		{
+			desc: "Success, all backend hosts are resolved",
+			clusters: []*clusterpb.Cluster{
+				makeCluster("backend-cluster-bookstore_local", clusterpb.Cluster_LOGICAL_DNS, "bookstore.example.com"),
+				makeCluster("backend-cluster-pets.example.com:443", clusterpb.Cluster_STRICT_DNS, "pets.example.com"),
+			},
+		},
+		{
+			desc: "Success, non-backend and non-DNS clusters are not resolved",
+			clusters: []*clusterpb.Cluster{
+				makeCluster("service-control-cluster", clusterpb.Cluster_LOGICAL_DNS, "servicecontrol.invalid"),
+				makeCluster("backend-cluster-static", clusterpb.Cluster_STATIC, "static.invalid"),
+			},
+		},
+		{
+			desc: "Failure, the backend host is not resolved",
+			clusters: []*clusterpb.Cluster{
+				makeCluster("backend-cluster-bookstore_local", clusterpb.Cluster_LOGICAL_DNS, "bookstore.example.com"),
+				makeCluster("backend-cluster-legacy.invalid:443", clusterpb.Cluster_LOGICAL_DNS, "legacy.invalid"),
+			},
+			wantErr: `fail to resolve the backend hosts required by --require_backend_dns_resolution: "legacy.invalid" of cluster "backend-cluster-legacy.invalid:443": no such host`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			clusters := make(map[string]types.Resource)
+			for _, cluster := range tc.clusters {
+				clusters[cluster.Name] = cluster
+			}
+
+			err := verifyBackendClustersDnsResolution(context.Background(), clusters, lookupHost)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("want no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("want error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
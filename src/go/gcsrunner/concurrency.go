@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsrunner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// can be overwritten in unit tests
+var cgroupRoot = "/sys/fs/cgroup"
+
+// CgroupCPULimit returns the number of CPUs the container is limited to by its
+// cgroup CPU quota, rounded up. It returns 0 if there is no quota.
+//
+// Both cgroup v2 (cpu.max) and cgroup v1 (cpu.cfs_quota_us and
+// cpu.cfs_period_us) are supported.
+func CgroupCPULimit() (int, error) {
+	// cgroup v2: "<quota> <period>", quota is "max" if unlimited.
+	if b, err := ioutil.ReadFile(filepath.Join(cgroupRoot, "cpu.max")); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 {
+			return 0, fmt.Errorf("invalid cgroup cpu.max %q", string(b))
+		}
+		if fields[0] == "max" {
+			return 0, nil
+		}
+		return cpuLimit(fields[0], fields[1])
+	}
+
+	// cgroup v1, quota is -1 if unlimited.
+	for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+		quota, err := ioutil.ReadFile(filepath.Join(cgroupRoot, dir, "cpu.cfs_quota_us"))
+		if err != nil {
+			continue
+		}
+		period, err := ioutil.ReadFile(filepath.Join(cgroupRoot, dir, "cpu.cfs_period_us"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read cgroup cpu.cfs_period_us: %v", err)
+		}
+		if strings.TrimSpace(string(quota)) == "-1" {
+			return 0, nil
+		}
+		return cpuLimit(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+
+	return 0, nil
+}
+
+func cpuLimit(quotaStr, periodStr string) (int, error) {
+	quota, err := strconv.ParseFloat(quotaStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cgroup CPU quota %q: %v", quotaStr, err)
+	}
+	period, err := strconv.ParseFloat(periodStr, 64)
+	if err != nil || period <= 0 {
+		return 0, fmt.Errorf("invalid cgroup CPU period %q", periodStr)
+	}
+	if quota <= 0 {
+		return 0, nil
+	}
+
+	return int(math.Ceil(quota / period)), nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsrunner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupCPULimit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		files   map[string]string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "no cgroup files",
+			want: 0,
+		},
+		{
+			name: "cgroup v2 with 1 CPU",
+			files: map[string]string{
+				"cpu.max": "100000 100000\n",
+			},
+			want: 1,
+		},
+		{
+			name: "cgroup v2 with fractional CPU rounded up",
+			files: map[string]string{
+				"cpu.max": "150000 100000\n",
+			},
+			want: 2,
+		},
+		{
+			name: "cgroup v2 unlimited",
+			files: map[string]string{
+				"cpu.max": "max 100000\n",
+			},
+			want: 0,
+		},
+		{
+			name: "cgroup v2 invalid",
+			files: map[string]string{
+				"cpu.max": "garbage\n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "cgroup v1 with 4 CPUs",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "400000\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+			},
+			want: 4,
+		},
+		{
+			name: "cgroup v1 combined controller with half CPU",
+			files: map[string]string{
+				"cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+				"cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			},
+			want: 1,
+		},
+		{
+			name: "cgroup v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "-1\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+			},
+			want: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			oldCgroupRoot := cgroupRoot
+			cgroupRoot = root
+			defer func() { cgroupRoot = oldCgroupRoot }()
+
+			got, err := CgroupCPULimit()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CgroupCPULimit() got error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("CgroupCPULimit() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
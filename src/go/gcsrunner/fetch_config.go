@@ -17,11 +17,14 @@
 package gcsrunner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -36,6 +39,16 @@ var (
 	newGCS             = newGCSClient
 	newDefaultCredsGCS = newDefaultCredsGCSClient
 	osCreate           = func(p string) (io.WriteCloser, error) { return os.Create(p) }
+	osRename           = os.Rename
+)
+
+const (
+	// stagedConfigSuffix is appended to WriteFilePath for the changed config
+	// while it is validated.
+	stagedConfigSuffix = ".staged"
+	// lastGoodConfigSuffix is appended to WriteFilePath for the copy of the
+	// previous config, restored if the changed one fails.
+	lastGoodConfigSuffix = ".last_good"
 )
 
 // can be mocked in unit tests
@@ -56,9 +69,23 @@ func (c *gcsClient) Reader(ctx context.Context, bucket, object string) (io.Reade
 type FetchConfigOptions struct {
 	// ServiceAccount, if provided, is impersonated in the GCS fetch call.
 	// If left blank, the default credentials are used.
-	ServiceAccount                string
-	BucketName                    string
-	ConfigFileName                string
+	ServiceAccount string
+	BucketName     string
+	ConfigFileName string
+	// ConfigURL, if provided, is fetched instead of the GCS object. It must be
+	// an https:// or s3://bucket/key URL, or an http:// URL if ConfigSHA256 or
+	// ConfigPublicKeyPath verifies the config.
+	ConfigURL string
+	// ConfigSHA256, if provided, is the hex encoded SHA-256 checksum the
+	// fetched config must match.
+	ConfigSHA256 string
+	// ConfigPublicKeyPath, if provided, is the path to a PEM encoded public
+	// key. The fetched config must then have a valid detached signature,
+	// stored next to it with the ".sig" suffix.
+	ConfigPublicKeyPath string
+	// ValidateConfig, if provided, is called with the path of a changed config
+	// before it replaces WriteFilePath. The change is rejected if it fails.
+	ValidateConfig                func(configPath string) error
 	WriteFilePath                 string
 	FetchGCSObjectInitialInterval time.Duration
 	FetchGCSObjectTimeout         time.Duration
@@ -117,14 +144,14 @@ func createGCSClient(ctx context.Context, serviceAccount string) (gcsReader, err
 	return client, err
 }
 
-// FetchConfigFromGCS handles fetching a config from GCS, applying any transformation,
-// and writing it to file.
+// FetchConfig handles fetching a config from GCS, or from `opts.ConfigURL`
+// if provided, applying any transformation, and writing it to file.
 //
-// Fetching the GCS object is retried either until success or until
+// Fetching the config is retried either until success or until
 // `opts.fetchGCSObjectTimeout` has passed.
 //
 // Note that writing to file does not time out.
-func FetchConfigFromGCS(opts FetchConfigOptions) error {
+func FetchConfig(opts FetchConfigOptions) error {
 	b, err := readBytes(opts)
 	if err != nil {
 		return fmt.Errorf("failed to read object: %v", err)
@@ -135,41 +162,240 @@ func FetchConfigFromGCS(opts FetchConfigOptions) error {
 	return nil
 }
 
+// ConfigWatcher checks the config for changes, see WatchConfig.
+type ConfigWatcher struct {
+	opts    FetchConfigOptions
+	changed chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+
+	mu sync.Mutex
+	// rejected is the last config failing validation or rolled back. It is
+	// not applied again until the fetched config changes.
+	rejected []byte
+}
+
+// WatchConfig fetches the config every interval and, whenever its content
+// differs from the one at `opts.WriteFilePath`, validates it, replaces the file
+// and notifies the Changed channel. The previous config is kept next to it
+// with the ".last_good" suffix, so it can be restored by Rollback.
+//
+// The content is compared instead of the object metadata so that all config
+// sources are supported, and the same verification applies to updates.
+func WatchConfig(opts FetchConfigOptions, interval time.Duration) *ConfigWatcher {
+	w := &ConfigWatcher{
+		opts:    opts,
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.stopped)
+		glog.Infof("start checking config changes every %v", interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+
+			b, err := readBytes(opts)
+			if err != nil {
+				glog.Errorf("error fetching config to check changes: %v", err)
+				continue
+			}
+
+			changed, err := w.apply(b)
+			if err != nil {
+				glog.Errorf("error applying changed config: %v", err)
+				continue
+			}
+			if changed {
+				glog.Infof("config changed, wrote it to %s", opts.WriteFilePath)
+				select {
+				case w.changed <- struct{}{}:
+				case <-w.stop:
+					return
+				}
+			}
+		}
+	}()
+	return w
+}
+
+// Stop stops checking the config, and waits for the ongoing check.
+func (w *ConfigWatcher) Stop() {
+	close(w.stop)
+	<-w.stopped
+}
+
+// Changed receives every time the config at `opts.WriteFilePath` is replaced.
+func (w *ConfigWatcher) Changed() <-chan struct{} {
+	return w.changed
+}
+
+// apply validates the fetched config and replaces the current one with it. It
+// returns false if the config is not changed, or is rejected before.
+func (w *ConfigWatcher) apply(b []byte) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cur, err := ioutil.ReadFile(w.opts.WriteFilePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read current config %s: %v", w.opts.WriteFilePath, err)
+	}
+	if bytes.Equal(cur, b) || bytes.Equal(w.rejected, b) {
+		return false, nil
+	}
+
+	stagedPath := w.opts.WriteFilePath + stagedConfigSuffix
+	if err := writeFileAtomic(b, stagedPath); err != nil {
+		return false, fmt.Errorf("failed to write changed config to %s: %v", stagedPath, err)
+	}
+	if w.opts.ValidateConfig != nil {
+		if err := w.opts.ValidateConfig(stagedPath); err != nil {
+			w.rejected = b
+			return false, fmt.Errorf("changed config is invalid, keep the current one: %v", err)
+		}
+	}
+
+	if err := writeFileAtomic(cur, w.opts.WriteFilePath+lastGoodConfigSuffix); err != nil {
+		return false, fmt.Errorf("failed to keep the current config: %v", err)
+	}
+	if err := osRename(stagedPath, w.opts.WriteFilePath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Rollback restores the config replaced by the last change, which is then not
+// applied again until the fetched config changes.
+func (w *ConfigWatcher) Rollback() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bad, err := ioutil.ReadFile(w.opts.WriteFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read current config %s: %v", w.opts.WriteFilePath, err)
+	}
+	lastGoodPath := w.opts.WriteFilePath + lastGoodConfigSuffix
+	lastGood, err := ioutil.ReadFile(lastGoodPath)
+	if err != nil {
+		return fmt.Errorf("failed to read last good config %s: %v", lastGoodPath, err)
+	}
+	if err := writeFileAtomic(lastGood, w.opts.WriteFilePath); err != nil {
+		return err
+	}
+	w.rejected = bad
+	glog.Warningf("rolled back config %s to the last good one", w.opts.WriteFilePath)
+	return nil
+}
+
 func readBytes(opts FetchConfigOptions) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), opts.FetchGCSObjectTimeout)
-	defer cancel()
+	verifier, err := newConfigVerifier(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	var client gcsReader
-	var clientErr error
+	read, err := newConfigReader(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	ebo := backoff.NewExponentialBackOff()
-	ebo.InitialInterval = opts.FetchGCSObjectInitialInterval
-	var out []byte
-	op := func() error {
-		if err := ctx.Err(); err != nil {
-			return backoff.Permanent(err)
+	return retryRead(opts, func(ctx context.Context) ([]byte, error) {
+		out, err := read(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		if verifier == nil {
+			return out, nil
 		}
 
+		var signature []byte
+		if verifier.needsSignature() {
+			if signature, err = read(ctx, configSignatureSuffix); err != nil {
+				return nil, err
+			}
+		}
+		if err := verifier.verify(out, signature); err != nil {
+			glog.Errorf("error verifying config (retrying): %v", err)
+			return nil, err
+		}
+		return out, nil
+	})
+}
+
+// newConfigReader returns a function reading the config, or the object next
+// to it with the given suffix appended to its name.
+func newConfigReader(opts FetchConfigOptions) (func(ctx context.Context, suffix string) ([]byte, error), error) {
+	if opts.ConfigURL != "" {
+		u, err := url.Parse(opts.ConfigURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config url %q: %v", opts.ConfigURL, err)
+		}
+		verified := opts.ConfigSHA256 != "" || opts.ConfigPublicKeyPath != ""
+		if _, err := newURLReader(opts.ConfigURL, verified); err != nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context, suffix string) ([]byte, error) {
+			objectURL := *u
+			objectURL.Path += suffix
+			read, err := newURLReader(objectURL.String(), verified)
+			if err != nil {
+				return nil, err
+			}
+			return read(ctx)
+		}, nil
+	}
+
+	var client gcsReader
+	var clientErr error
+	return func(ctx context.Context, suffix string) ([]byte, error) {
 		if client == nil {
 			client, clientErr = createGCSClient(ctx, opts.ServiceAccount)
 			if clientErr != nil {
-				return clientErr
+				return nil, clientErr
 			}
 		}
 
 		start := time.Now()
-		r, err := client.Reader(ctx, opts.BucketName, opts.ConfigFileName)
+		r, err := client.Reader(ctx, opts.BucketName, opts.ConfigFileName+suffix)
 		if err != nil {
 			glog.Errorf("error getting reader for object (retrying): %v", err)
-			return err
+			return nil, err
 		}
 		glog.Infof("obtained reader for object in %s", time.Since(start))
 
-		if out, err = ioutil.ReadAll(r); err != nil {
+		out, err := ioutil.ReadAll(r)
+		if err != nil {
 			glog.Errorf("error reading object bytes (retrying): %v", err)
-			return err
+			return nil, err
 		}
-		return nil
+		return out, nil
+	}, nil
+}
+
+// retryRead calls read with exponential backoff, until it succeeds or
+// `opts.FetchGCSObjectTimeout` has passed.
+func retryRead(opts FetchConfigOptions, read func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.FetchGCSObjectTimeout)
+	defer cancel()
+
+	ebo := backoff.NewExponentialBackOff()
+	ebo.InitialInterval = opts.FetchGCSObjectInitialInterval
+	var out []byte
+	op := func() error {
+		if err := ctx.Err(); err != nil {
+			return backoff.Permanent(err)
+		}
+
+		var err error
+		out, err = read(ctx)
+		return err
 	}
 
 	if err := backoff.Retry(op, ebo); err != nil {
@@ -179,13 +405,23 @@ func readBytes(opts FetchConfigOptions) ([]byte, error) {
 }
 
 func writeFile(b []byte, opts FetchConfigOptions) error {
-	file, err := osCreate(opts.WriteFilePath)
-	defer file.Close()
+	return writeFileAtomic(b, opts.WriteFilePath)
+}
+
+// writeFileAtomic writes to a temporary file renamed into place, so the file
+// is never seen partially written.
+func writeFileAtomic(b []byte, path string) error {
+	tmpPath := path + ".tmp"
+	file, err := osCreate(tmpPath)
 	if err != nil {
 		return err
 	}
 	if _, err := file.Write(b); err != nil {
+		file.Close()
 		return err
 	}
-	return nil
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return osRename(tmpPath, path)
 }
@@ -18,6 +18,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -259,27 +264,36 @@ func TestWriteFile(t *testing.T) {
 		createWant    string
 		createReturns *mockFile
 		createErr     error
+		renameErr     error
 	}{
 		{
 			name:          "failure to create file",
 			wantErr:       true,
-			createWant:    opts.WriteFilePath,
+			createWant:    opts.WriteFilePath + ".tmp",
 			createErr:     fmt.Errorf("create error"),
 			createReturns: &mockFile{},
 		},
 		{
 			name:       "failure to write file",
 			wantErr:    true,
-			createWant: opts.WriteFilePath,
+			createWant: opts.WriteFilePath + ".tmp",
 			createReturns: &mockFile{
 				writeErr: fmt.Errorf("write error"),
 			},
 		},
+		{
+			name:          "failure to rename file",
+			wantErr:       true,
+			input:         []byte(`{"some-key":"some-value"}`),
+			createWant:    opts.WriteFilePath + ".tmp",
+			createReturns: &mockFile{},
+			renameErr:     fmt.Errorf("rename error"),
+		},
 		{
 			name:          "success",
 			input:         []byte(`{"some-key":"some-value"}`),
 			wantOutput:    `{"some-key":"some-value"}`,
-			createWant:    opts.WriteFilePath,
+			createWant:    opts.WriteFilePath + ".tmp",
 			createReturns: &mockFile{},
 		},
 	}
@@ -292,15 +306,23 @@ func TestWriteFile(t *testing.T) {
 				}
 				return tc.createReturns, tc.createErr
 			}
+			oldOSRename := osRename
+			osRename = func(from, to string) error {
+				if from != tc.createWant || to != opts.WriteFilePath {
+					t.Errorf("osRename called with %s, %s, want %s, %s", from, to, tc.createWant, opts.WriteFilePath)
+				}
+				return tc.renameErr
+			}
 			defer func() {
 				osCreate = oldOSCreate
+				osRename = oldOSRename
 			}()
 			err := writeFile(tc.input, opts)
 			if err != nil != tc.wantErr {
 				t.Errorf("writeFile() wanted %v!=nil to be %v", err, tc.wantErr)
 
 			}
-			if !tc.createReturns.closeCalled {
+			if tc.createErr == nil && !tc.createReturns.closeCalled {
 				t.Errorf("Created file was not closed")
 			}
 			if !tc.wantErr {
@@ -311,3 +333,147 @@ func TestWriteFile(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchConfig(t *testing.T) {
+	var mu sync.Mutex
+	content := "config v1"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	oldHTTPClient := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = oldHTTPClient }()
+
+	opts := FetchConfigOptions{
+		ConfigURL:                     server.URL + "/envoy.json",
+		WriteFilePath:                 filepath.Join(t.TempDir(), "envoy.json"),
+		FetchGCSObjectInitialInterval: fetchInitialInterval,
+		FetchGCSObjectTimeout:         fetchTimeout,
+	}
+	if err := FetchConfig(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	w := WatchConfig(opts, 20*time.Millisecond)
+	defer w.Stop()
+	changed := w.Changed()
+	select {
+	case <-changed:
+		t.Fatalf("WatchConfig() notified a change while the config is not changed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	mu.Lock()
+	content = "config v2"
+	mu.Unlock()
+
+	select {
+	case <-changed:
+	case <-time.After(fetchTimeout):
+		t.Fatalf("WatchConfig() did not notify the config change")
+	}
+
+	got, err := os.ReadFile(opts.WriteFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("config v2", string(got)); diff != "" {
+		t.Errorf("WatchConfig() wrote unexpected config (-want/+got): %s", diff)
+	}
+}
+
+func TestWatchConfigValidatesAndRollsBack(t *testing.T) {
+	var mu sync.Mutex
+	content := "config v1"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	oldHTTPClient := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = oldHTTPClient }()
+
+	opts := FetchConfigOptions{
+		ConfigURL:                     server.URL + "/envoy.json",
+		WriteFilePath:                 filepath.Join(t.TempDir(), "envoy.json"),
+		FetchGCSObjectInitialInterval: fetchInitialInterval,
+		FetchGCSObjectTimeout:         fetchTimeout,
+		ValidateConfig: func(configPath string) error {
+			b, err := os.ReadFile(configPath)
+			if err != nil {
+				return err
+			}
+			if string(b) == "invalid config" {
+				return fmt.Errorf("invalid config")
+			}
+			return nil
+		},
+	}
+	if err := FetchConfig(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	setContent := func(c string) {
+		mu.Lock()
+		defer mu.Unlock()
+		content = c
+	}
+	readConfig := func() string {
+		b, err := os.ReadFile(opts.WriteFilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	w := WatchConfig(opts, 20*time.Millisecond)
+	defer w.Stop()
+
+	// The invalid config never replaces the current one.
+	setContent("invalid config")
+	select {
+	case <-w.Changed():
+		t.Fatalf("WatchConfig() notified an invalid config")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if got := readConfig(); got != "config v1" {
+		t.Errorf("got config %q after an invalid change, want %q", got, "config v1")
+	}
+
+	setContent("config v2")
+	select {
+	case <-w.Changed():
+	case <-time.After(fetchTimeout):
+		t.Fatalf("WatchConfig() did not notify the config change")
+	}
+
+	// The rolled back config is not applied again until it changes.
+	if err := w.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readConfig(); got != "config v1" {
+		t.Errorf("got config %q after rollback, want %q", got, "config v1")
+	}
+	select {
+	case <-w.Changed():
+		t.Fatalf("WatchConfig() notified the rolled back config again")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	setContent("config v3")
+	select {
+	case <-w.Changed():
+	case <-time.After(fetchTimeout):
+		t.Fatalf("WatchConfig() did not notify the config change after rollback")
+	}
+	if got := readConfig(); got != "config v3" {
+		t.Errorf("got config %q, want %q", got, "config v3")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsrunner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+var (
+	// can be overwritten in unit tests
+	httpClient = &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	s3Endpoint = func(bucket, region string) string {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	}
+	timeNow = time.Now
+)
+
+// newURLReader returns a function reading the config at the given https://
+// or s3:// URL. Plain http:// is only allowed if the config is verified, as
+// the config could be modified in transit otherwise.
+func newURLReader(rawURL string, verified bool) (func(ctx context.Context) ([]byte, error), error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config url %q: %v", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "http":
+		if !verified {
+			return nil, fmt.Errorf("invalid config url %q: http requires CONFIG_SHA256 or CONFIG_PUBLIC_KEY_PATH to verify the config, use https otherwise", rawURL)
+		}
+		fallthrough
+	case "https":
+		return func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			return doRequest(req)
+		}, nil
+	case "s3":
+		bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+		if bucket == "" || key == "" {
+			return nil, fmt.Errorf("invalid config url %q: must be in the format of s3://bucket/key", rawURL)
+		}
+		return func(ctx context.Context) ([]byte, error) {
+			return readS3Object(ctx, bucket, key)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid config url %q: scheme must be one of https, http or s3", rawURL)
+	}
+}
+
+func doRequest(req *http.Request) ([]byte, error) {
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		glog.Errorf("error fetching %s (retrying): %v", req.URL.Redacted(), err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		glog.Errorf("error reading %s (retrying): %v", req.URL.Redacted(), err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("fetching %s returns not 200 OK: %v", req.URL.Redacted(), resp.Status)
+		glog.Errorf("%v (retrying)", err)
+		return nil, err
+	}
+	glog.Infof("fetched %s in %s", req.URL.Redacted(), time.Since(start))
+	return body, nil
+}
+
+// readS3Object reads an S3 object, authenticating with the standard AWS
+// environment variables: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN (optional) and AWS_REGION (defaults to us-east-1).
+func readS3Object(ctx context.Context, bucket, key string) ([]byte, error) {
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to fetch config from S3")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	objectURL := fmt.Sprintf("%s/%s", s3Endpoint(bucket, region), key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	signAWSV4(req, creds, region, "s3", timeNow())
+	return doRequest(req)
+}
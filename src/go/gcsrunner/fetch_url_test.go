@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsrunner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadBytesFromURL(t *testing.T) {
+	output := "some output"
+
+	var calls int
+	var gotPath, gotAuth string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		// Fail the first call to exercise retries.
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(output))
+	}))
+	defer server.Close()
+
+	oldHTTPClient, oldS3Endpoint, oldTimeNow := httpClient, s3Endpoint, timeNow
+	httpClient = server.Client()
+	s3Endpoint = func(bucket, region string) string {
+		return server.URL + "/" + bucket + "/" + region
+	}
+	timeNow = func() time.Time { return time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() {
+		httpClient, s3Endpoint, timeNow = oldHTTPClient, oldS3Endpoint, oldTimeNow
+	}()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "access-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret-key")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	testCases := []struct {
+		name         string
+		configURL    string
+		wantPath     string
+		wantAuthPref string
+	}{
+		{
+			name:      "https url",
+			configURL: server.URL + "/configs/envoy.json",
+			wantPath:  "/configs/envoy.json",
+		},
+		{
+			name:         "s3 url with sigv4",
+			configURL:    "s3://bucket/configs/envoy config.json",
+			wantPath:     "/bucket/eu-west-1/configs/envoy%20config.json",
+			wantAuthPref: "AWS4-HMAC-SHA256 Credential=access-key/20230101/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = 0
+			b, err := readBytes(FetchConfigOptions{
+				ConfigURL:                     tc.configURL,
+				FetchGCSObjectInitialInterval: fetchInitialInterval,
+				FetchGCSObjectTimeout:         fetchTimeout,
+			})
+			if err != nil {
+				t.Fatalf("readBytes() got error %v", err)
+			}
+			if diff := cmp.Diff(output, string(b)); diff != "" {
+				t.Errorf("readBytes() got unexpected value (-want/+got): %s", diff)
+			}
+			if calls != 2 {
+				t.Errorf("readBytes() got %v calls, want 2", calls)
+			}
+			if gotPath != tc.wantPath {
+				t.Errorf("readBytes() got request path %s, want %s", gotPath, tc.wantPath)
+			}
+			if (tc.wantAuthPref == "" && gotAuth != "") || !strings.HasPrefix(gotAuth, tc.wantAuthPref) {
+				t.Errorf("readBytes() got Authorization header %q, want prefix %q", gotAuth, tc.wantAuthPref)
+			}
+		})
+	}
+}
+
+func TestNewURLReaderError(t *testing.T) {
+	testCases := []struct {
+		configURL string
+		wantErr   string
+	}{
+		{
+			configURL: "ftp://host/envoy.json",
+			wantErr:   "scheme must be one of https, http or s3",
+		},
+		{
+			configURL: "s3://bucket",
+			wantErr:   "must be in the format of s3://bucket/key",
+		},
+		{
+			configURL: "http://host/envoy.json",
+			wantErr:   "http requires CONFIG_SHA256 or CONFIG_PUBLIC_KEY_PATH to verify the config",
+		},
+	}
+
+	for _, tc := range testCases {
+		if _, err := newURLReader(tc.configURL, false); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("newURLReader(%q) got error %v, want %q", tc.configURL, err, tc.wantErr)
+		}
+	}
+}
+
+func TestNewURLReaderAllowsVerifiedHTTP(t *testing.T) {
+	if _, err := newURLReader("http://host/envoy.json", true); err != nil {
+		t.Errorf("newURLReader() got error %v, want nil for a verified config", err)
+	}
+	if _, err := newConfigReader(FetchConfigOptions{
+		ConfigURL:    "http://host/envoy.json",
+		ConfigSHA256: "abcd",
+	}); err != nil {
+		t.Errorf("newConfigReader() got error %v, want nil for a verified config", err)
+	}
+	if _, err := newConfigReader(FetchConfigOptions{
+		ConfigURL: "http://host/envoy.json",
+	}); err == nil {
+		t.Errorf("newConfigReader() got no error, want error for an unverified http config")
+	}
+}
@@ -27,10 +27,39 @@
 //
 // If RUN_AS_SERVICE_ACCOUNT is provided, gcsrunner will attempt to impersonate
 // the given service account in the call to read from GCS.
+//
+// If CONFIG_URL is provided, the config is fetched from that https:// or
+// s3://bucket/key URL instead, and `BUCKET` and `CONFIG_FILE_NAME` are not
+// required. A plain http:// URL is only accepted together with CONFIG_SHA256
+// or CONFIG_PUBLIC_KEY_PATH. S3 requests are signed with the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION environment variables.
+//
+// If CONFIG_SHA256 or CONFIG_PUBLIC_KEY_PATH is provided, the fetched config
+// must match the hex encoded SHA-256 checksum, or have a valid base64 encoded
+// detached signature stored next to it with the ".sig" suffix. A config
+// failing verification is rejected and fetched again until the fetch timeout.
+//
+// If CONFIG_REFRESH_INTERVAL is provided (e.g. "60s"), the config is fetched
+// again at that interval and Envoy is hot restarted whenever it changes.
+//
+// If ENVOY_CONCURRENCY is not provided, Envoy concurrency is derived from the
+// container cgroup CPU limit.
+//
+// If ENVOY_MAX_RESTARTS is provided, a crashed Envoy is restarted with
+// exponential backoff up to that many consecutive times. Crash metrics are
+// served at /debug/vars on DEBUG_PORT, if provided. They are only served on
+// the loopback address, unless DEBUG_ADDRESS is provided.
+//
+// If DRAIN_TIME_S is provided, on SIGTERM gcsrunner asks Envoy to fail health
+// checks and drain listeners through the admin interface at
+// ENVOY_ADMIN_ADDRESS, and only stops Envoy after DRAIN_TIME_S seconds.
 package main
 
 import (
 	"flag"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -46,6 +75,8 @@ const (
 	fetchGCSObjectInitialInterval = 100 * time.Millisecond
 	fetchGCSObjectTimeout         = 3 * time.Minute
 	terminateEnvoyTimeout         = time.Minute
+	restartEnvoyInitialInterval   = time.Second
+	restartEnvoyMaxInterval       = 30 * time.Second
 )
 
 var (
@@ -56,20 +87,35 @@ var (
 		"Envoy application logging path. Default is to write to stderr.")
 	envoyComponentLogLevel = flag.String("envoy_component_log_level", "", "Mapping for Envoy log level by component.")
 	sa                     = flag.String("run_as_service_account", "", "If provided, use this account when fetching the config from GCS. If not provided, the container's default credentials are used.")
+	configUrl              = flag.String("config_url", "", "If provided, fetch the config from this https:// or s3://bucket/key URL instead of GCS.")
+	configSha256           = flag.String("config_sha256", "", "If provided, the hex encoded SHA-256 checksum the fetched config must match.")
+	configPublicKeyPath    = flag.String("config_public_key_path", "", "If provided, path to a PEM encoded public key verifying the detached signature of the fetched config.")
+	configRefreshInterval  = flag.Duration("config_refresh_interval", 0, "If not 0, fetch the config again at this interval and hot restart Envoy whenever it changes. The changed config is validated by Envoy first, and rolled back if the hot restarted Envoy fails.")
+	envoyConcurrency       = flag.Uint("envoy_concurrency", 0, "Number of Envoy worker threads. Default is 0, which derives it from the container CPU limit.")
+	envoyMaxRestarts       = flag.Uint("envoy_max_restarts", 0, "How many consecutive times a crashed Envoy is restarted with exponential backoff. Default is 0, which exits on the first crash.")
+	debugPort              = flag.Uint("debug_port", 0, "If not 0, serve crash metrics at /debug/vars on this port.")
+	debugAddress           = flag.String("debug_address", "127.0.0.1", "IP address the crash metrics of --debug_port are served on. Default is the loopback address.")
+	envoyAdminAddress      = flag.String("envoy_admin_address", "", "Address (host:port) of the Envoy admin interface, used to drain Envoy on shutdown.")
+	drainTimeS             = flag.Uint("drain_time_s", 0, "On SIGTERM, fail Envoy health checks and drain listeners for this many seconds before stopping Envoy. Default is 0, which stops Envoy immediately.")
 )
 
 func main() {
 	flag.Set("alsologtostderr", "true")
 	flag.Parse()
 
+	configURL := os.Getenv("CONFIG_URL")
+	if configURL == "" {
+		configURL = *configUrl
+	}
+
 	bucketName := os.Getenv("BUCKET")
-	if bucketName == "" {
-		glog.Fatal("Must specify the BUCKET environment variable.")
+	if bucketName == "" && configURL == "" {
+		glog.Fatal("Must specify the BUCKET or CONFIG_URL environment variable.")
 	}
 
 	configFileName := os.Getenv("CONFIG_FILE_NAME")
-	if configFileName == "" {
-		glog.Fatal("Must specify the CONFIG_FILE_NAME environment variable.")
+	if configFileName == "" && configURL == "" {
+		glog.Fatal("Must specify the CONFIG_FILE_NAME or CONFIG_URL environment variable.")
 	}
 
 	logLevel := os.Getenv("ENVOY_LOG_LEVEL")
@@ -97,21 +143,92 @@ func main() {
 		runAsSA = *sa
 	}
 
+	configSHA256 := os.Getenv("CONFIG_SHA256")
+	if configSHA256 == "" {
+		configSHA256 = *configSha256
+	}
+
+	configPublicKey := os.Getenv("CONFIG_PUBLIC_KEY_PATH")
+	if configPublicKey == "" {
+		configPublicKey = *configPublicKeyPath
+	}
+
+	refreshInterval := *configRefreshInterval
+	if v := os.Getenv("CONFIG_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			glog.Fatalf("Invalid CONFIG_REFRESH_INTERVAL environment variable: %v", err)
+		}
+		refreshInterval = d
+	}
+
+	adminAddress := os.Getenv("ENVOY_ADMIN_ADDRESS")
+	if adminAddress == "" {
+		adminAddress = *envoyAdminAddress
+	}
+
+	drainTime, err := envNum("DRAIN_TIME_S", uint32(*drainTimeS))
+	if err != nil {
+		glog.Fatalf("Invalid DRAIN_TIME_S environment variable: %v", err)
+	}
+
+	concurrency, err := envNum("ENVOY_CONCURRENCY", uint32(*envoyConcurrency))
+	if err != nil {
+		glog.Fatalf("Invalid ENVOY_CONCURRENCY environment variable: %v", err)
+	}
+
+	maxRestarts, err := envNum("ENVOY_MAX_RESTARTS", uint32(*envoyMaxRestarts))
+	if err != nil {
+		glog.Fatalf("Invalid ENVOY_MAX_RESTARTS environment variable: %v", err)
+	}
+
+	port, err := envNum("DEBUG_PORT", uint32(*debugPort))
+	if err != nil {
+		glog.Fatalf("Invalid DEBUG_PORT environment variable: %v", err)
+	}
+	if port != 0 {
+		address := os.Getenv("DEBUG_ADDRESS")
+		if address == "" {
+			address = *debugAddress
+		}
+		go func() {
+			if err := http.ListenAndServe(net.JoinHostPort(address, fmt.Sprint(port)), gcsrunner.CrashMetricsHandler()); err != nil {
+				glog.Errorf("debug server fail to serve: %v", err)
+			}
+		}()
+	}
+
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 
 	start := time.Now()
-	if err := gcsrunner.FetchConfigFromGCS(gcsrunner.FetchConfigOptions{
+	fetchOpts := gcsrunner.FetchConfigOptions{
 		BucketName:                    bucketName,
 		ConfigFileName:                configFileName,
+		ConfigURL:                     configURL,
+		ConfigSHA256:                  configSHA256,
+		ConfigPublicKeyPath:           configPublicKey,
 		FetchGCSObjectInitialInterval: fetchGCSObjectInitialInterval,
 		FetchGCSObjectTimeout:         fetchGCSObjectTimeout,
 		WriteFilePath:                 envoyConfigPath,
 		ServiceAccount:                runAsSA,
-	}); err != nil {
+	}
+	if err := gcsrunner.FetchConfig(fetchOpts); err != nil {
 		glog.Fatalf("Failed to fetch config: %v", err)
 	}
-	glog.Infof("fetched config from GCS in %s", time.Since(start))
+	glog.Infof("fetched config in %s", time.Since(start))
+
+	var reloadChan <-chan struct{}
+	var rollbackConfig func() error
+	if refreshInterval > 0 {
+		// The changed config is validated by Envoy before it is hot restarted.
+		fetchOpts.ValidateConfig = func(configPath string) error {
+			return gcsrunner.ValidateEnvoyConfig(envoyBin, configPath)
+		}
+		watcher := gcsrunner.WatchConfig(fetchOpts, refreshInterval)
+		reloadChan = watcher.Changed()
+		rollbackConfig = watcher.Rollback
+	}
 
 	if err := gcsrunner.StartEnvoyAndWait(signalChan, gcsrunner.StartEnvoyOptions{
 		BinaryPath:        envoyBin,
@@ -120,6 +237,15 @@ func main() {
 		LogLevel:          logLevel,
 		LogPath:           logPath,
 		TerminateTimeout:  terminateEnvoyTimeout,
+		AdminAddress:      adminAddress,
+		DrainTime:         time.Duration(drainTime) * time.Second,
+		ReloadChan:        reloadChan,
+		RollbackConfig:    rollbackConfig,
+		Concurrency:       int(concurrency),
+
+		MaxRestarts:            int(maxRestarts),
+		RestartInitialInterval: restartEnvoyInitialInterval,
+		RestartMaxInterval:     restartEnvoyMaxInterval,
 	}); err != nil {
 		glog.Fatalf("Envoy erred: %v", err)
 	}
@@ -17,8 +17,8 @@ import (
 
 // staticTokenSource implements oath2.TokenSource
 type staticTokenSource struct {
-	accessToken string    `json:"access_token"`
-	expiry      time.Time `json:"expiry,omitempty"`
+	accessToken string
+	expiry      time.Time
 }
 
 func (s staticTokenSource) Token() (*oauth2.Token, error) {
@@ -29,8 +29,8 @@ func (s staticTokenSource) Token() (*oauth2.Token, error) {
 }
 
 type iamcredentialsResponse struct {
-	AccessToken string `json:accessToken`
-	ExpireTime  string `json:expireTime`
+	AccessToken string `json:"accessToken"`
+	ExpireTime  string `json:"expireTime"`
 }
 
 // TokenSource returns an oauth2.TokenSource which provides a short-lived token
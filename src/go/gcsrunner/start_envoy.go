@@ -15,15 +15,41 @@
 package gcsrunner
 
 import (
+	"expvar"
 	"fmt"
+	"math"
+	"net/http"
 	"os"
 	"os/exec"
 	"time"
 
+	"github.com/cenkalti/backoff"
 	"github.com/golang/glog"
 )
 
-var execCommand = exec.Command
+const (
+	// parentShutdownGracePeriodS is how long the previous Envoy is kept after
+	// its drain time during a hot restart.
+	parentShutdownGracePeriodS = 15
+
+	// restartResetPeriod is how long Envoy must run after a restart for its
+	// crashes to be considered unrelated.
+	restartResetPeriod = time.Minute
+)
+
+var (
+	execCommand = exec.Command
+
+	// Crash metrics, served at /debug/vars by ServeCrashMetrics. They are not
+	// published to the global expvar registry, so the default mux is unused.
+	envoyCrashes  = new(expvar.Int)
+	envoyRestarts = new(expvar.Int)
+	crashMetrics  = newCrashMetrics()
+
+	adminClient = &http.Client{
+		Timeout: 5 * time.Second,
+	}
+)
 
 // StartEnvoyOptions provides a set of configurations when starting Envoy.
 type StartEnvoyOptions struct {
@@ -33,6 +59,62 @@ type StartEnvoyOptions struct {
 	LogLevel          string
 	LogPath           string
 	TerminateTimeout  time.Duration
+
+	// AdminAddress is the host:port of the Envoy admin interface. It is used
+	// to start the drain sequence. If empty, Envoy is only given DrainTime
+	// to finish in-flight requests before it is signaled.
+	AdminAddress string
+	// DrainTime is how long Envoy drains listeners before it is signaled to
+	// stop. Draining is disabled if it is 0.
+	DrainTime time.Duration
+	// ReloadChan, if set, enables hot restart. Envoy is hot restarted every
+	// time it receives.
+	ReloadChan <-chan struct{}
+	// RollbackConfig, if set, is called when the Envoy hot restarted with a new
+	// config exits while the previous one is still running. It restores the
+	// previous config at ConfigPath.
+	RollbackConfig func() error
+	// Concurrency is the number of Envoy worker threads. If 0, it is derived
+	// from the container CPU limit, or left to Envoy if there is none.
+	Concurrency int
+
+	// MaxRestarts is how many consecutive times a crashed Envoy is restarted
+	// before giving up. Restarting is disabled if it is 0.
+	MaxRestarts int
+	// RestartInitialInterval and RestartMaxInterval bound the exponential
+	// backoff between restarts.
+	RestartInitialInterval time.Duration
+	RestartMaxInterval     time.Duration
+}
+
+// envoyProcess is a running Envoy of a restart epoch.
+type envoyProcess struct {
+	epoch int
+	cmd   *exec.Cmd
+}
+
+// envoyExit is received when the Envoy of a restart epoch exits.
+type envoyExit struct {
+	epoch int
+	err   error
+}
+
+func newCrashMetrics() *expvar.Map {
+	m := new(expvar.Map).Init()
+	m.Set("envoy_crashes", envoyCrashes)
+	m.Set("envoy_restarts", envoyRestarts)
+	return m
+}
+
+// CrashMetricsHandler returns a handler serving only the Envoy crash metrics
+// as JSON, in the format of expvar.
+func CrashMetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintln(w, crashMetrics.String())
+	})
+	return mux
 }
 
 // StartEnvoyAndWait starts Envoy and waits.
@@ -40,12 +122,143 @@ type StartEnvoyOptions struct {
 // Any Envoy exit is assumed to be an error.
 //
 // Any signal sent to signalChan is expected to be an exit signal. A failure to
-// signal Envoy results in an error.
+// signal Envoy results in an error. If DrainTime is set, Envoy is first asked
+// to fail health checks and drain listeners, and is only signaled after
+// DrainTime or when a second signal is received.
+//
+// If ReloadChan is set, Envoy is hot restarted with the config at ConfigPath
+// every time ReloadChan receives. The previous Envoy drains and exits on its
+// own once the new one has taken over. If the new Envoy exits while the
+// previous one is still running, the config is rolled back by RollbackConfig
+// and the previous Envoy keeps serving.
+//
+// If MaxRestarts is set, a crashed Envoy is restarted with exponential
+// backoff, so the container does not need to cold start. The backoff is reset
+// once Envoy has been running for restartResetPeriod.
 func StartEnvoyAndWait(signalChan chan os.Signal, opts StartEnvoyOptions) error {
+	// live holds the running Envoy of every epoch, the newest last.
+	var live []*envoyProcess
+	exitChan := make(chan envoyExit)
+	start := func(epoch int) error {
+		cmd, envoyExitChan, err := startEnvoy(opts, epoch)
+		if err != nil {
+			return err
+		}
+		live = append(live, &envoyProcess{epoch: epoch, cmd: cmd})
+		go func() {
+			exitChan <- envoyExit{epoch: epoch, err: <-envoyExitChan}
+		}()
+		return nil
+	}
+
+	if err := start(0); err != nil {
+		return err
+	}
+	startedAt := time.Now()
+
+	restarts := 0
+	ebo := backoff.NewExponentialBackOff()
+	ebo.InitialInterval = opts.RestartInitialInterval
+	ebo.MaxInterval = opts.RestartMaxInterval
+	ebo.MaxElapsedTime = 0
+
+	for {
+		select {
+		case exit := <-exitChan:
+			newest := live[len(live)-1]
+			live = removeEnvoyProcess(live, exit.epoch)
+			if exit.epoch != newest.epoch {
+				glog.Infof("Envoy with epoch %v exited after hot restart: %v", exit.epoch, exit.err)
+				continue
+			}
+
+			if len(live) > 0 {
+				// The hot restart failed, the previous Envoy is still serving.
+				glog.Errorf("Envoy with epoch %v exited while epoch %v is still running, keep running the previous one: %v", exit.epoch, live[len(live)-1].epoch, exit.err)
+				if opts.RollbackConfig != nil {
+					if err := opts.RollbackConfig(); err != nil {
+						glog.Errorf("Failed to roll back the config: %v", err)
+					}
+				}
+				continue
+			}
+
+			envoyCrashes.Add(1)
+			if time.Since(startedAt) > restartResetPeriod {
+				restarts = 0
+				ebo.Reset()
+			}
+			if restarts >= opts.MaxRestarts {
+				return fmt.Errorf("envoy exited: %v", exit.err)
+			}
+
+			restarts++
+			wait := ebo.NextBackOff()
+			glog.Errorf("Envoy crashed: %v, restarting it in %v (%v/%v)", exit.err, wait, restarts, opts.MaxRestarts)
+			select {
+			case sig := <-signalChan:
+				return fmt.Errorf("got signal %v while Envoy was down after crashing: %v", sig, exit.err)
+			case <-time.After(wait):
+			}
+
+			// There is no parent left to hot restart from.
+			if err := start(0); err != nil {
+				return err
+			}
+			startedAt = time.Now()
+			envoyRestarts.Add(1)
+		case <-opts.ReloadChan:
+			epoch := live[len(live)-1].epoch + 1
+			glog.Infof("Hot restarting Envoy with epoch %v to apply the new config", epoch)
+			if err := start(epoch); err != nil {
+				glog.Errorf("Failed to hot restart Envoy, keep running the previous one: %v", err)
+				if opts.RollbackConfig != nil {
+					if err := opts.RollbackConfig(); err != nil {
+						glog.Errorf("Failed to roll back the config: %v", err)
+					}
+				}
+				continue
+			}
+		case sig := <-signalChan:
+			return stopEnvoy(signalChan, sig, live, exitChan, opts)
+		}
+	}
+}
+
+// removeEnvoyProcess returns the processes without the one of the epoch.
+func removeEnvoyProcess(processes []*envoyProcess, epoch int) []*envoyProcess {
+	var remaining []*envoyProcess
+	for _, p := range processes {
+		if p.epoch != epoch {
+			remaining = append(remaining, p)
+		}
+	}
+	return remaining
+}
+
+// ValidateEnvoyConfig runs the Envoy binary in validate mode, which checks the
+// config without serving it.
+func ValidateEnvoyConfig(binaryPath, configPath string) error {
+	cmd := execCommand(binaryPath,
+		"--mode", "validate",
+		"--service-cluster", "front-envoy",
+		"--service-node", "front-envoy",
+		"--config-path", configPath,
+		"--allow-unknown-static-fields",
+	)
+	cmd.Env = append(cmd.Env, "TMPDIR=/tmp")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("envoy rejects config %s: %v, %s", configPath, err, out)
+	}
+	return nil
+}
+
+// startEnvoy starts the Envoy process. The returned channel receives once the
+// process exits.
+func startEnvoy(opts StartEnvoyOptions, epoch int) (*exec.Cmd, chan error, error) {
 	startupFlags := []string{
 		"--service-cluster", "front-envoy",
 		"--service-node", "front-envoy",
-		"--disable-hot-restart",
 		"--config-path", opts.ConfigPath,
 		"--log-level", opts.LogLevel,
 		"--log-path", opts.LogPath,
@@ -53,19 +266,35 @@ func StartEnvoyAndWait(signalChan chan os.Signal, opts StartEnvoyOptions) error
 		"--log-format-escaped",
 		"--allow-unknown-static-fields",
 	}
+	if opts.ReloadChan == nil {
+		startupFlags = append(startupFlags, "--disable-hot-restart")
+	} else {
+		startupFlags = append(startupFlags, "--restart-epoch", fmt.Sprintf("%d", epoch))
+	}
 	if opts.ComponentLogLevel != "" {
 		startupFlags = append(startupFlags, "--component-log-level", opts.ComponentLogLevel)
 	}
+	if concurrency := envoyConcurrency(opts.Concurrency); concurrency > 0 {
+		startupFlags = append(startupFlags, "--concurrency", fmt.Sprintf("%d", concurrency))
+	}
+	if opts.DrainTime > 0 {
+		drainTimeS := int(math.Ceil(opts.DrainTime.Seconds()))
+		startupFlags = append(startupFlags, "--drain-time-s", fmt.Sprintf("%d", drainTimeS))
+		if opts.ReloadChan != nil {
+			// The previous Envoy must outlive its drain time after a hot restart.
+			startupFlags = append(startupFlags, "--parent-shutdown-time-s", fmt.Sprintf("%d", drainTimeS+parentShutdownGracePeriodS))
+		}
+	}
 	cmd := execCommand(opts.BinaryPath, startupFlags...)
 	cmd.Env = append(cmd.Env, "TMPDIR=/tmp")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start Envoy: %v", err)
+		return nil, nil, fmt.Errorf("failed to start Envoy: %v", err)
 	}
 
-	envoyExitChan := make(chan error)
+	envoyExitChan := make(chan error, 1)
 	go func() {
 		err := cmd.Wait()
 		if err == nil {
@@ -73,25 +302,107 @@ func StartEnvoyAndWait(signalChan chan os.Signal, opts StartEnvoyOptions) error
 		}
 		envoyExitChan <- err
 	}()
+	return cmd, envoyExitChan, nil
+}
+
+// envoyConcurrency returns the explicit concurrency if set, otherwise the
+// container CPU limit. Envoy defaults to the number of host CPUs, which spawns
+// too many workers in CPU limited containers.
+func envoyConcurrency(concurrency int) int {
+	if concurrency > 0 {
+		glog.Infof("Envoy concurrency is explicitly set to %v", concurrency)
+		return concurrency
+	}
+
+	limit, err := CgroupCPULimit()
+	if err != nil {
+		glog.Warningf("Failed to read the container CPU limit, leaving Envoy concurrency to its default: %v", err)
+		return 0
+	}
+	if limit == 0 {
+		glog.Infof("No container CPU limit found, leaving Envoy concurrency to its default")
+		return 0
+	}
+	glog.Infof("Setting Envoy concurrency to %v from the container CPU limit", limit)
+	return limit
+}
 
-	select {
-	case err := <-envoyExitChan:
-		return fmt.Errorf("envoy exited: %v", err)
-	case sig := <-signalChan:
-		if cmd.Process == nil {
+// stopEnvoy drains and stops the Envoy of every live epoch, as a previous
+// Envoy may still be running after a hot restart.
+func stopEnvoy(signalChan chan os.Signal, sig os.Signal, live []*envoyProcess, exitChan chan envoyExit, opts StartEnvoyOptions) error {
+	for _, p := range live {
+		if p.cmd.Process == nil {
 			return fmt.Errorf("cmd not started, which should never happen")
 		}
-		glog.Errorf("Stopping Envoy due to signal: %v", sig)
+	}
+	if opts.DrainTime > 0 {
+		glog.Errorf("Draining Envoy for %v due to signal: %v", opts.DrainTime, sig)
+		drainEnvoy(opts.AdminAddress)
 
-		// This will always be a signal to stop the process.
-		if err := cmd.Process.Signal(sig); err != nil {
-			return fmt.Errorf("failed to signal Envoy: %v", err)
+		drainDone := time.After(opts.DrainTime)
+	drain:
+		for {
+			select {
+			case exit := <-exitChan:
+				newest := live[len(live)-1]
+				live = removeEnvoyProcess(live, exit.epoch)
+				if exit.epoch == newest.epoch {
+					return fmt.Errorf("envoy exited while draining: %v", exit.err)
+				}
+				glog.Infof("Envoy with epoch %v exited after hot restart: %v", exit.epoch, exit.err)
+			case sig = <-signalChan:
+				glog.Errorf("Aborting drain due to signal: %v", sig)
+				break drain
+			case <-drainDone:
+				break drain
+			}
 		}
+	}
+	glog.Errorf("Stopping Envoy due to signal: %v", sig)
+
+	// This will always be a signal to stop the process.
+	for _, p := range live {
+		if err := p.cmd.Process.Signal(sig); err != nil {
+			return fmt.Errorf("failed to signal Envoy with epoch %v: %v", p.epoch, err)
+		}
+	}
+
+	var err error
+	timeout := time.After(opts.TerminateTimeout)
+	for range live {
 		select {
-		case err := <-envoyExitChan:
-			return err
-		case <-time.After(opts.TerminateTimeout):
+		case exit := <-exitChan:
+			if err == nil {
+				err = exit.err
+			}
+		case <-timeout:
 			return fmt.Errorf("timed out waiting for Envoy to exit after %v", opts.TerminateTimeout)
 		}
 	}
+	return err
+}
+
+// drainEnvoy starts the drain sequence of Envoy through its admin interface:
+// health checks start failing so load balancers stop sending new requests,
+// then listeners gracefully drain the existing connections.
+//
+// Failures are only logged, the caller still waits for the drain time.
+func drainEnvoy(adminAddress string) {
+	if adminAddress == "" {
+		glog.Warningf("Envoy admin address is not set, skipping the drain sequence")
+		return
+	}
+
+	for _, path := range []string{"/healthcheck/fail", "/drain_listeners?graceful"} {
+		url := fmt.Sprintf("http://%s%s", adminAddress, path)
+		resp, err := adminClient.Post(url, "", nil)
+		if err != nil {
+			glog.Errorf("Failed to call Envoy admin %s: %v", url, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			glog.Errorf("Envoy admin %s returned %v", url, resp.Status)
+		}
+	}
 }
@@ -15,12 +15,18 @@
 package gcsrunner
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"os/signal"
+	"reflect"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -128,6 +134,284 @@ func TestStartEnvoyAndWait(t *testing.T) {
 	}
 }
 
+func TestStartEnvoyAndWaitDrains(t *testing.T) {
+	var mu sync.Mutex
+	var gotAdminCalls []string
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotAdminCalls = append(gotAdminCalls, fmt.Sprintf("%s %s", r.Method, r.URL.RequestURI()))
+	}))
+	defer admin.Close()
+
+	opts := StartEnvoyOptions{
+		BinaryPath:       "binary",
+		ConfigPath:       "config",
+		LogLevel:         "loglevel",
+		TerminateTimeout: testTimeout,
+		AdminAddress:     strings.TrimPrefix(admin.URL, "http://"),
+		DrainTime:        waitForSleep,
+	}
+
+	var gotArgs []string
+	execCommand = func(cmd string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return fakeExecCommand(fakeCmdOptions{
+			name: "test draining on signal",
+		}, cmd, args...)
+	}
+	defer func() { execCommand = exec.Command }()
+
+	signalChan := make(chan os.Signal, 1)
+	defer close(signalChan)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = StartEnvoyAndWait(signalChan, opts)
+	}()
+
+	time.Sleep(waitForEnvoyToStart)
+	signalStart := time.Now()
+	signalChan <- os.Interrupt
+	wg.Wait()
+	stoppedAfter := time.Since(signalStart)
+
+	if stoppedAfter < opts.DrainTime {
+		t.Errorf("Envoy stopped after %v, want at least the drain time %v", stoppedAfter, opts.DrainTime)
+	}
+
+	wantAdminCalls := []string{
+		"POST /healthcheck/fail",
+		"POST /drain_listeners?graceful",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(gotAdminCalls, wantAdminCalls) {
+		t.Errorf("got admin calls %v, want %v", gotAdminCalls, wantAdminCalls)
+	}
+
+	if !strings.Contains(strings.Join(gotArgs, " "), "--drain-time-s 1") {
+		t.Errorf("got Envoy args %v, want --drain-time-s", gotArgs)
+	}
+}
+
+func TestStartEnvoyAndWaitHotRestarts(t *testing.T) {
+	reloadChan := make(chan struct{})
+	opts := StartEnvoyOptions{
+		BinaryPath:       "binary",
+		ConfigPath:       "config",
+		LogLevel:         "loglevel",
+		TerminateTimeout: testTimeout,
+		ReloadChan:       reloadChan,
+	}
+
+	var mu sync.Mutex
+	var gotArgs [][]string
+	execCommand = func(cmd string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		gotArgs = append(gotArgs, args)
+		return fakeExecCommand(fakeCmdOptions{
+			name: fmt.Sprintf("test hot restart epoch %v", len(gotArgs)-1),
+		}, cmd, args...)
+	}
+	defer func() { execCommand = exec.Command }()
+
+	signalChan := make(chan os.Signal, 1)
+	defer close(signalChan)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = StartEnvoyAndWait(signalChan, opts)
+	}()
+
+	time.Sleep(waitForEnvoyToStart)
+	reloadChan <- struct{}{}
+	time.Sleep(waitForEnvoyToStart)
+	signalChan <- os.Interrupt
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(gotArgs) != 2 {
+		t.Fatalf("got %v Envoy processes started, want 2", len(gotArgs))
+	}
+	for epoch, args := range gotArgs {
+		joined := strings.Join(args, " ")
+		if want := fmt.Sprintf("--restart-epoch %v", epoch); !strings.Contains(joined, want) {
+			t.Errorf("got Envoy args %v, want %s", args, want)
+		}
+		if strings.Contains(joined, "--disable-hot-restart") {
+			t.Errorf("got Envoy args %v, want hot restart enabled", args)
+		}
+	}
+}
+
+func TestStartEnvoyAndWaitRollsBackFailedHotRestart(t *testing.T) {
+	reloadChan := make(chan struct{})
+	var rollbacks int32
+	rolledBack := make(chan struct{}, 1)
+	opts := StartEnvoyOptions{
+		BinaryPath:       "binary",
+		ConfigPath:       "config",
+		LogLevel:         "loglevel",
+		TerminateTimeout: testTimeout,
+		ReloadChan:       reloadChan,
+		RollbackConfig: func() error {
+			atomic.AddInt32(&rollbacks, 1)
+			select {
+			case rolledBack <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+	}
+
+	var mu sync.Mutex
+	var gotArgs [][]string
+	execCommand = func(cmd string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		gotArgs = append(gotArgs, args)
+		fakeOpts := fakeCmdOptions{
+			name: fmt.Sprintf("test failed hot restart %v", len(gotArgs)-1),
+		}
+		// The Envoy hot restarted with the new config fails to start.
+		if len(gotArgs) == 2 {
+			fakeOpts.exitStatus = "1"
+		}
+		return fakeExecCommand(fakeOpts, cmd, args...)
+	}
+	defer func() { execCommand = exec.Command }()
+
+	signalChan := make(chan os.Signal, 1)
+	defer close(signalChan)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- StartEnvoyAndWait(signalChan, opts)
+	}()
+
+	// The send returns once StartEnvoyAndWait hot restarts Envoy, and the
+	// rollback is only observed after the new Envoy exited.
+	reloadChan <- struct{}{}
+	select {
+	case <-rolledBack:
+	case <-time.After(helperCmdTimeout):
+		t.Fatalf("config was not rolled back after the failed hot restart")
+	}
+
+	// The previous Envoy keeps serving, and is hot restarted from again with
+	// the same epoch.
+	reloadChan <- struct{}{}
+	signalChan <- os.Interrupt
+
+	select {
+	case <-errChan:
+	case <-time.After(2 * helperCmdTimeout):
+		t.Fatalf("StartEnvoyAndWait() did not stop every Envoy")
+	}
+
+	if got := atomic.LoadInt32(&rollbacks); got != 1 {
+		t.Errorf("got config rolled back %v times, want 1", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(gotArgs) != 3 {
+		t.Fatalf("got %v Envoy processes started, want 3", len(gotArgs))
+	}
+	for i, wantEpoch := range []int{0, 1, 1} {
+		if want := fmt.Sprintf("--restart-epoch %v", wantEpoch); !strings.Contains(strings.Join(gotArgs[i], " "), want) {
+			t.Errorf("got Envoy args %v, want %s", gotArgs[i], want)
+		}
+	}
+}
+
+func TestValidateEnvoyConfig(t *testing.T) {
+	testCases := []struct {
+		name       string
+		exitStatus string
+		wantErr    bool
+	}{
+		{
+			name:       "valid config",
+			exitStatus: "0",
+		},
+		{
+			name:       "invalid config",
+			exitStatus: "1",
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotArgs []string
+			execCommand = func(cmd string, args ...string) *exec.Cmd {
+				gotArgs = args
+				return fakeExecCommand(fakeCmdOptions{
+					name:       tc.name,
+					exitStatus: tc.exitStatus,
+				}, cmd, args...)
+			}
+			defer func() { execCommand = exec.Command }()
+
+			err := ValidateEnvoyConfig("binary", "config")
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateEnvoyConfig() got error %v, want error %v", err, tc.wantErr)
+			}
+			if want := "--mode validate"; !strings.Contains(strings.Join(gotArgs, " "), want) {
+				t.Errorf("got Envoy args %v, want %s", gotArgs, want)
+			}
+		})
+	}
+}
+
+func TestStartEnvoyAndWaitRestartsCrashedEnvoy(t *testing.T) {
+	opts := StartEnvoyOptions{
+		BinaryPath:             "binary",
+		ConfigPath:             "config",
+		LogLevel:               "loglevel",
+		TerminateTimeout:       testTimeout,
+		MaxRestarts:            2,
+		RestartInitialInterval: time.Millisecond,
+		RestartMaxInterval:     10 * time.Millisecond,
+	}
+
+	starts := 0
+	execCommand = func(cmd string, args ...string) *exec.Cmd {
+		starts++
+		return fakeExecCommand(fakeCmdOptions{
+			name:       "test crashing",
+			exitStatus: "1",
+		}, cmd, args...)
+	}
+	defer func() { execCommand = exec.Command }()
+
+	crashesBefore, restartsBefore := envoyCrashes.Value(), envoyRestarts.Value()
+
+	signalChan := make(chan os.Signal, 1)
+	defer close(signalChan)
+	if err := StartEnvoyAndWait(signalChan, opts); err == nil {
+		t.Errorf("StartEnvoyAndWait(chan, %v) returned nil; should return an error after exhausting restarts", opts)
+	}
+
+	if starts != 3 {
+		t.Errorf("got Envoy started %v times, want 3", starts)
+	}
+	if got := envoyCrashes.Value() - crashesBefore; got != 3 {
+		t.Errorf("got %v crashes recorded, want 3", got)
+	}
+	if got := envoyRestarts.Value() - restartsBefore; got != 2 {
+		t.Errorf("got %v restarts recorded, want 2", got)
+	}
+}
+
 func TestHelperProcess(t *testing.T) {
 	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
 		return
@@ -169,3 +453,29 @@ func TestHelperProcess(t *testing.T) {
 		t.Fatalf("%s: timed out waiting for signal after %v", testName, testTimeout)
 	}
 }
+
+func TestCrashMetricsHandler(t *testing.T) {
+	handler := CrashMetricsHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /debug/vars got status %v, want 200", rec.Code)
+	}
+	var got map[string]int64
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /debug/vars got invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if len(got) != 2 {
+		t.Errorf("GET /debug/vars got %v, want only envoy_crashes and envoy_restarts", got)
+	}
+	if _, ok := got["envoy_crashes"]; !ok {
+		t.Errorf("GET /debug/vars got %v, want envoy_crashes", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /debug/pprof/ got status %v, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsrunner
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// configSignatureSuffix is appended to the config object name to locate its
+// detached signature.
+const configSignatureSuffix = ".sig"
+
+// configVerifier verifies the integrity of the fetched config before Envoy
+// is started with it.
+type configVerifier struct {
+	sha256    []byte
+	publicKey crypto.PublicKey
+}
+
+// newConfigVerifier returns nil if no verification is configured.
+func newConfigVerifier(opts FetchConfigOptions) (*configVerifier, error) {
+	if opts.ConfigSHA256 == "" && opts.ConfigPublicKeyPath == "" {
+		return nil, nil
+	}
+
+	v := &configVerifier{}
+	if opts.ConfigSHA256 != "" {
+		sum, err := hex.DecodeString(strings.TrimSpace(opts.ConfigSHA256))
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid config SHA-256 checksum %q, must be %d hex encoded bytes", opts.ConfigSHA256, sha256.Size)
+		}
+		v.sha256 = sum
+	}
+
+	if opts.ConfigPublicKeyPath != "" {
+		data, err := ioutil.ReadFile(opts.ConfigPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config public key: %v", err)
+		}
+		if v.publicKey, err = parsePublicKey(data); err != nil {
+			return nil, fmt.Errorf("failed to parse config public key %s: %v", opts.ConfigPublicKeyPath, err)
+		}
+	}
+	return v, nil
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func (v *configVerifier) needsSignature() bool {
+	return v.publicKey != nil
+}
+
+// verify checks the config against the expected checksum and, if a public key
+// is configured, the base64 encoded detached signature.
+//
+// ECDSA and RSA (PKCS #1 v1.5) signatures are over the SHA-256 digest of the
+// config, Ed25519 signatures are over the config itself.
+func (v *configVerifier) verify(config, signature []byte) error {
+	digest := sha256.Sum256(config)
+	if v.sha256 != nil && !bytes.Equal(digest[:], v.sha256) {
+		return fmt.Errorf("config SHA-256 checksum mismatch, got %x, want %x", digest, v.sha256)
+	}
+
+	if v.publicKey == nil {
+		return nil
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("config signature is not base64 encoded: %v", err)
+	}
+
+	switch key := v.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return fmt.Errorf("invalid ECDSA config signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("invalid RSA config signature: %v", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, config, sig) {
+			return fmt.Errorf("invalid Ed25519 config signature")
+		}
+	}
+	return nil
+}
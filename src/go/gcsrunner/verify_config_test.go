@@ -0,0 +1,232 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsrunner
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writePublicKey(t *testing.T, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigVerifier(t *testing.T) {
+	config := []byte(`{"static_resources": {}}`)
+	digest := sha256.Sum256(config)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSig := ed25519.Sign(edKey, config)
+
+	testCases := []struct {
+		name      string
+		opts      FetchConfigOptions
+		config    []byte
+		signature []byte
+		wantErr   string
+	}{
+		{
+			name:   "matching checksum",
+			opts:   FetchConfigOptions{ConfigSHA256: hex.EncodeToString(digest[:])},
+			config: config,
+		},
+		{
+			name:    "mismatching checksum",
+			opts:    FetchConfigOptions{ConfigSHA256: hex.EncodeToString(digest[:])},
+			config:  []byte("tampered"),
+			wantErr: "config SHA-256 checksum mismatch",
+		},
+		{
+			name:      "valid ECDSA signature",
+			opts:      FetchConfigOptions{ConfigPublicKeyPath: writePublicKey(t, &ecKey.PublicKey)},
+			config:    config,
+			signature: []byte(base64.StdEncoding.EncodeToString(ecSig) + "\n"),
+		},
+		{
+			name:      "invalid ECDSA signature",
+			opts:      FetchConfigOptions{ConfigPublicKeyPath: writePublicKey(t, &ecKey.PublicKey)},
+			config:    []byte("tampered"),
+			signature: []byte(base64.StdEncoding.EncodeToString(ecSig)),
+			wantErr:   "invalid ECDSA config signature",
+		},
+		{
+			name:      "valid RSA signature",
+			opts:      FetchConfigOptions{ConfigPublicKeyPath: writePublicKey(t, &rsaKey.PublicKey)},
+			config:    config,
+			signature: []byte(base64.StdEncoding.EncodeToString(rsaSig)),
+		},
+		{
+			name:      "invalid RSA signature",
+			opts:      FetchConfigOptions{ConfigPublicKeyPath: writePublicKey(t, &rsaKey.PublicKey)},
+			config:    []byte("tampered"),
+			signature: []byte(base64.StdEncoding.EncodeToString(rsaSig)),
+			wantErr:   "invalid RSA config signature",
+		},
+		{
+			name:      "valid Ed25519 signature",
+			opts:      FetchConfigOptions{ConfigPublicKeyPath: writePublicKey(t, edPub)},
+			config:    config,
+			signature: []byte(base64.StdEncoding.EncodeToString(edSig)),
+		},
+		{
+			name:      "signature not base64 encoded",
+			opts:      FetchConfigOptions{ConfigPublicKeyPath: writePublicKey(t, edPub)},
+			config:    config,
+			signature: edSig,
+			wantErr:   "config signature is not base64 encoded",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := newConfigVerifier(tc.opts)
+			if err != nil {
+				t.Fatalf("newConfigVerifier() got error %v", err)
+			}
+			err = v.verify(tc.config, tc.signature)
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("verify() got error %v, want no error", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("verify() got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewConfigVerifierError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    FetchConfigOptions
+		wantErr string
+	}{
+		{
+			name:    "invalid checksum",
+			opts:    FetchConfigOptions{ConfigSHA256: "abcd"},
+			wantErr: "invalid config SHA-256 checksum",
+		},
+		{
+			name:    "missing public key",
+			opts:    FetchConfigOptions{ConfigPublicKeyPath: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: "failed to read config public key",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newConfigVerifier(tc.opts); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("newConfigVerifier() got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadBytesRetriesUntilVerified(t *testing.T) {
+	tampered := []byte("tampered config")
+	config := []byte("genuine config")
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var configCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/envoy.json":
+			configCalls++
+			// Serve a tampered config first, which must be rejected.
+			if configCalls == 1 {
+				_, _ = w.Write(tampered)
+				return
+			}
+			_, _ = w.Write(config)
+		case "/envoy.json.sig":
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(edKey, config))))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldHTTPClient := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = oldHTTPClient }()
+
+	b, err := readBytes(FetchConfigOptions{
+		ConfigURL:                     server.URL + "/envoy.json",
+		ConfigPublicKeyPath:           writePublicKey(t, edPub),
+		FetchGCSObjectInitialInterval: fetchInitialInterval,
+		FetchGCSObjectTimeout:         fetchTimeout,
+	})
+	if err != nil {
+		t.Fatalf("readBytes() got error %v", err)
+	}
+	if diff := cmp.Diff(string(config), string(b)); diff != "" {
+		t.Errorf("readBytes() got unexpected value (-want/+got): %s", diff)
+	}
+	if configCalls != 2 {
+		t.Errorf("readBytes() fetched config %v times, want 2", configCalls)
+	}
+}
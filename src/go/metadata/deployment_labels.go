@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+const (
+	// The file of the namespace of the pod mounted by Kubernetes.
+	kubernetesNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Allows for unit tests to inject the environment of the deployment.
+var (
+	lookupEnv = os.LookupEnv
+	readFile  = ioutil.ReadFile
+)
+
+// FetchDeploymentLabels detects the labels of the deployment from the metadata
+// server and the environment variables:
+//   - Cloud Run: cloud_run_service, cloud_run_revision and
+//     cloud_run_configuration.
+//   - GKE: gke_cluster, gke_cluster_location, gke_namespace and gke_pod, in
+//     addition to the labels of the GCE instance of the node.
+//   - GCE: gce_instance and gce_instance_id.
+//
+// The labels that cannot be detected are omitted.
+func (mf *MetadataFetcher) FetchDeploymentLabels() map[string]string {
+	labels := make(map[string]string)
+	addLabel := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			labels[key] = value
+		}
+	}
+	addMetadataLabel := func(key, path string) {
+		if value, err := mf.fetchMetadata(path); err == nil {
+			addLabel(key, value)
+		}
+	}
+
+	if service, ok := lookupEnv("K_SERVICE"); ok {
+		// Cloud Run sets the environment variables of the container contract,
+		// and there is no instance to label.
+		addLabel("cloud_run_service", service)
+		if revision, ok := lookupEnv("K_REVISION"); ok {
+			addLabel("cloud_run_revision", revision)
+		}
+		if configuration, ok := lookupEnv("K_CONFIGURATION"); ok {
+			addLabel("cloud_run_configuration", configuration)
+		}
+		return labels
+	}
+
+	if _, ok := lookupEnv("KUBERNETES_SERVICE_HOST"); ok {
+		addMetadataLabel("gke_cluster", util.ClusterNamePath)
+		addMetadataLabel("gke_cluster_location", util.ClusterLocationPath)
+
+		if namespace, ok := lookupEnv("POD_NAMESPACE"); ok {
+			addLabel("gke_namespace", namespace)
+		} else if namespace, err := readFile(kubernetesNamespaceFile); err == nil {
+			addLabel("gke_namespace", string(namespace))
+		}
+		if pod, ok := lookupEnv("POD_NAME"); ok {
+			addLabel("gke_pod", pod)
+		} else if hostname, ok := lookupEnv("HOSTNAME"); ok {
+			// The hostname of a pod defaults to its name.
+			addLabel("gke_pod", hostname)
+		}
+	}
+
+	addMetadataLabel("gce_instance", util.InstanceNamePath)
+	addMetadataLabel("gce_instance_id", util.InstanceIDPath)
+	return labels
+}
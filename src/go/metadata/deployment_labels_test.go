@@ -0,0 +1,121 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFetchDeploymentLabels(t *testing.T) {
+	ts := httptest.NewServer(MakeStubHandler(StubValues{
+		"instance/name":                        "gke-prod-pool-1-abcd",
+		"instance/id":                          "4242424242",
+		"instance/attributes/cluster-name":     "prod",
+		"instance/attributes/cluster-location": "us-central1",
+	}))
+	defer ts.Close()
+
+	testData := []struct {
+		desc          string
+		env           map[string]string
+		namespaceFile string
+		wantLabels    map[string]string
+	}{
+		{
+			desc: "Cloud Run",
+			env: map[string]string{
+				"K_SERVICE":       "bookstore",
+				"K_REVISION":      "bookstore-00001-abc",
+				"K_CONFIGURATION": "bookstore",
+			},
+			wantLabels: map[string]string{
+				"cloud_run_service":       "bookstore",
+				"cloud_run_revision":      "bookstore-00001-abc",
+				"cloud_run_configuration": "bookstore",
+			},
+		},
+		{
+			desc: "GKE with the downward API",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"POD_NAMESPACE":           "bookstore",
+				"POD_NAME":                "bookstore-7d4b9c-xyz",
+				"HOSTNAME":                "ignored",
+			},
+			wantLabels: map[string]string{
+				"gke_cluster":          "prod",
+				"gke_cluster_location": "us-central1",
+				"gke_namespace":        "bookstore",
+				"gke_pod":              "bookstore-7d4b9c-xyz",
+				"gce_instance":         "gke-prod-pool-1-abcd",
+				"gce_instance_id":      "4242424242",
+			},
+		},
+		{
+			desc: "GKE without the downward API",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"HOSTNAME":                "bookstore-7d4b9c-xyz",
+			},
+			namespaceFile: "bookstore\n",
+			wantLabels: map[string]string{
+				"gke_cluster":          "prod",
+				"gke_cluster_location": "us-central1",
+				"gke_namespace":        "bookstore",
+				"gke_pod":              "bookstore-7d4b9c-xyz",
+				"gce_instance":         "gke-prod-pool-1-abcd",
+				"gce_instance_id":      "4242424242",
+			},
+		},
+		{
+			desc: "GCE",
+			wantLabels: map[string]string{
+				"gce_instance":    "gke-prod-pool-1-abcd",
+				"gce_instance_id": "4242424242",
+			},
+		},
+	}
+
+	defer func() {
+		lookupEnv = os.LookupEnv
+		readFile = ioutil.ReadFile
+	}()
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			lookupEnv = func(key string) (string, bool) {
+				value, ok := tc.env[key]
+				return value, ok
+			}
+			readFile = func(path string) ([]byte, error) {
+				if path != kubernetesNamespaceFile || tc.namespaceFile == "" {
+					return nil, fmt.Errorf("file %s not found", path)
+				}
+				return []byte(tc.namespaceFile), nil
+			}
+
+			mf := NewMockMetadataFetcher(ts.URL, time.Now())
+			if diff := cmp.Diff(tc.wantLabels, mf.FetchDeploymentLabels()); diff != "" {
+				t.Errorf("FetchDeploymentLabels() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -156,6 +157,12 @@ func (mf *MetadataFetcher) FetchRolloutStrategy() (string, error) {
 	return mf.fetchMetadata(util.RolloutStrategyPath)
 }
 
+// FetchInstanceAttribute fetches the value of the custom instance metadata
+// attribute.
+func (mf *MetadataFetcher) FetchInstanceAttribute(name string) (string, error) {
+	return mf.fetchMetadata(util.InstanceAttributesPath + name)
+}
+
 func (mf *MetadataFetcher) FetchIdentityJWTToken(audience string) (string, time.Duration, error) {
 	now := mf.timeNow()
 	// Follow the similar logic as GCE metadata server, where returned token will be valid for at
@@ -167,7 +174,7 @@ func (mf *MetadataFetcher) FetchIdentityJWTToken(audience string) (string, time.
 		}
 	}
 
-	identityTokenURI := util.IdentityTokenPath + "?audience=" + audience + "&format=standard"
+	identityTokenURI := util.IdentityTokenPath + "?audience=" + url.QueryEscape(audience) + "&format=standard"
 	token, err := mf.fetchMetadata(identityTokenURI)
 	if err != nil {
 		return "", 0, err
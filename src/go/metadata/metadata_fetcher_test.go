@@ -36,6 +36,7 @@ const (
 	fakeRegionPath       = "projects/4242424242/regions/us-central1"
 	fakeRegion           = "us-central1"
 	fakeProjectID        = "gcpproxy"
+	fakeTenantMapping    = `{"tenants": [{"name": "acme", "backendAddress": "https://acme.example.com", "consumerNumbers": ["123"]}]}`
 )
 
 type testToken struct {
@@ -202,6 +203,21 @@ func TestFetchConfigId(t *testing.T) {
 	}
 }
 
+func TestFetchInstanceAttribute(t *testing.T) {
+	ts := util.InitMockServer(fakeTenantMapping)
+	defer ts.Close()
+
+	mf := NewMockMetadataFetcher(ts.GetURL(), time.Now())
+
+	value, err := mf.FetchInstanceAttribute("espv2-tenant-mapping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != fakeTenantMapping {
+		t.Errorf("FetchInstanceAttribute = %s, want %s", value, fakeTenantMapping)
+	}
+}
+
 func TestFetchGCPAttributes(t *testing.T) {
 	testData := []struct {
 		desc                  string
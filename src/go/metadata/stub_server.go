@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+const (
+	stubPathPrefix = "/computeMetadata/v1/"
+)
+
+// StubValues are the static values served by the metadata server stub, keyed
+// by the metadata paths relative to "/computeMetadata/v1/", such as
+// "project/project-id" or "instance/service-accounts/default/token".
+type StubValues map[string]string
+
+// ReadStubFile reads the StubValues from the JSON file of flag
+// --metadata_stub_file.
+func ReadStubFile(path string) (StubValues, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read metadata stub file %q: %v", path, err)
+	}
+
+	values := StubValues{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("invalid metadata stub file %q, it should be a JSON object of strings: %v", path, err)
+	}
+	for key := range values {
+		if key == "" || strings.HasPrefix(key, "/") {
+			return nil, fmt.Errorf("invalid metadata stub file %q, path %q must be relative to %s", path, key, stubPathPrefix)
+		}
+	}
+	return values, nil
+}
+
+// MakeStubHandler creates the handler of the metadata server stub, so ESPv2
+// can run outside GCP without intercepting the traffic to the metadata server.
+//
+// It follows the following scheme:
+// Request: GET / or GET /computeMetadata/v1/ with the "Metadata-Flavor: Google"
+// header, used to check the metadata server is reachable.
+// Response: 200.
+// Request: GET /computeMetadata/v1/PATH with the "Metadata-Flavor: Google"
+// header. The query parameters, such as the audience of the identity token,
+// are ignored.
+// Response: 200 with the value of PATH in the stub file, 404 if not found.
+func MakeStubHandler(values StubValues) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, `missing header "Metadata-Flavor: Google"`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+
+		if r.URL.Path == "/" || r.URL.Path == stubPathPrefix {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		value, ok := values[strings.TrimPrefix(r.URL.Path, stubPathPrefix)]
+		if !ok || !strings.HasPrefix(r.URL.Path, stubPathPrefix) {
+			glog.Infof("metadata server stub has no value of %q", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(value))
+	})
+}
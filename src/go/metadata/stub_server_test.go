@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"google.golang.org/protobuf/proto"
+
+	scpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/service_control"
+)
+
+func TestReadStubFile(t *testing.T) {
+	testData := []struct {
+		desc       string
+		content    string
+		wantValues StubValues
+		wantError  string
+	}{
+		{
+			desc:    "Success",
+			content: `{"project/project-id": "gcpproxy", "instance/zone": "projects/4242424242/zones/us-west-1b"}`,
+			wantValues: StubValues{
+				"project/project-id": "gcpproxy",
+				"instance/zone":      "projects/4242424242/zones/us-west-1b",
+			},
+		},
+		{
+			desc:      "Not a JSON object of strings",
+			content:   `{"project/project-id": 123}`,
+			wantError: "it should be a JSON object of strings",
+		},
+		{
+			desc:      "Absolute path",
+			content:   `{"/computeMetadata/v1/project/project-id": "gcpproxy"}`,
+			wantError: `path "/computeMetadata/v1/project/project-id" must be relative to /computeMetadata/v1/`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metadata.json")
+			if err := ioutil.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := ReadStubFile(path)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("ReadStubFile() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadStubFile() got error: %v", err)
+			}
+			if len(values) != len(tc.wantValues) {
+				t.Errorf("ReadStubFile() got %v, want %v", values, tc.wantValues)
+			}
+			for key, want := range tc.wantValues {
+				if got := values[key]; got != want {
+					t.Errorf("ReadStubFile() got %q for %q, want %q", got, key, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataFetcherWithStub(t *testing.T) {
+	ts := httptest.NewServer(MakeStubHandler(StubValues{
+		"instance/service-accounts/default/token":    fakeToken,
+		"instance/service-accounts/default/identity": fakeIdentityJwtToken,
+		"project/project-id":                         fakeProjectID,
+		"instance/zone":                              fakeZonePath,
+	}))
+	defer ts.Close()
+
+	mf := NewMockMetadataFetcher(ts.URL, time.Now())
+
+	if token, _, err := mf.FetchAccessToken(); err != nil || token != "ya29.new" {
+		t.Errorf("FetchAccessToken() got %q, %v, want ya29.new", token, err)
+	}
+	if token, _, err := mf.FetchIdentityJWTToken("https://backend.com"); err != nil || token != fakeIdentityJwtToken {
+		t.Errorf("FetchIdentityJWTToken() got %q, %v, want %q", token, err, fakeIdentityJwtToken)
+	}
+
+	attrs, err := mf.FetchGCPAttributes()
+	if err != nil {
+		t.Fatalf("FetchGCPAttributes() got error: %v", err)
+	}
+	wantAttrs := &scpb.GcpAttributes{
+		ProjectId: fakeProjectID,
+		Zone:      fakeZone,
+		Platform:  util.GCE,
+	}
+	if !proto.Equal(attrs, wantAttrs) {
+		t.Errorf("FetchGCPAttributes() got %v, want %v", attrs, wantAttrs)
+	}
+
+	if _, err := mf.FetchServiceName(); err == nil || !strings.Contains(err.Error(), "status code 404") {
+		t.Errorf("FetchServiceName() got error %v, want not found", err)
+	}
+}
+
+func TestMetadataStubRequiresMetadataFlavor(t *testing.T) {
+	ts := httptest.NewServer(MakeStubHandler(StubValues{
+		"project/project-id": fakeProjectID,
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/computeMetadata/v1/project/project-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %v, want %v", resp.StatusCode, http.StatusForbidden)
+	}
+}
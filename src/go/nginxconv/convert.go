@@ -0,0 +1,670 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nginxconv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flag is an ESPv2 flag converted from the nginx config.
+type Flag struct {
+	Name  string
+	Value string
+
+	// Line is the line of the directive the flag is converted from.
+	Line int
+}
+
+func (f Flag) String() string {
+	return fmt.Sprintf("--%s=%s", f.Name, f.Value)
+}
+
+// Finding is a directive of the nginx config that needs the attention of the
+// migration.
+type Finding struct {
+	Line      int
+	Directive string
+	Reason    string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %q: %s", f.Line, f.Directive, f.Reason)
+}
+
+// Conversion is the result of the conversion of an nginx config.
+type Conversion struct {
+	// Flags are the ESPv2 flags equivalent to the nginx config.
+	Flags []Flag
+
+	// Unsupported are the directives without an equivalent in ESPv2, they
+	// must be migrated manually.
+	Unsupported []Finding
+
+	// Notes are the directives converted to the flags with a different
+	// behavior in ESPv2.
+	Notes []Finding
+}
+
+// ignoredDirectives are the directives of the nginx process, or the ones
+// whose behavior is already the default of ESPv2.
+var ignoredDirectives = map[string]bool{
+	"client_body_buffer_size": true,
+	"client_body_temp_path":   true,
+	"daemon":                  true,
+	"default_type":            true,
+	"error_log":               true,
+	"events":                  true,
+	"fastcgi_temp_path":       true,
+	"http2":                   true,
+	"keepalive_requests":      true,
+	"pid":                     true,
+	"proxy_buffering":         true,
+	"proxy_http_version":      true,
+	"proxy_redirect":          true,
+	"proxy_request_buffering": true,
+	"proxy_temp_path":         true,
+	"real_ip_recursive":       true,
+	"scgi_temp_path":          true,
+	"sendfile":                true,
+	"server_name":             true,
+	"server_tokens":           true,
+	"tcp_nodelay":             true,
+	"tcp_nopush":              true,
+	"types":                   true,
+	"user":                    true,
+	"uwsgi_temp_path":         true,
+	"worker_processes":        true,
+	"worker_rlimit_nofile":    true,
+}
+
+// sslProtocols maps the nginx ssl_protocols to the values of
+// --ssl_minimum_protocol and --ssl_maximum_protocol, in order.
+var sslProtocols = []struct {
+	nginx string
+	espv2 string
+}{
+	{"TLSv1", "TLSv1.0"},
+	{"TLSv1.1", "TLSv1.1"},
+	{"TLSv1.2", "TLSv1.2"},
+	{"TLSv1.3", "TLSv1.3"},
+}
+
+// Convert converts the directives of an ESP(v1) nginx config to the ESPv2
+// flags. The directives without an ESPv2 equivalent are reported as
+// unsupported, the conversion never fails.
+func Convert(directives []*Directive) *Conversion {
+	c := &converter{
+		conversion: &Conversion{},
+		upstreams:  make(map[string]*Directive),
+	}
+	c.collectUpstreams(directives)
+	c.convertBlock(directives)
+	c.convertListens()
+	c.setHeaderFlag("add_request_headers", c.requestHeaders)
+	c.setHeaderFlag("add_response_headers", c.responseHeaders)
+	return c.conversion
+}
+
+type converter struct {
+	conversion *Conversion
+
+	// upstreams are the upstream blocks by name, for proxy_pass and grpc_pass.
+	upstreams map[string]*Directive
+
+	// listens are the listen directives of the API servers.
+	listens []*Directive
+
+	// sslCertificate is the ssl_certificate directive of the API servers.
+	sslCertificate *Directive
+
+	requestHeaders  []*Directive
+	responseHeaders []*Directive
+}
+
+func (c *converter) setFlag(d *Directive, name, value string) {
+	for _, f := range c.conversion.Flags {
+		if f.Name != name {
+			continue
+		}
+		if f.Value != value {
+			c.unsupported(d, fmt.Sprintf("conflicts with --%s=%s converted from line %d, ESPv2 applies the flags to all the APIs", name, f.Value, f.Line))
+		}
+		return
+	}
+	c.conversion.Flags = append(c.conversion.Flags, Flag{
+		Name:  name,
+		Value: value,
+		Line:  d.Line,
+	})
+}
+
+func (c *converter) unsupported(d *Directive, reason string) {
+	c.conversion.Unsupported = append(c.conversion.Unsupported, Finding{
+		Line:      d.Line,
+		Directive: d.String(),
+		Reason:    reason,
+	})
+}
+
+func (c *converter) note(d *Directive, reason string) {
+	c.conversion.Notes = append(c.conversion.Notes, Finding{
+		Line:      d.Line,
+		Directive: d.String(),
+		Reason:    reason,
+	})
+}
+
+func (c *converter) collectUpstreams(directives []*Directive) {
+	for _, d := range directives {
+		if d.Name == "upstream" && len(d.Args) == 1 {
+			c.upstreams[d.Args[0]] = d
+			continue
+		}
+		c.collectUpstreams(d.Block)
+	}
+}
+
+func (c *converter) convertBlock(directives []*Directive) {
+	for _, d := range directives {
+		if ignoredDirectives[d.Name] {
+			continue
+		}
+
+		switch d.Name {
+		case "http":
+			c.convertBlock(d.Block)
+		case "server":
+			c.convertServer(d)
+		case "location":
+			c.convertLocation(d)
+		case "upstream":
+			c.convertUpstream(d)
+		case "endpoints":
+			c.convertEndpoints(d)
+		case "include":
+			if len(d.Args) != 1 || filepath.Base(d.Args[0]) != "mime.types" {
+				c.unsupported(d, "the included files are not read, convert them separately")
+			}
+		case "listen":
+			c.listens = append(c.listens, d)
+		case "ssl_certificate", "ssl_certificate_key":
+			c.convertSslCertificate(d)
+		case "ssl_protocols":
+			c.convertSslProtocols(d)
+		case "ssl_ciphers":
+			if len(d.Args) != 1 {
+				c.unsupported(d, "expected one argument")
+				break
+			}
+			c.setFlag(d, "ssl_server_cipher_suites", strings.ReplaceAll(d.Args[0], ":", ","))
+		case "ssl_client_certificate":
+			if len(d.Args) != 1 {
+				c.unsupported(d, "expected one argument")
+				break
+			}
+			c.setFlag(d, "ssl_server_root_cert_path", d.Args[0])
+		case "proxy_pass":
+			c.convertProxyPass(d, "http")
+		case "grpc_pass":
+			c.convertProxyPass(d, "grpc")
+		case "client_max_body_size":
+			c.convertClientMaxBodySize(d)
+		case "keepalive_timeout":
+			if timeout, ok := c.parseDuration(d); ok {
+				c.setFlag(d, "downstream_idle_timeout", formatDuration(timeout))
+			}
+		case "proxy_connect_timeout":
+			if timeout, ok := c.parseDuration(d); ok {
+				c.setFlag(d, "backend_cluster_connect_timeout", formatDuration(timeout))
+			}
+		case "proxy_read_timeout", "grpc_read_timeout":
+			if timeout, ok := c.parseDuration(d); ok {
+				c.setFlag(d, "operation_request_timeouts", fmt.Sprintf("*=%s", formatDuration(timeout)))
+				c.note(d, `nginx bounds the time between two reads from the backend, ESPv2 bounds the full response and overrides the "deadline" in the "x-google-backend" extension`)
+			}
+		case "underscores_in_headers":
+			if len(d.Args) == 1 && d.Args[0] == "on" {
+				c.setFlag(d, "underscores_in_headers", "true")
+			}
+		case "resolver":
+			c.convertResolver(d)
+		case "access_log":
+			c.convertAccessLog(d)
+		case "gzip":
+			if len(d.Args) == 1 && d.Args[0] == "on" {
+				c.setFlag(d, "enable_response_compression", "true")
+				c.note(d, "ESPv2 compresses the responses with brotli too, as accepted by the clients")
+			}
+		case "gzip_types":
+			c.setFlag(d, "response_compression_content_types", strings.Join(d.Args, ","))
+		case "gzip_min_length":
+			if len(d.Args) == 1 {
+				c.setFlag(d, "response_compression_min_length", d.Args[0])
+			}
+		case "gzip_comp_level":
+			if len(d.Args) == 1 {
+				c.setFlag(d, "response_compression_gzip_level", d.Args[0])
+			}
+		case "proxy_set_header", "grpc_set_header":
+			c.convertHeader(d, d.Args, &c.requestHeaders)
+		case "add_header":
+			c.convertHeader(d, d.Args, &c.responseHeaders)
+		case "more_set_headers":
+			for _, arg := range d.Args {
+				name, value, _ := strings.Cut(arg, ":")
+				c.convertHeader(d, []string{strings.TrimSpace(name), strings.TrimSpace(value)}, &c.responseHeaders)
+			}
+		case "real_ip_header", "set_real_ip_from":
+			c.unsupported(d, "ESPv2 takes the client IP from X-Forwarded-For after skipping the trusted proxies, set --envoy_xff_num_trusted_hops to the number of proxies in front of ESPv2")
+		case "rewrite", "return":
+			c.unsupported(d, "use the path translation of the \"x-google-backend\" extension, or --redirect_rules for the redirects")
+		case "endpoints_status", "stub_status":
+			c.unsupported(d, "the status of ESPv2 is served by the Envoy admin, see --admin_port")
+		default:
+			c.unsupported(d, "no ESPv2 equivalent")
+		}
+	}
+}
+
+// convertServer converts the server blocks of the APIs. The status server of
+// ESP(v1) is not converted.
+func (c *converter) convertServer(server *Directive) {
+	for _, d := range server.Block {
+		if d.Name != "location" {
+			continue
+		}
+		for _, child := range d.Block {
+			if child.Name == "endpoints_status" || child.Name == "stub_status" {
+				c.unsupported(server, "the status server of ESP(v1) is replaced by the Envoy admin, see --admin_port")
+				return
+			}
+		}
+	}
+	c.convertBlock(server.Block)
+}
+
+// convertLocation converts the health check locations, and the locations of
+// the APIs. ESPv2 routes the requests by the HTTP rules of the service config
+// instead of the locations.
+func (c *converter) convertLocation(location *Directive) {
+	if len(location.Args) == 0 {
+		c.unsupported(location, "expected the location path")
+		return
+	}
+	path := location.Args[len(location.Args)-1]
+
+	for _, d := range location.Block {
+		if d.Name == "return" && len(d.Args) > 0 && d.Args[0] == "200" {
+			c.setFlag(location, "healthz", strings.TrimPrefix(path, "/"))
+			return
+		}
+	}
+
+	isAPI := false
+	for _, d := range location.Block {
+		if d.Name == "endpoints" || d.Name == "proxy_pass" || d.Name == "grpc_pass" {
+			isAPI = true
+		}
+	}
+	if !isAPI {
+		c.unsupported(location, "ESPv2 routes the requests by the HTTP rules of the service config, see --static_paths and --redirect_rules for the other paths")
+		return
+	}
+	if len(location.Args) != 1 || path != "/" {
+		c.note(location, "ESPv2 routes the requests by the HTTP rules of the service config, the location is not used")
+	}
+	c.convertBlock(location.Block)
+}
+
+func (c *converter) convertUpstream(upstream *Directive) {
+	servers := 0
+	for _, d := range upstream.Block {
+		switch d.Name {
+		case "server":
+			servers++
+			if servers > 1 {
+				c.unsupported(d, "ESPv2 proxies to one backend address, see --backend_failover_addresses")
+			}
+		case "keepalive", "keepalive_timeout", "keepalive_requests":
+		default:
+			c.unsupported(d, "no ESPv2 equivalent")
+		}
+	}
+}
+
+// convertEndpoints converts the endpoints block of the ESP(v1) nginx module.
+func (c *converter) convertEndpoints(endpoints *Directive) {
+	for _, d := range endpoints.Block {
+		switch {
+		case d.Name == "on":
+		case d.Name == "off":
+			c.setFlag(d, "skip_service_control_filter", "true")
+			c.setFlag(d, "skip_jwt_authn_filter", "true")
+		case d.Name == "api" && len(d.Args) == 1:
+			c.setFlag(d, "service_json_path", d.Args[0])
+		case d.Name == "metadata_server" && len(d.Args) == 0:
+		case d.Name == "metadata_server" && len(d.Args) == 1 && d.Args[0] == "off":
+			c.setFlag(d, "non_gcp", "true")
+		case d.Name == "metadata_server" && len(d.Args) == 1:
+			c.setFlag(d, "metadata_url", d.Args[0])
+		case d.Name == "google_authentication_secret" && len(d.Args) == 1:
+			c.setFlag(d, "service_account_key", d.Args[0])
+		case d.Name == "server_config":
+			c.unsupported(d, "the server config of ESP(v1) is not read, convert its settings to the ESPv2 flags manually")
+		default:
+			c.unsupported(d, "no ESPv2 equivalent")
+		}
+	}
+}
+
+// convertListens converts the listen directives of the API servers to the
+// listener port, the HTTPS one if any.
+func (c *converter) convertListens() {
+	if len(c.listens) == 0 {
+		return
+	}
+
+	listen := c.listens[0]
+	for _, d := range c.listens {
+		if isSslListen(d) {
+			listen = d
+			break
+		}
+	}
+	for _, d := range c.listens {
+		if d != listen {
+			c.unsupported(d, "ESPv2 serves one listener port, see --http_redirect_port to redirect the plain HTTP port to HTTPS")
+		}
+	}
+
+	if len(listen.Args) == 0 {
+		c.unsupported(listen, "expected the listen address")
+		return
+	}
+	host, port, err := parseListenAddress(listen.Args[0])
+	if err != nil {
+		c.unsupported(listen, err.Error())
+		return
+	}
+	c.setFlag(listen, "listener_port", port)
+	if host != "" && host != "*" && host != "0.0.0.0" {
+		c.setFlag(listen, "listener_address", host)
+	}
+
+	if isSslListen(listen) && c.sslCertificate == nil {
+		c.unsupported(listen, "the ssl_certificate of the port is not found, set --ssl_server_cert_path")
+	}
+}
+
+func isSslListen(d *Directive) bool {
+	for i, arg := range d.Args {
+		if i > 0 && arg == "ssl" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseListenAddress parses the "address:port", "address" and "port" of the
+// listen directive.
+func parseListenAddress(address string) (string, string, error) {
+	if strings.HasPrefix(address, "unix:") {
+		return "", "", fmt.Errorf("ESPv2 does not listen on the unix domain sockets")
+	}
+	if _, err := strconv.ParseUint(address, 10, 16); err == nil {
+		return "", address, nil
+	}
+
+	host, port := address, "80"
+	if i := strings.LastIndex(address, ":"); i >= 0 && !strings.HasSuffix(address, "]") {
+		host, port = address[:i], address[i+1:]
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("%q is not a valid port", port)
+	}
+	return strings.Trim(host, "[]"), port, nil
+}
+
+// convertSslCertificate converts the certificate and the key of the server
+// to their directory. ESPv2 reads the "server.crt" and "server.key" files of
+// the directory, or "nginx.crt" and "nginx.key" under "/etc/nginx/ssl" for
+// ESP(v1) compatibility.
+func (c *converter) convertSslCertificate(d *Directive) {
+	if len(d.Args) != 1 {
+		c.unsupported(d, "expected one argument")
+		return
+	}
+	if d.Name == "ssl_certificate" {
+		c.sslCertificate = d
+	}
+
+	dir, file := filepath.Split(d.Args[0])
+	dir = filepath.Clean(dir)
+	c.setFlag(d, "ssl_server_cert_path", dir)
+
+	wantFile := "server"
+	if strings.Contains(dir, "/etc/nginx/ssl") {
+		wantFile = "nginx"
+	}
+	wantFile += ".crt"
+	if d.Name == "ssl_certificate_key" {
+		wantFile = strings.TrimSuffix(wantFile, ".crt") + ".key"
+	}
+	if file != wantFile {
+		c.note(d, fmt.Sprintf("ESPv2 reads the file %q of the directory, rename the file", wantFile))
+	}
+}
+
+func (c *converter) convertSslProtocols(d *Directive) {
+	minimum, maximum := -1, -1
+	for _, arg := range d.Args {
+		found := false
+		for i, protocol := range sslProtocols {
+			if arg != protocol.nginx {
+				continue
+			}
+			found = true
+			if minimum < 0 || i < minimum {
+				minimum = i
+			}
+			if i > maximum {
+				maximum = i
+			}
+		}
+		if !found {
+			c.unsupported(d, fmt.Sprintf("protocol %q is not supported by ESPv2", arg))
+		}
+	}
+	if minimum < 0 {
+		return
+	}
+	c.setFlag(d, "ssl_minimum_protocol", sslProtocols[minimum].espv2)
+	c.setFlag(d, "ssl_maximum_protocol", sslProtocols[maximum].espv2)
+}
+
+// convertProxyPass converts the backend of the proxy_pass and grpc_pass
+// directives, resolving the upstream blocks.
+func (c *converter) convertProxyPass(d *Directive, defaultScheme string) {
+	if len(d.Args) != 1 {
+		c.unsupported(d, "expected one argument")
+		return
+	}
+	if strings.Contains(d.Args[0], "$") {
+		c.unsupported(d, "the backend address cannot have nginx variables")
+		return
+	}
+
+	scheme, address := defaultScheme, d.Args[0]
+	if i := strings.Index(address, "://"); i >= 0 {
+		scheme, address = address[:i], address[i+3:]
+	}
+	host, path, hasPath := strings.Cut(address, "/")
+	if hasPath && path != "" {
+		c.unsupported(d, "ESPv2 does not rewrite the path to the backend, use the path translation of the \"x-google-backend\" extension")
+	}
+
+	if upstream, ok := c.upstreams[host]; ok {
+		host = ""
+		for _, server := range upstream.Block {
+			if server.Name == "server" && len(server.Args) > 0 {
+				host = server.Args[0]
+				break
+			}
+		}
+		if host == "" || strings.HasPrefix(host, "unix:") {
+			c.unsupported(d, fmt.Sprintf("upstream %q has no TCP server", upstream.Args[0]))
+			return
+		}
+	}
+	c.setFlag(d, "backend_address", fmt.Sprintf("%s://%s", scheme, host))
+}
+
+func (c *converter) convertClientMaxBodySize(d *Directive) {
+	if len(d.Args) != 1 {
+		c.unsupported(d, "expected one argument")
+		return
+	}
+	size, err := parseSize(d.Args[0])
+	if err != nil {
+		c.unsupported(d, err.Error())
+		return
+	}
+	// 0 disables the check in nginx, the same as the ESPv2 default.
+	if size > 0 {
+		c.setFlag(d, "max_request_bytes", strconv.FormatInt(size, 10))
+	}
+}
+
+func (c *converter) convertResolver(d *Directive) {
+	var addresses []string
+	for _, arg := range d.Args {
+		// Such as "valid=30s" and "ipv6=off".
+		if strings.Contains(arg, "=") {
+			continue
+		}
+		addresses = append(addresses, arg)
+	}
+	c.setFlag(d, "dns_resolver_addresses", strings.Join(addresses, ";"))
+}
+
+func (c *converter) convertAccessLog(d *Directive) {
+	if len(d.Args) == 0 || d.Args[0] == "off" {
+		return
+	}
+	c.setFlag(d, "access_log", d.Args[0])
+	if len(d.Args) > 1 {
+		c.note(d, "the nginx log format is not converted, see --access_log_format")
+	}
+}
+
+// convertHeader collects the headers of the constant values. The headers of
+// the nginx variables are either added by ESPv2 itself or not supported.
+func (c *converter) convertHeader(d *Directive, args []string, headers *[]*Directive) {
+	if len(args) < 2 || args[0] == "" {
+		c.unsupported(d, "expected the header name and value")
+		return
+	}
+	name, value := args[0], args[1]
+	if strings.Contains(value, "$") {
+		switch strings.ToLower(name) {
+		case "x-forwarded-for", "x-real-ip", "host", "x-forwarded-proto":
+			c.note(d, "ESPv2 sets X-Forwarded-For, X-Forwarded-Proto and Host to the backend itself")
+		default:
+			c.unsupported(d, "the header values cannot have nginx variables")
+		}
+		return
+	}
+	*headers = append(*headers, &Directive{
+		Name: name,
+		Args: []string{value},
+		Line: d.Line,
+	})
+}
+
+func (c *converter) setHeaderFlag(name string, headers []*Directive) {
+	if len(headers) == 0 {
+		return
+	}
+	var values []string
+	for _, h := range headers {
+		values = append(values, fmt.Sprintf("%s=%s", h.Name, h.Args[0]))
+	}
+	c.setFlag(headers[0], name, strings.Join(values, ";"))
+}
+
+// parseDuration parses the nginx time of the directive, in seconds if it has
+// no unit.
+func (c *converter) parseDuration(d *Directive) (time.Duration, bool) {
+	if len(d.Args) == 0 {
+		c.unsupported(d, "expected the time")
+		return 0, false
+	}
+
+	value := d.Args[0]
+	if _, err := strconv.ParseUint(value, 10, 32); err == nil {
+		value += "s"
+	}
+	// nginx "d" days are not supported by time.ParseDuration.
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.ParseUint(days, 10, 32); err == nil {
+			value = fmt.Sprintf("%dh", n*24)
+		}
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		c.unsupported(d, fmt.Sprintf("%q is not a valid time", d.Args[0]))
+		return 0, false
+	}
+	return timeout, true
+}
+
+// parseSize parses the nginx size such as "32m", in bytes if it has no unit.
+func parseSize(value string) (int64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("%q is not a valid size", value)
+	}
+	multiplier := int64(1)
+	number := value
+	switch strings.ToLower(value[len(value)-1:]) {
+	case "k":
+		multiplier = 1 << 10
+		number = value[:len(value)-1]
+	case "m":
+		multiplier = 1 << 20
+		number = value[:len(value)-1]
+	case "g":
+		multiplier = 1 << 30
+		number = value[:len(value)-1]
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("%q is not a valid size", value)
+	}
+	return size * multiplier, nil
+}
+
+// formatDuration formats the duration in seconds if it is a whole number
+// of seconds, such as "600s" instead of "10m0s".
+func formatDuration(d time.Duration) string {
+	if d%time.Second == 0 {
+		return fmt.Sprintf("%ds", int64(d/time.Second))
+	}
+	return d.String()
+}
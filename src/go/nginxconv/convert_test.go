@@ -0,0 +1,272 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nginxconv
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// espV1Config is the nginx config generated by ESP(v1) for an HTTPS
+// deployment, with a few custom directives.
+const espV1Config = `daemon off;
+user nginx nginx;
+pid /var/run/nginx.pid;
+worker_processes 1;
+error_log stderr warn;
+
+events { worker_connections 10240; }
+
+http {
+  include /etc/nginx/mime.types;
+  server_tokens off;
+  client_max_body_size 32m;
+  underscores_in_headers on;
+  keepalive_timeout 600s;
+  proxy_read_timeout 10m;
+
+  upstream app_server0 {
+    server localhost:8081;
+    keepalive 128;
+  }
+
+  set_real_ip_from 0.0.0.0/0;
+  real_ip_header X-Forwarded-For;
+  real_ip_recursive on;
+
+  server {
+    server_name "";
+    listen 8080;
+    listen 443 ssl http2;
+    ssl_certificate /etc/nginx/ssl/nginx.crt;
+    ssl_certificate_key /etc/nginx/ssl/nginx.key;
+    ssl_protocols TLSv1.2 TLSv1.3;
+    access_log /dev/stdout;
+
+    location = /healthz {
+      return 200;
+      access_log off;
+    }
+
+    location / {
+      endpoints {
+        api /etc/nginx/endpoints/service.json;
+        metadata_server http://169.254.169.254;
+        server_config /etc/nginx/server_config.pb.txt;
+        on;
+      }
+      proxy_pass http://app_server0;
+      proxy_redirect off;
+      proxy_set_header X-Real-IP $remote_addr;
+      proxy_set_header X-Api-Version v1;
+      add_header X-Frame-Options DENY;
+      limit_req zone=one burst=5;
+    }
+
+    include /var/lib/nginx/extra/*.conf;
+  }
+
+  server {
+    listen 8090;
+    location /endpoints_status {
+      endpoints_status;
+    }
+  }
+}
+`
+
+func TestConvert(t *testing.T) {
+	testData := []struct {
+		desc            string
+		config          string
+		wantFlags       []Flag
+		wantUnsupported []Finding
+		wantNotes       []Finding
+	}{
+		{
+			desc:   "ESP(v1) config",
+			config: espV1Config,
+			wantFlags: []Flag{
+				{Name: "max_request_bytes", Value: "33554432", Line: 12},
+				{Name: "underscores_in_headers", Value: "true", Line: 13},
+				{Name: "downstream_idle_timeout", Value: "600s", Line: 14},
+				{Name: "operation_request_timeouts", Value: "*=600s", Line: 15},
+				{Name: "ssl_server_cert_path", Value: "/etc/nginx/ssl", Line: 30},
+				{Name: "ssl_minimum_protocol", Value: "TLSv1.2", Line: 32},
+				{Name: "ssl_maximum_protocol", Value: "TLSv1.3", Line: 32},
+				{Name: "access_log", Value: "/dev/stdout", Line: 33},
+				{Name: "healthz", Value: "healthz", Line: 35},
+				{Name: "service_json_path", Value: "/etc/nginx/endpoints/service.json", Line: 42},
+				{Name: "metadata_url", Value: "http://169.254.169.254", Line: 43},
+				{Name: "backend_address", Value: "http://localhost:8081", Line: 47},
+				{Name: "listener_port", Value: "443", Line: 29},
+				{Name: "add_request_headers", Value: "X-Api-Version=v1", Line: 50},
+				{Name: "add_response_headers", Value: "X-Frame-Options=DENY", Line: 51},
+			},
+			wantUnsupported: []Finding{
+				{
+					Line:      22,
+					Directive: "set_real_ip_from 0.0.0.0/0",
+					Reason:    "ESPv2 takes the client IP from X-Forwarded-For after skipping the trusted proxies, set --envoy_xff_num_trusted_hops to the number of proxies in front of ESPv2",
+				},
+				{
+					Line:      23,
+					Directive: "real_ip_header X-Forwarded-For",
+					Reason:    "ESPv2 takes the client IP from X-Forwarded-For after skipping the trusted proxies, set --envoy_xff_num_trusted_hops to the number of proxies in front of ESPv2",
+				},
+				{
+					Line:      44,
+					Directive: "server_config /etc/nginx/server_config.pb.txt",
+					Reason:    "the server config of ESP(v1) is not read, convert its settings to the ESPv2 flags manually",
+				},
+				{
+					Line:      52,
+					Directive: "limit_req zone=one burst=5",
+					Reason:    "no ESPv2 equivalent",
+				},
+				{
+					Line:      55,
+					Directive: "include /var/lib/nginx/extra/*.conf",
+					Reason:    "the included files are not read, convert them separately",
+				},
+				{
+					Line:      58,
+					Directive: "server",
+					Reason:    "the status server of ESP(v1) is replaced by the Envoy admin, see --admin_port",
+				},
+				{
+					Line:      28,
+					Directive: "listen 8080",
+					Reason:    "ESPv2 serves one listener port, see --http_redirect_port to redirect the plain HTTP port to HTTPS",
+				},
+			},
+			wantNotes: []Finding{
+				{
+					Line:      15,
+					Directive: "proxy_read_timeout 10m",
+					Reason:    `nginx bounds the time between two reads from the backend, ESPv2 bounds the full response and overrides the "deadline" in the "x-google-backend" extension`,
+				},
+				{
+					Line:      49,
+					Directive: "proxy_set_header X-Real-IP $remote_addr",
+					Reason:    "ESPv2 sets X-Forwarded-For, X-Forwarded-Proto and Host to the backend itself",
+				},
+			},
+		},
+		{
+			desc: "gRPC backend with the listen address and renamed certificates",
+			config: `
+server {
+  listen 127.0.0.1:9000 ssl;
+  ssl_certificate /certs/tls.crt;
+  location /bookstore.Bookstore/ {
+    grpc_pass grpcs://backend.internal:8443;
+  }
+}`,
+			wantFlags: []Flag{
+				{Name: "ssl_server_cert_path", Value: "/certs", Line: 4},
+				{Name: "backend_address", Value: "grpcs://backend.internal:8443", Line: 6},
+				{Name: "listener_port", Value: "9000", Line: 3},
+				{Name: "listener_address", Value: "127.0.0.1", Line: 3},
+			},
+			wantNotes: []Finding{
+				{
+					Line:      4,
+					Directive: "ssl_certificate /certs/tls.crt",
+					Reason:    `ESPv2 reads the file "server.crt" of the directory, rename the file`,
+				},
+				{
+					Line:      5,
+					Directive: "location /bookstore.Bookstore/",
+					Reason:    "ESPv2 routes the requests by the HTTP rules of the service config, the location is not used",
+				},
+			},
+		},
+		{
+			desc: "Conflicting backends and path rewrites",
+			config: `
+server {
+  listen unix:/var/run/esp.sock;
+  location /v1/ {
+    proxy_pass http://127.0.0.1:8081/api/;
+  }
+  location /v2/ {
+    proxy_pass http://127.0.0.1:8082;
+  }
+  location /static/ {
+    root /var/www;
+  }
+}`,
+			wantFlags: []Flag{
+				{Name: "backend_address", Value: "http://127.0.0.1:8081", Line: 5},
+			},
+			wantUnsupported: []Finding{
+				{
+					Line:      5,
+					Directive: "proxy_pass http://127.0.0.1:8081/api/",
+					Reason:    `ESPv2 does not rewrite the path to the backend, use the path translation of the "x-google-backend" extension`,
+				},
+				{
+					Line:      8,
+					Directive: "proxy_pass http://127.0.0.1:8082",
+					Reason:    "conflicts with --backend_address=http://127.0.0.1:8081 converted from line 5, ESPv2 applies the flags to all the APIs",
+				},
+				{
+					Line:      10,
+					Directive: "location /static/",
+					Reason:    "ESPv2 routes the requests by the HTTP rules of the service config, see --static_paths and --redirect_rules for the other paths",
+				},
+				{
+					Line:      3,
+					Directive: "listen unix:/var/run/esp.sock",
+					Reason:    "ESPv2 does not listen on the unix domain sockets",
+				},
+			},
+			wantNotes: []Finding{
+				{
+					Line:      4,
+					Directive: "location /v1/",
+					Reason:    "ESPv2 routes the requests by the HTTP rules of the service config, the location is not used",
+				},
+				{
+					Line:      7,
+					Directive: "location /v2/",
+					Reason:    "ESPv2 routes the requests by the HTTP rules of the service config, the location is not used",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			directives, err := Parse(tc.config)
+			if err != nil {
+				t.Fatalf("Parse() got error: %v", err)
+			}
+
+			got := Convert(directives)
+			if diff := cmp.Diff(tc.wantFlags, got.Flags); diff != "" {
+				t.Errorf("Convert() flags diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantUnsupported, got.Unsupported); diff != "" {
+				t.Errorf("Convert() unsupported diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantNotes, got.Notes); diff != "" {
+				t.Errorf("Convert() notes diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
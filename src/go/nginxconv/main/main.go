@@ -0,0 +1,65 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// espv2-nginx-convert converts the nginx config of an ESP(v1) deployment to
+// the ESPv2 flags, and reports the directives to migrate manually:
+//
+//	espv2-nginx-convert /etc/nginx/nginx.conf
+//
+// The flags are printed one per line, the report is printed as comments.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/nginxconv"
+	"github.com/golang/glog"
+)
+
+func main() {
+	flag.Parse()
+	configPath := flag.Arg(0)
+	if configPath == "" {
+		glog.Exitf("Please specify the path of the nginx config to convert")
+	}
+
+	config, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		glog.Exitf("failed to read nginx config file %v, error: %v", configPath, err)
+	}
+	directives, err := nginxconv.Parse(string(config))
+	if err != nil {
+		glog.Exitf("failed to parse nginx config file %v, error: %v", configPath, err)
+	}
+
+	conversion := nginxconv.Convert(directives)
+	fmt.Printf("# ESPv2 flags converted from %s:\n", configPath)
+	for _, f := range conversion.Flags {
+		fmt.Println(f)
+	}
+	if len(conversion.Unsupported) > 0 {
+		fmt.Printf("\n# %d directives are not supported by ESPv2, migrate them manually:\n", len(conversion.Unsupported))
+		for _, finding := range conversion.Unsupported {
+			fmt.Printf("#   %s\n", finding)
+		}
+	}
+	if len(conversion.Notes) > 0 {
+		fmt.Printf("\n# %d directives are converted with a different behavior:\n", len(conversion.Notes))
+		for _, finding := range conversion.Notes {
+			fmt.Printf("#   %s\n", finding)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nginxconv converts the nginx config of ESP(v1) to the flags of
+// ESPv2, to migrate the deployments off ESP(v1).
+package nginxconv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Directive is an nginx directive, with the directives of its block if it is
+// a block directive such as "server".
+type Directive struct {
+	Name string
+	Args []string
+
+	// Line is the line of the directive name in the config, from 1.
+	Line int
+
+	// Block is nil for the simple directives ending with ";".
+	Block []*Directive
+}
+
+// String returns the directive as written in the config, without its block.
+func (d *Directive) String() string {
+	return strings.Join(append([]string{d.Name}, d.Args...), " ")
+}
+
+type token struct {
+	value string
+	line  int
+
+	// quoted tokens are never the special characters "{", "}" and ";".
+	quoted bool
+}
+
+// Parse parses the nginx config into its top level directives.
+func Parse(config string) ([]*Directive, error) {
+	tokens, err := tokenize(config)
+	if err != nil {
+		return nil, err
+	}
+
+	directives, rest, err := parseBlock(tokens, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected %q", rest[0].line, rest[0].value)
+	}
+	return directives, nil
+}
+
+// parseBlock parses the directives until the "}" closing the block, or the
+// end of the config for the top level. It returns the tokens after the block.
+func parseBlock(tokens []token, inBlock bool) ([]*Directive, []token, error) {
+	var directives []*Directive
+	for len(tokens) > 0 {
+		t := tokens[0]
+		if !t.quoted && t.value == "}" {
+			if !inBlock {
+				return nil, nil, fmt.Errorf("line %d: unexpected \"}\"", t.line)
+			}
+			return directives, tokens[1:], nil
+		}
+		if !t.quoted && (t.value == "{" || t.value == ";") {
+			return nil, nil, fmt.Errorf("line %d: unexpected %q", t.line, t.value)
+		}
+
+		d := &Directive{
+			Name: t.value,
+			Line: t.line,
+		}
+		tokens = tokens[1:]
+		for {
+			if len(tokens) == 0 {
+				return nil, nil, fmt.Errorf("line %d: directive %q is not terminated by \";\"", d.Line, d.Name)
+			}
+			t = tokens[0]
+			tokens = tokens[1:]
+			if t.quoted || (t.value != ";" && t.value != "{" && t.value != "}") {
+				d.Args = append(d.Args, t.value)
+				continue
+			}
+			if t.value == "}" {
+				return nil, nil, fmt.Errorf("line %d: directive %q is not terminated by \";\"", d.Line, d.Name)
+			}
+			if t.value == "{" {
+				var err error
+				if d.Block, tokens, err = parseBlock(tokens, true); err != nil {
+					return nil, nil, err
+				}
+				if d.Block == nil {
+					d.Block = []*Directive{}
+				}
+			}
+			break
+		}
+		directives = append(directives, d)
+	}
+
+	if inBlock {
+		return nil, nil, fmt.Errorf("unexpected end of config, missing \"}\"")
+	}
+	return directives, nil, nil
+}
+
+// tokenize splits the config into the words, the quoted strings and the
+// special characters "{", "}" and ";", without the comments.
+func tokenize(config string) ([]token, error) {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(config); {
+		c := config[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(config) && config[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, token{value: string(c), line: line})
+			i++
+		case c == '"' || c == '\'':
+			start := line
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(config) {
+					return nil, fmt.Errorf("line %d: unterminated quoted string", start)
+				}
+				if config[i] == c {
+					break
+				}
+				if config[i] == '\\' && i+1 < len(config) {
+					i++
+				}
+				if config[i] == '\n' {
+					line++
+				}
+				value.WriteByte(config[i])
+			}
+			i++
+			tokens = append(tokens, token{value: value.String(), line: start, quoted: true})
+		default:
+			start := i
+			for i < len(config) && !strings.ContainsRune(" \t\r\n{};\"'", rune(config[i])) {
+				if config[i] == '\\' && i+1 < len(config) {
+					i++
+				}
+				// The variables such as "${host}" are a part of the word.
+				if config[i] == '$' && i+1 < len(config) && config[i+1] == '{' {
+					if end := strings.IndexByte(config[i:], '}'); end > 0 {
+						i += end
+					}
+				}
+				i++
+			}
+			tokens = append(tokens, token{value: config[start:i], line: line})
+		}
+	}
+	return tokens, nil
+}
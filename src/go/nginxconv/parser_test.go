@@ -0,0 +1,127 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nginxconv
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	testData := []struct {
+		desc           string
+		config         string
+		wantDirectives []*Directive
+		wantError      string
+	}{
+		{
+			desc: "Simple and block directives with comments",
+			config: `# ESP(v1) config
+daemon off;
+http {
+  server {
+    listen 8080; # plain HTTP
+    location / { }
+  }
+}
+`,
+			wantDirectives: []*Directive{
+				{
+					Name: "daemon",
+					Args: []string{"off"},
+					Line: 2,
+				},
+				{
+					Name: "http",
+					Line: 3,
+					Block: []*Directive{
+						{
+							Name: "server",
+							Line: 4,
+							Block: []*Directive{
+								{
+									Name: "listen",
+									Args: []string{"8080"},
+									Line: 5,
+								},
+								{
+									Name:  "location",
+									Args:  []string{"/"},
+									Line:  6,
+									Block: []*Directive{},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:   "Quoted arguments and variables",
+			config: `add_header "X-Frame-Options" 'DENY;{}'; proxy_set_header Host ${host}:8080;`,
+			wantDirectives: []*Directive{
+				{
+					Name: "add_header",
+					Args: []string{"X-Frame-Options", "DENY;{}"},
+					Line: 1,
+				},
+				{
+					Name: "proxy_set_header",
+					Args: []string{"Host", "${host}:8080"},
+					Line: 1,
+				},
+			},
+		},
+		{
+			desc:      "Directive is not terminated",
+			config:    "http {\n  listen 8080\n}",
+			wantError: `line 2: directive "listen" is not terminated by ";"`,
+		},
+		{
+			desc:      "Block is not closed",
+			config:    "http {\n  server {\n}",
+			wantError: `unexpected end of config, missing "}"`,
+		},
+		{
+			desc:      "Unexpected closing brace",
+			config:    "daemon off;\n}",
+			wantError: `line 2: unexpected "}"`,
+		},
+		{
+			desc:      "Unterminated quoted string",
+			config:    "add_header X-Test \"value;",
+			wantError: "line 1: unterminated quoted string",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotDirectives, err := Parse(tc.config)
+			if tc.wantError != "" {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("Parse() got error %v, want %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantDirectives, gotDirectives); diff != "" {
+				t.Errorf("Parse() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -24,11 +24,17 @@ type CommonOptions struct {
 	// Flags for envoy
 	AdminAddress          string
 	AdminPort             int
+	AdminSocketPath       string
+	AdminLoopbackOnly     bool
 	AdsNamedPipe          string
 	Node                  string
 	GeneratedHeaderPrefix string
 	TracingOptions        *TracingOptions
 
+	// EnableOperationStats emits the per-route statistics tagged with the
+	// operation selector instead of the path.
+	EnableOperationStats bool
+
 	// Flags for metadata
 	NonGCP             bool
 	HttpRequestTimeout time.Duration
@@ -41,6 +47,14 @@ type CommonOptions struct {
 
 	// Whether to disallow colon in the url wildcard path segment.
 	DisallowColonInWildcardPathSegment bool
+
+	// Overload manager configurations. MaxDownstreamConnections limits the
+	// open downstream connections of all the listeners. The heap actions are
+	// triggered at the thresholds of OverloadMaxHeapSizeBytes.
+	MaxDownstreamConnections               int64
+	OverloadMaxHeapSizeBytes               uint64
+	OverloadShrinkHeapThreshold            float64
+	OverloadStopAcceptingRequestsThreshold float64
 }
 
 // TracingOptions are the shared options to create tracing config.
@@ -92,6 +106,10 @@ func DefaultCommonOptions() CommonOptions {
 		HttpRequestTimeout: 30 * time.Second,
 
 		Node: "ESPv2",
+
+		OverloadShrinkHeapThreshold:            0.95,
+		OverloadStopAcceptingRequestsThreshold: 0.98,
+
 		TracingOptions: &TracingOptions{
 			DisableTracing:      false,
 			SamplingRate:        0.001,
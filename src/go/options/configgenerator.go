@@ -28,6 +28,10 @@ import (
 type ConfigGeneratorOptions struct {
 	CommonOptions
 
+	// DeploymentMode selects the defaults of the options not set by their
+	// flags, one of "edge", "sidecar" or "gateway". Empty if none.
+	DeploymentMode string
+
 	// Cors related configurations.
 	CorsAllowCredentials   bool
 	CorsAllowHeaders       string
@@ -39,18 +43,120 @@ type ConfigGeneratorOptions struct {
 	CorsPreset             string
 	CorsOperationDelimiter string
 
+	CorsAllowUnmatchedPreflight bool
+
+	// Per-operation overrides of the CORS policy.
+	OperationCorsAllowOrigin      string
+	OperationCorsAllowCredentials string
+	OperationCorsExposeHeaders    string
+
 	// Backend routing configurations.
 	BackendDnsLookupFamily string
 
+	// Deny-by-default routing configurations.
+	StrictRouteAllowlist    bool
+	PassthroughPathPrefixes string
+
+	// DefaultBackendAddress is the backend of the requests matching no operation.
+	DefaultBackendAddress string
+
+	// Tenant routing configurations. TenantMapping is the tenant mapping JSON
+	// fetched from the metadata attribute by the config manager.
+	TenantMappingFile              string
+	TenantMappingMetadataAttribute string
+	TenantMapping                  string
+
+	// ConsumerBackends is the JSON array of the dedicated backend pools of the
+	// named API consumers.
+	ConsumerBackends string
+
+	// Consumer quota configurations. ConsumerQuotaLimits are the producer
+	// quota overrides of the consumers in QuotaOverrideConsumers, fetched
+	// from Service Consumer Management by the config manager.
+	ServiceConsumerManagementURL string
+	QuotaOverrideConsumers       string
+	ConsumerQuotaLimits          []ConsumerQuotaLimit
+
+	// VersionBackends is the JSON object mapping the values of the API version
+	// request header to the backends serving the versions.
+	VersionBackends string
+
 	// Envoy specific configurations.
 	ClusterConnectTimeout time.Duration
 	StreamIdleTimeout     time.Duration
 
+	// Downstream connection lifetime. The idle timeout and the drain timeout
+	// keep the Envoy defaults if 0, and the connection duration is unlimited
+	// if 0.
+	DownstreamIdleTimeout           time.Duration
+	DownstreamMaxConnectionDuration time.Duration
+	DownstreamDrainTimeout          time.Duration
+
+	// ServerHeader overrides the value of the Server response header, which is
+	// "envoy" if empty. ServerHeaderTransformation is one of "overwrite",
+	// "append_if_absent", "pass_through" and "suppress".
+	ServerHeader               string
+	ServerHeaderTransformation string
+
+	// ViaHeader is the value of the Via header added to the requests and the
+	// responses, with the "{key}" placeholders replaced by the deployment
+	// labels. No Via header is added if empty.
+	ViaHeader string
+
+	// MergeDuplicateRoutes merges the adjacent routes that only differ by the
+	// operation name and the path into one route matching all their paths.
+	MergeDuplicateRoutes bool
+
+	// Backend connection configurations.
+	BackendClusterConnectTimeout time.Duration
+	BackendTcpKeepaliveTime      time.Duration
+	BackendTcpKeepaliveInterval  time.Duration
+	BackendTcpKeepaliveProbes    int
+	BackendHappyEyeballs         bool
+
+	// The HTTP/2 keepalive of the gRPC and HTTP/2 backend connections, 0 for
+	// the defaults.
+	BackendHttp2KeepaliveInterval       time.Duration
+	BackendHttp2KeepaliveTimeout        time.Duration
+	BackendHttp2KeepaliveIntervalJitter float64
+
+	// BackendProtocolSelection chooses the upstream protocol of the backends
+	// serving both HTTP/1.1 and HTTP/2, keyed by the "hostname:port" of the
+	// backends.
+	BackendProtocolSelection string
+
+	// Connection upgrade related configurations.
+	UpgradeTypes                 string
+	OperationUpgradeTypes        string
+	OperationUpgradeIdleTimeouts string
+
+	// Streaming download related configurations.
+	StreamingDownloadBufferLimitBytes int
+	OperationStreamingDownloads       string
+
 	// Full URI to the backend: scheme, address/hostname, port
 	BackendAddress               string
 	EnableBackendAddressOverride bool
 	LocalHTTPBackendAddress      string
 
+	// Overrides the backend rule addresses of the operations, in the format of
+	// "selector1=address1;selector2=address2".
+	BackendAddressOverrides string
+
+	// Merges the backend rules of the wildcard selectors into the rules of the
+	// operations, the more specific ones override the less specific ones.
+	BackendRuleInheritance bool
+
+	// Failover backends of the remote backends used once all the hosts of the
+	// primary backends are unhealthy, in the format of
+	// "primary_address1=failover_address1;primary_address2=failover_address2".
+	BackendFailoverAddresses string
+
+	// Regional backends of the remote backends in the format of
+	// "address1=near_address1,far_address1;address2=near_address2,far_address2".
+	// The remote backends are aggregated over them, preferring the near one.
+	BackendRegionalAddresses string
+
 	// Health check related
 	Healthz                                 string
 	HealthCheckOperation                    string
@@ -59,6 +165,54 @@ type ConfigGeneratorOptions struct {
 	HealthCheckGrpcBackendService           string
 	HealthCheckGrpcBackendInterval          time.Duration
 	HealthCheckGrpcBackendNoTrafficInterval time.Duration
+	HealthzCheckBackend                     bool
+	HealthzBackendEvaluationWindow          time.Duration
+	HealthzBackendConsecutiveFailures       uint
+
+	// HealthPort is the port of the dedicated listener serving only the
+	// health check of Healthz, 0 to disable.
+	HealthPort int
+
+	// TcpProxyRoutes tunnel the TCP connections of the listener ports to the
+	// destinations, in the format of "PORT[/SERVER_NAME]=HOST:PORT;...".
+	TcpProxyRoutes string
+
+	// Active health check of the backend clusters.
+	BackendHealthCheckPath               string
+	BackendHealthCheckGrpc               bool
+	BackendHealthCheckInterval           time.Duration
+	BackendHealthCheckTimeout            time.Duration
+	BackendHealthCheckHealthyThreshold   uint
+	BackendHealthCheckUnhealthyThreshold uint
+	BackendHealthCheckExpectedStatuses   string
+
+	// Readiness related configurations.
+	ReadinessPort         uint
+	ReadinessCheckBackend bool
+	ReadinessCheckTimeout time.Duration
+
+	// The readiness endpoint also fails if the service config has not been
+	// checked by the managed rollout strategy for ReadinessMaxConfigAge, or
+	// the checks fail ReadinessMaxRolloutFetchFailures consecutive times. 0
+	// disables them.
+	ReadinessMaxConfigAge            time.Duration
+	ReadinessMaxRolloutFetchFailures int
+
+	// Startup related configurations.
+	StartupTimeout              time.Duration
+	RequireBackendDnsResolution bool
+
+	// Snapshot debugging configurations.
+	SnapshotAdminPort  uint
+	SnapshotReplayFile string
+
+	// Metadata server stub configurations.
+	MetadataStubFile string
+	MetadataStubPort uint
+
+	// Config manager gRPC server configurations.
+	GrpcHealthPort       uint
+	EnableGrpcReflection bool
 
 	// Network related configurations.
 	ListenerAddress                  string
@@ -71,6 +225,9 @@ type ConfigGeneratorOptions struct {
 	SslMinimumProtocol               string
 	SslMaximumProtocol               string
 	EnableHSTS                       bool
+	HttpRedirectPort                 int
+	HttpRedirectResponseCode         int
+	HttpsRedirectPort                int
 	SslSidestreamClientRootCertsPath string
 	SslBackendClientCertPath         string
 	SslBackendClientRootCertsPath    string
@@ -83,15 +240,54 @@ type ConfigGeneratorOptions struct {
 	AddResponseHeaders        string
 	AppendResponseHeaders     string
 	EnableOperationNameHeader bool
+	GrpcMetadata              string
+
+	// Security headers added to the responses of the operations, unless the
+	// backends already set them.
+	EnableSecurityHeaders           bool
+	SecurityHeaders                 string
+	OperationSecurityHeadersEnabled string
+
+	// Response headers removed from the responses of the operations, such as
+	// the latency disclosed by x-envoy-upstream-service-time.
+	SuppressUpstreamServiceTime      bool
+	OperationResponseHeadersToRemove string
+
+	// Deprecation of the operations signaled by the Deprecation and Sunset
+	// response headers. OperationDeprecations overrides the deprecation in
+	// the service config documentation rules with "true", "false" or the
+	// sunset dates.
+	EnableDeprecationHeaders bool
+	OperationDeprecations    string
 
 	// Flags for non_gcp deployment.
 	ServiceAccountKey                   string
 	TokenAgentPort                      uint
 	EnableApplicationDefaultCredentials bool
 
+	// Serves the tokens to the other containers of the pod on TokenAgentPort.
+	EnableTokenService bool
+
+	// The service account keys scoped by purpose, which default to
+	// ServiceAccountKey.
+	ServiceManagementServiceAccountKey string
+	ServiceControlServiceAccountKey    string
+	BackendAuthServiceAccountKey       string
+
+	// The templates of the JWT audiences of the backend auth for the backend
+	// rules without jwt_audience, globally and per operation.
+	BackendJwtAudienceTemplate    string
+	OperationJwtAudienceTemplates string
+
 	// Flags for external calls.
-	DisableOidcDiscovery    bool
-	DependencyErrorBehavior string
+	DisableOidcDiscovery                  bool
+	OidcDiscoveryTimeout                  time.Duration
+	OidcDiscoveryMaxRedirects             int
+	OidcDiscoveryNumRetries               int
+	OidcDiscoveryRetryBackOffBaseInterval time.Duration
+	OidcDiscoveryRetryBackOffMaxInterval  time.Duration
+	DisableHttpFetchCache                 bool
+	DependencyErrorBehavior               string
 
 	// Flags for testing purpose.
 	SkipJwtAuthnFilter       bool
@@ -100,6 +296,10 @@ type ConfigGeneratorOptions struct {
 	// Envoy configurations.
 	AccessLog       string
 	AccessLogFormat string
+	// AccessLogConsumerSampleRates maps the project numbers of the API
+	// consumers to the percentages of their requests written to the access
+	// log, "*" for the other consumers.
+	AccessLogConsumerSampleRates string
 
 	EnvoyUseRemoteAddress  bool
 	EnvoyXffNumTrustedHops int
@@ -111,14 +311,44 @@ type ConfigGeneratorOptions struct {
 
 	SuppressEnvoyHeaders                   bool
 	UnderscoresInHeaders                   bool
+	HeadersWithUnderscoresAction           string
+	MaxRequestHeadersCount                 int
+	MaxRequestHeadersKb                    int
+	HeaderKeyFormat                        string
 	NormalizePath                          bool
 	MergeSlashesInPath                     bool
 	DisallowEscapedSlashesInPath           bool
 	ServiceControlNetworkFailOpen          bool
 	ServiceControlEnableApiKeyUidReporting bool
 	EnableGrpcForHttp1                     bool
+	EnableGrpcWeb                          bool
 	ConnectionBufferLimitBytes             int
 
+	// PropagateRequestTimeout sends the remaining deadline of the routes to
+	// the backends in the headers x-envoy-expected-rq-timeout-ms and
+	// grpc-timeout.
+	PropagateRequestTimeout bool
+
+	// The API keys may also be sent in the Authorization header, as the
+	// Basic auth username or a bearer token with the prefix, by the clients
+	// migrated from the legacy gateways.
+	ApiKeyFromBasicAuthUsername bool
+	ApiKeyBearerTokenPrefix     string
+
+	// ListenerConnectionRateLimit is the maximum number of new connections per
+	// second accepted by the ingress listener, 0 means unlimited.
+	ListenerConnectionRateLimit int
+
+	// AllowedSourceRanges are the comma separated CIDRs of the peers allowed
+	// to connect to the ingress listener, empty if all the peers are allowed.
+	AllowedSourceRanges string
+
+	// ClientIPRateLimitPerSecond is the requests per second allowed from each
+	// client IP, 0 means unlimited. ClientIPRateLimitBurst is the max burst of
+	// the requests of each client IP, 0 means the same as the rate.
+	ClientIPRateLimitPerSecond int
+	ClientIPRateLimitBurst     int
+
 	// JwtAuthn related flags
 	DisableJwksAsyncFetch              bool
 	JwksAsyncFetchFastListener         bool
@@ -130,10 +360,23 @@ type ConfigGeneratorOptions struct {
 	JwtCacheSize                       uint
 	DisableJwtAudienceServiceNameCheck bool
 
+	// JwksResolveAtStartup resolves the hostnames of the JWKS URIs and the
+	// issuers in the config manager, and refreshes them every JwksResolveTTL,
+	// so the JWT provider clusters connect to the IP addresses without DNS at
+	// runtime. JwksResolvedAddresses maps the resolved hostnames to their
+	// IPv4 addresses.
+	JwksResolveAtStartup  bool
+	JwksResolveTTL        time.Duration
+	JwksResolvedAddresses map[string][]string
+
 	ScCheckTimeoutMs  int
 	ScQuotaTimeoutMs  int
 	ScReportTimeoutMs int
 
+	// ScCompressReports compresses the bodies of the Service Control Report
+	// calls with gzip.
+	ScCompressReports bool
+
 	BackendRetryOns           string
 	BackendRetryNum           uint
 	BackendPerTryTimeout      time.Duration
@@ -142,12 +385,100 @@ type ConfigGeneratorOptions struct {
 	ScQuotaRetries            int
 	ScReportRetries           int
 
+	// The per-operation overrides of the route response timeouts, which bound
+	// the full response including all the retries, and of the upstream per-try
+	// timeouts, in the format of "selector1=60s;selector2=10s".
+	OperationRequestTimeouts string
+	OperationPerTryTimeouts  string
+
+	// OperationQuotaMetricCosts overrides the quota metric costs of the
+	// operations in the service config.
+	OperationQuotaMetricCosts string
+
+	// ConsumerMaxConcurrentRequests is the max in-flight requests of each API
+	// key, the requests beyond it are rejected with 429. 0 if unlimited.
+	ConsumerMaxConcurrentRequests int
+
+	// OperationConsumerMaxConcurrentRequests overrides the max in-flight
+	// requests of each API key for the operations, counted per operation.
+	OperationConsumerMaxConcurrentRequests string
+
 	BackendClusterMaxRequests int
 
+	// Retry budget of the backend clusters, the percent of the active
+	// requests allowed to be retries. 0 disables the budget.
+	BackendRetryBudgetPercent        float64
+	BackendRetryBudgetMinConcurrency uint
+	BackendHedgeGetRequests          bool
+	OperationHedgeDelays             string
+
+	// Slow start of the newly added endpoints of the remote backend clusters.
+	// 0 disables the slow start.
+	BackendSlowStartWindow     time.Duration
+	BackendSlowStartAggression float64
+
 	ComputePlatformOverride     string
 	EnableResponseCompression   bool
 	ClientIPFromForwardedHeader bool
 
+	// DeploymentLabels are the labels of the deployment, "key=value" pairs
+	// separated by ";". They are merged with the labels detected from the
+	// platform, overriding the detected ones of the same keys.
+	DeploymentLabels string
+
+	// Client IP restrictions, comma separated CIDRs.
+	AllowedClientIps          string
+	DeniedClientIps           string
+	OperationAllowedClientIps string
+	OperationDeniedClientIps  string
+
+	// Request body size limits, 0 if unlimited.
+	MaxRequestBytes          int
+	OperationMaxRequestBytes string
+
+	// Adaptive concurrency related configurations, 0 for the defaults of
+	// Envoy.
+	EnableAdaptiveConcurrency             bool
+	AdaptiveConcurrencySamplePercentile   float64
+	AdaptiveConcurrencyMaxLimit           int
+	AdaptiveConcurrencyUpdateInterval     time.Duration
+	AdaptiveConcurrencyMinRttInterval     time.Duration
+	AdaptiveConcurrencyMinRttRequestCount int
+	AdaptiveConcurrencyBufferPercent      float64
+
+	// Tap related configurations, to capture the requests and the responses
+	// of the operations enabled by the selector map OperationTapEnabled to
+	// the files of TapOutputPathPrefix, or of the requests matched by the
+	// tap configs posted to the Envoy admin with TapAdminConfigId.
+	// TapMaxBufferedBytes limits the captured bytes of each body, 0 for the
+	// default of Envoy.
+	OperationTapEnabled string
+	TapOutputPathPrefix string
+	TapAdminConfigId    string
+	TapMaxBufferedBytes int
+
+	// Fault injection related configurations, for chaos testing. The selector
+	// maps OperationFaultAbort and OperationFaultDelay inject the faults into
+	// the requests of the operations with the FaultTriggerHeader.
+	OperationFaultAbort string
+	OperationFaultDelay string
+	FaultTriggerHeader  string
+
+	// Response cache related configurations. The responses of the GET
+	// requests are cached as allowed by their Cache-Control headers, with the
+	// shared cache TTLs of the selector map OperationResponseCacheTtl.
+	// ResponseCacheMaxBodyBytes is 0 if unlimited.
+	EnableResponseCache       bool
+	ResponseCacheMaxBodyBytes int
+	OperationResponseCacheTtl string
+
+	// Response compression related configurations.
+	ResponseCompressionTypes         string
+	ResponseCompressionContentTypes  string
+	ResponseCompressionMinLength     int
+	ResponseCompressionGzipLevel     int
+	ResponseCompressionBrotliQuality int
+
 	TranscodingAlwaysPrintPrimitiveFields         bool
 	TranscodingAlwaysPrintEnumsAsInts             bool
 	TranscodingStreamNewLineDelimited             bool
@@ -159,8 +490,75 @@ type ConfigGeneratorOptions struct {
 	TranscodingStrictRequestValidation            bool
 	TranscodingRejectCollision                    bool
 	TranscodingCaseInsensitiveEnumParsing         bool
-	APIAllowList                                  []string
-	AllowDiscoveryAPIs                            bool
+
+	// DisableTranscoding skips the gRPC-JSON transcoder and the http rules of
+	// the operations served by gRPC backends, which only serve native gRPC.
+	DisableTranscoding bool
+
+	// GrpcReflection explicitly allows or denies the gRPC server reflection
+	// calls through the proxy: "allow" or "deny". The allowed calls require a
+	// JWT of any of GrpcReflectionJwtProviders if set.
+	GrpcReflection             string
+	GrpcReflectionJwtProviders string
+
+	// GrpcErrorStatusCodes maps the gRPC status codes of transcoded error
+	// responses to HTTP status codes, in the format of "NOT_FOUND=404;14=503".
+	GrpcErrorStatusCodes string
+	// ErrorResponseTemplate is a JSON object used as the body of error
+	// responses, the values may contain Envoy command operators.
+	ErrorResponseTemplate string
+
+	// External processing (ext_proc) service related configurations.
+	ExtProcAddress                  string
+	ExtProcTimeout                  time.Duration
+	ExtProcFailureModeAllow         bool
+	ExtProcProcessingMode           string
+	OperationExtProcProcessingModes string
+
+	// WASM filter related configurations.
+	WasmFilterURI              string
+	WasmFilterSha256           string
+	WasmFilterConfig           string
+	WasmFilterBefore           string
+	OperationWasmFilterEnabled string
+
+	// HttpFilterOrder moves or disables the generated HTTP filters, in the
+	// format of "filter1=before:filter2;filter3=after:filter4;filter5=disabled".
+	HttpFilterOrder string
+
+	// Lua filter related configurations.
+	LuaFilterScript     string
+	LuaScripts          string
+	OperationLuaScripts string
+
+	// EnableRequestValidation rejects the requests missing the required query
+	// parameters or body, or with invalid query parameter values, of the
+	// operations in the service config with 400 before calling the backends.
+	EnableRequestValidation bool
+
+	// Read-only admin interface exposed on the main listener.
+	AdminReadOnlyPaths string
+	AdminPathPrefix    string
+	AdminTokenHeader   string
+	// AdminTokenPath is the file holding the admin token, so the token is
+	// neither in the arguments nor in the logged options.
+	AdminTokenPath string
+
+	// Maintenance mode serves a static response instead of calling the
+	// backends, for all the traffic or the selected operations.
+	MaintenanceMode       bool
+	MaintenanceOperations string
+	MaintenanceResponse   string
+
+	// StaticPaths is a JSON object of the paths answered with inline
+	// responses, such as "/robots.txt".
+	StaticPaths string
+
+	// RedirectRules is a JSON array of the redirects of the paths.
+	RedirectRules string
+
+	APIAllowList       []string
+	AllowDiscoveryAPIs bool
 }
 
 // DefaultConfigGeneratorOptions returns ConfigGeneratorOptions with default values.
@@ -175,6 +573,15 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		EnableBackendAddressOverride:            false,
 		ClusterConnectTimeout:                   20 * time.Second,
 		StreamIdleTimeout:                       util.DefaultIdleTimeout,
+		UpgradeTypes:                            "websocket",
+		StreamingDownloadBufferLimitBytes:       64 * 1024,
+		ResponseCompressionTypes:                "gzip,br",
+		ResponseCompressionBrotliQuality:        -1,
+		ExtProcTimeout:                          200 * time.Millisecond,
+		ExtProcProcessingMode:                   "headers_only",
+		WasmFilterBefore:                        "envoy.filters.http.router",
+		AdminPathPrefix:                         "/espv2_admin",
+		AdminTokenHeader:                        "x-espv2-admin-token",
 		EnvoyXffNumTrustedHops:                  2,
 		DisableJwksAsyncFetch:                   false,
 		JwksAsyncFetchFastListener:              false,
@@ -182,11 +589,22 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		JwksFetchNumRetries:                     0,
 		JwksFetchRetryBackOffBaseInterval:       200 * time.Millisecond,
 		JwksFetchRetryBackOffMaxInterval:        32 * time.Second,
+		JwksResolveTTL:                          5 * time.Minute,
 		JwtCacheSize:                            1000, // Max memory usage: 4.35 MB
 		ListenerAddress:                         "0.0.0.0",
 		ListenerPort:                            8080,
+		HttpRedirectResponseCode:                301,
 		TokenAgentPort:                          8791,
+		ReadinessPort:                           0,
+		ReadinessCheckBackend:                   false,
+		ReadinessCheckTimeout:                   5 * time.Second,
 		DisableOidcDiscovery:                    false,
+		OidcDiscoveryTimeout:                    10 * time.Second,
+		OidcDiscoveryMaxRedirects:               5,
+		OidcDiscoveryNumRetries:                 2,
+		OidcDiscoveryRetryBackOffBaseInterval:   200 * time.Millisecond,
+		OidcDiscoveryRetryBackOffMaxInterval:    2 * time.Second,
+		DisableHttpFetchCache:                   false,
 		DependencyErrorBehavior:                 commonpb.DependencyErrorBehavior_BLOCK_INIT_ON_ANY_ERROR.String(),
 		SslSidestreamClientRootCertsPath:        util.DefaultRootCAPaths,
 		SslBackendClientRootCertsPath:           util.DefaultRootCAPaths,
@@ -200,6 +618,7 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		ConnectionBufferLimitBytes:              -1,
 		ServiceManagementURL:                    "https://servicemanagement.googleapis.com",
 		ServiceControlURL:                       "https://servicecontrol.googleapis.com",
+		ServiceConsumerManagementURL:            "https://serviceconsumermanagement.googleapis.com",
 		BackendRetryNum:                         1,
 		BackendRetryOns:                         "reset,connect-failure,refused-stream",
 		ScCheckRetries:                          -1,
@@ -215,6 +634,12 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		HealthCheckAutogeneratedOperationPrefix: util.AutogeneratedOperationPrefix,
 		HealthCheckGrpcBackendInterval:          1 * time.Second,
 		HealthCheckGrpcBackendNoTrafficInterval: 60 * time.Second,
+		HealthzBackendEvaluationWindow:          30 * time.Second,
+		HealthzBackendConsecutiveFailures:       5,
+		BackendHealthCheckInterval:              5 * time.Second,
+		BackendHealthCheckTimeout:               1 * time.Second,
+		BackendHealthCheckHealthyThreshold:      2,
+		BackendHealthCheckUnhealthyThreshold:    3,
 		APIAllowList:                            []string{},
 		AllowDiscoveryAPIs:                      false,
 		TranscodingRejectCollision:              false,
@@ -222,3 +647,55 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		EnableApplicationDefaultCredentials:     false,
 	}
 }
+
+// OidcDiscoveryFetchOptions returns the options to fetch the OpenID Connect
+// discovery documents.
+func OidcDiscoveryFetchOptions(opts ConfigGeneratorOptions) util.RemoteContentOptions {
+	return util.RemoteContentOptions{
+		Timeout:                  opts.OidcDiscoveryTimeout,
+		MaxRedirects:             opts.OidcDiscoveryMaxRedirects,
+		NumRetries:               opts.OidcDiscoveryNumRetries,
+		RetryBackOffBaseInterval: opts.OidcDiscoveryRetryBackOffBaseInterval,
+		RetryBackOffMaxInterval:  opts.OidcDiscoveryRetryBackOffMaxInterval,
+		DisableCache:             opts.DisableHttpFetchCache,
+	}
+}
+
+// ServiceManagementServiceAccountKey returns the service account key to fetch
+// the service config, empty if the metadata server is used.
+func ServiceManagementServiceAccountKey(opts ConfigGeneratorOptions) string {
+	if opts.ServiceManagementServiceAccountKey != "" {
+		return opts.ServiceManagementServiceAccountKey
+	}
+	return opts.ServiceAccountKey
+}
+
+// ServiceControlServiceAccountKey returns the service account key to call
+// Service Control, empty if the metadata server is used.
+func ServiceControlServiceAccountKey(opts ConfigGeneratorOptions) string {
+	if opts.ServiceControlServiceAccountKey != "" {
+		return opts.ServiceControlServiceAccountKey
+	}
+	return opts.ServiceAccountKey
+}
+
+// BackendAuthServiceAccountKey returns the service account key to mint the
+// backend auth tokens, empty if the metadata server is used.
+func BackendAuthServiceAccountKey(opts ConfigGeneratorOptions) string {
+	if opts.BackendAuthServiceAccountKey != "" {
+		return opts.BackendAuthServiceAccountKey
+	}
+	return opts.ServiceAccountKey
+}
+
+// ConsumerQuotaLimit is the rate limit of the requests of an API consumer to
+// the operations consuming a quota metric.
+type ConsumerQuotaLimit struct {
+	// ConsumerNumber is the project number of the API consumer.
+	ConsumerNumber string
+	Metric         string
+
+	// Limit is the number of requests allowed per Interval.
+	Limit    int64
+	Interval time.Duration
+}
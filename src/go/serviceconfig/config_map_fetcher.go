@@ -0,0 +1,174 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// configMap is the subset of the Kubernetes ConfigMap resource used by
+// ConfigMapFetcher.
+type configMap struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+// ConfigMapFetcher fetches the service config stored in a Kubernetes
+// ConfigMap from the Kubernetes API server.
+type ConfigMapFetcher struct {
+	apiServerUrl       string
+	namespace          string
+	name               string
+	key                string
+	client             *http.Client
+	accessToken        util.GetAccessTokenFunc
+	curResourceVersion string
+	detectChangeTicker *time.Ticker
+	stop               chan struct{}
+	stopOnce           sync.Once
+}
+
+func NewConfigMapFetcher(client *http.Client, apiServerUrl, namespace, name, key string,
+	accessToken util.GetAccessTokenFunc) *ConfigMapFetcher {
+	return &ConfigMapFetcher{
+		client:       client,
+		apiServerUrl: apiServerUrl,
+		namespace:    namespace,
+		name:         name,
+		key:          key,
+		accessToken:  accessToken,
+		stop:         make(chan struct{}),
+	}
+}
+
+// FetchConfig fetches the ConfigMap and returns the service config stored
+// under the key, together with the resource version of the ConfigMap.
+func (f *ConfigMapFetcher) FetchConfig() (*confpb.Service, string, error) {
+	cm, err := f.fetchConfigMap()
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, ok := cm.Data[f.key]
+	if !ok {
+		return nil, "", fmt.Errorf("key %q is not found in ConfigMap %s/%s", f.key, f.namespace, f.name)
+	}
+
+	serviceConfig, err := util.UnmarshalServiceConfig([]byte(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("fail to read service config from ConfigMap %s/%s: %v", f.namespace, f.name, err)
+	}
+
+	return serviceConfig, cm.Metadata.ResourceVersion, nil
+}
+
+// SetAppliedResourceVersion records the resource version of the service config
+// that is applied successfully, so the change detection skips it.
+func (f *ConfigMapFetcher) SetAppliedResourceVersion(resourceVersion string) {
+	f.curResourceVersion = resourceVersion
+}
+
+func (f *ConfigMapFetcher) fetchConfigMap() (*configMap, error) {
+	url := util.FetchConfigMapURL(f.apiServerUrl, f.namespace, f.name)
+	req, err := http.NewRequest(util.GET, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request to %s: %v", url, err)
+	}
+
+	token, _, err := f.accessToken()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to fetch ConfigMap %s/%s: %v", f.namespace, f.name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read ConfigMap %s/%s: %v", f.namespace, f.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching ConfigMap %s/%s returns not 200 OK: %v, %s", f.namespace, f.name, resp.Status, body)
+	}
+
+	cm := new(configMap)
+	if err := json.Unmarshal(body, cm); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal ConfigMap %s/%s: %v", f.namespace, f.name, err)
+	}
+	return cm, nil
+}
+
+// SetDetectConfigMapChangeTimer polls the ConfigMap every interval and calls
+// the callback with the new service config whenever its resource version
+// changes. The resource version is recorded only if the callback succeeds, so
+// a service config failing to be applied is retried on the next poll. The
+// polling runs until Stop is called.
+func (f *ConfigMapFetcher) SetDetectConfigMapChangeTimer(interval time.Duration, callback func(serviceConfig *confpb.Service, resourceVersion string) error) {
+	glog.Infof("start polling ConfigMap %s/%s for changes every %v", f.namespace, f.name, interval)
+	f.detectChangeTicker = time.NewTicker(interval)
+
+	go func() {
+		defer f.detectChangeTicker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				glog.Infof("stop polling ConfigMap %s/%s for changes", f.namespace, f.name)
+				return
+			case <-f.detectChangeTicker.C:
+			}
+
+			lastResourceVersion := f.curResourceVersion
+			serviceConfig, resourceVersion, err := f.FetchConfig()
+			if err != nil {
+				glog.Errorf("error occurred when checking ConfigMap changes, %v", err)
+				continue
+			}
+
+			if resourceVersion == lastResourceVersion {
+				continue
+			}
+
+			if err := callback(serviceConfig, resourceVersion); err != nil {
+				glog.Errorf("error occurred when applying ConfigMap %s/%s of resource version %v, will retry on the next check, %v", f.namespace, f.name, resourceVersion, err)
+				continue
+			}
+			f.curResourceVersion = resourceVersion
+		}
+	}()
+}
+
+// Stop stops polling the ConfigMap started by SetDetectConfigMapChangeTimer.
+// It is safe to call more than once.
+func (f *ConfigMapFetcher) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+	})
+}
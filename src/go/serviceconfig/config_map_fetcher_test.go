@@ -0,0 +1,247 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+type fakeConfigMapServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	resp string
+	code int
+}
+
+func newFakeConfigMapServer(t *testing.T) *fakeConfigMapServer {
+	s := &fakeConfigMapServer{
+		code: http.StatusOK,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/api/v1/namespaces/test-namespace/configmaps/test-name"; got != want {
+			t.Errorf("got request path %s, want %s", got, want)
+		}
+		if got, want := r.Header.Get("Authorization"), "Bearer k8s-token"; got != want {
+			t.Errorf("got Authorization header %s, want %s", got, want)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.WriteHeader(s.code)
+		_, _ = w.Write([]byte(s.resp))
+	}))
+	return s
+}
+
+func (s *fakeConfigMapServer) set(code int, resp string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.code = code
+	s.resp = resp
+}
+
+func genFakeConfigMap(resourceVersion, key, serviceConfig string) string {
+	return fmt.Sprintf(`{
+  "kind": "ConfigMap",
+  "metadata": {
+    "name": "test-name",
+    "namespace": "test-namespace",
+    "resourceVersion": "%s"
+  },
+  "data": {
+    "%s": %s
+  }
+}`, resourceVersion, key, strconv.Quote(serviceConfig))
+}
+
+func TestConfigMapFetcherFetchConfig(t *testing.T) {
+	server := newFakeConfigMapServer(t)
+	defer server.Close()
+	accessToken := func() (string, time.Duration, error) { return "k8s-token", 0, nil }
+
+	testCases := []struct {
+		desc                string
+		code                int
+		resp                string
+		wantServiceName     string
+		wantResourceVersion string
+		wantError           string
+	}{
+		{
+			desc:                "success",
+			code:                http.StatusOK,
+			resp:                genFakeConfigMap("1234", "service.json", `{"name": "test-service"}`),
+			wantServiceName:     "test-service",
+			wantResourceVersion: "1234",
+		},
+		{
+			desc:      "failure, key is not found",
+			code:      http.StatusOK,
+			resp:      genFakeConfigMap("1234", "other.json", `{"name": "test-service"}`),
+			wantError: `key "service.json" is not found in ConfigMap test-namespace/test-name`,
+		},
+		{
+			desc:      "failure, service config is invalid",
+			code:      http.StatusOK,
+			resp:      genFakeConfigMap("1234", "service.json", `{"name": 1}`),
+			wantError: "fail to read service config from ConfigMap test-namespace/test-name",
+		},
+		{
+			desc:      "failure, ConfigMap is forbidden",
+			code:      http.StatusForbidden,
+			resp:      `{"kind": "Status"}`,
+			wantError: "fetching ConfigMap test-namespace/test-name returns not 200 OK: 403 Forbidden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			server.set(tc.code, tc.resp)
+			f := NewConfigMapFetcher(&http.Client{}, server.URL, "test-namespace", "test-name", "service.json", accessToken)
+
+			serviceConfig, resourceVersion, err := f.FetchConfig()
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("want error %q, got error %v", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got error %v", err)
+			}
+
+			if serviceConfig.GetName() != tc.wantServiceName {
+				t.Errorf("want service name %s, got %s", tc.wantServiceName, serviceConfig.GetName())
+			}
+			if resourceVersion != tc.wantResourceVersion {
+				t.Errorf("want resource version %s, got %s", tc.wantResourceVersion, resourceVersion)
+			}
+		})
+	}
+}
+
+func TestSetDetectConfigMapChangeTimer(t *testing.T) {
+	server := newFakeConfigMapServer(t)
+	defer server.Close()
+	server.set(http.StatusOK, genFakeConfigMap("1", "service.json", `{"name": "test-service-1"}`))
+
+	accessToken := func() (string, time.Duration, error) { return "k8s-token", 0, nil }
+	f := NewConfigMapFetcher(&http.Client{}, server.URL, "test-namespace", "test-name", "service.json", accessToken)
+	_, resourceVersion, err := f.FetchConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetAppliedResourceVersion(resourceVersion)
+
+	var cnt, wantCnt int32
+	wantCnt = 3
+	var lastServiceName atomic.Value
+	defer f.Stop()
+	f.SetDetectConfigMapChangeTimer(time.Millisecond*50, func(serviceConfig *confpb.Service, resourceVersion string) error {
+		c := atomic.AddInt32(&cnt, 1)
+		lastServiceName.Store(serviceConfig.GetName())
+
+		// Update the ConfigMap so the callback will be called again.
+		// It will be updated only three times.
+		if c < wantCnt {
+			next := c + 2
+			server.set(http.StatusOK, genFakeConfigMap(fmt.Sprint(next), "service.json", fmt.Sprintf(`{"name": "test-service-%v"}`, next)))
+		}
+		return nil
+	})
+
+	// The ConfigMap is not changed yet, the callback should not be called.
+	time.Sleep(time.Millisecond * 200)
+	if got := atomic.LoadInt32(&cnt); got != 0 {
+		t.Fatalf("want callback not called before ConfigMap changes, get %v times", got)
+	}
+
+	server.set(http.StatusOK, genFakeConfigMap("2", "service.json", `{"name": "test-service-2"}`))
+
+	// Sleep long enough to make sure the callback is called 3 times.
+	time.Sleep(time.Millisecond * 1000)
+
+	if got := atomic.LoadInt32(&cnt); got != wantCnt {
+		t.Fatalf("want callback called by %v times, get %v times", wantCnt, got)
+	}
+	if got, want := lastServiceName.Load(), "test-service-4"; got != want {
+		t.Errorf("want last service name %v, got %v", want, got)
+	}
+}
+
+func TestSetDetectConfigMapChangeTimerRetryFailedApply(t *testing.T) {
+	server := newFakeConfigMapServer(t)
+	defer server.Close()
+	server.set(http.StatusOK, genFakeConfigMap("1", "service.json", `{"name": "test-service-1"}`))
+
+	accessToken := func() (string, time.Duration, error) { return "k8s-token", 0, nil }
+	f := NewConfigMapFetcher(&http.Client{}, server.URL, "test-namespace", "test-name", "service.json", accessToken)
+	f.SetAppliedResourceVersion("0")
+
+	var cnt int32
+	defer f.Stop()
+	f.SetDetectConfigMapChangeTimer(time.Millisecond*50, func(serviceConfig *confpb.Service, resourceVersion string) error {
+		// Fail to apply the first time, the same resource version should be
+		// retried.
+		if atomic.AddInt32(&cnt, 1) == 1 {
+			return fmt.Errorf("fail to apply resource version %v", resourceVersion)
+		}
+		return nil
+	})
+
+	// Sleep long enough for several checks.
+	time.Sleep(time.Millisecond * 500)
+
+	if got, want := atomic.LoadInt32(&cnt), int32(2); got != want {
+		t.Fatalf("want callback called by %v times, get %v times", want, got)
+	}
+}
+
+func TestSetDetectConfigMapChangeTimerStop(t *testing.T) {
+	server := newFakeConfigMapServer(t)
+	defer server.Close()
+	server.set(http.StatusOK, genFakeConfigMap("1", "service.json", `{"name": "test-service-1"}`))
+
+	accessToken := func() (string, time.Duration, error) { return "k8s-token", 0, nil }
+	f := NewConfigMapFetcher(&http.Client{}, server.URL, "test-namespace", "test-name", "service.json", accessToken)
+	f.SetAppliedResourceVersion("1")
+
+	var cnt int32
+	f.SetDetectConfigMapChangeTimer(time.Millisecond*50, func(serviceConfig *confpb.Service, resourceVersion string) error {
+		atomic.AddInt32(&cnt, 1)
+		return nil
+	})
+	f.Stop()
+	// Stopping again is a no-op.
+	f.Stop()
+
+	// The ConfigMap changes after the polling is stopped.
+	server.set(http.StatusOK, genFakeConfigMap("2", "service.json", `{"name": "test-service-2"}`))
+	time.Sleep(time.Millisecond * 200)
+
+	if got := atomic.LoadInt32(&cnt); got != 0 {
+		t.Fatalf("want callback not called after Stop, get %v times", got)
+	}
+}
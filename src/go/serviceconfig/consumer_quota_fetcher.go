@@ -0,0 +1,163 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+)
+
+// quotaUnitIntervals are the intervals of the rate quota units in the format
+// of "1/{interval}/{project}". The allocation quotas are not rate limited.
+var quotaUnitIntervals = map[string]time.Duration{
+	"s":   time.Second,
+	"min": time.Minute,
+	"h":   time.Hour,
+	"d":   24 * time.Hour,
+}
+
+// ConsumerQuotaFetcher fetches the producer quota overrides of the API
+// consumers from Service Consumer Management.
+type ConsumerQuotaFetcher struct {
+	serviceConsumerManagementUrl string
+	client                       *http.Client
+	accessToken                  util.GetAccessTokenFunc
+}
+
+// The subset of the Service Consumer Management v1beta1 response listing the
+// quota metrics of a consumer.
+type consumerQuotaMetricsResponse struct {
+	Metrics []struct {
+		Metric              string `json:"metric"`
+		ConsumerQuotaLimits []struct {
+			Unit         string `json:"unit"`
+			QuotaBuckets []struct {
+				EffectiveLimit   int64             `json:"effectiveLimit,string"`
+				ProducerOverride *json.RawMessage  `json:"producerOverride"`
+				Dimensions       map[string]string `json:"dimensions"`
+			} `json:"quotaBuckets"`
+		} `json:"consumerQuotaLimits"`
+	} `json:"metrics"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func NewConsumerQuotaFetcher(client *http.Client, serviceConsumerManagementUrl string, accessToken util.GetAccessTokenFunc) *ConsumerQuotaFetcher {
+	return &ConsumerQuotaFetcher{
+		serviceConsumerManagementUrl: serviceConsumerManagementUrl,
+		client:                       client,
+		accessToken:                  accessToken,
+	}
+}
+
+// FetchConsumerQuotaLimits fetches the rate quota limits of the consumer
+// overridden by the service producer. The limits of the quota buckets with
+// dimensions, such as regions, are skipped, so are the unlimited ones and the
+// zero ones denied by Service Control anyway.
+func (f *ConsumerQuotaFetcher) FetchConsumerQuotaLimits(serviceName, consumerNumber string) ([]options.ConsumerQuotaLimit, error) {
+	token, _, err := f.accessToken()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get access token: %v", err)
+	}
+
+	var limits []options.ConsumerQuotaLimit
+	pageToken := ""
+	for {
+		resp, err := f.fetchConsumerQuotaMetrics(serviceName, consumerNumber, token, pageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, metric := range resp.Metrics {
+			for _, limit := range metric.ConsumerQuotaLimits {
+				interval, ok := parseQuotaUnitInterval(limit.Unit)
+				if !ok {
+					continue
+				}
+				for _, bucket := range limit.QuotaBuckets {
+					if bucket.ProducerOverride == nil || len(bucket.Dimensions) != 0 || bucket.EffectiveLimit <= 0 {
+						continue
+					}
+					limits = append(limits, options.ConsumerQuotaLimit{
+						ConsumerNumber: consumerNumber,
+						Metric:         metric.Metric,
+						Limit:          bucket.EffectiveLimit,
+						Interval:       interval,
+					})
+				}
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	glog.Infof("fetched %d quota overrides of consumer %s for service %s", len(limits), consumerNumber, serviceName)
+	return limits, nil
+}
+
+func (f *ConsumerQuotaFetcher) fetchConsumerQuotaMetrics(serviceName, consumerNumber, token, pageToken string) (*consumerQuotaMetricsResponse, error) {
+	fetchUrl := fmt.Sprintf("%s/v1beta1/services/%s/projects/%s/consumerQuotaMetrics", f.serviceConsumerManagementUrl, serviceName, consumerNumber)
+	if pageToken != "" {
+		fetchUrl += "?pageToken=" + url.QueryEscape(pageToken)
+	}
+
+	req, err := http.NewRequest(util.GET, fetchUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request to %s: %v", fetchUrl, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to fetch quota metrics of consumer %s: %v", consumerNumber, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read quota metrics of consumer %s: %v", consumerNumber, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching quota metrics of consumer %s returns not 200 OK: %v", consumerNumber, resp.Status)
+	}
+
+	metrics := &consumerQuotaMetricsResponse{}
+	if err := json.Unmarshal(body, metrics); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal quota metrics of consumer %s: %v", consumerNumber, err)
+	}
+	return metrics, nil
+}
+
+// parseQuotaUnitInterval returns the interval of the rate quota unit, such as
+// one minute of "1/min/{project}".
+func parseQuotaUnitInterval(unit string) (time.Duration, bool) {
+	parts := strings.Split(unit, "/")
+	if len(parts) != 3 || parts[0] != "1" {
+		return 0, false
+	}
+	interval, ok := quotaUnitIntervals[parts[1]]
+	return interval, ok
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	fakeQuotaMetricsPage1 = `{
+  "metrics": [
+    {
+      "metric": "bookstore.googleapis.com/read_requests",
+      "consumerQuotaLimits": [
+        {
+          "unit": "1/min/{project}",
+          "quotaBuckets": [
+            {
+              "effectiveLimit": "600",
+              "defaultLimit": "100",
+              "producerOverride": {"overrideValue": "600"}
+            },
+            {
+              "effectiveLimit": "60",
+              "defaultLimit": "10",
+              "producerOverride": {"overrideValue": "60"},
+              "dimensions": {"region": "us-central1"}
+            }
+          ]
+        },
+        {
+          "unit": "1/d/{project}",
+          "quotaBuckets": [
+            {
+              "effectiveLimit": "100000",
+              "defaultLimit": "100000"
+            }
+          ]
+        }
+      ]
+    }
+  ],
+  "nextPageToken": "page-2"
+}`
+
+	fakeQuotaMetricsPage2 = `{
+  "metrics": [
+    {
+      "metric": "bookstore.googleapis.com/write_requests",
+      "consumerQuotaLimits": [
+        {
+          "unit": "1/d/{project}",
+          "quotaBuckets": [
+            {
+              "effectiveLimit": "5000",
+              "defaultLimit": "1000",
+              "producerOverride": {"overrideValue": "5000"}
+            }
+          ]
+        },
+        {
+          "unit": "1/{project}",
+          "quotaBuckets": [
+            {
+              "effectiveLimit": "10",
+              "defaultLimit": "1",
+              "producerOverride": {"overrideValue": "10"}
+            }
+          ]
+        },
+        {
+          "unit": "1/min/{project}",
+          "quotaBuckets": [
+            {
+              "effectiveLimit": "-1",
+              "defaultLimit": "100",
+              "producerOverride": {"overrideValue": "-1"}
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`
+)
+
+func TestConsumerQuotaFetcherFetchConsumerQuotaLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/v1beta1/services/bookstore.endpoints.project123.cloud.goog/projects/123456/consumerQuotaMetrics"; got != want {
+			t.Errorf("got path %q, want %q", got, want)
+		}
+		if got, want := r.Header.Get("Authorization"), "Bearer ya29.token"; got != want {
+			t.Errorf("got Authorization header %q, want %q", got, want)
+		}
+
+		if r.URL.Query().Get("pageToken") == "page-2" {
+			_, _ = w.Write([]byte(fakeQuotaMetricsPage2))
+			return
+		}
+		_, _ = w.Write([]byte(fakeQuotaMetricsPage1))
+	}))
+	defer server.Close()
+
+	f := NewConsumerQuotaFetcher(&http.Client{}, server.URL, func() (string, time.Duration, error) {
+		return "ya29.token", time.Hour, nil
+	})
+
+	got, err := f.FetchConsumerQuotaLimits("bookstore.endpoints.project123.cloud.goog", "123456")
+	if err != nil {
+		t.Fatalf("FetchConsumerQuotaLimits() got error %v", err)
+	}
+
+	want := []options.ConsumerQuotaLimit{
+		{
+			ConsumerNumber: "123456",
+			Metric:         "bookstore.googleapis.com/read_requests",
+			Limit:          600,
+			Interval:       time.Minute,
+		},
+		{
+			ConsumerNumber: "123456",
+			Metric:         "bookstore.googleapis.com/write_requests",
+			Limit:          5000,
+			Interval:       24 * time.Hour,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FetchConsumerQuotaLimits() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestConsumerQuotaFetcherFetchConsumerQuotaLimitsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	f := NewConsumerQuotaFetcher(&http.Client{}, server.URL, func() (string, time.Duration, error) {
+		return "ya29.token", time.Hour, nil
+	})
+
+	_, err := f.FetchConsumerQuotaLimits("bookstore.endpoints.project123.cloud.goog", "123456")
+	if err == nil || !strings.Contains(err.Error(), "fetching quota metrics of consumer 123456 returns not 200 OK: 403 Forbidden") {
+		t.Errorf("FetchConsumerQuotaLimits() got error %v, want 403 Forbidden", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+)
+
+const gcsURLPrefix = "gs://"
+
+// DescriptorFetcher fetches the proto descriptor used by the gRPC-JSON
+// transcoder from a HTTPS or GCS URL.
+type DescriptorFetcher struct {
+	url                string
+	client             *http.Client
+	accessToken        util.GetAccessTokenFunc
+	curETag            string
+	curVersion         string
+	detectChangeTicker *time.Ticker
+}
+
+// NewDescriptorFetcher creates a DescriptorFetcher. The access token is only
+// sent for GCS URLs, which are in the format of "gs://bucket/object".
+func NewDescriptorFetcher(client *http.Client, url string, accessToken util.GetAccessTokenFunc) *DescriptorFetcher {
+	return &DescriptorFetcher{
+		client:      client,
+		url:         url,
+		accessToken: accessToken,
+	}
+}
+
+// FetchDescriptor fetches the proto descriptor and returns it with its
+// version, the SHA-256 checksum of its content. It returns a nil descriptor if
+// the server responds that the descriptor is not modified since the last
+// fetch.
+func (f *DescriptorFetcher) FetchDescriptor() ([]byte, string, error) {
+	url := f.url
+	isGCS := strings.HasPrefix(url, gcsURLPrefix)
+	if isGCS {
+		url = util.FetchGCSObjectURL(strings.TrimPrefix(url, gcsURLPrefix))
+	}
+
+	req, err := http.NewRequest(util.GET, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("fail to create request to %s: %v", url, err)
+	}
+	if isGCS {
+		token, _, err := f.accessToken()
+		if err != nil {
+			return nil, "", fmt.Errorf("fail to get access token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if f.curETag != "" {
+		req.Header.Set("If-None-Match", f.curETag)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("fail to fetch proto descriptor %s: %v", f.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, f.curVersion, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("fail to read proto descriptor %s: %v", f.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetching proto descriptor %s returns not 200 OK: %v", f.url, resp.Status)
+	}
+
+	f.curETag = resp.Header.Get("ETag")
+	f.curVersion = fmt.Sprintf("%x", sha256.Sum256(body))
+	return body, f.curVersion, nil
+}
+
+// SetDetectDescriptorChangeTimer periodically fetches the proto descriptor and
+// calls the callback with the new descriptor whenever it changes.
+func (f *DescriptorFetcher) SetDetectDescriptorChangeTimer(interval time.Duration, callback func(descriptor []byte, version string)) {
+	go func() {
+		glog.Infof("start detect changes of proto descriptor %s every %v", f.url, interval)
+		f.detectChangeTicker = time.NewTicker(interval)
+
+		for range f.detectChangeTicker.C {
+			lastVersion := f.curVersion
+			descriptor, version, err := f.FetchDescriptor()
+			if err != nil {
+				glog.Errorf("error occurred when checking proto descriptor changes, %v", err)
+				continue
+			}
+
+			if descriptor == nil || version == lastVersion {
+				continue
+			}
+
+			callback(descriptor, version)
+		}
+	}()
+}
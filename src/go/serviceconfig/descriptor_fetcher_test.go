@@ -0,0 +1,150 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+type fakeDescriptorServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	descriptor string
+	eTag       string
+	code       int
+}
+
+func newFakeDescriptorServer(t *testing.T, wantAuthorization string) *fakeDescriptorServer {
+	s := &fakeDescriptorServer{
+		code: http.StatusOK,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != wantAuthorization {
+			t.Errorf("got Authorization header %q, want %q", got, wantAuthorization)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.eTag != "" && r.Header.Get("If-None-Match") == s.eTag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.eTag)
+		w.WriteHeader(s.code)
+		_, _ = w.Write([]byte(s.descriptor))
+	}))
+	return s
+}
+
+func (s *fakeDescriptorServer) set(code int, eTag, descriptor string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.code = code
+	s.eTag = eTag
+	s.descriptor = descriptor
+}
+
+func TestDescriptorFetcherFetchDescriptor(t *testing.T) {
+	server := newFakeDescriptorServer(t, "")
+	defer server.Close()
+
+	f := NewDescriptorFetcher(&http.Client{}, server.URL+"/api_descriptor.pb", nil)
+
+	server.set(http.StatusOK, `"v1"`, "descriptor-1")
+	descriptor, version, err := f.FetchDescriptor()
+	if err != nil {
+		t.Fatalf("want no error, got error %v", err)
+	}
+	if string(descriptor) != "descriptor-1" || version == "" {
+		t.Fatalf("want descriptor-1 with a version, got %q with version %q", descriptor, version)
+	}
+
+	// The ETag is not changed, the descriptor is not modified.
+	descriptor, notModifiedVersion, err := f.FetchDescriptor()
+	if err != nil {
+		t.Fatalf("want no error, got error %v", err)
+	}
+	if descriptor != nil || notModifiedVersion != version {
+		t.Errorf("want not modified descriptor with version %q, got %q with version %q", version, descriptor, notModifiedVersion)
+	}
+
+	server.set(http.StatusNotFound, "", "")
+	if _, _, err := f.FetchDescriptor(); err == nil || !strings.Contains(err.Error(), "returns not 200 OK: 404 Not Found") {
+		t.Errorf("want not found error, got error %v", err)
+	}
+}
+
+func TestDescriptorFetcherFetchDescriptorFromGCS(t *testing.T) {
+	server := newFakeDescriptorServer(t, "Bearer gcs-token")
+	defer server.Close()
+	server.set(http.StatusOK, "", "descriptor")
+
+	oldFetchGCSObjectURL := util.FetchGCSObjectURL
+	defer func() { util.FetchGCSObjectURL = oldFetchGCSObjectURL }()
+	util.FetchGCSObjectURL = func(object string) string {
+		if want := "bucket/api_descriptor.pb"; object != want {
+			t.Errorf("got GCS object %s, want %s", object, want)
+		}
+		return server.URL
+	}
+
+	accessToken := func() (string, time.Duration, error) { return "gcs-token", 0, nil }
+	f := NewDescriptorFetcher(&http.Client{}, "gs://bucket/api_descriptor.pb", accessToken)
+	if descriptor, _, err := f.FetchDescriptor(); err != nil || string(descriptor) != "descriptor" {
+		t.Errorf("want descriptor, got %q with error %v", descriptor, err)
+	}
+}
+
+func TestSetDetectDescriptorChangeTimer(t *testing.T) {
+	server := newFakeDescriptorServer(t, "")
+	defer server.Close()
+	server.set(http.StatusOK, "", "descriptor-1")
+
+	f := NewDescriptorFetcher(&http.Client{}, server.URL, nil)
+	if _, _, err := f.FetchDescriptor(); err != nil {
+		t.Fatal(err)
+	}
+
+	var cnt int32
+	var lastDescriptor atomic.Value
+	f.SetDetectDescriptorChangeTimer(time.Millisecond*50, func(descriptor []byte, version string) {
+		atomic.AddInt32(&cnt, 1)
+		lastDescriptor.Store(string(descriptor))
+	})
+
+	// The descriptor is not changed yet, the callback should not be called.
+	time.Sleep(time.Millisecond * 200)
+	if got := atomic.LoadInt32(&cnt); got != 0 {
+		t.Fatalf("want callback not called before descriptor changes, get %v times", got)
+	}
+
+	server.set(http.StatusOK, "", "descriptor-2")
+	time.Sleep(time.Millisecond * 200)
+
+	if got := atomic.LoadInt32(&cnt); got != 1 {
+		t.Fatalf("want callback called once, get %v times", got)
+	}
+	if got, want := lastDescriptor.Load(), "descriptor-2"; got != want {
+		t.Errorf("want last descriptor %v, got %v", want, got)
+	}
+}
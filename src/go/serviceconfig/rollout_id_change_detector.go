@@ -31,6 +31,10 @@ type RolloutIdChangeDetector struct {
 	curRolloutId          string
 	accessToken           util.GetAccessTokenFunc
 	detectRolloutIdTicker *time.Ticker
+
+	// checkResultCallback is called with the result of each check, after the
+	// change callback if the rollout id changes.
+	checkResultCallback func(err error)
 }
 
 func NewRolloutIdChangeDetector(client *http.Client, serviceControlUrl, serviceName string,
@@ -57,6 +61,12 @@ func (c *RolloutIdChangeDetector) fetchLatestRolloutId() (string, error) {
 	return reportResponse.ServiceRolloutId, nil
 }
 
+// SetCheckResultCallback sets the callback of the result of each check. It
+// must be called before SetDetectRolloutIdChangeTimer.
+func (c *RolloutIdChangeDetector) SetCheckResultCallback(callback func(err error)) {
+	c.checkResultCallback = callback
+}
+
 func (c *RolloutIdChangeDetector) SetDetectRolloutIdChangeTimer(interval time.Duration, callback func()) {
 	go func() {
 		glog.Infof("start detect latest rollout id every %v", interval)
@@ -66,15 +76,21 @@ func (c *RolloutIdChangeDetector) SetDetectRolloutIdChangeTimer(interval time.Du
 			latestRolloutId, err := c.fetchLatestRolloutId()
 			if err != nil {
 				glog.Errorf("error occurred when checking new rollout id, %v", err)
+				c.reportCheckResult(err)
 				continue
 			}
 
-			if latestRolloutId == c.curRolloutId {
-				continue
+			if latestRolloutId != c.curRolloutId {
+				c.curRolloutId = latestRolloutId
+				callback()
 			}
-
-			c.curRolloutId = latestRolloutId
-			callback()
+			c.reportCheckResult(nil)
 		}
 	}()
 }
+
+func (c *RolloutIdChangeDetector) reportCheckResult(err error) {
+	if c.checkResultCallback != nil {
+		c.checkResultCallback(err)
+	}
+}
@@ -119,3 +119,40 @@ func TestSetDetectRolloutIdChangeTimer(t *testing.T) {
 		t.Errorf("want curRolloutId: %s, get curRolloutId: %s", wantRolloutId, cif.curRolloutId)
 	}
 }
+
+func TestSetCheckResultCallback(t *testing.T) {
+	serviceControlServer := util.InitMockServer(genFakeReport("test-rollout-id"))
+	accessToken := func() (string, time.Duration, error) { return "token", time.Duration(60), nil }
+	cif := NewRolloutIdChangeDetector(&http.Client{}, serviceControlServer.GetURL(), "service-name", accessToken)
+
+	var changeCnt, successCnt, failureCnt int32
+	cif.SetCheckResultCallback(func(err error) {
+		if err != nil {
+			atomic.AddInt32(&failureCnt, 1)
+			return
+		}
+		atomic.AddInt32(&successCnt, 1)
+	})
+	cif.SetDetectRolloutIdChangeTimer(time.Millisecond*50, func() {
+		atomic.AddInt32(&changeCnt, 1)
+	})
+
+	// The checks succeed whether the rollout id changes or not.
+	time.Sleep(time.Millisecond * 500)
+	if got := atomic.LoadInt32(&changeCnt); got != 1 {
+		t.Errorf("want change callback called once, get %v times", got)
+	}
+	if got := atomic.LoadInt32(&successCnt); got < 2 {
+		t.Errorf("want check result callback called with success at least twice, get %v times", got)
+	}
+	if got := atomic.LoadInt32(&failureCnt); got != 0 {
+		t.Errorf("want no failed check, get %v", got)
+	}
+
+	// The checks fail once Service Control is unreachable.
+	serviceControlServer.Close()
+	time.Sleep(time.Millisecond * 500)
+	if got := atomic.LoadInt32(&failureCnt); got == 0 {
+		t.Errorf("want failed checks after Service Control is closed, get none")
+	}
+}
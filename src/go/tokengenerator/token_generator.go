@@ -15,6 +15,7 @@
 package tokengenerator
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -26,6 +27,8 @@ import (
 	"github.com/gorilla/mux"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/api/option"
 )
 
 var (
@@ -34,13 +37,19 @@ var (
 		"https://www.googleapis.com/auth/service.management.readonly",
 		// Call servicecontrol to get latest rollout id.
 		"https://www.googleapis.com/auth/servicecontrol",
+		// Call IAM credentials to get the tokens of --service_control_credentials
+		// and --backend_auth_credentials.
+		"https://www.googleapis.com/auth/iam",
 	}
-	tokenCache = &oauth2.Token{}
+
+	// tokenCache caches the access token of each service account key file.
+	// The application default credentials are cached with the empty key.
+	tokenCache = make(map[string]*oauth2.Token)
 	tokenMux   = sync.Mutex{}
 )
 
 var GenerateAccessTokenFromFile = func(saFilePath string) (string, time.Duration, error) {
-	if token, duration := activeAccessToken(); token != "" {
+	if token, duration := activeAccessToken(saFilePath); token != "" {
 		return token, duration, nil
 	}
 
@@ -49,34 +58,35 @@ var GenerateAccessTokenFromFile = func(saFilePath string) (string, time.Duration
 		return "", 0, err
 	}
 
-	return generateAccessToken(data)
+	return generateAccessToken(saFilePath, data)
 }
 
 // A test-friendly version of `GenerateAccessTokenFromFile`
 func generateAccessTokenFromData(saData []byte) (string, time.Duration, error) {
-	if token, duration := activeAccessToken(); token != "" {
+	if token, duration := activeAccessToken(""); token != "" {
 		return token, duration, nil
 	}
 
-	return generateAccessToken(saData)
+	return generateAccessToken("", saData)
 }
 
-func activeAccessToken() (string, time.Duration) {
+func activeAccessToken(cacheKey string) (string, time.Duration) {
 	now := time.Now()
 	tokenMux.Lock()
 	defer tokenMux.Unlock()
 
 	// Follow the similar logic as GCE metadata server, where returned token will be valid for at
 	// least 60s.
-	if tokenCache.AccessToken == "" || now.After(tokenCache.Expiry.Add(-time.Second*60)) {
+	token := tokenCache[cacheKey]
+	if token == nil || token.AccessToken == "" || now.After(token.Expiry.Add(-time.Second*60)) {
 		return "", 0
 
 	}
 
-	return tokenCache.AccessToken, tokenCache.Expiry.Sub(now)
+	return token.AccessToken, token.Expiry.Sub(now)
 }
 
-func generateAccessToken(keyData []byte) (string, time.Duration, error) {
+func generateAccessToken(cacheKey string, keyData []byte) (string, time.Duration, error) {
 	creds, err := google.CredentialsFromJSON(oauth2.NoContext, keyData, _GOOGLE_API_SCOPE...)
 	if err != nil {
 		return "", 0, err
@@ -90,12 +100,28 @@ func generateAccessToken(keyData []byte) (string, time.Duration, error) {
 	tokenMux.Lock()
 	defer tokenMux.Unlock()
 
-	tokenCache = token
+	tokenCache[cacheKey] = token
 	return token.AccessToken, token.Expiry.Sub(time.Now()), nil
 }
 
+// GenerateIdentityTokenFromFile mints a Google-signed identity token of the
+// audience with the service account key file. The identity tokens are not
+// cached, as Envoy assumes a fresh token and caches it per audience.
+var GenerateIdentityTokenFromFile = func(saFilePath string, audience string) (string, error) {
+	tokenSource, err := idtoken.NewTokenSource(context.Background(), audience, option.WithCredentialsFile(saFilePath))
+	if err != nil {
+		return "", err
+	}
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
 func GenerateApplicationDefaultCredentialsToken() (string, time.Duration, error) {
-	if token, duration := activeAccessToken(); token != "" {
+	if token, duration := activeAccessToken(""); token != "" {
 		return token, duration, nil
 	}
 
@@ -107,7 +133,7 @@ func GenerateApplicationDefaultCredentialsToken() (string, time.Duration, error)
 	tokenMux.Lock()
 	defer tokenMux.Unlock()
 
-	tokenCache = token
+	tokenCache[""] = token
 	return token.AccessToken, token.Expiry.Sub(time.Now()), nil
 }
 
@@ -115,17 +141,58 @@ func GenerateApplicationDefaultCredentialsToken() (string, time.Duration, error)
 // token generated by the service account credential.
 //
 // It follows the following scheme:
-// Request: GET /local/access_token.
+// Request: GET /local/access_token for the token to call Service Control, or
+// GET /local/backend_auth/access_token for the token to call IAM for the
+// backend auth.
 // Response: access token response is a JSON payload in the format:
 //
 //	{
 //	  "access_token": "string",
 //	  "expires_in": uint
 //	}
-func MakeTokenAgentHandler(serviceAccountKey string) http.Handler {
+//
+// Request: GET /local/backend_auth/identity_token?audience=AUDIENCE.
+// Response: the identity token of the audience, in the same format as the
+// identity token of the metadata server.
+//
+// The paths are only served if their service account key is specified. The
+// endpoints of the token service are served if it is not nil, see
+// TokenService.register.
+func MakeTokenAgentHandler(serviceControlKey string, backendAuthKey string, service *TokenService) http.Handler {
 	r := mux.NewRouter()
 
-	r.PathPrefix(util.TokenAgentAccessTokenPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if service != nil {
+		service.register(r)
+	}
+
+	if serviceControlKey != "" {
+		r.PathPrefix(util.TokenAgentAccessTokenPath).Methods("GET").HandlerFunc(accessTokenHandler(serviceControlKey))
+	}
+	if backendAuthKey != "" {
+		r.PathPrefix(util.TokenAgentBackendAuthAccessTokenPath).Methods("GET").HandlerFunc(accessTokenHandler(backendAuthKey))
+		r.PathPrefix(util.TokenAgentBackendAuthIdentityTokenPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			audience := r.URL.Query().Get("audience")
+			if audience == "" {
+				http.Error(w, "missing query parameter audience", http.StatusBadRequest)
+				return
+			}
+
+			token, err := GenerateIdentityTokenFromFile(backendAuthKey, audience)
+			if err != nil {
+				glog.Errorf("local identity token agent had error: %v", err)
+				http.Error(w, err.Error(), 500)
+				return
+			}
+
+			_, _ = w.Write([]byte(token))
+		})
+	}
+
+	return r
+}
+
+func accessTokenHandler(serviceAccountKey string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		token, expire, err := GenerateAccessTokenFromFile(serviceAccountKey)
 
 		if err != nil {
@@ -135,7 +202,5 @@ func MakeTokenAgentHandler(serviceAccountKey string) http.Handler {
 		}
 
 		_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token": "%s", "expires_in": %v}`, token, int(expire.Seconds()))))
-	})
-
-	return r
+	}
 }
@@ -53,7 +53,7 @@ func TestGenerateAccessToken(t *testing.T) {
 
 func TestMakeTokenAgentHandler(t *testing.T) {
 
-	s := httptest.NewServer(MakeTokenAgentHandler(platform.GetFilePath(platform.FakeServiceAccountFile)))
+	s := httptest.NewServer(MakeTokenAgentHandler(platform.GetFilePath(platform.FakeServiceAccountFile), platform.GetFilePath(platform.FakeServiceAccountFile), nil))
 
 	testCases := []struct {
 		desc                   string
@@ -72,6 +72,15 @@ func TestMakeTokenAgentHandler(t *testing.T) {
 			method:   "GET",
 			wantResp: `{"access_token": "ya29.new", "expires_in": 100}`,
 		},
+		{
+			desc: "success, get access token of backend auth",
+			genAccessTokenFromFile: func(saFilePath string) (string, time.Duration, error) {
+				return "ya29.backend-auth", time.Duration(time.Second * 100), nil
+			},
+			path:     "/local/backend_auth/access_token",
+			method:   "GET",
+			wantResp: `{"access_token": "ya29.backend-auth", "expires_in": 100}`,
+		},
 		{
 			desc: "fail, error in generating access token",
 			genAccessTokenFromFile: func(saFilePath string) (string, time.Duration, error) {
@@ -108,3 +117,60 @@ func TestMakeTokenAgentHandler(t *testing.T) {
 
 	}
 }
+
+func TestMakeTokenAgentHandlerIdentityToken(t *testing.T) {
+	GenerateIdentityTokenFromFile = func(saFilePath string, audience string) (string, error) {
+		if audience == "bad-audience" {
+			return "", fmt.Errorf("gen-identity-token-error")
+		}
+		return "id-token-of-" + audience, nil
+	}
+
+	testCases := []struct {
+		desc           string
+		backendAuthKey string
+		path           string
+		wantResp       string
+		wantError      string
+	}{
+		{
+			desc:           "success, get identity token in the format of metadata server",
+			backendAuthKey: platform.GetFilePath(platform.FakeServiceAccountFile),
+			path:           "/local/backend_auth/identity_token?format=standard&audience=https://backend.com",
+			wantResp:       "id-token-of-https://backend.com",
+		},
+		{
+			desc:           "fail, no audience",
+			backendAuthKey: platform.GetFilePath(platform.FakeServiceAccountFile),
+			path:           "/local/backend_auth/identity_token",
+			wantError:      "400 Bad Request",
+		},
+		{
+			desc:           "fail, error in generating identity token",
+			backendAuthKey: platform.GetFilePath(platform.FakeServiceAccountFile),
+			path:           "/local/backend_auth/identity_token?audience=bad-audience",
+			wantError:      "500 Internal Server Error, gen-identity-token-error",
+		},
+		{
+			desc:      "fail, no backend auth service account key",
+			path:      "/local/backend_auth/identity_token?audience=https://backend.com",
+			wantError: "404 Not Found",
+		},
+	}
+
+	for _, tc := range testCases {
+		s := httptest.NewServer(MakeTokenAgentHandler(platform.GetFilePath(platform.FakeServiceAccountFile), tc.backendAuthKey, nil))
+		_, resp, err := utils.DoWithHeaders(s.URL+tc.path, "GET", "", nil)
+		s.Close()
+		if tc.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("test(%s): get error: %v, want error: %s", tc.desc, err, tc.wantError)
+			}
+			continue
+		}
+
+		if err != nil || tc.wantResp != string(resp) {
+			t.Errorf("test(%s): get resp: %s, err: %v, want resp %s", tc.desc, string(resp), err, tc.wantResp)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokengenerator
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/api/option"
+)
+
+const (
+	// The paths of the token service for the other containers of the pod.
+	TokenServiceAccessTokenPath   = "/v1/access_token"
+	TokenServiceIdentityTokenPath = "/v1/identity_token"
+
+	// The path of the token service metrics in the expvar format.
+	TokenServiceMetricsPath = "/debug/vars"
+
+	// maxIdentityTokenCacheEntries bounds identityTokenCache, as the clients of
+	// the token service can request any audience.
+	maxIdentityTokenCacheEntries = 128
+)
+
+var (
+	// Token service metrics, exported at /debug/vars.
+	tokenServiceMetrics = expvar.NewMap("token_service")
+
+	// identityTokenCache caches the identity tokens of each service account key
+	// file and audience.
+	identityTokenCache = make(map[identityTokenCacheKey]*oauth2.Token)
+	identityTokenMux   = sync.Mutex{}
+)
+
+type identityTokenCacheKey struct {
+	saFilePath string
+	audience   string
+}
+
+// TokenService serves the cached access and identity tokens to the other
+// containers of the pod, so they do not need their own credentials.
+type TokenService struct {
+	// AccessToken gets the access token.
+	AccessToken util.GetAccessTokenFunc
+
+	// IdentityToken gets the identity token of the audience.
+	IdentityToken func(audience string) (string, time.Duration, error)
+}
+
+// NewTokenServiceFromFile creates a TokenService backed by the service account
+// key file.
+func NewTokenServiceFromFile(saFilePath string) *TokenService {
+	return &TokenService{
+		AccessToken: func() (string, time.Duration, error) {
+			return GenerateAccessTokenFromFile(saFilePath)
+		},
+		IdentityToken: func(audience string) (string, time.Duration, error) {
+			return GenerateCachedIdentityTokenFromFile(saFilePath, audience)
+		},
+	}
+}
+
+// GenerateCachedIdentityTokenFromFile mints the identity token of the audience
+// with the service account key file, cached per audience. The returned token is
+// valid for at least 60s.
+var GenerateCachedIdentityTokenFromFile = func(saFilePath string, audience string) (string, time.Duration, error) {
+	key := identityTokenCacheKey{saFilePath: saFilePath, audience: audience}
+	now := time.Now()
+
+	identityTokenMux.Lock()
+	token := identityTokenCache[key]
+	identityTokenMux.Unlock()
+	if token != nil && !now.After(token.Expiry.Add(-time.Second*60)) {
+		tokenServiceMetrics.Add("identity_token_cache_hits", 1)
+		return token.AccessToken, token.Expiry.Sub(now), nil
+	}
+
+	tokenSource, err := idtoken.NewTokenSource(context.Background(), audience, option.WithCredentialsFile(saFilePath))
+	if err != nil {
+		return "", 0, err
+	}
+	token, err = tokenSource.Token()
+	if err != nil {
+		return "", 0, err
+	}
+
+	identityTokenMux.Lock()
+	defer identityTokenMux.Unlock()
+	if _, ok := identityTokenCache[key]; !ok && len(identityTokenCache) >= maxIdentityTokenCacheEntries {
+		evictIdentityTokens(now)
+	}
+	identityTokenCache[key] = token
+	return token.AccessToken, token.Expiry.Sub(now), nil
+}
+
+// evictIdentityTokens removes the expired identity tokens, or the one expiring
+// first if none has expired. It must be called with identityTokenMux held.
+func evictIdentityTokens(now time.Time) {
+	var firstExpiring *identityTokenCacheKey
+	for key, token := range identityTokenCache {
+		if now.After(token.Expiry) {
+			delete(identityTokenCache, key)
+			continue
+		}
+		if firstExpiring == nil || token.Expiry.Before(identityTokenCache[*firstExpiring].Expiry) {
+			k := key
+			firstExpiring = &k
+		}
+	}
+	if len(identityTokenCache) >= maxIdentityTokenCacheEntries && firstExpiring != nil {
+		delete(identityTokenCache, *firstExpiring)
+	}
+}
+
+type accessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+type identityTokenResponse struct {
+	IdToken   string `json:"id_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// register adds the token service endpoints to the router.
+//
+// It follows the following scheme:
+// Request: GET /v1/access_token.
+// Response: the access token in the JSON format of the metadata server:
+//
+//	{
+//	  "access_token": "string",
+//	  "expires_in": uint,
+//	  "token_type": "Bearer"
+//	}
+//
+// Request: GET /v1/identity_token?audience=AUDIENCE.
+// Response: the identity token of the audience in the JSON format:
+//
+//	{
+//	  "id_token": "string",
+//	  "expires_in": uint
+//	}
+//
+// Request: GET /debug/vars.
+// Response: the request, error and cache hit counts of the token service in
+// the expvar format. Only the token service metrics are served, not the other
+// expvar variables such as the command line flags.
+func (s *TokenService) register(r *mux.Router) {
+	r.Path(TokenServiceAccessTokenPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenServiceMetrics.Add("access_token_requests", 1)
+		token, expire, err := s.AccessToken()
+		if err != nil {
+			tokenServiceMetrics.Add("access_token_errors", 1)
+			glog.Errorf("token service fail to get access token: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, &accessTokenResponse{
+			AccessToken: token,
+			ExpiresIn:   int(expire.Seconds()),
+			TokenType:   "Bearer",
+		})
+	})
+
+	r.Path(TokenServiceIdentityTokenPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenServiceMetrics.Add("identity_token_requests", 1)
+		audience := r.URL.Query().Get("audience")
+		if audience == "" {
+			http.Error(w, "missing query parameter audience", http.StatusBadRequest)
+			return
+		}
+
+		token, expire, err := s.IdentityToken(audience)
+		if err != nil {
+			tokenServiceMetrics.Add("identity_token_errors", 1)
+			glog.Errorf("token service fail to get identity token of audience %q: %v", audience, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, &identityTokenResponse{
+			IdToken:   token,
+			ExpiresIn: int(expire.Seconds()),
+		})
+	})
+
+	r.Path(TokenServiceMetricsPath).Methods("GET").HandlerFunc(serveMetrics)
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = fmt.Fprintf(w, "{\n%q: %s\n}\n", "token_service", tokenServiceMetrics.String())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokengenerator
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/tests/utils"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenService(t *testing.T) {
+	service := &TokenService{
+		AccessToken: func() (string, time.Duration, error) {
+			return "ya29.new", 100 * time.Second, nil
+		},
+		IdentityToken: func(audience string) (string, time.Duration, error) {
+			if audience == "bad-audience" {
+				return "", 0, fmt.Errorf("gen-identity-token-error")
+			}
+			return "id-token-of-" + audience, 200 * time.Second, nil
+		},
+	}
+	s := httptest.NewServer(MakeTokenAgentHandler("", "", service))
+	defer s.Close()
+
+	testCases := []struct {
+		desc      string
+		path      string
+		wantResp  string
+		wantError string
+	}{
+		{
+			desc:     "success, get access token",
+			path:     "/v1/access_token",
+			wantResp: `{"access_token":"ya29.new","expires_in":100,"token_type":"Bearer"}`,
+		},
+		{
+			desc:     "success, get identity token",
+			path:     "/v1/identity_token?audience=https://backend.com",
+			wantResp: `{"id_token":"id-token-of-https://backend.com","expires_in":200}`,
+		},
+		{
+			desc:      "fail, no audience",
+			path:      "/v1/identity_token",
+			wantError: "400 Bad Request",
+		},
+		{
+			desc:      "fail, error in generating identity token",
+			path:      "/v1/identity_token?audience=bad-audience",
+			wantError: "500 Internal Server Error, gen-identity-token-error",
+		},
+		{
+			desc:      "fail, the access token of service control is not served without its service account key",
+			path:      "/local/access_token",
+			wantError: "404 Not Found",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, resp, err := utils.DoWithHeaders(s.URL+tc.path, "GET", "", nil)
+		if tc.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("test(%s): get error: %v, want error: %s", tc.desc, err, tc.wantError)
+			}
+			continue
+		}
+
+		if err != nil || tc.wantResp != string(resp) {
+			t.Errorf("test(%s): get resp: %s, err: %v, want resp %s", tc.desc, string(resp), err, tc.wantResp)
+		}
+	}
+
+	_, resp, err := utils.DoWithHeaders(s.URL+"/debug/vars", "GET", "", nil)
+	if err != nil {
+		t.Fatalf("fail to get metrics: %v", err)
+	}
+	for _, want := range []string{`"access_token_requests": 1`, `"identity_token_requests": 3`, `"identity_token_errors": 1`} {
+		if !strings.Contains(string(resp), want) {
+			t.Errorf("metrics %s, want %s", string(resp), want)
+		}
+	}
+	// The other expvar variables, such as the command line flags, are not exposed.
+	for _, notWant := range []string{`"cmdline"`, `"memstats"`} {
+		if strings.Contains(string(resp), notWant) {
+			t.Errorf("metrics %s, want no %s", string(resp), notWant)
+		}
+	}
+}
+
+func TestGenerateCachedIdentityTokenFromFile(t *testing.T) {
+	now := time.Now()
+	identityTokenCache[identityTokenCacheKey{saFilePath: "key.json", audience: "https://fresh.com"}] = &oauth2.Token{
+		AccessToken: "fresh-token",
+		Expiry:      now.Add(30 * time.Minute),
+	}
+	identityTokenCache[identityTokenCacheKey{saFilePath: "key.json", audience: "https://expiring.com"}] = &oauth2.Token{
+		AccessToken: "expiring-token",
+		Expiry:      now.Add(30 * time.Second),
+	}
+
+	token, expire, err := GenerateCachedIdentityTokenFromFile("key.json", "https://fresh.com")
+	if err != nil || token != "fresh-token" || expire < 29*time.Minute {
+		t.Errorf("got token %q, expire %v, err %v, want the cached token", token, expire, err)
+	}
+
+	// The token expiring within 60s is minted again, which fails with the
+	// missing key file.
+	if _, _, err := GenerateCachedIdentityTokenFromFile("key.json", "https://expiring.com"); err == nil {
+		t.Errorf("want error minting the token with missing key file, got nil")
+	}
+
+	// The tokens are cached per audience.
+	if _, _, err := GenerateCachedIdentityTokenFromFile("key.json", "https://other.com"); err == nil {
+		t.Errorf("want error minting the token with missing key file, got nil")
+	}
+}
+
+func TestEvictIdentityTokens(t *testing.T) {
+	now := time.Now()
+	identityTokenCache = make(map[identityTokenCacheKey]*oauth2.Token)
+	for i := 0; i < maxIdentityTokenCacheEntries; i++ {
+		identityTokenCache[identityTokenCacheKey{saFilePath: "key.json", audience: fmt.Sprintf("https://%d.com", i)}] = &oauth2.Token{
+			Expiry: now.Add(time.Duration(i+1) * time.Minute),
+		}
+	}
+
+	evictIdentityTokens(now)
+	if len(identityTokenCache) != maxIdentityTokenCacheEntries-1 {
+		t.Errorf("got %d cached tokens, want %d", len(identityTokenCache), maxIdentityTokenCacheEntries-1)
+	}
+	if _, ok := identityTokenCache[identityTokenCacheKey{saFilePath: "key.json", audience: "https://0.com"}]; ok {
+		t.Errorf("want the token expiring first to be evicted")
+	}
+
+	// All the expired tokens are evicted.
+	evictIdentityTokens(now.Add(time.Hour + time.Second))
+	if len(identityTokenCache) != maxIdentityTokenCacheEntries-60 {
+		t.Errorf("got %d cached tokens, want %d", len(identityTokenCache), maxIdentityTokenCacheEntries-60)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ParseCIDRRanges parses comma separated CIDRs, such as "10.0.0.0/8,::1".
+// An IP address without prefix length is a single address range.
+func ParseCIDRRanges(s string) ([]*corepb.CidrRange, error) {
+	var ranges []*corepb.CidrRange
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", cidr)
+			}
+			prefixLen := 128
+			if ip.To4() != nil {
+				prefixLen = 32
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, prefixLen)
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %v", cidr, err)
+		}
+		prefixLen, _ := ipNet.Mask.Size()
+		ranges = append(ranges, &corepb.CidrRange{
+			AddressPrefix: ipNet.IP.String(),
+			PrefixLen:     &wrapperspb.UInt32Value{Value: uint32(prefixLen)},
+		})
+	}
+	return ranges, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParseCIDRRanges(t *testing.T) {
+	testData := []struct {
+		desc       string
+		in         string
+		wantRanges []*corepb.CidrRange
+		wantError  string
+	}{
+		{
+			desc: "CIDRs and IP addresses",
+			in:   "10.1.2.3/8, 192.168.0.1,2001:db8::/32, ::1",
+			wantRanges: []*corepb.CidrRange{
+				{AddressPrefix: "10.0.0.0", PrefixLen: &wrapperspb.UInt32Value{Value: 8}},
+				{AddressPrefix: "192.168.0.1", PrefixLen: &wrapperspb.UInt32Value{Value: 32}},
+				{AddressPrefix: "2001:db8::", PrefixLen: &wrapperspb.UInt32Value{Value: 32}},
+				{AddressPrefix: "::1", PrefixLen: &wrapperspb.UInt32Value{Value: 128}},
+			},
+		},
+		{
+			desc: "Empty",
+			in:   "",
+		},
+		{
+			desc:      "Invalid IP address",
+			in:        "10.0.0.256",
+			wantError: `invalid IP address "10.0.0.256"`,
+		},
+		{
+			desc:      "Invalid prefix length",
+			in:        "10.0.0.0/33",
+			wantError: `invalid CIDR "10.0.0.0/33"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseCIDRRanges(tc.in)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("ParseCIDRRanges(%q) got error %v, want error containing %q", tc.in, err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCIDRRanges(%q) got error: %v", tc.in, err)
+			}
+			if diff := cmp.Diff(tc.wantRanges, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ParseCIDRRanges(%q) diff (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
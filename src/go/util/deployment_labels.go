@@ -0,0 +1,64 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var deploymentLabelKeyRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParseDeploymentLabels parses the deployment labels in the format of
+// "key1=value1;key2=value2". The keys must be lowercase letters, digits and
+// '_', starting with a letter.
+func ParseDeploymentLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid deployment label %q, must be in the format of key=value", pair)
+		}
+		key, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if !deploymentLabelKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid deployment label key %q, must only contain lowercase letters, digits and '_', starting with a letter", key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// FormatDeploymentLabels formats the deployment labels sorted by the keys, in
+// the format parsed by ParseDeploymentLabels. The ';' in the values are
+// replaced by '_'.
+func FormatDeploymentLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+strings.ReplaceAll(labels[key], ";", "_"))
+	}
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDeploymentLabels(t *testing.T) {
+	testData := []struct {
+		desc       string
+		in         string
+		wantLabels map[string]string
+		wantError  string
+	}{
+		{
+			desc:       "Empty",
+			wantLabels: map[string]string{},
+		},
+		{
+			desc: "Success",
+			in:   "gke_cluster=prod; gke_namespace = bookstore;env=",
+			wantLabels: map[string]string{
+				"gke_cluster":   "prod",
+				"gke_namespace": "bookstore",
+				"env":           "",
+			},
+		},
+		{
+			desc: "The last value wins",
+			in:   "env=staging;env=prod",
+			wantLabels: map[string]string{
+				"env": "prod",
+			},
+		},
+		{
+			desc:      "No value",
+			in:        "env",
+			wantError: `invalid deployment label "env", must be in the format of key=value`,
+		},
+		{
+			desc:      "Invalid key",
+			in:        "Env=prod",
+			wantError: `invalid deployment label key "Env"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseDeploymentLabels(tc.in)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("ParseDeploymentLabels() got error %v, want error containing %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDeploymentLabels() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantLabels, got); diff != "" {
+				t.Errorf("ParseDeploymentLabels() diff (-want +got):\n%s", diff)
+			}
+
+			if roundTrip, err := ParseDeploymentLabels(FormatDeploymentLabels(got)); err != nil || !cmp.Equal(roundTrip, got) {
+				t.Errorf("FormatDeploymentLabels() got %q, which is not parsed back: %v", FormatDeploymentLabels(got), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// ConditionalCacheTransport is a http.RoundTripper caching the 200 OK
+// responses of GET requests with an ETag or Last-Modified header.
+//
+// The cached responses are revalidated with conditional requests. A 304 Not
+// Modified response is replaced by the cached response, so the callers always
+// get the full body while the unchanged content is not transferred again.
+type ConditionalCacheTransport struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	entries map[string]*conditionalCacheEntry
+}
+
+type conditionalCacheEntry struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// NewConditionalCacheTransport creates a ConditionalCacheTransport sending
+// the requests with the base transport, http.DefaultTransport if nil.
+func NewConditionalCacheTransport(base http.RoundTripper) *ConditionalCacheTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &ConditionalCacheTransport{
+		base:    base,
+		entries: make(map[string]*conditionalCacheEntry),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *ConditionalCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The conditional requests of the callers are not interfered with.
+	if req.Method != GET || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	t.mu.Lock()
+	entry := t.entries[key]
+	t.mu.Unlock()
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        entry.header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(entry.body)),
+			ContentLength: int64(len(entry.body)),
+			Request:       req,
+		}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		t.mu.Lock()
+		delete(t.entries, key)
+		t.mu.Unlock()
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	t.entries[key] = &conditionalCacheEntry{
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header.Clone(),
+		body:         body,
+	}
+	t.mu.Unlock()
+	return resp, nil
+}
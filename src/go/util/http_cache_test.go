@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalCacheTransport(t *testing.T) {
+	testData := []struct {
+		desc string
+		// The validator header set on the responses, empty for none.
+		validatorHeader string
+		// The conditional request header checked against the validator.
+		conditionalHeader string
+		wantNotModified   int
+	}{
+		{
+			desc:              "Success, revalidated with the ETag",
+			validatorHeader:   "ETag",
+			conditionalHeader: "If-None-Match",
+			wantNotModified:   2,
+		},
+		{
+			desc:              "Success, revalidated with the Last-Modified",
+			validatorHeader:   "Last-Modified",
+			conditionalHeader: "If-Modified-Since",
+			wantNotModified:   2,
+		},
+		{
+			desc:            "Success, not cached without validator",
+			wantNotModified: 0,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			content, version := "v1", "Mon, 02 Jan 2023 15:04:05 GMT"
+			notModified := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.validatorHeader != "" {
+					if r.Header.Get(tc.conditionalHeader) == version {
+						notModified++
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					w.Header().Set(tc.validatorHeader, version)
+				}
+				_, _ = w.Write([]byte(content))
+			}))
+			defer s.Close()
+
+			client := &http.Client{Transport: NewConditionalCacheTransport(nil)}
+			get := func() string {
+				resp, err := client.Get(s.URL)
+				if err != nil {
+					t.Fatalf("fail to get: %v", err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("want status 200, got %v", resp.StatusCode)
+				}
+				body, err := ioutil.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatalf("fail to read body: %v", err)
+				}
+				return string(body)
+			}
+
+			for i := 0; i < 3; i++ {
+				if got := get(); got != "v1" {
+					t.Errorf("want body v1, got %q", got)
+				}
+			}
+
+			content, version = "v2", "Tue, 03 Jan 2023 15:04:05 GMT"
+			if got := get(); got != "v2" {
+				t.Errorf("want body v2 after the change, got %q", got)
+			}
+			if notModified != tc.wantNotModified {
+				t.Errorf("want %v not modified responses, got %v", tc.wantNotModified, notModified)
+			}
+		})
+	}
+}
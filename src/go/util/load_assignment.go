@@ -15,10 +15,12 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	preservecasepb "github.com/envoyproxy/go-control-plane/envoy/extensions/http/header_formatters/preserve_case/v3"
 	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -27,19 +29,80 @@ import (
 const (
 	Http2KeepaliveInterval = 30 * time.Second
 	Http2KeepaliveTimeout  = 10 * time.Second
+
+	// ProperCaseHeaderKeyFormat capitalizes the first letter and the letters
+	// following the non-alphanumeric characters of the HTTP/1 header names.
+	ProperCaseHeaderKeyFormat = "proper_case"
+	// PreserveCaseHeaderKeyFormat preserves the casing of the HTTP/1 header
+	// names as received.
+	PreserveCaseHeaderKeyFormat = "preserve_case"
 )
 
 // CreateUpstreamProtocolOptions creates a http2 protocol option as a typed upstream extension.
 func CreateUpstreamProtocolOptions() map[string]*anypb.Any {
+	return CreateUpstreamHttp2ProtocolOptions(&corepb.KeepaliveSettings{
+		Interval: durationpb.New(Http2KeepaliveInterval),
+		Timeout:  durationpb.New(Http2KeepaliveTimeout),
+	})
+}
+
+// CreateUpstreamHttp2ProtocolOptions creates a http2 protocol option with the
+// connection keepalive as a typed upstream extension.
+func CreateUpstreamHttp2ProtocolOptions(keepalive *corepb.KeepaliveSettings) map[string]*anypb.Any {
 	o := &httppb.HttpProtocolOptions{
 		UpstreamProtocolOptions: &httppb.HttpProtocolOptions_ExplicitHttpConfig_{
 			ExplicitHttpConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig{
 				ProtocolConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig_Http2ProtocolOptions{
 					Http2ProtocolOptions: &corepb.Http2ProtocolOptions{
-						ConnectionKeepalive: &corepb.KeepaliveSettings{
-							Interval: durationpb.New(Http2KeepaliveInterval),
-							Timeout:  durationpb.New(Http2KeepaliveTimeout),
-						},
+						ConnectionKeepalive: keepalive,
+					},
+				},
+			},
+		},
+	}
+	a, _ := anypb.New(o)
+
+	return map[string]*anypb.Any{
+		UpstreamProtocolOptions: a,
+	}
+}
+
+// CreateHeaderKeyFormat creates the HTTP/1 header key format, nil if the
+// format is empty, when the header names are lower cased.
+func CreateHeaderKeyFormat(format string) (*corepb.Http1ProtocolOptions_HeaderKeyFormat, error) {
+	switch format {
+	case "":
+		return nil, nil
+	case ProperCaseHeaderKeyFormat:
+		return &corepb.Http1ProtocolOptions_HeaderKeyFormat{
+			HeaderFormat: &corepb.Http1ProtocolOptions_HeaderKeyFormat_ProperCaseWords_{
+				ProperCaseWords: &corepb.Http1ProtocolOptions_HeaderKeyFormat_ProperCaseWords{},
+			},
+		}, nil
+	case PreserveCaseHeaderKeyFormat:
+		a, _ := anypb.New(&preservecasepb.PreserveCaseFormatterConfig{})
+		return &corepb.Http1ProtocolOptions_HeaderKeyFormat{
+			HeaderFormat: &corepb.Http1ProtocolOptions_HeaderKeyFormat_StatefulFormatter{
+				StatefulFormatter: &corepb.TypedExtensionConfig{
+					Name:        PreserveCaseHeaderFormatter,
+					TypedConfig: a,
+				},
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid header key format %q, must be %q or %q", format, ProperCaseHeaderKeyFormat, PreserveCaseHeaderKeyFormat)
+	}
+}
+
+// CreateUpstreamHttp1ProtocolOptions creates a http1 protocol option with the
+// header key format as a typed upstream extension.
+func CreateUpstreamHttp1ProtocolOptions(headerKeyFormat *corepb.Http1ProtocolOptions_HeaderKeyFormat) map[string]*anypb.Any {
+	o := &httppb.HttpProtocolOptions{
+		UpstreamProtocolOptions: &httppb.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &httppb.HttpProtocolOptions_ExplicitHttpConfig_HttpProtocolOptions{
+					HttpProtocolOptions: &corepb.Http1ProtocolOptions{
+						HeaderKeyFormat: headerKeyFormat,
 					},
 				},
 			},
@@ -52,6 +115,50 @@ func CreateUpstreamProtocolOptions() map[string]*anypb.Any {
 	}
 }
 
+// CreateUpstreamDownstreamProtocolOptions creates a protocol option as a typed
+// upstream extension, which uses the protocol of the downstream connection.
+func CreateUpstreamDownstreamProtocolOptions(headerKeyFormat *corepb.Http1ProtocolOptions_HeaderKeyFormat, keepalive *corepb.KeepaliveSettings) map[string]*anypb.Any {
+	o := &httppb.HttpProtocolOptions{
+		UpstreamProtocolOptions: &httppb.HttpProtocolOptions_UseDownstreamProtocolConfig{
+			UseDownstreamProtocolConfig: &httppb.HttpProtocolOptions_UseDownstreamHttpConfig{
+				HttpProtocolOptions: &corepb.Http1ProtocolOptions{
+					HeaderKeyFormat: headerKeyFormat,
+				},
+				Http2ProtocolOptions: &corepb.Http2ProtocolOptions{
+					ConnectionKeepalive: keepalive,
+				},
+			},
+		},
+	}
+	a, _ := anypb.New(o)
+
+	return map[string]*anypb.Any{
+		UpstreamProtocolOptions: a,
+	}
+}
+
+// CreateUpstreamAutoProtocolOptions creates a protocol option as a typed
+// upstream extension, which chooses the protocol by the ALPN negotiation.
+func CreateUpstreamAutoProtocolOptions(headerKeyFormat *corepb.Http1ProtocolOptions_HeaderKeyFormat, keepalive *corepb.KeepaliveSettings) map[string]*anypb.Any {
+	o := &httppb.HttpProtocolOptions{
+		UpstreamProtocolOptions: &httppb.HttpProtocolOptions_AutoConfig{
+			AutoConfig: &httppb.HttpProtocolOptions_AutoHttpConfig{
+				HttpProtocolOptions: &corepb.Http1ProtocolOptions{
+					HeaderKeyFormat: headerKeyFormat,
+				},
+				Http2ProtocolOptions: &corepb.Http2ProtocolOptions{
+					ConnectionKeepalive: keepalive,
+				},
+			},
+		},
+	}
+	a, _ := anypb.New(o)
+
+	return map[string]*anypb.Any{
+		UpstreamProtocolOptions: a,
+	}
+}
+
 // CreateLoadAssignment creates a cluster for a TCP/IP port.
 func CreateLoadAssignment(hostname string, port uint32) *endpointpb.ClusterLoadAssignment {
 	return &endpointpb.ClusterLoadAssignment{
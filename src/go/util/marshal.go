@@ -25,6 +25,7 @@ import (
 
 	// Import all protos that should be linked into the binary here.
 	_ "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/backend_auth"
+	_ "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/client_ip_rate_limit"
 	_ "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/grpc_metadata_scrubber"
 	_ "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/header_sanitizer"
 	_ "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/path_rewrite"
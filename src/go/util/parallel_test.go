@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParallelFor(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		n         int
+		failAt    map[int]bool
+		wantError string
+	}{
+		{
+			desc: "No indexes",
+			n:    0,
+		},
+		{
+			desc: "All indexes are called",
+			n:    1000,
+		},
+		{
+			desc:      "Error of the lowest index is returned",
+			n:         1000,
+			failAt:    map[int]bool{998: true, 17: true, 500: true},
+			wantError: "fail at 17",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := make([]int, tc.n)
+			err := ParallelFor(tc.n, func(i int) error {
+				if tc.failAt[i] {
+					return fmt.Errorf("fail at %d", i)
+				}
+				got[i] = i * i
+				return nil
+			})
+
+			if tc.wantError != "" {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("ParallelFor() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParallelFor() got error: %v", err)
+			}
+			for i, v := range got {
+				if v != i*i {
+					t.Fatalf("ParallelFor() index %d got %d, want %d", i, v, i*i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SelectorMap maps operation selectors to values. It is parsed from flags in
+// the format of "selector1=value1;selector2=value2".
+//
+// A selector may contain "*" wildcards, such as "endpoints.examples.bookstore.*".
+// The first selector matching an operation wins.
+type SelectorMap struct {
+	entries []selectorMapEntry
+}
+
+type selectorMapEntry struct {
+	selector string
+	value    string
+}
+
+// ParseSelectorMap parses a SelectorMap. An empty string gives an empty map.
+func ParseSelectorMap(s string) (*SelectorMap, error) {
+	m := &SelectorMap{}
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid selector mapping %q, must be in the format of selector=value", pair)
+		}
+		selector, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if selector == "" {
+			return nil, fmt.Errorf("invalid selector mapping %q, selector cannot be empty", pair)
+		}
+		if _, err := path.Match(selector, ""); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+
+		m.entries = append(m.entries, selectorMapEntry{
+			selector: selector,
+			value:    value,
+		})
+	}
+	return m, nil
+}
+
+// Lookup returns the value of the first selector matching the operation.
+func (m *SelectorMap) Lookup(operation string) (string, bool) {
+	for _, e := range m.entries {
+		if matched, _ := path.Match(e.selector, operation); matched {
+			return e.value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns all the values in the order they are specified.
+func (m *SelectorMap) Values() []string {
+	var values []string
+	for _, e := range m.entries {
+		values = append(values, e.value)
+	}
+	return values
+}
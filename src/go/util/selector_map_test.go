@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectorMap(t *testing.T) {
+	m, err := ParseSelectorMap("bookstore.Bookstore.GetShelf=a; bookstore.Bookstore.*=b;;*=c")
+	if err != nil {
+		t.Fatalf("ParseSelectorMap() got error: %v", err)
+	}
+
+	testCases := []struct {
+		operation string
+		wantValue string
+	}{
+		{
+			operation: "bookstore.Bookstore.GetShelf",
+			wantValue: "a",
+		},
+		{
+			operation: "bookstore.Bookstore.ListShelves",
+			wantValue: "b",
+		},
+		{
+			operation: "library.Library.GetBook",
+			wantValue: "c",
+		},
+	}
+	for _, tc := range testCases {
+		if got, ok := m.Lookup(tc.operation); !ok || got != tc.wantValue {
+			t.Errorf("Lookup(%q) got (%q, %v), want (%q, true)", tc.operation, got, ok, tc.wantValue)
+		}
+	}
+
+	if got, want := m.Values(), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() got %v, want %v", got, want)
+	}
+
+	empty, err := ParseSelectorMap("")
+	if err != nil {
+		t.Fatalf("ParseSelectorMap() got error: %v", err)
+	}
+	if _, ok := empty.Lookup("bookstore.Bookstore.GetShelf"); ok {
+		t.Errorf("Lookup() on empty map got a match")
+	}
+}
+
+func TestParseSelectorMap_BadInput(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		in        string
+		wantError string
+	}{
+		{
+			desc:      "missing value",
+			in:        "bookstore.Bookstore.GetShelf",
+			wantError: "must be in the format of selector=value",
+		},
+		{
+			desc:      "empty selector",
+			in:        "=a",
+			wantError: "selector cannot be empty",
+		},
+		{
+			desc:      "malformed pattern",
+			in:        "bookstore.[=a",
+			wantError: `invalid selector "bookstore.["`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ParseSelectorMap(tc.in)
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("ParseSelectorMap(%q) got error %v, want error containing %q", tc.in, err, tc.wantError)
+			}
+		})
+	}
+}
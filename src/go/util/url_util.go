@@ -15,14 +15,20 @@
 package util
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
 )
 
 const (
@@ -143,31 +149,120 @@ func IsBackendGRPC(address string) (bool, error) {
 	return protocol == GRPC, nil
 }
 
-// Note: the path of openID discovery may be https
-var getRemoteContent = func(path string) ([]byte, error) {
-	req, _ := http.NewRequest("GET", path, nil)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// RemoteContentOptions configures the fetches of remote content, such as the
+// OpenID Connect discovery documents.
+type RemoteContentOptions struct {
+	// Timeout of each attempt, 0 means no timeout.
+	Timeout time.Duration
+	// MaxRedirects is the maximum number of redirects followed.
+	MaxRedirects int
+	// NumRetries is the number of retries after the first failed attempt.
+	// Only the network errors, 429 and 5xx responses are retried.
+	NumRetries               int
+	RetryBackOffBaseInterval time.Duration
+	RetryBackOffMaxInterval  time.Duration
+	// DisableCache disables the conditional caching of the fetched content.
+	DisableCache bool
+}
+
+// remoteContentCache is shared by all the fetches of remote content, so the
+// unchanged content is revalidated instead of fetched again.
+var remoteContentCache = NewConditionalCacheTransport(nil)
+
+// FetchRemoteContent fetches the content of the URL, which may be http or
+// https. The gzip and deflate content encodings are decoded.
+func FetchRemoteContent(path string, opts RemoteContentOptions) ([]byte, error) {
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+			}
+			return nil
+		},
+	}
+	if !opts.DisableCache {
+		client.Transport = remoteContentCache
+	}
+
+	// The number of retries is limited instead of the elapsed time.
+	var b backoff.BackOff = &backoff.StopBackOff{}
+	if opts.NumRetries > 0 {
+		ebo := backoff.NewExponentialBackOff()
+		ebo.InitialInterval = opts.RetryBackOffBaseInterval
+		ebo.MaxInterval = opts.RetryBackOffMaxInterval
+		ebo.MaxElapsedTime = 0
+		b = backoff.WithMaxRetries(ebo, uint64(opts.NumRetries))
+	}
+
+	var body []byte
+	op := func() error {
+		var err error
+		body, err = fetchRemoteContentOnce(client, path)
+		return err
+	}
+	if err := backoff.Retry(op, b); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
 
+// fetchRemoteContentOnce returns a backoff.PermanentError for the errors that
+// should not be retried.
+func fetchRemoteContentOnce(client *http.Client, path string) ([]byte, error) {
+	req, err := http.NewRequest(GET, path, nil)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	// Setting the header disables the transparent decompression of the
+	// transport, so the encodings are decoded below.
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Fetching JwkUri returns not 200 OK: %v", resp.Status)
+		err := fmt.Errorf("fetching %s returns not 200 OK: %v", path, resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, err
+		}
+		return nil, backoff.Permanent(err)
+	}
+
+	var reader io.Reader = resp.Body
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, backoff.Permanent(fmt.Errorf("fail to decode gzip content: %v", err))
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case "deflate":
+		zlibReader, err := zlib.NewReader(resp.Body)
+		if err != nil {
+			return nil, backoff.Permanent(fmt.Errorf("fail to decode deflate content: %v", err))
+		}
+		defer zlibReader.Close()
+		reader = zlibReader
+	default:
+		return nil, backoff.Permanent(fmt.Errorf("unsupported content encoding %q", encoding))
 	}
-	return ioutil.ReadAll(resp.Body)
+	return ioutil.ReadAll(reader)
 }
 
-func ResolveJwksUriUsingOpenID(uri string) (string, error) {
+func ResolveJwksUriUsingOpenID(uri string, opts RemoteContentOptions) (string, error) {
 	if !strings.HasPrefix(uri, "http") {
 		uri = fmt.Sprintf("https://%s", uri)
 	}
 	uri = strings.TrimSuffix(uri, "/")
 	uri = fmt.Sprintf("%s%s", uri, OpenIDDiscoveryCfgURLSuffix)
 
-	body, err := getRemoteContent(uri)
+	body, err := FetchRemoteContent(uri, opts)
 	if err != nil {
 		return "", fmt.Errorf("Failed to fetch jwks_uri from %s: %v", uri, err)
 	}
@@ -215,4 +310,14 @@ var (
 		return fmt.Sprintf("%s/v1/services/%s/configs/%s?view=FULL",
 			serviceManagementUrl, serviceName, configId)
 	}
+
+	FetchConfigMapURL = func(apiServerUrl, namespace, name string) string {
+		return fmt.Sprintf("%s/api/v1/namespaces/%s/configmaps/%s",
+			apiServerUrl, namespace, name)
+	}
+
+	// FetchGCSObjectURL takes the object in the format of "bucket/object".
+	FetchGCSObjectURL = func(object string) string {
+		return fmt.Sprintf("https://storage.googleapis.com/%s", object)
+	}
 )
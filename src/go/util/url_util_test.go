@@ -15,12 +15,16 @@
 package util
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/gorilla/mux"
@@ -510,7 +514,7 @@ func TestResolveJwksUriUsingOpenID(t *testing.T) {
 		},
 	}
 	for i, tc := range testData {
-		uri, err := ResolveJwksUriUsingOpenID(tc.issuer)
+		uri, err := ResolveJwksUriUsingOpenID(tc.issuer, RemoteContentOptions{})
 		if uri != tc.wantUri {
 			t.Errorf("Test Desc(%d): %s, resolve jwksUri by openID got: %v, want: %v", i, tc.desc, uri, tc.wantUri)
 		}
@@ -521,6 +525,114 @@ func TestResolveJwksUriUsingOpenID(t *testing.T) {
 
 }
 
+func TestFetchRemoteContent(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(`{"keys":[]}`))
+	_ = gzipWriter.Close()
+
+	var flakyCalls int32
+	r := mux.NewRouter()
+	r.Path("/plain").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[]}`))
+	}))
+	r.Path("/gzip").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(gzipped.Bytes())
+	}))
+	r.Path("/brotli").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte("not-brotli"))
+	}))
+	r.Path("/redirect").Handler(http.RedirectHandler("/redirect", http.StatusFound))
+	r.Path("/flaky").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flakyCalls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"keys":[]}`))
+	}))
+	r.Path("/not-found").Handler(http.NotFoundHandler())
+	r.Path("/stuck").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	testData := []struct {
+		desc     string
+		path     string
+		opts     RemoteContentOptions
+		wantBody string
+		wantErr  string
+	}{
+		{
+			desc:     "Success, plain content",
+			path:     "/plain",
+			wantBody: `{"keys":[]}`,
+		},
+		{
+			desc:     "Success, gzip content is decoded",
+			path:     "/gzip",
+			wantBody: `{"keys":[]}`,
+		},
+		{
+			desc:    "Fail, unsupported content encoding",
+			path:    "/brotli",
+			wantErr: `unsupported content encoding "br"`,
+		},
+		{
+			desc:    "Fail, too many redirects",
+			path:    "/redirect",
+			opts:    RemoteContentOptions{MaxRedirects: 3},
+			wantErr: "stopped after 3 redirects",
+		},
+		{
+			desc: "Success, 503 is retried",
+			path: "/flaky",
+			opts: RemoteContentOptions{
+				NumRetries:               2,
+				RetryBackOffBaseInterval: time.Millisecond,
+				RetryBackOffMaxInterval:  time.Millisecond,
+			},
+			wantBody: `{"keys":[]}`,
+		},
+		{
+			desc: "Fail, 404 is not retried",
+			path: "/not-found",
+			opts: RemoteContentOptions{
+				NumRetries:               2,
+				RetryBackOffBaseInterval: time.Millisecond,
+				RetryBackOffMaxInterval:  time.Millisecond,
+			},
+			wantErr: "returns not 200 OK: 404 Not Found",
+		},
+		{
+			desc:    "Fail, attempt times out",
+			path:    "/stuck",
+			opts:    RemoteContentOptions{Timeout: 100 * time.Millisecond},
+			wantErr: "Client.Timeout exceeded",
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			body, err := FetchRemoteContent(s.URL+tc.path, tc.opts)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("FetchRemoteContent() got error %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchRemoteContent() got error: %v", err)
+			}
+			if string(body) != tc.wantBody {
+				t.Errorf("FetchRemoteContent() got body %q, want %q", body, tc.wantBody)
+			}
+		})
+	}
+}
+
 func TestExtraAddressFromURI(t *testing.T) {
 	testData := []struct {
 		desc          string
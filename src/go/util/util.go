@@ -52,6 +52,15 @@ const (
 	RolloutStrategyPath   = "/computeMetadata/v1/instance/attributes/endpoints-rollout-strategy"
 	ServiceNamePath       = "/computeMetadata/v1/instance/attributes/endpoints-service-name"
 
+	// The prefix of the paths of the custom instance metadata attributes.
+	InstanceAttributesPath = "/computeMetadata/v1/instance/attributes/"
+
+	// The paths of the instance and GKE cluster, used by the deployment labels.
+	InstanceNamePath    = "/computeMetadata/v1/instance/name"
+	InstanceIDPath      = "/computeMetadata/v1/instance/id"
+	ClusterNamePath     = "/computeMetadata/v1/instance/attributes/cluster-name"
+	ClusterLocationPath = "/computeMetadata/v1/instance/attributes/cluster-location"
+
 	AccessTokenPath   = "/computeMetadata/v1/instance/service-accounts/default/token"
 	IdentityTokenPath = "/computeMetadata/v1/instance/service-accounts/default/identity"
 	ProjectIDPath     = "/computeMetadata/v1/project/project-id"
@@ -65,6 +74,21 @@ const (
 	// The path of getting access token from token agent server
 	TokenAgentAccessTokenPath = "/local/access_token"
 
+	// The paths of getting the access token to call IAM and the identity token
+	// of the backend auth from token agent server.
+	TokenAgentBackendAuthAccessTokenPath   = "/local/backend_auth/access_token"
+	TokenAgentBackendAuthIdentityTokenPath = "/local/backend_auth/identity_token"
+
+	// The path of the readiness endpoint served by config manager.
+	ReadinessPath = "/ready"
+
+	// Default address of the Kubernetes API server within a cluster.
+	KubernetesAPIServerURL = "https://kubernetes.default.svc"
+
+	// Service account credentials mounted into every Kubernetes pod.
+	KubernetesServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	KubernetesServiceAccountCAPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
 	// b/147591854: This string must NOT have a trailing slash
 	OpenIDDiscoveryCfgURLSuffix = "/.well-known/openid-configuration"
 
@@ -110,8 +134,12 @@ const (
 	// Standard type url prefix.
 	TypeUrlPrefix = "type.googleapis.com/"
 
+	// The type url of google.api.HttpBody, used for arbitrary HTTP responses.
+	HttpBodyTypeUrl = TypeUrlPrefix + "google.api.HttpBody"
+
 	// Loopback Address
 	LoopbackIPv4Addr = "127.0.0.1"
+	LoopbackIPv6Addr = "::1"
 
 	// All operations auto-generated by ESPv2 be in the format:
 	// `{prefix}_{component}`, with an optional `_{formatted_path}` suffix.
@@ -129,6 +157,15 @@ const (
 	// The stat prefix.
 	StatPrefix = "ingress_http"
 
+	// HttpRedirectStatPrefix is the stat prefix of the HTTP to HTTPS redirect listener.
+	HttpRedirectStatPrefix = "http_redirect"
+
+	// HealthCheckStatPrefix is the stat prefix of the health check listener.
+	HealthCheckStatPrefix = "health_check"
+
+	// TcpProxyStatPrefix is the stat prefix of the TCP proxy listeners.
+	TcpProxyStatPrefix = "tcp_proxy"
+
 	// The suffix that forms the operation name header.
 	OperationHeaderSuffix = "Api-Operation-Name"
 
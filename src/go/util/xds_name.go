@@ -25,9 +25,15 @@ const (
 	AccessFileLogger = "envoy.access_loggers.file"
 	// UpstreamProtocolOptions is the xDS extension name for HTTP options.
 	UpstreamProtocolOptions = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
+	// PreserveCaseHeaderFormatter is the stateful header formatter preserving
+	// the header casing of HTTP/1.
+	PreserveCaseHeaderFormatter = "envoy.http.stateful_header_formatters.preserve_case"
 
-	IngressListenerName  = "ingress_listener"
-	LoopbackListenerName = "loopback_listener"
+	IngressListenerName      = "ingress_listener"
+	LoopbackListenerName     = "loopback_listener"
+	HttpRedirectListenerName = "http_redirect_listener"
+	HealthCheckListenerName  = "health_check_listener"
+	TcpProxyListenerName     = "tcp_proxy_listener"
 )
 
 // Jwt provider cluster's name will be in form of "jwt-provider-cluster-${JWT_PROVIDER_ADDRESS}".
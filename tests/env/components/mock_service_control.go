@@ -15,23 +15,44 @@
 package components
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/tests/utils"
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/mux"
 
 	scpb "google.golang.org/genproto/googleapis/api/servicecontrol/v1"
 )
 
-const defaultTimeout = 2500 * time.Millisecond
+const (
+	defaultTimeout = 2500 * time.Millisecond
+
+	// CheckResponsesControlPath is the path of the control API to script the
+	// sequence of the next Check responses, such as:
+	//
+	//	POST /control/check_responses
+	//	{
+	//	  "responses": [
+	//	    {"checkResponse": {"checkInfo": {"consumerInfo": {"projectNumber": "123456"}}}},
+	//	    {"checkResponse": {"checkErrors": [{"code": "RESOURCE_EXHAUSTED"}]}},
+	//	    {"statusCode": 503}
+	//	  ]
+	//	}
+	//
+	// An empty sequence clears the script.
+	CheckResponsesControlPath = "/control/check_responses"
+)
 
 type serviceResponse struct {
 	reqType        utils.ServiceRequestType
@@ -56,6 +77,10 @@ type MockServiceCtrl struct {
 type serviceHandler struct {
 	m    *MockServiceCtrl
 	resp *serviceResponse
+
+	// script is the sequence of the next responses, used before resp.
+	mu     sync.Mutex
+	script []*serviceResponse
 }
 
 func (h *serviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -68,15 +93,56 @@ func (h *serviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	req.ReqBody, _ = ioutil.ReadAll(r.Body)
 	h.m.ch <- req
 
-	if h.resp.respStatusCode != 0 {
-		w.WriteHeader(h.resp.respStatusCode)
+	resp := h.nextResponse()
+	if resp.respStatusCode != 0 {
+		w.WriteHeader(resp.respStatusCode)
 		return
 	}
-	_, _ = w.Write(h.resp.respBody)
+	_, _ = w.Write(resp.respBody)
 }
 
-func setOKCheckResponse() []byte {
-	req := &scpb.CheckResponse{
+// nextResponse pops the next scripted response, or returns the default one
+// once the script is used up.
+func (h *serviceHandler) nextResponse() *serviceResponse {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.script) == 0 {
+		return h.resp
+	}
+	resp := h.script[0]
+	h.script = h.script[1:]
+	return resp
+}
+
+func (h *serviceHandler) setScript(script []*serviceResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.script = script
+}
+
+// ScriptedCheckResponse is one response of a scripted sequence of Check
+// responses.
+type ScriptedCheckResponse struct {
+	// StatusCode is the HTTP status of the response, such as 503 to test the
+	// network fail open. The CheckResponse is only sent if 0.
+	StatusCode int
+
+	// CheckResponse is sent with status 200, such as the ones made by
+	// OKCheckResponse and CheckErrorResponse.
+	CheckResponse *scpb.CheckResponse
+}
+
+// checkResponsesControlRequest is the JSON request of the control API.
+type checkResponsesControlRequest struct {
+	Responses []struct {
+		StatusCode    int             `json:"statusCode"`
+		CheckResponse json.RawMessage `json:"checkResponse"`
+	} `json:"responses"`
+}
+
+// OKCheckResponse is the default Check response, of a valid consumer.
+func OKCheckResponse() *scpb.CheckResponse {
+	return &scpb.CheckResponse{
 		CheckInfo: &scpb.CheckResponse_CheckInfo{
 			ConsumerInfo: &scpb.CheckResponse_ConsumerInfo{
 				ProjectNumber:  123456,
@@ -85,7 +151,69 @@ func setOKCheckResponse() []byte {
 			},
 		},
 	}
-	req_b, _ := proto.Marshal(req)
+}
+
+// CheckErrorResponse is a Check response with the error, such as
+// RESOURCE_EXHAUSTED for the quota exhausted.
+func CheckErrorResponse(code scpb.CheckError_Code) *scpb.CheckResponse {
+	return &scpb.CheckResponse{
+		CheckErrors: []*scpb.CheckError{
+			{
+				Code: code,
+			},
+		},
+	}
+}
+
+func parseScriptedCheckResponses(body []byte) ([]ScriptedCheckResponse, error) {
+	var req checkResponsesControlRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid scripted check responses: %v", err)
+	}
+
+	var script []ScriptedCheckResponse
+	for i, resp := range req.Responses {
+		scripted := ScriptedCheckResponse{
+			StatusCode: resp.StatusCode,
+		}
+		if resp.CheckResponse != nil {
+			scripted.CheckResponse = &scpb.CheckResponse{}
+			if err := jsonpb.Unmarshal(bytes.NewReader(resp.CheckResponse), scripted.CheckResponse); err != nil {
+				return nil, fmt.Errorf("invalid CheckResponse of scripted check response #%d: %v", i, err)
+			}
+		}
+		if scripted.StatusCode == 0 && scripted.CheckResponse == nil {
+			return nil, fmt.Errorf("scripted check response #%d must set statusCode or checkResponse", i)
+		}
+		script = append(script, scripted)
+	}
+	return script, nil
+}
+
+type checkResponsesControlHandler struct {
+	m *MockServiceCtrl
+}
+
+func (h *checkResponsesControlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	script, err := parseScriptedCheckResponses(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.m.SetCheckResponseSequence(script); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setOKCheckResponse() []byte {
+	req_b, _ := proto.Marshal(OKCheckResponse())
 	return req_b
 }
 
@@ -147,6 +275,7 @@ func (m *MockServiceCtrl) Setup() {
 	r.Path(checkPath).Methods("POST").Handler(m.checkHandler)
 	r.Path(quotaPath).Methods("POST").Handler(m.quotaHandler)
 	r.Path(reportPath).Methods("POST").Handler(m.reportHandler)
+	r.Path(CheckResponsesControlPath).Methods("POST").Handler(&checkResponsesControlHandler{m: m})
 
 	glog.Infof("Start mock service control server for service: %s\n", m.serviceName)
 	m.s = httptest.NewUnstartedServer(r)
@@ -219,6 +348,31 @@ func (m *MockServiceCtrl) SetCheckResponse(checkResponse *scpb.CheckResponse) {
 	(m.checkHandler).(*serviceHandler).resp.respBody = req_b
 }
 
+// SetCheckResponseSequence scripts the next Check responses, one for each
+// Check request in order. Once they are used up, the response set by
+// SetCheckResponse and SetCheckResponseStatus is sent. It fails if the check
+// handler is overridden.
+func (m *MockServiceCtrl) SetCheckResponseSequence(responses []ScriptedCheckResponse) error {
+	h, ok := m.checkHandler.(*serviceHandler)
+	if !ok {
+		return fmt.Errorf("check handler is overridden, cannot script the check responses")
+	}
+
+	script := make([]*serviceResponse, 0, len(responses))
+	for _, resp := range responses {
+		scripted := &serviceResponse{
+			reqType:        utils.CheckRequest,
+			respStatusCode: resp.StatusCode,
+		}
+		if resp.CheckResponse != nil {
+			scripted.respBody, _ = proto.Marshal(resp.CheckResponse)
+		}
+		script = append(script, scripted)
+	}
+	h.setScript(script)
+	return nil
+}
+
 // SetCheckResponseStatus sets the response status code for the check of the service control.
 func (m *MockServiceCtrl) SetCheckResponseStatus(status int) {
 	(m.checkHandler).(*serviceHandler).resp.respStatusCode = status
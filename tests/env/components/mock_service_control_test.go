@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -202,3 +203,102 @@ func TestMockServiceControlReportStatus(t *testing.T) {
 		}
 	}
 }
+
+func TestMockServiceControlCheckResponseSequence(t *testing.T) {
+	testdata := []struct {
+		desc        string
+		script      []ScriptedCheckResponse
+		controlBody string
+		wantStatus  []int
+		wantErrors  []scpb.CheckError_Code
+		wantControl int
+	}{
+		{
+			desc: "Scripted responses are sent in order, then the default one",
+			script: []ScriptedCheckResponse{
+				{CheckResponse: OKCheckResponse()},
+				{CheckResponse: CheckErrorResponse(scpb.CheckError_RESOURCE_EXHAUSTED)},
+				{StatusCode: http.StatusServiceUnavailable},
+			},
+			wantStatus: []int{200, 200, 503, 200},
+			wantErrors: []scpb.CheckError_Code{scpb.CheckError_ERROR_CODE_UNSPECIFIED, scpb.CheckError_RESOURCE_EXHAUSTED, scpb.CheckError_ERROR_CODE_UNSPECIFIED, scpb.CheckError_ERROR_CODE_UNSPECIFIED},
+		},
+		{
+			desc: "Responses are scripted with the control API",
+			controlBody: `{"responses": [
+				{"checkResponse": {"checkErrors": [{"code": "RESOURCE_EXHAUSTED"}]}},
+				{"statusCode": 503}
+			]}`,
+			wantControl: http.StatusNoContent,
+			wantStatus:  []int{200, 503, 200},
+			wantErrors:  []scpb.CheckError_Code{scpb.CheckError_RESOURCE_EXHAUSTED, scpb.CheckError_ERROR_CODE_UNSPECIFIED, scpb.CheckError_ERROR_CODE_UNSPECIFIED},
+		},
+		{
+			desc:        "Invalid response of the control API",
+			controlBody: `{"responses": [{}]}`,
+			wantControl: http.StatusBadRequest,
+			wantStatus:  []int{200},
+			wantErrors:  []scpb.CheckError_Code{scpb.CheckError_ERROR_CODE_UNSPECIFIED},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := NewMockServiceCtrl("mmm", "test-rollout-id")
+			s.Setup()
+
+			if tc.script != nil {
+				if err := s.SetCheckResponseSequence(tc.script); err != nil {
+					t.Fatalf("SetCheckResponseSequence() got error: %v", err)
+				}
+			}
+			if tc.controlBody != "" {
+				resp, err := http.Post(s.GetURL()+CheckResponsesControlPath, "application/json", strings.NewReader(tc.controlBody))
+				if err != nil {
+					t.Fatalf("Failed in control request: %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tc.wantControl {
+					t.Fatalf("control API got status %v, want %v", resp.StatusCode, tc.wantControl)
+				}
+			}
+
+			req_body, _ := proto.Marshal(&scpb.CheckRequest{ServiceName: "mmm"})
+			for i, wantStatus := range tc.wantStatus {
+				resp, err := http.Post(s.GetURL()+"/v1/services/mmm:check", "application/x-protobuf", bytes.NewReader(req_body))
+				if err != nil {
+					t.Fatalf("Failed in request #%d: %v", i, err)
+				}
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != wantStatus {
+					t.Errorf("request #%d got status %v, want %v", i, resp.StatusCode, wantStatus)
+					continue
+				}
+				if wantStatus != http.StatusOK {
+					continue
+				}
+
+				checkResp := &scpb.CheckResponse{}
+				if err := proto.Unmarshal(body, checkResp); err != nil {
+					t.Fatalf("request #%d failed to parse body into CheckResponse: %v", i, err)
+				}
+				gotError := scpb.CheckError_ERROR_CODE_UNSPECIFIED
+				if len(checkResp.CheckErrors) > 0 {
+					gotError = checkResp.CheckErrors[0].Code
+				}
+				if gotError != tc.wantErrors[i] {
+					t.Errorf("request #%d got check error %v, want %v", i, gotError, tc.wantErrors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMockServiceControlCheckResponseSequenceOverriddenHandler(t *testing.T) {
+	s := NewMockServiceCtrl("mmm", "test-rollout-id")
+	s.OverrideCheckHandler(http.NotFoundHandler())
+	if err := s.SetCheckResponseSequence([]ScriptedCheckResponse{{StatusCode: 503}}); err == nil {
+		t.Errorf("SetCheckResponseSequence() got no error with an overridden check handler")
+	}
+}
@@ -93,9 +93,14 @@ type TestEnv struct {
 }
 
 func NewTestEnv(testId uint16, backend platform.Backend) *TestEnv {
-	glog.Infof("Running test function #%v", testId)
+	return NewTestEnvWithServiceConfig(testId, backend, testdata.SetupServiceConfig(backend))
+}
 
-	fakeServiceConfig := testdata.SetupServiceConfig(backend)
+// NewTestEnvWithServiceConfig creates a TestEnv serving the given service
+// config from the mock Service Management server, instead of the fake one of
+// the backend.
+func NewTestEnvWithServiceConfig(testId uint16, backend platform.Backend, fakeServiceConfig *confpb.Service) *TestEnv {
+	glog.Infof("Running test function #%v", testId)
 
 	return &TestEnv{
 		backend:                     backend,
@@ -558,7 +563,7 @@ func (e *TestEnv) StopBackendServer() error {
 }
 
 // TearDown shutdown the servers.
-func (e *TestEnv) TearDown(t *testing.T) {
+func (e *TestEnv) TearDown(t testing.TB) {
 	glog.Infof("start tearing down...")
 
 	// Run all health checks. If they fail, our test causes a server to crash.
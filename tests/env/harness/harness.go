@@ -0,0 +1,226 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package harness runs ESPv2 end to end, with the fake Service Management and
+// Service Control servers and one of the test backends, so the users can test
+// their own service configs and flags:
+//
+//	h, err := harness.New(harness.Config{
+//		TestId:        1000,
+//		Backend:       harness.EchoSidecar,
+//		ServiceConfig: serviceConfig,
+//		Flags:         []string{"--cors_preset=basic"},
+//	})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer h.Stop(t)
+//	if err := h.Start(); err != nil {
+//		t.Fatal(err)
+//	}
+//	resp, err := http.Get(h.ProxyURL() + "/echo")
+//
+// The harness starts the ESPv2 binaries, so they must be built first, such as
+// with `make build build-envoy`.
+package harness
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env"
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env/components"
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env/platform"
+	"github.com/golang/protobuf/jsonpb"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+
+	// Link in all protos, to resolve the types in the service configs.
+	_ "github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+// Backend is the test backend started behind ESPv2.
+type Backend = platform.Backend
+
+// The test backends. The sidecar backends are served with flag
+// --backend_address, and the remote ones with the backend rules of the
+// service config, whose port is written as "-1".
+const (
+	EchoSidecar          = platform.EchoSidecar
+	EchoRemote           = platform.EchoRemote
+	GrpcBookstoreSidecar = platform.GrpcBookstoreSidecar
+	GrpcBookstoreRemote  = platform.GrpcBookstoreRemote
+	GrpcEchoSidecar      = platform.GrpcEchoSidecar
+	GrpcEchoRemote       = platform.GrpcEchoRemote
+	GrpcInteropSidecar   = platform.GrpcInteropSidecar
+)
+
+// The range of the test ids of the harness. The lower ids are used by the
+// ESPv2 integration tests, and the higher ones run out of ports.
+const (
+	minTestId = 1000
+	maxTestId = 1500
+)
+
+// Config configures the harness.
+type Config struct {
+	// TestId allocates the ports of the harness. The tests run in parallel
+	// must use different ids, in the range of [1000, 1500).
+	TestId uint16
+
+	// Backend is the test backend started behind ESPv2.
+	Backend Backend
+
+	// ServiceConfig is served by the fake Service Management server. If nil,
+	// the fake service config of the backend is used.
+	ServiceConfig *confpb.Service
+
+	// Flags are passed to the config manager, such as "--cors_preset=basic".
+	// The flags of the harness, such as --service and --listener_port, are
+	// added after them.
+	Flags []string
+
+	// BinDir is the directory of the ESPv2 and test backend binaries. If
+	// empty, the "bin" directory of the repository is used, relative to the
+	// integration tests.
+	BinDir string
+
+	// TestDataDir is the directory of the certificates and the other files of
+	// tests/env/testdata. If empty, the directory of the repository is used,
+	// relative to the integration tests.
+	TestDataDir string
+}
+
+// Harness is ESPv2 running with the fake Google services and a test backend.
+type Harness struct {
+	env   *env.TestEnv
+	flags []string
+}
+
+// New creates a harness. The servers are only started by Start.
+func New(cfg Config) (*Harness, error) {
+	if cfg.TestId < minTestId || cfg.TestId >= maxTestId {
+		return nil, fmt.Errorf("invalid test id %d, must be in the range of [%d, %d)", cfg.TestId, minTestId, maxTestId)
+	}
+	setRuntimeDirs(cfg.BinDir, cfg.TestDataDir)
+
+	var e *env.TestEnv
+	if cfg.ServiceConfig != nil {
+		e = env.NewTestEnvWithServiceConfig(cfg.TestId, cfg.Backend, cfg.ServiceConfig)
+	} else {
+		e = env.NewTestEnv(cfg.TestId, cfg.Backend)
+	}
+	return &Harness{
+		env:   e,
+		flags: cfg.Flags,
+	}, nil
+}
+
+var (
+	binFiles = map[platform.RuntimeFile]string{
+		platform.Bootstrapper:      "bootstrap",
+		platform.ConfigManager:     "configmanager",
+		platform.Echo:              "echo/server",
+		platform.Envoy:             "envoy",
+		platform.GrpcEchoServer:    "grpc_echo_server",
+		platform.GrpcInteropServer: "interop_server",
+	}
+	testDataFiles = map[platform.RuntimeFile]string{
+		platform.ServerCert: "server.crt",
+		platform.ServerKey:  "server.key",
+		platform.ProxyCert:  "proxy.crt",
+		platform.ProxyKey:   "proxy.key",
+		platform.LogMetrics: "logs_metrics.pb.txt",
+	}
+)
+
+func setRuntimeDirs(binDir, testDataDir string) {
+	if binDir != "" {
+		for file, name := range binFiles {
+			platform.SetFilePath(file, filepath.Join(binDir, name))
+		}
+	}
+	if testDataDir != "" {
+		for file, name := range testDataFiles {
+			platform.SetFilePath(file, filepath.Join(testDataDir, name))
+		}
+		platform.SetFilePath(platform.TestDataFolder, testDataDir+string(filepath.Separator))
+	}
+}
+
+// Start starts the fake Google services, ESPv2 and the backend, and waits
+// until they are healthy.
+func (h *Harness) Start() error {
+	return h.env.Setup(h.flags)
+}
+
+// Stop stops all the servers, failing the test if any of them crashed.
+func (h *Harness) Stop(t testing.TB) {
+	h.env.TearDown(t)
+}
+
+// ProxyAddress is the host:port of the ESPv2 listener.
+func (h *Harness) ProxyAddress() string {
+	return net.JoinHostPort(platform.GetLoopbackAddress(), strconv.Itoa(int(h.env.Ports().ListenerPort)))
+}
+
+// ProxyURL is the http URL of the ESPv2 listener, without a trailing "/".
+func (h *Harness) ProxyURL() string {
+	return "http://" + h.ProxyAddress()
+}
+
+// AdminURL is the http URL of the Envoy admin interface, such as for the
+// "/stats" endpoint.
+func (h *Harness) AdminURL() string {
+	return fmt.Sprintf("http://%s", net.JoinHostPort(platform.GetLoopbackAddress(), strconv.Itoa(int(h.env.Ports().AdminPort))))
+}
+
+// ServiceControl is the fake Service Control server, to set the Check and
+// Quota responses and to verify the Reports.
+func (h *Harness) ServiceControl() *components.MockServiceCtrl {
+	return h.env.ServiceControlServer
+}
+
+// ServiceManagement is the fake Service Management server.
+func (h *Harness) ServiceManagement() *components.MockServiceMrg {
+	return h.env.MockServiceManagementServer
+}
+
+// Env is the underlying test environment, for the settings not covered by
+// the harness. It is not part of the stable API.
+func (h *Harness) Env() *env.TestEnv {
+	return h.env
+}
+
+// LoadServiceConfig reads a service config in JSON, such as the output of
+// `gcloud endpoints configs describe --format=json`.
+func LoadServiceConfig(path string) (*confpb.Service, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read service config %q: %v", path, err)
+	}
+
+	unmarshaler := &jsonpb.Unmarshaler{
+		AllowUnknownFields: true,
+	}
+	serviceConfig := &confpb.Service{}
+	if err := unmarshaler.Unmarshal(bytes.NewReader(b), serviceConfig); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal service config %q: %v", path, err)
+	}
+	return serviceConfig, nil
+}
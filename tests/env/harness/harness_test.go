@@ -0,0 +1,110 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env/platform"
+)
+
+func TestNew(t *testing.T) {
+	testData := []struct {
+		desc      string
+		cfg       Config
+		wantError string
+	}{
+		{
+			desc: "Test id used by the integration tests",
+			cfg: Config{
+				TestId:  platform.TestAccessLog,
+				Backend: EchoSidecar,
+			},
+			wantError: "invalid test id 0, must be in the range of [1000, 1500)",
+		},
+		{
+			desc: "Test id out of ports",
+			cfg: Config{
+				TestId:  1500,
+				Backend: EchoSidecar,
+			},
+			wantError: "invalid test id 1500, must be in the range of [1000, 1500)",
+		},
+		{
+			desc: "Binaries and test data from other directories",
+			cfg: Config{
+				TestId:      1000,
+				Backend:     EchoSidecar,
+				BinDir:      "/opt/espv2/bin",
+				TestDataDir: "/opt/espv2/testdata",
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			h, err := New(tc.cfg)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("New() got error %v, want error %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() got error %v", err)
+			}
+
+			if got, want := platform.GetFilePath(platform.Envoy), "/opt/espv2/bin/envoy"; got != want {
+				t.Errorf("Envoy path got %q, want %q", got, want)
+			}
+			if got, want := platform.GetFilePath(platform.ProxyCert), "/opt/espv2/testdata/proxy.crt"; got != want {
+				t.Errorf("proxy cert path got %q, want %q", got, want)
+			}
+			if got := h.ServiceManagement(); got == nil {
+				t.Errorf("ServiceManagement() got nil")
+			}
+		})
+	}
+}
+
+func TestLoadServiceConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service_config.json")
+	if err := ioutil.WriteFile(path, []byte(`{
+  "name": "bookstore.endpoints.project.cloud.goog",
+  "apis": [{"name": "endpoints.examples.bookstore.Bookstore"}],
+  "unknownField": true
+}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	serviceConfig, err := LoadServiceConfig(path)
+	if err != nil {
+		t.Fatalf("LoadServiceConfig() got error %v", err)
+	}
+	if got, want := serviceConfig.GetName(), "bookstore.endpoints.project.cloud.goog"; got != want {
+		t.Errorf("service name got %q, want %q", got, want)
+	}
+	if got, want := serviceConfig.GetApis()[0].GetName(), "endpoints.examples.bookstore.Bookstore"; got != want {
+		t.Errorf("API name got %q, want %q", got, want)
+	}
+
+	if _, err := LoadServiceConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("LoadServiceConfig() of missing file got no error")
+	}
+}
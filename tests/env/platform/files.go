@@ -130,3 +130,9 @@ var fileMap = map[RuntimeFile]string{
 func GetFilePath(file RuntimeFile) string {
 	return fileMap[file]
 }
+
+// SetFilePath overrides the runtime file path for the specified file, such as
+// the binaries when the tests are not run from this repository.
+func SetFilePath(file RuntimeFile, path string) {
+	fileMap[file] = path
+}
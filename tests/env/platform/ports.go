@@ -163,6 +163,7 @@ const (
 	TestTranscodingBindingsForCustomVerb
 	TestTranscodingUnescapePlus
 	TestTranscodingErrors
+	TestTranscodingErrorsCustomStatusCodes
 	TestTranscodingIgnoreQueryParameters
 	TestTranscodingPrintOptions
 	TestWebsocket
@@ -16,6 +16,7 @@ package transcoding_errors_test
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 
@@ -165,3 +166,61 @@ theme" : "Children"}EXTRA
 		})
 	}
 }
+
+func TestTranscodingErrorsCustomStatusCodes(t *testing.T) {
+	t.Parallel()
+
+	configID := "test-config-id"
+	args := []string{"--service_config_id=" + configID,
+		"--rollout_strategy=fixed",
+		"--grpc_error_status_codes=NOT_FOUND=410",
+		`--error_response_template={"error": {"code": "%RESPONSE_CODE%"}}`,
+	}
+
+	s := env.NewTestEnv(platform.TestTranscodingErrorsCustomStatusCodes, platform.GrpcBookstoreSidecar)
+	defer s.TearDown(t)
+	if err := s.Setup(args); err != nil {
+		t.Fatalf("fail to setup test env, %v", err)
+	}
+
+	tests := []TranscodingTestType{
+		{
+			desc:           "gRPC NOT_FOUND from the backend is mapped to 410",
+			clientProtocol: "http",
+			httpMethod:     "GET",
+			method:         "/v1/shelves/200/books/2002?key=api-key",
+			wantErr:        `410 Gone, {"error":{"code":410}}`,
+		},
+		{
+			desc:           "gRPC errors not mapped keep the transcoded status code",
+			clientProtocol: "http",
+			httpMethod:     "POST",
+			method:         "/v1/shelves/0/books?key=api-key",
+			bodyBytes:      []byte(`NO_BRACES_JSON`),
+			wantErr:        `400 Bad Request`,
+		},
+		{
+			desc:           "gRPC clients keep the gRPC status",
+			clientProtocol: "grpc",
+			method:         "GetShelfInvalid",
+			headers:        http.Header{"x-api-key": []string{"api-key"}},
+			wantErr:        "code = NotFound",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			addr := fmt.Sprintf("%v:%v", platform.GetLoopbackAddress(), s.Ports().ListenerPort)
+			var resp string
+			var err error
+			if tc.clientProtocol == "grpc" {
+				resp, err = client.MakeCall(tc.clientProtocol, addr, "GET", tc.method, tc.token, tc.headers)
+			} else {
+				resp, err = client.MakeHttpCallWithBody(addr, tc.httpMethod, tc.method, tc.token, tc.bodyBytes)
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Test (%s): failed, expected err: %v, got: %v, resp: %s", tc.desc, tc.wantErr, err, resp)
+			}
+		})
+	}
+}
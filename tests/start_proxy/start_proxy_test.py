@@ -41,6 +41,22 @@ class TestStartProxy(unittest.TestCase):
             ([], ['bin/bootstrap',
                   '--logtostderr', '--admin_port', '0',
                   '/tmp/bootstrap.json']),
+            (['--admin_loopback_only', '--admin_socket_path=/var/run/espv2/admin.sock'],
+             ['bin/bootstrap', '--logtostderr', '--admin_port', '0',
+              '--admin_loopback_only',
+              '--admin_socket_path', '/var/run/espv2/admin.sock',
+              '/tmp/bootstrap.json']),
+            (['--enable_operation_stats'],
+             ['bin/bootstrap', '--logtostderr', '--admin_port', '0',
+              '--enable_operation_stats',
+              '/tmp/bootstrap.json']),
+            (['--max_downstream_connections=1000', '--overload_max_heap_size_bytes=1073741824', '--overload_shrink_heap_threshold=0.9', '--overload_stop_accepting_requests_threshold=0.95'],
+             ['bin/bootstrap', '--logtostderr', '--admin_port', '0',
+              '--max_downstream_connections', '1000',
+              '--overload_max_heap_size_bytes', '1073741824',
+              '--overload_shrink_heap_threshold', '0.9',
+              '--overload_stop_accepting_requests_threshold', '0.95',
+              '/tmp/bootstrap.json']),
         ]
 
         for flags, wantedArgs in testcases:
@@ -1041,6 +1057,1014 @@ class TestStartProxy(unittest.TestCase):
               '--service_config_id', '2019-11-09r0',
               '--service_control_enable_api_key_uid_reporting',
               ]),
+            # readiness flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--readiness_port=8090',
+              '--readiness_check_backend',
+              '--readiness_check_timeout=2s'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--readiness_port', '8090',
+              '--readiness_check_backend',
+              '--readiness_check_timeout', '2s',
+              ]),
+            # k8s flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--k8s_config_map=espv2/bookstore-config',
+              '--k8s_config_map_key=service.json',
+              '--k8s_api_server_url=https://10.0.0.1:443'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--k8s_config_map', 'espv2/bookstore-config',
+              '--k8s_config_map_key', 'service.json',
+              '--k8s_api_server_url', 'https://10.0.0.1:443',
+              ]),
+            # HTTP to HTTPS redirect flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--http_redirect_port=8080',
+              '--https_redirect_port=8443',
+              '--http_redirect_response_code=308'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--http_redirect_port', '8080',
+              '--https_redirect_port', '8443',
+              '--http_redirect_response_code', '308',
+              ]),
+            # healthz flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--healthz_check_backend',
+              '--healthz_backend_consecutive_failures=5',
+              '--healthz_backend_evaluation_window=30s'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--healthz_check_backend',
+              '--healthz_backend_consecutive_failures', '5',
+              '--healthz_backend_evaluation_window', '30s',
+              ]),
+            # backend_health_check flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_health_check_path=/healthz',
+              '--backend_health_check_grpc',
+              '--backend_health_check_interval=10s',
+              '--backend_health_check_timeout=2s',
+              '--backend_health_check_healthy_threshold=2',
+              '--backend_health_check_unhealthy_threshold=3',
+              '--backend_health_check_expected_statuses=200,204'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_health_check_path', '/healthz',
+              '--backend_health_check_grpc',
+              '--backend_health_check_interval', '10s',
+              '--backend_health_check_timeout', '2s',
+              '--backend_health_check_healthy_threshold', '2',
+              '--backend_health_check_unhealthy_threshold', '3',
+              '--backend_health_check_expected_statuses', '200,204',
+              ]),
+            # enable_grpc_web specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_grpc_web'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_grpc_web',
+              ]),
+            # connection upgrade flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--upgrade_types=websocket',
+              '--operation_upgrade_types=bookstore.Chat=websocket',
+              '--operation_upgrade_idle_timeouts=bookstore.Chat=1h'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--upgrade_types', 'websocket',
+              '--operation_upgrade_types', 'bookstore.Chat=websocket',
+              '--operation_upgrade_idle_timeouts', 'bookstore.Chat=1h',
+              ]),
+            # grpc_error_status_codes and error_response_template specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--grpc_error_status_codes=UNAVAILABLE=503',
+              '--error_response_template={"error": "%LOCAL_REPLY_BODY%"}'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--grpc_error_status_codes', 'UNAVAILABLE=503',
+              '--error_response_template', '{"error": "%LOCAL_REPLY_BODY%"}',
+              ]),
+            # operation_streaming_downloads and streaming_download_buffer_limit_bytes specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--operation_streaming_downloads=bookstore.Download=true',
+              '--streaming_download_buffer_limit_bytes=65536'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--operation_streaming_downloads', 'bookstore.Download=true',
+              '--streaming_download_buffer_limit_bytes', '65536',
+              ]),
+            # transcoding_proto_descriptor_url and transcoding_proto_descriptor_refresh_interval specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--transcoding_proto_descriptor_url=gs://bucket/api_descriptor.pb',
+              '--transcoding_proto_descriptor_refresh_interval=5m'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--transcoding_proto_descriptor_url', 'gs://bucket/api_descriptor.pb',
+              '--transcoding_proto_descriptor_refresh_interval', '5m',
+              ]),
+            # response_compression flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--response_compression_types=br,gzip',
+              '--response_compression_content_types=application/json',
+              '--response_compression_min_length=1024',
+              '--response_compression_gzip_level=6',
+              '--response_compression_brotli_quality=4'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--response_compression_types', 'br,gzip',
+              '--response_compression_content_types', 'application/json',
+              '--response_compression_min_length', '1024',
+              '--response_compression_gzip_level', '6',
+              '--response_compression_brotli_quality', '4',
+              ]),
+            # max_request_bytes and operation_max_request_bytes specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--max_request_bytes=1048576',
+              '--operation_max_request_bytes=bookstore.Upload=10485760'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--max_request_bytes', '1048576',
+              '--operation_max_request_bytes', 'bookstore.Upload=10485760',
+              ]),
+            # client IP filtering flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--allowed_client_ips=10.0.0.0/8',
+              '--denied_client_ips=192.168.1.1',
+              '--operation_allowed_client_ips=bookstore.Admin=10.0.0.0/8',
+              '--operation_denied_client_ips=bookstore.Public=192.168.1.1'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--allowed_client_ips', '10.0.0.0/8',
+              '--denied_client_ips', '192.168.1.1',
+              '--operation_allowed_client_ips', 'bookstore.Admin=10.0.0.0/8',
+              '--operation_denied_client_ips', 'bookstore.Public=192.168.1.1',
+              ]),
+            # ext_proc flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--ext_proc_address=grpc://127.0.0.1:9000',
+              '--ext_proc_processing_mode=buffered',
+              '--ext_proc_timeout=200ms',
+              '--ext_proc_failure_mode_allow',
+              '--operation_ext_proc_processing_modes=bookstore.Health=disabled'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--ext_proc_address', 'grpc://127.0.0.1:9000',
+              '--ext_proc_processing_mode', 'buffered',
+              '--ext_proc_timeout', '200ms',
+              '--ext_proc_failure_mode_allow',
+              '--operation_ext_proc_processing_modes', 'bookstore.Health=disabled',
+              ]),
+            # WASM filter flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--wasm_filter_uri=/etc/espv2/filter.wasm',
+              '--wasm_filter_sha256=abc123',
+              '--wasm_filter_config={"mode": "strict"}',
+              '--wasm_filter_before=envoy.filters.http.jwt_authn',
+              '--operation_wasm_filter_enabled=bookstore.Health=false'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--wasm_filter_uri', '/etc/espv2/filter.wasm',
+              '--wasm_filter_sha256', 'abc123',
+              '--wasm_filter_config', '{"mode": "strict"}',
+              '--wasm_filter_before', 'envoy.filters.http.jwt_authn',
+              '--operation_wasm_filter_enabled', 'bookstore.Health=false',
+              ]),
+            # Lua filter flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--lua_filter_script=/etc/espv2/remap_headers.lua',
+              '--lua_scripts=audit=/etc/espv2/audit.lua',
+              '--operation_lua_scripts=bookstore.CreateShelf=audit'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--lua_filter_script', '/etc/espv2/remap_headers.lua',
+              '--lua_scripts', 'audit=/etc/espv2/audit.lua',
+              '--operation_lua_scripts', 'bookstore.CreateShelf=audit',
+              ]),
+            # admin flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--admin_read_only_paths=/stats,/ready',
+              '--admin_path_prefix=/espv2_admin',
+              '--admin_token_path=/etc/espv2/admin_token',
+              '--admin_token_header=x-espv2-admin-token',
+              '--admin_loopback_only',
+              '--admin_socket_path=/var/run/espv2/admin.sock'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--admin_read_only_paths', '/stats,/ready',
+              '--admin_path_prefix', '/espv2_admin',
+              '--admin_token_path', '/etc/espv2/admin_token',
+              '--admin_token_header', 'x-espv2-admin-token',
+              '--admin_loopback_only',
+              '--admin_socket_path', '/var/run/espv2/admin.sock',
+              ]),
+            # operation_cors flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--operation_cors_allow_origin=bookstore.ListShelves=https://example.com',
+              '--operation_cors_allow_credentials=bookstore.ListShelves=true',
+              '--operation_cors_expose_headers=bookstore.ListShelves=X-Total'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--operation_cors_allow_origin', 'bookstore.ListShelves=https://example.com',
+              '--operation_cors_allow_credentials', 'bookstore.ListShelves=true',
+              '--operation_cors_expose_headers', 'bookstore.ListShelves=X-Total',
+              ]),
+            # cors_allow_unmatched_preflight specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--cors_allow_unmatched_preflight'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--cors_allow_unmatched_preflight',
+              ]),
+            # oidc_discovery flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--oidc_discovery_timeout=5s',
+              '--oidc_discovery_num_retries=3',
+              '--oidc_discovery_retry_backoff_base_interval=200ms',
+              '--oidc_discovery_retry_backoff_max_interval=2s',
+              '--oidc_discovery_max_redirects=3'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--oidc_discovery_timeout', '5s',
+              '--oidc_discovery_num_retries', '3',
+              '--oidc_discovery_retry_backoff_base_interval', '200ms',
+              '--oidc_discovery_retry_backoff_max_interval', '2s',
+              '--oidc_discovery_max_redirects', '3',
+              ]),
+            # disable_http_fetch_cache specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--disable_http_fetch_cache'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--disable_http_fetch_cache',
+              ]),
+            # backend connection flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_cluster_connect_timeout=5s',
+              '--backend_tcp_keepalive_time=60s',
+              '--backend_tcp_keepalive_interval=10s',
+              '--backend_tcp_keepalive_probes=3',
+              '--backend_happy_eyeballs'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_cluster_connect_timeout', '5s',
+              '--backend_tcp_keepalive_time', '60s',
+              '--backend_tcp_keepalive_interval', '10s',
+              '--backend_tcp_keepalive_probes', '3',
+              '--backend_happy_eyeballs',
+              ]),
+            # request header limit flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--max_request_headers_kb=96',
+              '--max_request_headers_count=200',
+              '--headers_with_underscores_action=drop_header',
+              '--header_key_format=preserve_case'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--max_request_headers_kb', '96',
+              '--max_request_headers_count', '200',
+              '--headers_with_underscores_action', 'drop_header',
+              '--header_key_format', 'preserve_case',
+              ]),
+            # strict_route_allowlist and passthrough_path_prefixes specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--strict_route_allowlist',
+              '--passthrough_path_prefixes=/static/'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--strict_route_allowlist',
+              '--passthrough_path_prefixes', '/static/',
+              ]),
+            # default_backend_address specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--default_backend_address=https://legacy.example.com'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--default_backend_address', 'https://legacy.example.com',
+              ]),
+            # tenant_mapping_file and tenant_mapping_metadata_attribute specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--tenant_mapping_file=/etc/espv2/tenants.json',
+              '--tenant_mapping_metadata_attribute=espv2-tenants'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--tenant_mapping_file', '/etc/espv2/tenants.json',
+              '--tenant_mapping_metadata_attribute', 'espv2-tenants',
+              ]),
+            # enable_operation_stats specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_operation_stats'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_operation_stats',
+              ]),
+            # enable_grpc_reflection and grpc_health_port specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_grpc_reflection',
+              '--grpc_health_port=8091'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_grpc_reflection',
+              '--grpc_health_port', '8091',
+              ]),
+            # startup_timeout and require_backend_dns_resolution specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--startup_timeout=2m',
+              '--require_backend_dns_resolution'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--startup_timeout', '2m',
+              '--require_backend_dns_resolution',
+              ]),
+            # snapshot_admin_port and snapshot_replay_file specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--snapshot_admin_port=8092',
+              '--snapshot_replay_file=/tmp/snapshot.tar.gz'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--snapshot_admin_port', '8092',
+              '--snapshot_replay_file', '/tmp/snapshot.tar.gz',
+              ]),
+            # backend hedging and retry budget flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_hedge_get_requests',
+              '--backend_retry_budget_percent=20',
+              '--backend_retry_budget_min_concurrency=3'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_hedge_get_requests',
+              '--backend_retry_budget_percent', '20',
+              '--backend_retry_budget_min_concurrency', '3',
+              ]),
+            # operation_hedge_delays specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--operation_hedge_delays=bookstore.GetShelf=50ms'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--operation_hedge_delays', 'bookstore.GetShelf=50ms',
+              ]),
+            # maintenance flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--maintenance_mode',
+              '--maintenance_operations=bookstore.CreateShelf',
+              '--maintenance_response={"status": 503}'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--maintenance_mode',
+              '--maintenance_operations', 'bookstore.CreateShelf',
+              '--maintenance_response', '{"status": 503}',
+              ]),
+            # static_paths specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--static_paths={"/favicon.ico": {"status": 204}}'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--static_paths', '{"/favicon.ico": {"status": 204}}',
+              ]),
+            # redirect_rules specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--redirect_rules=[{"path": "/docs", "host": "docs.example.com"}]'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--redirect_rules', '[{"path": "/docs", "host": "docs.example.com"}]',
+              ]),
+            # consumer_backends specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--consumer_backends=[{"name": "bigcorp"}]'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--consumer_backends', '[{"name": "bigcorp"}]',
+              ]),
+            # version_backends specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--version_backends={"header": "Accept-Version"}'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--version_backends', '{"header": "Accept-Version"}',
+              ]),
+            # grpc_metadata specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--grpc_metadata=bookstore.GetShelf=x-env:prod'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--grpc_metadata', 'bookstore.GetShelf=x-env:prod',
+              ]),
+            # connection limit and overload manager flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--listener_connection_rate_limit=100',
+              '--max_downstream_connections=1000',
+              '--overload_max_heap_size_bytes=1073741824',
+              '--overload_shrink_heap_threshold=0.9',
+              '--overload_stop_accepting_requests_threshold=0.95'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--listener_connection_rate_limit', '100',
+              '--max_downstream_connections', '1000',
+              '--overload_max_heap_size_bytes', '1073741824',
+              '--overload_shrink_heap_threshold', '0.9',
+              '--overload_stop_accepting_requests_threshold', '0.95',
+              ]),
+            # adaptive_concurrency flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_adaptive_concurrency',
+              '--adaptive_concurrency_sample_percentile=90',
+              '--adaptive_concurrency_update_interval=100ms',
+              '--adaptive_concurrency_min_rtt_interval=60s',
+              '--adaptive_concurrency_min_rtt_request_count=50',
+              '--adaptive_concurrency_buffer_percent=25',
+              '--adaptive_concurrency_max_limit=1000'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_adaptive_concurrency',
+              '--adaptive_concurrency_sample_percentile', '90',
+              '--adaptive_concurrency_update_interval', '100ms',
+              '--adaptive_concurrency_min_rtt_interval', '60s',
+              '--adaptive_concurrency_min_rtt_request_count', '50',
+              '--adaptive_concurrency_buffer_percent', '25',
+              '--adaptive_concurrency_max_limit', '1000',
+              ]),
+            # backend_slow_start_window and backend_slow_start_aggression specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_slow_start_window=30s',
+              '--backend_slow_start_aggression=2.0'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_slow_start_window', '30s',
+              '--backend_slow_start_aggression', '2.0',
+              ]),
+            # backend_address_overrides specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_address_overrides=bookstore.GetShelf=https://staging.example.com'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_address_overrides', 'bookstore.GetShelf=https://staging.example.com',
+              ]),
+            # per-service account key flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--service_management_service_account_key=/etc/creds/sm.json',
+              '--service_control_service_account_key=/etc/creds/sc.json',
+              '--backend_auth_service_account_key=/etc/creds/ba.json'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--service_management_service_account_key', '/etc/creds/sm.json',
+              '--service_control_service_account_key', '/etc/creds/sc.json',
+              '--backend_auth_service_account_key', '/etc/creds/ba.json',
+              ]),
+            # metadata_stub_file and metadata_stub_port specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--metadata_stub_file=/etc/espv2/metadata.json',
+              '--metadata_stub_port=8093'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--metadata_stub_file', '/etc/espv2/metadata.json',
+              '--metadata_stub_port', '8093',
+              ]),
+            # enable_token_service specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_token_service'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_token_service',
+              ]),
+            # deployment_labels specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--deployment_labels=environment=prod'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--deployment_labels', 'environment=prod',
+              ]),
+            # operation_quota_metric_costs specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--operation_quota_metric_costs=bookstore.ListShelves=read-requests:2'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--operation_quota_metric_costs', 'bookstore.ListShelves=read-requests:2',
+              ]),
+            # backend_http2_keepalive flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_http2_keepalive_interval=30s',
+              '--backend_http2_keepalive_timeout=10s',
+              '--backend_http2_keepalive_interval_jitter=15'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_http2_keepalive_interval', '30s',
+              '--backend_http2_keepalive_timeout', '10s',
+              '--backend_http2_keepalive_interval_jitter', '15',
+              ]),
+            # backend_protocol_selection specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_protocol_selection=127.0.0.1:8082=auto'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_protocol_selection', '127.0.0.1:8082=auto',
+              ]),
+            # health_port specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--health_port=8094'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--health_port', '8094',
+              ]),
+            # tcp_proxy_routes specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--tcp_proxy_routes=1883=mqtt-broker:1883'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--tcp_proxy_routes', '1883=mqtt-broker:1883',
+              ]),
+            # enable_request_validation specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_request_validation'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_request_validation',
+              ]),
+            # security header flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_security_headers',
+              '--security_headers={"X-Frame-Options": "SAMEORIGIN"}',
+              '--operation_security_headers_enabled=bookstore.Embed=false'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_security_headers',
+              '--security_headers', '{"X-Frame-Options": "SAMEORIGIN"}',
+              '--operation_security_headers_enabled', 'bookstore.Embed=false',
+              ]),
+            # api_key_bearer_token_prefix and api_key_from_basic_auth_username specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--api_key_bearer_token_prefix=AIza',
+              '--api_key_from_basic_auth_username'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--api_key_bearer_token_prefix', 'AIza',
+              '--api_key_from_basic_auth_username',
+              ]),
+            # enable_deprecation_headers and operation_deprecations specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_deprecation_headers',
+              '--operation_deprecations=bookstore.GetBook=true'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_deprecation_headers',
+              '--operation_deprecations', 'bookstore.GetBook=true',
+              ]),
+            # access_log_consumer_sample_rates specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--access_log_consumer_sample_rates=123456=10'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--access_log_consumer_sample_rates', '123456=10',
+              ]),
+            # propagate_request_timeout specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--propagate_request_timeout'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--propagate_request_timeout',
+              ]),
+            # quota_override_consumers and service_consumer_management_url specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--quota_override_consumers=123456789',
+              '--service_consumer_management_url=https://serviceconsumermanagement.googleapis.com'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--quota_override_consumers', '123456789',
+              '--service_consumer_management_url', 'https://serviceconsumermanagement.googleapis.com',
+              ]),
+            # backend_failover_addresses specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_failover_addresses=https://a.example.com=https://b.example.com'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_failover_addresses', 'https://a.example.com=https://b.example.com',
+              ]),
+            # backend_regional_addresses specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_regional_addresses=https://a.example.com=https://a-us.example.com,https://a-eu.example.com'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_regional_addresses', 'https://a.example.com=https://a-us.example.com,https://a-eu.example.com',
+              ]),
+            # downstream flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--downstream_idle_timeout=5m',
+              '--downstream_max_connection_duration=1h',
+              '--downstream_drain_timeout=5s'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--downstream_idle_timeout', '5m',
+              '--downstream_max_connection_duration', '1h',
+              '--downstream_drain_timeout', '5s',
+              ]),
+            # disable_transcoding specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--disable_transcoding'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--disable_transcoding',
+              ]),
+            # grpc_reflection and grpc_reflection_jwt_providers specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--grpc_reflection=allow',
+              '--grpc_reflection_jwt_providers=google_id_token'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--grpc_reflection', 'allow',
+              '--grpc_reflection_jwt_providers', 'google_id_token',
+              ]),
+            # jwks_resolve_at_startup and jwks_resolve_ttl specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--jwks_resolve_at_startup',
+              '--jwks_resolve_ttl=1h'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--jwks_resolve_at_startup',
+              '--jwks_resolve_ttl', '1h',
+              ]),
+            # readiness_max_config_age and readiness_max_rollout_fetch_failures specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--readiness_max_config_age=1h',
+              '--readiness_max_rollout_fetch_failures=5'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--readiness_max_config_age', '1h',
+              '--readiness_max_rollout_fetch_failures', '5',
+              ]),
+            # http_filter_order specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--http_filter_order=envoy.filters.http.compressor=disabled'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--http_filter_order', 'envoy.filters.http.compressor=disabled',
+              ]),
+            # backend_rule_inheritance specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_rule_inheritance'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_rule_inheritance',
+              ]),
+            # operation_request_timeouts and operation_per_try_timeouts specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--operation_request_timeouts=bookstore.Export=60s',
+              '--operation_per_try_timeouts=bookstore.Export=25s'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--operation_request_timeouts', 'bookstore.Export=60s',
+              '--operation_per_try_timeouts', 'bookstore.Export=25s',
+              ]),
+            # service_control_compress_reports specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--service_control_compress_reports'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--service_control_compress_reports',
+              ]),
+            # allowed_source_ranges specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--allowed_source_ranges=10.0.0.0/8'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--allowed_source_ranges', '10.0.0.0/8',
+              ]),
+            # backend_jwt_audience_template and operation_jwt_audience_templates specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--backend_jwt_audience_template=https://{host}',
+              '--operation_jwt_audience_templates=bookstore.GetShelf={scheme}://{host}{path}'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--backend_jwt_audience_template', 'https://{host}',
+              '--operation_jwt_audience_templates', 'bookstore.GetShelf={scheme}://{host}{path}',
+              ]),
+            # suppress_upstream_service_time and operation_response_headers_to_remove specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--suppress_upstream_service_time',
+              '--operation_response_headers_to_remove=bookstore.GetShelf=x-powered-by'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--suppress_upstream_service_time',
+              '--operation_response_headers_to_remove', 'bookstore.GetShelf=x-powered-by',
+              ]),
+            # Server and Via header flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--server_header=espv2',
+              '--server_header_transformation=pass_through',
+              '--via_header=1.1 espv2'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--server_header', 'espv2',
+              '--server_header_transformation', 'pass_through',
+              '--via_header', '1.1 espv2',
+              ]),
+            # merge_duplicate_routes specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--merge_duplicate_routes'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--merge_duplicate_routes',
+              ]),
+            # tap flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--tap_output_path_prefix=/tmp/espv2_tap',
+              '--operation_tap_enabled=bookstore.GetShelf=true',
+              '--tap_max_buffered_bytes=1024',
+              '--tap_admin_config_id=espv2_tap'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--tap_output_path_prefix', '/tmp/espv2_tap',
+              '--operation_tap_enabled', 'bookstore.GetShelf=true',
+              '--tap_max_buffered_bytes', '1024',
+              '--tap_admin_config_id', 'espv2_tap',
+              ]),
+            # fault injection flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--operation_fault_abort=bookstore.GetShelf=503@10',
+              '--operation_fault_delay=bookstore.GetShelf=2s@50',
+              '--fault_trigger_header=x-chaos=on'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--operation_fault_abort', 'bookstore.GetShelf=503@10',
+              '--operation_fault_delay', 'bookstore.GetShelf=2s@50',
+              '--fault_trigger_header', 'x-chaos=on',
+              ]),
+            # response cache flags specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--enable_response_cache',
+              '--operation_response_cache_ttl=bookstore.ListShelves=60s',
+              '--response_cache_max_body_bytes=65536'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--enable_response_cache',
+              '--operation_response_cache_ttl', 'bookstore.ListShelves=60s',
+              '--response_cache_max_body_bytes', '65536',
+              ]),
+            # consumer_max_concurrent_requests and operation_consumer_max_concurrent_requests specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--consumer_max_concurrent_requests=10',
+              '--operation_consumer_max_concurrent_requests=bookstore.CreateShelf=2'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--consumer_max_concurrent_requests', '10',
+              '--operation_consumer_max_concurrent_requests', 'bookstore.CreateShelf=2',
+              ]),
+            # client_ip_rate_limit_per_second and client_ip_rate_limit_burst specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--client_ip_rate_limit_per_second=10',
+              '--client_ip_rate_limit_burst=20'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--client_ip_rate_limit_per_second', '10',
+              '--client_ip_rate_limit_burst', '20',
+              ]),
+            # deployment_mode specified
+            (['--version=2019-11-09r0', '--disable_tracing',
+              '--deployment_mode=sidecar'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--deployment_mode', 'sidecar',
+              ]),
         ]
 
         i = 0